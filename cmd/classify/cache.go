@@ -0,0 +1,126 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/nodeadmin/chebi-parser/reasoner"
+)
+
+// cacheVersion is part of every cache key; bump it when the reasoner's
+// output for the same axioms changes, so old entries are not reused.
+const cacheVersion = "4"
+
+// resultCache stores classification results in a directory, keyed by the
+// fingerprints of the partitions' normalized axioms and the options that
+// shape the output. An entry is <key>.json, plus <key>.closure.tsv once a
+// run with -closure has stored it.
+type resultCache struct {
+	dir string
+	key string
+}
+
+// cacheEntry is what <key>.json holds.
+type cacheEntry struct {
+	Hierarchy     *reasoner.ClassifiedHierarchy `json:"hierarchy"`
+	Unrooted      []string                      `json:"unrooted,omitempty"`
+	Unsatisfiable []string                      `json:"unsatisfiable,omitempty"`
+}
+
+func newResultCache(dir string, parts []*partition, opts reasoner.NormalizeOptions, partitioned bool, root, fillers string) (*resultCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "v%s approximate=%t oneof=%s provenance=%t obsolete=%s partition=%t root=%s fillers=%s\n", cacheVersion, opts.Approximate, opts.OneOf, opts.Provenance, opts.Obsolete, partitioned, root, fillers)
+	for _, p := range parts {
+		fmt.Fprintf(h, "%s %s\n", p.key, p.store.Fingerprint(p.st))
+	}
+	return &resultCache{dir: dir, key: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+func (c *resultCache) path(suffix string) string {
+	return filepath.Join(c.dir, c.key+suffix)
+}
+
+// load returns the cached entry, or false if there is none or it lacks
+// the closure table and withClosure is set.
+func (c *resultCache) load(withClosure bool) (*cacheEntry, bool) {
+	data, err := os.ReadFile(c.path(".json"))
+	if err != nil {
+		return nil, false
+	}
+	var e cacheEntry
+	if err := json.Unmarshal(data, &e); err != nil || e.Hierarchy == nil {
+		return nil, false
+	}
+	if withClosure {
+		if _, err := os.Stat(c.path(".closure.tsv")); err != nil {
+			return nil, false
+		}
+	}
+	return &e, true
+}
+
+// writeClosure copies the cached closure table to w.
+func (c *resultCache) writeClosure(w io.Writer) error {
+	f, err := os.Open(c.path(".closure.tsv"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// store saves the entry and, if closure is not empty, a copy of the
+// closure table written there.
+func (c *resultCache) store(e *cacheEntry, closure string) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if err := writeCacheFile(c.path(".json"), func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	}); err != nil {
+		return err
+	}
+	if closure == "" {
+		return nil
+	}
+	return writeCacheFile(c.path(".closure.tsv"), func(w io.Writer) error {
+		f, err := os.Open(closure)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(w, f)
+		return err
+	})
+}
+
+// writeCacheFile writes path through a temporary file and a rename, so
+// concurrent runs sharing the directory never read a partial entry.
+func writeCacheFile(path string, write func(io.Writer) error) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	err = write(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/nodeadmin/chebi-parser/ontology"
+	"github.com/nodeadmin/chebi-parser/reasoner"
+)
+
+// runConformance classifies every ontology in dir that has a sibling
+// <name>.expected.tsv of reference subsumptions and reports discrepancies.
+// It returns false if any ontology disagrees with its reference.
+//
+// The bundled references in testdata/conformance are written by hand from
+// the EL semantics, with a comment saying why each pair holds; they are not
+// reasoner output. A reference produced by ELK (robot reason --reasoner ELK
+// --include-indirect true, then the named sub<TAB>super pairs) is read the
+// same way.
+func runConformance(dir string) (bool, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false, err
+	}
+	var inputs []string
+	for _, e := range entries {
+		name := e.Name()
+		switch strings.ToLower(filepath.Ext(name)) {
+		case ".obo", ".owl":
+			inputs = append(inputs, filepath.Join(dir, name))
+		}
+	}
+	sort.Strings(inputs)
+
+	passed, failed := 0, 0
+	for _, input := range inputs {
+		base := strings.TrimSuffix(input, filepath.Ext(input))
+		f, err := os.Open(base + ".expected.tsv")
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		want, err := reasoner.ReadSubsumptions(f)
+		f.Close()
+		if err != nil {
+			return false, fmt.Errorf("%s.expected.tsv: %w", base, err)
+		}
+
+		ont, err := parse(input, ontology.ParseOptions{})
+		if err != nil {
+			return false, fmt.Errorf("%s: %w", input, err)
+		}
+
+		st, store := reasoner.Normalize(ont)
+		contexts := reasoner.Saturate(st, store)
+		missing, unexpected := reasoner.CompareSubsumptions(reasoner.Subsumptions(contexts, st), want)
+		if len(missing) == 0 && len(unexpected) == 0 {
+			passed++
+			fmt.Printf("ok   %s (%d subsumptions)\n", filepath.Base(input), len(want))
+			continue
+		}
+		failed++
+		fmt.Printf("FAIL %s\n", filepath.Base(input))
+		for _, s := range missing {
+			fmt.Printf("  missing     %s ⊑ %s\n", s.Sub, s.Super)
+		}
+		for _, s := range unexpected {
+			fmt.Printf("  unexpected  %s ⊑ %s\n", s.Sub, s.Super)
+		}
+	}
+
+	if passed+failed == 0 {
+		return false, fmt.Errorf("no ontologies with .expected.tsv files in %s", dir)
+	}
+	fmt.Printf("%d passed, %d failed\n", passed, failed)
+	return failed == 0, nil
+}
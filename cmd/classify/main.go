@@ -0,0 +1,388 @@
+// Command classify runs the EL reasoner over a ChEBI ontology and writes
+// the classified hierarchy as JSON.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/nodeadmin/chebi-parser/internal/exitcode"
+	"github.com/nodeadmin/chebi-parser/ontology"
+	"github.com/nodeadmin/chebi-parser/reasoner"
+)
+
+func main() {
+	input := flag.String("input", "", "Path to ontology file (.obo or .owl)")
+	output := flag.String("output", "", "Path to output JSON file (default: stdout)")
+	workers := flag.Int("workers", 0, "Saturation workers (default: number of CPUs)")
+	closure := flag.String("closure", "", "Also write the inferred is_a closure table (TSV) to this path")
+	approximate := flag.Bool("approximate", false, "Rewrite non-EL axioms (union_of) into sound EL approximations instead of dropping them")
+	oneOf := flag.String("oneof", "skip", "Handle owl:oneOf enumerations: skip, fresh ({a} ⊑ C) or expand (also C ⊑ common types of the members)")
+	approxReport := flag.String("approx-report", "", "Write how each non-EL axiom was handled, and the duplicate and self is_a axioms dropped (TSV), to this path")
+	maxMemory := flag.String("max-memory", "", "Heap budget (e.g. 1.5GB); term bodies (definitions, synonyms, ...) are spilled to a temporary file to stay under it")
+	spillDir := flag.String("spill-dir", "", "Directory for the -max-memory spill file (default: system temp directory)")
+	root := flag.String("root", "", "Designated root class(es), comma-separated (e.g. CHEBI:24431); roots are reported without parents and classes outside them as unrooted")
+	rootReport := flag.String("root-report", "", "Write the classes not under any -root (TSV) to this path")
+	conformance := flag.String("conformance", "", "Classify each ontology in this directory and compare with its .expected.tsv")
+	partitioned := flag.Bool("partition", false, "Classify groups of namespaces that never reference each other as separate partitions, concurrently")
+	properties := flag.Int("properties", 0, "Check reasoner invariants on this many random ontologies and exit")
+	fillerRoles := flag.String("fillers", "", "Add to each concept its inferred targets for these relations, comma-separated (e.g. has_part,has_role)")
+	obsoleteFlag := flag.String("obsolete", "", "Classify obsolete terms too (include); by default they are left out")
+	provenance := flag.Bool("provenance", false, "Add to each concept the rule and source axioms behind each direct parent")
+	cacheDir := flag.String("cache", "", "Reuse classification results from this directory when the normalized axioms and options are unchanged, and store new ones there")
+	failOnUnsat := flag.Bool("fail-on-unsat", false, "Exit with code 5 after writing the output if any class is unsatisfiable")
+	flag.StringVar(&errorsJSON, "errors-json", "", "Write a JSON envelope with the exit code, failure class and message to this file")
+	flag.Parse()
+
+	// run returns its failure rather than exiting, so its deferred
+	// cleanup (closing the output, removing the spill file) has run.
+	run := func() error {
+		if *conformance != "" {
+			ok, err := runConformance(*conformance)
+			if err != nil {
+				return exitcode.Errorf(exitcode.Of(err), "Error: %v", err)
+			}
+			if !ok {
+				return exitcode.Errorf(exitcode.Validation, "Error: conformance check failed")
+			}
+			return nil
+		}
+
+		if *properties > 0 {
+			if !runProperties(*properties) {
+				return exitcode.Errorf(exitcode.Validation, "Error: property check failed")
+			}
+			return nil
+		}
+
+		if *input == "" {
+			return exitcode.Errorf(exitcode.Usage, "Usage: classify -input <file.obo|file.owl> [-output <file>] [-workers N] [-closure <file.tsv>] [-approximate] [-oneof skip|fresh|expand] [-approx-report <file.tsv>] [-root CHEBI:24431 [-root-report <file.tsv>]] [-partition] [-provenance] [-fillers has_part,...] [-obsolete include|exclude] [-max-memory 1.5GB] [-cache <dir>] [-fail-on-unsat] [-errors-json <file>]\n       classify -conformance <dir>\n       classify -properties <runs>")
+		}
+		oneOfStrategy, err := reasoner.ParseOneOfStrategy(*oneOf)
+		if err != nil {
+			return exitcode.Errorf(exitcode.Usage, "Error: %v", err)
+		}
+
+		obsolete, err := ontology.ParseObsoletePolicy(*obsoleteFlag)
+		if err != nil {
+			return exitcode.Errorf(exitcode.Usage, "Error: -obsolete: %v", err)
+		}
+
+		opts := ontology.ParseOptions{Obsolete: obsolete}
+		if *maxMemory != "" {
+			if opts.MaxMemory, err = ontology.ParseByteSize(*maxMemory); err != nil {
+				return exitcode.Errorf(exitcode.Usage, "Error: -max-memory: %v", err)
+			}
+			debug.SetMemoryLimit(opts.MaxMemory)
+			if opts.Bodies, err = ontology.NewBodyStore(*spillDir); err != nil {
+				return exitcode.Errorf(exitcode.IO, "Error creating spill file: %v", err)
+			}
+			defer opts.Bodies.Close()
+		}
+
+		start := time.Now()
+		ont, err := parse(*input, opts)
+		if err != nil {
+			return exitcode.Errorf(exitcode.Parse, "Error parsing: %v", err)
+		}
+		parseTime := time.Since(start)
+		fmt.Fprintf(os.Stderr, "Parse time: %v (%d terms)\n", parseTime, len(ont.Terms))
+
+		// The reasoner never reads term bodies, so under a budget they go to
+		// disk before normalization whether or not parsing reached it.
+		if opts.Bodies != nil {
+			if err := opts.Bodies.SpillAll(ont); err != nil {
+				return exitcode.Errorf(exitcode.IO, "Error spilling term bodies: %v", err)
+			}
+			runtime.GC()
+			fmt.Fprintf(os.Stderr, "Spilled %d term bodies (%d MB)\n", opts.Bodies.Len(), opts.Bodies.Size()>>20)
+		}
+
+		parts := []*partition{{ont: ont}}
+		if *partitioned {
+			parts = parts[:0]
+			var keys []string
+			for _, sp := range ontology.PartitionNamespaces(ont) {
+				parts = append(parts, &partition{key: sp.Key, ont: sp.Ontology})
+				keys = append(keys, sp.Key)
+			}
+			fmt.Fprintf(os.Stderr, "Partitions: %d (%s)\n", len(parts), strings.Join(keys, ", "))
+		}
+
+		normOpts := reasoner.NormalizeOptions{
+			Approximate: *approximate,
+			OneOf:       oneOfStrategy,
+			Provenance:  *provenance,
+			Obsolete:    obsolete,
+		}
+		normTime := eachPart(parts, func(p *partition) {
+			p.st, p.store, p.approx = reasoner.NormalizeWithOptions(p.ont, normOpts)
+		})
+		approx := parts[0].approx
+		concepts, roles := 0, 0
+		for i, p := range parts {
+			concepts += p.st.ConceptCount()
+			roles = max(roles, p.st.RoleCount())
+			if i > 0 {
+				concepts -= 2 // owl:Thing and owl:Nothing are in every partition
+				approx.Entries = append(approx.Entries, p.approx.Entries...)
+				approx.Cleanup = append(approx.Cleanup, p.approx.Cleanup...)
+			}
+		}
+		fmt.Fprintf(os.Stderr, "Normalize time: %v (%d concepts, %d roles)\n", normTime, concepts, roles)
+		if len(approx.Entries) > 0 {
+			rewritten, dropped := approx.Counts()
+			fmt.Fprintf(os.Stderr, "Non-EL axioms: %d rewritten, %d dropped\n", rewritten, dropped)
+			if n := skippedOneOf(approx); n > 0 {
+				fmt.Fprintf(os.Stderr, "Warning: %d owl:oneOf enumerations skipped; use -oneof fresh or -oneof expand to keep them\n", n)
+			}
+		}
+		if len(approx.Cleanup) > 0 {
+			duplicates, selfIsA := approx.CleanupCounts()
+			fmt.Fprintf(os.Stderr, "Cleanup: %d duplicate axioms, %d self is_a edges dropped\n", duplicates, selfIsA)
+		}
+		if *approxReport != "" {
+			if err := writeApproxReport(*approxReport, approx); err != nil {
+				return exitcode.Errorf(exitcode.Of(err), "Error writing approximation report: %v", err)
+			}
+		}
+
+		var cache *resultCache
+		var cached *cacheEntry
+		if *cacheDir != "" {
+			if cache, err = newResultCache(*cacheDir, parts, normOpts, *partitioned, *root, *fillerRoles); err != nil {
+				return exitcode.Errorf(exitcode.IO, "Error: -cache: %v", err)
+			}
+			var hit bool
+			if cached, hit = cache.load(*closure != ""); hit {
+				fmt.Fprintf(os.Stderr, "Cache hit: %s\n", cache.key)
+			}
+		}
+
+		var hierarchy *reasoner.ClassifiedHierarchy
+		var unrooted, unsat []string
+		closureTo := func(w io.Writer) error { return writeClosure(w, parts, obsolete) }
+		if cached != nil {
+			// Only the timings of this run are new; saturation and reduction
+			// were skipped.
+			hierarchy, unrooted, unsat = cached.Hierarchy, cached.Unrooted, cached.Unsatisfiable
+			inferred := hierarchy.Stats.InferredSubsumptions
+			hierarchy.Stats = mergeStats(parts, parseTime, normTime, 0, 0)
+			hierarchy.Stats.InferredSubsumptions = inferred
+			closureTo = cache.writeClosure
+		} else {
+			satWorkers := partWorkers(*workers, len(parts))
+			satTime := eachPart(parts, func(p *partition) {
+				p.contexts = reasoner.SaturateParallel(p.st, p.store, satWorkers)
+				if !*provenance {
+					p.store = nil
+				}
+			})
+			fmt.Fprintf(os.Stderr, "Saturation time: %v\n", satTime)
+
+			redTime := eachPart(parts, func(p *partition) {
+				p.tax = reasoner.BuildTaxonomy(p.contexts, p.st)
+			})
+			fmt.Fprintf(os.Stderr, "Reduction time: %v\n", redTime)
+
+			if *root != "" {
+				for _, id := range strings.Split(*root, ",") {
+					found := false
+					for _, p := range parts {
+						if c, err := reasoner.ResolveRoots(p.st, []string{id}); err == nil {
+							p.roots = append(p.roots, c...)
+							found = true
+						}
+					}
+					if !found {
+						return exitcode.Errorf(exitcode.Usage, "Error: -root: unknown root class %q", id)
+					}
+				}
+				for _, p := range parts {
+					unrooted = append(unrooted, reasoner.Unrooted(p.contexts, p.st, p.roots)...)
+				}
+			}
+
+			if *fillerRoles != "" {
+				for _, id := range strings.Split(*fillerRoles, ",") {
+					found := false
+					for _, p := range parts {
+						if r, err := reasoner.ResolveRoles(p.st, []string{id}); err == nil {
+							p.fillers = append(p.fillers, r...)
+							found = true
+						}
+					}
+					if !found {
+						return exitcode.Errorf(exitcode.Usage, "Error: -fillers: unknown relation %q", id)
+					}
+				}
+			}
+
+			for _, p := range parts {
+				unsat = append(unsat, reasoner.Unsatisfiable(p.contexts, p.st)...)
+			}
+			stats := mergeStats(parts, parseTime, normTime, satTime, redTime)
+			hierarchy = mergeHierarchies(ont, parts, stats)
+		}
+
+		if *root != "" {
+			if len(unrooted) > 0 {
+				fmt.Fprintf(os.Stderr, "Warning: %d classes are not under %s\n", len(unrooted), *root)
+			}
+			if *rootReport != "" {
+				if err := writeRootReport(*rootReport, unrooted); err != nil {
+					return exitcode.Errorf(exitcode.Of(err), "Error writing root report: %v", err)
+				}
+			}
+		}
+
+		out := os.Stdout
+		if *output != "" {
+			out, err = os.Create(*output)
+			if err != nil {
+				return exitcode.Errorf(exitcode.IO, "Error creating output: %v", err)
+			}
+			defer out.Close()
+		}
+		if err := reasoner.WriteClassifiedJSON(out, hierarchy); err != nil {
+			return exitcode.Errorf(exitcode.Of(err), "Error writing output: %v", err)
+		}
+		if *output != "" {
+			if err := out.Close(); err != nil {
+				return exitcode.Errorf(exitcode.IO, "Error writing output: %v", err)
+			}
+		}
+
+		if *closure != "" {
+			f, err := os.Create(*closure)
+			if err != nil {
+				return exitcode.Errorf(exitcode.IO, "Error creating closure file: %v", err)
+			}
+			err = closureTo(f)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return exitcode.Errorf(exitcode.Of(err), "Error writing closure: %v", err)
+			}
+		}
+
+		if cache != nil && cached == nil {
+			// A failed store only costs the next run its cache hit.
+			if err := cache.store(&cacheEntry{Hierarchy: hierarchy, Unrooted: unrooted, Unsatisfiable: unsat}, *closure); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: storing in -cache: %v\n", err)
+			}
+		}
+
+		fmt.Fprintf(os.Stderr, "Inferred subsumptions: %d\n", hierarchy.Stats.InferredSubsumptions)
+		fmt.Fprintf(os.Stderr, "Total time: %v\n", time.Since(start))
+		if len(unsat) > 0 {
+			fmt.Fprintf(os.Stderr, "Warning: %d unsatisfiable classes (e.g. %s)\n", len(unsat), unsat[0])
+			if *failOnUnsat {
+				shown := unsat
+				if len(shown) > 10 {
+					shown = append(shown[:10:10], "...")
+				}
+				return exitcode.Errorf(exitcode.Unsat, "Error: %d unsatisfiable classes: %s", len(unsat), strings.Join(shown, ", "))
+			}
+		}
+		return nil
+	}
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		writeEnvelope(exitcode.Of(err), err.Error())
+		os.Exit(exitcode.Of(err))
+	}
+	succeed()
+}
+
+// errorsJSON is the -errors-json file.
+var errorsJSON string
+
+// succeed writes the -errors-json envelope of a successful run.
+func succeed() {
+	writeEnvelope(exitcode.OK, "")
+}
+
+func writeEnvelope(code int, msg string) {
+	if errorsJSON == "" {
+		return
+	}
+	if err := exitcode.WriteEnvelope(errorsJSON, "classify", code, msg); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing -errors-json: %v\n", err)
+	}
+}
+
+func writeApproxReport(path string, report *reasoner.ApproxReport) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(f)
+	fmt.Fprintln(bw, "term_id\tconstruct\taction\taxioms")
+	for _, e := range report.Entries {
+		fmt.Fprintln(bw, e.String())
+	}
+	for _, e := range report.Cleanup {
+		fmt.Fprintln(bw, e.String())
+	}
+	err = bw.Flush()
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// writeRootReport writes one "unrooted" finding per class outside the
+// designated roots.
+func writeRootReport(path string, unrooted []string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(f)
+	fmt.Fprintln(bw, "term_id\tfinding")
+	for _, id := range unrooted {
+		fmt.Fprintf(bw, "%s\tunrooted\n", id)
+	}
+	err = bw.Flush()
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// skippedOneOf counts the enumerations the report lists as dropped.
+func skippedOneOf(report *reasoner.ApproxReport) int {
+	n := 0
+	for _, e := range report.Entries {
+		if e.Construct == "one_of" && e.Action == reasoner.ApproxDropped {
+			n++
+		}
+	}
+	return n
+}
+
+func parse(path string, opts ontology.ParseOptions) (*ontology.Ontology, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".obo":
+		return ontology.ParseOBOWithOptions(f, opts)
+	case ".owl", ".xml", ".rdf":
+		return ontology.ParseOWLWithOptions(f, opts)
+	}
+	return nil, fmt.Errorf("cannot detect format for %q", path)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nodeadmin/chebi-parser/ontology"
+	"github.com/nodeadmin/chebi-parser/reasoner"
+)
+
+// partition is one independently classified part of the ontology; without
+// -partition the whole ontology is the only one.
+type partition struct {
+	key      string
+	ont      *ontology.Ontology
+	st       *reasoner.SymbolTable
+	store    *reasoner.AxiomStore
+	approx   *reasoner.ApproxReport
+	contexts []reasoner.Context
+	tax      *reasoner.Taxonomy
+	roots    []reasoner.ConceptID
+	fillers  []reasoner.RoleID
+}
+
+// eachPart runs fn on every partition concurrently and returns the wall
+// time until all are done, so the phase timings stay comparable with an
+// unpartitioned run.
+func eachPart(parts []*partition, fn func(*partition)) time.Duration {
+	start := time.Now()
+	if len(parts) == 1 {
+		fn(parts[0])
+		return time.Since(start)
+	}
+	var wg sync.WaitGroup
+	for _, p := range parts {
+		wg.Add(1)
+		go func(p *partition) {
+			defer wg.Done()
+			fn(p)
+		}(p)
+	}
+	wg.Wait()
+	return time.Since(start)
+}
+
+// partWorkers shares the saturation workers between concurrently
+// saturated partitions.
+func partWorkers(workers, parts int) int {
+	if parts == 1 {
+		return workers
+	}
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	return max(1, workers/parts)
+}
+
+// mergeHierarchies concatenates the partitions' hierarchies. With several
+// partitions the concepts are put back in ontology term order, with
+// concepts that are not terms last.
+func mergeHierarchies(ont *ontology.Ontology, parts []*partition, stats reasoner.ClassificationStats) *reasoner.ClassifiedHierarchy {
+	if len(parts) == 1 {
+		p := parts[0]
+		return p.tax.ToJSONWithOptions(p.contexts, p.st, stats, p.taxonomyOptions())
+	}
+	merged := &reasoner.ClassifiedHierarchy{Stats: stats}
+	for _, p := range parts {
+		h := p.tax.ToJSONWithOptions(p.contexts, p.st, stats, p.taxonomyOptions())
+		merged.Concepts = append(merged.Concepts, h.Concepts...)
+		merged.Stats.InferredSubsumptions += h.Stats.InferredSubsumptions
+	}
+	order := make(map[string]int, len(ont.Terms))
+	for i := range ont.Terms {
+		if _, ok := order[ont.Terms[i].ID]; !ok {
+			order[ont.Terms[i].ID] = i
+		}
+	}
+	rank := func(id string) int {
+		if i, ok := order[id]; ok {
+			return i
+		}
+		return len(ont.Terms)
+	}
+	sort.SliceStable(merged.Concepts, func(i, j int) bool {
+		return rank(merged.Concepts[i].ID) < rank(merged.Concepts[j].ID)
+	})
+	return merged
+}
+
+// taxonomyOptions returns the partition's roots, filler relations and, if
+// its axioms were kept for it, provenance.
+func (p *partition) taxonomyOptions() reasoner.TaxonomyOptions {
+	return reasoner.TaxonomyOptions{Roots: p.roots, Provenance: p.store, Fillers: p.fillers}
+}
+
+// mergeStats sums the partitions' concept counts; every partition carries
+// all typedefs, so the role count is the largest one.
+func mergeStats(parts []*partition, parseTime, normTime, satTime, redTime time.Duration) reasoner.ClassificationStats {
+	stats := reasoner.MakeStats(parts[0].st, parseTime, normTime, satTime, redTime)
+	for _, p := range parts[1:] {
+		s := reasoner.MakeStats(p.st, 0, 0, 0, 0)
+		stats.ConceptCount += s.ConceptCount
+		stats.RoleCount = max(stats.RoleCount, s.RoleCount)
+	}
+	return stats
+}
+
+// writeClosure writes the is_a closure of every partition's
+// reasoner.BuildGraph, asserted and inferred edges alike, as one table.
+func writeClosure(w io.Writer, parts []*partition, obsolete ontology.ObsoletePolicy) error {
+	bw := bufio.NewWriterSize(w, 256*1024)
+	if _, err := bw.WriteString("term_id\tancestor_id\tdistance\trelation\n"); err != nil {
+		return err
+	}
+	isA := ontology.EdgeFilter{Relations: []string{"is_a"}}
+	for _, p := range parts {
+		g := reasoner.BuildGraph(p.ont, p.st, p.contexts, p.tax, ontology.GraphOptions{Obsolete: obsolete})
+		g.Closure(isA, func(row ontology.ClosureRow) {
+			ontology.WriteClosureRow(bw, row)
+		})
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+
+	"github.com/nodeadmin/chebi-parser/ontology"
+	"github.com/nodeadmin/chebi-parser/reasoner"
+	"github.com/nodeadmin/chebi-parser/testgen"
+)
+
+// propertyTerms is the size of each generated ontology: small enough for
+// hundreds of runs, large enough for chains, cross-products and cycles.
+const propertyTerms = 300
+
+// propertyExtraAxioms is how many random axioms are added per run for the
+// monotonicity check.
+const propertyExtraAxioms = 5
+
+// runProperties classifies runs random testgen ontologies (seeds 1..runs)
+// and checks reasoner.CheckProperties on each, plus monotonicity: adding random is_a
+// and existential axioms must not lose any subsumption. It returns false
+// if any run violates a property.
+func runProperties(runs int) bool {
+	passed, failed := 0, 0
+	for seed := 1; seed <= runs; seed++ {
+		cfg := testgen.DefaultConfig()
+		cfg.Terms = propertyTerms
+		cfg.Relations = 4
+		cfg.Chains = 1
+		cfg.CrossProducts = 0.1
+		cfg.Seed = uint64(seed)
+
+		ont := testgen.Generate(cfg)
+		st, store := reasoner.Normalize(ont)
+		contexts := reasoner.Saturate(st, store)
+		failures := reasoner.CheckProperties(contexts, st, reasoner.BuildTaxonomy(contexts, st))
+		before := reasoner.Subsumptions(contexts, st)
+
+		addRandomAxioms(ont, uint64(seed))
+		st, store = reasoner.Normalize(ont)
+		contexts = reasoner.Saturate(st, store)
+		lost, _ := reasoner.CompareSubsumptions(reasoner.Subsumptions(contexts, st), before)
+		for _, s := range lost {
+			failures = append(failures, fmt.Sprintf("not monotone: %s ⊑ %s lost after adding axioms", s.Sub, s.Super))
+		}
+
+		if len(failures) == 0 {
+			passed++
+			continue
+		}
+		failed++
+		fmt.Printf("FAIL seed %d\n", seed)
+		for i, f := range failures {
+			if i == 10 {
+				fmt.Printf("  ... and %d more\n", len(failures)-i)
+				break
+			}
+			fmt.Printf("  %s\n", f)
+		}
+	}
+	fmt.Printf("%d passed, %d failed\n", passed, failed)
+	return failed == 0
+}
+
+// addRandomAxioms adds propertyExtraAxioms is_a or existential
+// relationships between random terms, which may introduce cycles.
+func addRandomAxioms(ont *ontology.Ontology, seed uint64) {
+	rng := rand.New(rand.NewPCG(seed, 0x5eed))
+	for i := 0; i < propertyExtraAxioms; i++ {
+		t := &ont.Terms[rng.IntN(len(ont.Terms))]
+		rel := ontology.Relationship{Type: "is_a", TargetID: ont.Terms[rng.IntN(len(ont.Terms))].ID}
+		if len(ont.TypeDefs) > 0 && rng.IntN(2) == 0 {
+			rel.Type = ont.TypeDefs[rng.IntN(len(ont.TypeDefs))].ID
+		}
+		t.Relationships = append(t.Relationships, rel)
+	}
+}
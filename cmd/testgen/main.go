@@ -0,0 +1,85 @@
+// Command testgen writes a synthetic OBO or OWL ontology for parser fuzzing
+// and reasoner benchmarks.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nodeadmin/chebi-parser/ontology"
+	"github.com/nodeadmin/chebi-parser/testgen"
+)
+
+func main() {
+	def := testgen.DefaultConfig()
+	output := flag.String("output", "", "Output file; format from extension (.obo or .owl) (default: stdout as OBO)")
+	format := flag.String("format", "", "Output format: obo or owl (overrides the extension)")
+	terms := flag.Int("terms", def.Terms, "Number of terms")
+	branching := flag.Int("branching", def.Branching, "Children per term in the backbone is_a tree")
+	multi := flag.Float64("multi-parent", def.MultiParent, "Probability of an extra is_a parent")
+	relations := flag.Int("relations", def.Relations, "Number of relation types")
+	transitive := flag.Float64("transitive", def.Transitive, "Fraction of transitive relation types")
+	chains := flag.Int("chains", def.Chains, "Number of two-step property chains")
+	density := flag.Float64("rel-density", def.RelDensity, "Mean existential relationships per term")
+	cross := flag.Float64("cross-products", def.CrossProducts, "Fraction of terms with an intersection_of definition")
+	obsolete := flag.Float64("obsolete", def.Obsolete, "Fraction of obsolete terms")
+	synonyms := flag.Float64("synonyms", def.Synonyms, "Mean synonyms per term")
+	prefix := flag.String("prefix", def.Prefix, "ID prefix")
+	seed := flag.Uint64("seed", def.Seed, "Random seed")
+	flag.Parse()
+
+	outFmt := *format
+	if outFmt == "" {
+		outFmt = "obo"
+		if strings.HasSuffix(strings.ToLower(*output), ".owl") {
+			outFmt = "owl"
+		}
+	}
+	var write func(*ontology.Ontology, io.Writer) error
+	switch outFmt {
+	case "obo":
+		write = testgen.WriteOBO
+	case "owl":
+		write = testgen.WriteOWL
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown format %q: use obo or owl\n", outFmt)
+		os.Exit(1)
+	}
+
+	start := time.Now()
+	ont := testgen.Generate(testgen.Config{
+		Terms:         *terms,
+		Branching:     *branching,
+		MultiParent:   *multi,
+		Relations:     *relations,
+		Transitive:    *transitive,
+		Chains:        *chains,
+		RelDensity:    *density,
+		CrossProducts: *cross,
+		Obsolete:      *obsolete,
+		Synonyms:      *synonyms,
+		Prefix:        *prefix,
+		Seed:          *seed,
+	})
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating output: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		out = f
+	}
+	if err := write(ont, out); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "Generated %d terms, %d relation types in %v\n",
+		len(ont.Terms), len(ont.TypeDefs), time.Since(start))
+}
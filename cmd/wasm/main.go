@@ -0,0 +1,114 @@
+//go:build js && wasm
+
+// Command wasm exposes the ontology parser to JavaScript.
+//
+// Build with:
+//
+//	GOOS=js GOARCH=wasm go build -o bin/chebi.wasm ./cmd/wasm
+//
+// After loading the module (via wasm_exec.js), a global `chebi` object is
+// available:
+//
+//	chebi.parseOBO(text)  → {terms, error}
+//	chebi.term(id)        → term JSON string, or null
+//	chebi.parents(id)     → array of parent IDs (is_a)
+//	chebi.children(id)    → array of child IDs (is_a)
+//
+// id may also be an alt ID, a label or an unambiguous synonym.
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"syscall/js"
+
+	"github.com/nodeadmin/chebi-parser/ontology"
+)
+
+// state holds the most recently parsed ontology and its index.
+var state struct {
+	ont *ontology.Ontology
+	ix  *ontology.Index
+}
+
+func main() {
+	api := js.Global().Get("Object").New()
+	api.Set("parseOBO", js.FuncOf(parseOBO))
+	api.Set("term", js.FuncOf(term))
+	api.Set("parents", js.FuncOf(parents))
+	api.Set("children", js.FuncOf(children))
+	js.Global().Set("chebi", api)
+
+	// Keep the Go runtime alive so the callbacks stay valid.
+	select {}
+}
+
+func parseOBO(this js.Value, args []js.Value) any {
+	result := map[string]any{"terms": 0, "error": nil}
+	if len(args) < 1 {
+		result["error"] = "parseOBO: missing text argument"
+		return result
+	}
+
+	ont, err := ontology.ParseOBO(strings.NewReader(args[0].String()))
+	if err != nil {
+		result["error"] = err.Error()
+		return result
+	}
+
+	state.ont = ont
+	state.ix = ontology.NewIndex(ont)
+
+	result["terms"] = len(ont.Terms)
+	return result
+}
+
+func lookup(args []js.Value) *ontology.Term {
+	if state.ont == nil || len(args) < 1 {
+		return nil
+	}
+	id, err := state.ix.Lookup(args[0].String())
+	if err != nil {
+		return nil
+	}
+	return state.ix.Term(id)
+}
+
+func term(this js.Value, args []js.Value) any {
+	t := lookup(args)
+	if t == nil {
+		return js.Null()
+	}
+	data, err := json.Marshal(t)
+	if err != nil {
+		return js.Null()
+	}
+	return string(data)
+}
+
+func parents(this js.Value, args []js.Value) any {
+	t := lookup(args)
+	if t == nil {
+		return []any{}
+	}
+	ids := make([]any, 0, len(t.Relationships))
+	for _, rel := range t.Relationships {
+		if rel.Type == "is_a" {
+			ids = append(ids, rel.TargetID)
+		}
+	}
+	return ids
+}
+
+func children(this js.Value, args []js.Value) any {
+	t := lookup(args)
+	if t == nil {
+		return []any{}
+	}
+	kids := state.ix.Children(t.ID)
+	ids := make([]any, len(kids))
+	for i, id := range kids {
+		ids[i] = id
+	}
+	return ids
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/nodeadmin/chebi-parser/internal/exitcode"
+	"github.com/nodeadmin/chebi-parser/ontology"
+)
+
+// command defines a subcommand's flags on a new FlagSet and returns it
+// with the command body, which runs once the flags are parsed and returns
+// an error to be reported on stderr. -help-json and completion build the
+// FlagSets without running the bodies.
+type command func() (*flag.FlagSet, func() error)
+
+// commands maps subcommand names to their commands.
+var commands = map[string]command{
+	"complete-terms":  completeTermsCommand,
+	"convert":         convertCommand,
+	"definitions":     definitionsCommand,
+	"diff-classified": diffClassifiedCommand,
+	"embed-slim":      embedSlimCommand,
+	"explore":         exploreCommand,
+	"features":        featuresCommand,
+	"history":         historyCommand,
+	"lint":            lintCommand,
+	"path":            pathCommand,
+	"pipeline":        pipelineCommand,
+	"profile-check":   profileCheckCommand,
+	"propagate":       propagateCommand,
+	"query":           queryCommand,
+	"references":      referencesCommand,
+	"relations":       relationsCommand,
+	"resolve":         resolveCommand,
+	"rollup":          rollupCommand,
+	"sample":          sampleCommand,
+	"search-index":    searchIndexCommand,
+	"serve":           serveCommand,
+	"show":            showCommand,
+	"signature":       signatureCommand,
+	"validate-ids":    validateIDsCommand,
+}
+
+func runCommand(name string, args []string) {
+	cmd, ok := commands[name]
+	if !ok {
+		names := make([]string, 0, len(commands))
+		for n := range commands {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		fmt.Fprintf(os.Stderr, "Unknown command %q. Available commands: %v\n", name, names)
+		fmt.Fprintln(os.Stderr, "Run without a command to convert: chebi-parser -input <file> ...")
+		os.Exit(exitcode.Usage)
+	}
+	fs, run := cmd()
+	fs.Parse(args)
+	err := run()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}
+	exitWith(name, exitcode.Of(err), err)
+}
+
+// errorsJSON is the -errors-json file every command accepts.
+var errorsJSON string
+
+// exitWith writes the -errors-json envelope, if one was asked for, and
+// exits with code unless it is 0. err is the failure, if any.
+func exitWith(command string, code int, err error) {
+	if errorsJSON != "" {
+		msg := ""
+		if err != nil {
+			msg = err.Error()
+		}
+		if werr := exitcode.WriteEnvelope(errorsJSON, command, code, msg); werr != nil {
+			fmt.Fprintf(os.Stderr, "Error writing -errors-json: %v\n", werr)
+		}
+	}
+	if code != exitcode.OK {
+		os.Exit(code)
+	}
+}
+
+// newFlagSet returns the flag set for a command. Every command accepts
+// -errors-json.
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.StringVar(&errorsJSON, "errors-json", "", "Write a JSON envelope with the exit code, failure class and message to this file")
+	return fs
+}
+
+// obsoleteUsage is the help text of every -obsolete flag.
+const obsoleteUsage = "Keep obsolete terms (include) or drop them (exclude) in every stage; by default parsing and exports keep them and classification and SKOS leave them out"
+
+// charsetUsage is the help text of every -charset flag.
+const charsetUsage = "OBO lines that are not UTF-8: report them as encoding warnings (report) or read the bad bytes as Windows-1252 (transcode)"
+
+// synonymsUsage is the help text of every -synonyms flag.
+const synonymsUsage = "Keep synonyms as given (keep), or merge those differing only in whitespace and drop those equal to the name (collapse), also ignoring case (fold)"
+
+// orientUsage is the help text of every -orient flag.
+const orientUsage = "Comma-separated relation types (e.g. part_of) to assert every edge of their inverse_of pair as: X has_part Y is rewritten as Y part_of X"
+
+// orientRelationships applies an -orient value to ont and reports it.
+func orientRelationships(ont *ontology.Ontology, value string) error {
+	n, err := ontology.OrientRelationships(ont, strings.Split(value, ","))
+	if err != nil {
+		return exitcode.Wrap(exitcode.Usage, fmt.Errorf("-orient: %w", err))
+	}
+	fmt.Fprintf(os.Stderr, "Orient: rewrote %d edges as %s\n", n, value)
+	return nil
+}
+
+// labelPrefsUsage is the help text of every -label-prefs flag.
+const labelPrefsUsage = "Label terms by the first of these synonym types (TYPE or TYPE@SOURCE) they have, most preferred first, then name, e.g. INN,IUPAC_NAME@IUPAC,name (default: the name)"
+
+// labelPrefs reads a -label-prefs value.
+func labelPrefs(value string) (ontology.LabelPrefs, error) {
+	prefs, err := ontology.ParseLabelPrefs(value)
+	if err != nil {
+		return nil, exitcode.Wrap(exitcode.Usage, fmt.Errorf("-label-prefs: %w", err))
+	}
+	return prefs, nil
+}
+
+// excludeBranchUsage is the help text of every -exclude-branch flag.
+const excludeBranchUsage = "Comma-separated is_a roots (IDs or names, e.g. role) whose subtrees classification leaves out, so role classes do not mix into chemical groupings"
+
+// excludedBranches resolves an -exclude-branch value against ix.
+func excludedBranches(ix *ontology.Index, value string) ([]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+	roots, err := ix.LookupAll(strings.Split(value, ","))
+	if err != nil {
+		return nil, exitcode.Wrap(exitcode.Usage, fmt.Errorf("-exclude-branch: %w", err))
+	}
+	return roots, nil
+}
+
+// loadOntology opens and parses path, detecting the format from its
+// extension unless format is given explicitly.
+func loadOntology(path, format string) (*ontology.Ontology, error) {
+	return loadOntologyWithOptions(path, format, ontology.ParseOptions{})
+}
+
+// loadOntologyWithOptions is loadOntology with parse options, such as an
+// obsolete-term policy.
+func loadOntologyWithOptions(path, format string, opts ontology.ParseOptions) (*ontology.Ontology, error) {
+	inputFmt := detectFormat(path, format)
+	if inputFmt == "" {
+		return nil, exitcode.Errorf(exitcode.Usage, "cannot detect format for %q; use -format obo or -format owl", path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	ont, err := parseInputWithOptions(f, inputFmt, opts)
+	return ont, exitcode.Wrap(exitcode.Parse, err)
+}
+
+// checkWarnings prints the parser warnings' counts per category and fails
+// with exitcode.Validation if there are more than max.
+func checkWarnings(log *ontology.WarningLog, max int) error {
+	n := log.Count("")
+	if n == 0 {
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, "Parser warnings: %s\n", log.Summary())
+	if n > max {
+		for _, w := range log.Warnings[:min(n, 10)] {
+			fmt.Fprintf(os.Stderr, "  %s\n", w)
+		}
+		return exitcode.Errorf(exitcode.Validation, "%d parser warnings, more than the limit of %d", n, max)
+	}
+	return nil
+}
+
+// readIDs reads identifiers from path ("-" for stdin), one per line or in the
+// given 1-based TSV column. Blank lines and lines starting with # are skipped,
+// as is the first line when header is set.
+func readIDs(path string, column int, header bool) ([]string, error) {
+	if column < 1 {
+		return nil, fmt.Errorf("column must be 1 or greater")
+	}
+	var in io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	var ids []string
+	sc := bufio.NewScanner(in)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 0; sc.Scan(); line++ {
+		if line == 0 && header {
+			continue
+		}
+		fields := strings.Split(sc.Text(), "\t")
+		if len(fields) < column {
+			continue
+		}
+		id := strings.TrimSpace(fields[column-1])
+		if id == "" || strings.HasPrefix(id, "#") {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids, sc.Err()
+}
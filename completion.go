@@ -0,0 +1,307 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nodeadmin/chebi-parser/internal/exitcode"
+	"github.com/nodeadmin/chebi-parser/ontology"
+)
+
+// termFlags lists, per command ("" for the default conversion mode), the
+// flags whose value is a term ID or name, so completion can offer IDs.
+var termFlags = map[string][]string{
+	"":           {"tree-root", "split-root"},
+	"explore":    {"start"},
+	"path":       {"from", "to"},
+	"references": {"id"},
+	"sample":     {"root"},
+}
+
+// CLIFlag describes one flag in -help-json output.
+type CLIFlag struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"` // bool, int, float, duration, string or strings (repeatable)
+	Default    string `json:"default,omitempty"`
+	Usage      string `json:"usage"`
+	TermValued bool   `json:"term_valued,omitempty"` // takes a term ID or name
+}
+
+// CLICommand describes a subcommand, or the default mode when Name is
+// empty.
+type CLICommand struct {
+	Name  string    `json:"name"`
+	Flags []CLIFlag `json:"flags"`
+}
+
+// CLIHelp is the -help-json document.
+type CLIHelp struct {
+	Program  string       `json:"program"`
+	Flags    []CLIFlag    `json:"flags"` // of the default conversion mode
+	Commands []CLICommand `json:"commands"`
+}
+
+// describeCLI collects the flags of the default mode and every command.
+// Building their FlagSets resets the -errors-json value, so it is kept.
+func describeCLI() CLIHelp {
+	defer func(v string) { errorsJSON = v }(errorsJSON)
+	root, _ := rootCommand()
+	help := CLIHelp{
+		Program: "chebi-parser",
+		Flags:   cliFlags("", root),
+	}
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fs, _ := commands[name]()
+		help.Commands = append(help.Commands, CLICommand{Name: name, Flags: cliFlags(name, fs)})
+	}
+	return help
+}
+
+func cliFlags(command string, fs *flag.FlagSet) []CLIFlag {
+	flags := []CLIFlag{}
+	terms := make(map[string]bool)
+	for _, name := range termFlags[command] {
+		terms[name] = true
+	}
+	fs.VisitAll(func(f *flag.Flag) {
+		flags = append(flags, CLIFlag{
+			Name:       f.Name,
+			Type:       flagType(f),
+			Default:    f.DefValue,
+			Usage:      f.Usage,
+			TermValued: terms[f.Name],
+		})
+	})
+	return flags
+}
+
+func flagType(f *flag.Flag) string {
+	g, ok := f.Value.(flag.Getter)
+	if !ok {
+		return "string"
+	}
+	switch g.Get().(type) {
+	case bool:
+		return "bool"
+	case time.Duration:
+		return "duration"
+	case int, int64, uint, uint64:
+		return "int"
+	case float64:
+		return "float"
+	case []string:
+		return "strings"
+	}
+	return "string"
+}
+
+func writeHelpJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(describeCLI())
+}
+
+// completion reads the commands map, so it is registered here rather
+// than in the map's initializer.
+func init() {
+	commands["completion"] = completionCommand
+}
+
+// completionCommand prints a shell completion script.
+func completionCommand() (*flag.FlagSet, func() error) {
+	fs := newFlagSet("completion")
+	return fs, func() error {
+		if fs.NArg() != 1 {
+			return exitcode.Errorf(exitcode.Usage, "usage: chebi-parser completion bash|zsh|fish")
+		}
+		help := describeCLI()
+		switch fs.Arg(0) {
+		case "bash":
+			writeBashCompletion(os.Stdout, help)
+		case "zsh":
+			// zsh runs the bash script through bashcompinit.
+			fmt.Println("#compdef chebi-parser")
+			fmt.Println("autoload -U +X bashcompinit && bashcompinit")
+			writeBashCompletion(os.Stdout, help)
+		case "fish":
+			writeFishCompletion(os.Stdout, help)
+		default:
+			return fmt.Errorf("unknown shell %q: use bash, zsh or fish", fs.Arg(0))
+		}
+		return nil
+	}
+}
+
+// flagWords returns the flag names as completion words, and those taking
+// a value and those taking a term.
+func flagWords(flags []CLIFlag) (all, valued, terms []string) {
+	for _, f := range flags {
+		all = append(all, "-"+f.Name)
+		if f.Type != "bool" {
+			valued = append(valued, "-"+f.Name)
+		}
+		if f.TermValued {
+			terms = append(terms, "-"+f.Name)
+		}
+	}
+	return all, valued, terms
+}
+
+// writeBashCompletion writes a bash completion function. Term-valued
+// flags complete IDs through complete-terms, from the -input given on
+// the command line or else $CHEBI_SNAPSHOT.
+func writeBashCompletion(w io.Writer, help CLIHelp) {
+	var names []string
+	for _, c := range help.Commands {
+		names = append(names, c.Name)
+	}
+	fmt.Fprintf(w, `# bash completion for chebi-parser; generated by "chebi-parser completion bash".
+_chebi_parser() {
+    local cur prev words cword
+    # Term IDs contain ':', which bash splits words on; bash-completion's
+    # helpers undo that when they are available.
+    if declare -F _get_comp_words_by_ref >/dev/null; then
+        _get_comp_words_by_ref -n : cur prev words cword
+    else
+        words=("${COMP_WORDS[@]}") cword=$COMP_CWORD
+        cur="${words[cword]}" prev="${words[cword-1]}"
+    fi
+    local cmd="" flags valued terms input="$CHEBI_SNAPSHOT" i
+    if [[ $cword -gt 1 && ${words[1]} != -* ]]; then
+        cmd="${words[1]}"
+    fi
+    for ((i = 1; i < cword - 1; i++)); do
+        [[ ${words[i]} == -input || ${words[i]} == --input ]] && input="${words[i+1]}"
+    done
+    case "$cmd" in
+`)
+	writeCase := func(name string, flags []CLIFlag) {
+		all, valued, terms := flagWords(flags)
+		pattern := name
+		if name == "" {
+			pattern = `""`
+		}
+		fmt.Fprintf(w, "    %s) flags=%q valued=%q terms=%q ;;\n", pattern,
+			strings.Join(all, " "), " "+strings.Join(valued, " ")+" ", " "+strings.Join(terms, " ")+" ")
+	}
+	writeCase("", help.Flags)
+	for _, c := range help.Commands {
+		writeCase(c.Name, c.Flags)
+	}
+	fmt.Fprintf(w, `    esac
+    if [[ $terms == *" $prev "* ]]; then
+        [[ -n $input ]] && COMPREPLY=($(chebi-parser complete-terms -input "$input" -prefix "$cur" 2>/dev/null))
+        declare -F __ltrim_colon_completions >/dev/null && __ltrim_colon_completions "$cur"
+        return
+    fi
+    if [[ $valued == *" $prev "* ]]; then
+        COMPREPLY=($(compgen -f -- "$cur"))
+        return
+    fi
+    if [[ $cword -eq 1 && $cur != -* ]]; then
+        COMPREPLY=($(compgen -W %q -- "$cur"))
+        return
+    fi
+    COMPREPLY=($(compgen -W "$flags" -- "$cur"))
+}
+complete -o default -F _chebi_parser chebi-parser
+`, strings.Join(names, " "))
+}
+
+// writeFishCompletion writes fish completions. Go flags are single-dash
+// long options, fish's -o.
+func writeFishCompletion(w io.Writer, help CLIHelp) {
+	var names []string
+	for _, c := range help.Commands {
+		names = append(names, c.Name)
+	}
+	fmt.Fprint(w, `# fish completion for chebi-parser; generated by "chebi-parser completion fish".
+function __chebi_parser_terms
+    set -l tokens (commandline -opc)
+    set -l input $CHEBI_SNAPSHOT
+    set -l i (contains -i -- -input $tokens)
+    and set input $tokens[(math $i + 1)]
+    test -n "$input"; and chebi-parser complete-terms -input $input -prefix (commandline -ct) 2>/dev/null
+end
+complete -c chebi-parser -f
+`)
+	fmt.Fprintf(w, "complete -c chebi-parser -n __fish_use_subcommand -a %q\n", strings.Join(names, " "))
+	writeFlags := func(cond string, flags []CLIFlag) {
+		for _, f := range flags {
+			fmt.Fprintf(w, "complete -c chebi-parser -n %q -o %s", cond, f.Name)
+			switch {
+			case f.TermValued:
+				fmt.Fprint(w, " -x -a '(__chebi_parser_terms)'")
+			case f.Type != "bool":
+				fmt.Fprint(w, " -r -F")
+			}
+			fmt.Fprintf(w, " -d %q\n", firstSentence(f.Usage))
+		}
+	}
+	writeFlags("__fish_use_subcommand", help.Flags)
+	for _, c := range help.Commands {
+		writeFlags("__fish_seen_subcommand_from "+c.Name, c.Flags)
+	}
+}
+
+// firstSentence shortens a flag's usage for a completion description.
+func firstSentence(s string) string {
+	if i := strings.IndexAny(s, ";("); i > 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s)
+}
+
+// completeTermsCommand prints the term IDs starting with a prefix, one per
+// line; the completion scripts call it for term-valued flags.
+func completeTermsCommand() (*flag.FlagSet, func() error) {
+	fs := newFlagSet("complete-terms")
+	input := fs.String("input", os.Getenv("CHEBI_SNAPSHOT"), "Ontology file or snapshot (default: $CHEBI_SNAPSHOT)")
+	format := fs.String("format", "auto", "Input format: auto, obo, owl, msgpack")
+	prefix := fs.String("prefix", "", "Complete IDs starting with this, ignoring case")
+	limit := fs.Int("limit", 200, "Maximum IDs to print")
+	return fs, func() error {
+		if *input == "" {
+			return exitcode.Errorf(exitcode.Usage, "usage: chebi-parser complete-terms -input <file> [-prefix CHEBI:153] [-limit N]")
+		}
+		ont, err := loadOntology(*input, *format)
+		if err != nil {
+			return err
+		}
+		ids := matchingIDs(ont, *prefix, *limit)
+		for _, id := range ids {
+			fmt.Println(id)
+		}
+		return nil
+	}
+}
+
+// matchingIDs returns up to limit sorted IDs of live terms starting with
+// prefix.
+func matchingIDs(ont *ontology.Ontology, prefix string, limit int) []string {
+	prefix = strings.ToUpper(prefix)
+	var ids []string
+	for i := range ont.Terms {
+		t := &ont.Terms[i]
+		if !t.IsObsolete && strings.HasPrefix(strings.ToUpper(t.ID), prefix) {
+			ids = append(ids, t.ID)
+		}
+	}
+	sort.Strings(ids)
+	if limit > 0 && len(ids) > limit {
+		ids = ids[:limit]
+	}
+	return ids
+}
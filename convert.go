@@ -0,0 +1,213 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nodeadmin/chebi-parser/internal/exitcode"
+	"github.com/nodeadmin/chebi-parser/ontology"
+)
+
+// ontologyWriters are the serializations convert can produce.
+var ontologyWriters = map[string]func(*ontology.Ontology, io.Writer) error{
+	"obo":       ontology.WriteOBO,
+	"owl":       ontology.WriteOWL,
+	"ttl":       ontology.WriteTurtle,
+	"skos":      ontology.WriteSKOS,
+	"obographs": ontology.WriteOBOGraphs,
+	"json":      ontology.WriteJSON,
+	"jsonld":    ontology.WriteJSONLD,
+	"msgpack":   ontology.WriteMsgpack,
+	"protobuf":  ontology.WriteProtoStream,
+	"avro":      ontology.WriteAvro,
+}
+
+// formatExtensions are the file extensions written for each output format.
+var formatExtensions = map[string]string{
+	"obo":       ".obo",
+	"owl":       ".owl",
+	"ttl":       ".ttl",
+	"skos":      ".skos.ttl",
+	"obographs": ".obographs.json",
+	"json":      ".json",
+	"jsonld":    ".jsonld",
+	"msgpack":   ".msgpack",
+	"protobuf":  ".pb",
+	"avro":      ".avro",
+}
+
+// ontologyWriter returns the writer for format under an obsolete-term
+// policy. Only SKOS output changes with it; the other writers write what
+// was parsed, which ParseOptions.Obsolete already filtered.
+func ontologyWriter(format string, obsolete ontology.ObsoletePolicy) (func(*ontology.Ontology, io.Writer) error, bool) {
+	if format == "skos" {
+		return func(ont *ontology.Ontology, w io.Writer) error {
+			return ontology.WriteSKOSWithOptions(ont, w, ontology.SKOSOptions{Obsolete: obsolete})
+		}, true
+	}
+	write, ok := ontologyWriters[format]
+	return write, ok
+}
+
+func sortedWriterNames() []string {
+	names := make([]string, 0, len(ontologyWriters))
+	for n := range ontologyWriters {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// convertCommand converts an ontology between any pair of supported formats,
+// covering the OBO/OWL/OBO Graphs conversions of ROBOT convert without a JVM.
+func convertCommand() (*flag.FlagSet, func() error) {
+	fs := newFlagSet("convert")
+	input := fs.String("input", "", "Ontology file")
+	from := fs.String("from", "auto", "Input format: auto, obo, owl, json, obographs, msgpack")
+	to := fs.String("to", "", "Output format: obo, owl, ttl, skos, obographs, json, jsonld, msgpack, protobuf, avro (default: from the -output extension)")
+	output := fs.String("output", "", "Output file (default: stdout)")
+	canonical := fs.Bool("canonical", false, "Sort terms and their fields canonically before writing")
+	inverses := fs.Bool("inverses", false, "Also write the inverse of every relationship whose Typedef declares inverse_of")
+	orient := fs.String("orient", "", orientUsage+" (before -inverses)")
+	obsoleteFlag := fs.String("obsolete", "", obsoleteUsage)
+	charsetFlag := fs.String("charset", "report", charsetUsage)
+	synonymsFlag := fs.String("synonyms", "keep", synonymsUsage)
+	stream := fs.Bool("stream", false, "Copy OBO to OBO stanza by stanza in bounded memory, for files larger than RAM (not with -canonical, -inverses or -orient)")
+	return fs, func() error {
+		if *input == "" {
+			return exitcode.Errorf(exitcode.Usage, "usage: chebi-parser convert -input <file> [-from auto|obo|owl|json|obographs|msgpack] [-to obo|owl|ttl|skos|obographs|json|jsonld|...] [-output file] [-orient part_of] [-inverses] [-obsolete include|exclude] [-charset report|transcode] [-synonyms keep|collapse|fold] [-stream]")
+		}
+		obsolete, err := ontology.ParseObsoletePolicy(*obsoleteFlag)
+		if err != nil {
+			return exitcode.Errorf(exitcode.Usage, "-obsolete: %v", err)
+		}
+		charset, err := ontology.ParseCharsetPolicy(*charsetFlag)
+		if err != nil {
+			return exitcode.Errorf(exitcode.Usage, "-charset: %v", err)
+		}
+		synonyms, err := ontology.ParseSynonymPolicy(*synonymsFlag)
+		if err != nil {
+			return exitcode.Errorf(exitcode.Usage, "-synonyms: %v", err)
+		}
+		outFmt := *to
+		if outFmt == "" {
+			outFmt = outputFormat(*output)
+		}
+		write, ok := ontologyWriter(outFmt, obsolete)
+		if !ok {
+			if outFmt == "" {
+				return fmt.Errorf("cannot detect output format for %q; use -to", *output)
+			}
+			return fmt.Errorf("unknown output format %q", outFmt)
+		}
+
+		opts := ontology.ParseOptions{Obsolete: obsolete, Charset: charset, Synonyms: synonyms}
+		if *stream && (outFmt != "obo" || detectFormat(*input, *from) != "obo" || *canonical || *inverses || *orient != "") {
+			return exitcode.Errorf(exitcode.Usage, "-stream converts OBO to OBO only, without -canonical, -inverses or -orient")
+		}
+
+		start := time.Now()
+		var ont *ontology.Ontology
+		if !*stream {
+			if ont, err = loadOntologyWithOptions(*input, *from, opts); err != nil {
+				return err
+			}
+		}
+		if *orient != "" {
+			if err := orientRelationships(ont, *orient); err != nil {
+				return err
+			}
+		}
+		if *inverses {
+			fmt.Fprintf(os.Stderr, "Inverses: added %d edges\n", ontology.AddInverses(ont))
+		}
+		if *canonical {
+			ontology.Sort(ont)
+		}
+
+		out := os.Stdout
+		if *output != "" {
+			if out, err = os.Create(*output); err != nil {
+				return err
+			}
+			defer out.Close()
+		}
+		if *stream {
+			n, err := streamOBO(*input, out, opts)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Streamed %d terms to obo in %v\n", n, time.Since(start))
+			return nil
+		}
+		if err := write(ont, out); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Converted %d terms to %s in %v\n", len(ont.Terms), outFmt, time.Since(start))
+		return nil
+	}
+}
+
+// streamOBO copies the OBO file at path to w through ParseOBOStream and an
+// OBOWriter, applying opts as it goes, and returns the number of terms
+// written. Stanzas keep their order in the file.
+func streamOBO(path string, w io.Writer, opts ontology.ParseOptions) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	var ow *ontology.OBOWriter
+	n := 0
+	err = ontology.ParseOBOStream(f, opts, ontology.OBOHandler{
+		Header: func(head *ontology.Ontology) error {
+			ow = ontology.NewOBOWriter(w, head)
+			return nil
+		},
+		Term: func(t *ontology.Term) error {
+			n++
+			return ow.WriteTerm(t)
+		},
+		TypeDef:  func(td *ontology.TypeDef) error { return ow.WriteTypeDef(td) },
+		Instance: func(ind *ontology.Individual) error { return ow.WriteIndividual(ind) },
+	})
+	if err != nil {
+		return n, err
+	}
+	return n, ow.Flush()
+}
+
+// outputFormat maps an output file extension to a convert format name.
+func outputFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".obo":
+		return "obo"
+	case ".owl", ".rdf", ".xml":
+		return "owl"
+	case ".ttl":
+		if strings.HasSuffix(strings.ToLower(path), ".skos.ttl") {
+			return "skos"
+		}
+		return "ttl"
+	case ".json":
+		if strings.HasSuffix(strings.ToLower(path), ".obographs.json") {
+			return "obographs"
+		}
+		return "json"
+	case ".jsonld":
+		return "jsonld"
+	case ".msgpack", ".mpk":
+		return "msgpack"
+	case ".pb":
+		return "protobuf"
+	case ".avro":
+		return "avro"
+	}
+	return ""
+}
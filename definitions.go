@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nodeadmin/chebi-parser/internal/exitcode"
+	"github.com/nodeadmin/chebi-parser/ontology"
+)
+
+// definitionsCommand writes the catalogue of defined classes (intersection_of
+// cross-products) with their genus and differentiae as TSV or JSON.
+func definitionsCommand() (*flag.FlagSet, func() error) {
+	fs := newFlagSet("definitions")
+	input := fs.String("input", "", "Ontology file (.obo, .owl or .msgpack)")
+	format := fs.String("format", "auto", "Input format: auto, obo, owl, msgpack")
+	asJSON := fs.Bool("json", false, "Write JSON instead of TSV")
+	issuesOnly := fs.Bool("issues", false, "Only list definitions with issues")
+	output := fs.String("output", "", "Report file (default: stdout)")
+	return fs, func() error {
+		if *input == "" {
+			return exitcode.Errorf(exitcode.Usage, "usage: chebi-parser definitions -input <file> [-json] [-issues] [-output definitions.tsv]")
+		}
+		ont, err := loadOntology(*input, *format)
+		if err != nil {
+			return err
+		}
+		defs := ontology.NewIndex(ont).Definitions()
+		total, withIssues := len(defs), 0
+		for _, d := range defs {
+			if len(d.Issues) > 0 {
+				withIssues++
+			}
+		}
+		if *issuesOnly {
+			kept := defs[:0]
+			for _, d := range defs {
+				if len(d.Issues) > 0 {
+					kept = append(kept, d)
+				}
+			}
+			defs = kept
+		}
+
+		out := os.Stdout
+		if *output != "" {
+			f, err := os.Create(*output)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			out = f
+		}
+		bw := bufio.NewWriter(out)
+		if *asJSON {
+			enc := json.NewEncoder(bw)
+			enc.SetEscapeHTML(false)
+			enc.SetIndent("", "  ")
+			if defs == nil {
+				defs = []ontology.Definition{}
+			}
+			if err := enc.Encode(defs); err != nil {
+				return err
+			}
+		} else {
+			fmt.Fprintln(bw, "id\tname\tgenus\tgenus_names\tdifferentia\tdifferentia_names\tdefinition\tissues")
+			for _, d := range defs {
+				var genus, genusNames, diff, diffNames []string
+				for _, g := range d.Genus {
+					genus = append(genus, g.ID)
+					genusNames = append(genusNames, g.Name)
+				}
+				for _, df := range d.Differentia {
+					target, targetName := df.Target, df.TargetName
+					if df.Self {
+						target, targetName = "Self", "Self"
+					}
+					diff = append(diff, df.Relation+" "+target)
+					diffNames = append(diffNames, orID(df.RelationName, df.Relation)+" "+orID(targetName, target))
+				}
+				fmt.Fprintf(bw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", d.ID, d.Name,
+					strings.Join(genus, "; "), strings.Join(genusNames, "; "),
+					strings.Join(diff, "; "), strings.Join(diffNames, "; "),
+					d.Text, strings.Join(d.Issues, "; "))
+			}
+		}
+		if err := bw.Flush(); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(os.Stderr, "%d defined classes, %d with issues\n", total, withIssues)
+		return nil
+	}
+}
+
+// orID returns name, or id when the name is empty.
+func orID(name, id string) string {
+	if name == "" {
+		return id
+	}
+	return name
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/nodeadmin/chebi-parser/internal/exitcode"
+	"github.com/nodeadmin/chebi-parser/ontology"
+	"github.com/nodeadmin/chebi-parser/reasoner"
+)
+
+// diffClassifiedCommand compares two classifications and prints the
+// subsumptions each class gained and lost, indirect ones included. Each
+// side is classify JSON output or an ontology, which is classified first.
+func diffClassifiedCommand() (*flag.FlagSet, func() error) {
+	fs := newFlagSet("diff-classified")
+	oldPath := fs.String("old", "", "Old classify JSON output or ontology file")
+	newPath := fs.String("new", "", "New classify JSON output or ontology file")
+	format := fs.String("format", "auto", "Format of ontology inputs: auto, obo, owl, json, msgpack")
+	workers := fs.Int("workers", 0, "Saturation workers for ontology inputs (default: number of CPUs)")
+	asJSON := fs.Bool("json", false, "Write the diff as JSON")
+	output := fs.String("output", "", "Output file (default: stdout)")
+	return fs, func() error {
+		if *oldPath == "" || *newPath == "" {
+			return exitcode.Errorf(exitcode.Usage, "usage: chebi-parser diff-classified -old <file> -new <file> [-format auto] [-workers N] [-json] [-output file]")
+		}
+		oldH, oldOnt, err := loadHierarchy(*oldPath, *format, *workers)
+		if err != nil {
+			return fmt.Errorf("%s: %w", *oldPath, err)
+		}
+		newH, newOnt, err := loadHierarchy(*newPath, *format, *workers)
+		if err != nil {
+			return fmt.Errorf("%s: %w", *newPath, err)
+		}
+		d := reasoner.DiffHierarchies(oldH, newH)
+
+		// Label with the new ontology's names, falling back to the old one's.
+		var ixs []*ontology.Index
+		for _, ont := range []*ontology.Ontology{newOnt, oldOnt} {
+			if ont != nil {
+				ixs = append(ixs, ontology.NewIndex(ont))
+			}
+		}
+		name := func(id string) string {
+			for _, ix := range ixs {
+				if t := ix.Term(id); t != nil && t.Name != "" {
+					return t.Name
+				}
+			}
+			return ""
+		}
+		for i := range d.Terms {
+			d.Terms[i].Name = name(d.Terms[i].ID)
+		}
+
+		var out io.Writer = os.Stdout
+		if *output != "" {
+			f, err := os.Create(*output)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			out = f
+		}
+		fmt.Fprintf(os.Stderr, "%d terms changed: %d subsumptions gained, %d lost; %d terms added, %d removed\n",
+			len(d.Terms), d.Gained, d.Lost, len(d.AddedTerms), len(d.RemovedTerms))
+		if *asJSON {
+			enc := json.NewEncoder(out)
+			enc.SetEscapeHTML(false)
+			enc.SetIndent("", "  ")
+			return enc.Encode(d)
+		}
+		bw := bufio.NewWriter(out)
+		label := func(id string) string {
+			if n := name(id); n != "" {
+				return id + " " + n
+			}
+			return id
+		}
+		for _, c := range d.Terms {
+			fmt.Fprintln(bw, label(c.ID))
+			for _, s := range c.Gained {
+				fmt.Fprintf(bw, "  + %s\n", label(s))
+			}
+			for _, s := range c.Lost {
+				fmt.Fprintf(bw, "  - %s\n", label(s))
+			}
+		}
+		for _, id := range d.AddedTerms {
+			fmt.Fprintf(bw, "added %s\n", label(id))
+		}
+		for _, id := range d.RemovedTerms {
+			fmt.Fprintf(bw, "removed %s\n", label(id))
+		}
+		return bw.Flush()
+	}
+}
+
+// loadHierarchy reads classify JSON output, told apart from ontology JSON
+// by its leading "concepts" key, or classifies an ontology file, which it
+// also returns for labels.
+func loadHierarchy(path, format string, workers int) (*reasoner.ClassifiedHierarchy, *ontology.Ontology, error) {
+	if format == "auto" && strings.HasSuffix(strings.ToLower(path), ".json") {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer f.Close()
+		br := bufio.NewReader(f)
+		head, _ := br.Peek(512)
+		if key, _, ok := strings.Cut(strings.TrimLeft(string(head), "{ \t\r\n"), ":"); ok && strings.TrimSpace(key) == `"concepts"` {
+			h, err := reasoner.ReadClassifiedJSON(br)
+			return h, nil, exitcode.Wrap(exitcode.Parse, err)
+		}
+	}
+	ont, err := loadOntology(path, format)
+	if err != nil {
+		return nil, nil, err
+	}
+	return reasoner.Classify(ont, reasoner.NormalizeOptions{}, workers), ont, nil
+}
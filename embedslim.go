@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/nodeadmin/chebi-parser/internal/exitcode"
+	"github.com/nodeadmin/chebi-parser/ontology"
+	"github.com/nodeadmin/chebi-parser/slim"
+)
+
+// embedSlimCommand writes the compact snapshot that package slim embeds with
+// the chebislim build tag.
+func embedSlimCommand() (*flag.FlagSet, func() error) {
+	fs := newFlagSet("embed-slim")
+	input := fs.String("input", "", "Ontology file (.obo, .owl or .msgpack)")
+	format := fs.String("format", "auto", "Input format: auto, obo, owl, msgpack")
+	subsets := fs.String("subset", "", "Comma-separated subsets (e.g. 3_STAR) whose terms to keep (default: all live terms)")
+	roots := fs.String("root", "", "Comma-separated terms (IDs or names) to keep with their is_a descendants only")
+	props := fs.String("properties", "", "Comma-separated property keys to keep, e.g. the ChEBI formula IRI")
+	output := fs.String("output", "slim/chebi_slim.msgpack.gz", "Snapshot file")
+	return fs, func() error {
+		if *input == "" {
+			return exitcode.Errorf(exitcode.Usage, "usage: chebi-parser embed-slim -input <file> [-subset 3_STAR] [-root TERMS] [-properties KEYS] [-output slim/chebi_slim.msgpack.gz]")
+		}
+		ont, err := loadOntology(*input, *format)
+		if err != nil {
+			return err
+		}
+		var opts slim.Options
+		if *subsets != "" {
+			opts.Subsets = strings.Split(*subsets, ",")
+		}
+		if *props != "" {
+			opts.Properties = strings.Split(*props, ",")
+		}
+		if *roots != "" {
+			if opts.Roots, err = ontology.NewIndex(ont).LookupAll(strings.Split(*roots, ",")); err != nil {
+				return exitcode.Wrap(exitcode.Usage, fmt.Errorf("-root: %w", err))
+			}
+		}
+		snap := slim.Build(ont, opts)
+		if len(snap.Terms) == 0 {
+			return exitcode.Errorf(exitcode.Validation, "no terms selected for the snapshot")
+		}
+		if err := writeFileWith(*output, func(w io.Writer) error { return slim.Write(snap, w) }); err != nil {
+			return exitcode.Wrap(exitcode.IO, err)
+		}
+		fi, err := os.Stat(*output)
+		if err != nil {
+			return exitcode.Wrap(exitcode.IO, err)
+		}
+		fmt.Fprintf(os.Stderr, "Wrote %d of %d terms to %s (%d bytes)\n", len(snap.Terms), len(ont.Terms), *output, fi.Size())
+		return nil
+	}
+}
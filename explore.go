@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/nodeadmin/chebi-parser/internal/exitcode"
+	"github.com/nodeadmin/chebi-parser/ontology"
+)
+
+// exploreCommand browses an ontology interactively from the terminal: each
+// line read from stdin is a command that moves around the is_a hierarchy
+// or searches, and numbered listings let the next command jump by number.
+func exploreCommand() (*flag.FlagSet, func() error) {
+	fs := newFlagSet("explore")
+	input := fs.String("input", "", "Ontology file (.obo, .owl or .msgpack)")
+	format := fs.String("format", "auto", "Input format: auto, obo, owl, msgpack")
+	start := fs.String("start", "", "Term ID or name to start at (default: list the roots)")
+	labels := fs.String("label-prefs", "", labelPrefsUsage)
+	return fs, func() error {
+		if *input == "" {
+			return exitcode.Errorf(exitcode.Usage, "usage: chebi-parser explore -input <file> [-start TERM] [-label-prefs INN,name]")
+		}
+		prefs, err := labelPrefs(*labels)
+		if err != nil {
+			return err
+		}
+		ont, err := loadOntology(*input, *format)
+		if err != nil {
+			return err
+		}
+		ix := ontology.NewIndexWithOptions(ont, ontology.IndexOptions{Labels: prefs})
+		e := &explorer{ix: ix, out: bufio.NewWriter(os.Stdout)}
+		defer e.out.Flush()
+		fmt.Fprintf(e.out, "%d terms loaded. Type help for commands.\n", len(ont.Terms))
+		if *start != "" {
+			e.exec("go " + *start)
+		} else {
+			e.exec("roots")
+		}
+		return e.loop(os.Stdin)
+	}
+}
+
+// explorer is the state of an explore session.
+type explorer struct {
+	ix      *ontology.Index
+	out     *bufio.Writer
+	cur     string   // current term, "" before the first jump
+	history []string // terms visited before cur, for back
+	listing []string // IDs of the last numbered list
+}
+
+const exploreHelp = `Commands:
+  N                 go to entry N of the last list
+  go TERM           go to a term by ID or name
+  / TEXT            search labels and synonyms (also: search TEXT)
+  parents, up       list the parents of the current term
+  children, down    list its children
+  siblings          list its siblings
+  tree [DEPTH]      show the is_a subtree below it (default depth 2)
+  path TERM         show the shortest path from it to TERM
+  show              show the current term again
+  roots             list the terms without parents in the ontology
+  back              return to the previous term
+  help              show this help
+  quit              leave
+`
+
+func (e *explorer) loop(in io.Reader) error {
+	sc := bufio.NewScanner(in)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for {
+		e.prompt()
+		e.out.Flush()
+		if !sc.Scan() {
+			fmt.Fprintln(e.out)
+			return sc.Err()
+		}
+		if !e.exec(sc.Text()) {
+			return nil
+		}
+	}
+}
+
+func (e *explorer) prompt() {
+	if e.cur == "" {
+		fmt.Fprint(e.out, "> ")
+		return
+	}
+	fmt.Fprintf(e.out, "%s> ", e.cur)
+}
+
+// exec runs one command line and reports whether the session goes on.
+func (e *explorer) exec(line string) bool {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return true
+	}
+	cmd, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+	if strings.HasPrefix(line, "/") {
+		cmd, arg = "search", strings.TrimSpace(line[1:])
+	}
+	if n, err := strconv.Atoi(cmd); err == nil {
+		if n < 1 || n > len(e.listing) {
+			fmt.Fprintf(e.out, "No entry %d; the last list has %d.\n", n, len(e.listing))
+			return true
+		}
+		e.visit(e.listing[n-1])
+		return true
+	}
+
+	switch cmd {
+	case "quit", "exit", "q":
+		return false
+	case "help", "?":
+		fmt.Fprint(e.out, exploreHelp)
+	case "go", "cd":
+		id, err := e.ix.Lookup(arg)
+		if err != nil {
+			fmt.Fprintln(e.out, err)
+			return true
+		}
+		e.visit(id)
+	case "search", "find":
+		matches := e.ix.Resolve(arg, ontology.ResolveOptions{Limit: 20})
+		if len(matches) == 0 {
+			fmt.Fprintf(e.out, "No match for %q.\n", arg)
+			return true
+		}
+		ids := make([]string, len(matches))
+		for i, m := range matches {
+			ids[i] = m.ID
+		}
+		e.list(ids)
+	case "roots":
+		e.list(e.roots())
+	case "back":
+		if len(e.history) == 0 {
+			fmt.Fprintln(e.out, "Nothing to go back to.")
+			return true
+		}
+		e.cur = e.history[len(e.history)-1]
+		e.history = e.history[:len(e.history)-1]
+		e.show()
+	default:
+		if e.cur == "" {
+			fmt.Fprintf(e.out, "Unknown command %q, or no current term yet. Type help for commands.\n", cmd)
+			return true
+		}
+		e.termCommand(cmd, arg)
+	}
+	return true
+}
+
+// termCommand runs the commands that act on the current term.
+func (e *explorer) termCommand(cmd, arg string) {
+	switch cmd {
+	case "show", ".":
+		e.show()
+	case "parents", "up":
+		e.list(e.ix.Parents(e.cur))
+	case "children", "down":
+		e.list(e.ix.Children(e.cur))
+	case "siblings":
+		e.list(e.ix.Siblings(e.cur))
+	case "tree":
+		depth := 2
+		if arg != "" {
+			d, err := strconv.Atoi(arg)
+			if err != nil || d < 1 {
+				fmt.Fprintln(e.out, "tree depth must be a positive number")
+				return
+			}
+			depth = d
+		}
+		e.listing = e.listing[:0]
+		e.tree(e.ix.Tree(e.cur, depth), 0)
+	case "path":
+		to, err := e.ix.Lookup(arg)
+		if err != nil {
+			fmt.Fprintln(e.out, err)
+			return
+		}
+		path := e.ix.Path(e.cur, to, nil)
+		if path == nil {
+			fmt.Fprintf(e.out, "No path from %s to %s.\n", e.cur, to)
+			return
+		}
+		fmt.Fprintln(e.out, e.ix.FormatPath(path))
+	default:
+		fmt.Fprintf(e.out, "Unknown command %q. Type help for commands.\n", cmd)
+	}
+}
+
+// visit makes id the current term and shows it.
+func (e *explorer) visit(id string) {
+	if e.cur != "" && e.cur != id {
+		e.history = append(e.history, e.cur)
+	}
+	e.cur = id
+	e.show()
+}
+
+// show prints the detail pane of the current term; its parents and
+// children are numbered as one list.
+func (e *explorer) show() {
+	t := e.ix.Term(e.cur)
+	if t == nil {
+		fmt.Fprintf(e.out, "%s is not a term here.\n", e.cur)
+		return
+	}
+	fmt.Fprintf(e.out, "\n%s\n", e.label(t.ID))
+	if t.IsObsolete {
+		fmt.Fprintln(e.out, "  OBSOLETE")
+	}
+	if t.Namespace != "" {
+		fmt.Fprintf(e.out, "  namespace: %s\n", t.Namespace)
+	}
+	if t.Definition != "" {
+		fmt.Fprintf(e.out, "  def: %s\n", t.Definition)
+	}
+	for _, s := range t.Synonyms {
+		fmt.Fprintf(e.out, "  synonym: %s (%s)\n", s.Text, s.Scope)
+	}
+	if len(t.Xrefs) > 0 {
+		fmt.Fprintf(e.out, "  xrefs: %d\n", len(t.Xrefs))
+	}
+	for _, rel := range t.Relationships {
+		if rel.Type != "is_a" {
+			fmt.Fprintf(e.out, "  %s %s\n", rel.Type, e.label(rel.TargetID))
+		}
+	}
+
+	e.listing = e.listing[:0]
+	parents := e.ix.Parents(e.cur)
+	children := e.ix.Children(e.cur)
+	if len(parents) > 0 {
+		fmt.Fprintln(e.out, "  parents:")
+		e.entries(parents, "    ")
+	}
+	if len(children) > 0 {
+		fmt.Fprintln(e.out, "  children:")
+		e.entries(children, "    ")
+	}
+}
+
+// list prints ids as a new numbered list.
+func (e *explorer) list(ids []string) {
+	if len(ids) == 0 {
+		fmt.Fprintln(e.out, "(none)")
+		return
+	}
+	e.listing = e.listing[:0]
+	e.entries(ids, "  ")
+}
+
+// entries appends ids to the numbered list, printing each.
+func (e *explorer) entries(ids []string, indent string) {
+	for _, id := range ids {
+		e.listing = append(e.listing, id)
+		fmt.Fprintf(e.out, "%s%3d  %s\n", indent, len(e.listing), e.label(id))
+	}
+}
+
+func (e *explorer) tree(n *ontology.TreeNode, depth int) {
+	if n == nil {
+		return
+	}
+	e.listing = append(e.listing, n.ID)
+	suffix := ""
+	if n.Truncated {
+		suffix = " …"
+	}
+	if n.Cycle {
+		suffix = " (cycle)"
+	}
+	fmt.Fprintf(e.out, "%3d  %s%s%s\n", len(e.listing), strings.Repeat("  ", depth), e.label(n.ID), suffix)
+	for _, c := range n.Children {
+		e.tree(c, depth+1)
+	}
+}
+
+func (e *explorer) label(id string) string {
+	if label := e.ix.Label(id); label != "" {
+		return id + "  " + label
+	}
+	return id
+}
+
+// roots returns the live terms with no parent among the ontology's
+// terms.
+func (e *explorer) roots() []string {
+	var ids []string
+	terms := e.ix.Ontology().Terms
+	for i := range terms {
+		t := &terms[i]
+		if t.IsObsolete {
+			continue
+		}
+		root := true
+		for _, p := range e.ix.Parents(t.ID) {
+			if e.ix.Term(p) != nil {
+				root = false
+				break
+			}
+		}
+		if root {
+			ids = append(ids, t.ID)
+		}
+	}
+	return ids
+}
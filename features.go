@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nodeadmin/chebi-parser/internal/exitcode"
+	"github.com/nodeadmin/chebi-parser/ontology"
+)
+
+// featuresCommand exports a sparse binary terms × is_a ancestors matrix for
+// class prediction models, as libsvm text or a scipy-compatible .npz.
+func featuresCommand() (*flag.FlagSet, func() error) {
+	fs := newFlagSet("features")
+	input := fs.String("input", "", "Ontology file (.obo, .owl or .msgpack)")
+	format := fs.String("format", "auto", "Input format: auto, obo, owl, msgpack")
+	outFmt := fs.String("fmt", "", "Matrix format: libsvm, npz (default: from -output extension, else libsvm)")
+	rowsFile := fs.String("rows", "", "File of term IDs, one per line, to use as rows (default: all non-obsolete terms)")
+	featFile := fs.String("features", "", "File of ancestor IDs, one per line, to use as columns (default: every ancestor)")
+	subset := fs.String("subset", "", "Use the terms in this subset as columns")
+	minSupport := fs.Int("min-support", 1, "Drop columns set in fewer rows")
+	self := fs.Bool("include-self", false, "Count each term as one of its own features")
+	output := fs.String("output", "", "Output file (default: stdout)")
+	return fs, func() error {
+		if *input == "" || (*featFile != "" && *subset != "") {
+			return exitcode.Errorf(exitcode.Usage, "usage: chebi-parser features -input <file> [-fmt libsvm|npz] [-rows file] [-features file | -subset NAME] [-min-support 1] [-include-self] [-output file]")
+		}
+		if *outFmt == "" {
+			*outFmt = "libsvm"
+			if strings.HasSuffix(*output, ".npz") {
+				*outFmt = "npz"
+			}
+		}
+		if *outFmt != "libsvm" && *outFmt != "npz" {
+			return fmt.Errorf("unknown matrix format %q (use libsvm or npz)", *outFmt)
+		}
+		if *outFmt == "npz" && *output == "" {
+			return fmt.Errorf("npz output needs -output")
+		}
+
+		ont, err := loadOntology(*input, *format)
+		if err != nil {
+			return err
+		}
+		ix := ontology.NewIndex(ont)
+
+		var rows []string
+		if *rowsFile != "" {
+			ids, err := readIDs(*rowsFile, 1, false)
+			if err != nil {
+				return err
+			}
+			for _, id := range ids {
+				if p := ix.Primary(id); p != "" {
+					rows = append(rows, p)
+				} else {
+					fmt.Fprintf(os.Stderr, "Skipping unknown term %s\n", id)
+				}
+			}
+		} else {
+			for i := range ont.Terms {
+				if !ont.Terms[i].IsObsolete {
+					rows = append(rows, ont.Terms[i].ID)
+				}
+			}
+		}
+
+		opts := ontology.FeatureOptions{MinSupport: *minSupport, IncludeSelf: *self}
+		switch {
+		case *featFile != "":
+			if opts.Features, err = readIDs(*featFile, 1, false); err != nil {
+				return err
+			}
+		case *subset != "":
+			opts.Features = []string{}
+			for i := range ont.Terms {
+				for _, s := range ont.Terms[i].Subsets {
+					if s == *subset {
+						opts.Features = append(opts.Features, ont.Terms[i].ID)
+						break
+					}
+				}
+			}
+			if len(opts.Features) == 0 {
+				return fmt.Errorf("no terms in subset %q", *subset)
+			}
+		}
+		m := ix.AncestorFeatures(rows, opts)
+
+		out := os.Stdout
+		if *output != "" {
+			f, err := os.Create(*output)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			out = f
+		}
+		if *outFmt == "npz" {
+			err = m.WriteNPZ(out)
+		} else {
+			err = m.WriteLibSVM(out)
+			// libsvm has no room for IDs, so name rows and columns alongside.
+			if err == nil && *output != "" {
+				if err = writeIDList(*output+".rows", m.Rows); err == nil {
+					err = writeIDList(*output+".features", m.Features)
+				}
+			}
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Wrote %d × %d matrix with %d nonzeros\n", len(m.Rows), len(m.Features), len(m.Indices))
+		return nil
+	}
+}
+
+func writeIDList(path string, ids []string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	bw := bufio.NewWriter(f)
+	for _, id := range ids {
+		fmt.Fprintln(bw, id)
+	}
+	return bw.Flush()
+}
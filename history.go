@@ -0,0 +1,240 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nodeadmin/chebi-parser/internal/exitcode"
+	"github.com/nodeadmin/chebi-parser/ontology"
+)
+
+// historyCommand builds a per-term change log (first seen, renames,
+// re-parenting, obsoletion, merges) from archived releases, or queries
+// one built before.
+func historyCommand() (*flag.FlagSet, func() error) {
+	fs := newFlagSet("history")
+	releases := fs.String("releases", "", "Archived releases: a directory, a glob, or a path or URL with a {version} placeholder (.gz files are decompressed)")
+	versions := fs.String("versions", "", "Comma-separated versions or ranges (200-246) to fill {version} with, oldest first")
+	format := fs.String("format", "auto", "Release format: auto, obo, owl, msgpack")
+	from := fs.String("from", "", "Query a history JSON file written before instead of reading releases")
+	output := fs.String("output", "", "Write the history as JSON to this file")
+	sqlOut := fs.String("sql", "", "Write the history as SQL to this file, for sqlite3 history.db < file")
+	term := fs.String("term", "", "Print the history of this term ID as JSON")
+	return fs, func() error {
+		if (*releases == "") == (*from == "") || (*from == "" && *output == "" && *sqlOut == "" && *term == "") {
+			return exitcode.Errorf(exitcode.Usage, "usage: chebi-parser history (-releases DIR|GLOB|PATTERN [-versions LIST] | -from history.json) [-output history.json] [-sql history.sql] [-term ID]")
+		}
+
+		var log *ontology.HistoryLog
+		if *from != "" {
+			data, err := os.ReadFile(*from)
+			if err != nil {
+				return exitcode.Wrap(exitcode.IO, err)
+			}
+			log = new(ontology.HistoryLog)
+			if err := json.Unmarshal(data, log); err != nil {
+				return exitcode.Errorf(exitcode.Parse, "%s: %v", *from, err)
+			}
+		} else {
+			sources, labels, err := expandReleases(*releases, *versions)
+			if err != nil {
+				return err
+			}
+			b := ontology.NewHistoryBuilder()
+			seen := make(map[string]string, len(sources))
+			for i, src := range sources {
+				ont, err := loadArchived(src, *format)
+				if err != nil {
+					return err
+				}
+				version := ont.DataVersion
+				if version == "" {
+					version = labels[i]
+				}
+				if prev, dup := seen[version]; dup {
+					return exitcode.Errorf(exitcode.Usage, "%s and %s are both version %q", prev, src, version)
+				}
+				seen[version] = src
+				b.Add(version, ont)
+				fmt.Fprintf(os.Stderr, "Read %s as version %q: %d terms\n", src, version, len(ont.Terms))
+			}
+			log = b.Log()
+		}
+
+		if *output != "" {
+			err := writeFileWith(*output, func(w io.Writer) error {
+				enc := json.NewEncoder(w)
+				enc.SetEscapeHTML(false)
+				return enc.Encode(log)
+			})
+			if err != nil {
+				return exitcode.Wrap(exitcode.IO, err)
+			}
+		}
+		if *sqlOut != "" {
+			err := writeFileWith(*sqlOut, func(w io.Writer) error { return ontology.WriteHistorySQL(log, w) })
+			if err != nil {
+				return exitcode.Wrap(exitcode.IO, err)
+			}
+		}
+		if *term != "" {
+			h := log.Term(*term)
+			if h == nil {
+				return exitcode.Errorf(exitcode.Validation, "%s is not in any of the %d releases", *term, len(log.Versions))
+			}
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetEscapeHTML(false)
+			enc.SetIndent("", "  ")
+			return enc.Encode(h)
+		}
+		fmt.Fprintf(os.Stderr, "History of %d terms over %d releases\n", len(log.Terms), len(log.Versions))
+		return nil
+	}
+}
+
+// expandReleases lists the release files or URLs of -releases, oldest
+// first, each with a fallback version label: the {version} value, or the
+// file name without extensions. A directory or glob is ordered by name, comparing runs of
+// digits as numbers so rel99 comes before rel100.
+func expandReleases(pattern, versions string) (sources, labels []string, err error) {
+	if strings.Contains(pattern, "{version}") {
+		if versions == "" {
+			return nil, nil, exitcode.Errorf(exitcode.Usage, "-releases has a {version} placeholder; list them with -versions")
+		}
+		vs, err := parseVersionList(versions)
+		if err != nil {
+			return nil, nil, exitcode.Wrap(exitcode.Usage, err)
+		}
+		for _, v := range vs {
+			sources = append(sources, strings.ReplaceAll(pattern, "{version}", v))
+		}
+		return sources, vs, nil
+	}
+	if versions != "" {
+		return nil, nil, exitcode.Errorf(exitcode.Usage, "-versions needs a {version} placeholder in -releases")
+	}
+	if fi, err := os.Stat(pattern); err == nil && fi.IsDir() {
+		entries, err := os.ReadDir(pattern)
+		if err != nil {
+			return nil, nil, exitcode.Wrap(exitcode.IO, err)
+		}
+		for _, e := range entries {
+			if !e.IsDir() {
+				sources = append(sources, filepath.Join(pattern, e.Name()))
+			}
+		}
+	} else if sources, err = filepath.Glob(pattern); err != nil {
+		return nil, nil, exitcode.Wrap(exitcode.Usage, err)
+	}
+	if len(sources) == 0 {
+		return nil, nil, exitcode.Errorf(exitcode.IO, "no releases match %s", pattern)
+	}
+	sort.Slice(sources, func(i, j int) bool { return naturalLess(sources[i], sources[j]) })
+	for _, s := range sources {
+		base := strings.TrimSuffix(filepath.Base(s), ".gz")
+		labels = append(labels, strings.TrimSuffix(base, filepath.Ext(base)))
+	}
+	return sources, labels, nil
+}
+
+// parseVersionList reads a -versions value such as "200-203,210".
+func parseVersionList(s string) ([]string, error) {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		lo, hi, isRange := strings.Cut(part, "-")
+		if !isRange {
+			if part != "" {
+				out = append(out, part)
+			}
+			continue
+		}
+		a, err1 := strconv.Atoi(lo)
+		b, err2 := strconv.Atoi(hi)
+		if err1 != nil || err2 != nil || a > b {
+			return nil, fmt.Errorf("invalid version range %q", part)
+		}
+		for n := a; n <= b; n++ {
+			out = append(out, strconv.Itoa(n))
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no versions in %q", s)
+	}
+	return out, nil
+}
+
+// naturalLess compares a and b with runs of digits compared as numbers.
+func naturalLess(a, b string) bool {
+	for a != "" && b != "" {
+		da, db := digitPrefix(a), digitPrefix(b)
+		if da == "" || db == "" {
+			if a[0] != b[0] {
+				return a[0] < b[0]
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+		na, nb := strings.TrimLeft(da, "0"), strings.TrimLeft(db, "0")
+		if len(na) != len(nb) {
+			return len(na) < len(nb)
+		}
+		if na != nb {
+			return na < nb
+		}
+		a, b = a[len(da):], b[len(db):]
+	}
+	return len(a) < len(b)
+}
+
+func digitPrefix(s string) string {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	return s[:i]
+}
+
+// loadArchived parses a release file or URL, decompressing it if its name
+// ends in .gz. The format is detected from the name without .gz; compressed
+// JSON cannot be sniffed and is read as this tool's JSON unless -format
+// says otherwise.
+func loadArchived(source, format string) (*ontology.Ontology, error) {
+	file, cleanup, err := fetchSource(source)
+	if err != nil {
+		return nil, exitcode.Wrap(exitcode.IO, err)
+	}
+	defer cleanup()
+	name, gz := strings.CutSuffix(source, ".gz")
+	inputFmt := detectFormat(name, format)
+	if inputFmt == "" {
+		return nil, exitcode.Errorf(exitcode.Usage, "cannot detect format for %q; use -format obo or -format owl", source)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, exitcode.Wrap(exitcode.IO, err)
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if gz {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, exitcode.Errorf(exitcode.Parse, "%s: %w", source, err)
+		}
+		defer zr.Close()
+		r = zr
+	}
+	ont, err := parseInputWithOptions(r, inputFmt, ontology.ParseOptions{})
+	if err != nil {
+		return nil, exitcode.Errorf(exitcode.Parse, "%s: %w", source, err)
+	}
+	return ont, nil
+}
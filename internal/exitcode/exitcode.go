@@ -0,0 +1,109 @@
+// Package exitcode defines the exit codes of chebi-parser and classify,
+// one per class of failure, and the JSON error envelope written with
+// -errors-json, so CI pipelines can branch on the kind of failure.
+package exitcode
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/url"
+	"os"
+)
+
+// Exit codes. Usage is also what the flag package exits with on a bad
+// flag.
+const (
+	OK         = 0
+	Error      = 1 // any failure not classified below
+	Usage      = 2 // missing or invalid arguments
+	Parse      = 3 // the input ontology could not be parsed
+	Validation = 4 // a -strict check found problems
+	Unsat      = 5 // classification found unsatisfiable classes
+	IO         = 6 // reading or writing a file, or the network, failed
+)
+
+var names = map[int]string{
+	OK:         "ok",
+	Error:      "error",
+	Usage:      "usage",
+	Parse:      "parse",
+	Validation: "validation",
+	Unsat:      "unsat",
+	IO:         "io",
+}
+
+// Name returns the class name of an exit code, as used in the envelope.
+func Name(code int) string {
+	if n, ok := names[code]; ok {
+		return n
+	}
+	return names[Error]
+}
+
+// codedError carries an exit code with the error.
+type codedError struct {
+	code int
+	err  error
+}
+
+func (e *codedError) Error() string { return e.err.Error() }
+func (e *codedError) Unwrap() error { return e.err }
+
+// Wrap returns err with an exit code, or nil if err is nil.
+func Wrap(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &codedError{code, err}
+}
+
+// Errorf is fmt.Errorf with an exit code.
+func Errorf(code int, format string, args ...any) error {
+	return &codedError{code, fmt.Errorf(format, args...)}
+}
+
+// Of returns the exit code for err: the code it was wrapped with, IO for
+// file and network errors, Error otherwise, and OK for nil.
+func Of(err error) int {
+	if err == nil {
+		return OK
+	}
+	var ce *codedError
+	if errors.As(err, &ce) {
+		return ce.code
+	}
+	var pathErr *fs.PathError
+	var netErr net.Error
+	var urlErr *url.Error
+	if errors.As(err, &pathErr) || errors.As(err, &netErr) || errors.As(err, &urlErr) {
+		return IO
+	}
+	return Error
+}
+
+// Envelope is the -errors-json document. It is written on success too,
+// with code 0, so its absence means the process did not get that far.
+type Envelope struct {
+	Code    int    `json:"code"`
+	Class   string `json:"class"`
+	Command string `json:"command"`
+	Message string `json:"message,omitempty"`
+}
+
+// WriteEnvelope writes the envelope for a run of command that ended with
+// code and message to path.
+func WriteEnvelope(path, command string, code int, message string) error {
+	data, err := json.MarshalIndent(Envelope{
+		Code:    code,
+		Class:   Name(code),
+		Command: command,
+		Message: message,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,144 @@
+// Package intsets implements set operations on sorted, duplicate-free
+// slices of 32-bit IDs, the representation the reasoner uses for concept
+// sets. Inputs of similar size are merged linearly; when one side is much
+// smaller its elements are located in the other by galloping (exponential
+// then binary search), so the cost follows the smaller set.
+package intsets
+
+// gallopRatio is the size ratio above which Intersect gallops instead of
+// merging.
+const gallopRatio = 16
+
+// Contains reports whether x is in the sorted set s.
+func Contains[T ~uint32](s []T, x T) bool {
+	i := search(s, x)
+	return i < len(s) && s[i] == x
+}
+
+// Intersect appends the elements common to the sorted sets a and b to dst
+// and returns the extended slice. dst must not overlap a or b beyond
+// their start, as with append.
+func Intersect[T ~uint32](dst, a, b []T) []T {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	if len(a) == 0 {
+		return dst
+	}
+	if len(b) >= gallopRatio*len(a) {
+		lo := 0
+		for _, x := range a {
+			lo += gallop(b[lo:], x)
+			if lo == len(b) {
+				break
+			}
+			if b[lo] == x {
+				dst = append(dst, x)
+				lo++
+			}
+		}
+		return dst
+	}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		x, y := a[i], b[j]
+		if x == y {
+			dst = append(dst, x)
+		}
+		if x <= y {
+			i++
+		}
+		if y <= x {
+			j++
+		}
+	}
+	return dst
+}
+
+// Intersects reports whether the sorted sets a and b share an element,
+// without building the intersection.
+func Intersects[T ~uint32](a, b []T) bool {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	lo := 0
+	for _, x := range a {
+		lo += gallop(b[lo:], x)
+		if lo == len(b) {
+			return false
+		}
+		if b[lo] == x {
+			return true
+		}
+	}
+	return false
+}
+
+// Subset reports whether every element of the sorted set a is in the
+// sorted set b.
+func Subset[T ~uint32](a, b []T) bool {
+	if len(a) > len(b) {
+		return false
+	}
+	lo := 0
+	for _, x := range a {
+		lo += gallop(b[lo:], x)
+		if lo == len(b) || b[lo] != x {
+			return false
+		}
+		lo++
+	}
+	return true
+}
+
+// Union appends the elements of the sorted sets a and b to dst, in order
+// and without duplicates, and returns the extended slice.
+func Union[T ~uint32](dst, a, b []T) []T {
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		x, y := a[i], b[j]
+		switch {
+		case x < y:
+			dst = append(dst, x)
+			i++
+		case y < x:
+			dst = append(dst, y)
+			j++
+		default:
+			dst = append(dst, x)
+			i++
+			j++
+		}
+	}
+	dst = append(dst, a[i:]...)
+	return append(dst, b[j:]...)
+}
+
+// gallop returns the index of the first element of s not less than x,
+// probing 1, 2, 4, ... positions ahead before a binary search, so finding
+// an element near the front costs O(log distance) rather than O(log len).
+func gallop[T ~uint32](s []T, x T) int {
+	hi := 1
+	for hi < len(s) && s[hi-1] < x {
+		hi *= 2
+	}
+	lo := hi / 2
+	if hi > len(s) {
+		hi = len(s)
+	}
+	return lo + search(s[lo:hi], x)
+}
+
+// search returns the index of the first element of s not less than x.
+func search[T ~uint32](s []T, x T) int {
+	lo, hi := 0, len(s)
+	for lo < hi {
+		m := int(uint(lo+hi) >> 1)
+		if s[m] < x {
+			lo = m + 1
+		} else {
+			hi = m
+		}
+	}
+	return lo
+}
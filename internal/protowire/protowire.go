@@ -0,0 +1,84 @@
+// Package protowire implements the subset of the Protocol Buffers wire
+// format needed to emit the messages in proto/chebi.proto without a
+// generated-code dependency.
+package protowire
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// Wire types.
+const (
+	TypeVarint = 0
+	TypeBytes  = 2
+)
+
+// AppendVarint appends v as a base-128 varint.
+func AppendVarint(b []byte, v uint64) []byte {
+	return binary.AppendUvarint(b, v)
+}
+
+// AppendTag appends a field key for the given field number and wire type.
+func AppendTag(b []byte, field int, wireType int) []byte {
+	return AppendVarint(b, uint64(field)<<3|uint64(wireType))
+}
+
+// AppendString appends a length-delimited string field. Empty strings are
+// omitted, matching proto3 default-value semantics.
+func AppendString(b []byte, field int, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = AppendTag(b, field, TypeBytes)
+	b = AppendVarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+// AppendStrings appends a repeated string field. Unlike AppendString,
+// empty elements are kept so the element count is preserved.
+func AppendStrings(b []byte, field int, vals []string) []byte {
+	for _, s := range vals {
+		b = AppendTag(b, field, TypeBytes)
+		b = AppendVarint(b, uint64(len(s)))
+		b = append(b, s...)
+	}
+	return b
+}
+
+// AppendBool appends a bool field, omitting false.
+func AppendBool(b []byte, field int, v bool) []byte {
+	if !v {
+		return b
+	}
+	b = AppendTag(b, field, TypeVarint)
+	return append(b, 1)
+}
+
+// AppendInt64 appends an int64 field, omitting zero.
+func AppendInt64(b []byte, field int, v int64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = AppendTag(b, field, TypeVarint)
+	return AppendVarint(b, uint64(v))
+}
+
+// AppendMessage appends an already-encoded embedded message field.
+func AppendMessage(b []byte, field int, msg []byte) []byte {
+	b = AppendTag(b, field, TypeBytes)
+	b = AppendVarint(b, uint64(len(msg)))
+	return append(b, msg...)
+}
+
+// WriteDelimited writes msg prefixed with its varint length, the framing
+// used by parseDelimitedFrom/writeDelimitedTo in the protobuf runtimes.
+func WriteDelimited(w io.Writer, msg []byte) error {
+	var prefix [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(prefix[:], uint64(len(msg)))
+	if _, err := w.Write(prefix[:n]); err != nil {
+		return err
+	}
+	_, err := w.Write(msg)
+	return err
+}
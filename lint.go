@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nodeadmin/chebi-parser/internal/exitcode"
+	"github.com/nodeadmin/chebi-parser/ontology"
+)
+
+// lintCommand reports curation problems: conjugate acid/base pairs that do
+// not differ by one proton and one unit of charge, mass properties that
+// disagree with the formula, and distinct terms with the same structure.
+func lintCommand() (*flag.FlagSet, func() error) {
+	fs := newFlagSet("lint")
+	input := fs.String("input", "", "Ontology file (.obo, .owl or .msgpack)")
+	format := fs.String("format", "auto", "Input format: auto, obo, owl, msgpack")
+	checks := fs.String("checks", "", "Comma-separated checks to run (default: all): "+strings.Join(ontology.LintCheckNames(), ", "))
+	tolerance := fs.Float64("mass-tolerance", ontology.DefaultMassTolerance, "Largest accepted difference in daltons between a mass property and the formula")
+	asJSON := fs.Bool("json", false, "Write the report as JSON")
+	output := fs.String("output", "", "Report file (default: stdout)")
+	mergePairs := fs.String("merge-pairs", "", "Write the terms the duplicate checks matched as candidate merge pairs (TSV) to this file")
+	strict := fs.Bool("strict", false, "Exit with an error if there are findings")
+	return fs, func() error {
+		if *input == "" {
+			return exitcode.Errorf(exitcode.Usage, "usage: chebi-parser lint -input <file> [-checks a,b] [-mass-tolerance 0.01] [-json] [-output report.tsv] [-merge-pairs pairs.tsv] [-strict]")
+		}
+		opts := ontology.LintOptions{MassTolerance: *tolerance}
+		if *checks != "" {
+			opts.Checks = strings.Split(*checks, ",")
+		}
+		ont, err := loadOntology(*input, *format)
+		if err != nil {
+			return err
+		}
+		rep, err := ontology.Lint(ont, opts)
+		if err != nil {
+			return err
+		}
+
+		out := os.Stdout
+		if *output != "" {
+			f, err := os.Create(*output)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			out = f
+		}
+		bw := bufio.NewWriter(out)
+		if *asJSON {
+			enc := json.NewEncoder(bw)
+			enc.SetEscapeHTML(false)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(rep); err != nil {
+				return err
+			}
+		} else {
+			fmt.Fprintln(bw, "check\tterm_id\trelated\tmessage")
+			for _, f := range rep.Findings {
+				fmt.Fprintf(bw, "%s\t%s\t%s\t%s\n", f.Check, f.TermID, f.Related, f.Message)
+			}
+		}
+		if err := bw.Flush(); err != nil {
+			return err
+		}
+
+		if *mergePairs != "" {
+			if err := writeMergePairs(*mergePairs, ont, ontology.MergePairs(rep)); err != nil {
+				return err
+			}
+		}
+
+		fmt.Fprintf(os.Stderr, "%d findings; %d formulas could not be parsed\n", len(rep.Findings), rep.Unparsed)
+		if *strict && len(rep.Findings) > 0 {
+			return exitcode.Errorf(exitcode.Validation, "lint found %d problems", len(rep.Findings))
+		}
+		return nil
+	}
+}
+
+// writeMergePairs writes one line per candidate merge: both terms with
+// their names and the checks that matched them.
+func writeMergePairs(path string, ont *ontology.Ontology, pairs []ontology.MergePair) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	ix := ontology.NewIndex(ont)
+	name := func(id string) string {
+		if t := ix.Term(id); t != nil {
+			return t.Name
+		}
+		return ""
+	}
+	bw := bufio.NewWriter(f)
+	fmt.Fprintln(bw, "term_a\tname_a\tterm_b\tname_b\tshared")
+	for _, p := range pairs {
+		fmt.Fprintf(bw, "%s\t%s\t%s\t%s\t%s\n", p.A, name(p.A), p.B, name(p.B), strings.Join(p.Shared, ","))
+	}
+	err = bw.Flush()
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -1,92 +1,563 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime/debug"
 	"strings"
+	"text/template"
 	"time"
 
+	"github.com/nodeadmin/chebi-parser/internal/exitcode"
 	"github.com/nodeadmin/chebi-parser/ontology"
 )
 
 func main() {
-	input := flag.String("input", "", "Path to ChEBI ontology file (.obo or .owl)")
-	output := flag.String("output", "", "Path to output JSON file (default: stdout)")
-	format := flag.String("format", "auto", "Input format: auto, obo, owl")
-	pretty := flag.Bool("pretty", false, "Pretty-print JSON output")
-	flag.Parse()
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		runCommand(os.Args[1], os.Args[2:])
+		return
+	}
+	fs, run := rootCommand()
+	fs.Parse(os.Args[1:])
+	// run returns its failure rather than exiting, so its deferred
+	// cleanup (closing the output, removing the spill file) has run.
+	err := run()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	exitWith("", exitcode.Of(err), err)
+}
+
+// rootCommand converts an ontology file: the default mode, used when no
+// subcommand is given.
+func rootCommand() (*flag.FlagSet, func() error) {
+	fs := newFlagSet("chebi-parser")
+	input := fs.String("input", "", "Path to ChEBI ontology file (.obo or .owl)")
+	output := fs.String("output", "", "Path to output JSON file (default: stdout)")
+	format := fs.String("format", "auto", "Input format: auto, obo, owl, msgpack, json, obographs")
+	to := fs.String("to", "json", "Output format: json, jsonld, msgpack, protobuf, avro, obo, owl, ttl, skos, obographs, elastic, postgres (directory), closure, tree, report (directory)")
+	pretty := fs.Bool("pretty", false, "Pretty-print JSON output")
+	links := fs.Bool("links", false, "Add resolved entry, image and xref URLs to each term")
+	esIndex := fs.String("es-index", "chebi", "Index name for -to elastic")
+	esURL := fs.String("es-url", "", "Push -to elastic output to this cluster URL instead of writing a file")
+	pgSchema := fs.String("pg-schema", "", "Schema name for -to postgres DDL")
+	closureRels := fs.String("closure-relations", "is_a", "Comma-separated relation types for -to closure")
+	excludeBranch := fs.String("exclude-branch", "", excludeBranchUsage+" (-to closure)")
+	treeRoot := fs.String("tree-root", "CHEBI:24431", "Root term ID or name for -to tree")
+	treeDepth := fs.Int("tree-depth", 0, "Maximum depth below the root for -to tree (0 = unlimited)")
+	labelPrefsFlag := fs.String("label-prefs", "", labelPrefsUsage+" (-to tree and report, -split subtree)")
+	reportFormat := fs.String("report-format", "markdown", "Report format for -to report: markdown, html")
+	reportTerms := fs.String("report-terms", "", "Comma-separated term IDs or names to write per-term report pages for")
+	reportSubset := fs.String("report-subset", "", "Write per-term report pages for every term in this subset")
+	canonical := fs.Bool("canonical", false, "Sort terms and their fields canonically so output is byte-stable across runs")
+	dedupe := fs.Bool("dedupe", false, "Merge duplicate stanzas for the same ID and drop duplicate values before writing")
+	maxMemory := fs.String("max-memory", "", "Heap budget for parsing OBO/OWL (e.g. 1.5GB); past it, term bodies are spilled to a temporary file")
+	spillDir := fs.String("spill-dir", "", "Directory for the -max-memory spill file (default: system temp directory)")
+	chunkSize := fs.String("chunk-size", "", "Write -to json as numbered chunk files of at most this size (e.g. 100MB) plus manifest.json into the -output directory")
+	split := fs.String("split", "", "Write one file per namespace or per top-level is_a subtree into the -output directory: namespace, subtree")
+	splitRoot := fs.String("split-root", "", "With -split subtree, split by the children of this term ID or name instead of the top-level terms")
+	rulesFile := fs.String("rules", "", "File of derived-relationship rules (\"X rel Y if X has_role R and Y has_role R\"); derived edges are added before writing")
+	rulesMax := fs.Int("rules-max-edges", 10000000, "Stop with an error once -rules has derived this many edges (0 = unlimited)")
+	inverses := fs.Bool("inverses", false, "Also write the inverse of every relationship whose Typedef declares inverse_of (after -rules)")
+	orient := fs.String("orient", "", orientUsage+" (after -rules, before -inverses)")
+	linkTemplates := fs.String("link-templates", "", "JSON file of URL templates overriding the defaults (implies -links)")
+	templateFile := fs.String("template", "", "Write each term through this text/template file instead of -to (see ontology.TemplateTerm)")
+	obsoleteFlag := fs.String("obsolete", "", obsoleteUsage)
+	charsetFlag := fs.String("charset", "report", charsetUsage)
+	synonymsFlag := fs.String("synonyms", "keep", synonymsUsage)
+	maxWarnings := fs.Int("max-warnings", -1, "Fail when parsing OBO/OWL reports more than this many warnings (unknown tags, malformed synonyms, duplicate IDs, non-CHEBI IDs, non-UTF-8 lines, constructs the format-version does not allow); -1 = no check")
+	helpJSON := fs.Bool("help-json", false, "Describe every command and its flags as JSON and exit")
+	return fs, func() error {
+		if *helpJSON {
+			if err := writeHelpJSON(os.Stdout); err != nil {
+				return exitcode.Errorf(exitcode.Of(err), "Error: %v", err)
+			}
+			return nil
+		}
+
+		if *input == "" {
+			return exitcode.Errorf(exitcode.Usage, "Usage: chebi-parser -input <file> [-output <file>] [-format auto|obo|owl|msgpack|json|obographs] [-to json|msgpack|protobuf|avro|elastic|postgres|closure|tree|report] [-pretty]")
+		}
+
+		var tmpl *template.Template
+		if *templateFile != "" {
+			text, err := os.ReadFile(*templateFile)
+			if err != nil {
+				return exitcode.Errorf(exitcode.IO, "Error reading template: %v", err)
+			}
+			if tmpl, err = ontology.ParseTemplate(filepath.Base(*templateFile), string(text)); err != nil {
+				return exitcode.Errorf(exitcode.Usage, "Error: -template: %v", err)
+			}
+			*to = "template"
+		} else if *to == "template" {
+			return exitcode.Errorf(exitcode.Usage, "Error: -to template requires -template <file>")
+		}
+
+		// Detect format
+		inputFmt := detectFormat(*input, *format)
+		if inputFmt == "" {
+			return exitcode.Errorf(exitcode.Usage, "Error: cannot detect format for %q. Use -format obo or -format owl.", *input)
+		}
+
+		// Open input
+		f, err := os.Open(*input)
+		if err != nil {
+			return exitcode.Errorf(exitcode.IO, "Error opening input: %v", err)
+		}
+		defer f.Close()
+
+		// Parse
+		fmt.Fprintf(os.Stderr, "Parsing %s as %s...\n", filepath.Base(*input), inputFmt)
+		start := time.Now()
+
+		var opts ontology.ParseOptions
+		if opts.Obsolete, err = ontology.ParseObsoletePolicy(*obsoleteFlag); err != nil {
+			return exitcode.Errorf(exitcode.Usage, "Error: -obsolete: %v", err)
+		}
+		if opts.Charset, err = ontology.ParseCharsetPolicy(*charsetFlag); err != nil {
+			return exitcode.Errorf(exitcode.Usage, "Error: -charset: %v", err)
+		}
+		if opts.Synonyms, err = ontology.ParseSynonymPolicy(*synonymsFlag); err != nil {
+			return exitcode.Errorf(exitcode.Usage, "Error: -synonyms: %v", err)
+		}
+		labels, err := labelPrefs(*labelPrefsFlag)
+		if err != nil {
+			return exitcode.Errorf(exitcode.Usage, "Error: %v", err)
+		}
+		if *maxMemory != "" {
+			if opts.MaxMemory, err = ontology.ParseByteSize(*maxMemory); err != nil {
+				return exitcode.Errorf(exitcode.Usage, "Error: -max-memory: %v", err)
+			}
+			debug.SetMemoryLimit(opts.MaxMemory)
+			if opts.Bodies, err = ontology.NewBodyStore(*spillDir); err != nil {
+				return exitcode.Errorf(exitcode.IO, "Error creating spill file: %v", err)
+			}
+			defer opts.Bodies.Close()
+		}
+
+		var warnings ontology.WarningLog
+		if *maxWarnings >= 0 {
+			opts.Warn = warnings.Add
+		}
+		ont, err := parseInputWithOptions(f, inputFmt, opts)
+		if err != nil {
+			return exitcode.Errorf(exitcode.Parse, "Error parsing: %v", err)
+		}
+		if *maxWarnings >= 0 {
+			if err := checkWarnings(&warnings, *maxWarnings); err != nil {
+				return exitcode.Errorf(exitcode.Of(err), "Error: %v", err)
+			}
+		}
+
+		elapsed := time.Since(start)
+		fmt.Fprintf(os.Stderr, "Parsed %d terms in %v\n", len(ont.Terms), elapsed)
+
+		// Only plain JSON output streams spilled bodies back; everything else
+		// needs them in memory.
+		bodies := opts.Bodies
+		if bodies != nil && bodies.Len() > 0 {
+			fmt.Fprintf(os.Stderr, "Spilled %d term bodies (%d MB) over the %s budget\n", bodies.Len(), bodies.Size()>>20, *maxMemory)
+			if *to != "json" || *pretty || *dedupe || *links || *linkTemplates != "" || *canonical || *split != "" || *chunkSize != "" {
+				fmt.Fprintf(os.Stderr, "Restoring spilled bodies: only plain -to json output can stream them\n")
+				if err := bodies.RestoreAll(ont); err != nil {
+					return exitcode.Errorf(exitcode.IO, "Error reading spilled bodies: %v", err)
+				}
+				bodies = nil
+			}
+		}
+
+		if *dedupe {
+			reportDedupe(ontology.Dedupe(ont))
+		}
 
-	if *input == "" {
-		fmt.Fprintln(os.Stderr, "Usage: chebi-parser -input <file> [-output <file>] [-format auto|obo|owl] [-pretty]")
-		os.Exit(1)
+		if *rulesFile != "" {
+			if err := applyRules(ont, *rulesFile, *rulesMax); err != nil {
+				return exitcode.Errorf(exitcode.Of(err), "Error applying rules: %v", err)
+			}
+		}
+		if *orient != "" {
+			if err := orientRelationships(ont, *orient); err != nil {
+				return exitcode.Errorf(exitcode.Of(err), "Error: %v", err)
+			}
+		}
+		if *inverses {
+			fmt.Fprintf(os.Stderr, "Inverses: added %d edges\n", ontology.AddInverses(ont))
+		}
+
+		if *links || *linkTemplates != "" {
+			lt, err := loadLinkTemplates(*linkTemplates)
+			if err != nil {
+				return exitcode.Errorf(exitcode.Of(err), "Error loading link templates: %v", err)
+			}
+			ontology.AddLinks(ont, lt)
+		}
+		if *canonical {
+			ontology.Sort(ont)
+		}
+
+		// Write output
+		start = time.Now()
+		if *chunkSize != "" {
+			if *output == "" || *to != "json" || *split != "" {
+				return exitcode.Errorf(exitcode.Usage, "Error: -chunk-size requires -to json and -output <directory>, without -split")
+			}
+			maxBytes, err := ontology.ParseByteSize(*chunkSize)
+			if err != nil {
+				return exitcode.Errorf(exitcode.Usage, "Error: -chunk-size: %v", err)
+			}
+			m, err := ontology.WriteJSONChunks(ont, *output, ontology.ShortName(ont), maxBytes)
+			if err != nil {
+				return exitcode.Errorf(exitcode.Of(err), "Error writing chunks: %v", err)
+			}
+			fmt.Fprintf(os.Stderr, "Wrote %d chunks and %s to %s in %v\n", len(m.Chunks), ontology.ChunkManifestFile, *output, time.Since(start))
+			return nil
+		}
+		if *split != "" {
+			if *output == "" {
+				return exitcode.Errorf(exitcode.Usage, "Error: -split requires -output <directory>")
+			}
+			n, err := writeSplit(ont, *output, *split, *splitRoot, *to, *pretty, labels, opts.Obsolete)
+			if err != nil {
+				return exitcode.Errorf(exitcode.Of(err), "Error writing split output: %v", err)
+			}
+			fmt.Fprintf(os.Stderr, "Wrote %d %s files to %s in %v\n", n, *to, *output, time.Since(start))
+			return nil
+		}
+		if *to == "elastic" && *esURL != "" {
+			if err := ontology.PushElasticBulk(ont, *esURL, *esIndex); err != nil {
+				return exitcode.Errorf(exitcode.IO, "Error pushing to %s: %v", *esURL, err)
+			}
+			fmt.Fprintf(os.Stderr, "Pushed %d terms to %s in %v\n", len(ont.Terms), *esURL, time.Since(start))
+			return nil
+		}
+		if *to == "report" {
+			if *output == "" {
+				return exitcode.Errorf(exitcode.Usage, "Error: -to report requires -output <directory>")
+			}
+			var ids []string
+			if *reportTerms != "" {
+				ids = strings.Split(*reportTerms, ",")
+			}
+			if err := writeReport(ont, *output, *reportFormat, ids, *reportSubset, labels); err != nil {
+				return exitcode.Errorf(exitcode.Of(err), "Error writing report: %v", err)
+			}
+			fmt.Fprintf(os.Stderr, "Wrote report to %s in %v\n", *output, time.Since(start))
+			return nil
+		}
+		if *to == "postgres" {
+			if *output == "" {
+				return exitcode.Errorf(exitcode.Usage, "Error: -to postgres requires -output <directory>")
+			}
+			if err := writePostgres(ont, *output, *pgSchema); err != nil {
+				return exitcode.Errorf(exitcode.Of(err), "Error writing output: %v", err)
+			}
+			fmt.Fprintf(os.Stderr, "Wrote postgres tables to %s in %v\n", *output, time.Since(start))
+			return nil
+		}
+
+		out := os.Stdout
+		if *output != "" {
+			out, err = os.Create(*output)
+			if err != nil {
+				return exitcode.Errorf(exitcode.IO, "Error creating output: %v", err)
+			}
+			defer out.Close()
+		}
+
+		switch *to {
+		case "json":
+			switch {
+			case *pretty:
+				err = ontology.WriteJSONPretty(ont, out)
+			case bodies != nil && bodies.Len() > 0:
+				err = ontology.WriteJSONWithBodies(ont, bodies, out)
+			default:
+				err = ontology.WriteJSON(ont, out)
+			}
+		case "msgpack":
+			err = ontology.WriteMsgpack(ont, out)
+		case "protobuf":
+			err = ontology.WriteProtoStream(ont, out)
+		case "avro":
+			err = ontology.WriteAvro(ont, out)
+		case "elastic":
+			err = ontology.WriteElasticBulk(ont, out, *esIndex)
+		case "template":
+			err = ontology.WriteTemplate(ont, tmpl, out)
+		case "closure":
+			ix := ontology.NewIndex(ont)
+			var roots []string
+			if roots, err = excludedBranches(ix, *excludeBranch); err == nil {
+				ix = ontology.NewIndexWithOptions(ont, ontology.IndexOptions{ExcludeBranches: roots})
+				err = ontology.WriteIndexClosureTSV(ix, out, strings.Split(*closureRels, ","))
+			}
+		case "tree":
+			ix := ontology.NewIndexWithOptions(ont, ontology.IndexOptions{Labels: labels})
+			var root string
+			if root, err = ix.Lookup(*treeRoot); err != nil {
+				err = fmt.Errorf("tree root: %w", err)
+			} else {
+				err = ontology.WriteTreeJSON(ix.Tree(root, *treeDepth), out)
+			}
+		default:
+			if write, ok := ontologyWriter(*to, opts.Obsolete); ok {
+				err = write(ont, out)
+			} else {
+				err = fmt.Errorf("unknown output format %q", *to)
+			}
+		}
+		if err != nil {
+			return exitcode.Errorf(exitcode.Of(err), "Error writing output: %v", err)
+		}
+
+		if *output != "" {
+			if err := out.Close(); err != nil {
+				return exitcode.Errorf(exitcode.IO, "Error writing output: %v", err)
+			}
+			writeElapsed := time.Since(start)
+			fmt.Fprintf(os.Stderr, "Wrote %s in %v\n", *to, writeElapsed)
+		}
+		return nil
 	}
+}
 
-	// Detect format
-	inputFmt := detectFormat(*input, *format)
-	if inputFmt == "" {
-		fmt.Fprintf(os.Stderr, "Error: cannot detect format for %q. Use -format obo or -format owl.\n", *input)
-		os.Exit(1)
+// writeSplit writes one file per part of ont, split by namespace or
+// subtree, into dir as <short>_<key>.<ext>, and returns the number of
+// files. Subtree keys are the roots' labels under labels. obsolete is the
+// -obsolete policy, which the index and writers follow as for unsplit output.
+func writeSplit(ont *ontology.Ontology, dir, by, root, to string, pretty bool, labels ontology.LabelPrefs, obsolete ontology.ObsoletePolicy) (int, error) {
+	write, ok := ontologyWriter(to, obsolete)
+	if !ok {
+		return 0, fmt.Errorf("-split supports %s output, not %q", strings.Join(sortedWriterNames(), ", "), to)
 	}
+	if to == "json" && pretty {
+		write = ontology.WriteJSONPretty
+	}
+	var parts []ontology.SplitPart
+	switch by {
+	case "namespace":
+		parts = ontology.SplitByNamespace(ont)
+	case "subtree":
+		ix := ontology.NewIndexWithOptions(ont, ontology.IndexOptions{Labels: labels, Obsolete: obsolete})
+		if root != "" {
+			id, err := ix.Lookup(root)
+			if err != nil {
+				return 0, fmt.Errorf("split root: %w", err)
+			}
+			root = id
+		}
+		parts = ix.SplitBySubtree(root)
+	default:
+		return 0, fmt.Errorf("unknown -split mode %q (want namespace or subtree)", by)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return 0, err
+	}
+	short := ontology.ShortName(ont)
+	for _, part := range parts {
+		f, err := os.Create(filepath.Join(dir, short+"_"+part.Key+formatExtensions[to]))
+		if err != nil {
+			return 0, err
+		}
+		err = write(part.Ontology, f)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(parts), nil
+}
 
-	// Open input
-	f, err := os.Open(*input)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error opening input: %v\n", err)
-		os.Exit(1)
+// writePostgres writes schema.sql and one COPY file per table into dir.
+func writePostgres(ont *ontology.Ontology, dir, schema string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	ddl := ontology.PostgresDDL(schema)
+	if err := os.WriteFile(filepath.Join(dir, "schema.sql"), []byte(ddl), 0o644); err != nil {
+		return err
+	}
+	for _, table := range ontology.PostgresTables {
+		f, err := os.Create(filepath.Join(dir, table+".tsv"))
+		if err != nil {
+			return err
+		}
+		err = ontology.WritePostgresTable(ont, table, f)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeReport writes a release page (index.md or index.html) and one page
+// per selected term into dir.
+func writeReport(ont *ontology.Ontology, dir, format string, ids []string, subset string, labels ontology.LabelPrefs) error {
+	ext := ".md"
+	if format == ontology.ReportHTML {
+		ext = ".html"
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
 	}
-	defer f.Close()
 
-	// Parse
-	fmt.Fprintf(os.Stderr, "Parsing %s as %s...\n", filepath.Base(*input), inputFmt)
-	start := time.Now()
+	ix := ontology.NewIndexWithOptions(ont, ontology.IndexOptions{Labels: labels})
+	if subset != "" {
+		for i := range ont.Terms {
+			for _, s := range ont.Terms[i].Subsets {
+				if s == subset {
+					ids = append(ids, ont.Terms[i].ID)
+					break
+				}
+			}
+		}
+	}
 
-	var ont *ontology.Ontology
-	switch inputFmt {
-	case "obo":
-		ont, err = ontology.ParseOBO(f)
-	case "owl":
-		ont, err = ontology.ParseOWL(f)
+	writePage := func(name string, render func(f *os.File) error) error {
+		f, err := os.Create(filepath.Join(dir, name+ext))
+		if err != nil {
+			return err
+		}
+		err = render(f)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		return err
 	}
+
+	stats := ontology.ComputeStats(ont)
+	err := writePage("index", func(f *os.File) error {
+		return ontology.WriteReleaseReport(stats, f, format)
+	})
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error parsing: %v\n", err)
-		os.Exit(1)
+		return err
 	}
+	for _, ref := range ids {
+		id, err := ix.Lookup(ref)
+		if err != nil {
+			return err
+		}
+		r := ix.NewTermReport(id)
+		err = writePage(strings.ReplaceAll(r.Term.ID, ":", "_"), func(f *os.File) error {
+			return ontology.WriteTermReport(r, f, format)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	elapsed := time.Since(start)
-	fmt.Fprintf(os.Stderr, "Parsed %d terms in %v\n", len(ont.Terms), elapsed)
+// applyRules derives the edges of the rules in path and reports the count
+// per rule on stderr.
+func applyRules(ont *ontology.Ontology, path string, maxEdges int) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	rules, err := ontology.ParseRules(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	rep, err := ontology.ApplyRules(ont, rules, ontology.RuleOptions{MaxEdges: maxEdges})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Rules: derived %d edges in %d rounds\n", rep.Total(), rep.Rounds)
+	for i, r := range rules {
+		fmt.Fprintf(os.Stderr, "  %6d  line %d: %s\n", rep.Derived[i], r.Line, r.Text)
+	}
+	return nil
+}
 
-	// Write output
-	start = time.Now()
-	if *output != "" {
-		if *pretty {
-			outFile, err := os.Create(*output)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error creating output: %v\n", err)
-				os.Exit(1)
-			}
-			defer outFile.Close()
-			err = ontology.WriteJSONPretty(ont, outFile)
-		} else {
-			err = ontology.WriteJSONFile(ont, *output)
-		}
-	} else {
-		if *pretty {
-			err = ontology.WriteJSONPretty(ont, os.Stdout)
-		} else {
-			err = ontology.WriteJSON(ont, os.Stdout)
+// maxDedupeConflicts caps the conflicts listed on stderr by -dedupe.
+const maxDedupeConflicts = 20
+
+// reportDedupe prints a Dedupe summary and the first conflicts to stderr.
+func reportDedupe(rep *ontology.DedupeReport) {
+	removed := 0
+	for _, n := range rep.Removed {
+		removed += n
+	}
+	fmt.Fprintf(os.Stderr, "Dedupe: merged %d terms, %d typedefs, %d individuals; removed %d duplicate values; %d conflicts\n",
+		rep.MergedTerms, rep.MergedTypeDefs, rep.MergedIndividuals, removed, len(rep.Conflicts))
+	for i, c := range rep.Conflicts {
+		if i == maxDedupeConflicts {
+			fmt.Fprintf(os.Stderr, "  ... and %d more\n", len(rep.Conflicts)-i)
+			break
 		}
+		fmt.Fprintf(os.Stderr, "  %s %s: kept %q, dropped %q\n", c.ID, c.Field, c.Kept, c.Dropped)
 	}
+}
+
+// loadLinkTemplates returns the default templates overlaid with any
+// entries from the given JSON file.
+func loadLinkTemplates(path string) (ontology.LinkTemplates, error) {
+	lt := make(ontology.LinkTemplates, len(ontology.DefaultLinkTemplates))
+	for k, v := range ontology.DefaultLinkTemplates {
+		lt[k] = v
+	}
+	if path == "" {
+		return lt, nil
+	}
+	data, err := os.ReadFile(path)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
-		os.Exit(1)
+		return nil, err
+	}
+	var custom map[string]string
+	if err := json.Unmarshal(data, &custom); err != nil {
+		return nil, err
+	}
+	for k, v := range custom {
+		lt[k] = v
 	}
+	return lt, nil
+}
+
+// parseInput parses r according to a format name returned by detectFormat.
+func parseInput(r io.Reader, format string) (*ontology.Ontology, error) {
+	return parseInputWithOptions(r, format, ontology.ParseOptions{})
+}
 
-	if *output != "" {
-		writeElapsed := time.Since(start)
-		fmt.Fprintf(os.Stderr, "Wrote JSON in %v\n", writeElapsed)
+// parseInputWithOptions is parseInput with parse options. The memory
+// budget and warnings apply to OBO and OWL input only; the obsolete-term
+// and synonym policies to every format.
+func parseInputWithOptions(r io.Reader, format string, opts ontology.ParseOptions) (*ontology.Ontology, error) {
+	var read func(io.Reader) (*ontology.Ontology, error)
+	switch format {
+	case "obo":
+		return ontology.ParseOBOWithOptions(r, opts)
+	case "owl":
+		return ontology.ParseOWLWithOptions(r, opts)
+	case "msgpack":
+		read = ontology.ReadMsgpack
+	case "json":
+		read = ontology.ReadJSON
+	case "obographs":
+		read = ontology.ReadOBOGraphs
+	case "ttl":
+		return nil, fmt.Errorf("Turtle input is not supported; read the OWL (RDF/XML) or OBO release instead")
+	default:
+		return nil, fmt.Errorf("unknown input format %q", format)
+	}
+	ont, err := read(r)
+	if err == nil && !opts.Obsolete.Keep(true) {
+		ontology.DropObsolete(ont)
+	}
+	if err == nil {
+		ontology.CollapseSynonyms(ont, opts.Synonyms)
 	}
+	return ont, err
 }
 
 func detectFormat(path, explicit string) string {
@@ -99,6 +570,28 @@ func detectFormat(path, explicit string) string {
 		return "obo"
 	case ".owl", ".xml", ".rdf":
 		return "owl"
+	case ".msgpack", ".mpk":
+		return "msgpack"
+	case ".ttl":
+		return "ttl"
+	case ".json":
+		return sniffJSONFormat(path)
 	}
 	return ""
 }
+
+// sniffJSONFormat tells OBO Graphs JSON from this tool's own JSON by
+// looking for the top-level "graphs" key near the start of the file.
+func sniffJSONFormat(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return "json" // let the caller report the open error
+	}
+	defer f.Close()
+	head := make([]byte, 4096)
+	n, _ := io.ReadFull(f, head)
+	if strings.Contains(string(head[:n]), `"graphs"`) {
+		return "obographs"
+	}
+	return "json"
+}
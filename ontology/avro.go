@@ -0,0 +1,259 @@
+package ontology
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"strings"
+)
+
+// avroTermSchema is the Avro schema embedded in the object container file.
+// Optional strings default to "" rather than using ["null","string"] unions,
+// matching the JSON output where empty fields are simply absent.
+const avroTermSchema = `{
+  "type": "record",
+  "name": "Term",
+  "namespace": "org.chebi",
+  "fields": [
+    {"name": "id", "type": "string"},
+    {"name": "name", "type": "string", "default": ""},
+    {"name": "namespace", "type": "string", "default": ""},
+    {"name": "definition", "type": "string", "default": ""},
+    {"name": "is_obsolete", "type": "boolean", "default": false},
+    {"name": "comment", "type": "string", "default": ""},
+    {"name": "subsets", "type": {"type": "array", "items": "string"}, "default": []},
+    {"name": "synonyms", "type": {"type": "array", "items": {
+      "type": "record", "name": "Synonym", "fields": [
+        {"name": "text", "type": "string"},
+        {"name": "scope", "type": "string"},
+        {"name": "type", "type": "string", "default": ""},
+        {"name": "xrefs", "type": {"type": "array", "items": "string"}, "default": []}
+      ]}}, "default": []},
+    {"name": "xrefs", "type": {"type": "array", "items": "string"}, "default": []},
+    {"name": "alt_ids", "type": {"type": "array", "items": "string"}, "default": []},
+    {"name": "relationships", "type": {"type": "array", "items": {
+      "type": "record", "name": "Relationship", "fields": [
+        {"name": "type", "type": "string"},
+        {"name": "target_id", "type": "string"},
+        {"name": "name", "type": "string", "default": ""},
+        {"name": "has_value", "type": "boolean", "default": false},
+        {"name": "self", "type": "boolean", "default": false},
+        {"name": "qualifiers", "type": {"type": "map", "values": "string"}, "default": {}},
+        {"name": "cardinality", "type": ["null", {
+          "type": "record", "name": "Cardinality", "fields": [
+            {"name": "min", "type": "int"},
+            {"name": "max", "type": "int"}
+          ]}], "default": null}
+      ]}}, "default": []},
+    {"name": "intersection_of", "type": {"type": "array", "items": {
+      "type": "record", "name": "IntersectionPart", "fields": [
+        {"name": "relationship", "type": "string", "default": ""},
+        {"name": "target_id", "type": "string"},
+        {"name": "self", "type": "boolean", "default": false}
+      ]}}, "default": []},
+    {"name": "properties", "type": {"type": "map", "values": "string"}, "default": {}},
+    {"name": "replaced_by", "type": {"type": "array", "items": "string"}, "default": []},
+    {"name": "consider", "type": {"type": "array", "items": "string"}, "default": []},
+    {"name": "union_of", "type": {"type": "array", "items": "string"}, "default": []},
+    {"name": "one_of", "type": {"type": "array", "items": "string"}, "default": []},
+    {"name": "xref_qualifiers", "type": {"type": "map", "values": {"type": "map", "values": "string"}}, "default": {}},
+    {"name": "property_types", "type": {"type": "map", "values": "string"}, "default": {}},
+    {"name": "obsolescence", "type": ["null", {
+      "type": "record", "name": "Obsolescence", "fields": [
+        {"name": "reason", "type": "string"},
+        {"name": "source", "type": "string"}
+      ]}], "default": null}
+  ]
+}`
+
+const avroBlockRecords = 4096 // records per OCF data block
+
+// WriteAvro writes the ontology terms as an Avro object container file with
+// the schema embedded in the header. Ontology-level fields are stored as
+// chebi.* metadata entries. Blocks are uncompressed (codec "null").
+func WriteAvro(ont *Ontology, w io.Writer) error {
+	bw := bufio.NewWriterSize(w, writerBufferSize)
+
+	var sync [16]byte
+	if _, err := rand.Read(sync[:]); err != nil {
+		return err
+	}
+
+	meta := map[string]string{
+		"avro.schema": avroTermSchema,
+		"avro.codec":  "null",
+	}
+	if ont.FormatVersion != "" {
+		meta["chebi.format_version"] = ont.FormatVersion
+	}
+	if ont.DataVersion != "" {
+		meta["chebi.data_version"] = ont.DataVersion
+	}
+	if ont.Ontology != "" {
+		meta["chebi.ontology"] = ont.Ontology
+	}
+	if m := ont.Metadata; m != nil {
+		// Metadata keys hold one value per line.
+		for _, p := range metadataWriteProperties {
+			if vals := m.values(p.field); len(vals) > 0 {
+				meta["chebi."+p.field] = strings.Join(vals, "\n")
+			}
+		}
+	}
+
+	hdr := []byte("Obj\x01")
+	hdr = appendAvroStringMap(hdr, meta)
+	hdr = append(hdr, sync[:]...)
+	if _, err := bw.Write(hdr); err != nil {
+		return err
+	}
+
+	var block, prefix []byte
+	count := 0
+	flush := func() error {
+		if count == 0 {
+			return nil
+		}
+		prefix = appendAvroLong(prefix[:0], int64(count))
+		prefix = appendAvroLong(prefix, int64(len(block)))
+		bw.Write(prefix)
+		bw.Write(block)
+		_, err := bw.Write(sync[:])
+		block = block[:0]
+		count = 0
+		return err
+	}
+
+	for i := range ont.Terms {
+		block = appendAvroTerm(block, &ont.Terms[i])
+		count++
+		if count == avroBlockRecords {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// appendAvroLong appends a zig-zag varint, Avro's encoding for int and long.
+func appendAvroLong(b []byte, v int64) []byte {
+	return binary.AppendUvarint(b, uint64(v<<1)^uint64(v>>63))
+}
+
+func appendAvroString(b []byte, s string) []byte {
+	b = appendAvroLong(b, int64(len(s)))
+	return append(b, s...)
+}
+
+func appendAvroBool(b []byte, v bool) []byte {
+	if v {
+		return append(b, 1)
+	}
+	return append(b, 0)
+}
+
+// appendAvroStrings appends an array<string> as a single block.
+func appendAvroStrings(b []byte, vals []string) []byte {
+	if len(vals) > 0 {
+		b = appendAvroLong(b, int64(len(vals)))
+		for _, s := range vals {
+			b = appendAvroString(b, s)
+		}
+	}
+	return append(b, 0)
+}
+
+// appendAvroStringMap appends a map<string> with keys in sorted order.
+func appendAvroStringMap(b []byte, m map[string]string) []byte {
+	if len(m) > 0 {
+		b = appendAvroLong(b, int64(len(m)))
+		for _, k := range sortedKeys(m) {
+			b = appendAvroString(b, k)
+			b = appendAvroString(b, m[k])
+		}
+	}
+	return append(b, 0)
+}
+
+func appendAvroTerm(b []byte, t *Term) []byte {
+	b = appendAvroString(b, t.ID)
+	b = appendAvroString(b, t.Name)
+	b = appendAvroString(b, t.Namespace)
+	b = appendAvroString(b, t.Definition)
+	b = appendAvroBool(b, t.IsObsolete)
+	b = appendAvroString(b, t.Comment)
+	b = appendAvroStrings(b, t.Subsets)
+
+	if len(t.Synonyms) > 0 {
+		b = appendAvroLong(b, int64(len(t.Synonyms)))
+		for i := range t.Synonyms {
+			syn := &t.Synonyms[i]
+			b = appendAvroString(b, syn.Text)
+			b = appendAvroString(b, syn.Scope)
+			b = appendAvroString(b, syn.Type)
+			b = appendAvroStrings(b, syn.Xrefs)
+		}
+	}
+	b = append(b, 0)
+
+	b = appendAvroStrings(b, t.Xrefs)
+	b = appendAvroStrings(b, t.AltIDs)
+
+	if len(t.Relationships) > 0 {
+		b = appendAvroLong(b, int64(len(t.Relationships)))
+		for i := range t.Relationships {
+			rel := &t.Relationships[i]
+			b = appendAvroString(b, rel.Type)
+			b = appendAvroString(b, rel.TargetID)
+			b = appendAvroString(b, rel.Name)
+			b = appendAvroBool(b, rel.HasValue)
+			b = appendAvroBool(b, rel.Self)
+			b = appendAvroStringMap(b, rel.Qualifiers)
+			if c := rel.Cardinality; c != nil {
+				b = appendAvroLong(b, 1) // union branch: Cardinality
+				b = appendAvroLong(b, int64(c.Min))
+				b = appendAvroLong(b, int64(c.Max))
+			} else {
+				b = appendAvroLong(b, 0) // union branch: null
+			}
+		}
+	}
+	b = append(b, 0)
+
+	if len(t.IntersectionOf) > 0 {
+		b = appendAvroLong(b, int64(len(t.IntersectionOf)))
+		for i := range t.IntersectionOf {
+			b = appendAvroString(b, t.IntersectionOf[i].Relationship)
+			b = appendAvroString(b, t.IntersectionOf[i].TargetID)
+			b = appendAvroBool(b, t.IntersectionOf[i].Self)
+		}
+	}
+	b = append(b, 0)
+
+	b = appendAvroStringMap(b, t.Properties)
+	b = appendAvroStrings(b, t.ReplacedBy)
+	b = appendAvroStrings(b, t.Consider)
+	b = appendAvroStrings(b, t.UnionOf)
+	b = appendAvroStrings(b, t.OneOf)
+
+	if len(t.XrefQualifiers) > 0 {
+		b = appendAvroLong(b, int64(len(t.XrefQualifiers)))
+		for _, x := range sortedKeys(t.XrefQualifiers) {
+			b = appendAvroString(b, x)
+			b = appendAvroStringMap(b, t.XrefQualifiers[x])
+		}
+	}
+	b = append(b, 0)
+	b = appendAvroStringMap(b, t.PropertyTypes)
+	if o := t.Obsolescence; o != nil {
+		b = appendAvroLong(b, 1) // union branch: Obsolescence
+		b = appendAvroString(b, o.Reason)
+		return appendAvroString(b, o.Source)
+	}
+	return appendAvroLong(b, 0) // union branch: null
+}
@@ -0,0 +1,96 @@
+package ontology
+
+import "sync"
+
+// ChEBI's top-level is_a branches. Roles (biological and chemical roles,
+// applications) are classes in their own hierarchy, beside the chemical
+// entities they are linked to by has_role.
+const (
+	ChEBIChemicalEntity    = "CHEBI:24431"
+	ChEBIRole              = "CHEBI:50906"
+	ChEBISubatomicParticle = "CHEBI:36342"
+)
+
+// branchIndex holds the terms only under IndexOptions.ExcludeBranches. It
+// is built on first use.
+type branchIndex struct {
+	roots []string
+	once  sync.Once
+	ids   map[string]bool
+}
+
+// buildBranches collects the descendants of the excluded roots, then
+// takes back those also reachable from a term outside them without
+// passing through a root.
+func (ix *Index) buildBranches() {
+	roots := make(map[string]bool, len(ix.branches.roots))
+	ids := make(map[string]bool)
+	for _, root := range ix.branches.roots {
+		if p := ix.Primary(root); p != "" {
+			root = p
+		}
+		roots[root] = true
+		for id := range ix.descendants(root) {
+			ids[id] = true
+		}
+	}
+	var stack []string
+	for i := range ix.ont.Terms {
+		if id := ix.ont.Terms[i].ID; !ids[id] {
+			stack = append(stack, id)
+		}
+	}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, k := range ix.Children(id) {
+			if ids[k] && !roots[k] {
+				delete(ids, k)
+				stack = append(stack, k)
+			}
+		}
+	}
+	ix.branches.ids = ids
+}
+
+// Excluded reports whether id is a root of IndexOptions.ExcludeBranches
+// or a term whose every is_a path up leads to one. A term also classified
+// elsewhere, such as a chemical asserted is_a a role, is not excluded;
+// only its is_a edges into the branch are ignored.
+func (ix *Index) Excluded(id string) bool {
+	if len(ix.branches.roots) == 0 {
+		return false
+	}
+	ix.branches.once.Do(ix.buildBranches)
+	if p := ix.Primary(id); p != "" {
+		id = p
+	}
+	return ix.branches.ids[id]
+}
+
+// includedAncestors returns id's is_a ancestors outside the excluded
+// branches.
+func (ix *Index) includedAncestors(id string) []string {
+	as := ix.Ancestors(id)
+	if len(ix.branches.roots) == 0 {
+		return as
+	}
+	out := as[:0:0]
+	for _, a := range as {
+		if !ix.Excluded(a) {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// topLevel reports whether id has no is_a parents outside the excluded
+// branches.
+func (ix *Index) topLevel(id string) bool {
+	for _, p := range ix.Parents(id) {
+		if !ix.Excluded(p) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,123 @@
+package ontology
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// CardXrefPrefixes are the xref databases a term card shows, in order;
+// a ChEBI entry often has dozens of xrefs, most of them literature.
+var CardXrefPrefixes = []string{"CAS", "KEGG", "PubChem", "HMDB", "DrugBank", "MetaCyc", "Wikipedia"}
+
+// roleRelations are the relationship types ChEBI states roles with, by
+// name and by RO ID.
+var roleRelations = map[string]bool{"has_role": true, "RO:0000087": true}
+
+// Card is a one-screen summary of a term for quick lookups.
+type Card struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name,omitempty"`
+	Obsolete   bool      `json:"obsolete,omitempty"`
+	ReplacedBy []string  `json:"replaced_by,omitempty"`
+	Definition string    `json:"definition,omitempty"`
+	Formula    string    `json:"formula,omitempty"`
+	Mass       string    `json:"mass,omitempty"`
+	Charge     string    `json:"charge,omitempty"`
+	Parents    []CardRef `json:"parents,omitempty"`
+	Roles      []CardRef `json:"roles,omitempty"`
+	Xrefs      []string  `json:"xrefs,omitempty"`
+}
+
+// CardRef is a term ID with its label, if known.
+type CardRef struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+// NewCard assembles the card for one term, or returns nil if the term is
+// not in the index.
+func (ix *Index) NewCard(id string) *Card {
+	t := ix.Term(id)
+	if t == nil {
+		return nil
+	}
+	c := &Card{
+		ID:         t.ID,
+		Name:       ix.Label(t.ID),
+		Obsolete:   t.IsObsolete,
+		ReplacedBy: t.ReplacedBy,
+		Definition: t.Definition,
+	}
+	c.Formula, _ = chemProperty(t, "formula")
+	c.Mass, _ = chemProperty(t, "mass")
+	if q, ok := chemProperty(t, "charge"); ok {
+		c.Charge = NormalizeCharge(q)
+	}
+	for _, rel := range t.Relationships {
+		ref := ix.ref(rel.TargetID)
+		switch {
+		case rel.Type == "is_a":
+			c.Parents = append(c.Parents, CardRef(ref))
+		case roleRelations[rel.Type]:
+			c.Roles = append(c.Roles, CardRef(ref))
+		}
+	}
+	for _, prefix := range CardXrefPrefixes {
+		for _, x := range t.Xrefs {
+			if p, _, ok := strings.Cut(x, ":"); ok && p == prefix {
+				c.Xrefs = append(c.Xrefs, x)
+			}
+		}
+	}
+	return c
+}
+
+// WriteCard prints a card as aligned text for the terminal.
+func WriteCard(c *Card, w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s  %s\n", c.ID, c.Name)
+	if c.Obsolete {
+		b.WriteString("  OBSOLETE")
+		if len(c.ReplacedBy) > 0 {
+			b.WriteString(", replaced by " + strings.Join(c.ReplacedBy, ", "))
+		}
+		b.WriteString("\n")
+	}
+	field := func(label, value string) {
+		if value == "" {
+			return
+		}
+		if label != "" {
+			label += ":"
+		}
+		fmt.Fprintf(&b, "  %-10s %s\n", label, value)
+	}
+	field("def", c.Definition)
+	field("formula", c.Formula)
+	field("mass", c.Mass)
+	field("charge", c.Charge)
+	refs := func(label string, refs []CardRef) {
+		for i, r := range refs {
+			if i > 0 {
+				label = ""
+			}
+			if r.Name != "" {
+				field(label, r.Name+" ("+r.ID+")")
+			} else {
+				field(label, r.ID)
+			}
+		}
+	}
+	refs("parent", c.Parents)
+	refs("role", c.Roles)
+	for i, x := range c.Xrefs {
+		label := "xref"
+		if i > 0 {
+			label = ""
+		}
+		field(label, x)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
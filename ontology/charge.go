@@ -0,0 +1,103 @@
+package ontology
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// signReplacer maps the Unicode signs, dashes and superscripts that turn
+// up in charge and mass values to ASCII.
+var signReplacer = strings.NewReplacer(
+	"−", "-", // minus sign
+	"–", "-", // en dash
+	"‒", "-", // figure dash
+	"﹣", "-", // small hyphen-minus
+	"－", "-", // fullwidth hyphen-minus
+	"⁻", "-", // superscript minus
+	"＋", "+", // fullwidth plus
+	"⁺", "+", // superscript plus
+	"⁰", "0", "¹", "1", "²", "2", "³", "3", "⁴", "4",
+	"⁵", "5", "⁶", "6", "⁷", "7", "⁸", "8", "⁹", "9",
+	" ", "", " ", "", " ", "", // no-break and thin spaces
+)
+
+// ParseCharge reads a formal charge in any of the forms sources write it:
+// "0", "+1", "-2", "1+", "2-", a bare "+" or "-", in parentheses as in
+// names ("(1-)"), and with Unicode minus signs or superscripts ("2⁻").
+func ParseCharge(s string) (int, error) {
+	v := strings.TrimSpace(signReplacer.Replace(s))
+	v = strings.TrimSuffix(strings.TrimPrefix(v, "("), ")")
+	switch v {
+	case "":
+		return 0, fmt.Errorf("empty charge")
+	case "+":
+		return 1, nil
+	case "-":
+		return -1, nil
+	}
+	if last := v[len(v)-1]; last == '+' || last == '-' {
+		// Trailing sign, as in "2-": only digits may precede it.
+		digits := v[:len(v)-1]
+		n, err := strconv.Atoi(digits)
+		if err != nil || digits[0] == '+' || digits[0] == '-' {
+			return 0, fmt.Errorf("invalid charge %q", s)
+		}
+		if last == '-' {
+			n = -n
+		}
+		return n, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid charge %q", s)
+	}
+	return n, nil
+}
+
+// FormatCharge renders a charge as ChEBI's charge property writes it:
+// "0", "+1", "-2".
+func FormatCharge(q int) string {
+	if q > 0 {
+		return "+" + strconv.Itoa(q)
+	}
+	return strconv.Itoa(q)
+}
+
+// ChargeLabel renders a charge as ChEBI names write it, without the
+// parentheses: "1+", "2-", and "0" for a neutral entity.
+func ChargeLabel(q int) string {
+	switch {
+	case q > 0:
+		return strconv.Itoa(q) + "+"
+	case q < 0:
+		return strconv.Itoa(-q) + "-"
+	}
+	return "0"
+}
+
+// NormalizeCharge rewrites a charge value in FormatCharge form, or returns
+// it trimmed but otherwise unchanged if it does not parse.
+func NormalizeCharge(s string) string {
+	if q, err := ParseCharge(s); err == nil {
+		return FormatCharge(q)
+	}
+	return strings.TrimSpace(s)
+}
+
+// ParseMass reads a mass in daltons. Unicode minus signs and digit-group
+// spaces are accepted, and a decimal comma ("180,156") is read as a point;
+// a comma alongside a point is a thousands separator.
+func ParseMass(s string) (float64, error) {
+	v := strings.ReplaceAll(strings.TrimSpace(signReplacer.Replace(s)), " ", "")
+	if strings.Contains(v, ".") {
+		v = strings.ReplaceAll(v, ",", "")
+	} else if strings.Count(v, ",") == 1 {
+		v = strings.Replace(v, ",", ".", 1)
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid mass %q", s)
+	}
+	return f, nil
+}
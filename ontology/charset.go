@@ -0,0 +1,121 @@
+package ontology
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"unicode/utf8"
+)
+
+// CharsetPolicy says what the OBO parser does with lines that are not valid
+// UTF-8, typically hand-edited extensions saved as Windows-1252. Either
+// way a leading byte order mark is dropped and lines may end in LF, CRLF
+// or a lone CR.
+type CharsetPolicy string
+
+const (
+	// CharsetReport keeps the bytes as they are and reports each such
+	// line as WarnEncoding.
+	CharsetReport CharsetPolicy = ""
+	// CharsetTranscode reads every byte that is not part of a valid UTF-8
+	// sequence as Windows-1252, so "caf\xe9" becomes "café". Valid UTF-8
+	// on the same line is kept, for files mixing the two.
+	CharsetTranscode CharsetPolicy = "transcode"
+)
+
+// ParseCharsetPolicy reads a -charset flag value: report (or "") or
+// transcode.
+func ParseCharsetPolicy(s string) (CharsetPolicy, error) {
+	switch p := CharsetPolicy(s); p {
+	case CharsetReport, CharsetTranscode:
+		return p, nil
+	case "report":
+		return CharsetReport, nil
+	}
+	return "", fmt.Errorf("invalid charset policy %q (want report or transcode)", s)
+}
+
+var utf8BOM = []byte("\ufeff")
+
+// splitOBOLines returns the bufio.SplitFunc of the OBO parser: lines ended
+// by LF, CRLF or a lone CR, the first without a byte order mark, and
+// invalid UTF-8 handled by policy.
+func splitOBOLines(policy CharsetPolicy, warn *warner) bufio.SplitFunc {
+	line := 0
+	token := func(advance int, tok []byte) (int, []byte, error) {
+		line++
+		if line == 1 {
+			tok = bytes.TrimPrefix(tok, utf8BOM)
+		}
+		if !utf8.Valid(tok) {
+			if policy == CharsetTranscode {
+				tok = transcodeWindows1252(tok)
+			} else {
+				col := invalidUTF8(tok)
+				warn.add(WarnEncoding, "", "line %d: byte 0x%02X at column %d is not UTF-8", line, tok[col], col+1)
+			}
+		}
+		return advance, tok, nil
+	}
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		lf := bytes.IndexByte(data, '\n')
+		end := lf
+		if lf < 0 {
+			end = len(data)
+		}
+		if cr := bytes.IndexByte(data[:end], '\r'); cr >= 0 {
+			switch {
+			case cr+1 == lf:
+				return token(lf+1, data[:cr])
+			case cr+1 < len(data) || atEOF:
+				return token(cr+1, data[:cr]) // a lone CR
+			}
+			return 0, nil, nil // wait for the byte after the CR
+		}
+		switch {
+		case lf >= 0:
+			return token(lf+1, data[:lf])
+		case atEOF && len(data) > 0:
+			return token(len(data), data)
+		}
+		return 0, nil, nil
+	}
+}
+
+// invalidUTF8 returns the offset of the first byte of b that does not
+// start a valid UTF-8 sequence.
+func invalidUTF8(b []byte) int {
+	for i := 0; i < len(b); {
+		r, size := utf8.DecodeRune(b[i:])
+		if r == utf8.RuneError && size == 1 {
+			return i
+		}
+		i += size
+	}
+	return 0
+}
+
+// windows1252 maps the bytes 0x80-0x9F, where Windows-1252 differs from
+// Latin-1, to runes. Its five unassigned bytes map to the C1 controls of
+// the same value, as WHATWG's decoder does.
+var windows1252 = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8D, 'Ž', 0x8F,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9D, 'ž', 'Ÿ',
+}
+
+// transcodeWindows1252 returns b with every byte that is not part of a
+// valid UTF-8 sequence decoded as Windows-1252.
+func transcodeWindows1252(b []byte) []byte {
+	out := make([]byte, 0, len(b)+8)
+	for i := 0; i < len(b); {
+		r, size := utf8.DecodeRune(b[i:])
+		if r == utf8.RuneError && size == 1 {
+			if r = rune(b[i]); r >= 0x80 && r < 0xA0 {
+				r = windows1252[r-0x80]
+			}
+		}
+		out = utf8.AppendRune(out, r)
+		i += size
+	}
+	return out
+}
@@ -0,0 +1,182 @@
+//go:build !js
+
+package ontology
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ChunkManifest describes the files written by WriteJSONChunks. Loading
+// every chunk in order and concatenating their terms gives the ontology
+// back; typedefs and individuals are in the first chunk.
+type ChunkManifest struct {
+	FormatVersion string            `json:"format_version,omitempty"`
+	DataVersion   string            `json:"data_version,omitempty"`
+	Ontology      string            `json:"ontology,omitempty"`
+	Metadata      *OntologyMetadata `json:"metadata,omitempty"`
+	MaxBytes      int64             `json:"max_bytes"`
+	Terms         int               `json:"terms"`
+	Chunks        []ChunkFile       `json:"chunks"`
+}
+
+// ChunkFile is one chunk: a complete ontology JSON document holding a
+// contiguous run of terms.
+type ChunkFile struct {
+	File    string `json:"file"`
+	Terms   int    `json:"terms"`
+	Bytes   int64  `json:"bytes"`
+	SHA256  string `json:"sha256"`
+	FirstID string `json:"first_id,omitempty"`
+	LastID  string `json:"last_id,omitempty"`
+}
+
+// ChunkManifestFile is the name of the manifest WriteJSONChunks writes
+// next to the chunks.
+const ChunkManifestFile = "manifest.json"
+
+// WriteJSONChunks writes the ontology into dir as numbered JSON files
+// (<prefix>-0001.json, ...), each a complete document in the WriteJSON
+// format of at most maxBytes, plus a manifest listing them. A term whose
+// encoding alone exceeds maxBytes gets a chunk of its own, which is then
+// the only chunk over the limit.
+func WriteJSONChunks(ont *Ontology, dir, prefix string, maxBytes int64) (*ChunkManifest, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	m := &ChunkManifest{
+		FormatVersion: ont.FormatVersion,
+		DataVersion:   ont.DataVersion,
+		Ontology:      ont.Ontology,
+		Metadata:      ont.Metadata,
+		MaxBytes:      maxBytes,
+		Terms:         len(ont.Terms),
+		Chunks:        []ChunkFile{},
+	}
+
+	// Encode the pieces around the term array once to know their sizes.
+	var scratch bytes.Buffer
+	enc := &jsonWriter{w: bufio.NewWriter(&scratch), buf: make([]byte, 0, 256)}
+	encode := func(fn func()) []byte {
+		scratch.Reset()
+		fn()
+		enc.w.Flush()
+		return append([]byte(nil), scratch.Bytes()...)
+	}
+	head := encode(func() { enc.ontologyHead(ont) })
+	firstTail := encode(func() { enc.ontologyTail(fieldState{jw: enc}, ont.TypeDefs, ont.Individuals) })
+	tail := encode(func() { enc.ontologyTail(fieldState{jw: enc}, nil, nil) })
+
+	cw := &chunkWriter{dir: dir, prefix: prefix, manifest: m}
+	for i := range ont.Terms {
+		t := &ont.Terms[i]
+		term := encode(func() { enc.term(t) })
+		closing := tail
+		if len(m.Chunks) == 0 {
+			closing = firstTail
+		}
+		if cw.f != nil && cw.size+1+int64(len(term))+1+int64(len(closing)) > maxBytes {
+			if err := cw.close(closing); err != nil {
+				return nil, err
+			}
+		}
+		if cw.f == nil {
+			if err := cw.open(head); err != nil {
+				return nil, err
+			}
+		}
+		if err := cw.term(t.ID, term); err != nil {
+			return nil, err
+		}
+	}
+	if cw.f == nil {
+		if err := cw.open(head); err != nil {
+			return nil, err
+		}
+	}
+	closing := tail
+	if len(m.Chunks) == 0 {
+		closing = firstTail
+	}
+	if err := cw.close(closing); err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return m, os.WriteFile(filepath.Join(dir, ChunkManifestFile), append(data, '\n'), 0o644)
+}
+
+// chunkWriter writes the currently open chunk and records it in the
+// manifest when it is closed.
+type chunkWriter struct {
+	dir, prefix string
+	manifest    *ChunkManifest
+
+	f     *os.File
+	w     *bufio.Writer
+	hash  hash.Hash
+	size  int64
+	entry ChunkFile
+}
+
+func (cw *chunkWriter) open(head []byte) error {
+	name := fmt.Sprintf("%s-%04d.json", cw.prefix, len(cw.manifest.Chunks)+1)
+	f, err := os.Create(filepath.Join(cw.dir, name))
+	if err != nil {
+		return err
+	}
+	cw.f, cw.hash = f, sha256.New()
+	cw.w = bufio.NewWriterSize(io.MultiWriter(f, cw.hash), writerBufferSize)
+	cw.entry = ChunkFile{File: name}
+	cw.size = 0
+	return cw.write(append(head, '['))
+}
+
+func (cw *chunkWriter) term(id string, data []byte) error {
+	if cw.entry.Terms > 0 {
+		if err := cw.write([]byte{','}); err != nil {
+			return err
+		}
+	}
+	if cw.entry.Terms == 0 {
+		cw.entry.FirstID = id
+	}
+	cw.entry.LastID = id
+	cw.entry.Terms++
+	return cw.write(data)
+}
+
+func (cw *chunkWriter) close(closing []byte) error {
+	err := cw.write(append([]byte{']'}, closing...))
+	if err == nil {
+		err = cw.w.Flush()
+	}
+	if cerr := cw.f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	cw.entry.Bytes = cw.size
+	cw.entry.SHA256 = hex.EncodeToString(cw.hash.Sum(nil))
+	cw.manifest.Chunks = append(cw.manifest.Chunks, cw.entry)
+	cw.f = nil
+	return nil
+}
+
+func (cw *chunkWriter) write(p []byte) error {
+	n, err := cw.w.Write(p)
+	cw.size += int64(n)
+	return err
+}
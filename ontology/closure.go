@@ -0,0 +1,65 @@
+package ontology
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+)
+
+// ClosureRow is one entry of a transitive closure table: AncestorID is
+// reachable from TermID by Distance edges of type Relation.
+type ClosureRow struct {
+	TermID     string
+	AncestorID string
+	Distance   int
+	Relation   string
+}
+
+// Closure computes the asserted transitive closure of each relation type
+// independently, following only edges of that type, as Graph.Closure
+// does over NewGraph's edges, obsolete terms included. Rows are produced
+// per term in ontology order. The is_a closure leaves out terms under
+// IndexOptions.ExcludeBranches, as term and as ancestor.
+func (ix *Index) Closure(relations []string, fn func(ClosureRow)) {
+	if len(relations) == 0 {
+		return
+	}
+	g := NewGraphWithOptions(ix.ont, GraphOptions{
+		Obsolete: ObsoleteInclude,
+		Keep: func(from string, rel *Relationship) bool {
+			return rel.Type != "is_a" || !ix.Excluded(from) && !ix.Excluded(rel.TargetID)
+		},
+	})
+	g.Closure(EdgeFilter{Relations: relations}, fn)
+}
+
+// WriteClosureTSV writes the asserted closure for the given relation types
+// as tab-separated term_id, ancestor_id, distance, relation with a header.
+func WriteClosureTSV(ont *Ontology, w io.Writer, relations []string) error {
+	return WriteIndexClosureTSV(NewIndex(ont), w, relations)
+}
+
+// WriteIndexClosureTSV is WriteClosureTSV over an existing index, so its
+// IndexOptions (such as ExcludeBranches) apply.
+func WriteIndexClosureTSV(ix *Index, w io.Writer, relations []string) error {
+	bw := bufio.NewWriterSize(w, writerBufferSize)
+	if _, err := bw.WriteString("term_id\tancestor_id\tdistance\trelation\n"); err != nil {
+		return err
+	}
+	ix.Closure(relations, func(row ClosureRow) {
+		WriteClosureRow(bw, row)
+	})
+	return bw.Flush()
+}
+
+// WriteClosureRow writes a single closure row in the WriteClosureTSV layout.
+func WriteClosureRow(bw *bufio.Writer, row ClosureRow) {
+	bw.WriteString(row.TermID)
+	bw.WriteByte('\t')
+	bw.WriteString(row.AncestorID)
+	bw.WriteByte('\t')
+	bw.WriteString(strconv.Itoa(row.Distance))
+	bw.WriteByte('\t')
+	bw.WriteString(row.Relation)
+	bw.WriteByte('\n')
+}
@@ -0,0 +1,206 @@
+package ontology
+
+// IndexOptions configure NewIndexWithOptions.
+type IndexOptions struct {
+	// Columnar builds the is_a graph as Columns (flat int32 arrays) instead
+	// of a map of child slices, trading a slightly slower build for less
+	// memory and cache-friendlier traversal in long-running services.
+	Columnar bool
+	// Inverses makes Edges also return the inverse of every relationship
+	// whose type has a declared inverse_of, so callers need not know which
+	// direction the ontology asserts.
+	Inverses bool
+	// Obsolete terms are indexed unless this is ObsoleteExclude. The index
+	// is then built over a copy of the ontology without them, which
+	// Ontology returns, so lookups, traversal and name search all skip
+	// them.
+	Obsolete ObsoletePolicy
+	// ExcludeBranches lists is_a roots (such as ChEBIRole) whose subtrees
+	// are kept out of classification: the is_a rows of Closure, Rollup and
+	// InformativeAncestors treat their terms (see Excluded) as neither
+	// inputs, bins nor ancestors, so role classes do not mix into chemical
+	// groupings.
+	// Traversal (Ancestors, Children) and other relations still see them.
+	ExcludeBranches []string
+	// Labels is the DisplayName preference for the term labels the index
+	// puts in reports, cards, trees, rollups, relation matrices, path text
+	// and name search results (see Label), such as INN first for a
+	// clinician-facing UI. Empty means the primary name.
+	Labels LabelPrefs
+	// Build lists the sub-indexes to build before NewIndexWithOptions
+	// returns, in parallel, instead of on first use: for services that
+	// would rather pay at load time than on their first requests. With
+	// IndexSearch, UseSearchIndex can no longer be used.
+	Build IndexParts
+}
+
+// Columns is a struct-of-arrays view of an ontology. Nodes are numbered
+// terms first, in ont.Terms order, then relationship targets that are not
+// terms (dangling parents, individuals), so every edge has a node at both
+// ends. Adjacency is stored as CSR: the entries of node n are
+// X[XOffsets[n]:XOffsets[n+1]].
+type Columns struct {
+	IDs        []string // per node
+	Names      []string // per term
+	Namespaces []string // per term, interned
+	Obsolete   []bool   // per term
+
+	// Relationships of each term, including is_a. RelTypes index
+	// RelTypeNames; RelTargets is a node, or -1 for Self.
+	RelTypeNames []string
+	RelOffsets   []int32
+	RelTypes     []int32
+	RelTargets   []int32
+
+	// Asserted is_a edges in both directions.
+	ParentOffsets []int32
+	Parents       []int32
+	ChildOffsets  []int32
+	Children      []int32
+
+	byID map[string]int32
+}
+
+// NewColumns builds the columnar view of ont. It copies no term bodies;
+// the strings are shared with ont.
+func NewColumns(ont *Ontology) *Columns {
+	n := len(ont.Terms)
+	c := &Columns{
+		IDs:        make([]string, n, n+n/8),
+		Names:      make([]string, n),
+		Namespaces: make([]string, n),
+		Obsolete:   make([]bool, n),
+		RelOffsets: make([]int32, n+1),
+		byID:       make(map[string]int32, n),
+	}
+	for i := range ont.Terms {
+		t := &ont.Terms[i]
+		c.IDs[i], c.Names[i], c.Namespaces[i], c.Obsolete[i] = t.ID, t.Name, t.Namespace, t.IsObsolete
+		c.byID[t.ID] = int32(i) // the last stanza wins, as in Index
+	}
+
+	relType := make(map[string]int32)
+	node := func(id string) int32 {
+		if k, ok := c.byID[id]; ok {
+			return k
+		}
+		k := int32(len(c.IDs))
+		c.IDs = append(c.IDs, id)
+		c.byID[id] = k
+		return k
+	}
+	isA := int32(-1)
+	for i := range ont.Terms {
+		for _, rel := range ont.Terms[i].Relationships {
+			typ, ok := relType[rel.Type]
+			if !ok {
+				typ = int32(len(c.RelTypeNames))
+				relType[rel.Type] = typ
+				c.RelTypeNames = append(c.RelTypeNames, rel.Type)
+				if rel.Type == "is_a" {
+					isA = typ
+				}
+			}
+			target := int32(-1)
+			if !rel.Self {
+				target = node(rel.TargetID)
+			}
+			c.RelTypes = append(c.RelTypes, typ)
+			c.RelTargets = append(c.RelTargets, target)
+		}
+		c.RelOffsets[i+1] = int32(len(c.RelTypes))
+	}
+
+	// Count is_a edges per node, then fill both directions.
+	nodes := len(c.IDs)
+	c.ParentOffsets = make([]int32, nodes+1)
+	c.ChildOffsets = make([]int32, nodes+1)
+	for i := 0; i < n; i++ {
+		for r := c.RelOffsets[i]; r < c.RelOffsets[i+1]; r++ {
+			if c.RelTypes[r] == isA && c.RelTargets[r] >= 0 {
+				c.ParentOffsets[i+1]++
+				c.ChildOffsets[c.RelTargets[r]+1]++
+			}
+		}
+	}
+	for k := 0; k < nodes; k++ {
+		c.ParentOffsets[k+1] += c.ParentOffsets[k]
+		c.ChildOffsets[k+1] += c.ChildOffsets[k]
+	}
+	c.Parents = make([]int32, c.ParentOffsets[nodes])
+	c.Children = make([]int32, c.ChildOffsets[nodes])
+	nextChild := append([]int32(nil), c.ChildOffsets[:nodes]...)
+	for i := 0; i < n; i++ {
+		p := c.ParentOffsets[i]
+		for r := c.RelOffsets[i]; r < c.RelOffsets[i+1]; r++ {
+			if c.RelTypes[r] == isA && c.RelTargets[r] >= 0 {
+				target := c.RelTargets[r]
+				c.Parents[p] = target
+				p++
+				c.Children[nextChild[target]] = int32(i)
+				nextChild[target]++
+			}
+		}
+	}
+	return c
+}
+
+// Node returns the node number of id.
+func (c *Columns) Node(id string) (int32, bool) {
+	k, ok := c.byID[id]
+	return k, ok
+}
+
+// ParentsOf returns the asserted is_a parents of node k.
+func (c *Columns) ParentsOf(k int32) []int32 {
+	return c.Parents[c.ParentOffsets[k]:c.ParentOffsets[k+1]]
+}
+
+// ChildrenOf returns the asserted is_a children of node k.
+func (c *Columns) ChildrenOf(k int32) []int32 {
+	return c.Children[c.ChildOffsets[k]:c.ChildOffsets[k+1]]
+}
+
+// RelationshipsOf returns the relationship types and targets of node k,
+// which are empty for nodes that are not terms.
+func (c *Columns) RelationshipsOf(k int32) (types, targets []int32) {
+	if int(k) >= len(c.Names) {
+		return nil, nil
+	}
+	lo, hi := c.RelOffsets[k], c.RelOffsets[k+1]
+	return c.RelTypes[lo:hi], c.RelTargets[lo:hi]
+}
+
+// ids maps node numbers to IDs.
+func (c *Columns) ids(nodes []int32) []string {
+	if len(nodes) == 0 {
+		return nil
+	}
+	out := make([]string, len(nodes))
+	for i, k := range nodes {
+		out[i] = c.IDs[k]
+	}
+	return out
+}
+
+// ancestors returns the transitive is_a ancestors of node k in
+// breadth-first order, excluding k.
+func (c *Columns) ancestors(k int32) []int32 {
+	seen := map[int32]bool{k: true}
+	var out []int32
+	for _, p := range c.ParentsOf(k) {
+		if !seen[p] {
+			seen[p] = true
+			out = append(out, p)
+		}
+	}
+	for i := 0; i < len(out); i++ {
+		for _, p := range c.ParentsOf(out[i]) {
+			if !seen[p] {
+				seen[p] = true
+				out = append(out, p)
+			}
+		}
+	}
+	return out
+}
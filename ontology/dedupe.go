@@ -0,0 +1,262 @@
+package ontology
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DedupeConflict is a field on which two stanzas for the same ID disagree.
+// The first stanza's value is kept.
+type DedupeConflict struct {
+	ID      string `json:"id"`
+	Field   string `json:"field"`
+	Kept    string `json:"kept"`
+	Dropped string `json:"dropped"`
+}
+
+// DedupeReport summarizes a Dedupe pass. Removed counts dropped duplicate
+// values per field, using the JSON field names ("relationships",
+// "synonyms", "xrefs", ...).
+type DedupeReport struct {
+	MergedTerms       int              `json:"merged_terms"`
+	MergedTypeDefs    int              `json:"merged_typedefs"`
+	MergedIndividuals int              `json:"merged_individuals"`
+	Removed           map[string]int   `json:"removed"`
+	Conflicts         []DedupeConflict `json:"conflicts,omitempty"`
+}
+
+// Dedupe merges stanzas that share an ID, as merged multi-file inputs
+// often contain, and removes duplicate values within each term. Merging
+// keeps the first stanza's position and scalar values, filling in empty
+// ones from later stanzas and reporting disagreements as conflicts; list
+// fields are unioned. Relationships are duplicates when they agree on
+// type, filler and cardinality, synonyms when they agree on text, scope
+// and type; duplicates are folded into the first (xrefs and qualifiers
+// unioned). It modifies ont in place.
+func Dedupe(ont *Ontology) *DedupeReport {
+	rep := &DedupeReport{Removed: make(map[string]int)}
+
+	pos := make(map[string]int, len(ont.Terms))
+	terms := ont.Terms[:0]
+	for i := range ont.Terms {
+		t := ont.Terms[i]
+		if j, ok := pos[t.ID]; ok {
+			mergeTerm(&terms[j], &t, rep)
+			rep.MergedTerms++
+			continue
+		}
+		pos[t.ID] = len(terms)
+		terms = append(terms, t)
+	}
+	clear(ont.Terms[len(terms):])
+	ont.Terms = terms
+	for i := range ont.Terms {
+		dedupeTerm(&ont.Terms[i], rep)
+	}
+
+	tdPos := make(map[string]int, len(ont.TypeDefs))
+	typeDefs := ont.TypeDefs[:0]
+	for _, td := range ont.TypeDefs {
+		j, ok := tdPos[td.ID]
+		if !ok {
+			tdPos[td.ID] = len(typeDefs)
+			typeDefs = append(typeDefs, td)
+			continue
+		}
+		kept := &typeDefs[j]
+		rep.scalar(td.ID, "name", &kept.Name, td.Name)
+		kept.IsTransitive = kept.IsTransitive || td.IsTransitive
+		kept.IsReflexive = kept.IsReflexive || td.IsReflexive
+		kept.HoldsOverChain = rep.union("holds_over_chain", kept.HoldsOverChain, td.HoldsOverChain)
+		rep.scalar(td.ID, "inverse_of", &kept.InverseOf, td.InverseOf)
+		rep.MergedTypeDefs++
+	}
+	ont.TypeDefs = typeDefs
+
+	indPos := make(map[string]int, len(ont.Individuals))
+	individuals := ont.Individuals[:0]
+	for _, ind := range ont.Individuals {
+		j, ok := indPos[ind.ID]
+		if !ok {
+			indPos[ind.ID] = len(individuals)
+			individuals = append(individuals, ind)
+			continue
+		}
+		kept := &individuals[j]
+		rep.scalar(ind.ID, "name", &kept.Name, ind.Name)
+		kept.Types = rep.union("types", kept.Types, ind.Types)
+		rep.MergedIndividuals++
+	}
+	ont.Individuals = individuals
+	return rep
+}
+
+// mergeTerm folds a later stanza b into a. Lists are concatenated here
+// and deduplicated afterwards by dedupeTerm.
+func mergeTerm(a, b *Term, rep *DedupeReport) {
+	rep.scalar(a.ID, "name", &a.Name, b.Name)
+	rep.scalar(a.ID, "namespace", &a.Namespace, b.Namespace)
+	rep.scalar(a.ID, "definition", &a.Definition, b.Definition)
+	rep.scalar(a.ID, "comment", &a.Comment, b.Comment)
+	if a.IsObsolete != b.IsObsolete {
+		rep.conflict(a.ID, "is_obsolete", strconv.FormatBool(a.IsObsolete), strconv.FormatBool(b.IsObsolete))
+	}
+	a.ReplacedBy = append(a.ReplacedBy, b.ReplacedBy...)
+	a.Consider = append(a.Consider, b.Consider...)
+	a.Subsets = append(a.Subsets, b.Subsets...)
+	a.Synonyms = append(a.Synonyms, b.Synonyms...)
+	a.Xrefs = append(a.Xrefs, b.Xrefs...)
+	a.AltIDs = append(a.AltIDs, b.AltIDs...)
+	a.Relationships = append(a.Relationships, b.Relationships...)
+	a.UnionOf = append(a.UnionOf, b.UnionOf...)
+	a.OneOf = append(a.OneOf, b.OneOf...)
+	a.NonEL = append(a.NonEL, b.NonEL...)
+
+	// Two different definitions are not one longer definition.
+	switch {
+	case len(a.IntersectionOf) == 0:
+		a.IntersectionOf = b.IntersectionOf
+	case len(b.IntersectionOf) > 0 && intersectionKey(a.IntersectionOf) != intersectionKey(b.IntersectionOf):
+		rep.conflict(a.ID, "intersection_of", intersectionKey(a.IntersectionOf), intersectionKey(b.IntersectionOf))
+	}
+
+	for k := range b.Properties {
+		v, _ := b.Property(k)
+		if old, ok := a.Property(k); !ok {
+			a.setProperty(k, v.Value, v.Datatype)
+		} else if old != v {
+			rep.conflict(a.ID, "properties."+k, old.String(), v.String())
+		}
+	}
+	for x, q := range b.XrefQualifiers {
+		if a.XrefQualifiers == nil {
+			a.XrefQualifiers = make(map[string]map[string]string, len(b.XrefQualifiers))
+		}
+		a.XrefQualifiers[x] = mergeQualifiers(a.XrefQualifiers[x], q)
+	}
+	if a.Links == nil {
+		a.Links = b.Links
+	}
+	if a.Obsolescence == nil {
+		a.Obsolescence = b.Obsolescence
+	}
+}
+
+// dedupeTerm removes duplicate values from t's list fields.
+func dedupeTerm(t *Term, rep *DedupeReport) {
+	t.ReplacedBy = rep.union("replaced_by", t.ReplacedBy, nil)
+	t.Consider = rep.union("consider", t.Consider, nil)
+	t.Subsets = rep.union("subsets", t.Subsets, nil)
+	t.Xrefs = rep.union("xrefs", t.Xrefs, nil)
+	t.AltIDs = rep.union("alt_ids", t.AltIDs, nil)
+	t.UnionOf = rep.union("union_of", t.UnionOf, nil)
+	t.OneOf = rep.union("one_of", t.OneOf, nil)
+
+	synPos := make(map[string]int, len(t.Synonyms))
+	syns := t.Synonyms[:0]
+	for _, syn := range t.Synonyms {
+		key := syn.Text + "\x00" + syn.Scope + "\x00" + syn.Type
+		if j, ok := synPos[key]; ok {
+			syns[j].Xrefs = rep.union("", syns[j].Xrefs, syn.Xrefs)
+			rep.Removed["synonyms"]++
+			continue
+		}
+		synPos[key] = len(syns)
+		syns = append(syns, syn)
+	}
+	clear(t.Synonyms[len(syns):])
+	t.Synonyms = syns
+
+	relPos := make(map[string]int, len(t.Relationships))
+	rels := t.Relationships[:0]
+	for _, rel := range t.Relationships {
+		key := rel.Type + " " + fillerKey(rel.TargetID, rel.HasValue, rel.Self)
+		if rel.Cardinality != nil {
+			key += " " + rel.Cardinality.String()
+		}
+		if j, ok := relPos[key]; ok {
+			if rels[j].Name == "" {
+				rels[j].Name = rel.Name
+			}
+			rels[j].Qualifiers = mergeQualifiers(rels[j].Qualifiers, rel.Qualifiers)
+			rep.Removed["relationships"]++
+			continue
+		}
+		relPos[key] = len(rels)
+		rels = append(rels, rel)
+	}
+	clear(t.Relationships[len(rels):])
+	t.Relationships = rels
+
+	seen := make(map[IntersectionPart]bool, len(t.IntersectionOf))
+	parts := t.IntersectionOf[:0]
+	for _, p := range t.IntersectionOf {
+		if seen[p] {
+			rep.Removed["intersection_of"]++
+			continue
+		}
+		seen[p] = true
+		parts = append(parts, p)
+	}
+	t.IntersectionOf = parts
+}
+
+// union appends the values of b missing from a and drops repeats within
+// a, counting the drops under field unless field is "".
+func (rep *DedupeReport) union(field string, a, b []string) []string {
+	if len(a)+len(b) == 0 {
+		return a
+	}
+	seen := make(map[string]bool, len(a)+len(b))
+	out := a[:0:len(a)] // reuse a, but never write past it
+	for _, list := range [][]string{a, b} {
+		for _, v := range list {
+			if seen[v] {
+				if field != "" {
+					rep.Removed[field]++
+				}
+				continue
+			}
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// scalar fills *kept from other if it is empty, and records a conflict if
+// both are set and differ.
+func (rep *DedupeReport) scalar(id, field string, kept *string, other string) {
+	switch {
+	case *kept == "":
+		*kept = other
+	case other != "" && other != *kept:
+		rep.conflict(id, field, *kept, other)
+	}
+}
+
+func (rep *DedupeReport) conflict(id, field, kept, dropped string) {
+	rep.Conflicts = append(rep.Conflicts, DedupeConflict{ID: id, Field: field, Kept: kept, Dropped: dropped})
+}
+
+// mergeQualifiers adds the qualifiers of b missing from a.
+func mergeQualifiers(a, b map[string]string) map[string]string {
+	for k, v := range b {
+		if a == nil {
+			a = make(map[string]string, len(b))
+		}
+		if _, ok := a[k]; !ok {
+			a[k] = v
+		}
+	}
+	return a
+}
+
+// intersectionKey renders a definition for comparison and reports,
+// ignoring the order of its parts.
+func intersectionKey(parts []IntersectionPart) string {
+	keys := intersectionKeys(&Term{IntersectionOf: parts})
+	sort.Strings(keys)
+	return strings.Join(keys, " and ")
+}
@@ -0,0 +1,128 @@
+package ontology
+
+import (
+	"sort"
+	"strings"
+)
+
+// ClassRef is a class ID with its label.
+type ClassRef struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+// Differentia is one ∃Relation.Target conjunct of a definition.
+type Differentia struct {
+	Relation     string `json:"relation"`
+	RelationName string `json:"relation_name,omitempty"`
+	Target       string `json:"target,omitempty"` // empty for ∃Relation.Self
+	TargetName   string `json:"target_name,omitempty"`
+	Self         bool   `json:"self,omitempty"`
+}
+
+// Definition is a defined class (one with intersection_of) in normalized
+// form: genus classes and differentiae, each deduplicated and sorted.
+// Issues lists problems a curator should look at, such as a missing genus
+// or a conjunct that points at an unknown or obsolete term.
+type Definition struct {
+	ID          string        `json:"id"`
+	Name        string        `json:"name,omitempty"`
+	Genus       []ClassRef    `json:"genus"`
+	Differentia []Differentia `json:"differentia,omitempty"`
+	Text        string        `json:"text"` // Manchester syntax
+	Issues      []string      `json:"issues,omitempty"`
+}
+
+// Definitions returns the normalized definition of every non-obsolete
+// defined class, in ontology order.
+func (ix *Index) Definitions() []Definition {
+	relNames := make(map[string]string, len(ix.ont.TypeDefs))
+	for i := range ix.ont.TypeDefs {
+		relNames[ix.ont.TypeDefs[i].ID] = ix.ont.TypeDefs[i].Name
+	}
+	indNames := make(map[string]string, len(ix.ont.Individuals))
+	for i := range ix.ont.Individuals {
+		indNames[ix.ont.Individuals[i].ID] = ix.ont.Individuals[i].Name
+	}
+
+	var defs []Definition
+	for i := range ix.ont.Terms {
+		t := &ix.ont.Terms[i]
+		if len(t.IntersectionOf) == 0 || t.IsObsolete {
+			continue
+		}
+		d := Definition{ID: t.ID, Name: t.Name, Genus: []ClassRef{}}
+		seen := make(map[IntersectionPart]bool, len(t.IntersectionOf))
+		for _, part := range t.IntersectionOf {
+			if seen[part] {
+				continue
+			}
+			seen[part] = true
+
+			name := ""
+			if part.TargetID != "" {
+				var issue string
+				name, issue = ix.refName(part.TargetID, indNames)
+				if issue != "" {
+					d.Issues = append(d.Issues, issue)
+				}
+			}
+			if part.Relationship == "" {
+				d.Genus = append(d.Genus, ClassRef{ID: part.TargetID, Name: name})
+				continue
+			}
+			d.Differentia = append(d.Differentia, Differentia{
+				Relation:     part.Relationship,
+				RelationName: relNames[part.Relationship],
+				Target:       part.TargetID,
+				TargetName:   name,
+				Self:         part.Self,
+			})
+		}
+		if len(d.Genus) == 0 {
+			d.Issues = append(d.Issues, "no genus")
+		}
+		sort.Slice(d.Genus, func(i, j int) bool { return d.Genus[i].ID < d.Genus[j].ID })
+		sort.Slice(d.Differentia, func(i, j int) bool {
+			a, b := d.Differentia[i], d.Differentia[j]
+			if a.Relation != b.Relation {
+				return a.Relation < b.Relation
+			}
+			return a.Target < b.Target
+		})
+		d.Text = d.manchester()
+		defs = append(defs, d)
+	}
+	return defs
+}
+
+// refName returns the label of a conjunct's target and, if the target is
+// not a live term or individual, an issue describing it.
+func (ix *Index) refName(id string, indNames map[string]string) (name, issue string) {
+	if t := ix.Term(id); t != nil {
+		if t.IsObsolete {
+			return t.Name, "obsolete term " + id
+		}
+		return t.Name, ""
+	}
+	if name, ok := indNames[id]; ok {
+		return name, ""
+	}
+	return "", "unknown term " + id
+}
+
+// manchester renders the definition as "G1 and (R some T) and (S Self)".
+func (d *Definition) manchester() string {
+	parts := make([]string, 0, len(d.Genus)+len(d.Differentia))
+	for _, g := range d.Genus {
+		parts = append(parts, g.ID)
+	}
+	for _, df := range d.Differentia {
+		if df.Self {
+			parts = append(parts, "("+df.Relation+" Self)")
+		} else {
+			parts = append(parts, "("+df.Relation+" some "+df.Target+")")
+		}
+	}
+	return strings.Join(parts, " and ")
+}
@@ -0,0 +1,178 @@
+package ontology
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Direction is the way an edge is read. OBO and OWL assert every
+// relationship on its subject, the child of the pair ("X part_of Y" is
+// listed under X); a parent-side view lists it under Y instead, as Y
+// has_part X. Stating the direction with each edge keeps consumers from
+// reading part_of where has_part was meant.
+type Direction string
+
+const (
+	// DirectionUp lists an edge under its subject, as asserted: child
+	// lists parent.
+	DirectionUp Direction = "up"
+	// DirectionDown lists an edge under its target: parent lists child.
+	DirectionDown Direction = "down"
+)
+
+// ParseDirection reads a direction flag or query value: up (or
+// child-to-parent) or down (or parent-to-child).
+func ParseDirection(s string) (Direction, error) {
+	switch s {
+	case "up", "child-to-parent":
+		return DirectionUp, nil
+	case "down", "parent-to-child":
+		return DirectionDown, nil
+	}
+	return "", fmt.Errorf("invalid direction %q (want up or down)", s)
+}
+
+// DirectedEdge is a relationship listed under Subject and read as Subject
+// Relation Object, unless Reversed is set. Asserted is the relationship
+// type the ontology states, always on the child: for an up edge it is
+// Relation; for a down edge Relation is its declared inverse, or, if it
+// has none, Asserted itself with Reversed set, to be read Object Relation
+// Subject.
+type DirectedEdge struct {
+	Subject   string    `json:"subject"`
+	Relation  string    `json:"relation"`
+	Object    string    `json:"object"`
+	Direction Direction `json:"direction"`
+	Asserted  string    `json:"asserted"`
+	Reversed  bool      `json:"reversed,omitempty"`
+}
+
+// Flip returns e listed under its other end. inv is InverseRelations of
+// the ontology.
+func (e DirectedEdge) Flip(inv map[string]string) DirectedEdge {
+	f := DirectedEdge{Subject: e.Object, Object: e.Subject, Asserted: e.Asserted}
+	if e.Direction == DirectionDown {
+		f.Direction = DirectionUp
+		f.Relation = e.Asserted
+		return f
+	}
+	f.Direction = DirectionDown
+	if r, ok := inv[e.Asserted]; ok {
+		f.Relation = r
+	} else {
+		f.Relation, f.Reversed = e.Asserted, true
+	}
+	return f
+}
+
+// DirectedEdges lists the relationships of id, is_a included, read in
+// direction d: up, those id asserts, in asserted order; down, those other
+// terms assert to id (or one of its alt IDs), flipped, sorted by the
+// other term and relation.
+func (ix *Index) DirectedEdges(id string, d Direction) []DirectedEdge {
+	primary := ix.Primary(id)
+	if primary == "" {
+		primary = id
+	}
+	var out []DirectedEdge
+	if d == DirectionUp {
+		if t := ix.Term(primary); t != nil {
+			for _, rel := range t.Relationships {
+				out = append(out, DirectedEdge{Subject: t.ID, Relation: rel.Type, Object: rel.TargetID, Direction: DirectionUp, Asserted: rel.Type})
+			}
+		}
+		return out
+	}
+	inv := InverseRelations(ix.ont)
+	for _, r := range ix.ReferencedBy(primary) {
+		if r.Field == RefRelationship {
+			up := DirectedEdge{Subject: r.ID, Relation: r.Relation, Object: primary, Direction: DirectionUp, Asserted: r.Relation}
+			out = append(out, up.Flip(inv))
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Object != out[j].Object {
+			return out[i].Object < out[j].Object
+		}
+		return out[i].Relation < out[j].Relation
+	})
+	return out
+}
+
+// OrientRelationships makes each of types the one way its pair of
+// relations is asserted, for exports whose consumers expect, say, every
+// part-whole edge as part_of: each X R⁻ Y, where R⁻ is the declared
+// inverse of one of types R, becomes Y R X on the term Y, keeping its
+// qualifiers, unless Y already asserts it. Edges whose target is not a
+// term in ont, and has_value, Self and cardinality edges, stay as they
+// are. It returns the number of edges rewritten, or an error if one of
+// types has no declared inverse or types holds both relations of a pair.
+func OrientRelationships(ont *Ontology, types []string) (int, error) {
+	inv := InverseRelations(ont)
+	from := make(map[string]string, len(types)) // R⁻ → R
+	for _, r := range types {
+		ri, ok := inv[r]
+		if !ok {
+			return 0, fmt.Errorf("relation %q declares no inverse_of", r)
+		}
+		if ri == r {
+			continue // symmetric: both ways are the same
+		}
+		if _, clash := from[r]; clash {
+			return 0, fmt.Errorf("relations %q and %q are inverses of each other", r, ri)
+		}
+		from[ri] = r
+	}
+	if len(from) == 0 {
+		return 0, nil
+	}
+
+	pos := make(map[string]int, len(ont.Terms))
+	for i := range ont.Terms {
+		pos[ont.Terms[i].ID] = i
+	}
+	type edge struct{ typ, target string }
+	have := make(map[string]map[edge]bool)
+	for i := range ont.Terms {
+		t := &ont.Terms[i]
+		for _, rel := range t.Relationships {
+			if _, ok := inv[rel.Type]; ok {
+				if have[t.ID] == nil {
+					have[t.ID] = make(map[edge]bool)
+				}
+				have[t.ID][edge{rel.Type, rel.TargetID}] = true
+			}
+		}
+	}
+
+	added := make(map[int][]Relationship)
+	n := 0
+	for i := range ont.Terms {
+		t := &ont.Terms[i]
+		kept := t.Relationships[:0]
+		for _, rel := range t.Relationships {
+			r, ok := from[rel.Type]
+			j, isTerm := pos[rel.TargetID]
+			if !ok || !isTerm || rel.HasValue || rel.Self || rel.Cardinality != nil {
+				kept = append(kept, rel)
+				continue
+			}
+			n++
+			e := edge{r, t.ID}
+			if have[rel.TargetID][e] {
+				continue
+			}
+			if have[rel.TargetID] == nil {
+				have[rel.TargetID] = make(map[edge]bool)
+			}
+			have[rel.TargetID][e] = true
+			added[j] = append(added[j], Relationship{Type: r, TargetID: t.ID, Name: t.Name, Qualifiers: rel.Qualifiers})
+		}
+		clear(t.Relationships[len(kept):])
+		t.Relationships = kept
+	}
+	for j, rels := range added {
+		ont.Terms[j].Relationships = append(ont.Terms[j].Relationships, rels...)
+	}
+	return n, nil
+}
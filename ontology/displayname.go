@@ -0,0 +1,113 @@
+package ontology
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LabelName is the LabelPrefs entry for a term's primary name.
+const LabelName = "name"
+
+// LabelPref is one entry of a LabelPrefs: a synonym type, such as INN or
+// IUPAC_NAME, optionally only from a source (an xref or its prefix, such
+// as IUPAC for the IUPAC-recommended name), or LabelName.
+type LabelPref struct {
+	Type   string
+	Source string
+}
+
+// LabelPrefs orders the labels DisplayName picks from. The first entry a
+// term has wins; a term with none of them is shown by its name.
+type LabelPrefs []LabelPref
+
+// ParseLabelPrefs reads a -label-prefs value: comma-separated synonym
+// types, each optionally followed by @SOURCE, and name, as in
+// "INN,IUPAC_NAME@IUPAC,name".
+func ParseLabelPrefs(s string) (LabelPrefs, error) {
+	var prefs LabelPrefs
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		typ, source, _ := strings.Cut(entry, "@")
+		if typ == "" || strings.ContainsAny(typ, " \t") {
+			return nil, fmt.Errorf("invalid label preference %q (want TYPE, TYPE@SOURCE or name)", entry)
+		}
+		prefs = append(prefs, LabelPref{Type: typ, Source: source})
+	}
+	return prefs, nil
+}
+
+// String formats prefs as ParseLabelPrefs reads them.
+func (prefs LabelPrefs) String() string {
+	parts := make([]string, len(prefs))
+	for i, p := range prefs {
+		parts[i] = p.Type
+		if p.Source != "" {
+			parts[i] += "@" + p.Source
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// DisplayName returns the label to show for id under prefs: the text of
+// the first of its synonyms with the most preferred type (and source) it
+// has, its name for a LabelName entry, and its name if no entry matches.
+// Types are compared case-insensitively by local name, so an OBO Graphs
+// synonymType IRI ending in #INN matches INN. It returns "" for an unknown
+// term.
+func (ix *Index) DisplayName(id string, prefs LabelPrefs) string {
+	t := ix.Term(id)
+	if t == nil {
+		return ""
+	}
+	for _, p := range prefs {
+		if strings.EqualFold(p.Type, LabelName) {
+			if t.Name != "" {
+				return t.Name
+			}
+			continue
+		}
+		for _, syn := range t.Synonyms {
+			if strings.EqualFold(localName(syn.Type), p.Type) && synonymFrom(syn, p.Source) {
+				return syn.Text
+			}
+		}
+	}
+	return t.Name
+}
+
+// Label is id's DisplayName under IndexOptions.Labels: the label
+// reports, cards, trees, rollups, paths and name search show.
+func (ix *Index) Label(id string) string {
+	return ix.DisplayName(id, ix.labels)
+}
+
+// Labels returns IndexOptions.Labels.
+func (ix *Index) Labels() LabelPrefs {
+	return ix.labels
+}
+
+// synonymFrom reports whether syn has an xref from source, matching the
+// whole xref or its prefix; any synonym matches an empty source.
+func synonymFrom(syn Synonym, source string) bool {
+	if source == "" {
+		return true
+	}
+	for _, x := range syn.Xrefs {
+		prefix, _, _ := strings.Cut(x, ":")
+		if strings.EqualFold(x, source) || strings.EqualFold(prefix, source) {
+			return true
+		}
+	}
+	return false
+}
+
+// localName returns the part of an IRI after its last # or /, or s.
+func localName(s string) string {
+	if i := strings.LastIndexAny(s, "#/"); i >= 0 {
+		return s[i+1:]
+	}
+	return s
+}
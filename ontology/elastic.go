@@ -0,0 +1,148 @@
+package ontology
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const elasticPushBatch = 5000 // documents per _bulk request
+
+// ElasticMapping is a suggested index mapping for documents written by
+// WriteElasticBulk: names and synonyms are analyzed text with a keyword
+// sub-field for exact matching; IDs, ancestors and xrefs are keywords.
+const ElasticMapping = `{
+  "mappings": {
+    "properties": {
+      "id":          {"type": "keyword"},
+      "name":        {"type": "text", "fields": {"keyword": {"type": "keyword"}}},
+      "synonyms":    {"type": "text", "fields": {"keyword": {"type": "keyword"}}},
+      "definition":  {"type": "text"},
+      "namespace":   {"type": "keyword"},
+      "subsets":     {"type": "keyword"},
+      "xrefs":       {"type": "keyword"},
+      "alt_ids":     {"type": "keyword"},
+      "parents":     {"type": "keyword"},
+      "ancestors":   {"type": "keyword"},
+      "is_obsolete": {"type": "boolean"}
+    }
+  }
+}`
+
+// elasticDoc is the document indexed for each term.
+type elasticDoc struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name,omitempty"`
+	Synonyms   []string `json:"synonyms,omitempty"`
+	Definition string   `json:"definition,omitempty"`
+	Namespace  string   `json:"namespace,omitempty"`
+	Subsets    []string `json:"subsets,omitempty"`
+	Xrefs      []string `json:"xrefs,omitempty"`
+	AltIDs     []string `json:"alt_ids,omitempty"`
+	Parents    []string `json:"parents,omitempty"`
+	Ancestors  []string `json:"ancestors,omitempty"`
+	IsObsolete bool     `json:"is_obsolete"`
+}
+
+type elasticAction struct {
+	Index struct {
+		Index string `json:"_index"`
+		ID    string `json:"_id"`
+	} `json:"index"`
+}
+
+// WriteElasticBulk writes newline-delimited bulk-index actions for every
+// term into the named index, suitable for POSTing to the _bulk endpoint.
+func WriteElasticBulk(ont *Ontology, w io.Writer, index string) error {
+	bw := bufio.NewWriterSize(w, writerBufferSize)
+	ix := NewIndex(ont)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+	for i := range ont.Terms {
+		if err := encodeElasticTerm(enc, ix, &ont.Terms[i], index); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// PushElasticBulk sends bulk-index actions for every term directly to the
+// cluster at baseURL (e.g. http://localhost:9200), in batches. It fails on
+// the first batch the cluster rejects or reports item errors for.
+func PushElasticBulk(ont *Ontology, baseURL, index string) error {
+	url := strings.TrimRight(baseURL, "/") + "/_bulk"
+	ix := NewIndex(ont)
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	for start := 0; start < len(ont.Terms); start += elasticPushBatch {
+		end := min(start+elasticPushBatch, len(ont.Terms))
+		buf.Reset()
+		for i := start; i < end; i++ {
+			if err := encodeElasticTerm(enc, ix, &ont.Terms[i], index); err != nil {
+				return err
+			}
+		}
+		if err := postElasticBulk(url, &buf); err != nil {
+			return fmt.Errorf("bulk batch at term %d: %w", start, err)
+		}
+	}
+	return nil
+}
+
+func encodeElasticTerm(enc *json.Encoder, ix *Index, t *Term, index string) error {
+	var action elasticAction
+	action.Index.Index = index
+	action.Index.ID = t.ID
+	if err := enc.Encode(&action); err != nil {
+		return err
+	}
+
+	doc := elasticDoc{
+		ID:         t.ID,
+		Name:       t.Name,
+		Definition: t.Definition,
+		Namespace:  t.Namespace,
+		Subsets:    t.Subsets,
+		Xrefs:      t.Xrefs,
+		AltIDs:     t.AltIDs,
+		Parents:    ix.Parents(t.ID),
+		Ancestors:  ix.Ancestors(t.ID),
+		IsObsolete: t.IsObsolete,
+	}
+	for _, syn := range t.Synonyms {
+		doc.Synonyms = append(doc.Synonyms, syn.Text)
+	}
+	return enc.Encode(&doc)
+}
+
+func postElasticBulk(url string, body io.Reader) error {
+	resp, err := http.Post(url, "application/x-ndjson", body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Errors bool `json:"errors"`
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(data))
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return err
+	}
+	if result.Errors {
+		return fmt.Errorf("cluster reported item errors")
+	}
+	return nil
+}
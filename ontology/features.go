@@ -0,0 +1,190 @@
+package ontology
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// FeatureOptions selects the columns of an ancestor feature matrix.
+type FeatureOptions struct {
+	Features    []string // candidate ancestor columns; nil for every ancestor of a row
+	MinSupport  int      // drop columns set in fewer rows
+	IncludeSelf bool     // a row's own term counts as one of its features
+}
+
+// FeatureMatrix is a sparse binary terms × ancestors matrix in CSR form:
+// row i has a 1 in each column Indices[Indptr[i]:Indptr[i+1]].
+type FeatureMatrix struct {
+	Rows     []string // term IDs
+	Features []string // ancestor IDs, sorted
+	Indptr   []int64
+	Indices  []int32 // ascending within each row
+}
+
+// AncestorFeatures builds the matrix of rows against their asserted is_a
+// ancestors, a common featurization for chemical class prediction.
+func (ix *Index) AncestorFeatures(rows []string, opts FeatureOptions) *FeatureMatrix {
+	ancestors := make([][]string, len(rows))
+	support := make(map[string]int)
+	for i, r := range rows {
+		id := ix.Primary(r)
+		anc := ix.Ancestors(id)
+		if opts.IncludeSelf && id != "" {
+			anc = append(anc, id)
+		}
+		ancestors[i] = anc
+		for _, a := range anc {
+			support[a]++
+		}
+	}
+
+	var cols []string
+	if opts.Features != nil {
+		cols = append(cols, opts.Features...)
+	} else {
+		for a := range support {
+			cols = append(cols, a)
+		}
+	}
+	kept := cols[:0]
+	for _, c := range cols {
+		if support[c] >= opts.MinSupport {
+			kept = append(kept, c)
+		}
+	}
+	sort.Strings(kept)
+	col := make(map[string]int32, len(kept))
+	m := &FeatureMatrix{Rows: rows, Indptr: make([]int64, 1, len(rows)+1)}
+	for _, c := range kept {
+		if _, dup := col[c]; !dup {
+			col[c] = int32(len(m.Features))
+			m.Features = append(m.Features, c)
+		}
+	}
+
+	for _, anc := range ancestors {
+		start := len(m.Indices)
+		for _, a := range anc {
+			if j, ok := col[a]; ok {
+				m.Indices = append(m.Indices, j)
+			}
+		}
+		row := m.Indices[start:]
+		sort.Slice(row, func(i, j int) bool { return row[i] < row[j] })
+		m.Indptr = append(m.Indptr, int64(len(m.Indices)))
+	}
+	return m
+}
+
+// WriteLibSVM writes one line per row, "0 j:1 k:1 ...", with 1-based
+// column numbers. The label is always 0; pair rows with m.Rows.
+func (m *FeatureMatrix) WriteLibSVM(w io.Writer) error {
+	bw := bufio.NewWriterSize(w, 256*1024)
+	for i := range m.Rows {
+		bw.WriteByte('0')
+		for _, j := range m.Indices[m.Indptr[i]:m.Indptr[i+1]] {
+			fmt.Fprintf(bw, " %d:1", j+1)
+		}
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+// WriteNPZ writes the matrix as an .npz archive that
+// scipy.sparse.load_npz reads as a CSR matrix (arrays data, indices,
+// indptr, format and shape), plus row_ids and feature_ids string arrays
+// for numpy.load.
+func (m *FeatureMatrix) WriteNPZ(w io.Writer) error {
+	zw := zip.NewWriter(w)
+	le := binary.LittleEndian
+	data := make([]byte, len(m.Indices))
+	for i := range data {
+		data[i] = 1
+	}
+	indices := make([]byte, 0, 4*len(m.Indices))
+	for _, j := range m.Indices {
+		indices = le.AppendUint32(indices, uint32(j))
+	}
+	indptr := make([]byte, 0, 8*len(m.Indptr))
+	for _, p := range m.Indptr {
+		indptr = le.AppendUint64(indptr, uint64(p))
+	}
+	shape := le.AppendUint64(le.AppendUint64(nil, uint64(len(m.Rows))), uint64(len(m.Features)))
+
+	arrays := []struct {
+		name, descr, shape string
+		body               []byte
+	}{
+		{"data", "|i1", fmt.Sprintf("(%d,)", len(data)), data},
+		{"indices", "<i4", fmt.Sprintf("(%d,)", len(m.Indices)), indices},
+		{"indptr", "<i8", fmt.Sprintf("(%d,)", len(m.Indptr)), indptr},
+		{"format", "|S3", "()", []byte("csr")},
+		{"shape", "<i8", "(2,)", shape},
+	}
+	for _, a := range arrays {
+		if err := writeNPY(zw, a.name, a.descr, a.shape, a.body); err != nil {
+			return err
+		}
+	}
+	for _, s := range []struct {
+		name string
+		ids  []string
+	}{{"row_ids", m.Rows}, {"feature_ids", m.Features}} {
+		descr, body := npyStrings(s.ids)
+		if err := writeNPY(zw, s.name, descr, fmt.Sprintf("(%d,)", len(s.ids)), body); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// writeNPY adds name.npy to the archive in .npy format version 1.0.
+func writeNPY(zw *zip.Writer, name, descr, shape string, body []byte) error {
+	f, err := zw.Create(name + ".npy")
+	if err != nil {
+		return err
+	}
+	header := fmt.Sprintf("{'descr': '%s', 'fortran_order': False, 'shape': %s, }", descr, shape)
+	// Magic (6) + version (2) + length (2) + header must be a multiple of
+	// 64, with the header ending in a newline.
+	pad := 64 - (10+len(header)+1)%64
+	if pad == 64 {
+		pad = 0
+	}
+	header += strings.Repeat(" ", pad) + "\n"
+	pre := append([]byte("\x93NUMPY\x01\x00"), byte(len(header)), byte(len(header)>>8))
+	if _, err := f.Write(pre); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(f, header); err != nil {
+		return err
+	}
+	_, err = f.Write(body)
+	return err
+}
+
+// npyStrings encodes ids as a fixed-width UTF-32 array ('<U n').
+func npyStrings(ids []string) (string, []byte) {
+	width := 1
+	for _, id := range ids {
+		width = max(width, utf8.RuneCountInString(id))
+	}
+	body := make([]byte, 0, 4*width*len(ids))
+	for _, id := range ids {
+		n := 0
+		for _, r := range id {
+			body = binary.LittleEndian.AppendUint32(body, uint32(r))
+			n++
+		}
+		for ; n < width; n++ {
+			body = binary.LittleEndian.AppendUint32(body, 0)
+		}
+	}
+	return fmt.Sprintf("<U%d", width), body
+}
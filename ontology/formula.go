@@ -0,0 +1,196 @@
+package ontology
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Formula is an element count map parsed from a molecular formula such as
+// "C6H12O6", "Ca(OH)2" or "C2H3O2.Na". Generic groups written with an
+// initial capital (R, X) count as elements.
+type Formula map[string]int
+
+// ParseFormula parses a formula with parenthesised groups, counts and
+// dot-separated components, each optionally led by a multiplier
+// ("CuSO4.5H2O"). Polymer formulas ("(C2H4)n") and other variable counts
+// are rejected.
+func ParseFormula(s string) (Formula, error) {
+	f := make(Formula)
+	if strings.TrimSpace(s) == "" {
+		return nil, fmt.Errorf("empty formula")
+	}
+	for _, comp := range strings.Split(s, ".") {
+		mult, rest := leadingInt(comp)
+		if mult == 0 {
+			mult = 1
+		}
+		p := &formulaParser{s: rest}
+		group, err := p.group()
+		if err != nil {
+			return nil, fmt.Errorf("formula %q: %w", s, err)
+		}
+		if p.pos < len(p.s) {
+			return nil, fmt.Errorf("formula %q: unexpected %q", s, p.s[p.pos:])
+		}
+		for el, n := range group {
+			f[el] += n * mult
+		}
+	}
+	return f, nil
+}
+
+// String writes the formula in Hill order: C, H, then the other elements
+// alphabetically; alphabetically throughout if there is no carbon.
+func (f Formula) String() string {
+	els := make([]string, 0, len(f))
+	for el, n := range f {
+		if n != 0 {
+			els = append(els, el)
+		}
+	}
+	_, carbon := f["C"]
+	sort.Slice(els, func(i, j int) bool {
+		if carbon {
+			for _, first := range []string{"C", "H"} {
+				if els[i] == first || els[j] == first {
+					return els[i] == first && els[j] != first
+				}
+			}
+		}
+		return els[i] < els[j]
+	})
+	var b strings.Builder
+	for _, el := range els {
+		b.WriteString(el)
+		if n := f[el]; n != 1 {
+			fmt.Fprintf(&b, "%d", n)
+		}
+	}
+	return b.String()
+}
+
+// Diff returns f minus g, omitting elements with equal counts.
+func (f Formula) Diff(g Formula) Formula {
+	d := make(Formula)
+	for el, n := range f {
+		if n != g[el] {
+			d[el] = n - g[el]
+		}
+	}
+	for el, n := range g {
+		if _, ok := f[el]; !ok && n != 0 {
+			d[el] = -n
+		}
+	}
+	return d
+}
+
+// Mass returns the formula's average and monoisotopic masses in daltons,
+// or ok false if it has an element (or generic group) with no known mass.
+func (f Formula) Mass() (average, monoisotopic float64, ok bool) {
+	for el, n := range f {
+		m, known := elementMasses[el]
+		if !known {
+			return 0, 0, false
+		}
+		average += float64(n) * m[0]
+		monoisotopic += float64(n) * m[1]
+	}
+	return average, monoisotopic, true
+}
+
+type formulaParser struct {
+	s   string
+	pos int
+}
+
+// group parses elements and parenthesised groups up to ')' or the end.
+func (p *formulaParser) group() (Formula, error) {
+	f := make(Formula)
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		switch {
+		case c == '(' || c == '[':
+			p.pos++
+			inner, err := p.group()
+			if err != nil {
+				return nil, err
+			}
+			if p.pos >= len(p.s) || (p.s[p.pos] != ')' && p.s[p.pos] != ']') {
+				return nil, fmt.Errorf("unclosed %q", c)
+			}
+			p.pos++
+			n := p.count()
+			for el, k := range inner {
+				f[el] += k * n
+			}
+		case c == ')' || c == ']':
+			return f, nil
+		case c >= 'A' && c <= 'Z':
+			start := p.pos
+			p.pos++
+			for p.pos < len(p.s) && p.s[p.pos] >= 'a' && p.s[p.pos] <= 'z' {
+				p.pos++
+			}
+			el := p.s[start:p.pos]
+			f[el] += p.count()
+		default:
+			return nil, fmt.Errorf("unexpected %q", p.s[p.pos:])
+		}
+	}
+	return f, nil
+}
+
+// count parses an optional count after an element or group, 1 if absent.
+func (p *formulaParser) count() int {
+	n, rest := leadingInt(p.s[p.pos:])
+	p.pos = len(p.s) - len(rest)
+	if n == 0 {
+		return 1
+	}
+	return n
+}
+
+// leadingInt splits off the decimal number s starts with, 0 if none.
+func leadingInt(s string) (int, string) {
+	n, i := 0, 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		n = n*10 + int(s[i]-'0')
+		i++
+	}
+	return n, s[i:]
+}
+
+// elementMasses are standard atomic weights and monoisotopic masses, in
+// that order, for the elements common in ChEBI, using the values ChEBI
+// computes its mass properties with.
+var elementMasses = map[string][2]float64{
+	"H":  {1.00794, 1.00782503207},
+	"D":  {2.01410, 2.01410177785},
+	"Li": {6.941, 7.01600455},
+	"B":  {10.811, 11.0093054},
+	"C":  {12.0107, 12.0},
+	"N":  {14.0067, 14.0030740048},
+	"O":  {15.9994, 15.99491461956},
+	"F":  {18.99840, 18.99840322},
+	"Na": {22.98977, 22.9897692809},
+	"Mg": {24.30500, 23.985041700},
+	"Al": {26.98154, 26.98153863},
+	"Si": {28.08550, 27.9769265325},
+	"P":  {30.97376, 30.97376163},
+	"S":  {32.06500, 31.97207100},
+	"Cl": {35.45300, 34.96885268},
+	"K":  {39.09830, 38.96370668},
+	"Ca": {40.07800, 39.96259098},
+	"Mn": {54.93805, 54.9380451},
+	"Fe": {55.84500, 55.9349375},
+	"Co": {58.93320, 58.9331950},
+	"Ni": {58.69340, 57.9353429},
+	"Cu": {63.54600, 62.9295975},
+	"Zn": {65.40900, 63.9291422},
+	"Se": {78.96000, 79.9165213},
+	"Br": {79.90400, 78.9183371},
+	"Mo": {95.94000, 97.9054082},
+	"I":  {126.90447, 126.904473},
+}
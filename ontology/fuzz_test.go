@@ -0,0 +1,92 @@
+package ontology
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+)
+
+// The fuzz targets check that malformed input is reported or recovered
+// from, never a panic or a hang. Seeds are the sample files plus the
+// corpora in testdata/fuzz; run one with, for example,
+//
+//	go test ./ontology -run '^$' -fuzz FuzzParseOBO -fuzztime 1m
+
+func addSeedFile(f *testing.F, path string) {
+	f.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(data)
+}
+
+func FuzzParseOBO(f *testing.F) {
+	addSeedFile(f, "../testdata/sample.obo")
+	f.Fuzz(func(t *testing.T, data []byte) {
+		ont, err := ParseOBOWithOptions(bytes.NewReader(data), ParseOptions{Warn: func(Warning) {}})
+		if err != nil {
+			return
+		}
+		// Whatever parses must also write.
+		if err := WriteOBO(ont, io.Discard); err != nil {
+			t.Fatalf("WriteOBO: %v", err)
+		}
+		if err := WriteJSON(ont, io.Discard); err != nil {
+			t.Fatalf("WriteJSON: %v", err)
+		}
+	})
+}
+
+func FuzzParseOWL(f *testing.F) {
+	addSeedFile(f, "../testdata/sample.owl")
+	f.Fuzz(func(t *testing.T, data []byte) {
+		ont, err := ParseOWLWithOptions(bytes.NewReader(data), ParseOptions{Warn: func(Warning) {}})
+		if err != nil {
+			return
+		}
+		if err := WriteJSON(ont, io.Discard); err != nil {
+			t.Fatalf("WriteJSON: %v", err)
+		}
+	})
+}
+
+func FuzzParseSynonym(f *testing.F) {
+	for _, s := range []string{
+		`"H2O" EXACT FORMULA [ChEBI:FORMULA]`,
+		`"dihydrogen oxide" RELATED [ChEBI:ChEBI, Wikipedia:Water]`,
+		`"quoted \"inner\" text" NARROW []`,
+		`"unterminated`,
+		`"" EXACT`,
+	} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		syn := parseSynonym(s)
+		if len(syn.Text) > len(s) {
+			t.Fatalf("parseSynonym(%q): text %q longer than the input", s, syn.Text)
+		}
+	})
+}
+
+func FuzzParsePropertyValue(f *testing.F) {
+	for _, s := range []string{
+		`http://purl.obolibrary.org/obo/chebi/mass "18.01528" xsd:decimal`,
+		`http://purl.obolibrary.org/obo/chebi/formula "H2O" xsd:string`,
+		`IAO:0000231 IAO:0000227`,
+		`key "unterminated`,
+		`key "v" {source="x"} ! comment`,
+	} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		key, value, datatype := parsePropertyValue(s)
+		if key == "" && (value != "" || datatype != "") {
+			t.Fatalf("parsePropertyValue(%q) = %q, %q, %q: value without a key", s, key, value, datatype)
+		}
+		var term Term
+		term.setProperty(key, value, datatype)
+		term.Property(key)
+	})
+}
@@ -0,0 +1,314 @@
+package ontology
+
+import "slices"
+
+// Origin says where a Graph edge comes from.
+type Origin string
+
+const (
+	// Asserted edges are stated in the ontology.
+	Asserted Origin = "asserted"
+	// Inferred edges are those GraphOptions.InferredParents adds, such as
+	// the direct superclasses a classification finds, and asserted
+	// relationships qualified is_inferred="true" (added by Materialize,
+	// AddInverses or rules).
+	Inferred Origin = "inferred"
+)
+
+// GraphEdge is one edge of a Graph: From —Relation→ To.
+type GraphEdge struct {
+	From     string `json:"from"`
+	Relation string `json:"relation"`
+	To       string `json:"to"`
+	Origin   Origin `json:"origin"`
+}
+
+// EdgeFilter selects the edges a Graph query follows. The zero value
+// follows every edge.
+type EdgeFilter struct {
+	Relations []string // relation types, is_a included; all if empty
+	Origin    Origin   // only edges of this origin; both if ""
+}
+
+// GraphOptions configures NewGraphWithOptions.
+type GraphOptions struct {
+	// Obsolete terms and their edges are left out unless this is
+	// ObsoleteInclude.
+	Obsolete ObsoletePolicy
+	// Keep, if set, selects the asserted edges to store.
+	Keep func(from string, rel *Relationship) bool
+	// InferredParents, if set, returns is_a parents of a term it does not
+	// assert, stored as Inferred edges after its asserted ones. The
+	// reasoner passes the direct superclasses of a classification.
+	InferredParents func(t *Term) []string
+}
+
+// graphEdge is an edge in the Graph's CSR arrays: the node at the other
+// end, the relation as an index into Graph.rels, and the origin.
+type graphEdge struct {
+	node     int32
+	rel      uint16
+	inferred bool
+}
+
+// Graph holds the asserted and inferred edges of an ontology in one
+// structure, so closures, exports and traversals read is_a and
+// relationships, as stated or as classified, from one place. Nodes are
+// the terms, in ontology order, then the targets of their edges that are
+// not terms. Edges are stored as CSR in both directions; an edge asserted
+// more than once is stored once.
+type Graph struct {
+	ids   []string
+	nodes map[string]int32
+	rels  []string
+
+	outOffsets []uint32
+	out        []graphEdge
+	inOffsets  []uint32
+	in         []graphEdge
+}
+
+// NewGraph builds the Graph of ont's asserted edges, without obsolete
+// terms.
+func NewGraph(ont *Ontology) *Graph {
+	return NewGraphWithOptions(ont, GraphOptions{})
+}
+
+// NewGraphWithOptions builds the Graph of ont under opts.
+func NewGraphWithOptions(ont *Ontology, opts GraphOptions) *Graph {
+	g := &Graph{nodes: make(map[string]int32, len(ont.Terms))}
+	keepObsolete := opts.Obsolete.Keep(false)
+	for i := range ont.Terms {
+		if keepObsolete || !ont.Terms[i].IsObsolete {
+			g.node(ont.Terms[i].ID)
+		}
+	}
+
+	relIDs := make(map[string]uint16)
+	type edge struct {
+		from int32
+		e    graphEdge
+	}
+	var edges []edge
+	seen := make(map[edge]bool)
+	add := func(from, rel, to string, inferred bool) {
+		r, ok := relIDs[rel]
+		if !ok {
+			r = uint16(len(g.rels))
+			relIDs[rel] = r
+			g.rels = append(g.rels, rel)
+		}
+		e := edge{g.node(from), graphEdge{g.node(to), r, inferred}}
+		if !seen[e] {
+			seen[e] = true
+			edges = append(edges, e)
+		}
+	}
+	for i := range ont.Terms {
+		t := &ont.Terms[i]
+		if t.IsObsolete && !keepObsolete {
+			continue
+		}
+		for j := range t.Relationships {
+			rel := &t.Relationships[j]
+			if rel.TargetID == "" || rel.Self || opts.Keep != nil && !opts.Keep(t.ID, rel) {
+				continue
+			}
+			add(t.ID, rel.Type, rel.TargetID, rel.Qualifiers["is_inferred"] == "true")
+		}
+		if opts.InferredParents != nil {
+			for _, p := range opts.InferredParents(t) {
+				add(t.ID, "is_a", p, true)
+			}
+		}
+	}
+
+	n := len(g.ids)
+	g.outOffsets = make([]uint32, n+1)
+	g.inOffsets = make([]uint32, n+1)
+	for _, e := range edges {
+		g.outOffsets[e.from+1]++
+		g.inOffsets[e.e.node+1]++
+	}
+	for i := 0; i < n; i++ {
+		g.outOffsets[i+1] += g.outOffsets[i]
+		g.inOffsets[i+1] += g.inOffsets[i]
+	}
+	g.out = make([]graphEdge, len(edges))
+	g.in = make([]graphEdge, len(edges))
+	outPos := slices.Clone(g.outOffsets[:n])
+	inPos := slices.Clone(g.inOffsets[:n])
+	for _, e := range edges {
+		g.out[outPos[e.from]] = e.e
+		outPos[e.from]++
+		g.in[inPos[e.e.node]] = graphEdge{e.from, e.e.rel, e.e.inferred}
+		inPos[e.e.node]++
+	}
+	return g
+}
+
+// node returns the index of id, adding it if needed.
+func (g *Graph) node(id string) int32 {
+	if k, ok := g.nodes[id]; ok {
+		return k
+	}
+	k := int32(len(g.ids))
+	g.nodes[id] = k
+	g.ids = append(g.ids, id)
+	return k
+}
+
+// Has reports whether id is a node of the graph.
+func (g *Graph) Has(id string) bool {
+	_, ok := g.nodes[id]
+	return ok
+}
+
+// Relations returns the relation types the graph's edges use, in the
+// order first met.
+func (g *Graph) Relations() []string {
+	return slices.Clone(g.rels)
+}
+
+// Out returns the edges from id that f selects, in asserted order with
+// inferred is_a edges last.
+func (g *Graph) Out(id string, f EdgeFilter) []GraphEdge {
+	k, ok := g.nodes[id]
+	if !ok {
+		return nil
+	}
+	var out []GraphEdge
+	for _, e := range g.out[g.outOffsets[k]:g.outOffsets[k+1]] {
+		if g.match(e, f) {
+			out = append(out, g.edge(k, e))
+		}
+	}
+	return out
+}
+
+// In returns the edges to id that f selects.
+func (g *Graph) In(id string, f EdgeFilter) []GraphEdge {
+	k, ok := g.nodes[id]
+	if !ok {
+		return nil
+	}
+	var out []GraphEdge
+	for _, e := range g.in[g.inOffsets[k]:g.inOffsets[k+1]] {
+		if g.match(e, f) {
+			out = append(out, g.edge(e.node, graphEdge{k, e.rel, e.inferred}))
+		}
+	}
+	return out
+}
+
+// Walk visits, breadth-first from id, every edge f selects that reaches a
+// node not yet visited: outgoing edges, or incoming ones if reverse is
+// set. It stops when visit returns false.
+func (g *Graph) Walk(id string, reverse bool, f EdgeFilter, visit func(GraphEdge) bool) {
+	start, ok := g.nodes[id]
+	if !ok {
+		return
+	}
+	seen := map[int32]bool{start: true}
+	queue := []int32{start}
+	for len(queue) > 0 {
+		k := queue[0]
+		queue = queue[1:]
+		var es []graphEdge
+		if reverse {
+			es = g.in[g.inOffsets[k]:g.inOffsets[k+1]]
+		} else {
+			es = g.out[g.outOffsets[k]:g.outOffsets[k+1]]
+		}
+		for _, e := range es {
+			if seen[e.node] || !g.match(e, f) {
+				continue
+			}
+			seen[e.node] = true
+			queue = append(queue, e.node)
+			edge := g.edge(k, e)
+			if reverse {
+				edge = g.edge(e.node, graphEdge{k, e.rel, e.inferred})
+			}
+			if !visit(edge) {
+				return
+			}
+		}
+	}
+}
+
+// Reachable returns the nodes reachable from id over edges f selects, in
+// breadth-first order and excluding id: its ancestors under the chosen
+// relations, or its descendants if reverse is set.
+func (g *Graph) Reachable(id string, reverse bool, f EdgeFilter) []string {
+	var out []string
+	g.Walk(id, reverse, f, func(e GraphEdge) bool {
+		if reverse {
+			out = append(out, e.From)
+		} else {
+			out = append(out, e.To)
+		}
+		return true
+	})
+	return out
+}
+
+// Closure computes the transitive closure of each relation of f (every
+// relation if it lists none) independently, over the edges of that
+// relation and f's origin. Distance is the length of the shortest path.
+// Rows are produced per node in graph order and, within a node, in
+// breadth-first order for each relation.
+func (g *Graph) Closure(f EdgeFilter, fn func(ClosureRow)) {
+	relations := f.Relations
+	if len(relations) == 0 {
+		relations = g.rels
+	}
+	type step struct {
+		node int32
+		dist int
+	}
+	stamp := make([]int, len(g.ids)) // stamp[k] == walk once k is seen in it
+	walk := 0
+	var queue []step
+	for k := range g.ids {
+		if g.outOffsets[k] == g.outOffsets[k+1] {
+			continue
+		}
+		for _, r := range relations {
+			rel := slices.Index(g.rels, r)
+			if rel < 0 {
+				continue
+			}
+			walk++
+			stamp[k] = walk
+			queue = append(queue[:0], step{int32(k), 0})
+			for len(queue) > 0 {
+				cur := queue[0]
+				queue = queue[1:]
+				for _, e := range g.out[g.outOffsets[cur.node]:g.outOffsets[cur.node+1]] {
+					if int(e.rel) != rel || stamp[e.node] == walk || f.Origin != "" && (f.Origin == Inferred) != e.inferred {
+						continue
+					}
+					stamp[e.node] = walk
+					fn(ClosureRow{TermID: g.ids[k], AncestorID: g.ids[e.node], Distance: cur.dist + 1, Relation: r})
+					queue = append(queue, step{e.node, cur.dist + 1})
+				}
+			}
+		}
+	}
+}
+
+func (g *Graph) match(e graphEdge, f EdgeFilter) bool {
+	if f.Origin != "" && (f.Origin == Inferred) != e.inferred {
+		return false
+	}
+	return len(f.Relations) == 0 || slices.Contains(f.Relations, g.rels[e.rel])
+}
+
+func (g *Graph) edge(from int32, e graphEdge) GraphEdge {
+	origin := Asserted
+	if e.inferred {
+		origin = Inferred
+	}
+	return GraphEdge{From: g.ids[from], Relation: g.rels[e.rel], To: g.ids[e.node], Origin: origin}
+}
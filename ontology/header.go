@@ -0,0 +1,76 @@
+package ontology
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+// SourceHeader is what an ontology file says about itself before its
+// first stanza or class: enough to identify and license it without
+// parsing the whole file.
+type SourceHeader struct {
+	Ontology   string            `json:"ontology,omitempty"`    // ontology IRI or OBO short name
+	VersionIRI string            `json:"version_iri,omitempty"` // owl:versionIRI, or the IRI OBO's data-version maps to
+	Metadata   *OntologyMetadata `json:"metadata,omitempty"`
+}
+
+// ScanHeader reads the header of an OBO or RDF/XML OWL file, telling them
+// apart by the first non-blank character, and stops at the first stanza
+// (OBO) or after the owl:Ontology element (OWL).
+func ScanHeader(r io.Reader) (*SourceHeader, error) {
+	br := bufio.NewReaderSize(r, 64*1024)
+	start, _ := br.Peek(512)
+	start = bytes.TrimLeft(bytes.TrimPrefix(start, []byte("\ufeff")), " \t\r\n")
+	if len(start) > 0 && start[0] == '<' {
+		return scanOWLHeader(br)
+	}
+	return scanOBOHeader(br)
+}
+
+func scanOBOHeader(r io.Reader) (*SourceHeader, error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), scannerBufferSize)
+	sc.Split(splitOBOLines(CharsetReport, newWarner(ParseOptions{})))
+	ont := &Ontology{}
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.HasPrefix(line, "[") {
+			break
+		}
+		parseHeaderLine(ont, line)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	h := &SourceHeader{Ontology: ont.Ontology, Metadata: ont.Metadata}
+	if ont.Ontology != "" || ont.DataVersion != "" {
+		_, h.VersionIRI = ontologyIRI(ont)
+	}
+	return h, nil
+}
+
+func scanOWLHeader(r io.Reader) (*SourceHeader, error) {
+	decoder := xml.NewDecoder(r)
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			return &SourceHeader{}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok || matchElement(se, nsRDF, "RDF") {
+			continue
+		}
+		ont := &Ontology{}
+		if matchElement(se, nsOWL, "Ontology") {
+			parseOWLOntologyHeader(decoder, se, ont)
+		}
+		// The header comes first; anything else means there is none.
+		return &SourceHeader{Ontology: ont.Ontology, VersionIRI: ont.DataVersion, Metadata: ont.Metadata}, nil
+	}
+}
@@ -0,0 +1,210 @@
+package ontology
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Kinds of HistoryEvent.
+const (
+	HistoryAdded       = "added"       // first seen, or back after being removed
+	HistoryRenamed     = "renamed"     // name changed from Old to New
+	HistoryReparented  = "reparented"  // is_a parents Added and Removed
+	HistoryObsoleted   = "obsoleted"   // became obsolete; New lists replaced_by
+	HistoryUnobsoleted = "unobsoleted" // no longer obsolete
+	HistoryMerged      = "merged"      // gone, its ID now an alt_id of New
+	HistoryRemoved     = "removed"     // gone without a trace
+)
+
+// HistoryEvent is one change to a term, in the release Version.
+type HistoryEvent struct {
+	Version string   `json:"version"`
+	Kind    string   `json:"kind"`
+	Old     string   `json:"old,omitempty"`
+	New     string   `json:"new,omitempty"`
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// TermHistory is the change log of one term ID across releases. Name and
+// IsObsolete are as last seen.
+type TermHistory struct {
+	ID         string         `json:"id"`
+	Name       string         `json:"name,omitempty"`
+	FirstSeen  string         `json:"first_seen"`
+	LastSeen   string         `json:"last_seen"`
+	IsObsolete bool           `json:"is_obsolete,omitempty"`
+	Events     []HistoryEvent `json:"events"`
+}
+
+// HistoryLog is the change log of every term seen in a series of
+// releases, Versions oldest first and Terms sorted by ID.
+type HistoryLog struct {
+	Versions []string      `json:"versions"`
+	Terms    []TermHistory `json:"terms"`
+}
+
+// Term returns the history of id, or nil if no release had it as a
+// primary ID.
+func (l *HistoryLog) Term(id string) *TermHistory {
+	i := sort.Search(len(l.Terms), func(i int) bool { return l.Terms[i].ID >= id })
+	if i < len(l.Terms) && l.Terms[i].ID == id {
+		return &l.Terms[i]
+	}
+	return nil
+}
+
+// historyState is what HistoryBuilder remembers of a term between
+// releases.
+type historyState struct {
+	name     string
+	parents  []string
+	obsolete bool
+}
+
+// HistoryBuilder builds a HistoryLog from releases added oldest first.
+// Unlike VersionedStore it keeps only the previous release's names,
+// parents and obsolete flags, so years of releases can be read one at a
+// time.
+type HistoryBuilder struct {
+	versions []string
+	prev     map[string]historyState
+	terms    map[string]*TermHistory
+}
+
+// NewHistoryBuilder returns a builder with no releases.
+func NewHistoryBuilder() *HistoryBuilder {
+	return &HistoryBuilder{terms: make(map[string]*TermHistory)}
+}
+
+// Add records the changes from the previous release to ont. Every term of
+// the first release is added.
+func (b *HistoryBuilder) Add(version string, ont *Ontology) {
+	b.versions = append(b.versions, version)
+	cur := make(map[string]historyState, len(ont.Terms))
+	alt := make(map[string]string)
+	for i := range ont.Terms {
+		t := &ont.Terms[i]
+		if _, dup := cur[t.ID]; dup {
+			continue
+		}
+		for _, a := range t.AltIDs {
+			alt[a] = t.ID
+		}
+		s := historyState{name: t.Name, obsolete: t.IsObsolete}
+		for _, rel := range t.Relationships {
+			if rel.Type == "is_a" && !rel.HasValue && !rel.Self {
+				s.parents = append(s.parents, rel.TargetID)
+			}
+		}
+		cur[t.ID] = s
+
+		h := b.terms[t.ID]
+		if h == nil {
+			h = &TermHistory{ID: t.ID, FirstSeen: version}
+			b.terms[t.ID] = h
+		}
+		event := func(e HistoryEvent) {
+			e.Version = version
+			h.Events = append(h.Events, e)
+		}
+		p, ok := b.prev[t.ID]
+		if !ok {
+			event(HistoryEvent{Kind: HistoryAdded, New: s.name, Added: s.parents})
+			if s.obsolete {
+				event(HistoryEvent{Kind: HistoryObsoleted, New: strings.Join(t.ReplacedBy, " ")})
+			}
+		} else {
+			if p.name != s.name {
+				event(HistoryEvent{Kind: HistoryRenamed, Old: p.name, New: s.name})
+			}
+			if added, removed := diffSets(p.parents, s.parents); len(added) > 0 || len(removed) > 0 {
+				event(HistoryEvent{Kind: HistoryReparented, Added: added, Removed: removed})
+			}
+			switch {
+			case s.obsolete && !p.obsolete:
+				event(HistoryEvent{Kind: HistoryObsoleted, New: strings.Join(t.ReplacedBy, " ")})
+			case p.obsolete && !s.obsolete:
+				event(HistoryEvent{Kind: HistoryUnobsoleted})
+			}
+		}
+		h.Name, h.IsObsolete, h.LastSeen = s.name, s.obsolete, version
+	}
+	for id := range b.prev {
+		if _, ok := cur[id]; ok {
+			continue
+		}
+		h := b.terms[id]
+		if into := alt[id]; into != "" {
+			h.Events = append(h.Events, HistoryEvent{Version: version, Kind: HistoryMerged, New: into})
+		} else {
+			h.Events = append(h.Events, HistoryEvent{Version: version, Kind: HistoryRemoved})
+		}
+	}
+	b.prev = cur
+}
+
+// Log returns the history of every term seen so far.
+func (b *HistoryBuilder) Log() *HistoryLog {
+	l := &HistoryLog{Versions: b.versions, Terms: make([]TermHistory, 0, len(b.terms))}
+	for _, h := range b.terms {
+		l.Terms = append(l.Terms, *h)
+	}
+	sort.Slice(l.Terms, func(i, j int) bool { return l.Terms[i].ID < l.Terms[j].ID })
+	return l
+}
+
+// WriteHistorySQL writes l as SQL that sqlite3 (or psql) loads into three
+// tables: release (seq, version), term_history (one row per term) and
+// term_event (one row per event; added and removed space-separated).
+func WriteHistorySQL(l *HistoryLog, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(`BEGIN;
+CREATE TABLE release (
+    seq     integer PRIMARY KEY,
+    version text NOT NULL
+);
+CREATE TABLE term_history (
+    id          text PRIMARY KEY,
+    name        text,
+    first_seen  text NOT NULL,
+    last_seen   text NOT NULL,
+    is_obsolete boolean NOT NULL
+);
+CREATE TABLE term_event (
+    term_id text NOT NULL,
+    version text NOT NULL,
+    kind    text NOT NULL,
+    old     text,
+    new     text,
+    added   text,
+    removed text
+);
+CREATE INDEX term_event_term_id ON term_event (term_id);
+`)
+	for i, v := range l.Versions {
+		fmt.Fprintf(bw, "INSERT INTO release VALUES (%d, %s);\n", i+1, sqlString(v))
+	}
+	for _, h := range l.Terms {
+		fmt.Fprintf(bw, "INSERT INTO term_history VALUES (%s, %s, %s, %s, %t);\n",
+			sqlString(h.ID), sqlString(h.Name), sqlString(h.FirstSeen), sqlString(h.LastSeen), h.IsObsolete)
+		for _, e := range h.Events {
+			fmt.Fprintf(bw, "INSERT INTO term_event VALUES (%s, %s, %s, %s, %s, %s, %s);\n",
+				sqlString(h.ID), sqlString(e.Version), sqlString(e.Kind), sqlString(e.Old), sqlString(e.New),
+				sqlString(strings.Join(e.Added, " ")), sqlString(strings.Join(e.Removed, " ")))
+		}
+	}
+	bw.WriteString("COMMIT;\n")
+	return bw.Flush()
+}
+
+// sqlString quotes s as an SQL string literal, or NULL if it is empty.
+func sqlString(s string) string {
+	if s == "" {
+		return "NULL"
+	}
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
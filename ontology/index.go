@@ -0,0 +1,255 @@
+package ontology
+
+import (
+	"sort"
+	"sync"
+)
+
+// Index provides lookup and asserted is_a traversal over a parsed ontology.
+// It holds pointers into ont.Terms, so the ontology must not be modified
+// while the index is in use. Only the ID and alt ID maps are built up
+// front; the other sub-indexes are built on first use, or up front as
+// IndexOptions.Build asks.
+type Index struct {
+	ont       *Ontology
+	byID      map[string]int
+	altIDs    map[string]string   // alt_id → primary ID
+	childOnce sync.Once           // builds children
+	children  map[string][]string // is_a target → subclasses; nil if cols is set
+	cols      *Columns            // columnar is_a graph, see IndexOptions
+	names     nameIndex           // labels and synonyms, see Lookup
+	search    searchIndex         // name tables for Resolve
+	refs      refIndex            // reverse mentions, see ReferencedBy
+	xrefs     xrefIndex           // xref → terms, see TermsWithXref
+	edges     edgeIndex           // inverse edges, see Edges
+	branches  branchIndex         // terms under ExcludeBranches, see Excluded
+	labels    LabelPrefs          // see IndexOptions.Labels
+}
+
+// IndexParts selects sub-indexes of an Index for IndexOptions.Build.
+type IndexParts uint8
+
+const (
+	IndexChildren IndexParts = 1 << iota // is_a children, see Children
+	IndexNames                           // labels and synonyms, see Lookup
+	IndexSearch                          // name tables, see Resolve
+	IndexRefs                            // reverse mentions, see ReferencedBy
+	IndexXrefs                           // xref → terms, see TermsWithXref
+	IndexEdges                           // inverse edges, see Edges
+
+	IndexAll = IndexChildren | IndexNames | IndexSearch | IndexRefs | IndexXrefs | IndexEdges
+)
+
+// NewIndex builds an Index over the ontology's terms.
+func NewIndex(ont *Ontology) *Index {
+	return NewIndexWithOptions(ont, IndexOptions{})
+}
+
+// NewIndexWithOptions builds an Index with the given representation, whose
+// methods and results are the same either way, obsolete-term policy,
+// excluded branches and label preference.
+func NewIndexWithOptions(ont *Ontology, opts IndexOptions) *Index {
+	if !opts.Obsolete.Keep(true) {
+		ont = Filter(ont, FilterOptions{DropObsolete: true})
+	}
+	ix := &Index{
+		ont:    ont,
+		byID:   make(map[string]int, len(ont.Terms)),
+		altIDs: make(map[string]string),
+	}
+	ix.edges.inverses = opts.Inverses
+	ix.branches.roots = opts.ExcludeBranches
+	ix.labels = opts.Labels
+	if opts.Columnar {
+		ix.cols = NewColumns(ont)
+	}
+	for i := range ont.Terms {
+		t := &ont.Terms[i]
+		ix.byID[t.ID] = i
+		for _, alt := range t.AltIDs {
+			ix.altIDs[alt] = t.ID
+		}
+	}
+	ix.build(opts.Build)
+	return ix
+}
+
+// build builds the given sub-indexes concurrently, each through its
+// sync.Once, so a caller needing one meanwhile waits for it rather than
+// building it again.
+func (ix *Index) build(parts IndexParts) {
+	var wg sync.WaitGroup
+	for _, p := range []struct {
+		part  IndexParts
+		once  *sync.Once
+		build func()
+	}{
+		{IndexChildren, &ix.childOnce, ix.buildChildren},
+		{IndexNames, &ix.names.once, ix.buildNames},
+		{IndexSearch, &ix.search.once, ix.buildSearch},
+		{IndexRefs, &ix.refs.once, ix.buildRefs},
+		{IndexXrefs, &ix.xrefs.once, ix.buildXrefs},
+		{IndexEdges, &ix.edges.once, ix.buildEdges},
+	} {
+		if parts&p.part == 0 || p.part == IndexEdges && !ix.edges.inverses {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.once.Do(p.build)
+		}()
+	}
+	wg.Wait()
+}
+
+// buildChildren inverts the is_a edges, unless Columns already has them.
+func (ix *Index) buildChildren() {
+	if ix.cols != nil {
+		return
+	}
+	ix.children = make(map[string][]string, len(ix.ont.Terms))
+	for i := range ix.ont.Terms {
+		t := &ix.ont.Terms[i]
+		for _, rel := range t.Relationships {
+			if rel.Type == "is_a" {
+				ix.children[rel.TargetID] = append(ix.children[rel.TargetID], t.ID)
+			}
+		}
+	}
+}
+
+// Ontology returns the indexed ontology.
+func (ix *Index) Ontology() *Ontology { return ix.ont }
+
+// Term returns the term with the given ID, or nil if it is not present.
+// Alt IDs are not resolved; use Primary first if needed.
+func (ix *Index) Term(id string) *Term {
+	if i, ok := ix.byID[id]; ok {
+		return &ix.ont.Terms[i]
+	}
+	return nil
+}
+
+// Primary returns the primary ID for id, resolving alt IDs. It returns ""
+// if id is neither a term ID nor an alt ID.
+func (ix *Index) Primary(id string) string {
+	if _, ok := ix.byID[id]; ok {
+		return id
+	}
+	return ix.altIDs[id]
+}
+
+// Columns returns the columnar view of the ontology if the index was built
+// with IndexOptions.Columnar, else nil.
+func (ix *Index) Columns() *Columns { return ix.cols }
+
+// Parents returns the asserted is_a parents of id.
+func (ix *Index) Parents(id string) []string {
+	if ix.cols != nil {
+		k, ok := ix.cols.Node(id)
+		if !ok {
+			return nil
+		}
+		return ix.cols.ids(ix.cols.ParentsOf(k))
+	}
+	t := ix.Term(id)
+	if t == nil {
+		return nil
+	}
+	var parents []string
+	for _, rel := range t.Relationships {
+		if rel.Type == "is_a" {
+			parents = append(parents, rel.TargetID)
+		}
+	}
+	return parents
+}
+
+// Children returns the asserted is_a children of id.
+func (ix *Index) Children(id string) []string {
+	if ix.cols != nil {
+		k, ok := ix.cols.Node(id)
+		if !ok {
+			return nil
+		}
+		return ix.cols.ids(ix.cols.ChildrenOf(k))
+	}
+	ix.childOnce.Do(ix.buildChildren)
+	return ix.children[id]
+}
+
+// Ancestors returns all transitive is_a ancestors of id in breadth-first
+// order, excluding id itself.
+func (ix *Index) Ancestors(id string) []string {
+	if ix.cols != nil {
+		k, ok := ix.cols.Node(id)
+		if !ok {
+			return nil
+		}
+		return ix.cols.ids(ix.cols.ancestors(k))
+	}
+	seen := map[string]struct{}{id: {}}
+	var out []string
+	queue := ix.Parents(id)
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		if _, ok := seen[p]; ok {
+			continue
+		}
+		seen[p] = struct{}{}
+		out = append(out, p)
+		queue = append(queue, ix.Parents(p)...)
+	}
+	return out
+}
+
+// IsLeaf reports whether id has no asserted is_a children.
+func (ix *Index) IsLeaf(id string) bool {
+	return len(ix.Children(id)) == 0
+}
+
+// Leaves returns the asserted is_a descendants of rootID that have no
+// children, sorted; rootID itself if it is a leaf.
+func (ix *Index) Leaves(rootID string) []string {
+	if ix.Term(rootID) == nil {
+		return nil
+	}
+	seen := map[string]bool{rootID: true}
+	var out []string
+	stack := []string{rootID}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		kids := ix.Children(id)
+		if len(kids) == 0 {
+			out = append(out, id)
+		}
+		for _, k := range kids {
+			if !seen[k] {
+				seen[k] = true
+				stack = append(stack, k)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Siblings returns the terms sharing an asserted is_a parent with id,
+// excluding id, sorted.
+func (ix *Index) Siblings(id string) []string {
+	seen := map[string]bool{id: true}
+	var out []string
+	for _, p := range ix.Parents(id) {
+		for _, k := range ix.Children(p) {
+			if !seen[k] {
+				seen[k] = true
+				out = append(out, k)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,155 @@
+package ontology
+
+import (
+	"sort"
+	"sync"
+)
+
+// InverseRelations maps each relation with a declared inverse to that
+// inverse, in both directions: inverse_of is symmetric, so a Typedef
+// "has_part inverse_of part_of" also makes part_of the inverse of
+// has_part. A relation declared its own inverse (a symmetric one) maps to
+// itself.
+func InverseRelations(ont *Ontology) map[string]string {
+	inv := make(map[string]string)
+	for i := range ont.TypeDefs {
+		td := &ont.TypeDefs[i]
+		if td.InverseOf == "" {
+			continue
+		}
+		inv[td.ID] = td.InverseOf
+		if _, ok := inv[td.InverseOf]; !ok {
+			inv[td.InverseOf] = td.ID
+		}
+	}
+	return inv
+}
+
+// AddInverses materializes the inverse of every relationship whose type
+// has a declared inverse: for X R Y it adds Y R⁻ X to the term Y, with the
+// qualifiers is_inferred="true" and source="inverse_of:R", unless Y already
+// asserts it. Only plain existential edges between non-obsolete terms are
+// inverted; has_value, Self and cardinality edges say more than their
+// inverse would. It returns the number of edges added.
+func AddInverses(ont *Ontology) int {
+	inv := InverseRelations(ont)
+	if len(inv) == 0 {
+		return 0
+	}
+	pos := make(map[string]int, len(ont.Terms))
+	type edge struct{ typ, target string }
+	have := make(map[string]map[edge]bool)
+	for i := range ont.Terms {
+		t := &ont.Terms[i]
+		pos[t.ID] = i
+		for _, rel := range t.Relationships {
+			if _, ok := inv[rel.Type]; ok {
+				if have[t.ID] == nil {
+					have[t.ID] = make(map[edge]bool)
+				}
+				have[t.ID][edge{rel.Type, rel.TargetID}] = true
+			}
+		}
+	}
+
+	added := make(map[int][]Relationship)
+	n := 0
+	for i := range ont.Terms {
+		t := &ont.Terms[i]
+		if t.IsObsolete {
+			continue
+		}
+		for _, rel := range t.Relationships {
+			r, ok := inv[rel.Type]
+			if !ok || rel.HasValue || rel.Self || rel.Cardinality != nil {
+				continue
+			}
+			j, ok := pos[rel.TargetID]
+			if !ok || ont.Terms[j].IsObsolete {
+				continue
+			}
+			e := edge{r, t.ID}
+			if have[rel.TargetID][e] {
+				continue
+			}
+			if have[rel.TargetID] == nil {
+				have[rel.TargetID] = make(map[edge]bool)
+			}
+			have[rel.TargetID][e] = true
+			added[j] = append(added[j], Relationship{
+				Type:       r,
+				TargetID:   t.ID,
+				Name:       t.Name,
+				Qualifiers: map[string]string{"is_inferred": "true", "source": "inverse_of:" + rel.Type},
+			})
+			n++
+		}
+	}
+	for j, rels := range added {
+		sort.SliceStable(rels, func(a, b int) bool {
+			if rels[a].Type != rels[b].Type {
+				return rels[a].Type < rels[b].Type
+			}
+			return rels[a].TargetID < rels[b].TargetID
+		})
+		ont.Terms[j].Relationships = append(ont.Terms[j].Relationships, rels...)
+	}
+	return n
+}
+
+// Edge is a relationship from a term: asserted, or the inverse of one
+// another term asserts when Inverse is set.
+type Edge struct {
+	Type     string `json:"type"`
+	TargetID string `json:"target_id"`
+	Inverse  bool   `json:"inverse,omitempty"`
+}
+
+// edgeIndex holds the inverse edges of each term. It is built on first
+// use, and only for an Index made with IndexOptions.Inverses.
+type edgeIndex struct {
+	inverses bool
+	once     sync.Once
+	by       map[string][]Edge
+}
+
+func (ix *Index) buildEdges() {
+	inv := InverseRelations(ix.ont)
+	by := make(map[string][]Edge)
+	for i := range ix.ont.Terms {
+		t := &ix.ont.Terms[i]
+		for _, rel := range t.Relationships {
+			if r, ok := inv[rel.Type]; ok && rel.TargetID != "" && !rel.HasValue && !rel.Self && rel.Cardinality == nil {
+				by[rel.TargetID] = append(by[rel.TargetID], Edge{Type: r, TargetID: t.ID, Inverse: true})
+			}
+		}
+	}
+	ix.edges.by = by
+}
+
+// Edges returns the relationships of id, is_a included, in asserted
+// order. With IndexOptions.Inverses, the inverses of other terms'
+// relationships to id follow, skipping any id already asserts.
+func (ix *Index) Edges(id string) []Edge {
+	t := ix.Term(id)
+	var out []Edge
+	seen := make(map[Edge]bool)
+	if t != nil {
+		for _, rel := range t.Relationships {
+			e := Edge{Type: rel.Type, TargetID: rel.TargetID}
+			seen[e] = true
+			out = append(out, e)
+		}
+	}
+	if !ix.edges.inverses {
+		return out
+	}
+	ix.edges.once.Do(ix.buildEdges)
+	for _, e := range ix.edges.by[id] {
+		if asserted := (Edge{Type: e.Type, TargetID: e.TargetID}); !seen[asserted] {
+			seen[asserted] = true
+			out = append(out, e)
+		}
+	}
+	return out
+}
@@ -0,0 +1,68 @@
+package ontology
+
+import (
+	"path"
+	"strings"
+)
+
+// idIRI maps an OBO identifier to its IRI the way the OBO Foundry does:
+// CHEBI:15377 becomes http://purl.obolibrary.org/obo/CHEBI_15377 and an
+// ontology-local ID such as has_part becomes
+// http://purl.obolibrary.org/obo/<short>#has_part. IDs that are already
+// IRIs are returned as is. oboIDFromURI is the inverse.
+func idIRI(id, short string) string {
+	if strings.Contains(id, "://") {
+		return id
+	}
+	if prefix, local, ok := strings.Cut(id, ":"); ok && prefix != "" && !strings.ContainsAny(prefix, " /#") {
+		return nsOBO + prefix + "_" + local
+	}
+	return nsOBO + short + "#" + id
+}
+
+// ShortName returns the ontology's short name ("chebi") from the OBO
+// ontology header or the OWL ontology IRI, falling back to the lower-cased
+// prefix of the first term ID.
+func ShortName(ont *Ontology) string {
+	if o := ont.Ontology; o != "" {
+		if strings.Contains(o, "://") {
+			base := path.Base(o)
+			return strings.TrimSuffix(strings.TrimSuffix(base, ".owl"), ".obo")
+		}
+		return o
+	}
+	if len(ont.Terms) > 0 {
+		if prefix, _, ok := strings.Cut(ont.Terms[0].ID, ":"); ok {
+			return strings.ToLower(prefix)
+		}
+	}
+	return "ontology"
+}
+
+// ontologyIRI returns the ontology IRI and version IRI for RDF output,
+// converting OBO-style headers ("chebi", "releases/2024-01-01") to the OBO
+// Foundry PURL layout.
+func ontologyIRI(ont *Ontology) (iri, version string) {
+	short := ShortName(ont)
+	iri = ont.Ontology
+	if !strings.Contains(iri, "://") {
+		iri = nsOBO + short + ".owl"
+	}
+	version = ont.DataVersion
+	if version != "" && !strings.Contains(version, "://") {
+		version = nsOBO + short + "/" + version + "/" + short + ".owl"
+	}
+	return iri, version
+}
+
+// oboHeaderValues is the inverse of ontologyIRI: it returns the OBO
+// ontology and data-version header values for an ontology that may have
+// been read from OWL.
+func oboHeaderValues(ont *Ontology) (name, version string) {
+	name = ShortName(ont)
+	version = ont.DataVersion
+	if strings.Contains(version, "://") {
+		version = strings.TrimSuffix(strings.TrimPrefix(version, nsOBO+name+"/"), "/"+name+".owl")
+	}
+	return name, version
+}
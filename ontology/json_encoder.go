@@ -0,0 +1,423 @@
+package ontology
+
+import (
+	"bufio"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+)
+
+// jsonWriter is a hand-rolled JSON encoder for the ontology model. It writes
+// fields in a fixed order and streams the term array, avoiding the reflection
+// and per-value allocations of encoding/json.
+//
+// Field order follows the struct declarations in model.go:
+//
+//	Ontology: format_version, data_version, ontology, metadata, terms,
+//	          typedefs, individuals
+//	Metadata: title, description, licenses, contributors
+//	Term:     id, name, namespace, definition, is_obsolete, comment,
+//	          replaced_by, consider, subsets, synonyms, xrefs, alt_ids,
+//	          relationships, intersection_of, union_of, one_of, properties
+//	          (keys sorted), property_types (keys sorted), xref_qualifiers
+//	          (keys sorted), links, obsolescence
+//
+// Empty optional fields are omitted exactly as the omitempty tags would,
+// so the output is byte-identical to encoding/json with SetEscapeHTML(false).
+type jsonWriter struct {
+	w      *bufio.Writer
+	buf    []byte     // scratch space for escaping
+	bodies *BodyStore // if set, spilled term bodies are filled in as terms are written
+}
+
+// fieldState tracks whether a comma is needed before the next object field.
+type fieldState struct {
+	jw    *jsonWriter
+	first bool
+}
+
+func (jw *jsonWriter) object() fieldState {
+	jw.w.WriteByte('{')
+	return fieldState{jw: jw, first: true}
+}
+
+// key writes a field name known not to need escaping.
+func (f *fieldState) key(name string) {
+	if !f.first {
+		f.jw.w.WriteByte(',')
+	}
+	f.first = false
+	f.jw.w.WriteByte('"')
+	f.jw.w.WriteString(name)
+	f.jw.w.WriteString(`":`)
+}
+
+// escapedKey writes an arbitrary field name, such as a property key.
+func (f *fieldState) escapedKey(name string) {
+	if !f.first {
+		f.jw.w.WriteByte(',')
+	}
+	f.first = false
+	f.jw.string(name)
+	f.jw.w.WriteByte(':')
+}
+
+func (f *fieldState) str(name, val string) {
+	f.key(name)
+	f.jw.string(val)
+}
+
+func (f *fieldState) strOmit(name, val string) {
+	if val != "" {
+		f.str(name, val)
+	}
+}
+
+func (f *fieldState) boolOmit(name string, val bool) {
+	if val {
+		f.key(name)
+		f.jw.w.WriteString("true")
+	}
+}
+
+func (f *fieldState) strsOmit(name string, vals []string) {
+	if len(vals) > 0 {
+		f.key(name)
+		f.jw.strings(vals)
+	}
+}
+
+func (f *fieldState) int(name string, val int) {
+	f.key(name)
+	f.jw.w.WriteString(strconv.Itoa(val))
+}
+
+// strMapOmit writes a string map as an object with sorted keys.
+func (f *fieldState) strMapOmit(name string, m map[string]string) {
+	if len(m) > 0 {
+		f.key(name)
+		f.jw.strMap(m)
+	}
+}
+
+func (f *fieldState) end() {
+	f.jw.w.WriteByte('}')
+}
+
+func (jw *jsonWriter) strings(vals []string) {
+	jw.w.WriteByte('[')
+	for i, v := range vals {
+		if i > 0 {
+			jw.w.WriteByte(',')
+		}
+		jw.string(v)
+	}
+	jw.w.WriteByte(']')
+}
+
+func (jw *jsonWriter) strMap(m map[string]string) {
+	o := jw.object()
+	for _, k := range sortedKeys(m) {
+		o.escapedKey(k)
+		jw.string(m[k])
+	}
+	o.end()
+}
+
+// sortedKeys returns the keys of m in sorted order, so encoders write maps
+// deterministically.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+const hexDigits = "0123456789abcdef"
+
+// string writes a quoted JSON string using the same escaping rules as
+// encoding/json with HTML escaping disabled.
+func (jw *jsonWriter) string(s string) {
+	b := append(jw.buf[:0], '"')
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' {
+				i++
+				continue
+			}
+			b = append(b, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				b = append(b, '\\', c)
+			case '\n':
+				b = append(b, '\\', 'n')
+			case '\r':
+				b = append(b, '\\', 'r')
+			case '\t':
+				b = append(b, '\\', 't')
+			default:
+				b = append(b, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b = append(b, s[start:i]...)
+			b = utf8.AppendRune(b, utf8.RuneError)
+			i += size
+			start = i
+			continue
+		}
+		// U+2028 and U+2029 are valid JSON but break JavaScript parsers.
+		if r == '\u2028' || r == '\u2029' {
+			b = append(b, s[start:i]...)
+			b = append(b, '\\', 'u', '2', '0', '2', hexDigits[r&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	b = append(b, s[start:]...)
+	b = append(b, '"')
+	jw.w.Write(b)
+	jw.buf = b
+}
+
+func (jw *jsonWriter) ontology(ont *Ontology) error {
+	o := jw.ontologyHead(ont)
+	if ont.Terms == nil {
+		jw.w.WriteString("null")
+	} else {
+		jw.w.WriteByte('[')
+		for i := range ont.Terms {
+			if i > 0 {
+				jw.w.WriteByte(',')
+			}
+			t := &ont.Terms[i]
+			if jw.bodies != nil && jw.bodies.Spilled(i) {
+				filled := *t
+				if err := jw.bodies.Fill(i, &filled); err != nil {
+					return err
+				}
+				t = &filled
+			}
+			jw.term(t)
+		}
+		jw.w.WriteByte(']')
+	}
+	return jw.ontologyTail(o, ont.TypeDefs, ont.Individuals)
+}
+
+// ontologyHead writes the ontology header fields up to the "terms" key.
+func (jw *jsonWriter) ontologyHead(ont *Ontology) fieldState {
+	o := jw.object()
+	o.strOmit("format_version", ont.FormatVersion)
+	o.strOmit("data_version", ont.DataVersion)
+	o.strOmit("ontology", ont.Ontology)
+	if ont.Metadata != nil {
+		o.key("metadata")
+		jw.metadata(ont.Metadata)
+	}
+	o.key("terms")
+	return o
+}
+
+func (jw *jsonWriter) metadata(m *OntologyMetadata) {
+	o := jw.object()
+	o.strOmit("title", m.Title)
+	o.strOmit("description", m.Description)
+	o.strsOmit("licenses", m.Licenses)
+	o.strsOmit("contributors", m.Contributors)
+	o.end()
+}
+
+// ontologyTail writes the fields after "terms" and closes the ontology.
+func (jw *jsonWriter) ontologyTail(o fieldState, typeDefs []TypeDef, individuals []Individual) error {
+	if len(typeDefs) > 0 {
+		o.key("typedefs")
+		jw.w.WriteByte('[')
+		for i := range typeDefs {
+			if i > 0 {
+				jw.w.WriteByte(',')
+			}
+			jw.typeDef(&typeDefs[i])
+		}
+		jw.w.WriteByte(']')
+	}
+
+	if len(individuals) > 0 {
+		o.key("individuals")
+		jw.w.WriteByte('[')
+		for i := range individuals {
+			if i > 0 {
+				jw.w.WriteByte(',')
+			}
+			ind := &individuals[i]
+			io := jw.object()
+			io.str("id", ind.ID)
+			io.strOmit("name", ind.Name)
+			io.strsOmit("types", ind.Types)
+			io.end()
+		}
+		jw.w.WriteByte(']')
+	}
+	o.end()
+	_, err := jw.w.WriteString("\n")
+	return err
+}
+
+func (jw *jsonWriter) typeDef(td *TypeDef) {
+	o := jw.object()
+	o.str("id", td.ID)
+	o.strOmit("name", td.Name)
+	o.boolOmit("is_transitive", td.IsTransitive)
+	o.boolOmit("is_reflexive", td.IsReflexive)
+	o.strsOmit("holds_over_chain", td.HoldsOverChain)
+	o.strOmit("inverse_of", td.InverseOf)
+	o.end()
+}
+
+func (jw *jsonWriter) term(t *Term) {
+	o := jw.object()
+	o.str("id", t.ID)
+	o.strOmit("name", t.Name)
+	o.strOmit("namespace", t.Namespace)
+	o.strOmit("definition", t.Definition)
+	o.boolOmit("is_obsolete", t.IsObsolete)
+	o.strOmit("comment", t.Comment)
+	o.strsOmit("replaced_by", t.ReplacedBy)
+	o.strsOmit("consider", t.Consider)
+	o.strsOmit("subsets", t.Subsets)
+
+	if len(t.Synonyms) > 0 {
+		o.key("synonyms")
+		jw.w.WriteByte('[')
+		for i := range t.Synonyms {
+			if i > 0 {
+				jw.w.WriteByte(',')
+			}
+			syn := &t.Synonyms[i]
+			so := jw.object()
+			so.str("text", syn.Text)
+			so.str("scope", syn.Scope)
+			so.strOmit("type", syn.Type)
+			so.strsOmit("xrefs", syn.Xrefs)
+			so.end()
+		}
+		jw.w.WriteByte(']')
+	}
+
+	o.strsOmit("xrefs", t.Xrefs)
+	o.strsOmit("alt_ids", t.AltIDs)
+
+	if len(t.Relationships) > 0 {
+		o.key("relationships")
+		jw.w.WriteByte('[')
+		for i := range t.Relationships {
+			if i > 0 {
+				jw.w.WriteByte(',')
+			}
+			rel := &t.Relationships[i]
+			ro := jw.object()
+			ro.str("type", rel.Type)
+			ro.str("target_id", rel.TargetID)
+			ro.strOmit("name", rel.Name)
+			ro.boolOmit("has_value", rel.HasValue)
+			ro.boolOmit("self", rel.Self)
+			ro.strMapOmit("qualifiers", rel.Qualifiers)
+			if c := rel.Cardinality; c != nil {
+				ro.key("cardinality")
+				co := jw.object()
+				co.int("min", c.Min)
+				co.int("max", c.Max)
+				co.end()
+			}
+			ro.end()
+		}
+		jw.w.WriteByte(']')
+	}
+
+	if len(t.IntersectionOf) > 0 {
+		o.key("intersection_of")
+		jw.w.WriteByte('[')
+		for i := range t.IntersectionOf {
+			if i > 0 {
+				jw.w.WriteByte(',')
+			}
+			part := &t.IntersectionOf[i]
+			po := jw.object()
+			po.strOmit("relationship", part.Relationship)
+			po.str("target_id", part.TargetID)
+			po.boolOmit("self", part.Self)
+			po.end()
+		}
+		jw.w.WriteByte(']')
+	}
+	o.strsOmit("union_of", t.UnionOf)
+	o.strsOmit("one_of", t.OneOf)
+	if len(t.NonEL) > 0 {
+		o.key("non_el")
+		jw.w.WriteByte('[')
+		for i := range t.NonEL {
+			if i > 0 {
+				jw.w.WriteByte(',')
+			}
+			ax := &t.NonEL[i]
+			ao := jw.object()
+			ao.str("construct", ax.Construct)
+			ao.strOmit("relationship", ax.Relationship)
+			ao.strsOmit("targets", ax.Targets)
+			ao.end()
+		}
+		jw.w.WriteByte(']')
+	}
+
+	o.strMapOmit("properties", t.Properties)
+	o.strMapOmit("property_types", t.PropertyTypes)
+	if len(t.XrefQualifiers) > 0 {
+		o.key("xref_qualifiers")
+		xo := jw.object()
+		for _, x := range sortedKeys(t.XrefQualifiers) {
+			xo.escapedKey(x)
+			jw.strMap(t.XrefQualifiers[x])
+		}
+		xo.end()
+	}
+
+	if t.Links != nil {
+		o.key("links")
+		lo := jw.object()
+		lo.strOmit("entry", t.Links.Entry)
+		lo.strOmit("image", t.Links.Image)
+		if len(t.Links.Xrefs) > 0 {
+			lo.key("xrefs")
+			jw.w.WriteByte('[')
+			for i := range t.Links.Xrefs {
+				if i > 0 {
+					jw.w.WriteByte(',')
+				}
+				xo := jw.object()
+				xo.str("xref", t.Links.Xrefs[i].Xref)
+				xo.str("url", t.Links.Xrefs[i].URL)
+				xo.end()
+			}
+			jw.w.WriteByte(']')
+		}
+		lo.end()
+	}
+	if t.Obsolescence != nil {
+		o.key("obsolescence")
+		ob := jw.object()
+		ob.str("reason", t.Obsolescence.Reason)
+		ob.str("source", t.Obsolescence.Source)
+		ob.end()
+	}
+	o.end()
+}
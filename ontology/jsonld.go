@@ -0,0 +1,266 @@
+package ontology
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// jsonldFields maps the fixed term fields of WriteJSONLD to the IRIs the
+// OWL writer uses for them, and the @type of their values: "@id" for
+// IRIs, "" for plain strings.
+var jsonldFields = []struct{ name, iri, typ string }{
+	{"id", "oboInOwl:id", ""},
+	{"name", "rdfs:label", ""},
+	{"definition", "obo:IAO_0000115", ""},
+	{"namespace", "oboInOwl:hasOBONamespace", ""},
+	{"comment", "rdfs:comment", ""},
+	{"is_obsolete", "owl:deprecated", "xsd:boolean"},
+	{"replaced_by", "obo:IAO_0100001", "@id"},
+	{"consider", "oboInOwl:consider", ""},
+	{"subsets", "oboInOwl:inSubset", "@id"},
+	{"exact_synonyms", "oboInOwl:hasExactSynonym", ""},
+	{"broad_synonyms", "oboInOwl:hasBroadSynonym", ""},
+	{"narrow_synonyms", "oboInOwl:hasNarrowSynonym", ""},
+	{"related_synonyms", "oboInOwl:hasRelatedSynonym", ""},
+	{"xrefs", "oboInOwl:hasDbXref", ""},
+	{"alt_ids", "oboInOwl:hasAlternativeId", ""},
+	{"is_a", "rdfs:subClassOf", "@id"},
+	{"types", "rdf:type", "@id"},
+	{"version_iri", "owl:versionIRI", "@id"},
+	{"title", "dcterms:title", ""},
+	{"description", "dcterms:description", ""},
+	{"licenses", "dcterms:license", ""},
+	{"contributors", "dcterms:contributor", ""},
+}
+
+// jsonldPrefixes are the namespaces every JSON-LD @context declares.
+var jsonldPrefixes = []struct{ prefix, ns string }{
+	{"dcterms", nsDCTerms},
+	{"obo", nsOBO},
+	{"oboInOwl", nsOBOInOwl},
+	{"owl", nsOWL},
+	{"rdf", nsRDF},
+	{"rdfs", nsRDFS},
+	{"xsd", nsXSD},
+}
+
+// WriteJSONLD writes the ontology as JSON-LD: a @graph of the ontology,
+// its Typedefs, terms and individuals, with a @context generated for it
+// that maps each field to the IRI the OWL output uses (rdfs:label,
+// IAO_0000115, the oboInOwl annotations). is_a is rdfs:subClassOf; every
+// other relationship type gets a field of its own linking the term
+// straight to the target, the OBO relation-graph reading rather than
+// OWL's restrictions. Property values get a field named after the local
+// part of their IRI. intersection_of, union_of and one_of are left out;
+// use OWL or obographs output for logical definitions.
+func WriteJSONLD(ont *Ontology, w io.Writer) error {
+	bw := bufio.NewWriterSize(w, writerBufferSize)
+	jw := &jsonWriter{w: bw, buf: make([]byte, 0, 256)}
+	short := ShortName(ont)
+	ctx := newJSONLDContext(ont, short)
+	compact := func(iri string) string {
+		if rest, ok := strings.CutPrefix(iri, nsOBO); ok {
+			return "obo:" + rest
+		}
+		return iri
+	}
+	ref := func(id string) string { return compact(idIRI(id, short)) }
+	refs := func(ids []string) []string {
+		out := make([]string, len(ids))
+		for i, id := range ids {
+			out[i] = ref(id)
+		}
+		return out
+	}
+
+	doc := jw.object()
+	doc.key("@context")
+	ctx.write(jw)
+	doc.key("@graph")
+	bw.WriteByte('[')
+
+	iri, version := ontologyIRI(ont)
+	o := jw.object()
+	o.str("@id", compact(iri))
+	o.str("@type", "owl:Ontology")
+	if version != "" {
+		o.str("version_iri", compact(version))
+	}
+	if m := ont.Metadata; m != nil {
+		o.strOmit("title", m.Title)
+		o.strOmit("description", m.Description)
+		o.strsOmit("licenses", m.Licenses)
+		o.strsOmit("contributors", m.Contributors)
+	}
+	o.end()
+
+	for i := range ont.TypeDefs {
+		td := &ont.TypeDefs[i]
+		bw.WriteString(",\n")
+		o := jw.object()
+		o.str("@id", ref(td.ID))
+		o.str("@type", "owl:ObjectProperty")
+		o.strOmit("name", td.Name)
+		o.end()
+	}
+
+	for i := range ont.Terms {
+		t := &ont.Terms[i]
+		bw.WriteString(",\n")
+		o := jw.object()
+		o.str("@id", ref(t.ID))
+		o.str("@type", "owl:Class")
+		o.str("id", t.ID)
+		o.strOmit("name", t.Name)
+		o.strOmit("definition", t.Definition)
+		o.strOmit("namespace", t.Namespace)
+		o.strOmit("comment", t.Comment)
+		o.boolOmit("is_obsolete", t.IsObsolete)
+		o.strsOmit("replaced_by", refs(t.ReplacedBy))
+		o.strsOmit("consider", t.Consider)
+		o.strsOmit("subsets", refs(t.Subsets))
+		for _, scope := range []string{"EXACT", "BROAD", "NARROW", "RELATED"} {
+			var texts []string
+			for _, s := range t.Synonyms {
+				if s.Scope == scope || scope == "RELATED" && synonymProperty(s.Scope) == "hasRelatedSynonym" {
+					texts = append(texts, s.Text)
+				}
+			}
+			o.strsOmit(strings.ToLower(scope)+"_synonyms", texts)
+		}
+		o.strsOmit("xrefs", t.Xrefs)
+		o.strsOmit("alt_ids", t.AltIDs)
+
+		var rels []string
+		targets := make(map[string][]string)
+		for _, rel := range t.Relationships {
+			if rel.Self || rel.TargetID == "" {
+				continue
+			}
+			if _, ok := targets[rel.Type]; !ok {
+				rels = append(rels, rel.Type)
+			}
+			targets[rel.Type] = append(targets[rel.Type], ref(rel.TargetID))
+		}
+		for _, r := range rels {
+			o.escapedKey(ctx.relations[r])
+			jw.strings(targets[r])
+		}
+		for _, k := range sortedKeys(t.Properties) {
+			o.escapedKey(ctx.properties[k])
+			if dt, ok := t.PropertyTypes[k]; ok {
+				vo := jw.object()
+				vo.str("@value", t.Properties[k])
+				vo.str("@type", dt)
+				vo.end()
+			} else {
+				jw.string(t.Properties[k])
+			}
+		}
+		o.end()
+	}
+
+	for i := range ont.Individuals {
+		ind := &ont.Individuals[i]
+		bw.WriteString(",\n")
+		o := jw.object()
+		o.str("@id", ref(ind.ID))
+		o.str("@type", "owl:NamedIndividual")
+		o.strOmit("name", ind.Name)
+		o.strsOmit("types", refs(ind.Types))
+		o.end()
+	}
+
+	bw.WriteString("]")
+	doc.end()
+	bw.WriteByte('\n')
+	return bw.Flush()
+}
+
+// jsonldContext is the generated part of a JSON-LD @context: the field
+// names chosen for the ontology's relationship types and property keys.
+type jsonldContext struct {
+	relations  map[string]string // relationship type → field
+	properties map[string]string // property key → field
+	iris       map[string]string // field → IRI, for the generated fields
+	literal    map[string]bool   // generated fields holding literals, not IRIs
+	order      []string          // generated fields in first-use order
+}
+
+// newJSONLDContext names a field for every relationship type and property
+// key in the ontology. Relationship fields are the type with ':' made '_'
+// (a term containing ':' would read as a compact IRI); property fields are
+// the local part of the key's IRI. A name that is taken falls back to the
+// full IRI, which JSON-LD accepts as a field name without a definition.
+func newJSONLDContext(ont *Ontology, short string) *jsonldContext {
+	c := &jsonldContext{
+		relations:  make(map[string]string),
+		properties: make(map[string]string),
+		iris:       make(map[string]string),
+		literal:    make(map[string]bool),
+	}
+	taken := make(map[string]bool, len(jsonldFields)+len(jsonldPrefixes))
+	for _, f := range jsonldFields {
+		taken[f.name] = true
+	}
+	for _, p := range jsonldPrefixes {
+		taken[p.prefix] = true
+	}
+	name := func(want, iri string) string {
+		if want == "" || taken[want] {
+			return iri
+		}
+		taken[want] = true
+		c.iris[want] = iri
+		c.order = append(c.order, want)
+		return want
+	}
+	for i := range ont.Terms {
+		t := &ont.Terms[i]
+		for _, rel := range t.Relationships {
+			if _, ok := c.relations[rel.Type]; !ok && rel.Type != "is_a" {
+				c.relations[rel.Type] = name(strings.ReplaceAll(rel.Type, ":", "_"), idIRI(rel.Type, short))
+			}
+		}
+		for k := range t.Properties {
+			if _, ok := c.properties[k]; !ok {
+				iri := propertyIRI(k, short)
+				_, local, _ := splitIRI(iri)
+				c.properties[k] = name(local, iri)
+				c.literal[c.properties[k]] = true
+			}
+		}
+	}
+	c.relations["is_a"] = "is_a"
+	return c
+}
+
+func (c *jsonldContext) write(jw *jsonWriter) {
+	o := jw.object()
+	for _, p := range jsonldPrefixes {
+		o.str(p.prefix, p.ns)
+	}
+	term := func(name, iri, typ string) {
+		o.escapedKey(name)
+		if typ == "" {
+			jw.string(iri)
+			return
+		}
+		to := jw.object()
+		to.str("@id", iri)
+		to.str("@type", typ)
+		to.end()
+	}
+	for _, f := range jsonldFields {
+		term(f.name, f.iri, f.typ)
+	}
+	for _, name := range c.order {
+		if c.literal[name] {
+			term(name, c.iris[name], "")
+		} else {
+			term(name, c.iris[name], "@id")
+		}
+	}
+	o.end()
+}
@@ -0,0 +1,97 @@
+package ontology
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Link template keys for term-level URLs. Any other key is treated as an
+// xref database prefix (the part of an xref before the first colon).
+const (
+	LinkEntry = "entry"
+	LinkImage = "image"
+)
+
+// LinkTemplates maps a link kind or xref prefix to a URL template.
+// Templates may contain {id} (the full CURIE, e.g. CHEBI:15377) and
+// {local} (the part after the prefix, e.g. 15377). They are substituted
+// path-escaped before the template's '?' and query-escaped after it, so a
+// space, slash or '&' in an xref cannot break the URL or add parameters.
+type LinkTemplates map[string]string
+
+// DefaultLinkTemplates covers the ChEBI entry page, structure image and the
+// xref databases most commonly seen in ChEBI releases.
+var DefaultLinkTemplates = LinkTemplates{
+	LinkEntry:             "https://www.ebi.ac.uk/chebi/searchId.do?chebiId={id}",
+	LinkImage:             "https://www.ebi.ac.uk/chebi/displayImage.do?defaultImage=true&chebiId={id}",
+	"CAS":                 "https://commonchemistry.cas.org/detail?cas_rn={local}",
+	"KEGG":                "https://www.kegg.jp/entry/{local}",
+	"HMDB":                "https://hmdb.ca/metabolites/{local}",
+	"DrugBank":            "https://go.drugbank.com/drugs/{local}",
+	"PMID":                "https://pubmed.ncbi.nlm.nih.gov/{local}",
+	"Wikipedia":           "https://en.wikipedia.org/wiki/{local}",
+	"MetaCyc":             "https://metacyc.org/compound?id={local}",
+	"LIPID_MAPS_instance": "https://www.lipidmaps.org/databases/lmsd/{local}",
+}
+
+// TermLinks holds resolved URLs for a term.
+type TermLinks struct {
+	Entry string     `json:"entry,omitempty"`
+	Image string     `json:"image,omitempty"`
+	Xrefs []XrefLink `json:"xrefs,omitempty"`
+}
+
+// XrefLink pairs an xref with its resolved URL.
+type XrefLink struct {
+	Xref string `json:"xref"`
+	URL  string `json:"url"`
+}
+
+// Resolve builds the links for a term. Xrefs whose prefix has no template
+// are omitted. Returns nil if nothing could be resolved.
+func (lt LinkTemplates) Resolve(t *Term) *TermLinks {
+	var links TermLinks
+	if tmpl, ok := lt[LinkEntry]; ok {
+		links.Entry = expandLink(tmpl, t.ID)
+	}
+	if tmpl, ok := lt[LinkImage]; ok {
+		links.Image = expandLink(tmpl, t.ID)
+	}
+	for _, x := range t.Xrefs {
+		// OBO xrefs may carry a trailing quoted description.
+		curie, _, _ := strings.Cut(x, " ")
+		prefix, _, ok := strings.Cut(curie, ":")
+		if !ok {
+			continue
+		}
+		if tmpl, ok := lt[prefix]; ok {
+			links.Xrefs = append(links.Xrefs, XrefLink{Xref: curie, URL: expandLink(tmpl, curie)})
+		}
+	}
+	if links.Entry == "" && links.Image == "" && len(links.Xrefs) == 0 {
+		return nil
+	}
+	return &links
+}
+
+// AddLinks resolves links for every term in the ontology.
+func AddLinks(ont *Ontology, lt LinkTemplates) {
+	for i := range ont.Terms {
+		ont.Terms[i].Links = lt.Resolve(&ont.Terms[i])
+	}
+}
+
+// expandLink substitutes {id} and {local} in a URL template, with
+// url.PathEscape in its path and url.QueryEscape in its query.
+func expandLink(tmpl, curie string) string {
+	local := curie
+	if _, after, ok := strings.Cut(curie, ":"); ok {
+		local = after
+	}
+	path, query, hasQuery := strings.Cut(tmpl, "?")
+	link := strings.NewReplacer("{id}", url.PathEscape(curie), "{local}", url.PathEscape(local)).Replace(path)
+	if hasQuery {
+		link += "?" + strings.NewReplacer("{id}", url.QueryEscape(curie), "{local}", url.QueryEscape(local)).Replace(query)
+	}
+	return link
+}
@@ -0,0 +1,19 @@
+package ontology
+
+import "testing"
+
+func TestExpandLinkEscaping(t *testing.T) {
+	for _, c := range []struct {
+		tmpl, curie, want string
+	}{
+		{"https://e.org/c?id={id}", "CHEBI:15377", "https://e.org/c?id=CHEBI%3A15377"},
+		{"https://e.org/x/{local}", "X:a&b=c/d", "https://e.org/x/a&b=c%2Fd"},
+		// An '&' or '=' in a query value must not start another parameter.
+		{"https://e.org/s?q={local}&db=x", "X:a&db=y", "https://e.org/s?q=a%26db%3Dy&db=x"},
+		{"https://e.org/{local}?full={id}", "X:a b+c", "https://e.org/a%20b+c?full=X%3Aa+b%2Bc"},
+	} {
+		if got := expandLink(c.tmpl, c.curie); got != c.want {
+			t.Errorf("expandLink(%q, %q) = %q, want %q", c.tmpl, c.curie, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,346 @@
+package ontology
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Lint checks.
+const (
+	LintConjugateFormula  = "conjugate_formula"  // conjugate acid ≠ base + H
+	LintConjugateCharge   = "conjugate_charge"   // conjugate acid charge ≠ base charge + 1
+	LintMass              = "mass"               // average mass disagrees with the formula
+	LintMonoisotopicMass  = "monoisotopic_mass"  // monoisotopic mass disagrees with the formula
+	LintDuplicateInChIKey = "duplicate_inchikey" // distinct terms share an InChIKey
+	LintDuplicateSMILES   = "duplicate_smiles"   // distinct terms share a SMILES string
+)
+
+// lintPasses are the passes over the ontology and the checks each one
+// reports; a pass runs if any of its checks is enabled.
+var lintPasses = []struct {
+	checks []string
+	run    func(*linter)
+}{
+	{[]string{LintConjugateFormula, LintConjugateCharge}, (*linter).conjugates},
+	{[]string{LintMass, LintMonoisotopicMass}, (*linter).masses},
+	{[]string{LintDuplicateInChIKey, LintDuplicateSMILES}, (*linter).duplicates},
+}
+
+// LintCheckNames returns the available checks, sorted.
+func LintCheckNames() []string {
+	var names []string
+	for _, p := range lintPasses {
+		names = append(names, p.checks...)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LintFinding is one curation problem. Related is the other term of a
+// pair check, such as the conjugate acid of a base.
+type LintFinding struct {
+	Check   string `json:"check"`
+	TermID  string `json:"term_id"`
+	Related string `json:"related,omitempty"`
+	Message string `json:"message"`
+}
+
+// LintReport is the result of Lint, sorted by check and term.
+type LintReport struct {
+	Findings []LintFinding `json:"findings"`
+	// Unparsed counts terms whose formula could not be parsed (polymers,
+	// variable counts), which the formula checks skip.
+	Unparsed int `json:"unparsed_formulas"`
+}
+
+// LintOptions configure Lint.
+type LintOptions struct {
+	Checks        []string // checks to run; all if empty
+	MassTolerance float64  // in daltons; DefaultMassTolerance if 0
+}
+
+// DefaultMassTolerance absorbs the rounding of ChEBI's mass properties.
+const DefaultMassTolerance = 0.01
+
+// Lint runs curation checks over the non-obsolete terms.
+func Lint(ont *Ontology, opts LintOptions) (*LintReport, error) {
+	if len(opts.Checks) == 0 {
+		opts.Checks = LintCheckNames()
+	}
+	if opts.MassTolerance == 0 {
+		opts.MassTolerance = DefaultMassTolerance
+	}
+	l := &linter{ont: ont, opts: opts, enabled: make(map[string]bool), formulas: make(map[string]Formula)}
+	known := LintCheckNames()
+	for _, c := range opts.Checks {
+		if i := sort.SearchStrings(known, c); i == len(known) || known[i] != c {
+			return nil, fmt.Errorf("unknown lint check %q (available: %s)", c, strings.Join(known, ", "))
+		}
+		l.enabled[c] = true
+	}
+	for _, p := range lintPasses {
+		for _, c := range p.checks {
+			if l.enabled[c] {
+				p.run(l)
+				break
+			}
+		}
+	}
+	sort.SliceStable(l.rep.Findings, func(i, j int) bool {
+		a, b := l.rep.Findings[i], l.rep.Findings[j]
+		if a.Check != b.Check {
+			return a.Check < b.Check
+		}
+		return a.TermID < b.TermID
+	})
+	return &l.rep, nil
+}
+
+type linter struct {
+	ont      *Ontology
+	opts     LintOptions
+	enabled  map[string]bool
+	rep      LintReport
+	formulas map[string]Formula // parsed on first use; nil if unparsable
+}
+
+func (l *linter) report(check, id, related, format string, args ...any) {
+	if l.enabled[check] {
+		l.rep.Findings = append(l.rep.Findings, LintFinding{Check: check, TermID: id, Related: related, Message: fmt.Sprintf(format, args...)})
+	}
+}
+
+// chemistry property names, as ChEBI has published them over time.
+var chemPropertyKeys = map[string][]string{
+	"formula":          {"http://purl.obolibrary.org/obo/chebi/formula", "http://purl.obolibrary.org/obo/chemrof/generalized_empirical_formula"},
+	"charge":           {"http://purl.obolibrary.org/obo/chebi/charge", "http://purl.obolibrary.org/obo/chemrof/charge"},
+	"mass":             {"http://purl.obolibrary.org/obo/chebi/mass", "http://purl.obolibrary.org/obo/chemrof/mass"},
+	"inchikey":         {"http://purl.obolibrary.org/obo/chebi/inchikey", "http://purl.obolibrary.org/obo/chemrof/inchi_key_string"},
+	"smiles":           {"http://purl.obolibrary.org/obo/chebi/smiles", "http://purl.obolibrary.org/obo/chemrof/smiles_string"},
+	"monoisotopicmass": {"http://purl.obolibrary.org/obo/chebi/monoisotopicmass", "http://purl.obolibrary.org/obo/chemrof/monoisotopic_mass"},
+}
+
+// chemProperty returns a chemistry property of t ("formula", "charge",
+// "mass", "monoisotopicmass", "inchikey", "smiles").
+func chemProperty(t *Term, name string) (string, bool) {
+	for _, k := range chemPropertyKeys[name] {
+		if v, ok := t.Properties[k]; ok {
+			return strings.TrimSpace(v), true
+		}
+	}
+	return "", false
+}
+
+// ChemProperty is chemProperty for other packages.
+func ChemProperty(t *Term, name string) (string, bool) {
+	return chemProperty(t, name)
+}
+
+// formula returns t's parsed formula, or nil if it has none or it does
+// not parse.
+func (l *linter) formula(t *Term) Formula {
+	if f, ok := l.formulas[t.ID]; ok {
+		return f
+	}
+	var f Formula
+	if s, ok := chemProperty(t, "formula"); ok {
+		var err error
+		if f, err = ParseFormula(s); err != nil {
+			l.rep.Unparsed++
+			f = nil
+		}
+	}
+	l.formulas[t.ID] = f
+	return f
+}
+
+// conjugates checks every is_conjugate_base_of / is_conjugate_acid_of
+// pair once: the acid must be the base plus one H and one unit of charge.
+func (l *linter) conjugates() {
+	terms := make(map[string]*Term, len(l.ont.Terms))
+	for i := range l.ont.Terms {
+		if t := &l.ont.Terms[i]; !t.IsObsolete {
+			terms[t.ID] = t
+		}
+	}
+	seen := make(map[[2]string]bool)
+	for i := range l.ont.Terms {
+		t := &l.ont.Terms[i]
+		if t.IsObsolete {
+			continue
+		}
+		for _, rel := range t.Relationships {
+			var base, acid *Term
+			switch rel.Type {
+			case "is_conjugate_base_of":
+				base, acid = t, terms[rel.TargetID]
+			case "is_conjugate_acid_of":
+				base, acid = terms[rel.TargetID], t
+			default:
+				continue
+			}
+			if base == nil || acid == nil || seen[[2]string{base.ID, acid.ID}] {
+				continue
+			}
+			seen[[2]string{base.ID, acid.ID}] = true
+
+			if fb, fa := l.formula(base), l.formula(acid); fb != nil && fa != nil {
+				d := fa.Diff(fb)
+				if len(d) != 1 || d["H"] != 1 {
+					l.report(LintConjugateFormula, base.ID, acid.ID, "conjugate acid %s (%s) minus base (%s) is %s, not H",
+						acid.ID, fa, fb, signedFormula(d))
+				}
+			}
+			cb, okb := chemProperty(base, "charge")
+			ca, oka := chemProperty(acid, "charge")
+			if okb && oka {
+				qb, errb := ParseCharge(cb)
+				qa, erra := ParseCharge(ca)
+				if errb == nil && erra == nil && qa-qb != 1 {
+					l.report(LintConjugateCharge, base.ID, acid.ID, "conjugate acid %s has charge %d, base has %d; expected a difference of +1",
+						acid.ID, qa, qb)
+				}
+			}
+		}
+	}
+}
+
+// signedFormula writes a formula difference such as "+H2 -O" or "none".
+func signedFormula(d Formula) string {
+	if len(d) == 0 {
+		return "none"
+	}
+	var parts []string
+	for el, n := range d {
+		sign := "+"
+		if n < 0 {
+			sign, n = "-", -n
+		}
+		if n == 1 {
+			parts = append(parts, sign+el)
+		} else {
+			parts = append(parts, fmt.Sprintf("%s%s%d", sign, el, n))
+		}
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, " ")
+}
+
+// masses compares the mass properties with the masses computed from the
+// formula, for formulas made only of elements with known masses.
+func (l *linter) masses() {
+	for i := range l.ont.Terms {
+		t := &l.ont.Terms[i]
+		if t.IsObsolete {
+			continue
+		}
+		f := l.formula(t)
+		if f == nil {
+			continue
+		}
+		avg, mono, ok := f.Mass()
+		if !ok {
+			continue
+		}
+		for _, c := range []struct {
+			check, prop string
+			want        float64
+		}{{LintMass, "mass", avg}, {LintMonoisotopicMass, "monoisotopicmass", mono}} {
+			s, ok := chemProperty(t, c.prop)
+			if !ok {
+				continue
+			}
+			got, err := ParseMass(s)
+			if err != nil {
+				l.report(c.check, t.ID, "", "%s %q is not a number", c.prop, s)
+				continue
+			}
+			if math.Abs(got-c.want) > l.opts.MassTolerance {
+				l.report(c.check, t.ID, "", "%s %s differs from %.5f computed from %s", c.prop, s, c.want, f)
+			}
+		}
+	}
+}
+
+// duplicates reports every pair of distinct terms with the same InChIKey
+// or SMILES. SMILES are compared as written, without canonicalization,
+// which finds the duplicates ChEBI's own toolkit wrote identically.
+func (l *linter) duplicates() {
+	for _, c := range []struct{ check, prop, label string }{
+		{LintDuplicateInChIKey, "inchikey", "InChIKey"},
+		{LintDuplicateSMILES, "smiles", "SMILES"},
+	} {
+		if !l.enabled[c.check] {
+			continue
+		}
+		groups := make(map[string][]string)
+		var keys []string
+		for i := range l.ont.Terms {
+			t := &l.ont.Terms[i]
+			if t.IsObsolete {
+				continue
+			}
+			v, ok := chemProperty(t, c.prop)
+			if !ok || v == "" {
+				continue
+			}
+			if c.prop == "inchikey" {
+				v = strings.TrimPrefix(strings.ToUpper(v), "INCHIKEY=")
+			}
+			if _, ok := groups[v]; !ok {
+				keys = append(keys, v)
+			}
+			groups[v] = append(groups[v], t.ID)
+		}
+		for _, v := range keys {
+			ids := groups[v]
+			sort.Strings(ids)
+			ids = slices.Compact(ids) // repeated stanzas of one term
+			for i := range ids {
+				for j := i + 1; j < len(ids); j++ {
+					l.report(c.check, ids[i], ids[j], "shares %s %s with %s", c.label, v, ids[j])
+				}
+			}
+		}
+	}
+}
+
+// MergePair is a pair of terms that duplicate checks found to describe
+// the same structure, a candidate for merging.
+type MergePair struct {
+	A, B   string
+	Shared []string // the duplicate checks that matched, sorted
+}
+
+// MergePairs collects the duplicate findings of rep into one pair per
+// pair of terms, ordered by A then B.
+func MergePairs(rep *LintReport) []MergePair {
+	var pairs []MergePair
+	pos := make(map[[2]string]int)
+	for _, f := range rep.Findings {
+		if f.Check != LintDuplicateInChIKey && f.Check != LintDuplicateSMILES {
+			continue
+		}
+		key := [2]string{f.TermID, f.Related}
+		i, ok := pos[key]
+		if !ok {
+			i = len(pairs)
+			pos[key] = i
+			pairs = append(pairs, MergePair{A: f.TermID, B: f.Related})
+		}
+		pairs[i].Shared = append(pairs[i].Shared, f.Check)
+	}
+	for i := range pairs {
+		sort.Strings(pairs[i].Shared)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].A != pairs[j].A {
+			return pairs[i].A < pairs[j].A
+		}
+		return pairs[i].B < pairs[j].B
+	})
+	return pairs
+}
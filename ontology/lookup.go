@@ -0,0 +1,120 @@
+package ontology
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// RefError reports a term reference that resolves to no term or to
+// several. Candidates is empty for unknown references.
+type RefError struct {
+	Ref        string
+	Candidates []string
+}
+
+func (e *RefError) Error() string {
+	if len(e.Candidates) == 0 {
+		return fmt.Sprintf("unknown term %q", e.Ref)
+	}
+	return fmt.Sprintf("term %q is ambiguous: %s", e.Ref, strings.Join(e.Candidates, ", "))
+}
+
+// nameIndex maps lower-cased labels and synonyms to term IDs. It is built
+// on first use so commands that only look up IDs don't pay for it.
+type nameIndex struct {
+	once     sync.Once
+	labels   map[string][]string
+	exact    map[string][]string // EXACT synonyms
+	synonyms map[string][]string // other scopes
+}
+
+func (ix *Index) buildNames() {
+	n := &ix.names
+	n.labels = make(map[string][]string, len(ix.ont.Terms))
+	n.exact = make(map[string][]string)
+	n.synonyms = make(map[string][]string)
+	for i := range ix.ont.Terms {
+		t := &ix.ont.Terms[i]
+		if t.Name != "" {
+			addName(n.labels, t.Name, t.ID)
+		}
+		for _, s := range t.Synonyms {
+			if s.Scope == "EXACT" {
+				addName(n.exact, s.Text, t.ID)
+			} else {
+				addName(n.synonyms, s.Text, t.ID)
+			}
+		}
+	}
+}
+
+func addName(m map[string][]string, name, id string) {
+	key := normalizeRef(name)
+	for _, have := range m[key] {
+		if have == id {
+			return
+		}
+	}
+	m[key] = append(m[key], id)
+}
+
+// NormalizeRef returns the key Lookup matches names by: s lower-cased
+// with whitespace collapsed.
+func NormalizeRef(s string) string { return normalizeRef(s) }
+
+func normalizeRef(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// Lookup resolves a term reference to a primary ID. ref may be an ID, an
+// alt ID, a label or a synonym; names are matched case-insensitively with
+// whitespace collapsed. Matches are tried in that order, labels before
+// EXACT synonyms before other synonyms, and the first tier with a match
+// decides. Within a tier live terms win over obsolete ones. A *RefError is
+// returned if nothing matches or the deciding tier has several terms.
+func (ix *Index) Lookup(ref string) (string, error) {
+	ref = strings.TrimSpace(ref)
+	if id := ix.Primary(ref); id != "" {
+		return id, nil
+	}
+	ix.names.once.Do(ix.buildNames)
+	key := normalizeRef(ref)
+	for _, tier := range []map[string][]string{ix.names.labels, ix.names.exact, ix.names.synonyms} {
+		ids := tier[key]
+		if len(ids) == 0 {
+			continue
+		}
+		var live []string
+		for _, id := range ids {
+			if !ix.Term(id).IsObsolete {
+				live = append(live, id)
+			}
+		}
+		if len(live) > 0 {
+			ids = live
+		}
+		if len(ids) == 1 {
+			return ids[0], nil
+		}
+		sorted := append([]string(nil), ids...)
+		sort.Strings(sorted)
+		return "", &RefError{Ref: ref, Candidates: sorted}
+	}
+	return "", &RefError{Ref: ref}
+}
+
+// LookupAll resolves every reference with Lookup, stopping at the first
+// failure.
+func (ix *Index) LookupAll(refs []string) ([]string, error) {
+	ids := make([]string, len(refs))
+	for i, ref := range refs {
+		id, err := ix.Lookup(ref)
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
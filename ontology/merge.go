@@ -0,0 +1,96 @@
+package ontology
+
+// Merge combines ontologies into one, taking the header of the first and
+// the terms, typedefs and individuals of all, in order. Stanzas for the
+// same ID are merged as Dedupe does, whose report is returned. The title
+// and description are the first's too, but licenses and contributors are
+// collected from all, since the result is covered by each source's
+// license.
+func Merge(onts ...*Ontology) (*Ontology, *DedupeReport) {
+	out := &Ontology{}
+	if len(onts) > 0 {
+		out.FormatVersion = onts[0].FormatVersion
+		out.DataVersion = onts[0].DataVersion
+		out.Ontology = onts[0].Ontology
+	}
+	for _, o := range onts {
+		if m := o.Metadata; m != nil {
+			addMetadata(out, nsDCTerms+"title", m.Title)
+			addMetadata(out, nsDCTerms+"description", m.Description)
+			for _, l := range m.Licenses {
+				addMetadata(out, nsDCTerms+"license", l)
+			}
+			for _, c := range m.Contributors {
+				addMetadata(out, nsDCTerms+"contributor", c)
+			}
+		}
+		out.Terms = append(out.Terms, o.Terms...)
+		out.TypeDefs = append(out.TypeDefs, o.TypeDefs...)
+		out.Individuals = append(out.Individuals, o.Individuals...)
+	}
+	return out, Dedupe(out)
+}
+
+// FilterOptions select the terms Filter keeps. A term is kept if it meets
+// every criterion given; empty criteria select everything.
+type FilterOptions struct {
+	Namespaces   []string // terms in one of these namespaces
+	Subsets      []string // terms in at least one of these subsets
+	Roots        []string // the roots and their is_a descendants (primary IDs)
+	DropObsolete bool
+}
+
+// Filter returns a copy of ont with only the selected terms. Typedefs and
+// individuals are kept; relationships to terms left out are kept as they
+// are, as in a split.
+func Filter(ont *Ontology, opts FilterOptions) *Ontology {
+	var under map[string]bool
+	if len(opts.Roots) > 0 {
+		ix := NewIndex(ont)
+		under = make(map[string]bool)
+		for _, r := range opts.Roots {
+			for id := range ix.descendants(r) {
+				under[id] = true
+			}
+		}
+	}
+	namespaces := make(map[string]bool, len(opts.Namespaces))
+	for _, ns := range opts.Namespaces {
+		namespaces[ns] = true
+	}
+	subsets := make(map[string]bool, len(opts.Subsets))
+	for _, s := range opts.Subsets {
+		subsets[s] = true
+	}
+
+	out := &Ontology{
+		FormatVersion: ont.FormatVersion,
+		DataVersion:   ont.DataVersion,
+		Ontology:      ont.Ontology,
+		Metadata:      ont.Metadata,
+		TypeDefs:      ont.TypeDefs,
+		Individuals:   ont.Individuals,
+	}
+	for i := range ont.Terms {
+		t := &ont.Terms[i]
+		if opts.DropObsolete && t.IsObsolete ||
+			len(namespaces) > 0 && !namespaces[t.Namespace] ||
+			under != nil && !under[t.ID] {
+			continue
+		}
+		if len(subsets) > 0 {
+			in := false
+			for _, s := range t.Subsets {
+				if subsets[s] {
+					in = true
+					break
+				}
+			}
+			if !in {
+				continue
+			}
+		}
+		out.Terms = append(out.Terms, *t)
+	}
+	return out
+}
@@ -0,0 +1,131 @@
+package ontology
+
+import (
+	"slices"
+	"strings"
+)
+
+const (
+	nsDC      = "http://purl.org/dc/elements/1.1/"
+	nsDCTerms = "http://purl.org/dc/terms/"
+)
+
+// OntologyMetadata holds the ontology-level annotations that say what an
+// ontology is and under which terms it may be used, read from the OBO
+// header's property_values or the owl:Ontology element. Licenses are IRIs
+// where the source gives one, otherwise the license text.
+type OntologyMetadata struct {
+	Title        string   `json:"title,omitempty"`
+	Description  string   `json:"description,omitempty"`
+	Licenses     []string `json:"licenses,omitempty"`
+	Contributors []string `json:"contributors,omitempty"`
+}
+
+// metadataProperties maps each annotation property OntologyMetadata is
+// read from to the field it fills. Both Dublin Core vocabularies are in
+// use; dc:rights is taken as a license and creators as contributors.
+var metadataProperties = map[string]string{
+	nsDCTerms + "license":     "license",
+	nsDC + "rights":           "license",
+	nsDCTerms + "rights":      "license",
+	nsDCTerms + "title":       "title",
+	nsDC + "title":            "title",
+	nsDCTerms + "description": "description",
+	nsDC + "description":      "description",
+	nsDCTerms + "contributor": "contributor",
+	nsDC + "contributor":      "contributor",
+	nsDCTerms + "creator":     "contributor",
+	nsDC + "creator":          "contributor",
+}
+
+// metadataPrefixes expands the prefixes OBO headers write these
+// properties with ("dcterms:license", "dc:title").
+var metadataPrefixes = map[string]string{
+	"dc":      nsDC,
+	"dce":     nsDC,
+	"dcterms": nsDCTerms,
+	"terms":   nsDCTerms,
+}
+
+// metadataWriteProperties are the properties writers use for each field,
+// in output order. A source's dc:rights or dc:creator is written back as
+// dcterms:license or dcterms:contributor.
+var metadataWriteProperties = []struct{ field, iri string }{
+	{"title", nsDCTerms + "title"},
+	{"description", nsDCTerms + "description"},
+	{"license", nsDCTerms + "license"},
+	{"contributor", nsDCTerms + "contributor"},
+}
+
+// metadataField returns the OntologyMetadata field the annotation
+// property fills, or "" if it is not a metadata property.
+func metadataField(prop string) string {
+	if prefix, local, ok := strings.Cut(prop, ":"); ok && !strings.HasPrefix(local, "//") {
+		if ns, ok := metadataPrefixes[prefix]; ok {
+			prop = ns + local
+		}
+	}
+	return metadataProperties[prop]
+}
+
+// addMetadata records value under the property's field, allocating
+// ont.Metadata on first use. Titles and descriptions keep the first value
+// given (others are usually translations); licenses and contributors
+// collect every distinct value. It reports whether prop is a metadata
+// property.
+func addMetadata(ont *Ontology, prop, value string) bool {
+	field := metadataField(prop)
+	value = strings.TrimSpace(value)
+	if field == "" || value == "" {
+		return field != ""
+	}
+	if ont.Metadata == nil {
+		ont.Metadata = &OntologyMetadata{}
+	}
+	m := ont.Metadata
+	switch field {
+	case "title":
+		if m.Title == "" {
+			m.Title = value
+		}
+	case "description":
+		if m.Description == "" {
+			m.Description = value
+		}
+	case "license":
+		if !slices.Contains(m.Licenses, value) {
+			m.Licenses = append(m.Licenses, value)
+		}
+	case "contributor":
+		if !slices.Contains(m.Contributors, value) {
+			m.Contributors = append(m.Contributors, value)
+		}
+	}
+	return true
+}
+
+// values returns the metadata's values for a field of
+// metadataWriteProperties.
+func (m *OntologyMetadata) values(field string) []string {
+	switch field {
+	case "title":
+		if m.Title != "" {
+			return []string{m.Title}
+		}
+	case "description":
+		if m.Description != "" {
+			return []string{m.Description}
+		}
+	case "license":
+		return m.Licenses
+	case "contributor":
+		return m.Contributors
+	}
+	return nil
+}
+
+// isIRI reports whether a metadata value is an IRI rather than text, so
+// writers can emit it as a resource.
+func isIRI(s string) bool {
+	return strings.Contains(s, "://") && !strings.ContainsAny(s, " \t\n\"<>")
+}
@@ -0,0 +1,17 @@
+//go:build !unix && !js
+
+package ontology
+
+import (
+	"io"
+	"os"
+)
+
+// mapFile reads f into memory where mmap is unavailable.
+func mapFile(f *os.File, size int) ([]byte, func() error, error) {
+	data := make([]byte, size)
+	if _, err := io.ReadFull(f, data); err != nil {
+		return nil, nil, err
+	}
+	return data, nil, nil
+}
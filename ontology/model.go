@@ -1,45 +1,79 @@
 package ontology
 
+import "strconv"
+
 // Ontology represents a parsed ChEBI ontology.
 type Ontology struct {
-	FormatVersion string    `json:"format_version,omitempty"`
-	DataVersion   string    `json:"data_version,omitempty"`
-	Ontology      string    `json:"ontology,omitempty"`
-	Terms         []Term    `json:"terms"`
-	TypeDefs      []TypeDef `json:"typedefs,omitempty"`
+	FormatVersion string            `json:"format_version,omitempty"`
+	DataVersion   string            `json:"data_version,omitempty"`
+	Ontology      string            `json:"ontology,omitempty"`
+	Metadata      *OntologyMetadata `json:"metadata,omitempty"`
+	Terms         []Term            `json:"terms"`
+	TypeDefs      []TypeDef         `json:"typedefs,omitempty"`
+	Individuals   []Individual      `json:"individuals,omitempty"`
+}
+
+// Individual is a named individual (OBO [Instance] stanza, OWL
+// owl:NamedIndividual). Types are the classes it is asserted to belong to.
+type Individual struct {
+	ID    string   `json:"id"`
+	Name  string   `json:"name,omitempty"`
+	Types []string `json:"types,omitempty"`
 }
 
 // TypeDef represents an OBO Typedef stanza (object property).
+// HoldsOverChain lists property chains implying this relation, each as
+// space-separated relation IDs in order ("R1 R2" for R1 ∘ R2 ⊑ this).
 type TypeDef struct {
-	ID           string `json:"id"`
-	Name         string `json:"name,omitempty"`
-	IsTransitive bool   `json:"is_transitive,omitempty"`
-	IsReflexive  bool   `json:"is_reflexive,omitempty"`
+	ID             string   `json:"id"`
+	Name           string   `json:"name,omitempty"`
+	IsTransitive   bool     `json:"is_transitive,omitempty"`
+	IsReflexive    bool     `json:"is_reflexive,omitempty"`
+	HoldsOverChain []string `json:"holds_over_chain,omitempty"`
+	InverseOf      string   `json:"inverse_of,omitempty"`
 }
 
 // IntersectionPart represents one part of an intersection_of definition.
 // If Relationship is empty, it's a genus (plain class). Otherwise it's
-// a differentia: ∃Relationship.TargetID.
+// a differentia: ∃Relationship.TargetID, or ∃Relationship.Self (OWL
+// ObjectHasSelf) with no TargetID if Self is set.
 type IntersectionPart struct {
 	Relationship string `json:"relationship,omitempty"`
 	TargetID     string `json:"target_id"`
+	Self         bool   `json:"self,omitempty"`
 }
 
 // Term represents a single ChEBI ontology term (chemical entity).
 type Term struct {
-	ID            string            `json:"id"`
-	Name          string            `json:"name,omitempty"`
-	Namespace     string            `json:"namespace,omitempty"`
-	Definition    string            `json:"definition,omitempty"`
-	IsObsolete    bool              `json:"is_obsolete,omitempty"`
-	Comment       string            `json:"comment,omitempty"`
-	Subsets       []string          `json:"subsets,omitempty"`
-	Synonyms      []Synonym         `json:"synonyms,omitempty"`
-	Xrefs         []string          `json:"xrefs,omitempty"`
-	AltIDs        []string          `json:"alt_ids,omitempty"`
-	Relationships  []Relationship    `json:"relationships,omitempty"`
+	ID             string             `json:"id"`
+	Name           string             `json:"name,omitempty"`
+	Namespace      string             `json:"namespace,omitempty"`
+	Definition     string             `json:"definition,omitempty"`
+	IsObsolete     bool               `json:"is_obsolete,omitempty"`
+	Comment        string             `json:"comment,omitempty"`
+	ReplacedBy     []string           `json:"replaced_by,omitempty"`
+	Consider       []string           `json:"consider,omitempty"`
+	Subsets        []string           `json:"subsets,omitempty"`
+	Synonyms       []Synonym          `json:"synonyms,omitempty"`
+	Xrefs          []string           `json:"xrefs,omitempty"`
+	AltIDs         []string           `json:"alt_ids,omitempty"`
+	Relationships  []Relationship     `json:"relationships,omitempty"`
 	IntersectionOf []IntersectionPart `json:"intersection_of,omitempty"`
-	Properties     map[string]string `json:"properties,omitempty"`
+	UnionOf        []string           `json:"union_of,omitempty"` // term ≡ ⊔ UnionOf (not EL)
+	OneOf          []string           `json:"one_of,omitempty"`   // term ≡ {OneOf...}, individual IDs
+	NonEL          []NonELAxiom       `json:"non_el,omitempty"`   // OWL subClassOf axioms outside EL
+	Properties     map[string]string  `json:"properties,omitempty"`
+	// PropertyTypes holds the XSD datatype of a property value, keyed like
+	// Properties, as a compact name such as xsd:decimal. Values without an
+	// entry are xsd:string; see Term.Property.
+	PropertyTypes map[string]string `json:"property_types,omitempty"`
+	// XrefQualifiers holds the OBO trailing qualifiers of an xref, keyed by
+	// the xref as it appears in Xrefs.
+	XrefQualifiers map[string]map[string]string `json:"xref_qualifiers,omitempty"`
+	Links          *TermLinks                   `json:"links,omitempty"`
+	// Obsolescence is why an obsolete term was retired, if its
+	// annotations or comment say; the parsers derive it.
+	Obsolescence *ObsolescenceInfo `json:"obsolescence,omitempty"`
 }
 
 // Synonym represents a term synonym with its scope type.
@@ -50,9 +84,62 @@ type Synonym struct {
 	Xrefs []string `json:"xrefs,omitempty"`
 }
 
-// Relationship represents a typed relationship to another term.
+// Relationship represents a typed relationship to another term. If HasValue
+// is set, TargetID names an individual and the relationship is ∃Type.{TargetID}
+// (OWL ObjectHasValue) rather than ∃Type.TargetID. If Self is set, TargetID
+// is empty and the relationship is ∃Type.Self (OWL ObjectHasSelf).
+// Qualifiers are the OBO trailing {name="value"} annotations of the line,
+// such as source or is_inferred. Cardinality, if set, bounds the number of
+// Type fillers in TargetID (OBO cardinality qualifiers, OWL qualified
+// cardinality restrictions).
 type Relationship struct {
-	Type     string `json:"type"`      // is_a, has_part, has_role, etc.
-	TargetID string `json:"target_id"`
-	Name     string `json:"name,omitempty"`
+	Type        string            `json:"type"` // is_a, has_part, has_role, etc.
+	TargetID    string            `json:"target_id"`
+	Name        string            `json:"name,omitempty"`
+	HasValue    bool              `json:"has_value,omitempty"`
+	Self        bool              `json:"self,omitempty"`
+	Qualifiers  map[string]string `json:"qualifiers,omitempty"`
+	Cardinality *Cardinality      `json:"cardinality,omitempty"`
+}
+
+// Cardinality is a number restriction: between Min and Max fillers, with
+// Max -1 for no upper bound. Only Min ≥ 1 implies the existential the
+// reasoner works with; the bounds themselves are outside OWL 2 EL.
+type Cardinality struct {
+	Min int `json:"min"`
+	Max int `json:"max"`
+}
+
+// Constructs of a NonELAxiom.
+const (
+	NonELUnionOf       = "subclass_union_of" // term ⊑ B₁ ⊔ … ⊔ Bₙ
+	NonELAllValuesFrom = "all_values_from"   // term ⊑ ∀R.B
+	NonELComplementOf  = "complement_of"     // term ⊑ ¬B
+	NonELClass         = "class_expression"  // any other anonymous class
+	NonELRestriction   = "restriction"       // a restriction the parser cannot read
+)
+
+// NonELAxiom is an rdfs:subClassOf axiom whose superclass is an anonymous
+// class expression outside EL, which OWL input can state but OBO cannot.
+// Targets are the named classes it mentions (the union's members, B of
+// ∀R.B or ¬B), empty if any of them is anonymous. The reasoner drops
+// these axioms, or approximates unions, and reports each one.
+type NonELAxiom struct {
+	Construct    string   `json:"construct"`
+	Relationship string   `json:"relationship,omitempty"` // R of ∀R.B
+	Targets      []string `json:"targets,omitempty"`
+}
+
+// String renders c the way Manchester syntax writes it: "exactly 2",
+// "min 1", "max 3" or "min 1 max 3".
+func (c Cardinality) String() string {
+	switch {
+	case c.Min == c.Max:
+		return "exactly " + strconv.Itoa(c.Min)
+	case c.Max < 0:
+		return "min " + strconv.Itoa(c.Min)
+	case c.Min == 0:
+		return "max " + strconv.Itoa(c.Max)
+	}
+	return "min " + strconv.Itoa(c.Min) + " max " + strconv.Itoa(c.Max)
 }
@@ -0,0 +1,857 @@
+package ontology
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// MessagePack encoding of the ontology model.
+//
+// Structs are encoded as maps keyed by their JSON field names, with the same
+// omitempty behaviour as the JSON output, so a msgpack document decodes to
+// the same shape as the JSON one in any generic msgpack library. Unknown
+// keys are skipped when reading, so newer writers stay readable.
+
+// WriteMsgpack writes the ontology as a MessagePack document.
+func WriteMsgpack(ont *Ontology, w io.Writer) error {
+	bw := bufio.NewWriterSize(w, writerBufferSize)
+	mw := &msgpackWriter{w: bw}
+	mw.ontology(ont)
+	if mw.err != nil {
+		return mw.err
+	}
+	return bw.Flush()
+}
+
+// ReadMsgpack reads an ontology written by WriteMsgpack.
+func ReadMsgpack(r io.Reader) (*Ontology, error) {
+	mr := &msgpackReader{r: bufio.NewReaderSize(r, writerBufferSize)}
+	ont := mr.ontology()
+	if mr.err != nil {
+		return nil, mr.err
+	}
+	return ont, nil
+}
+
+type msgpackWriter struct {
+	w       *bufio.Writer
+	scratch [9]byte
+	err     error
+}
+
+func (mw *msgpackWriter) header(fix byte, fixMax int, code16, code32 byte, n int) {
+	b := mw.scratch[:0]
+	switch {
+	case n <= fixMax:
+		b = append(b, fix|byte(n))
+	case n <= math.MaxUint16:
+		b = append(b, code16)
+		b = binary.BigEndian.AppendUint16(b, uint16(n))
+	default:
+		b = append(b, code32)
+		b = binary.BigEndian.AppendUint32(b, uint32(n))
+	}
+	if _, err := mw.w.Write(b); err != nil && mw.err == nil {
+		mw.err = err
+	}
+}
+
+func (mw *msgpackWriter) mapHeader(n int)   { mw.header(0x80, 15, 0xde, 0xdf, n) }
+func (mw *msgpackWriter) arrayHeader(n int) { mw.header(0x90, 15, 0xdc, 0xdd, n) }
+
+func (mw *msgpackWriter) string(s string) {
+	n := len(s)
+	if n < 256 && n > 31 {
+		mw.w.WriteByte(0xd9)
+		mw.w.WriteByte(byte(n))
+	} else {
+		mw.header(0xa0, 31, 0xda, 0xdb, n)
+	}
+	mw.w.WriteString(s)
+}
+
+func (mw *msgpackWriter) bool(v bool) {
+	if v {
+		mw.w.WriteByte(0xc3)
+	} else {
+		mw.w.WriteByte(0xc2)
+	}
+}
+
+// int writes a fixint where possible, else an int32.
+func (mw *msgpackWriter) int(v int) {
+	switch {
+	case v >= 0 && v <= 0x7f, v < 0 && v >= -32:
+		mw.w.WriteByte(byte(v))
+	default:
+		mw.w.WriteByte(0xd2)
+		mw.w.Write(binary.BigEndian.AppendUint32(mw.scratch[:0], uint32(int32(v))))
+	}
+}
+
+func (mw *msgpackWriter) strings(vals []string) {
+	mw.arrayHeader(len(vals))
+	for _, v := range vals {
+		mw.string(v)
+	}
+}
+
+func (mw *msgpackWriter) ontology(ont *Ontology) {
+	n := 1 + countNonEmpty(ont.FormatVersion, ont.DataVersion, ont.Ontology)
+	if ont.Metadata != nil {
+		n++
+	}
+	if len(ont.TypeDefs) > 0 {
+		n++
+	}
+	if len(ont.Individuals) > 0 {
+		n++
+	}
+	mw.mapHeader(n)
+	mw.strOmit("format_version", ont.FormatVersion)
+	mw.strOmit("data_version", ont.DataVersion)
+	mw.strOmit("ontology", ont.Ontology)
+	if m := ont.Metadata; m != nil {
+		mw.string("metadata")
+		mw.mapHeader(countNonEmpty(m.Title, m.Description) + countTrue(len(m.Licenses) > 0, len(m.Contributors) > 0))
+		mw.strOmit("title", m.Title)
+		mw.strOmit("description", m.Description)
+		mw.strsOmit("licenses", m.Licenses)
+		mw.strsOmit("contributors", m.Contributors)
+	}
+
+	mw.string("terms")
+	mw.arrayHeader(len(ont.Terms))
+	for i := range ont.Terms {
+		mw.term(&ont.Terms[i])
+	}
+
+	if len(ont.TypeDefs) > 0 {
+		mw.string("typedefs")
+		mw.arrayHeader(len(ont.TypeDefs))
+		for i := range ont.TypeDefs {
+			td := &ont.TypeDefs[i]
+			mw.mapHeader(1 + countNonEmpty(td.Name, td.InverseOf) + countTrue(td.IsTransitive, td.IsReflexive, len(td.HoldsOverChain) > 0))
+			mw.str("id", td.ID)
+			mw.strOmit("name", td.Name)
+			mw.boolOmit("is_transitive", td.IsTransitive)
+			mw.boolOmit("is_reflexive", td.IsReflexive)
+			mw.strsOmit("holds_over_chain", td.HoldsOverChain)
+			mw.strOmit("inverse_of", td.InverseOf)
+		}
+	}
+
+	if len(ont.Individuals) > 0 {
+		mw.string("individuals")
+		mw.arrayHeader(len(ont.Individuals))
+		for i := range ont.Individuals {
+			ind := &ont.Individuals[i]
+			mw.mapHeader(1 + countNonEmpty(ind.Name) + countTrue(len(ind.Types) > 0))
+			mw.str("id", ind.ID)
+			mw.strOmit("name", ind.Name)
+			mw.strsOmit("types", ind.Types)
+		}
+	}
+}
+
+func (mw *msgpackWriter) term(t *Term) {
+	n := 1 + countNonEmpty(t.Name, t.Namespace, t.Definition, t.Comment) +
+		countTrue(t.IsObsolete, len(t.ReplacedBy) > 0, len(t.Consider) > 0, len(t.Subsets) > 0, len(t.Synonyms) > 0, len(t.Xrefs) > 0,
+			len(t.AltIDs) > 0, len(t.Relationships) > 0, len(t.IntersectionOf) > 0, len(t.UnionOf) > 0,
+			len(t.OneOf) > 0, len(t.NonEL) > 0, len(t.Properties) > 0, len(t.PropertyTypes) > 0, len(t.XrefQualifiers) > 0, t.Links != nil,
+			t.Obsolescence != nil)
+	mw.mapHeader(n)
+	mw.str("id", t.ID)
+	mw.strOmit("name", t.Name)
+	mw.strOmit("namespace", t.Namespace)
+	mw.strOmit("definition", t.Definition)
+	mw.boolOmit("is_obsolete", t.IsObsolete)
+	mw.strOmit("comment", t.Comment)
+	mw.strsOmit("replaced_by", t.ReplacedBy)
+	mw.strsOmit("consider", t.Consider)
+	mw.strsOmit("subsets", t.Subsets)
+
+	if len(t.Synonyms) > 0 {
+		mw.string("synonyms")
+		mw.arrayHeader(len(t.Synonyms))
+		for i := range t.Synonyms {
+			syn := &t.Synonyms[i]
+			mw.mapHeader(2 + countNonEmpty(syn.Type) + countTrue(len(syn.Xrefs) > 0))
+			mw.str("text", syn.Text)
+			mw.str("scope", syn.Scope)
+			mw.strOmit("type", syn.Type)
+			mw.strsOmit("xrefs", syn.Xrefs)
+		}
+	}
+
+	mw.strsOmit("xrefs", t.Xrefs)
+	mw.strsOmit("alt_ids", t.AltIDs)
+
+	if len(t.Relationships) > 0 {
+		mw.string("relationships")
+		mw.arrayHeader(len(t.Relationships))
+		for i := range t.Relationships {
+			rel := &t.Relationships[i]
+			mw.mapHeader(2 + countNonEmpty(rel.Name) + countTrue(rel.HasValue, rel.Self, len(rel.Qualifiers) > 0, rel.Cardinality != nil))
+			mw.str("type", rel.Type)
+			mw.str("target_id", rel.TargetID)
+			mw.strOmit("name", rel.Name)
+			mw.boolOmit("has_value", rel.HasValue)
+			mw.boolOmit("self", rel.Self)
+			if len(rel.Qualifiers) > 0 {
+				mw.string("qualifiers")
+				mw.strMap(rel.Qualifiers)
+			}
+			if c := rel.Cardinality; c != nil {
+				mw.string("cardinality")
+				mw.mapHeader(2)
+				mw.string("min")
+				mw.int(c.Min)
+				mw.string("max")
+				mw.int(c.Max)
+			}
+		}
+	}
+
+	if len(t.IntersectionOf) > 0 {
+		mw.string("intersection_of")
+		mw.arrayHeader(len(t.IntersectionOf))
+		for i := range t.IntersectionOf {
+			part := &t.IntersectionOf[i]
+			mw.mapHeader(1 + countNonEmpty(part.Relationship) + countTrue(part.Self))
+			mw.strOmit("relationship", part.Relationship)
+			mw.str("target_id", part.TargetID)
+			mw.boolOmit("self", part.Self)
+		}
+	}
+	mw.strsOmit("union_of", t.UnionOf)
+	mw.strsOmit("one_of", t.OneOf)
+	if len(t.NonEL) > 0 {
+		mw.string("non_el")
+		mw.arrayHeader(len(t.NonEL))
+		for i := range t.NonEL {
+			ax := &t.NonEL[i]
+			mw.mapHeader(1 + countNonEmpty(ax.Relationship) + countTrue(len(ax.Targets) > 0))
+			mw.str("construct", ax.Construct)
+			mw.strOmit("relationship", ax.Relationship)
+			mw.strsOmit("targets", ax.Targets)
+		}
+	}
+
+	if len(t.Properties) > 0 {
+		mw.string("properties")
+		mw.strMap(t.Properties)
+	}
+	if len(t.PropertyTypes) > 0 {
+		mw.string("property_types")
+		mw.strMap(t.PropertyTypes)
+	}
+	if len(t.XrefQualifiers) > 0 {
+		mw.string("xref_qualifiers")
+		mw.mapHeader(len(t.XrefQualifiers))
+		for _, x := range sortedKeys(t.XrefQualifiers) {
+			mw.string(x)
+			mw.strMap(t.XrefQualifiers[x])
+		}
+	}
+
+	if t.Links != nil {
+		mw.string("links")
+		mw.mapHeader(countNonEmpty(t.Links.Entry, t.Links.Image) + countTrue(len(t.Links.Xrefs) > 0))
+		mw.strOmit("entry", t.Links.Entry)
+		mw.strOmit("image", t.Links.Image)
+		if len(t.Links.Xrefs) > 0 {
+			mw.string("xrefs")
+			mw.arrayHeader(len(t.Links.Xrefs))
+			for _, x := range t.Links.Xrefs {
+				mw.mapHeader(2)
+				mw.str("xref", x.Xref)
+				mw.str("url", x.URL)
+			}
+		}
+	}
+	if t.Obsolescence != nil {
+		mw.string("obsolescence")
+		mw.mapHeader(2)
+		mw.str("reason", t.Obsolescence.Reason)
+		mw.str("source", t.Obsolescence.Source)
+	}
+}
+
+func (mw *msgpackWriter) str(key, val string) {
+	mw.string(key)
+	mw.string(val)
+}
+
+func (mw *msgpackWriter) strOmit(key, val string) {
+	if val != "" {
+		mw.str(key, val)
+	}
+}
+
+func (mw *msgpackWriter) boolOmit(key string, val bool) {
+	if val {
+		mw.string(key)
+		mw.bool(true)
+	}
+}
+
+func (mw *msgpackWriter) strsOmit(key string, vals []string) {
+	if len(vals) > 0 {
+		mw.string(key)
+		mw.strings(vals)
+	}
+}
+
+// strMap writes a string map with sorted keys.
+func (mw *msgpackWriter) strMap(m map[string]string) {
+	mw.mapHeader(len(m))
+	for _, k := range sortedKeys(m) {
+		mw.str(k, m[k])
+	}
+}
+
+func countNonEmpty(vals ...string) int {
+	n := 0
+	for _, v := range vals {
+		if v != "" {
+			n++
+		}
+	}
+	return n
+}
+
+func countTrue(vals ...bool) int {
+	n := 0
+	for _, v := range vals {
+		if v {
+			n++
+		}
+	}
+	return n
+}
+
+// msgpackReader decodes the subset of MessagePack produced by msgpackWriter.
+// The first error is sticky; subsequent reads return zero values.
+type msgpackReader struct {
+	r     *bufio.Reader
+	err   error
+	depth int // nesting of skipped containers
+}
+
+var errMsgpackType = errors.New("msgpack: unexpected type")
+
+// msgpackMaxPrealloc caps allocations sized from length headers, so a
+// corrupt or hostile header cannot request gigabytes up front. Longer
+// values still decode; they just grow as they are read.
+const msgpackMaxPrealloc = 1 << 16
+
+// msgpackMaxSkipDepth bounds the nesting of unknown values that skip will
+// descend into, so deeply nested input fails instead of exhausting the stack.
+const msgpackMaxSkipDepth = 64
+
+func (mr *msgpackReader) fail(err error) {
+	if mr.err == nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		mr.err = err
+	}
+}
+
+func (mr *msgpackReader) byte() byte {
+	if mr.err != nil {
+		return 0
+	}
+	b, err := mr.r.ReadByte()
+	if err != nil {
+		mr.fail(err)
+	}
+	return b
+}
+
+func (mr *msgpackReader) uint(size int) int {
+	if mr.err != nil {
+		return 0
+	}
+	var buf [4]byte
+	if _, err := io.ReadFull(mr.r, buf[:size]); err != nil {
+		mr.fail(err)
+		return 0
+	}
+	switch size {
+	case 1:
+		return int(buf[0])
+	case 2:
+		return int(binary.BigEndian.Uint16(buf[:2]))
+	default:
+		return int(binary.BigEndian.Uint32(buf[:4]))
+	}
+}
+
+// length decodes a map, array or string header of the given family.
+func (mr *msgpackReader) length(b, fixBase, fixMask byte, code8, code16, code32 byte) int {
+	switch {
+	case b&^fixMask == fixBase:
+		return int(b & fixMask)
+	case code8 != 0 && b == code8:
+		return mr.uint(1)
+	case b == code16:
+		return mr.uint(2)
+	case b == code32:
+		return mr.uint(4)
+	}
+	mr.fail(fmt.Errorf("%w 0x%02x", errMsgpackType, b))
+	return 0
+}
+
+func (mr *msgpackReader) mapLen() int {
+	return mr.length(mr.byte(), 0x80, 0x0f, 0, 0xde, 0xdf)
+}
+
+func (mr *msgpackReader) arrayLen() int {
+	return mr.length(mr.byte(), 0x90, 0x0f, 0, 0xdc, 0xdd)
+}
+
+func (mr *msgpackReader) string() string {
+	n := mr.length(mr.byte(), 0xa0, 0x1f, 0xd9, 0xda, 0xdb)
+	if mr.err != nil {
+		return ""
+	}
+	if n > msgpackMaxPrealloc {
+		var sb strings.Builder
+		if _, err := io.CopyN(&sb, mr.r, int64(n)); err != nil {
+			mr.fail(err)
+			return ""
+		}
+		return sb.String()
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(mr.r, buf); err != nil {
+		mr.fail(err)
+		return ""
+	}
+	return string(buf)
+}
+
+func (mr *msgpackReader) bool() bool {
+	switch b := mr.byte(); b {
+	case 0xc3:
+		return true
+	case 0xc2, 0xc0:
+		return false
+	default:
+		mr.fail(fmt.Errorf("%w 0x%02x", errMsgpackType, b))
+		return false
+	}
+}
+
+// int reads any msgpack integer of up to 32 bits.
+func (mr *msgpackReader) int() int {
+	b := mr.byte()
+	switch {
+	case b <= 0x7f:
+		return int(b)
+	case b >= 0xe0:
+		return int(int8(b))
+	case b == 0xcc:
+		return mr.uint(1)
+	case b == 0xcd:
+		return mr.uint(2)
+	case b == 0xce:
+		return mr.uint(4)
+	case b == 0xd0:
+		return int(int8(mr.uint(1)))
+	case b == 0xd1:
+		return int(int16(mr.uint(2)))
+	case b == 0xd2:
+		return int(int32(mr.uint(4)))
+	}
+	mr.fail(fmt.Errorf("%w 0x%02x", errMsgpackType, b))
+	return 0
+}
+
+func (mr *msgpackReader) strings() []string {
+	n := mr.arrayLen()
+	if mr.err != nil {
+		return nil
+	}
+	vals := make([]string, 0, min(n, msgpackMaxPrealloc))
+	for i := 0; i < n && mr.err == nil; i++ {
+		vals = append(vals, mr.string())
+	}
+	return vals
+}
+
+func (mr *msgpackReader) strMap() map[string]string {
+	n := mr.mapLen()
+	if mr.err != nil {
+		return nil
+	}
+	m := make(map[string]string, min(n, msgpackMaxPrealloc))
+	for i := 0; i < n && mr.err == nil; i++ {
+		k := mr.string()
+		m[k] = mr.string()
+	}
+	return m
+}
+
+// skip discards one value of any type.
+func (mr *msgpackReader) skip() {
+	b := mr.byte()
+	if mr.err != nil {
+		return
+	}
+	var n int
+	switch {
+	case b <= 0x7f || b >= 0xe0 || b == 0xc0 || b == 0xc2 || b == 0xc3:
+		return
+	case b&0xf0 == 0x80:
+		mr.skipN(2 * int(b&0x0f))
+		return
+	case b&0xf0 == 0x90:
+		mr.skipN(int(b & 0x0f))
+		return
+	case b&0xe0 == 0xa0:
+		n = int(b & 0x1f)
+	case b == 0xcc || b == 0xd0:
+		n = 1
+	case b == 0xcd || b == 0xd1:
+		n = 2
+	case b == 0xca || b == 0xce || b == 0xd2:
+		n = 4
+	case b == 0xcb || b == 0xcf || b == 0xd3:
+		n = 8
+	case b == 0xd9 || b == 0xc4:
+		n = mr.uint(1)
+	case b == 0xda || b == 0xc5:
+		n = mr.uint(2)
+	case b == 0xdb || b == 0xc6:
+		n = mr.uint(4)
+	case b == 0xdc:
+		mr.skipN(mr.uint(2))
+		return
+	case b == 0xdd:
+		mr.skipN(mr.uint(4))
+		return
+	case b == 0xde:
+		mr.skipN(2 * mr.uint(2))
+		return
+	case b == 0xdf:
+		mr.skipN(2 * mr.uint(4))
+		return
+	default:
+		mr.fail(fmt.Errorf("%w 0x%02x", errMsgpackType, b))
+		return
+	}
+	if _, err := mr.r.Discard(n); err != nil {
+		mr.fail(err)
+	}
+}
+
+func (mr *msgpackReader) skipN(n int) {
+	if mr.depth++; mr.depth > msgpackMaxSkipDepth {
+		mr.fail(errors.New("msgpack: value nested too deeply"))
+	}
+	for i := 0; i < n && mr.err == nil; i++ {
+		mr.skip()
+	}
+	mr.depth--
+}
+
+func (mr *msgpackReader) ontology() *Ontology {
+	ont := &Ontology{}
+	n := mr.mapLen()
+	for i := 0; i < n && mr.err == nil; i++ {
+		switch mr.string() {
+		case "format_version":
+			ont.FormatVersion = mr.string()
+		case "data_version":
+			ont.DataVersion = mr.string()
+		case "ontology":
+			ont.Ontology = mr.string()
+		case "metadata":
+			ont.Metadata = mr.metadata()
+		case "terms":
+			ont.Terms = readArray(mr, mr.term)
+		case "typedefs":
+			ont.TypeDefs = readArray(mr, mr.typeDef)
+		case "individuals":
+			ont.Individuals = readArray(mr, mr.individual)
+		default:
+			mr.skip()
+		}
+	}
+	return ont
+}
+
+// readArray decodes an array whose elements are read by read.
+func readArray[T any](mr *msgpackReader, read func(*T)) []T {
+	count := mr.arrayLen()
+	out := make([]T, 0, min(count, msgpackMaxPrealloc))
+	for j := 0; j < count && mr.err == nil; j++ {
+		out = append(out, *new(T))
+		read(&out[len(out)-1])
+	}
+	return out
+}
+
+func (mr *msgpackReader) typeDef(td *TypeDef) {
+	n := mr.mapLen()
+	for i := 0; i < n && mr.err == nil; i++ {
+		switch mr.string() {
+		case "id":
+			td.ID = mr.string()
+		case "name":
+			td.Name = mr.string()
+		case "is_transitive":
+			td.IsTransitive = mr.bool()
+		case "is_reflexive":
+			td.IsReflexive = mr.bool()
+		case "holds_over_chain":
+			td.HoldsOverChain = mr.strings()
+		case "inverse_of":
+			td.InverseOf = mr.string()
+		default:
+			mr.skip()
+		}
+	}
+}
+
+func (mr *msgpackReader) metadata() *OntologyMetadata {
+	m := &OntologyMetadata{}
+	n := mr.mapLen()
+	for i := 0; i < n && mr.err == nil; i++ {
+		switch mr.string() {
+		case "title":
+			m.Title = mr.string()
+		case "description":
+			m.Description = mr.string()
+		case "licenses":
+			m.Licenses = mr.strings()
+		case "contributors":
+			m.Contributors = mr.strings()
+		default:
+			mr.skip()
+		}
+	}
+	return m
+}
+
+func (mr *msgpackReader) individual(ind *Individual) {
+	n := mr.mapLen()
+	for i := 0; i < n && mr.err == nil; i++ {
+		switch mr.string() {
+		case "id":
+			ind.ID = mr.string()
+		case "name":
+			ind.Name = mr.string()
+		case "types":
+			ind.Types = mr.strings()
+		default:
+			mr.skip()
+		}
+	}
+}
+
+func (mr *msgpackReader) term(t *Term) {
+	n := mr.mapLen()
+	for i := 0; i < n && mr.err == nil; i++ {
+		switch mr.string() {
+		case "id":
+			t.ID = mr.string()
+		case "name":
+			t.Name = mr.string()
+		case "namespace":
+			t.Namespace = mr.string()
+		case "definition":
+			t.Definition = mr.string()
+		case "is_obsolete":
+			t.IsObsolete = mr.bool()
+		case "comment":
+			t.Comment = mr.string()
+		case "replaced_by":
+			t.ReplacedBy = mr.strings()
+		case "consider":
+			t.Consider = mr.strings()
+		case "subsets":
+			t.Subsets = mr.strings()
+		case "synonyms":
+			t.Synonyms = readArray(mr, mr.synonym)
+		case "xrefs":
+			t.Xrefs = mr.strings()
+		case "alt_ids":
+			t.AltIDs = mr.strings()
+		case "relationships":
+			t.Relationships = readArray(mr, mr.relationship)
+		case "intersection_of":
+			t.IntersectionOf = readArray(mr, mr.intersectionPart)
+		case "union_of":
+			t.UnionOf = mr.strings()
+		case "one_of":
+			t.OneOf = mr.strings()
+		case "non_el":
+			t.NonEL = readArray(mr, mr.nonEL)
+		case "properties":
+			t.Properties = mr.strMap()
+		case "property_types":
+			t.PropertyTypes = mr.strMap()
+		case "xref_qualifiers":
+			count := mr.mapLen()
+			t.XrefQualifiers = make(map[string]map[string]string, min(count, msgpackMaxPrealloc))
+			for j := 0; j < count && mr.err == nil; j++ {
+				x := mr.string()
+				t.XrefQualifiers[x] = mr.strMap()
+			}
+		case "links":
+			t.Links = mr.links()
+		case "obsolescence":
+			t.Obsolescence = mr.obsolescence()
+		default:
+			mr.skip()
+		}
+	}
+}
+
+func (mr *msgpackReader) synonym(syn *Synonym) {
+	n := mr.mapLen()
+	for i := 0; i < n && mr.err == nil; i++ {
+		switch mr.string() {
+		case "text":
+			syn.Text = mr.string()
+		case "scope":
+			syn.Scope = mr.string()
+		case "type":
+			syn.Type = mr.string()
+		case "xrefs":
+			syn.Xrefs = mr.strings()
+		default:
+			mr.skip()
+		}
+	}
+}
+
+func (mr *msgpackReader) relationship(rel *Relationship) {
+	n := mr.mapLen()
+	for i := 0; i < n && mr.err == nil; i++ {
+		switch mr.string() {
+		case "type":
+			rel.Type = mr.string()
+		case "target_id":
+			rel.TargetID = mr.string()
+		case "name":
+			rel.Name = mr.string()
+		case "has_value":
+			rel.HasValue = mr.bool()
+		case "self":
+			rel.Self = mr.bool()
+		case "qualifiers":
+			rel.Qualifiers = mr.strMap()
+		case "cardinality":
+			rel.Cardinality = mr.cardinality()
+		default:
+			mr.skip()
+		}
+	}
+}
+
+func (mr *msgpackReader) cardinality() *Cardinality {
+	c := &Cardinality{Max: -1}
+	n := mr.mapLen()
+	for i := 0; i < n && mr.err == nil; i++ {
+		switch mr.string() {
+		case "min":
+			c.Min = mr.int()
+		case "max":
+			c.Max = mr.int()
+		default:
+			mr.skip()
+		}
+	}
+	return c
+}
+
+func (mr *msgpackReader) intersectionPart(part *IntersectionPart) {
+	n := mr.mapLen()
+	for i := 0; i < n && mr.err == nil; i++ {
+		switch mr.string() {
+		case "relationship":
+			part.Relationship = mr.string()
+		case "target_id":
+			part.TargetID = mr.string()
+		case "self":
+			part.Self = mr.bool()
+		default:
+			mr.skip()
+		}
+	}
+}
+
+func (mr *msgpackReader) nonEL(ax *NonELAxiom) {
+	n := mr.mapLen()
+	for i := 0; i < n && mr.err == nil; i++ {
+		switch mr.string() {
+		case "construct":
+			ax.Construct = mr.string()
+		case "relationship":
+			ax.Relationship = mr.string()
+		case "targets":
+			ax.Targets = mr.strings()
+		default:
+			mr.skip()
+		}
+	}
+}
+
+func (mr *msgpackReader) links() *TermLinks {
+	links := &TermLinks{}
+	n := mr.mapLen()
+	for i := 0; i < n && mr.err == nil; i++ {
+		switch mr.string() {
+		case "entry":
+			links.Entry = mr.string()
+		case "image":
+			links.Image = mr.string()
+		case "xrefs":
+			links.Xrefs = readArray(mr, mr.xrefLink)
+		default:
+			mr.skip()
+		}
+	}
+	return links
+}
+
+func (mr *msgpackReader) obsolescence() *ObsolescenceInfo {
+	o := &ObsolescenceInfo{}
+	n := mr.mapLen()
+	for i := 0; i < n && mr.err == nil; i++ {
+		switch mr.string() {
+		case "reason":
+			o.Reason = mr.string()
+		case "source":
+			o.Source = mr.string()
+		default:
+			mr.skip()
+		}
+	}
+	return o
+}
+
+func (mr *msgpackReader) xrefLink(x *XrefLink) {
+	n := mr.mapLen()
+	for i := 0; i < n && mr.err == nil; i++ {
+		switch mr.string() {
+		case "xref":
+			x.Xref = mr.string()
+		case "url":
+			x.URL = mr.string()
+		default:
+			mr.skip()
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package ontology
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// oboDialect is the OBO format version a file declares in its
+// format-version header, which changes how a few tags are read:
+//
+//   - OBO 1.0 and 1.2 files may write synonyms as exact_synonym,
+//     narrow_synonym, broad_synonym and related_synonym, xrefs as
+//     xref_analog and xref_unk, and consider as use_term. These are read
+//     in either dialect, but are reported as WarnDialect in a 1.4 file,
+//     which no longer allows them.
+//   - A 1.2 synonym without a scope is RELATED; in 1.4 the scope is
+//     required and its absence is a WarnMalformedSynonym.
+//   - 1.2 decodes its escapes (\n, \t, \W for a space, and \: \, \! \{ \}
+//     \( \) \[ \] for the character itself) in names, comments,
+//     definitions and synonyms, except SMILES and InChI synonyms, whose
+//     backslashes are bonds. 1.4 files are read as before, decoding only
+//     \" and \\ inside quotes, so backslashes in SMILES survive.
+//   - 1.4 tags and qualifiers in a 1.0 or 1.2 file are read as usual but
+//     reported as WarnDialect, once per construct: ChEBI itself declares
+//     1.2 and has a property_value in every term.
+//
+// A file without format-version is read as 1.4.
+type oboDialect struct {
+	legacy   bool            // OBO 1.0 or 1.2
+	version  string          // the declared format-version
+	reported map[string]bool // 1.4 constructs already warned about
+}
+
+// newOBODialect returns the dialect of a format-version header value,
+// reporting versions it does not know, which are read as 1.4.
+func newOBODialect(version string, warn *warner) oboDialect {
+	switch v := strings.TrimSpace(version); v {
+	case "1.0", "1.2":
+		return oboDialect{legacy: true, version: v, reported: make(map[string]bool)}
+	case "", "1.4":
+		return oboDialect{}
+	}
+	warn.add(WarnDialect, "", "unknown format-version %q, read as OBO 1.4", version)
+	return oboDialect{}
+}
+
+// legacySynonymScopes maps the OBO 1.2 synonym tags to their scope.
+var legacySynonymScopes = map[string]string{
+	"exact_synonym":   "EXACT",
+	"narrow_synonym":  "NARROW",
+	"broad_synonym":   "BROAD",
+	"related_synonym": "RELATED",
+}
+
+// text decodes an unquoted text value, such as a name or comment.
+func (d oboDialect) text(val string) string {
+	if !d.legacy {
+		return val
+	}
+	return unescapeOBO(expandEscapes12(val))
+}
+
+// quoted prepares a value holding a quoted string (def, synonym) for
+// splitQuoted, which decodes \" and \\ itself.
+func (d oboDialect) quoted(val string) string {
+	if !d.legacy {
+		return val
+	}
+	return expandEscapes12(val)
+}
+
+// structureSynonymTypes are the synonym types holding chemical structures,
+// which no dialect unescapes.
+var structureSynonymTypes = map[string]bool{
+	"SMILES":   true,
+	"INCHI":    true,
+	"INCHIKEY": true,
+}
+
+// synonym parses the value of a synonym tag.
+func (d oboDialect) synonym(val string) Synonym {
+	syn := parseSynonym(val)
+	if !d.legacy {
+		return syn
+	}
+	if !structureSynonymTypes[strings.ToUpper(syn.Type)] {
+		syn = parseSynonym(d.quoted(val))
+	}
+	if syn.Scope == "" {
+		syn.Scope = "RELATED"
+	}
+	return syn
+}
+
+// legacyTag reads an OBO 1.2 tag that 1.4 dropped into t, reporting it if
+// the file declares 1.4. It returns false for any other tag.
+func (d oboDialect) legacyTag(t *Term, key, val string, warn *warner) bool {
+	var use string
+	if scope, ok := legacySynonymScopes[key]; ok {
+		// `"text" [xrefs]` becomes `"text" SCOPE [xrefs]`.
+		if end := quotedEnd(val); end > 0 {
+			val = val[:end] + " " + scope + val[end:]
+		}
+		syn := d.synonym(val)
+		syn.Scope = scope
+		t.Synonyms = append(t.Synonyms, syn)
+		use = "synonym: ... " + scope
+	} else {
+		switch key {
+		case "xref_analog", "xref_unk":
+			x, _ := splitQualifiers(val)
+			t.Xrefs = append(t.Xrefs, x)
+			use = "xref"
+		case "use_term":
+			t.Consider = append(t.Consider, tagValue(val))
+			use = "consider"
+		default:
+			return false
+		}
+	}
+	if !d.legacy {
+		warn.add(WarnDialect, t.ID, "tag %q is OBO 1.2; OBO 1.4 uses %s", key, use)
+	}
+	return true
+}
+
+// obo14HeaderTags and obo14TermTags are the tags OBO 1.4 added that the
+// parser reads or knowingly skips.
+var (
+	obo14HeaderTags = map[string]bool{"property_value": true, "owl-axioms": true}
+	obo14TermTags   = map[string]bool{"property_value": true, "created_by": true, "creation_date": true}
+)
+
+// obo12Qualifiers are the trailing modifiers OBO 1.2 defines; any other
+// qualifier, such as source or is_inferred, is OBO 1.4.
+var obo12Qualifiers = map[string]bool{
+	"cardinality": true, "minCardinality": true, "maxCardinality": true,
+	"necessary": true, "inverse_necessary": true, "implied": true,
+	"namespace": true, "derived": true,
+}
+
+// headerTags reports the 1.4 tags among the header tags of a legacy file.
+func (d oboDialect) headerTags(keys []string, warn *warner) {
+	for _, key := range keys {
+		if obo14HeaderTags[key] {
+			d.report("", fmt.Sprintf("header tag %q", key), warn)
+		}
+	}
+}
+
+// termTag reports a 1.4 term tag, or a 1.4 qualifier on its value, in a
+// legacy file.
+func (d oboDialect) termTag(termID, key, val string, warn *warner) {
+	if !d.legacy {
+		return
+	}
+	if obo14TermTags[key] {
+		d.report(termID, fmt.Sprintf("tag %q", key), warn)
+	}
+	switch key {
+	case "is_a", "relationship", "intersection_of", "xref":
+		if strings.IndexByte(val, '{') < 0 {
+			return
+		}
+		_, q := splitQualifiers(val)
+		names := make([]string, 0, len(q))
+		for name := range q {
+			if !obo12Qualifiers[name] {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			d.report(termID, fmt.Sprintf("qualifier %q", name), warn)
+		}
+	}
+}
+
+// report warns about the first use of a 1.4 construct in a legacy file.
+func (d oboDialect) report(termID, construct string, warn *warner) {
+	if !d.legacy || d.reported[construct] {
+		return
+	}
+	d.reported[construct] = true
+	warn.add(WarnDialect, termID, "%s is OBO 1.4 but the file declares format-version %s; further uses are not reported", construct, d.version)
+}
+
+// quotedEnd returns the index just past the closing quote of the first
+// quoted string in s, or -1 if there is none.
+func quotedEnd(s string) int {
+	start := strings.IndexByte(s, '"')
+	if start < 0 {
+		return -1
+	}
+	for i := start + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// expandEscapes12 decodes the OBO 1.2 escapes other than \" and \\, which
+// it leaves for unescapeOBO so quoted strings still split correctly.
+// Unknown escapes are kept as written.
+func expandEscapes12(s string) string {
+	if strings.IndexByte(s, '\\') < 0 {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		switch c := s[i+1]; c {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'W':
+			b.WriteByte(' ')
+		case ':', ',', '!', '{', '}', '(', ')', '[', ']':
+			b.WriteByte(c)
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		}
+		i++
+	}
+	return b.String()
+}
@@ -3,17 +3,44 @@ package ontology
 import (
 	"bufio"
 	"io"
+	"io/fs"
+	"strconv"
 	"strings"
 )
 
 const (
-	initialTermCapacity = 200000 // ChEBI has ~180k terms
-	scannerBufferSize   = 1 << 20 // 1 MB
+	maxTermCapacity   = 200000  // ChEBI has ~180k terms
+	scannerBufferSize = 1 << 20 // 1 MB, the longest line read
+	initialScanBuffer = 64 << 10
+	unknownSizeTerms  = 1024 // capacity when the input size is unknown
+	oboBytesPerTerm   = 1200 // chebi.obo: 248 MB for ~205k terms
+	owlBytesPerTerm   = 3500 // chebi.owl: 774 MB for ~224k terms
 )
 
-// internPool avoids duplicate string allocations for repeated values.
+// termCapacity guesses how many terms r holds from its size, so a small
+// input does not preallocate room for all of ChEBI.
+func termCapacity(r io.Reader, bytesPerTerm int64) int {
+	var n int64 = -1
+	switch v := r.(type) {
+	case interface{ Len() int }: // bytes.Reader, strings.Reader, bytes.Buffer
+		n = int64(v.Len())
+	case interface{ Stat() (fs.FileInfo, error) }: // *os.File
+		if fi, err := v.Stat(); err == nil && fi.Mode().IsRegular() {
+			n = fi.Size()
+		}
+	}
+	if n < 0 {
+		return unknownSizeTerms
+	}
+	return int(min(n/bytesPerTerm+1, maxTermCapacity))
+}
+
+// internPool avoids duplicate string allocations for repeated values. If
+// max is set the pool is emptied whenever it reaches max entries, which
+// bounds its memory when nothing parsed is kept.
 type internPool struct {
-	m map[string]string
+	m   map[string]string
+	max int
 }
 
 func newInternPool() *internPool {
@@ -24,53 +51,112 @@ func (p *internPool) get(s string) string {
 	if v, ok := p.m[s]; ok {
 		return v
 	}
+	if p.max > 0 && len(p.m) >= p.max {
+		clear(p.m)
+	}
 	p.m[s] = s
 	return s
 }
 
 // ParseOBO parses a ChEBI OBO-format ontology from the given reader.
 func ParseOBO(r io.Reader) (*Ontology, error) {
-	scanner := bufio.NewScanner(r)
-	scanner.Buffer(make([]byte, scannerBufferSize), scannerBufferSize)
+	return ParseOBOWithOptions(r, ParseOptions{})
+}
 
+// ParseOBOWithOptions is ParseOBO with a memory budget, warnings and an
+// obsolete-term policy; see ParseOptions.
+func ParseOBOWithOptions(r io.Reader, opts ParseOptions) (*Ontology, error) {
 	ont := &Ontology{
-		Terms: make([]Term, 0, initialTermCapacity),
+		Terms: make([]Term, 0, termCapacity(r, oboBytesPerTerm)),
+	}
+	budget := &memoryBudget{opts: opts}
+	err := parseOBO(r, opts, ont, newInternPool(), OBOHandler{
+		Term: func(t *Term) error {
+			ont.Terms = append(ont.Terms, *t)
+			budget.added(ont)
+			return budget.err
+		},
+		TypeDef: func(td *TypeDef) error {
+			ont.TypeDefs = append(ont.TypeDefs, *td)
+			return nil
+		},
+		Instance: func(ind *Individual) error {
+			ont.Individuals = append(ont.Individuals, *ind)
+			return nil
+		},
+	})
+	if budget.err != nil {
+		return nil, budget.err
 	}
-	pool := newInternPool()
+	return ont, err
+}
 
-	// Parse header
+// parseOBO reads the header into head, then hands each stanza to h.
+func parseOBO(r io.Reader, opts ParseOptions, head *Ontology, pool *internPool, h OBOHandler) error {
+	warn := newWarner(opts)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, initialScanBuffer), scannerBufferSize)
+	scanner.Split(splitOBOLines(opts.Charset, warn))
+
+	keepObsolete := opts.Obsolete.Keep(true)
+	var dialect oboDialect
+	var headerTags []string
+	inHeader := true
 	for scanner.Scan() {
 		line := scanner.Text()
-		if line == "" {
-			continue
-		}
-		if line == "[Term]" {
-			term := parseTerm(scanner, pool)
-			ont.Terms = append(ont.Terms, term)
-			break
-		}
-		if line[0] == '[' {
-			// Skip non-Term stanzas in header area
-			break
+		if inHeader {
+			if line == "" {
+				continue
+			}
+			if line[0] != '[' {
+				parseHeaderLine(head, line)
+				if key, _, ok := strings.Cut(line, ": "); ok {
+					headerTags = append(headerTags, key)
+				}
+				continue
+			}
+			inHeader = false
+			dialect = newOBODialect(head.FormatVersion, warn)
+			dialect.headerTags(headerTags, warn)
+			if h.Header != nil {
+				if err := h.Header(head); err != nil {
+					return err
+				}
+			}
 		}
-		parseHeaderLine(ont, line)
-	}
 
-	// Parse remaining stanzas
-	for scanner.Scan() {
-		line := scanner.Text()
+		var err error
 		switch line {
 		case "[Term]":
-			term := parseTerm(scanner, pool)
-			ont.Terms = append(ont.Terms, term)
+			term := parseTerm(scanner, pool, warn, dialect)
+			warn.term(term.ID)
+			opts.Synonyms.apply(&term)
+			term.Obsolescence = obsolescenceOf(&term)
+			if h.Term != nil && (keepObsolete || !term.IsObsolete) {
+				err = h.Term(&term)
+			}
 		case "[Typedef]":
 			td := parseTypeDef(scanner, pool)
-			ont.TypeDefs = append(ont.TypeDefs, td)
+			if h.TypeDef != nil {
+				err = h.TypeDef(&td)
+			}
+		case "[Instance]":
+			ind := parseInstance(scanner, pool)
+			if h.Instance != nil {
+				err = h.Instance(&ind)
+			}
 		}
 		// Skip other stanza types
+		if err != nil {
+			return err
+		}
 	}
-
-	return ont, scanner.Err()
+	if inHeader && h.Header != nil {
+		if err := h.Header(head); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
 }
 
 func parseHeaderLine(ont *Ontology, line string) {
@@ -85,10 +171,13 @@ func parseHeaderLine(ont *Ontology, line string) {
 		ont.DataVersion = val
 	case "ontology":
 		ont.Ontology = val
+	case "property_value":
+		k, v, _ := parsePropertyValue(val)
+		addMetadata(ont, k, v)
 	}
 }
 
-func parseTerm(scanner *bufio.Scanner, pool *internPool) Term {
+func parseTerm(scanner *bufio.Scanner, pool *internPool, warn *warner, d oboDialect) Term {
 	var t Term
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -100,24 +189,33 @@ func parseTerm(scanner *bufio.Scanner, pool *internPool) Term {
 		if !ok {
 			continue
 		}
+		d.termTag(t.ID, key, val, warn)
 
 		switch key {
 		case "id":
 			t.ID = val
 		case "name":
-			t.Name = val
+			t.Name = d.text(val)
 		case "namespace":
 			t.Namespace = pool.get(val)
 		case "def":
-			t.Definition = parseQuoted(val)
+			t.Definition = parseQuoted(d.quoted(val))
 		case "comment":
-			t.Comment = val
+			t.Comment = d.text(val)
 		case "subset":
 			t.Subsets = append(t.Subsets, pool.get(val))
 		case "synonym":
-			t.Synonyms = append(t.Synonyms, parseSynonym(val))
+			warn.synonym(t.ID, val, d.legacy)
+			t.Synonyms = append(t.Synonyms, d.synonym(val))
 		case "xref":
-			t.Xrefs = append(t.Xrefs, val)
+			x, q := splitQualifiers(val)
+			t.Xrefs = append(t.Xrefs, x)
+			if q != nil {
+				if t.XrefQualifiers == nil {
+					t.XrefQualifiers = make(map[string]map[string]string)
+				}
+				t.XrefQualifiers[x] = q
+			}
 		case "alt_id":
 			t.AltIDs = append(t.AltIDs, val)
 		case "is_a":
@@ -128,15 +226,22 @@ func parseTerm(scanner *bufio.Scanner, pool *internPool) Term {
 			t.Relationships = append(t.Relationships, rel)
 		case "intersection_of":
 			t.IntersectionOf = append(t.IntersectionOf, parseIntersectionOf(val, pool))
+		case "union_of":
+			id, _, _ := strings.Cut(val, " ! ")
+			t.UnionOf = append(t.UnionOf, strings.TrimSpace(id))
 		case "is_obsolete":
 			t.IsObsolete = val == "true"
+		case "replaced_by":
+			t.ReplacedBy = append(t.ReplacedBy, val)
+		case "consider":
+			t.Consider = append(t.Consider, val)
 		case "property_value":
-			k, v := parsePropertyValue(val)
-			if k != "" {
-				if t.Properties == nil {
-					t.Properties = make(map[string]string, 4)
-				}
-				t.Properties[k] = v
+			if k, v, dt := parsePropertyValue(val); k != "" {
+				t.setProperty(k, v, dt)
+			}
+		default:
+			if !d.legacyTag(&t, key, val, warn) {
+				warn.tag(t.ID, key)
 			}
 		}
 	}
@@ -145,37 +250,70 @@ func parseTerm(scanner *bufio.Scanner, pool *internPool) Term {
 
 // parseQuoted extracts text between the first pair of double quotes.
 func parseQuoted(s string) string {
+	text, _ := splitQuoted(s)
+	return text
+}
+
+// splitQuoted returns the text between the first pair of double quotes and
+// whatever follows the closing quote. Without an opening quote the whole
+// string is the text; without a closing quote the text runs to the end.
+// Escaped quotes and backslashes inside the quotes are decoded; other
+// backslashes, common in SMILES, are kept.
+func splitQuoted(s string) (text, rest string) {
 	start := strings.IndexByte(s, '"')
 	if start < 0 {
-		return s
+		return s, ""
 	}
 	start++
-	end := strings.IndexByte(s[start:], '"')
-	if end < 0 {
-		return s[start:]
+	end := start
+	for end < len(s) && s[end] != '"' {
+		if s[end] == '\\' {
+			end++
+		}
+		end++
+	}
+	if end >= len(s) {
+		return unescapeOBO(s[start:]), ""
+	}
+	return unescapeOBO(s[start:end]), s[end+1:]
+}
+
+// unescapeOBO decodes \" and \\ in an OBO quoted string.
+func unescapeOBO(s string) string {
+	if strings.IndexByte(s, '\\') < 0 {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		if c := s[i+1]; c == '"' || c == '\\' {
+			i++
+		}
+		b.WriteByte(s[i])
 	}
-	return s[start : start+end]
+	return b.String()
 }
 
 // parseSynonym parses: "text" SCOPE [xrefs]
 func parseSynonym(s string) Synonym {
 	var syn Synonym
-	syn.Text = parseQuoted(s)
-
-	// Find scope after closing quote
-	afterQuote := strings.IndexByte(s[1:], '"')
-	if afterQuote < 0 {
+	text, rest := splitQuoted(s)
+	syn.Text = text
+	if rest == "" {
 		return syn
 	}
-	rest := s[afterQuote+3:] // skip past closing quote and space
 
-	// Scope is the first word
+	// Scope is the first word; OBO 1.2 synonyms may go straight to the
+	// xrefs.
 	parts := strings.Fields(rest)
-	if len(parts) > 0 {
+	if len(parts) > 0 && !strings.HasPrefix(parts[0], "[") {
 		syn.Scope = parts[0]
-	}
-	if len(parts) > 1 && !strings.HasPrefix(parts[1], "[") {
-		syn.Type = parts[1]
+		if len(parts) > 1 && !strings.HasPrefix(parts[1], "[") {
+			syn.Type = parts[1]
+		}
 	}
 
 	// Extract xrefs from brackets
@@ -191,18 +329,116 @@ func parseSynonym(s string) Synonym {
 	return syn
 }
 
-// parseIsA parses: "CHEBI:12345 ! name"
+// splitQualifiers removes an OBO 1.4 trailing qualifier block, as in
+// `CHEBI:12345 {source="x", is_inferred="true"} ! name`, and returns the
+// rest of the value (comment kept) and the qualifiers, nil if there are
+// none. Braces inside quotes or after the " ! " comment are left alone.
+func splitQualifiers(val string) (string, map[string]string) {
+	inQuote := false
+	for i := 0; i < len(val); i++ {
+		switch c := val[i]; {
+		case c == '\\' && inQuote:
+			i++
+		case c == '"':
+			inQuote = !inQuote
+		case inQuote:
+		case c == '!' && i > 0 && val[i-1] == ' ':
+			return val, nil
+		case c == '{':
+			end, q := parseQualifierBlock(val, i+1)
+			if end < 0 {
+				return val, nil
+			}
+			rest := strings.TrimSpace(val[:i])
+			if tail := strings.TrimSpace(val[end:]); tail != "" {
+				rest += " " + tail
+			}
+			return rest, q
+		}
+	}
+	return val, nil
+}
+
+// parseQualifierBlock parses `name="value", name2=value2}` starting at
+// val[i] and returns the index just past the closing brace (-1 if there
+// is none) and the qualifiers.
+func parseQualifierBlock(val string, i int) (int, map[string]string) {
+	q := make(map[string]string, 2)
+	for i < len(val) {
+		for i < len(val) && (val[i] == ' ' || val[i] == ',') {
+			i++
+		}
+		if i < len(val) && val[i] == '}' {
+			return i + 1, q
+		}
+		eq := strings.IndexAny(val[i:], "=}")
+		if eq < 0 || val[i+eq] == '}' {
+			return -1, nil
+		}
+		name := strings.TrimSpace(val[i : i+eq])
+		i += eq + 1
+		for i < len(val) && val[i] == ' ' {
+			i++
+		}
+		var b strings.Builder
+		if i < len(val) && val[i] == '"' {
+			for i++; i < len(val) && val[i] != '"'; i++ {
+				if val[i] == '\\' && i+1 < len(val) {
+					i++
+				}
+				b.WriteByte(val[i])
+			}
+			if i == len(val) {
+				return -1, nil
+			}
+			i++
+		} else {
+			for ; i < len(val) && val[i] != ',' && val[i] != '}'; i++ {
+				b.WriteByte(val[i])
+			}
+		}
+		if name != "" {
+			q[name] = strings.TrimSpace(b.String())
+		}
+	}
+	return -1, nil
+}
+
+// qualifierCardinality reads the cardinality, minCardinality and
+// maxCardinality qualifiers, or returns nil if there are none.
+func qualifierCardinality(q map[string]string) *Cardinality {
+	var c *Cardinality
+	set := func(name string, apply func(n int)) {
+		n, err := strconv.Atoi(q[name])
+		if err != nil || n < 0 {
+			return
+		}
+		if c == nil {
+			c = &Cardinality{Max: -1}
+		}
+		apply(n)
+	}
+	set("cardinality", func(n int) { c.Min, c.Max = n, n })
+	set("minCardinality", func(n int) { c.Min = n })
+	set("maxCardinality", func(n int) { c.Max = n })
+	return c
+}
+
+// parseIsA parses: "CHEBI:12345 {qualifiers} ! name"
 func parseIsA(val string, pool *internPool) Relationship {
 	rel := Relationship{Type: pool.get("is_a")}
+	val, rel.Qualifiers = splitQualifiers(val)
 	id, name, _ := strings.Cut(val, " ! ")
 	rel.TargetID = id
 	rel.Name = name
 	return rel
 }
 
-// parseRelationship parses: "type CHEBI:12345 ! name"
+// parseRelationship parses: "type CHEBI:12345 {qualifiers} ! name"
 func parseRelationship(val string, pool *internPool) Relationship {
 	var rel Relationship
+	val, rel.Qualifiers = splitQualifiers(val)
+	rel.Cardinality = qualifierCardinality(rel.Qualifiers)
 	parts := strings.SplitN(val, " ", 3)
 	if len(parts) >= 1 {
 		rel.Type = pool.get(parts[0])
@@ -221,8 +457,9 @@ func parseRelationship(val string, pool *internPool) Relationship {
 
 // parseIntersectionOf parses: "CHEBI:12345" (genus) or "relationship CHEBI:12345" (differentia).
 func parseIntersectionOf(val string, pool *internPool) IntersectionPart {
-	// Strip trailing comment
-	v, _, _ := strings.Cut(val, " ! ")
+	// Strip qualifiers and trailing comment
+	v, _ := splitQualifiers(val)
+	v, _, _ = strings.Cut(v, " ! ")
 	v = strings.TrimSpace(v)
 
 	parts := strings.SplitN(v, " ", 2)
@@ -251,28 +488,82 @@ func parseTypeDef(scanner *bufio.Scanner, pool *internPool) TypeDef {
 		}
 		switch key {
 		case "id":
-			td.ID = pool.get(val)
+			// The ID must match relationship types exactly, or the
+			// reasoner sees two roles and the Typedef's properties are
+			// lost.
+			td.ID = pool.get(tagValue(val))
 		case "name":
 			td.Name = val
 		case "is_transitive":
-			td.IsTransitive = val == "true"
+			td.IsTransitive = tagValue(val) == "true"
 		case "is_reflexive":
-			td.IsReflexive = val == "true"
+			td.IsReflexive = tagValue(val) == "true"
+		case "holds_over_chain":
+			td.HoldsOverChain = append(td.HoldsOverChain, strings.Join(strings.Fields(tagValue(val)), " "))
+		case "inverse_of":
+			td.InverseOf = pool.get(tagValue(val))
 		}
 	}
 	return td
 }
 
+// tagValue strips a trailing "! comment" and qualifier block from a tag
+// value that is a single token or list of tokens, and surrounding space.
+func tagValue(val string) string {
+	val, _ = splitQualifiers(val)
+	if i := strings.Index(val, " !"); i >= 0 {
+		val = val[:i]
+	}
+	return strings.TrimSpace(val)
+}
+
+// parseInstance parses an [Instance] stanza. Only the class assertions are
+// kept; property assertions between individuals are not modelled.
+func parseInstance(scanner *bufio.Scanner, pool *internPool) Individual {
+	var ind Individual
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			break
+		}
+		key, val, ok := strings.Cut(line, ": ")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "id":
+			ind.ID = val
+		case "name":
+			ind.Name = val
+		case "instance_of":
+			id, _, _ := strings.Cut(val, " ! ")
+			ind.Types = append(ind.Types, pool.get(strings.TrimSpace(id)))
+		}
+	}
+	return ind
+}
+
 // parsePropertyValue parses: "key value xsd:type" or "key \"value\" xsd:type"
-func parsePropertyValue(val string) (string, string) {
+// and returns the key, value and datatype, "" if there is none. An unquoted
+// value is usually an ID, typed only by an explicit xsd: name.
+func parsePropertyValue(val string) (key, value, datatype string) {
 	parts := strings.SplitN(val, " ", 3)
-	if len(parts) < 2 {
-		return "", ""
+	if len(parts) < 2 || parts[0] == "" {
+		return "", "", ""
 	}
-	key := parts[0]
+	key = parts[0]
 	if strings.HasPrefix(parts[1], "\"") {
 		// Quoted value
-		return key, parseQuoted(val)
+		text, rest := splitQuoted(val[len(key)+1:])
+		if f := strings.Fields(rest); len(f) > 0 && f[0] != "!" && !strings.HasPrefix(f[0], "{") {
+			datatype = f[0]
+		}
+		return key, text, datatype
+	}
+	if len(parts) == 3 {
+		if f := strings.Fields(parts[2]); len(f) > 0 && (strings.HasPrefix(f[0], "xsd:") || strings.HasPrefix(f[0], nsXSD)) {
+			datatype = f[0]
+		}
 	}
-	return key, parts[1]
+	return key, parts[1], datatype
 }
@@ -0,0 +1,29 @@
+package ontology
+
+import "io"
+
+// streamInternMax bounds the string pool of ParseOBOStream.
+const streamInternMax = 1 << 16
+
+// OBOHandler receives an OBO file as ParseOBOStream reads it. Header is
+// called once, with the header fields (Terms and the other slices empty),
+// before the first stanza. The stanza functions get a fresh value per
+// stanza, which they may keep. A nil function skips that kind of stanza;
+// an error stops parsing and is returned.
+type OBOHandler struct {
+	Header   func(head *Ontology) error
+	Term     func(t *Term) error
+	TypeDef  func(td *TypeDef) error
+	Instance func(ind *Individual) error
+}
+
+// ParseOBOStream parses an OBO file stanza by stanza, handing each to h
+// instead of collecting them, so memory stays bounded by the largest
+// stanza however big the file is. Pair it with OBOWriter to filter or
+// rewrite ontologies larger than RAM. opts apply as in
+// ParseOBOWithOptions, except MaxMemory and Bodies, which are not needed.
+// With opts.Warn set, the duplicate-ID check still remembers every ID.
+func ParseOBOStream(r io.Reader, opts ParseOptions, h OBOHandler) error {
+	pool := &internPool{m: make(map[string]string, 64), max: streamInternMax}
+	return parseOBO(r, opts, &Ontology{}, pool, h)
+}
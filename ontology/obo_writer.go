@@ -0,0 +1,261 @@
+package ontology
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// WriteOBO writes the ontology in OBO 1.4 flat-file format: the header,
+// then [Term], [Typedef] and [Instance] stanzas with tags in the order
+// the OBO 1.4 guide recommends. Relationships to individuals (HasValue)
+// are written as plain relationships and Self relationships, which OBO
+// cannot express, are left out. Cardinality read from OWL is written as
+// cardinality qualifiers.
+func WriteOBO(ont *Ontology, w io.Writer) error {
+	ow := NewOBOWriter(w, ont)
+	for i := range ont.Terms {
+		ow.WriteTerm(&ont.Terms[i])
+	}
+	for i := range ont.TypeDefs {
+		ow.WriteTypeDef(&ont.TypeDefs[i])
+	}
+	for i := range ont.Individuals {
+		ow.WriteIndividual(&ont.Individuals[i])
+	}
+	return ow.Flush()
+}
+
+// OBOWriter writes an OBO file one stanza at a time, as WriteOBO does but
+// without the whole ontology in memory: only the stanza being written is
+// buffered. Feed it from ParseOBOStream to filter or rewrite ontologies
+// larger than RAM. Stanzas are written in the order they are given.
+type OBOWriter struct {
+	bw *bufio.Writer
+	ow oboWriter
+}
+
+// NewOBOWriter writes the header of head (its terms and other stanzas are
+// not written) and returns a writer for the stanzas that follow.
+func NewOBOWriter(w io.Writer, head *Ontology) *OBOWriter {
+	bw := bufio.NewWriterSize(w, writerBufferSize)
+	ow := &OBOWriter{bw: bw, ow: oboWriter{w: bw}}
+	ow.ow.header(head)
+	return ow
+}
+
+// WriteTerm writes a [Term] stanza. It returns the first write error, as
+// do WriteTypeDef and WriteIndividual.
+func (w *OBOWriter) WriteTerm(t *Term) error {
+	w.ow.term(t)
+	return w.err()
+}
+
+// WriteTypeDef writes a [Typedef] stanza.
+func (w *OBOWriter) WriteTypeDef(td *TypeDef) error {
+	w.ow.typeDef(td)
+	return w.err()
+}
+
+// WriteIndividual writes an [Instance] stanza.
+func (w *OBOWriter) WriteIndividual(ind *Individual) error {
+	w.ow.individual(ind)
+	return w.err()
+}
+
+// Flush writes out any buffered stanzas.
+func (w *OBOWriter) Flush() error {
+	return w.bw.Flush()
+}
+
+// err returns the bufio.Writer's sticky error, which every write reports.
+func (w *OBOWriter) err() error {
+	_, err := w.bw.Write(nil)
+	return err
+}
+
+type oboWriter struct {
+	w *bufio.Writer
+}
+
+func (ow *oboWriter) header(ont *Ontology) {
+	version := ont.FormatVersion
+	if version == "" || strings.Contains(version, "://") {
+		version = "1.4"
+	}
+	name, dataVersion := oboHeaderValues(ont)
+	ow.tag("format-version", version)
+	if dataVersion != "" {
+		ow.tag("data-version", dataVersion)
+	}
+	ow.tag("ontology", name)
+	if ont.Metadata != nil {
+		for _, p := range metadataWriteProperties {
+			for _, v := range ont.Metadata.values(p.field) {
+				if isIRI(v) {
+					ow.tag("property_value", p.iri+" "+v)
+				} else {
+					ow.tag("property_value", p.iri+" "+quoteOBO(v)+" xsd:string")
+				}
+			}
+		}
+	}
+}
+
+func (ow *oboWriter) stanza(header string) {
+	ow.w.WriteString("\n" + header + "\n")
+}
+
+// tag writes "key: value", escaping newlines so the value stays on one
+// line.
+func (ow *oboWriter) tag(key, val string) {
+	ow.w.WriteString(key)
+	ow.w.WriteString(": ")
+	ow.w.WriteString(strings.ReplaceAll(val, "\n", `\n`))
+	ow.w.WriteByte('\n')
+}
+
+func (ow *oboWriter) tagOmit(key, val string) {
+	if val != "" {
+		ow.tag(key, val)
+	}
+}
+
+func (ow *oboWriter) term(t *Term) {
+	ow.stanza("[Term]")
+	ow.tag("id", t.ID)
+	ow.tagOmit("name", t.Name)
+	ow.tagOmit("namespace", t.Namespace)
+	for _, a := range t.AltIDs {
+		ow.tag("alt_id", a)
+	}
+	if t.Definition != "" {
+		ow.tag("def", quoteOBO(t.Definition)+" []")
+	}
+	ow.tagOmit("comment", t.Comment)
+	for _, s := range t.Subsets {
+		ow.tag("subset", s)
+	}
+	for i := range t.Synonyms {
+		syn := &t.Synonyms[i]
+		scope := syn.Scope
+		if scope == "" {
+			scope = "RELATED"
+		}
+		val := quoteOBO(syn.Text) + " " + scope
+		if syn.Type != "" {
+			val += " " + syn.Type
+		}
+		ow.tag("synonym", val+" ["+strings.Join(syn.Xrefs, ", ")+"]")
+	}
+	for _, x := range t.Xrefs {
+		ow.tag("xref", x+formatQualifiers(t.XrefQualifiers[x], nil))
+	}
+	for _, k := range sortedKeys(t.Properties) {
+		v, _ := t.Property(k)
+		ow.tag("property_value", k+" "+quoteOBO(v.Value)+" "+v.Datatype)
+	}
+	for i := range t.Relationships {
+		if rel := &t.Relationships[i]; rel.Type == "is_a" {
+			ow.tag("is_a", rel.TargetID+formatQualifiers(rel.Qualifiers, nil)+nameComment(rel.Name))
+		}
+	}
+	for _, p := range t.IntersectionOf {
+		switch {
+		case p.Relationship == "":
+			ow.tag("intersection_of", p.TargetID)
+		case !p.Self:
+			ow.tag("intersection_of", p.Relationship+" "+p.TargetID)
+		}
+	}
+	for _, u := range t.UnionOf {
+		ow.tag("union_of", u)
+	}
+	for i := range t.Relationships {
+		rel := &t.Relationships[i]
+		if rel.Type == "is_a" || rel.Self || rel.TargetID == "" {
+			continue
+		}
+		ow.tag("relationship", rel.Type+" "+rel.TargetID+formatQualifiers(rel.Qualifiers, rel.Cardinality)+nameComment(rel.Name))
+	}
+	if t.IsObsolete {
+		ow.tag("is_obsolete", "true")
+	}
+	for _, r := range t.ReplacedBy {
+		ow.tag("replaced_by", r)
+	}
+	for _, c := range t.Consider {
+		ow.tag("consider", c)
+	}
+}
+
+func (ow *oboWriter) typeDef(td *TypeDef) {
+	ow.stanza("[Typedef]")
+	ow.tag("id", td.ID)
+	ow.tagOmit("name", td.Name)
+	if td.IsReflexive {
+		ow.tag("is_reflexive", "true")
+	}
+	if td.IsTransitive {
+		ow.tag("is_transitive", "true")
+	}
+	for _, chain := range td.HoldsOverChain {
+		ow.tag("holds_over_chain", chain)
+	}
+	ow.tagOmit("inverse_of", td.InverseOf)
+}
+
+func (ow *oboWriter) individual(ind *Individual) {
+	ow.stanza("[Instance]")
+	ow.tag("id", ind.ID)
+	ow.tagOmit("name", ind.Name)
+	for _, t := range ind.Types {
+		ow.tag("instance_of", t)
+	}
+}
+
+// quoteOBO quotes s for a def, synonym or property value, escaping quotes
+// and backslashes.
+func quoteOBO(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
+
+func nameComment(name string) string {
+	if name == "" {
+		return ""
+	}
+	return " ! " + name
+}
+
+// formatQualifiers renders a trailing qualifier block, adding cardinality
+// qualifiers for c when q does not already carry them.
+func formatQualifiers(q map[string]string, c *Cardinality) string {
+	if c != nil && q["cardinality"] == "" && q["minCardinality"] == "" && q["maxCardinality"] == "" {
+		merged := make(map[string]string, len(q)+2)
+		for k, v := range q {
+			merged[k] = v
+		}
+		switch {
+		case c.Min == c.Max:
+			merged["cardinality"] = strconv.Itoa(c.Min)
+		default:
+			if c.Min > 0 {
+				merged["minCardinality"] = strconv.Itoa(c.Min)
+			}
+			if c.Max >= 0 {
+				merged["maxCardinality"] = strconv.Itoa(c.Max)
+			}
+		}
+		q = merged
+	}
+	if len(q) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(q))
+	for _, k := range sortedKeys(q) {
+		parts = append(parts, k+"="+quoteOBO(q[k]))
+	}
+	return " {" + strings.Join(parts, ", ") + "}"
+}
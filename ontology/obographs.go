@@ -0,0 +1,378 @@
+package ontology
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// OBO Graphs JSON (github.com/geneontology/obographs) document types.
+// Only the parts the model can represent are declared.
+type obographsDoc struct {
+	Graphs []obographsGraph `json:"graphs"`
+}
+
+type obographsGraph struct {
+	ID                      string                   `json:"id"`
+	Meta                    *obographsMeta           `json:"meta,omitempty"`
+	Nodes                   []obographsNode          `json:"nodes"`
+	Edges                   []obographsEdge          `json:"edges"`
+	LogicalDefinitionAxioms []obographsLogicalDef    `json:"logicalDefinitionAxioms,omitempty"`
+	PropertyChainAxioms     []obographsPropertyChain `json:"propertyChainAxioms,omitempty"`
+}
+
+type obographsNode struct {
+	ID   string         `json:"id"`
+	Lbl  string         `json:"lbl,omitempty"`
+	Type string         `json:"type,omitempty"` // CLASS, PROPERTY, INDIVIDUAL
+	Meta *obographsMeta `json:"meta,omitempty"`
+}
+
+type obographsMeta struct {
+	Definition          *obographsValue     `json:"definition,omitempty"`
+	Comments            []string            `json:"comments,omitempty"`
+	Subsets             []string            `json:"subsets,omitempty"`
+	Synonyms            []obographsSynonym  `json:"synonyms,omitempty"`
+	Xrefs               []obographsValue    `json:"xrefs,omitempty"`
+	BasicPropertyValues []obographsProperty `json:"basicPropertyValues,omitempty"`
+	Version             string              `json:"version,omitempty"`
+	Deprecated          bool                `json:"deprecated,omitempty"`
+}
+
+type obographsValue struct {
+	Val string `json:"val"`
+}
+
+type obographsSynonym struct {
+	Pred        string   `json:"pred"`
+	Val         string   `json:"val"`
+	SynonymType string   `json:"synonymType,omitempty"`
+	Xrefs       []string `json:"xrefs,omitempty"`
+}
+
+type obographsProperty struct {
+	Pred    string `json:"pred"`
+	Val     string `json:"val"`
+	ValType string `json:"valType,omitempty"` // compact XSD datatype, such as xsd:decimal
+}
+
+type obographsEdge struct {
+	Sub  string `json:"sub"`
+	Pred string `json:"pred"`
+	Obj  string `json:"obj"`
+}
+
+type obographsLogicalDef struct {
+	DefinedClassID string                 `json:"definedClassId"`
+	GenusIDs       []string               `json:"genusIds,omitempty"`
+	Restrictions   []obographsRestriction `json:"restrictions,omitempty"`
+}
+
+type obographsRestriction struct {
+	PropertyID string `json:"propertyId"`
+	FillerID   string `json:"fillerId"`
+}
+
+type obographsPropertyChain struct {
+	PredicateID       string   `json:"predicateId"`
+	ChainPredicateIDs []string `json:"chainPredicateIds"`
+}
+
+// OBO Graphs basicPropertyValues predicates for fields without a
+// dedicated slot.
+const (
+	obographsNamespace   = nsOBOInOwl + "hasOBONamespace"
+	obographsAltID       = nsOBOInOwl + "hasAlternativeId"
+	obographsConsider    = nsOBOInOwl + "consider"
+	obographsReplacedBy  = nsIAO + "0100001"
+	obographsTransitive  = nsOWL + "TransitiveProperty"
+	obographsReflexive   = nsOWL + "ReflexiveProperty"
+	obographsTrueLiteral = "true"
+)
+
+// WriteOBOGraphs writes the ontology as a single-graph OBO Graphs JSON
+// document, with IDs as OBO PURLs. OBO Graphs has no place for union_of,
+// one_of, Self or HasValue fillers, cardinality or qualifiers: union_of
+// and one_of are dropped, relationships become plain edges, and
+// intersection_of Self parts are dropped.
+func WriteOBOGraphs(ont *Ontology, w io.Writer) error {
+	short := ShortName(ont)
+	iri := func(id string) string { return idIRI(id, short) }
+	graphIRI, version := ontologyIRI(ont)
+	g := obographsGraph{ID: graphIRI, Nodes: []obographsNode{}, Edges: []obographsEdge{}}
+	if version != "" {
+		g.Meta = &obographsMeta{Version: version}
+	}
+	if ont.Metadata != nil {
+		if g.Meta == nil {
+			g.Meta = &obographsMeta{}
+		}
+		for _, p := range metadataWriteProperties {
+			for _, v := range ont.Metadata.values(p.field) {
+				g.Meta.BasicPropertyValues = append(g.Meta.BasicPropertyValues, obographsProperty{Pred: p.iri, Val: v})
+			}
+		}
+	}
+
+	for i := range ont.Terms {
+		t := &ont.Terms[i]
+		m := &obographsMeta{
+			Subsets:    make([]string, len(t.Subsets)),
+			Deprecated: t.IsObsolete,
+		}
+		if t.Definition != "" {
+			m.Definition = &obographsValue{Val: t.Definition}
+		}
+		if t.Comment != "" {
+			m.Comments = []string{t.Comment}
+		}
+		for j, s := range t.Subsets {
+			m.Subsets[j] = iri(s)
+		}
+		for _, syn := range t.Synonyms {
+			m.Synonyms = append(m.Synonyms, obographsSynonym{
+				Pred:        synonymProperty(syn.Scope),
+				Val:         syn.Text,
+				SynonymType: syn.Type,
+				Xrefs:       syn.Xrefs,
+			})
+		}
+		for _, x := range t.Xrefs {
+			m.Xrefs = append(m.Xrefs, obographsValue{Val: x})
+		}
+		bpv := func(pred string, vals ...string) {
+			for _, v := range vals {
+				if v != "" {
+					m.BasicPropertyValues = append(m.BasicPropertyValues, obographsProperty{Pred: pred, Val: v})
+				}
+			}
+		}
+		bpv(obographsNamespace, t.Namespace)
+		bpv(obographsAltID, t.AltIDs...)
+		bpv(obographsConsider, t.Consider...)
+		for _, r := range t.ReplacedBy {
+			bpv(obographsReplacedBy, iri(r))
+		}
+		for _, k := range sortedKeys(t.Properties) {
+			if v := t.Properties[k]; v != "" {
+				m.BasicPropertyValues = append(m.BasicPropertyValues,
+					obographsProperty{Pred: propertyIRI(k, short), Val: v, ValType: t.PropertyTypes[k]})
+			}
+		}
+		g.Nodes = append(g.Nodes, obographsNode{ID: iri(t.ID), Lbl: t.Name, Type: "CLASS", Meta: m})
+
+		for _, rel := range t.Relationships {
+			if rel.Self || rel.TargetID == "" {
+				continue
+			}
+			pred := "is_a"
+			if rel.Type != "is_a" {
+				pred = iri(rel.Type)
+			}
+			g.Edges = append(g.Edges, obographsEdge{Sub: iri(t.ID), Pred: pred, Obj: iri(rel.TargetID)})
+		}
+
+		if len(t.IntersectionOf) > 0 {
+			def := obographsLogicalDef{DefinedClassID: iri(t.ID)}
+			for _, p := range t.IntersectionOf {
+				switch {
+				case p.Relationship == "":
+					def.GenusIDs = append(def.GenusIDs, iri(p.TargetID))
+				case !p.Self:
+					def.Restrictions = append(def.Restrictions, obographsRestriction{PropertyID: iri(p.Relationship), FillerID: iri(p.TargetID)})
+				}
+			}
+			g.LogicalDefinitionAxioms = append(g.LogicalDefinitionAxioms, def)
+		}
+	}
+
+	for i := range ont.TypeDefs {
+		td := &ont.TypeDefs[i]
+		node := obographsNode{ID: iri(td.ID), Lbl: td.Name, Type: "PROPERTY"}
+		if td.IsTransitive || td.IsReflexive {
+			node.Meta = &obographsMeta{}
+			if td.IsTransitive {
+				node.Meta.BasicPropertyValues = append(node.Meta.BasicPropertyValues, obographsProperty{Pred: obographsTransitive, Val: obographsTrueLiteral})
+			}
+			if td.IsReflexive {
+				node.Meta.BasicPropertyValues = append(node.Meta.BasicPropertyValues, obographsProperty{Pred: obographsReflexive, Val: obographsTrueLiteral})
+			}
+		}
+		g.Nodes = append(g.Nodes, node)
+		for _, chain := range td.HoldsOverChain {
+			links := strings.Fields(chain)
+			for j, l := range links {
+				links[j] = iri(l)
+			}
+			g.PropertyChainAxioms = append(g.PropertyChainAxioms, obographsPropertyChain{PredicateID: iri(td.ID), ChainPredicateIDs: links})
+		}
+	}
+
+	for i := range ont.Individuals {
+		ind := &ont.Individuals[i]
+		g.Nodes = append(g.Nodes, obographsNode{ID: iri(ind.ID), Lbl: ind.Name, Type: "INDIVIDUAL"})
+		for _, t := range ind.Types {
+			g.Edges = append(g.Edges, obographsEdge{Sub: iri(ind.ID), Pred: "rdf:type", Obj: iri(t)})
+		}
+	}
+
+	bw := bufio.NewWriterSize(w, writerBufferSize)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(obographsDoc{Graphs: []obographsGraph{g}}); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// ReadOBOGraphs reads the first graph of an OBO Graphs JSON document.
+// Node and property IDs are converted from OBO PURLs back to CURIEs.
+func ReadOBOGraphs(r io.Reader) (*Ontology, error) {
+	var doc obographsDoc
+	if err := json.NewDecoder(bufio.NewReaderSize(r, writerBufferSize)).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("obographs: %w", err)
+	}
+	if len(doc.Graphs) == 0 {
+		return nil, fmt.Errorf("obographs: document has no graphs")
+	}
+	g := &doc.Graphs[0]
+
+	ont := &Ontology{Ontology: g.ID}
+	if g.Meta != nil {
+		ont.DataVersion = g.Meta.Version
+		for _, p := range g.Meta.BasicPropertyValues {
+			addMetadata(ont, p.Pred, p.Val)
+		}
+	}
+	short := ShortName(ont)
+	localProperty := nsOBO + short + "/"
+
+	termPos := make(map[string]int)
+	tdPos := make(map[string]int)
+	indPos := make(map[string]int)
+	for _, n := range g.Nodes {
+		id := oboIDFromURI(n.ID)
+		switch n.Type {
+		case "PROPERTY":
+			td := TypeDef{ID: id, Name: n.Lbl}
+			if n.Meta != nil {
+				for _, p := range n.Meta.BasicPropertyValues {
+					td.IsTransitive = td.IsTransitive || p.Pred == obographsTransitive && p.Val == obographsTrueLiteral
+					td.IsReflexive = td.IsReflexive || p.Pred == obographsReflexive && p.Val == obographsTrueLiteral
+				}
+			}
+			tdPos[n.ID] = len(ont.TypeDefs)
+			ont.TypeDefs = append(ont.TypeDefs, td)
+			continue
+		case "INDIVIDUAL":
+			indPos[n.ID] = len(ont.Individuals)
+			ont.Individuals = append(ont.Individuals, Individual{ID: id, Name: n.Lbl})
+			continue
+		case "CLASS", "":
+		default:
+			continue
+		}
+
+		t := Term{ID: id, Name: n.Lbl}
+		if m := n.Meta; m != nil {
+			if m.Definition != nil {
+				t.Definition = m.Definition.Val
+			}
+			t.Comment = strings.Join(m.Comments, "\n")
+			t.IsObsolete = m.Deprecated
+			for _, s := range m.Subsets {
+				t.Subsets = append(t.Subsets, oboIDFromURI(s))
+			}
+			for _, s := range m.Synonyms {
+				t.Synonyms = append(t.Synonyms, Synonym{Text: s.Val, Scope: synonymScope(s.Pred), Type: s.SynonymType, Xrefs: s.Xrefs})
+			}
+			for _, x := range m.Xrefs {
+				t.Xrefs = append(t.Xrefs, x.Val)
+			}
+			for _, p := range m.BasicPropertyValues {
+				switch p.Pred {
+				case obographsNamespace:
+					t.Namespace = p.Val
+				case obographsAltID:
+					t.AltIDs = append(t.AltIDs, p.Val)
+				case obographsConsider:
+					t.Consider = append(t.Consider, p.Val)
+				case obographsReplacedBy:
+					t.ReplacedBy = append(t.ReplacedBy, oboIDFromURI(p.Val))
+				default:
+					key := p.Pred
+					if strings.HasPrefix(key, localProperty) {
+						key = key[len(localProperty):]
+					} else {
+						key = oboIDFromURI(key)
+					}
+					t.setProperty(key, p.Val, p.ValType)
+				}
+			}
+		}
+		t.Obsolescence = obsolescenceOf(&t)
+		termPos[n.ID] = len(ont.Terms)
+		ont.Terms = append(ont.Terms, t)
+	}
+
+	for _, e := range g.Edges {
+		if j, ok := indPos[e.Sub]; ok {
+			ont.Individuals[j].Types = append(ont.Individuals[j].Types, oboIDFromURI(e.Obj))
+			continue
+		}
+		j, ok := termPos[e.Sub]
+		if !ok {
+			continue
+		}
+		typ := "is_a"
+		if e.Pred != "is_a" {
+			typ = oboIDFromURI(e.Pred)
+		}
+		t := &ont.Terms[j]
+		t.Relationships = append(t.Relationships, Relationship{Type: typ, TargetID: oboIDFromURI(e.Obj)})
+	}
+
+	for _, def := range g.LogicalDefinitionAxioms {
+		j, ok := termPos[def.DefinedClassID]
+		if !ok {
+			continue
+		}
+		t := &ont.Terms[j]
+		for _, genus := range def.GenusIDs {
+			t.IntersectionOf = append(t.IntersectionOf, IntersectionPart{TargetID: oboIDFromURI(genus)})
+		}
+		for _, r := range def.Restrictions {
+			t.IntersectionOf = append(t.IntersectionOf, IntersectionPart{Relationship: oboIDFromURI(r.PropertyID), TargetID: oboIDFromURI(r.FillerID)})
+		}
+	}
+
+	for _, pc := range g.PropertyChainAxioms {
+		j, ok := tdPos[pc.PredicateID]
+		if !ok {
+			continue
+		}
+		links := make([]string, len(pc.ChainPredicateIDs))
+		for k, l := range pc.ChainPredicateIDs {
+			links[k] = oboIDFromURI(l)
+		}
+		ont.TypeDefs[j].HoldsOverChain = append(ont.TypeDefs[j].HoldsOverChain, strings.Join(links, " "))
+	}
+	return ont, nil
+}
+
+// synonymScope is the inverse of synonymProperty, accepting the predicate
+// with or without the oboInOwl namespace.
+func synonymScope(pred string) string {
+	switch strings.TrimPrefix(pred, nsOBOInOwl) {
+	case "hasExactSynonym":
+		return "EXACT"
+	case "hasBroadSynonym":
+		return "BROAD"
+	case "hasNarrowSynonym":
+		return "NARROW"
+	}
+	return "RELATED"
+}
@@ -0,0 +1,118 @@
+package ontology
+
+import "strings"
+
+// Obsolescence reasons, named after the OBO Metadata Ontology's
+// obsolescence reason specifications.
+const (
+	ObsolescenceMerged      = "terms_merged"         // IAO:0000227
+	ObsolescenceSplit       = "term_split"           // IAO:0000229
+	ObsolescencePlaceholder = "placeholder_removed"  // IAO:0000226
+	ObsolescenceImported    = "term_imported"        // IAO:0000228
+	ObsolescenceExternal    = "external_replacement" // IAO:0000423
+	ObsolescenceOutOfScope  = "out_of_scope"         // OMO:0001000
+)
+
+// Where an ObsolescenceInfo's reason was found.
+const (
+	ObsolescenceFromAnnotation = "annotation" // an IAO:0000231 property value
+	ObsolescenceFromComment    = "comment"    // wording in the term's comment
+)
+
+// iaoObsolescenceReason is the "has obsolescence reason" annotation
+// property.
+const iaoObsolescenceReason = "IAO:0000231"
+
+// obsolescenceReasons maps the reasons' IDs, and their labels for
+// annotations written as literals, to the Obsolescence constants.
+var obsolescenceReasons = map[string]string{
+	"IAO:0000227": ObsolescenceMerged,
+	"IAO:0000229": ObsolescenceSplit,
+	"IAO:0000226": ObsolescencePlaceholder,
+	"IAO:0000228": ObsolescenceImported,
+	"IAO:0000423": ObsolescenceExternal,
+	"OMO:0001000": ObsolescenceOutOfScope,
+
+	"terms merged":        ObsolescenceMerged,
+	"term split":          ObsolescenceSplit,
+	"placeholder removed": ObsolescencePlaceholder,
+	"term imported":       ObsolescenceImported,
+	"to be replaced with external ontology term": ObsolescenceExternal,
+	"out of scope": ObsolescenceOutOfScope,
+}
+
+// obsolescenceWording maps comment phrases, lower-cased, to the reason
+// they state, in the order they are tried.
+var obsolescenceWording = []struct{ phrase, reason string }{
+	{"terms merged", ObsolescenceMerged},
+	{"merged into", ObsolescenceMerged},
+	{"merged with", ObsolescenceMerged},
+	{"was merged", ObsolescenceMerged},
+	{"duplicate of", ObsolescenceMerged},
+	{"term split", ObsolescenceSplit},
+	{"split into", ObsolescenceSplit},
+	{"was split", ObsolescenceSplit},
+	{"out of scope", ObsolescenceOutOfScope},
+	{"outside the scope", ObsolescenceOutOfScope},
+	{"placeholder", ObsolescencePlaceholder},
+}
+
+// ObsolescenceInfo records why an obsolete term was retired, so migration
+// tooling can tell a merge (follow replaced_by) from a term retired
+// outright. Reason is one of the Obsolescence constants, or the
+// annotation's value as given if it names no known reason.
+type ObsolescenceInfo struct {
+	Reason string `json:"reason"`
+	Source string `json:"source"`
+}
+
+// Merged reports whether the term was obsoleted by merging it into
+// another.
+func (o *ObsolescenceInfo) Merged() bool {
+	return o != nil && o.Reason == ObsolescenceMerged
+}
+
+// obsolescenceOf works out t's ObsolescenceInfo, or nil if t is not
+// obsolete or states no reason. An IAO:0000231 property value (however
+// its key and value IRIs are written) wins over the comment, which is
+// searched for the usual wording: "merged into", "split into", "out of
+// scope" and the like.
+func obsolescenceOf(t *Term) *ObsolescenceInfo {
+	if !t.IsObsolete {
+		return nil
+	}
+	for _, k := range sortedKeys(t.Properties) {
+		v := t.Properties[k]
+		if compactOBOID(k) != iaoObsolescenceReason {
+			continue
+		}
+		v = compactOBOID(strings.TrimSpace(v))
+		if r, ok := obsolescenceReasons[strings.ToLower(v)]; ok {
+			v = r
+		} else if r, ok := obsolescenceReasons[v]; ok {
+			v = r
+		}
+		return &ObsolescenceInfo{Reason: v, Source: ObsolescenceFromAnnotation}
+	}
+	if t.Comment != "" {
+		c := strings.ToLower(t.Comment)
+		for _, w := range obsolescenceWording {
+			if strings.Contains(c, w.phrase) {
+				return &ObsolescenceInfo{Reason: w.reason, Source: ObsolescenceFromComment}
+			}
+		}
+	}
+	return nil
+}
+
+// compactOBOID writes an OBO PURL, or a bare OWL local name such as
+// IAO_0000231, as a prefixed ID.
+func compactOBOID(s string) string {
+	s = oboIDFromURI(s)
+	if !strings.ContainsAny(s, ":/") {
+		if i := strings.IndexByte(s, '_'); i > 0 {
+			return s[:i] + ":" + s[i+1:]
+		}
+	}
+	return s
+}
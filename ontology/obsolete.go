@@ -0,0 +1,58 @@
+package ontology
+
+import "fmt"
+
+// ObsoletePolicy says whether obsolete terms are kept. It is the one
+// setting every stage that handles terms takes: ParseOptions (and so every
+// exporter, which writes what was parsed), IndexOptions, SKOSOptions and
+// reasoner.NormalizeOptions. The zero value keeps each stage's default:
+// parsing, indexing and the exporters keep obsolete terms, flagged as
+// such, while classification and SKOS leave them out, since an obsolete
+// term asserts nothing about the live hierarchy.
+type ObsoletePolicy string
+
+const (
+	ObsoleteDefault ObsoletePolicy = ""        // the stage's default
+	ObsoleteInclude ObsoletePolicy = "include" // keep obsolete terms everywhere
+	ObsoleteExclude ObsoletePolicy = "exclude" // drop them everywhere
+)
+
+// ParseObsoletePolicy reads an -obsolete flag value: include, exclude, or
+// "" or default for the stage defaults.
+func ParseObsoletePolicy(s string) (ObsoletePolicy, error) {
+	switch p := ObsoletePolicy(s); p {
+	case ObsoleteDefault, ObsoleteInclude, ObsoleteExclude:
+		return p, nil
+	case "default":
+		return ObsoleteDefault, nil
+	}
+	return "", fmt.Errorf("invalid obsolete policy %q (want include, exclude or default)", s)
+}
+
+// Keep reports whether a stage that keeps obsolete terms by default if def
+// is set keeps them under p.
+func (p ObsoletePolicy) Keep(def bool) bool {
+	switch p {
+	case ObsoleteInclude:
+		return true
+	case ObsoleteExclude:
+		return false
+	}
+	return def
+}
+
+// DropObsolete removes the obsolete terms from ont in place and returns
+// how many there were. Relationships to them are kept as they are, as in
+// Filter.
+func DropObsolete(ont *Ontology) int {
+	kept := ont.Terms[:0]
+	for i := range ont.Terms {
+		if !ont.Terms[i].IsObsolete {
+			kept = append(kept, ont.Terms[i])
+		}
+	}
+	n := len(ont.Terms) - len(kept)
+	clear(ont.Terms[len(kept):])
+	ont.Terms = kept
+	return n
+}
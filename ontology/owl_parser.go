@@ -3,6 +3,8 @@ package ontology
 import (
 	"encoding/xml"
 	"io"
+	"slices"
+	"strconv"
 	"strings"
 )
 
@@ -16,11 +18,44 @@ const (
 
 // ParseOWL parses a ChEBI OWL/RDF-XML ontology from the given reader.
 func ParseOWL(r io.Reader) (*Ontology, error) {
+	return ParseOWLWithOptions(r, ParseOptions{})
+}
+
+// ParseOWLWithOptions is ParseOWL with a memory budget, warnings and an
+// obsolete-term policy; see ParseOptions.
+func ParseOWLWithOptions(r io.Reader, opts ParseOptions) (*Ontology, error) {
 	decoder := xml.NewDecoder(r)
 	pool := newInternPool()
+	budget := &memoryBudget{opts: opts}
+	warn := newWarner(opts)
 
 	ont := &Ontology{
-		Terms: make([]Term, 0, initialTermCapacity),
+		Terms: make([]Term, 0, termCapacity(r, owlBytesPerTerm)),
+	}
+	tdPos := make(map[string]int)
+
+	// A class is held back until the next element that is not an
+	// owl:Axiom, since the axioms annotating its synonyms follow it.
+	// Axioms about any other class wait in early.
+	var pending *Term
+	early := make(map[string][]synonymAxiom)
+	finish := func() {
+		if pending == nil {
+			return
+		}
+		term := *pending
+		pending = nil
+		for _, ax := range early[term.ID] {
+			ax.apply(&term)
+		}
+		delete(early, term.ID)
+		warn.term(term.ID)
+		opts.Synonyms.apply(&term)
+		term.Obsolescence = obsolescenceOf(&term)
+		if !term.IsObsolete || opts.Obsolete.Keep(true) {
+			ont.Terms = append(ont.Terms, term)
+			budget.added(ont)
+		}
 	}
 
 	for {
@@ -36,20 +71,49 @@ func ParseOWL(r io.Reader) (*Ontology, error) {
 		if !ok {
 			continue
 		}
+		if !matchElement(se, nsOWL, "Axiom") {
+			finish()
+		}
 
 		switch {
 		case matchElement(se, nsOWL, "Class"):
-			term := parseOWLClass(decoder, se, pool)
-			if term.ID != "" {
-				ont.Terms = append(ont.Terms, term)
+			if term := parseOWLClass(decoder, se, pool); term.ID != "" {
+				pending = &term
+			}
+		case matchElement(se, nsOWL, "Axiom"):
+			ax, ok := parseOWLSynonymAxiom(decoder)
+			switch {
+			case !ok:
+			case pending != nil && pending.ID == ax.source:
+				ax.apply(pending)
+			default:
+				early[ax.source] = append(early[ax.source], ax)
 			}
 		case matchElement(se, nsOWL, "Ontology"):
 			parseOWLOntologyHeader(decoder, se, ont)
-		case matchElement(se, nsOWL, "ObjectProperty"):
+		case matchElement(se, nsOWL, "ObjectProperty"),
+			matchElement(se, nsOWL, "TransitiveProperty"),
+			matchElement(se, nsOWL, "ReflexiveProperty"):
+			// A property typed by its element name, or declared more
+			// than once, must stay one Typedef so the reasoner sees one
+			// role with all its characteristics.
 			td := parseOWLObjectProperty(decoder, se, pool)
-			if td.ID != "" {
+			td.IsTransitive = td.IsTransitive || se.Name.Local == "TransitiveProperty"
+			td.IsReflexive = td.IsReflexive || se.Name.Local == "ReflexiveProperty"
+			if td.ID == "" {
+				break
+			}
+			if i, ok := tdPos[td.ID]; ok {
+				mergeOWLTypeDef(&ont.TypeDefs[i], &td)
+			} else {
+				tdPos[td.ID] = len(ont.TypeDefs)
 				ont.TypeDefs = append(ont.TypeDefs, td)
 			}
+		case matchElement(se, nsOWL, "NamedIndividual"):
+			ind := parseOWLIndividual(decoder, se, pool)
+			if ind.ID != "" {
+				ont.Individuals = append(ont.Individuals, ind)
+			}
 		case matchElement(se, nsRDF, "RDF"):
 			// Container element — descend into it, don't skip
 		default:
@@ -57,9 +121,95 @@ func ParseOWL(r io.Reader) (*Ontology, error) {
 		}
 	}
 
+	finish()
+	// Axioms still waiting annotate classes finished before them, or
+	// none. Synonyms spilled under MaxMemory are not found, which only
+	// costs their types.
+	if len(early) > 0 {
+		for i := range ont.Terms {
+			if axs := early[ont.Terms[i].ID]; len(axs) > 0 {
+				for _, ax := range axs {
+					ax.apply(&ont.Terms[i])
+				}
+				opts.Synonyms.apply(&ont.Terms[i])
+			}
+		}
+	}
+
+	if budget.err != nil {
+		return nil, budget.err
+	}
 	return ont, nil
 }
 
+// synonymAxiom is an owl:Axiom annotating a synonym assertion of source
+// with its type and xrefs.
+type synonymAxiom struct {
+	source string
+	scope  string
+	text   string
+	typ    string
+	xrefs  []string
+}
+
+// apply sets the type and xrefs of t's synonym the axiom annotates.
+func (ax synonymAxiom) apply(t *Term) {
+	for i := range t.Synonyms {
+		syn := &t.Synonyms[i]
+		if syn.Scope == ax.scope && syn.Text == ax.text {
+			if ax.typ != "" {
+				syn.Type = ax.typ
+			}
+			syn.Xrefs = append(syn.Xrefs, ax.xrefs...)
+			return
+		}
+	}
+}
+
+// synonymPropertyScopes maps the oboInOwl synonym properties to OBO
+// scopes.
+var synonymPropertyScopes = map[string]string{
+	"hasExactSynonym":   "EXACT",
+	"hasBroadSynonym":   "BROAD",
+	"hasNarrowSynonym":  "NARROW",
+	"hasRelatedSynonym": "RELATED",
+}
+
+// parseOWLSynonymAxiom reads an owl:Axiom up to its end. It reports false
+// unless the axiom annotates a synonym assertion with a type or xrefs.
+func parseOWLSynonymAxiom(decoder *xml.Decoder) (ax synonymAxiom, ok bool) {
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return ax, false
+		}
+		switch el := tok.(type) {
+		case xml.StartElement:
+			switch {
+			case matchElement(el, nsOWL, "annotatedSource"):
+				ax.source = oboIDFromURI(getAttr(el, nsRDF, "resource"))
+				decoder.Skip()
+			case matchElement(el, nsOWL, "annotatedProperty"):
+				if prop := getAttr(el, nsRDF, "resource"); strings.HasPrefix(prop, nsOBOInOwl) {
+					ax.scope = synonymPropertyScopes[prop[len(nsOBOInOwl):]]
+				}
+				decoder.Skip()
+			case matchElement(el, nsOWL, "annotatedTarget"):
+				ax.text = readCharData(decoder)
+			case matchElement(el, nsOBOInOwl, "hasSynonymType"):
+				ax.typ = oboIDFromURI(getAttr(el, nsRDF, "resource"))
+				decoder.Skip()
+			case matchElement(el, nsOBOInOwl, "hasDbXref"):
+				ax.xrefs = append(ax.xrefs, readCharData(decoder))
+			default:
+				decoder.Skip()
+			}
+		case xml.EndElement:
+			return ax, ax.source != "" && ax.scope != "" && (ax.typ != "" || len(ax.xrefs) > 0)
+		}
+	}
+}
+
 func matchElement(se xml.StartElement, ns, local string) bool {
 	return se.Name.Space == ns && se.Name.Local == local
 }
@@ -74,9 +224,14 @@ func getAttr(se xml.StartElement, ns, local string) string {
 }
 
 func oboIDFromURI(uri string) string {
-	// Convert http://purl.obolibrary.org/obo/CHEBI_12345 to CHEBI:12345
+	// Convert http://purl.obolibrary.org/obo/CHEBI_12345 to CHEBI:12345,
+	// and an ontology-local http://purl.obolibrary.org/obo/chebi#has_part
+	// to has_part as OBO files write it.
 	if strings.HasPrefix(uri, nsOBO) {
 		id := uri[len(nsOBO):]
+		if _, local, ok := strings.Cut(id, "#"); ok {
+			return local
+		}
 		if idx := strings.IndexByte(id, '_'); idx >= 0 {
 			return id[:idx] + ":" + id[idx+1:]
 		}
@@ -104,6 +259,15 @@ func parseOWLOntologyHeader(decoder *xml.Decoder, se xml.StartElement, ont *Onto
 					ont.DataVersion = v
 				}
 			}
+			if prop := t.Name.Space + t.Name.Local; metadataField(prop) != "" {
+				if v := getAttr(t, nsRDF, "resource"); v != "" {
+					addMetadata(ont, prop, v)
+					decoder.Skip()
+				} else {
+					addMetadata(ont, prop, readCharData(decoder))
+				}
+				continue
+			}
 			decoder.Skip()
 		case xml.EndElement:
 			return
@@ -139,18 +303,48 @@ func parseOWLClass(decoder *xml.Decoder, se xml.StartElement, pool *internPool)
 					})
 					decoder.Skip()
 				} else {
-					// Complex restriction — parse owl:Restriction
-					rel := parseOWLRestriction(decoder, pool)
-					if rel.Type != "" && rel.TargetID != "" {
+					rel, ax := parseOWLSuperClass(decoder, pool)
+					if ax != nil {
+						t.NonEL = append(t.NonEL, *ax)
+					} else if rel.Type != "" {
 						t.Relationships = append(t.Relationships, rel)
 					}
 				}
+			case matchElement(el, nsOWL, "equivalentClass"):
+				union, oneOf, inter := parseOWLEquivalentClass(decoder, pool)
+				t.UnionOf = append(t.UnionOf, union...)
+				t.OneOf = append(t.OneOf, oneOf...)
+				if len(t.IntersectionOf) == 0 {
+					t.IntersectionOf = inter
+				}
 			case el.Name.Local == "deprecated":
-				val := readCharData(decoder)
-				t.IsObsolete = val == "true"
+				val := strings.TrimSpace(readCharData(decoder))
+				t.IsObsolete = val == "true" || val == "1"
+			case el.Name.Local == "IAO_0000231": // has obsolescence reason
+				// Kept under its OBO key, with the reason's ID.
+				if res := getAttr(el, nsRDF, "resource"); res != "" {
+					t.setProperty(iaoObsolescenceReason, oboIDFromURI(res), "")
+					decoder.Skip()
+				} else if val := readCharData(decoder); val != "" {
+					t.setProperty(iaoObsolescenceReason, val, getAttr(el, nsRDF, "datatype"))
+				}
+			case el.Name.Local == "IAO_0100001": // term replaced by
+				if res := getAttr(el, nsRDF, "resource"); res != "" {
+					t.ReplacedBy = append(t.ReplacedBy, oboIDFromURI(res))
+					decoder.Skip()
+				} else {
+					t.ReplacedBy = append(t.ReplacedBy, readCharData(decoder))
+				}
+			case el.Name.Local == "consider":
+				if res := getAttr(el, nsRDF, "resource"); res != "" {
+					t.Consider = append(t.Consider, oboIDFromURI(res))
+					decoder.Skip()
+				} else {
+					t.Consider = append(t.Consider, readCharData(decoder))
+				}
 			case el.Name.Local == "hasAlternativeId":
 				t.AltIDs = append(t.AltIDs, readCharData(decoder))
-			case el.Name.Local == "Definition" || el.Name.Local == "definition":
+			case el.Name.Local == "Definition" || el.Name.Local == "definition" || el.Name.Local == "IAO_0000115":
 				t.Definition = readCharData(decoder)
 			case el.Name.Local == "hasExactSynonym":
 				t.Synonyms = append(t.Synonyms, Synonym{
@@ -180,6 +374,10 @@ func parseOWLClass(decoder *xml.Decoder, se xml.StartElement, pool *internPool)
 					t.Subsets = append(t.Subsets, pool.get(oboIDFromURI(res)))
 				}
 				decoder.Skip()
+			case el.Name.Local == "hasOBONamespace":
+				t.Namespace = pool.get(readCharData(decoder))
+			case matchElement(el, nsOBOInOwl, "id"):
+				decoder.Skip() // repeats rdf:about
 			case el.Name.Local == "comment":
 				t.Comment = readCharData(decoder)
 			default:
@@ -187,10 +385,7 @@ func parseOWLClass(decoder *xml.Decoder, se xml.StartElement, pool *internPool)
 				name := el.Name.Local
 				val := readCharData(decoder)
 				if val != "" {
-					if t.Properties == nil {
-						t.Properties = make(map[string]string, 4)
-					}
-					t.Properties[name] = val
+					t.setProperty(name, val, getAttr(el, nsRDF, "datatype"))
 				}
 			}
 		case xml.EndElement:
@@ -200,20 +395,118 @@ func parseOWLClass(decoder *xml.Decoder, se xml.StartElement, pool *internPool)
 	}
 }
 
+// parseOWLSuperClass parses the anonymous superclass inside an
+// rdfs:subClassOf, up to its end. A restriction Relationship can hold comes
+// back as rel; any other expression, such as a union or a universal
+// restriction, as the NonELAxiom it states, so that it is reported rather
+// than lost.
+func parseOWLSuperClass(decoder *xml.Decoder, pool *internPool) (rel Relationship, ax *NonELAxiom) {
+	depth := 0
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return rel, ax
+		}
+		switch el := tok.(type) {
+		case xml.StartElement:
+			if depth > 0 || ax != nil || rel.Type != "" {
+				depth++
+				continue
+			}
+			switch {
+			case matchElement(el, nsOWL, "Restriction"):
+				r, universal := parseOWLRestriction(decoder, pool)
+				switch {
+				case universal:
+					ax = &NonELAxiom{Construct: NonELAllValuesFrom, Relationship: r.Type}
+					if r.TargetID != "" {
+						ax.Targets = []string{r.TargetID}
+					}
+				case r.Type != "" && (r.TargetID != "" || r.Self):
+					rel = r
+				default:
+					ax = &NonELAxiom{Construct: NonELRestriction, Relationship: r.Type}
+				}
+			case matchElement(el, nsOWL, "Class"):
+				ax = parseOWLAnonymousClass(decoder)
+			default:
+				ax = &NonELAxiom{Construct: NonELClass}
+				depth++
+			}
+		case xml.EndElement:
+			if depth == 0 {
+				return rel, ax
+			}
+			depth--
+		}
+	}
+}
+
+// parseOWLAnonymousClass parses an anonymous owl:Class used as a
+// superclass, after its start element and up to its end.
+func parseOWLAnonymousClass(decoder *xml.Decoder) *NonELAxiom {
+	ax := &NonELAxiom{Construct: NonELClass}
+	depth := 0
+	anonymous := false
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		switch el := tok.(type) {
+		case xml.StartElement:
+			depth++
+			switch depth {
+			case 1:
+				switch {
+				case matchElement(el, nsOWL, "unionOf"):
+					ax.Construct = NonELUnionOf
+				case matchElement(el, nsOWL, "complementOf"):
+					ax.Construct = NonELComplementOf
+					if res := getAttr(el, nsRDF, "resource"); res != "" {
+						ax.Targets = append(ax.Targets, oboIDFromURI(res))
+					}
+				}
+			case 2:
+				if about := getAttr(el, nsRDF, "about"); about != "" {
+					ax.Targets = append(ax.Targets, oboIDFromURI(about))
+				} else {
+					anonymous = true
+				}
+			}
+		case xml.EndElement:
+			if depth == 0 {
+				if anonymous || ax.Construct == NonELClass {
+					ax.Targets = nil
+				}
+				return ax
+			}
+			depth--
+		}
+	}
+	ax.Targets = nil
+	return ax
+}
+
 // parseOWLRestriction parses the content inside a rdfs:subClassOf that contains
-// an owl:Restriction with onProperty and someValuesFrom.
-func parseOWLRestriction(decoder *xml.Decoder, pool *internPool) Relationship {
-	var rel Relationship
+// an owl:Restriction with onProperty and someValuesFrom, hasValue or hasSelf,
+// or a qualified cardinality restriction (onClass plus a cardinality).
+// It also accepts being called just after the owl:Restriction start
+// element, and then returns at its end. universal reports an
+// owl:allValuesFrom restriction, whose named filler is rel.TargetID.
+func parseOWLRestriction(decoder *xml.Decoder, pool *internPool) (rel Relationship, universal bool) {
 	depth := 0
 	for {
 		tok, err := decoder.Token()
 		if err != nil {
-			return rel
+			return rel, universal
 		}
 		switch el := tok.(type) {
 		case xml.StartElement:
 			depth++
 			switch {
+			case matchElement(el, nsOWL, "Restriction"):
+				// Descend into the restriction body.
 			case matchElement(el, nsOWL, "onProperty"):
 				res := getAttr(el, nsRDF, "resource")
 				if res != "" {
@@ -228,6 +521,50 @@ func parseOWLRestriction(decoder *xml.Decoder, pool *internPool) Relationship {
 				}
 				decoder.Skip()
 				depth--
+			case matchElement(el, nsOWL, "hasValue"):
+				res := getAttr(el, nsRDF, "resource")
+				if res != "" {
+					rel.TargetID = oboIDFromURI(res)
+					rel.HasValue = true
+				}
+				decoder.Skip()
+				depth--
+			case matchElement(el, nsOWL, "allValuesFrom"):
+				universal = true
+				if res := getAttr(el, nsRDF, "resource"); res != "" {
+					rel.TargetID = oboIDFromURI(res)
+				}
+				decoder.Skip()
+				depth--
+			case matchElement(el, nsOWL, "hasSelf"):
+				rel.Self = strings.TrimSpace(readCharData(decoder)) == "true"
+				depth--
+			case matchElement(el, nsOWL, "onClass"):
+				res := getAttr(el, nsRDF, "resource")
+				if res != "" {
+					rel.TargetID = oboIDFromURI(res)
+				}
+				decoder.Skip()
+				depth--
+			case matchElement(el, nsOWL, "qualifiedCardinality"),
+				matchElement(el, nsOWL, "minQualifiedCardinality"),
+				matchElement(el, nsOWL, "maxQualifiedCardinality"):
+				n, err := strconv.Atoi(strings.TrimSpace(readCharData(decoder)))
+				depth--
+				if err != nil || n < 0 {
+					continue
+				}
+				if rel.Cardinality == nil {
+					rel.Cardinality = &Cardinality{Max: -1}
+				}
+				switch el.Name.Local {
+				case "qualifiedCardinality":
+					rel.Cardinality.Min, rel.Cardinality.Max = n, n
+				case "minQualifiedCardinality":
+					rel.Cardinality.Min = n
+				default:
+					rel.Cardinality.Max = n
+				}
 			default:
 				decoder.Skip()
 				depth--
@@ -235,7 +572,7 @@ func parseOWLRestriction(decoder *xml.Decoder, pool *internPool) Relationship {
 		case xml.EndElement:
 			depth--
 			if depth < 0 {
-				return rel
+				return rel, universal
 			}
 		}
 	}
@@ -243,7 +580,26 @@ func parseOWLRestriction(decoder *xml.Decoder, pool *internPool) Relationship {
 
 const nsOBOInOwl = "http://www.geneontology.org/formats/oboInOwl#"
 
-// parseOWLObjectProperty parses an owl:ObjectProperty element.
+// mergeOWLTypeDef adds what a repeated declaration of a property says to
+// the Typedef parsed first.
+func mergeOWLTypeDef(td, more *TypeDef) {
+	if td.Name == "" {
+		td.Name = more.Name
+	}
+	if td.InverseOf == "" {
+		td.InverseOf = more.InverseOf
+	}
+	td.IsTransitive = td.IsTransitive || more.IsTransitive
+	td.IsReflexive = td.IsReflexive || more.IsReflexive
+	for _, c := range more.HoldsOverChain {
+		if !slices.Contains(td.HoldsOverChain, c) {
+			td.HoldsOverChain = append(td.HoldsOverChain, c)
+		}
+	}
+}
+
+// parseOWLObjectProperty parses an owl:ObjectProperty element, or an
+// owl:TransitiveProperty or owl:ReflexiveProperty one.
 func parseOWLObjectProperty(decoder *xml.Decoder, se xml.StartElement, pool *internPool) TypeDef {
 	var td TypeDef
 	about := getAttr(se, nsRDF, "about")
@@ -267,8 +623,17 @@ func parseOWLObjectProperty(decoder *xml.Decoder, se xml.StartElement, pool *int
 					td.IsReflexive = true
 				}
 				decoder.Skip()
+			case matchElement(el, nsOWL, "inverseOf"):
+				if res := getAttr(el, nsRDF, "resource"); res != "" {
+					td.InverseOf = pool.get(oboIDFromURI(res))
+				}
+				decoder.Skip()
 			case matchElement(el, nsRDFS, "label"):
 				td.Name = readCharData(decoder)
+			case matchElement(el, nsOWL, "propertyChainAxiom"):
+				if chain := parseOWLPropertyChain(decoder); chain != "" {
+					td.HoldsOverChain = append(td.HoldsOverChain, chain)
+				}
 			default:
 				decoder.Skip()
 			}
@@ -278,8 +643,133 @@ func parseOWLObjectProperty(decoder *xml.Decoder, se xml.StartElement, pool *int
 	}
 }
 
+// parseOWLIndividual parses an owl:NamedIndividual element, keeping its
+// label and the named classes it is asserted to belong to.
+func parseOWLIndividual(decoder *xml.Decoder, se xml.StartElement, pool *internPool) Individual {
+	var ind Individual
+	if about := getAttr(se, nsRDF, "about"); about != "" {
+		ind.ID = oboIDFromURI(about)
+	}
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return ind
+		}
+		switch el := tok.(type) {
+		case xml.StartElement:
+			switch {
+			case matchElement(el, nsRDF, "type"):
+				res := getAttr(el, nsRDF, "resource")
+				if res != "" && res != nsOWL+"NamedIndividual" {
+					ind.Types = append(ind.Types, pool.get(oboIDFromURI(res)))
+				}
+				decoder.Skip()
+			case matchElement(el, nsRDFS, "label"):
+				ind.Name = readCharData(decoder)
+			default:
+				decoder.Skip()
+			}
+		case xml.EndElement:
+			return ind
+		}
+	}
+}
+
+// parseOWLEquivalentClass reads an owl:equivalentClass element and returns
+// the named members of an owl:unionOf, the individuals of an owl:oneOf, or
+// the parts of an intersection definition: an owl:intersectionOf of named
+// classes and simple restrictions, or a single restriction. An intersection
+// with any other member is dropped whole, since keeping the rest would make
+// the definition wrongly general. Other class expressions are skipped.
+func parseOWLEquivalentClass(decoder *xml.Decoder, pool *internPool) (union, oneOf []string, inter []IntersectionPart) {
+	var list *[]string // nil inside owl:intersectionOf
+	depth, listDepth := 0, -1
+	complete := true
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return union, oneOf, nil
+		}
+		switch el := tok.(type) {
+		case xml.StartElement:
+			depth++
+			inList := listDepth >= 0 && depth == listDepth+1
+			switch {
+			case matchElement(el, nsOWL, "Restriction") && (depth == 1 || inList && list == nil):
+				rel, universal := parseOWLRestriction(decoder, pool)
+				depth--
+				if universal || rel.Type == "" || (rel.TargetID == "" && !rel.Self) || rel.Cardinality != nil {
+					complete = false
+					continue
+				}
+				inter = append(inter, IntersectionPart{Relationship: rel.Type, TargetID: rel.TargetID, Self: rel.Self})
+			case inList:
+				about := getAttr(el, nsRDF, "about")
+				switch {
+				case about != "" && list != nil:
+					*list = append(*list, oboIDFromURI(about))
+				case about != "":
+					inter = append(inter, IntersectionPart{TargetID: oboIDFromURI(about)})
+				case list == nil:
+					complete = false
+				}
+			case listDepth < 0 && matchElement(el, nsOWL, "unionOf"):
+				list, listDepth = &union, depth
+			case listDepth < 0 && matchElement(el, nsOWL, "oneOf"):
+				list, listDepth = &oneOf, depth
+			case listDepth < 0 && matchElement(el, nsOWL, "intersectionOf"):
+				list, listDepth = nil, depth
+			}
+		case xml.EndElement:
+			if depth == 0 {
+				if !complete {
+					inter = nil
+				}
+				return union, oneOf, inter
+			}
+			if depth == listDepth {
+				listDepth = -1
+			}
+			depth--
+		}
+	}
+}
+
+// parseOWLPropertyChain reads an rdf:parseType="Collection" property chain
+// and returns its members as space-separated relation IDs.
+func parseOWLPropertyChain(decoder *xml.Decoder) string {
+	var ids []string
+	depth := 0
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		switch el := tok.(type) {
+		case xml.StartElement:
+			depth++
+			if about := getAttr(el, nsRDF, "about"); about != "" {
+				ids = append(ids, oboIDFromURI(about))
+			} else if res := getAttr(el, nsRDF, "resource"); res != "" {
+				ids = append(ids, oboIDFromURI(res))
+			}
+		case xml.EndElement:
+			if depth == 0 {
+				return strings.Join(ids, " ")
+			}
+			depth--
+		}
+	}
+	return strings.Join(ids, " ")
+}
+
+// readCharData collects the text content of the current element, including
+// text inside nested elements, and consumes its end tag. Nesting is tracked
+// with a counter rather than recursion so deeply nested input cannot
+// exhaust the stack.
 func readCharData(decoder *xml.Decoder) string {
 	var sb strings.Builder
+	depth := 0
 	for {
 		tok, err := decoder.Token()
 		if err != nil {
@@ -289,13 +779,12 @@ func readCharData(decoder *xml.Decoder) string {
 		case xml.CharData:
 			sb.Write(t)
 		case xml.StartElement:
-			// Nested element — recurse into it but still collect text
-			inner := readCharData(decoder)
-			if inner != "" {
-				sb.WriteString(inner)
-			}
+			depth++
 		case xml.EndElement:
-			return sb.String()
+			if depth == 0 {
+				return sb.String()
+			}
+			depth--
 		}
 	}
 }
@@ -0,0 +1,149 @@
+package ontology
+
+import "strings"
+
+// PathEdge is one step of a path, in path order. Inverse is set when the
+// ontology asserts To —Relation→ From and the path walks it backwards.
+type PathEdge struct {
+	From     string `json:"from"`
+	Relation string `json:"relation"`
+	To       string `json:"to"`
+	Inverse  bool   `json:"inverse,omitempty"`
+}
+
+// Path returns a shortest chain of relationships leading from a to b,
+// using only the given relation types (all types if relTypes is empty).
+// Paths that follow every relationship in its asserted direction are
+// preferred; only if none exists may edges be walked backwards, so two
+// siblings relate through their common parent. It returns nil if a and b
+// are not connected and an empty path if they are the same term. Alt IDs
+// are resolved.
+func (ix *Index) Path(a, b string, relTypes []string) []PathEdge {
+	from, to := ix.Primary(a), ix.Primary(b)
+	if from == "" || to == "" {
+		return nil
+	}
+	path, _ := FindPath(from, to, relTypes, func(id string, inverse bool) ([]PathEdge, error) {
+		var steps []PathEdge
+		if !inverse {
+			if t := ix.Term(id); t != nil {
+				for _, rel := range t.Relationships {
+					if rel.TargetID != "" {
+						steps = append(steps, PathEdge{From: id, Relation: rel.Type, To: rel.TargetID})
+					}
+				}
+			}
+			return steps, nil
+		}
+		ix.refs.once.Do(ix.buildRefs)
+		for _, r := range ix.refs.by[id] {
+			if r.Field == RefRelationship {
+				steps = append(steps, PathEdge{From: id, Relation: r.Relation, To: r.ID, Inverse: true})
+			}
+		}
+		return steps, nil
+	})
+	return path
+}
+
+// PathSteps lists the edges a path may take from id: with inverse
+// unset, the relationships id asserts; with it set, those other terms
+// assert to id, as Inverse edges from id.
+type PathSteps func(id string, inverse bool) ([]PathEdge, error)
+
+// FindPath is Path over a graph given by steps, for callers that don't
+// hold an Index; from and to must be primary IDs. Errors from steps end
+// the search.
+func FindPath(from, to string, relTypes []string, steps PathSteps) ([]PathEdge, error) {
+	if from == to {
+		return []PathEdge{}, nil
+	}
+	allowed := make(map[string]bool, len(relTypes))
+	for _, r := range relTypes {
+		allowed[r] = true
+	}
+	p, err := shortestPath(from, to, allowed, false, steps)
+	if p != nil || err != nil {
+		return p, err
+	}
+	return shortestPath(from, to, allowed, true, steps)
+}
+
+func shortestPath(from, to string, allowed map[string]bool, inverse bool, steps PathSteps) ([]PathEdge, error) {
+	prev := map[string]PathEdge{from: {}}
+	queue := []string{from}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		next, err := steps(cur, false)
+		if err != nil {
+			return nil, err
+		}
+		if inverse {
+			back, err := steps(cur, true)
+			if err != nil {
+				return nil, err
+			}
+			next = append(next, back...)
+		}
+		for _, e := range next {
+			if len(allowed) > 0 && !allowed[e.Relation] {
+				continue
+			}
+			if _, seen := prev[e.To]; seen {
+				continue
+			}
+			prev[e.To] = e
+			if e.To == to {
+				return unwindPath(prev, from, to), nil
+			}
+			queue = append(queue, e.To)
+		}
+	}
+	return nil, nil
+}
+
+func unwindPath(prev map[string]PathEdge, from, to string) []PathEdge {
+	var path []PathEdge
+	for cur := to; cur != from; {
+		e := prev[cur]
+		path = append(path, e)
+		cur = e.From
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// FormatPath renders a path with term labels (IDs where there is none) as
+// "caffeine —is_a→ trimethylxanthine —has_role→ stimulant"; inverse
+// edges are drawn as "←has_role—".
+func (ix *Index) FormatPath(path []PathEdge) string {
+	return FormatPathFunc(path, ix.Label)
+}
+
+// FormatPathFunc is FormatPath with labels from label.
+func FormatPathFunc(path []PathEdge, label func(id string) string) string {
+	if len(path) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(pathLabel(label, path[0].From))
+	for _, e := range path {
+		if e.Inverse {
+			b.WriteString(" ←" + e.Relation + "— ")
+		} else {
+			b.WriteString(" —" + e.Relation + "→ ")
+		}
+		b.WriteString(pathLabel(label, e.To))
+	}
+	return b.String()
+}
+
+func pathLabel(label func(string) string, id string) string {
+	if name := label(id); name != "" {
+		return name
+	}
+	return id
+}
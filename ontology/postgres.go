@@ -0,0 +1,191 @@
+package ontology
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PostgresTables lists the tables produced by WritePostgresTable, in load
+// order. Each table is written as a PostgreSQL COPY text-format file.
+var PostgresTables = []string{"term", "synonym", "xref", "alt_id", "relationship", "property", "term_closure"}
+
+// PostgresDDL returns CREATE TABLE statements for the COPY files, followed
+// by \copy commands that load them from the current directory (psql -f).
+func PostgresDDL(schema string) string {
+	var sb strings.Builder
+	q := func(table string) string {
+		if schema == "" {
+			return table
+		}
+		return schema + "." + table
+	}
+	if schema != "" {
+		fmt.Fprintf(&sb, "CREATE SCHEMA IF NOT EXISTS %s;\n\n", schema)
+	}
+	fmt.Fprintf(&sb, `CREATE TABLE %s (
+    id          text PRIMARY KEY,
+    name        text,
+    namespace   text,
+    definition  text,
+    comment     text,
+    is_obsolete boolean NOT NULL DEFAULT false
+);
+
+CREATE TABLE %s (
+    term_id text NOT NULL,
+    text    text,
+    scope   text,
+    type    text
+);
+
+CREATE TABLE %s (
+    term_id text NOT NULL,
+    xref    text NOT NULL
+);
+
+CREATE TABLE %s (
+    term_id text NOT NULL,
+    alt_id  text NOT NULL
+);
+
+CREATE TABLE %s (
+    term_id   text NOT NULL,
+    type      text NOT NULL,
+    target_id text NOT NULL
+);
+
+CREATE TABLE %s (
+    term_id text NOT NULL,
+    key      text NOT NULL,
+    value    text,
+    datatype text NOT NULL -- xsd:string, xsd:decimal, ...
+);
+
+-- Transitive is_a closure: one row per (term, ancestor) pair with the
+-- shortest path length.
+CREATE TABLE %s (
+    term_id     text NOT NULL,
+    ancestor_id text NOT NULL,
+    distance    integer NOT NULL,
+    relation    text NOT NULL
+);
+
+`, q("term"), q("synonym"), q("xref"), q("alt_id"), q("relationship"), q("property"), q("term_closure"))
+
+	for _, table := range PostgresTables {
+		fmt.Fprintf(&sb, "\\copy %s FROM '%s.tsv'\n", q(table), table)
+	}
+
+	fmt.Fprintf(&sb, `
+CREATE INDEX ON %s (term_id);
+CREATE INDEX ON %s (lower(text));
+CREATE INDEX ON %s (term_id);
+CREATE INDEX ON %s (alt_id);
+CREATE INDEX ON %s (term_id);
+CREATE INDEX ON %s (target_id);
+CREATE INDEX ON %s (term_id);
+CREATE INDEX ON %s (term_id);
+CREATE INDEX ON %s (ancestor_id);
+`, q("synonym"), q("synonym"), q("xref"), q("alt_id"), q("relationship"), q("relationship"), q("property"),
+		q("term_closure"), q("term_closure"))
+	return sb.String()
+}
+
+// WritePostgresTable writes the rows of one table from PostgresTables in
+// COPY text format.
+func WritePostgresTable(ont *Ontology, table string, w io.Writer) error {
+	bw := bufio.NewWriterSize(w, writerBufferSize)
+	row := func(fields ...string) {
+		for i, f := range fields {
+			if i > 0 {
+				bw.WriteByte('\t')
+			}
+			writeCopyField(bw, f)
+		}
+		bw.WriteByte('\n')
+	}
+
+	switch table {
+	case "term":
+		for i := range ont.Terms {
+			t := &ont.Terms[i]
+			obsolete := "f"
+			if t.IsObsolete {
+				obsolete = "t"
+			}
+			row(t.ID, t.Name, t.Namespace, t.Definition, t.Comment, obsolete)
+		}
+	case "synonym":
+		for i := range ont.Terms {
+			t := &ont.Terms[i]
+			for _, syn := range t.Synonyms {
+				row(t.ID, syn.Text, syn.Scope, syn.Type)
+			}
+		}
+	case "xref":
+		for i := range ont.Terms {
+			for _, x := range ont.Terms[i].Xrefs {
+				row(ont.Terms[i].ID, x)
+			}
+		}
+	case "alt_id":
+		for i := range ont.Terms {
+			for _, alt := range ont.Terms[i].AltIDs {
+				row(ont.Terms[i].ID, alt)
+			}
+		}
+	case "relationship":
+		for i := range ont.Terms {
+			for _, rel := range ont.Terms[i].Relationships {
+				row(ont.Terms[i].ID, rel.Type, rel.TargetID)
+			}
+		}
+	case "property":
+		for i := range ont.Terms {
+			t := &ont.Terms[i]
+			keys := make([]string, 0, len(t.Properties))
+			for k := range t.Properties {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				v, _ := t.Property(k)
+				row(t.ID, k, v.Value, v.Datatype)
+			}
+		}
+	case "term_closure":
+		NewIndex(ont).Closure([]string{"is_a"}, func(r ClosureRow) {
+			row(r.TermID, r.AncestorID, strconv.Itoa(r.Distance), r.Relation)
+		})
+	default:
+		return fmt.Errorf("unknown postgres table %q", table)
+	}
+	return bw.Flush()
+}
+
+// writeCopyField writes a value escaped for COPY text format. Empty strings
+// are written as \N (NULL).
+func writeCopyField(bw *bufio.Writer, s string) {
+	if s == "" {
+		bw.WriteString(`\N`)
+		return
+	}
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\':
+			bw.WriteString(`\\`)
+		case '\t':
+			bw.WriteString(`\t`)
+		case '\n':
+			bw.WriteString(`\n`)
+		case '\r':
+			bw.WriteString(`\r`)
+		default:
+			bw.WriteByte(c)
+		}
+	}
+}
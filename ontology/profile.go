@@ -0,0 +1,293 @@
+package ontology
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Profile finding categories.
+const (
+	ProfileNonEL       = "non-EL"      // outside OWL 2 EL
+	ProfileUnsupported = "unsupported" // within EL but dropped by the parsers or reasoner
+)
+
+// ProfileFinding counts one kind of construct the reasoner will not see.
+type ProfileFinding struct {
+	Category  string   `json:"category"`
+	Construct string   `json:"construct"`
+	Count     int      `json:"count"`
+	Examples  []string `json:"examples,omitempty"` // IDs of the entities using it
+}
+
+// ProfileReport is the result of CheckProfile, sorted with non-EL findings
+// first and then by descending count.
+type ProfileReport struct {
+	Findings []ProfileFinding `json:"findings"`
+}
+
+// NonEL reports whether any finding falls outside OWL 2 EL.
+func (r *ProfileReport) NonEL() bool {
+	for _, f := range r.Findings {
+		if f.Category == ProfileNonEL {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckProfile scans a raw OBO or OWL/RDF-XML document for axioms outside
+// OWL 2 EL and for EL axioms the parsers do not carry into the model. It
+// reads the source directly because the parsed Ontology no longer holds
+// what was dropped. At most maxExamples entity IDs are kept per construct.
+func CheckProfile(r io.Reader, format string, maxExamples int) (*ProfileReport, error) {
+	pc := &profileCounter{max: maxExamples, byKey: make(map[string]*ProfileFinding)}
+	var err error
+	switch format {
+	case "obo":
+		err = pc.scanOBO(r)
+	case "owl":
+		err = pc.scanOWL(r)
+	default:
+		return nil, fmt.Errorf("profile check supports obo and owl, not %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rep := &ProfileReport{Findings: make([]ProfileFinding, 0, len(pc.byKey))}
+	for _, f := range pc.byKey {
+		rep.Findings = append(rep.Findings, *f)
+	}
+	sort.Slice(rep.Findings, func(i, j int) bool {
+		a, b := rep.Findings[i], rep.Findings[j]
+		if a.Category != b.Category {
+			return a.Category == ProfileNonEL
+		}
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Construct < b.Construct
+	})
+	return rep, nil
+}
+
+type profileCounter struct {
+	max   int
+	byKey map[string]*ProfileFinding
+}
+
+func (pc *profileCounter) add(category, construct, subject string) {
+	f := pc.byKey[construct]
+	if f == nil {
+		f = &ProfileFinding{Category: category, Construct: construct}
+		pc.byKey[construct] = f
+	}
+	f.Count++
+	if subject != "" && len(f.Examples) < pc.max {
+		for _, ex := range f.Examples {
+			if ex == subject {
+				return
+			}
+		}
+		f.Examples = append(f.Examples, subject)
+	}
+}
+
+// oboTermTags and oboTypedefTags map OBO tags to the construct they express.
+var oboTermTags = map[string][2]string{
+	"union_of":      {ProfileNonEL, "union_of (ObjectUnionOf)"},
+	"disjoint_from": {ProfileUnsupported, "disjoint_from (DisjointClasses)"},
+	"equivalent_to": {ProfileUnsupported, "equivalent_to (EquivalentClasses)"},
+	"complement_of": {ProfileNonEL, "complement_of (ObjectComplementOf)"},
+}
+
+var oboTypedefTags = map[string][2]string{
+	"inverse_of":            {ProfileNonEL, "inverse_of (InverseObjectProperties)"},
+	"is_symmetric":          {ProfileNonEL, "is_symmetric (SymmetricObjectProperty)"},
+	"is_asymmetric":         {ProfileNonEL, "is_asymmetric (AsymmetricObjectProperty)"},
+	"is_functional":         {ProfileNonEL, "is_functional (FunctionalObjectProperty)"},
+	"is_inverse_functional": {ProfileNonEL, "is_inverse_functional (InverseFunctionalObjectProperty)"},
+	"is_a":                  {ProfileUnsupported, "is_a on Typedef (SubObjectPropertyOf)"},
+	"transitive_over":       {ProfileUnsupported, "transitive_over (property chain)"},
+	"domain":                {ProfileUnsupported, "domain (ObjectPropertyDomain)"},
+	"range":                 {ProfileUnsupported, "range (ObjectPropertyRange)"},
+	"disjoint_from":         {ProfileUnsupported, "disjoint_from on Typedef (DisjointObjectProperties)"},
+}
+
+// Boolean OBO tags count only when set to true.
+var oboBoolTags = map[string]bool{
+	"is_symmetric": true, "is_asymmetric": true, "is_functional": true,
+	"is_inverse_functional": true,
+}
+
+func (pc *profileCounter) scanOBO(r io.Reader) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), scannerBufferSize)
+	stanza, id := "", ""
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.HasPrefix(line, "[") {
+			stanza, id = line, ""
+			continue
+		}
+		key, val, ok := strings.Cut(line, ": ")
+		if !ok {
+			continue
+		}
+		if key == "id" {
+			id = val
+			continue
+		}
+		if oboBoolTags[key] && val != "true" {
+			continue
+		}
+
+		var tags map[string][2]string
+		switch stanza {
+		case "[Term]":
+			tags = oboTermTags
+		case "[Typedef]":
+			tags = oboTypedefTags
+		default:
+			continue
+		}
+		if c, ok := tags[key]; ok {
+			pc.add(c[0], c[1], id)
+			continue
+		}
+
+		// Relationship qualifiers: relationship: R X {cardinality="1"}
+		if stanza == "[Term]" && (key == "relationship" || key == "intersection_of") {
+			_, q, ok := strings.Cut(val, "{")
+			if !ok {
+				continue
+			}
+			switch {
+			case strings.Contains(q, "all_only=\"true\"") || strings.Contains(q, "all_only=true"):
+				pc.add(ProfileNonEL, "all_only qualifier (ObjectAllValuesFrom)", id)
+			case strings.Contains(strings.ToLower(q), "cardinality="):
+				pc.add(ProfileNonEL, "cardinality qualifier (Object*Cardinality)", id)
+			}
+		}
+	}
+	return sc.Err()
+}
+
+// owlElements maps OWL/RDF element names to the construct they express.
+var owlElements = map[string][2]string{
+	"unionOf":                 {ProfileNonEL, "owl:unionOf (ObjectUnionOf)"},
+	"allValuesFrom":           {ProfileNonEL, "owl:allValuesFrom (ObjectAllValuesFrom)"},
+	"complementOf":            {ProfileNonEL, "owl:complementOf (ObjectComplementOf)"},
+	"cardinality":             {ProfileNonEL, "owl:cardinality (ObjectExactCardinality)"},
+	"minCardinality":          {ProfileNonEL, "owl:minCardinality (ObjectMinCardinality)"},
+	"maxCardinality":          {ProfileNonEL, "owl:maxCardinality (ObjectMaxCardinality)"},
+	"qualifiedCardinality":    {ProfileNonEL, "owl:qualifiedCardinality (ObjectExactCardinality)"},
+	"minQualifiedCardinality": {ProfileNonEL, "owl:minQualifiedCardinality (ObjectMinCardinality)"},
+	"maxQualifiedCardinality": {ProfileNonEL, "owl:maxQualifiedCardinality (ObjectMaxCardinality)"},
+	"inverseOf":               {ProfileNonEL, "owl:inverseOf (inverse properties)"},
+	"disjointUnionOf":         {ProfileNonEL, "owl:disjointUnionOf (DisjointUnion)"},
+	"disjointWith":            {ProfileUnsupported, "owl:disjointWith (DisjointClasses)"},
+	"AllDisjointClasses":      {ProfileUnsupported, "owl:AllDisjointClasses (DisjointClasses)"},
+	"subPropertyOf":           {ProfileUnsupported, "rdfs:subPropertyOf (SubObjectPropertyOf)"},
+	"domain":                  {ProfileUnsupported, "rdfs:domain (ObjectPropertyDomain)"},
+	"range":                   {ProfileUnsupported, "rdfs:range (ObjectPropertyRange)"},
+	"equivalentProperty":      {ProfileUnsupported, "owl:equivalentProperty (EquivalentObjectProperties)"},
+	"propertyDisjointWith":    {ProfileUnsupported, "owl:propertyDisjointWith (DisjointObjectProperties)"},
+	"hasKey":                  {ProfileUnsupported, "owl:hasKey (HasKey)"},
+	"sourceIndividual":        {ProfileNonEL, "owl:NegativePropertyAssertion"},
+	"DataRange":               {ProfileNonEL, "owl:DataRange"},
+	"datatypeComplementOf":    {ProfileNonEL, "owl:datatypeComplementOf"},
+	"onDatatype":              {ProfileUnsupported, "owl:onDatatype (datatype restriction)"},
+	"withRestrictions":        {ProfileUnsupported, "owl:withRestrictions (datatype restriction)"},
+	"onDataRange":             {ProfileNonEL, "owl:onDataRange (qualified data cardinality)"},
+}
+
+// owlPropertyTypes are rdf:type values on properties outside OWL 2 EL.
+var owlPropertyTypes = map[string]string{
+	"SymmetricProperty":         "owl:SymmetricProperty",
+	"AsymmetricProperty":        "owl:AsymmetricProperty",
+	"FunctionalProperty":        "owl:FunctionalProperty",
+	"InverseFunctionalProperty": "owl:InverseFunctionalProperty",
+	"IrreflexiveProperty":       "owl:IrreflexiveProperty",
+}
+
+// owlFrame is one open element during the OWL scan.
+type owlFrame struct {
+	subject string // entity being described, inherited by nested elements
+	oneOf   bool   // element is owl:oneOf
+	members int    // direct children of an owl:oneOf
+}
+
+func (pc *profileCounter) scanOWL(r io.Reader) error {
+	dec := xml.NewDecoder(r)
+	var stack []owlFrame
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch el := tok.(type) {
+		case xml.StartElement:
+			subject := ""
+			if n := len(stack); n > 0 {
+				subject = stack[n-1].subject
+				if stack[n-1].oneOf {
+					stack[n-1].members++
+				}
+			}
+			// Top-level entities sit directly under rdf:RDF.
+			if len(stack) <= 1 {
+				if about := getAttr(el, nsRDF, "about"); about != "" {
+					subject = oboIDFromURI(about)
+				}
+			}
+			frame := owlFrame{subject: subject}
+
+			if el.Name.Space == nsOWL || el.Name.Space == nsRDFS || el.Name.Space == nsRDF {
+				local := el.Name.Local
+				if c, ok := owlElements[local]; ok {
+					pc.add(c[0], c[1], subject)
+				}
+				switch local {
+				case "type":
+					res := getAttr(el, nsRDF, "resource")
+					if name, ok := owlPropertyTypes[strings.TrimPrefix(res, nsOWL)]; ok && strings.HasPrefix(res, nsOWL) {
+						pc.add(ProfileNonEL, name, subject)
+					}
+				case "oneOf":
+					frame.oneOf = true
+				case "someValuesFrom":
+					if getAttr(el, nsRDF, "resource") == "" {
+						pc.add(ProfileUnsupported, "owl:someValuesFrom with a complex filler", subject)
+					}
+				case "hasValue":
+					if getAttr(el, nsRDF, "resource") == "" {
+						pc.add(ProfileUnsupported, "owl:hasValue with a literal (DataHasValue)", subject)
+					}
+				}
+			}
+			stack = append(stack, frame)
+		case xml.EndElement:
+			n := len(stack)
+			if n == 0 {
+				continue
+			}
+			if f := stack[n-1]; f.oneOf {
+				// EL allows ObjectOneOf with a single individual only.
+				if f.members > 1 {
+					pc.add(ProfileNonEL, "owl:oneOf with several individuals", f.subject)
+				} else {
+					pc.add(ProfileUnsupported, "owl:oneOf (ObjectOneOf)", f.subject)
+				}
+			}
+			stack = stack[:n-1]
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package ontology
+
+import (
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Annotation is one row of an annotation table: a subject (a sample, a
+// gene, a dataset) annotated with a term. Kind is an optional class of
+// annotation, such as an evidence code, that selects the propagation rule.
+type Annotation struct {
+	Subject string `json:"subject"`
+	Term    string `json:"term"`
+	Kind    string `json:"kind,omitempty"`
+}
+
+// PropagationRule names the relations annotations of one kind propagate
+// along. A relation is followed in its asserted direction, from the
+// annotated term to the target, so "has_part" carries an annotation of a
+// whole to its parts; "^has_part" follows it backwards, from a part to the
+// wholes containing it. Relations mix freely in a chain, as GO's is_a and
+// part_of do.
+type PropagationRule struct {
+	Kind      string   `json:"kind,omitempty"` // "" for kinds without a rule of their own
+	Relations []string `json:"relations"`
+}
+
+// DefaultPropagationRules propagates every annotation to the term's is_a
+// ancestors.
+var DefaultPropagationRules = []PropagationRule{{Relations: []string{"is_a"}}}
+
+// PropagatedAnnotation is one row of the expanded table. Direct is set
+// when the input annotated the term itself; From lists the directly
+// annotated terms the row was propagated from.
+type PropagatedAnnotation struct {
+	Subject string   `json:"subject"`
+	Term    string   `json:"term"`
+	Name    string   `json:"name,omitempty"`
+	Kind    string   `json:"kind,omitempty"`
+	Direct  bool     `json:"direct"`
+	From    []string `json:"from,omitempty"`
+}
+
+// PropagationResult is the outcome of Index.Propagate. Rows are grouped by
+// subject in input order, then by kind and term ID. Unknown holds input
+// terms that resolve to no term or, for names, to several.
+type PropagationResult struct {
+	Rows    []PropagatedAnnotation `json:"rows"`
+	Unknown []string               `json:"unknown,omitempty"`
+}
+
+// PropagationCount is the number of distinct subjects annotated with a
+// term after propagation, and how many of them were annotated directly.
+type PropagationCount struct {
+	Term     string `json:"term"`
+	Name     string `json:"name,omitempty"`
+	Kind     string `json:"kind,omitempty"`
+	Subjects int    `json:"subjects"`
+	Direct   int    `json:"direct"`
+}
+
+// Propagate expands annotations upward according to rules: each annotated
+// term also annotates every term reachable from it along the relations of
+// the rule for the annotation's kind. Annotation terms are resolved with
+// Lookup, so alt IDs, labels and synonyms are accepted, and duplicates
+// collapse into one row.
+func (ix *Index) Propagate(anns []Annotation, rules []PropagationRule) *PropagationResult {
+	forward := make(map[string]map[string][]string)
+	for _, rule := range rules {
+		for _, rel := range rule.Relations {
+			forward[strings.TrimPrefix(rel, "^")] = nil
+		}
+	}
+	inverse := make(map[string]map[string][]string, len(forward))
+	for rel := range forward {
+		forward[rel] = make(map[string][]string)
+		inverse[rel] = make(map[string][]string)
+	}
+	for i := range ix.ont.Terms {
+		t := &ix.ont.Terms[i]
+		for _, rel := range t.Relationships {
+			if m, ok := forward[rel.Type]; ok && rel.TargetID != "" {
+				m[t.ID] = append(m[t.ID], rel.TargetID)
+				inverse[rel.Type][rel.TargetID] = append(inverse[rel.Type][rel.TargetID], t.ID)
+			}
+		}
+	}
+
+	ruleFor := make(map[string]int, len(rules))
+	for i, rule := range rules {
+		if _, ok := ruleFor[rule.Kind]; !ok {
+			ruleFor[rule.Kind] = i
+		}
+	}
+	type reachKey struct {
+		rule int
+		id   string
+	}
+	reachable := make(map[reachKey][]string)
+	reach := func(rule int, id string) []string {
+		k := reachKey{rule, id}
+		if ids, ok := reachable[k]; ok {
+			return ids
+		}
+		var out []string
+		seen := map[string]bool{id: true}
+		queue := []string{id}
+		for len(queue) > 0 {
+			cur := queue[0]
+			queue = queue[1:]
+			for _, rel := range rules[rule].Relations {
+				edges := forward[rel]
+				if name, ok := strings.CutPrefix(rel, "^"); ok {
+					edges = inverse[name]
+				}
+				for _, next := range edges[cur] {
+					if !seen[next] {
+						seen[next] = true
+						out = append(out, next)
+						queue = append(queue, next)
+					}
+				}
+			}
+		}
+		reachable[k] = out
+		return out
+	}
+
+	res := &PropagationResult{}
+	type rowKey struct{ subject, kind, term string }
+	rows := make(map[rowKey]int)
+	subjectOrder := make(map[string]int)
+	add := func(subject, kind, term, from string) {
+		k := rowKey{subject, kind, term}
+		i, ok := rows[k]
+		if !ok {
+			i = len(res.Rows)
+			rows[k] = i
+			row := PropagatedAnnotation{Subject: subject, Term: term, Kind: kind}
+			if t := ix.Term(term); t != nil {
+				row.Name = t.Name
+			}
+			res.Rows = append(res.Rows, row)
+		}
+		row := &res.Rows[i]
+		if from == "" {
+			row.Direct = true
+		} else if !slices.Contains(row.From, from) {
+			row.From = append(row.From, from)
+		}
+	}
+	unknown := make(map[string]bool)
+	for _, a := range anns {
+		id, err := ix.Lookup(a.Term)
+		if err != nil {
+			if !unknown[a.Term] {
+				unknown[a.Term] = true
+				res.Unknown = append(res.Unknown, a.Term)
+			}
+			continue
+		}
+		if _, ok := subjectOrder[a.Subject]; !ok {
+			subjectOrder[a.Subject] = len(subjectOrder)
+		}
+		add(a.Subject, a.Kind, id, "")
+		rule, ok := ruleFor[a.Kind]
+		if !ok {
+			if rule, ok = ruleFor[""]; !ok {
+				continue
+			}
+		}
+		for _, anc := range reach(rule, id) {
+			add(a.Subject, a.Kind, anc, id)
+		}
+	}
+
+	sort.SliceStable(res.Rows, func(i, j int) bool {
+		a, b := &res.Rows[i], &res.Rows[j]
+		if a.Subject != b.Subject {
+			return subjectOrder[a.Subject] < subjectOrder[b.Subject]
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Term < b.Term
+	})
+	for i := range res.Rows {
+		sort.Strings(res.Rows[i].From)
+	}
+	return res
+}
+
+// Counts tallies the expanded table per term and kind, most annotated
+// terms first and then by term ID.
+func (r *PropagationResult) Counts() []PropagationCount {
+	type key struct{ term, kind string }
+	index := make(map[key]int)
+	var counts []PropagationCount
+	for _, row := range r.Rows {
+		k := key{row.Term, row.Kind}
+		i, ok := index[k]
+		if !ok {
+			i = len(counts)
+			index[k] = i
+			counts = append(counts, PropagationCount{Term: row.Term, Name: row.Name, Kind: row.Kind})
+		}
+		counts[i].Subjects++
+		if row.Direct {
+			counts[i].Direct++
+		}
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		a, b := &counts[i], &counts[j]
+		if a.Subjects != b.Subjects {
+			return a.Subjects > b.Subjects
+		}
+		if a.Term != b.Term {
+			return a.Term < b.Term
+		}
+		return a.Kind < b.Kind
+	})
+	return counts
+}
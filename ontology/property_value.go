@@ -0,0 +1,148 @@
+package ontology
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// xsdString is the datatype of a property value that names none.
+const xsdString = "xsd:string"
+
+// PropertyValue is a term property value and its XSD datatype, as a
+// compact name such as xsd:decimal, so "249.05" can be told apart from a
+// string that happens to look like a number.
+type PropertyValue struct {
+	Value    string `json:"value"`
+	Datatype string `json:"datatype"`
+}
+
+// Property returns t's value for the property key with its datatype,
+// xsd:string if the source gave none.
+func (t *Term) Property(key string) (PropertyValue, bool) {
+	v, ok := t.Properties[key]
+	if !ok {
+		return PropertyValue{}, false
+	}
+	dt := t.PropertyTypes[key]
+	if dt == "" {
+		dt = xsdString
+	}
+	return PropertyValue{Value: v, Datatype: dt}, true
+}
+
+// setProperty sets a property value and its datatype, given compact or as
+// an IRI. xsd:string, the default, is not recorded in PropertyTypes.
+func (t *Term) setProperty(key, val, datatype string) {
+	if t.Properties == nil {
+		t.Properties = make(map[string]string, 4)
+	}
+	t.Properties[key] = val
+	dt := compactDatatype(datatype)
+	if dt == "" || dt == xsdString {
+		delete(t.PropertyTypes, key)
+		return
+	}
+	if t.PropertyTypes == nil {
+		t.PropertyTypes = make(map[string]string, 2)
+	}
+	t.PropertyTypes[key] = dt
+}
+
+// compactDatatype writes an XSD datatype IRI as xsd:name; anything else is
+// returned as is.
+func compactDatatype(dt string) string {
+	if name, ok := strings.CutPrefix(dt, nsXSD); ok {
+		return "xsd:" + name
+	}
+	return dt
+}
+
+// datatypeIRI is the inverse of compactDatatype.
+func datatypeIRI(dt string) string {
+	if name, ok := strings.CutPrefix(dt, "xsd:"); ok {
+		return nsXSD + name
+	}
+	return dt
+}
+
+// String formats v as an RDF literal would read, "value"^^xsd:type, or
+// just the value for a string.
+func (v PropertyValue) String() string {
+	if v.Datatype == "" || v.Datatype == xsdString {
+		return v.Value
+	}
+	return strconv.Quote(v.Value) + "^^" + v.Datatype
+}
+
+// xsdIntegers are the XSD integer types, all derived from xsd:decimal.
+var xsdIntegers = map[string]bool{
+	"xsd:integer": true, "xsd:long": true, "xsd:int": true, "xsd:short": true, "xsd:byte": true,
+	"xsd:nonNegativeInteger": true, "xsd:positiveInteger": true,
+	"xsd:nonPositiveInteger": true, "xsd:negativeInteger": true,
+	"xsd:unsignedLong": true, "xsd:unsignedInt": true, "xsd:unsignedShort": true, "xsd:unsignedByte": true,
+}
+
+// Numeric reports whether v has an XSD numeric datatype.
+func (v PropertyValue) Numeric() bool {
+	switch v.Datatype {
+	case "xsd:decimal", "xsd:float", "xsd:double":
+		return true
+	}
+	return xsdIntegers[v.Datatype]
+}
+
+// Native converts v to the Go value of its datatype: int64 for the XSD
+// integer types, float64 for xsd:decimal, xsd:float and xsd:double, bool for
+// xsd:boolean, and the value as a string for any other datatype. It fails
+// if the value is not valid for its numeric or boolean datatype.
+func (v PropertyValue) Native() (any, error) {
+	s := strings.TrimSpace(v.Value)
+	switch {
+	case xsdIntegers[v.Datatype]:
+		n, err := strconv.ParseInt(strings.TrimPrefix(s, "+"), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s value %q is not an integer", v.Datatype, v.Value)
+		}
+		return n, nil
+	case v.Numeric():
+		// XSD spells infinity INF; strconv wants Inf.
+		f, err := strconv.ParseFloat(strings.Replace(s, "INF", "Inf", 1), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s value %q is not a number", v.Datatype, v.Value)
+		}
+		return f, nil
+	case v.Datatype == "xsd:boolean":
+		switch s {
+		case "true", "1":
+			return true, nil
+		case "false", "0":
+			return false, nil
+		}
+		return nil, fmt.Errorf("xsd:boolean value %q is not true or false", v.Value)
+	}
+	return v.Value, nil
+}
+
+// NativeProperties returns t's property values converted by Native, for
+// consumers that want numbers as numbers. A value that does not convert
+// is kept as its string, and the first such failure is returned.
+func (t *Term) NativeProperties() (map[string]any, error) {
+	if len(t.Properties) == 0 {
+		return nil, nil
+	}
+	out := make(map[string]any, len(t.Properties))
+	var first error
+	for _, k := range sortedKeys(t.Properties) {
+		v, _ := t.Property(k)
+		n, err := v.Native()
+		if err != nil {
+			if first == nil {
+				first = fmt.Errorf("%s %s: %w", t.ID, k, err)
+			}
+			n = v.Value
+		}
+		out[k] = n
+	}
+	return out, first
+}
@@ -0,0 +1,143 @@
+package ontology
+
+import (
+	"bufio"
+	"io"
+
+	"github.com/nodeadmin/chebi-parser/internal/protowire"
+)
+
+// WriteProtoStream writes the ontology as a length-delimited protobuf
+// stream: one OntologyHeader message followed by one Term message per term.
+// See proto/chebi.proto for the schema.
+func WriteProtoStream(ont *Ontology, w io.Writer) error {
+	bw := bufio.NewWriterSize(w, writerBufferSize)
+
+	var buf, sub, sub2 []byte
+
+	buf = protowire.AppendString(buf[:0], 1, ont.FormatVersion)
+	buf = protowire.AppendString(buf, 2, ont.DataVersion)
+	buf = protowire.AppendString(buf, 3, ont.Ontology)
+	for i := range ont.TypeDefs {
+		td := &ont.TypeDefs[i]
+		sub = protowire.AppendString(sub[:0], 1, td.ID)
+		sub = protowire.AppendString(sub, 2, td.Name)
+		sub = protowire.AppendBool(sub, 3, td.IsTransitive)
+		sub = protowire.AppendBool(sub, 4, td.IsReflexive)
+		sub = protowire.AppendStrings(sub, 5, td.HoldsOverChain)
+		sub = protowire.AppendString(sub, 6, td.InverseOf)
+		buf = protowire.AppendMessage(buf, 4, sub)
+	}
+	for i := range ont.Individuals {
+		ind := &ont.Individuals[i]
+		sub = protowire.AppendString(sub[:0], 1, ind.ID)
+		sub = protowire.AppendString(sub, 2, ind.Name)
+		sub = protowire.AppendStrings(sub, 3, ind.Types)
+		buf = protowire.AppendMessage(buf, 5, sub)
+	}
+	if m := ont.Metadata; m != nil {
+		sub = protowire.AppendString(sub[:0], 1, m.Title)
+		sub = protowire.AppendString(sub, 2, m.Description)
+		sub = protowire.AppendStrings(sub, 3, m.Licenses)
+		sub = protowire.AppendStrings(sub, 4, m.Contributors)
+		buf = protowire.AppendMessage(buf, 6, sub)
+	}
+	if err := protowire.WriteDelimited(bw, buf); err != nil {
+		return err
+	}
+
+	for i := range ont.Terms {
+		buf, sub, sub2 = appendTermProto(buf[:0], sub, sub2, &ont.Terms[i])
+		if err := protowire.WriteDelimited(bw, buf); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// appendTermProto encodes a Term message. sub and sub2 are scratch buffers
+// for nested messages; they are returned so callers can reuse them.
+func appendTermProto(b, sub, sub2 []byte, t *Term) ([]byte, []byte, []byte) {
+	b = protowire.AppendString(b, 1, t.ID)
+	b = protowire.AppendString(b, 2, t.Name)
+	b = protowire.AppendString(b, 3, t.Namespace)
+	b = protowire.AppendString(b, 4, t.Definition)
+	b = protowire.AppendBool(b, 5, t.IsObsolete)
+	b = protowire.AppendString(b, 6, t.Comment)
+	b = protowire.AppendStrings(b, 15, t.ReplacedBy)
+	b = protowire.AppendStrings(b, 16, t.Consider)
+	b = protowire.AppendStrings(b, 7, t.Subsets)
+	for i := range t.Synonyms {
+		syn := &t.Synonyms[i]
+		sub = protowire.AppendString(sub[:0], 1, syn.Text)
+		sub = protowire.AppendString(sub, 2, syn.Scope)
+		sub = protowire.AppendString(sub, 3, syn.Type)
+		sub = protowire.AppendStrings(sub, 4, syn.Xrefs)
+		b = protowire.AppendMessage(b, 8, sub)
+	}
+	b = protowire.AppendStrings(b, 9, t.Xrefs)
+	b = protowire.AppendStrings(b, 10, t.AltIDs)
+	for i := range t.Relationships {
+		rel := &t.Relationships[i]
+		sub = protowire.AppendString(sub[:0], 1, rel.Type)
+		sub = protowire.AppendString(sub, 2, rel.TargetID)
+		sub = protowire.AppendString(sub, 3, rel.Name)
+		sub = protowire.AppendBool(sub, 4, rel.HasValue)
+		sub = protowire.AppendBool(sub, 5, rel.Self)
+		sub = appendProtoStringMap(sub, sub2, 6, rel.Qualifiers)
+		if c := rel.Cardinality; c != nil {
+			// Max is always written so that an exact 0 reads back as such.
+			sub2 = protowire.AppendInt64(sub2[:0], 1, int64(c.Min))
+			sub2 = protowire.AppendTag(sub2, 2, protowire.TypeVarint)
+			sub2 = protowire.AppendVarint(sub2, uint64(int64(c.Max)))
+			sub = protowire.AppendMessage(sub, 7, sub2)
+		}
+		b = protowire.AppendMessage(b, 11, sub)
+	}
+	for i := range t.IntersectionOf {
+		part := &t.IntersectionOf[i]
+		sub = protowire.AppendString(sub[:0], 1, part.Relationship)
+		sub = protowire.AppendString(sub, 2, part.TargetID)
+		sub = protowire.AppendBool(sub, 3, part.Self)
+		b = protowire.AppendMessage(b, 12, sub)
+	}
+	b = protowire.AppendStrings(b, 17, t.UnionOf)
+	b = protowire.AppendStrings(b, 18, t.OneOf)
+	b = appendProtoStringMap(b, sub, 13, t.Properties)
+	if t.Links != nil {
+		sub = protowire.AppendString(sub[:0], 1, t.Links.Entry)
+		sub = protowire.AppendString(sub, 2, t.Links.Image)
+		for _, x := range t.Links.Xrefs {
+			sub2 = protowire.AppendString(sub2[:0], 1, x.Xref)
+			sub2 = protowire.AppendString(sub2, 2, x.URL)
+			sub = protowire.AppendMessage(sub, 3, sub2)
+		}
+		b = protowire.AppendMessage(b, 14, sub)
+	}
+	for _, x := range sortedKeys(t.XrefQualifiers) {
+		// map<string, Qualifiers>: the value is a Qualifiers message.
+		sub2 = appendProtoStringMap(sub2[:0], sub, 1, t.XrefQualifiers[x])
+		sub = protowire.AppendString(sub[:0], 1, x)
+		sub = protowire.AppendMessage(sub, 2, sub2)
+		b = protowire.AppendMessage(b, 19, sub)
+	}
+	b = appendProtoStringMap(b, sub, 20, t.PropertyTypes)
+	if t.Obsolescence != nil {
+		sub = protowire.AppendString(sub[:0], 1, t.Obsolescence.Reason)
+		sub = protowire.AppendString(sub, 2, t.Obsolescence.Source)
+		b = protowire.AppendMessage(b, 21, sub)
+	}
+	return b, sub, sub2
+}
+
+// appendProtoStringMap appends a map<string, string> field with sorted
+// keys, using scratch for the entries.
+func appendProtoStringMap(b, scratch []byte, field int, m map[string]string) []byte {
+	for _, k := range sortedKeys(m) {
+		// Map entries are encoded as messages with key = 1, value = 2.
+		scratch = protowire.AppendString(scratch[:0], 1, k)
+		scratch = protowire.AppendString(scratch, 2, m[k])
+		b = protowire.AppendMessage(b, field, scratch)
+	}
+	return b
+}
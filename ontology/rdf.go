@@ -0,0 +1,633 @@
+package ontology
+
+import (
+	"bufio"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	nsXSD = "http://www.w3.org/2001/XMLSchema#"
+	nsIAO = nsOBO + "IAO_"
+)
+
+// rdfNode is a subject and its statements, as the OWL and Turtle writers
+// produce them one term at a time. A node without an IRI is a blank node
+// nested in its parent's statement.
+type rdfNode struct {
+	iri   string
+	types []string // first type names the RDF/XML element
+	props []rdfProp
+}
+
+type rdfProp struct {
+	pred string
+	obj  rdfObject
+}
+
+// rdfObject is exactly one of: a resource IRI, a literal (isLit, with an
+// optional datatype), a nested blank node, or a collection of objects.
+type rdfObject struct {
+	iri      string
+	lit      string
+	datatype string
+	isLit    bool
+	node     *rdfNode
+	list     []rdfObject
+	isList   bool
+}
+
+func rdfIRI(iri string) rdfObject { return rdfObject{iri: iri} }
+
+func rdfLit(s string) rdfObject { return rdfObject{lit: s, isLit: true} }
+
+func rdfTyped(s, datatype string) rdfObject {
+	return rdfObject{lit: s, datatype: datatype, isLit: true}
+}
+
+func (n *rdfNode) add(pred string, obj rdfObject) {
+	n.props = append(n.props, rdfProp{pred: pred, obj: obj})
+}
+
+func (n *rdfNode) addLit(pred, s string) {
+	if s != "" {
+		n.add(pred, rdfLit(s))
+	}
+}
+
+// rdfBuilder maps the model to RDF nodes following the OBO-to-OWL mapping
+// the OWL parser reads back.
+type rdfBuilder struct {
+	short string
+}
+
+func (rb *rdfBuilder) iri(id string) string { return idIRI(id, rb.short) }
+
+func (rb *rdfBuilder) header(ont *Ontology) *rdfNode {
+	iri, version := ontologyIRI(ont)
+	n := &rdfNode{iri: iri, types: []string{nsOWL + "Ontology"}}
+	if version != "" {
+		n.add(nsOWL+"versionIRI", rdfIRI(version))
+	}
+	addMetadataRDF(n, ont.Metadata)
+	return n
+}
+
+// addMetadataRDF adds the ontology metadata to n as Dublin Core terms,
+// licenses given as IRIs linking to them.
+func addMetadataRDF(n *rdfNode, m *OntologyMetadata) {
+	if m == nil {
+		return
+	}
+	for _, p := range metadataWriteProperties {
+		for _, v := range m.values(p.field) {
+			if isIRI(v) {
+				n.add(p.iri, rdfIRI(v))
+			} else {
+				n.addLit(p.iri, v)
+			}
+		}
+	}
+}
+
+func (rb *rdfBuilder) term(t *Term) *rdfNode {
+	n := &rdfNode{iri: rb.iri(t.ID), types: []string{nsOWL + "Class"}}
+	n.addLit(nsRDFS+"label", t.Name)
+	n.addLit(nsIAO+"0000115", t.Definition)
+	n.addLit(nsOBOInOwl+"hasOBONamespace", t.Namespace)
+	n.addLit(nsOBOInOwl+"id", t.ID)
+	n.addLit(nsRDFS+"comment", t.Comment)
+	if t.IsObsolete {
+		n.add(nsOWL+"deprecated", rdfTyped("true", nsXSD+"boolean"))
+	}
+	for _, r := range t.ReplacedBy {
+		n.add(nsIAO+"0100001", rdfIRI(rb.iri(r)))
+	}
+	for _, c := range t.Consider {
+		n.addLit(nsOBOInOwl+"consider", c)
+	}
+	for _, a := range t.AltIDs {
+		n.addLit(nsOBOInOwl+"hasAlternativeId", a)
+	}
+	for _, s := range t.Subsets {
+		n.add(nsOBOInOwl+"inSubset", rdfIRI(rb.iri(s)))
+	}
+	for _, syn := range t.Synonyms {
+		n.addLit(nsOBOInOwl+synonymProperty(syn.Scope), syn.Text)
+	}
+	for _, x := range t.Xrefs {
+		n.addLit(nsOBOInOwl+"hasDbXref", x)
+	}
+	for _, k := range sortedKeys(t.Properties) {
+		if dt, ok := t.PropertyTypes[k]; ok {
+			n.add(propertyIRI(k, rb.short), rdfTyped(t.Properties[k], datatypeIRI(dt)))
+		} else {
+			n.add(propertyIRI(k, rb.short), rdfLit(t.Properties[k]))
+		}
+	}
+
+	for i := range t.Relationships {
+		rel := &t.Relationships[i]
+		if rel.Type == "is_a" {
+			n.add(nsRDFS+"subClassOf", rdfIRI(rb.iri(rel.TargetID)))
+			continue
+		}
+		for _, r := range rb.restrictions(rel) {
+			n.add(nsRDFS+"subClassOf", rdfObject{node: r})
+		}
+	}
+
+	if len(t.IntersectionOf) > 0 {
+		var members []rdfObject
+		for _, p := range t.IntersectionOf {
+			if p.Relationship == "" {
+				members = append(members, rdfIRI(rb.iri(p.TargetID)))
+				continue
+			}
+			r := rb.restrictions(&Relationship{Type: p.Relationship, TargetID: p.TargetID, Self: p.Self})
+			members = append(members, rdfObject{node: r[0]})
+		}
+		n.add(nsOWL+"equivalentClass", rdfObject{node: rb.classExpression(nsOWL+"intersectionOf", members)})
+	}
+	if len(t.UnionOf) > 0 {
+		n.add(nsOWL+"equivalentClass", rdfObject{node: rb.classExpression(nsOWL+"unionOf", rb.iris(t.UnionOf))})
+	}
+	if len(t.OneOf) > 0 {
+		n.add(nsOWL+"equivalentClass", rdfObject{node: rb.classExpression(nsOWL+"oneOf", rb.iris(t.OneOf))})
+	}
+	for _, ax := range t.NonEL {
+		if sup := rb.nonEL(&ax); sup != nil {
+			n.add(nsRDFS+"subClassOf", rdfObject{node: sup})
+		}
+	}
+	return n
+}
+
+// restrictions returns the owl:Restriction nodes for a relationship: one
+// existential, value or Self restriction, or one per cardinality bound.
+func (rb *rdfBuilder) restrictions(rel *Relationship) []*rdfNode {
+	restriction := func() *rdfNode {
+		r := &rdfNode{types: []string{nsOWL + "Restriction"}}
+		r.add(nsOWL+"onProperty", rdfIRI(rb.iri(rel.Type)))
+		return r
+	}
+	count := func(n int) rdfObject { return rdfTyped(strconv.Itoa(n), nsXSD+"nonNegativeInteger") }
+
+	if c := rel.Cardinality; c != nil {
+		var out []*rdfNode
+		bound := func(pred string, n int) {
+			r := restriction()
+			r.add(nsOWL+pred, count(n))
+			r.add(nsOWL+"onClass", rdfIRI(rb.iri(rel.TargetID)))
+			out = append(out, r)
+		}
+		if c.Min == c.Max {
+			bound("qualifiedCardinality", c.Min)
+			return out
+		}
+		if c.Min > 0 {
+			bound("minQualifiedCardinality", c.Min)
+		}
+		if c.Max >= 0 {
+			bound("maxQualifiedCardinality", c.Max)
+		}
+		return out
+	}
+
+	r := restriction()
+	switch {
+	case rel.Self:
+		r.add(nsOWL+"hasSelf", rdfTyped("true", nsXSD+"boolean"))
+	case rel.HasValue:
+		r.add(nsOWL+"hasValue", rdfIRI(rb.iri(rel.TargetID)))
+	default:
+		r.add(nsOWL+"someValuesFrom", rdfIRI(rb.iri(rel.TargetID)))
+	}
+	return []*rdfNode{r}
+}
+
+func (rb *rdfBuilder) classExpression(pred string, members []rdfObject) *rdfNode {
+	c := &rdfNode{types: []string{nsOWL + "Class"}}
+	c.add(pred, rdfObject{list: members, isList: true})
+	return c
+}
+
+// nonEL returns the superclass of a non-EL axiom, or nil if its class
+// expression was not kept.
+func (rb *rdfBuilder) nonEL(ax *NonELAxiom) *rdfNode {
+	switch {
+	case len(ax.Targets) == 0:
+		return nil
+	case ax.Construct == NonELUnionOf:
+		return rb.classExpression(nsOWL+"unionOf", rb.iris(ax.Targets))
+	case ax.Construct == NonELAllValuesFrom && ax.Relationship != "":
+		r := &rdfNode{types: []string{nsOWL + "Restriction"}}
+		r.add(nsOWL+"onProperty", rdfIRI(rb.iri(ax.Relationship)))
+		r.add(nsOWL+"allValuesFrom", rdfIRI(rb.iri(ax.Targets[0])))
+		return r
+	case ax.Construct == NonELComplementOf:
+		c := &rdfNode{types: []string{nsOWL + "Class"}}
+		c.add(nsOWL+"complementOf", rdfIRI(rb.iri(ax.Targets[0])))
+		return c
+	}
+	return nil
+}
+
+func (rb *rdfBuilder) iris(ids []string) []rdfObject {
+	out := make([]rdfObject, len(ids))
+	for i, id := range ids {
+		out[i] = rdfIRI(rb.iri(id))
+	}
+	return out
+}
+
+func (rb *rdfBuilder) typeDef(td *TypeDef) *rdfNode {
+	n := &rdfNode{iri: rb.iri(td.ID), types: []string{nsOWL + "ObjectProperty"}}
+	if td.IsTransitive {
+		n.types = append(n.types, nsOWL+"TransitiveProperty")
+	}
+	if td.IsReflexive {
+		n.types = append(n.types, nsOWL+"ReflexiveProperty")
+	}
+	n.addLit(nsRDFS+"label", td.Name)
+	for _, chain := range td.HoldsOverChain {
+		n.add(nsOWL+"propertyChainAxiom", rdfObject{list: rb.iris(strings.Fields(chain)), isList: true})
+	}
+	if td.InverseOf != "" {
+		n.add(nsOWL+"inverseOf", rdfObject{iri: rb.iri(td.InverseOf)})
+	}
+	return n
+}
+
+func (rb *rdfBuilder) individual(ind *Individual) *rdfNode {
+	n := &rdfNode{iri: rb.iri(ind.ID), types: []string{nsOWL + "NamedIndividual"}}
+	for _, t := range ind.Types {
+		n.types = append(n.types, rb.iri(t))
+	}
+	n.addLit(nsRDFS+"label", ind.Name)
+	return n
+}
+
+// undeclaredRelations returns relationship and differentia types with no
+// Typedef, which OWL output must still declare as object properties.
+func undeclaredRelations(ont *Ontology) []string {
+	declared := make(map[string]bool, len(ont.TypeDefs))
+	for i := range ont.TypeDefs {
+		declared[ont.TypeDefs[i].ID] = true
+	}
+	seen := make(map[string]bool)
+	var out []string
+	add := func(r string) {
+		if r != "" && r != "is_a" && !declared[r] && !seen[r] {
+			seen[r] = true
+			out = append(out, r)
+		}
+	}
+	for i := range ont.Terms {
+		for _, rel := range ont.Terms[i].Relationships {
+			add(rel.Type)
+		}
+		for _, p := range ont.Terms[i].IntersectionOf {
+			add(p.Relationship)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// synonymAxioms returns an owl:Axiom annotating each of t's synonyms that
+// has a type or xrefs with oboInOwl:hasSynonymType and hasDbXref, as the
+// OBO-to-OWL mapping states them.
+func (rb *rdfBuilder) synonymAxioms(t *Term) []*rdfNode {
+	var out []*rdfNode
+	for _, syn := range t.Synonyms {
+		if syn.Type == "" && len(syn.Xrefs) == 0 {
+			continue
+		}
+		n := &rdfNode{types: []string{nsOWL + "Axiom"}}
+		n.add(nsOWL+"annotatedSource", rdfIRI(rb.iri(t.ID)))
+		n.add(nsOWL+"annotatedProperty", rdfIRI(nsOBOInOwl+synonymProperty(syn.Scope)))
+		n.add(nsOWL+"annotatedTarget", rdfLit(syn.Text))
+		if syn.Type != "" {
+			n.add(nsOBOInOwl+"hasSynonymType", rdfIRI(rb.iri(syn.Type)))
+		}
+		for _, x := range syn.Xrefs {
+			n.addLit(nsOBOInOwl+"hasDbXref", x)
+		}
+		out = append(out, n)
+	}
+	return out
+}
+
+// synonymProperty returns the oboInOwl annotation property for a scope.
+func synonymProperty(scope string) string {
+	switch scope {
+	case "EXACT":
+		return "hasExactSynonym"
+	case "BROAD":
+		return "hasBroadSynonym"
+	case "NARROW":
+		return "hasNarrowSynonym"
+	}
+	return "hasRelatedSynonym"
+}
+
+// propertyIRI returns the annotation property IRI for a property_value
+// key: IRIs as is, CURIEs expanded like IDs, bare names under the
+// ontology's namespace.
+func propertyIRI(key, short string) string {
+	if strings.Contains(key, "://") {
+		return key
+	}
+	if strings.Contains(key, ":") {
+		return idIRI(key, short)
+	}
+	return nsOBO + short + "/" + key
+}
+
+// splitIRI splits an IRI after its last '#' or '/' into a namespace and a
+// local name, returning ok=false if the local name is not a valid XML
+// name (and so cannot be an RDF/XML element).
+func splitIRI(iri string) (ns, local string, ok bool) {
+	i := strings.LastIndexAny(iri, "#/")
+	if i < 0 || i == len(iri)-1 {
+		return "", "", false
+	}
+	ns, local = iri[:i+1], iri[i+1:]
+	for j, r := range local {
+		letter := r == '_' || r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r > 0x7f
+		if !letter && (j == 0 || !(r == '-' || r == '.' || r >= '0' && r <= '9')) {
+			return "", "", false
+		}
+	}
+	return ns, local, true
+}
+
+// rdfPrefixes are the namespaces every RDF output declares.
+var rdfPrefixes = []struct{ prefix, ns string }{
+	{"rdf", nsRDF},
+	{"rdfs", nsRDFS},
+	{"owl", nsOWL},
+	{"xsd", nsXSD},
+	{"oboInOwl", nsOBOInOwl},
+	{"obo", nsOBO},
+}
+
+// rdfNamespaces assigns prefixes to the namespaces of the ontology's
+// property_value keys, and dcterms to its metadata's, so both
+// serializations can declare them up front.
+func rdfNamespaces(ont *Ontology, short string) map[string]string {
+	prefixes := make(map[string]string, len(rdfPrefixes)+4)
+	for _, p := range rdfPrefixes {
+		prefixes[p.ns] = p.prefix
+	}
+	if ont.Metadata != nil {
+		prefixes[nsDCTerms] = "dcterms"
+	}
+	var extra []string
+	for i := range ont.Terms {
+		for k := range ont.Terms[i].Properties {
+			if ns, _, ok := splitIRI(propertyIRI(k, short)); ok {
+				if _, known := prefixes[ns]; !known {
+					prefixes[ns] = ""
+					extra = append(extra, ns)
+				}
+			}
+		}
+	}
+	sort.Strings(extra)
+	for i, ns := range extra {
+		prefixes[ns] = "ns" + strconv.Itoa(i+1)
+	}
+	return prefixes
+}
+
+// WriteOWL writes the ontology as OWL in RDF/XML, using the OBO-to-OWL
+// mapping (oboInOwl annotations, IAO definitions, restrictions for
+// relationships, owl:Axiom annotations for synonym types and xrefs) that
+// ParseOWL reads back. Qualifiers other than cardinality have no place in
+// this mapping and are left out.
+func WriteOWL(ont *Ontology, w io.Writer) error {
+	bw := bufio.NewWriterSize(w, writerBufferSize)
+	rb := &rdfBuilder{short: ShortName(ont)}
+	xw := &rdfXMLWriter{w: bw, prefixes: rdfNamespaces(ont, rb.short)}
+
+	bw.WriteString("<?xml version=\"1.0\"?>\n<rdf:RDF")
+	var nss []string
+	for ns := range xw.prefixes {
+		nss = append(nss, ns)
+	}
+	sort.Slice(nss, func(i, j int) bool { return xw.prefixes[nss[i]] < xw.prefixes[nss[j]] })
+	for _, ns := range nss {
+		bw.WriteString("\n     xmlns:" + xw.prefixes[ns] + "=\"" + xmlEscape(ns) + "\"")
+	}
+	bw.WriteString(">\n")
+
+	return writeRDF(ont, rb, xw.node, func() error {
+		_, err := bw.WriteString("</rdf:RDF>\n")
+		if err != nil {
+			return err
+		}
+		return bw.Flush()
+	})
+}
+
+// writeRDF emits every node of the ontology through emit, then calls done.
+func writeRDF(ont *Ontology, rb *rdfBuilder, emit func(*rdfNode), done func() error) error {
+	emit(rb.header(ont))
+	for i := range ont.TypeDefs {
+		emit(rb.typeDef(&ont.TypeDefs[i]))
+	}
+	for _, r := range undeclaredRelations(ont) {
+		emit(&rdfNode{iri: rb.iri(r), types: []string{nsOWL + "ObjectProperty"}})
+	}
+	for i := range ont.Terms {
+		emit(rb.term(&ont.Terms[i]))
+		for _, ax := range rb.synonymAxioms(&ont.Terms[i]) {
+			emit(ax)
+		}
+	}
+	for i := range ont.Individuals {
+		emit(rb.individual(&ont.Individuals[i]))
+	}
+	return done()
+}
+
+type rdfXMLWriter struct {
+	w        *bufio.Writer
+	prefixes map[string]string
+}
+
+// qname returns the prefixed element name for an IRI, or "" if it has none.
+func (xw *rdfXMLWriter) qname(iri string) string {
+	ns, local, ok := splitIRI(iri)
+	if !ok {
+		return ""
+	}
+	p, ok := xw.prefixes[ns]
+	if !ok {
+		return ""
+	}
+	return p + ":" + local
+}
+
+func (xw *rdfXMLWriter) node(n *rdfNode) {
+	xw.writeNode(n, 1)
+	xw.w.WriteByte('\n')
+}
+
+func (xw *rdfXMLWriter) writeNode(n *rdfNode, depth int) {
+	indent := strings.Repeat("    ", depth)
+	types := n.types
+	elem := "rdf:Description"
+	if len(types) > 0 {
+		if q := xw.qname(types[0]); q != "" {
+			elem, types = q, types[1:]
+		}
+	}
+	xw.w.WriteString(indent + "<" + elem)
+	if n.iri != "" {
+		xw.w.WriteString(` rdf:about="` + xmlEscape(n.iri) + `"`)
+	}
+	if len(types) == 0 && len(n.props) == 0 {
+		xw.w.WriteString("/>\n")
+		return
+	}
+	xw.w.WriteString(">\n")
+	for _, t := range types {
+		xw.w.WriteString(indent + `    <rdf:type rdf:resource="` + xmlEscape(t) + "\"/>\n")
+	}
+	for _, p := range n.props {
+		q := xw.qname(p.pred)
+		if q == "" {
+			continue // no RDF/XML element name for this predicate
+		}
+		xw.writeObject(q, p.obj, depth+1)
+	}
+	xw.w.WriteString(indent + "</" + elem + ">\n")
+}
+
+func (xw *rdfXMLWriter) writeObject(q string, o rdfObject, depth int) {
+	indent := strings.Repeat("    ", depth)
+	switch {
+	case o.isList:
+		xw.w.WriteString(indent + "<" + q + " rdf:parseType=\"Collection\">\n")
+		for _, m := range o.list {
+			if m.node != nil {
+				xw.writeNode(m.node, depth+1)
+			} else {
+				xw.writeNode(&rdfNode{iri: m.iri}, depth+1)
+			}
+		}
+		xw.w.WriteString(indent + "</" + q + ">\n")
+	case o.node != nil:
+		xw.w.WriteString(indent + "<" + q + ">\n")
+		xw.writeNode(o.node, depth+1)
+		xw.w.WriteString(indent + "</" + q + ">\n")
+	case o.isLit:
+		xw.w.WriteString(indent + "<" + q)
+		if o.datatype != "" {
+			xw.w.WriteString(` rdf:datatype="` + xmlEscape(o.datatype) + `"`)
+		}
+		xw.w.WriteString(">" + xmlEscape(o.lit) + "</" + q + ">\n")
+	default:
+		xw.w.WriteString(indent + "<" + q + ` rdf:resource="` + xmlEscape(o.iri) + "\"/>\n")
+	}
+}
+
+var xmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;", "\r", "&#xD;")
+
+func xmlEscape(s string) string { return xmlEscaper.Replace(s) }
+
+// WriteTurtle writes the same triples as WriteOWL in Turtle.
+func WriteTurtle(ont *Ontology, w io.Writer) error {
+	bw := bufio.NewWriterSize(w, writerBufferSize)
+	rb := &rdfBuilder{short: ShortName(ont)}
+	tw := &turtleWriter{w: bw, prefixes: rdfNamespaces(ont, rb.short)}
+
+	var nss []string
+	for ns := range tw.prefixes {
+		nss = append(nss, ns)
+	}
+	sort.Slice(nss, func(i, j int) bool { return tw.prefixes[nss[i]] < tw.prefixes[nss[j]] })
+	for _, ns := range nss {
+		bw.WriteString("@prefix " + tw.prefixes[ns] + ": <" + ns + "> .\n")
+	}
+
+	return writeRDF(ont, rb, tw.node, bw.Flush)
+}
+
+type turtleWriter struct {
+	w        *bufio.Writer
+	prefixes map[string]string
+}
+
+// ref writes an IRI as a prefixed name where the local part is safe to
+// write unescaped, else in angle brackets.
+func (tw *turtleWriter) ref(iri string) string {
+	if ns, local, ok := splitIRI(iri); ok && !strings.ContainsAny(local, ".") {
+		if p, ok := tw.prefixes[ns]; ok {
+			return p + ":" + local
+		}
+	}
+	return "<" + iri + ">"
+}
+
+func (tw *turtleWriter) node(n *rdfNode) {
+	if n.iri == "" {
+		// A blank node, such as an owl:Axiom, is its own statement.
+		tw.w.WriteString("\n[")
+		tw.body(n, 1)
+		tw.w.WriteString("\n] .\n")
+		return
+	}
+	tw.w.WriteString("\n" + tw.ref(n.iri))
+	tw.body(n, 1)
+	tw.w.WriteString(" .\n")
+}
+
+// body writes the predicate-object list of n.
+func (tw *turtleWriter) body(n *rdfNode, depth int) {
+	indent := "\n" + strings.Repeat("    ", depth)
+	sep := ""
+	if len(n.types) > 0 {
+		refs := make([]string, len(n.types))
+		for i, t := range n.types {
+			refs[i] = tw.ref(t)
+		}
+		tw.w.WriteString(indent + "a " + strings.Join(refs, ", "))
+		sep = " ;"
+	}
+	for _, p := range n.props {
+		tw.w.WriteString(sep + indent + tw.ref(p.pred) + " ")
+		tw.object(p.obj, depth)
+		sep = " ;"
+	}
+}
+
+func (tw *turtleWriter) object(o rdfObject, depth int) {
+	switch {
+	case o.isList:
+		tw.w.WriteString("(")
+		for _, m := range o.list {
+			tw.w.WriteString(" ")
+			tw.object(m, depth+1)
+		}
+		tw.w.WriteString(" )")
+	case o.node != nil:
+		tw.w.WriteString("[")
+		tw.body(o.node, depth+1)
+		tw.w.WriteString("\n" + strings.Repeat("    ", depth) + "]")
+	case o.isLit:
+		tw.w.WriteString(`"` + turtleEscaper.Replace(o.lit) + `"`)
+		if o.datatype != "" {
+			tw.w.WriteString("^^" + tw.ref(o.datatype))
+		}
+	default:
+		tw.w.WriteString(tw.ref(o.iri))
+	}
+}
+
+var turtleEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
@@ -0,0 +1,130 @@
+package ontology
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Fields a Reference can come from.
+const (
+	RefRelationship   = "relationship"
+	RefIntersectionOf = "intersection_of"
+	RefUnionOf        = "union_of"
+	RefXref           = "xref"
+	RefReplacedBy     = "replaced_by"
+	RefConsider       = "consider"
+)
+
+// Reference is one mention of a term by another term.
+type Reference struct {
+	ID       string `json:"id"`                 // referencing term
+	Field    string `json:"field"`              // one of the Ref* constants
+	Relation string `json:"relation,omitempty"` // relationship or differentia type
+	Via      string `json:"via,omitempty"`      // alt ID the mention used, if not the primary ID
+}
+
+// refIndex maps every mentioned ID to its mentions. It is built on first
+// use.
+type refIndex struct {
+	once sync.Once
+	by   map[string][]Reference
+}
+
+func (ix *Index) buildRefs() {
+	by := make(map[string][]Reference)
+	add := func(target string, r Reference) {
+		by[target] = append(by[target], r)
+	}
+	for i := range ix.ont.Terms {
+		t := &ix.ont.Terms[i]
+		for _, rel := range t.Relationships {
+			if rel.TargetID != "" {
+				add(rel.TargetID, Reference{ID: t.ID, Field: RefRelationship, Relation: rel.Type})
+			}
+		}
+		for _, p := range t.IntersectionOf {
+			if p.TargetID != "" {
+				add(p.TargetID, Reference{ID: t.ID, Field: RefIntersectionOf, Relation: p.Relationship})
+			}
+		}
+		for _, u := range t.UnionOf {
+			add(u, Reference{ID: t.ID, Field: RefUnionOf})
+		}
+		for _, x := range t.Xrefs {
+			// OBO xrefs may carry a quoted description after the ID.
+			if f := strings.Fields(x); len(f) > 0 {
+				add(f[0], Reference{ID: t.ID, Field: RefXref})
+			}
+		}
+		for _, r := range t.ReplacedBy {
+			add(r, Reference{ID: t.ID, Field: RefReplacedBy})
+		}
+		for _, c := range t.Consider {
+			add(c, Reference{ID: t.ID, Field: RefConsider})
+		}
+	}
+	ix.refs.by = by
+}
+
+// ReferencedBy returns every mention of id, or of one of its alt IDs, in
+// other terms' relationships, intersection_of, union_of, xrefs,
+// replaced_by and consider tags, sorted by referencing term and field. A
+// term's mentions of itself are left out.
+func (ix *Index) ReferencedBy(id string) []Reference {
+	ix.refs.once.Do(ix.buildRefs)
+	primary := ix.Primary(id)
+	if primary == "" {
+		primary = id
+	}
+	out := append([]Reference(nil), ix.refs.by[primary]...)
+	if t := ix.Term(primary); t != nil {
+		for _, alt := range t.AltIDs {
+			for _, r := range ix.refs.by[alt] {
+				r.Via = alt
+				out = append(out, r)
+			}
+		}
+	}
+	kept := out[:0]
+	for _, r := range out {
+		if r.ID != primary {
+			kept = append(kept, r)
+		}
+	}
+	sort.SliceStable(kept, func(i, j int) bool {
+		if kept[i].ID != kept[j].ID {
+			return kept[i].ID < kept[j].ID
+		}
+		return kept[i].Field < kept[j].Field
+	})
+	return kept
+}
+
+// xrefIndex maps every xref, without its description, to the terms that
+// carry it. It is built on first use.
+type xrefIndex struct {
+	once sync.Once
+	by   map[string][]string
+}
+
+func (ix *Index) buildXrefs() {
+	by := make(map[string][]string)
+	for i := range ix.ont.Terms {
+		t := &ix.ont.Terms[i]
+		for _, x := range t.Xrefs {
+			if f := strings.Fields(x); len(f) > 0 && !slices.Contains(by[f[0]], t.ID) {
+				by[f[0]] = append(by[f[0]], t.ID)
+			}
+		}
+	}
+	ix.xrefs.by = by
+}
+
+// TermsWithXref returns the IDs of the terms with xref (such as
+// "CAS:50-78-2"), in ontology order. The slice must not be modified.
+func (ix *Index) TermsWithXref(xref string) []string {
+	ix.xrefs.once.Do(ix.buildXrefs)
+	return ix.xrefs.by[strings.TrimSpace(xref)]
+}
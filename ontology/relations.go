@@ -0,0 +1,143 @@
+package ontology
+
+import "sort"
+
+// RelationOther keys the terms under none of the top-level classes of a
+// RelationMatrix, such as obsolete terms and targets outside the ontology.
+const RelationOther = "other"
+
+// RelationMatrix counts relationship edges per type, overall and broken
+// down by the top-level classes of their source and target.
+type RelationMatrix struct {
+	Tops  []RelationTop   `json:"tops"`
+	Types []RelationUsage `json:"types"`
+}
+
+// RelationTop is a top-level class of a RelationMatrix.
+type RelationTop struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+// RelationUsage is one relationship type's usage: its edges, the distinct
+// terms asserting them, and the edges per source and target top-level
+// class (IDs, or RelationOther). A term under several top-level classes
+// counts in each, so Cells can add up to more than Edges.
+type RelationUsage struct {
+	Type    string         `json:"type"`
+	Name    string         `json:"name,omitempty"`
+	Edges   int            `json:"edges"`
+	Sources int            `json:"sources"`
+	Cells   []RelationCell `json:"cells"`
+}
+
+// RelationCell is the number of edges of a type from terms under Source
+// to terms under Target.
+type RelationCell struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Edges  int    `json:"edges"`
+}
+
+// RelationMatrix counts the relationships of every term, is_a included.
+// The top-level classes are the live terms without is_a parents, as in
+// SplitBySubtree, or root's children if root is set. Types are sorted by
+// descending edge count, cells by source and target.
+func (ix *Index) RelationMatrix(root string) *RelationMatrix {
+	m := &RelationMatrix{Tops: []RelationTop{}, Types: []RelationUsage{}}
+	var tops []string
+	if root == "" {
+		for i := range ix.ont.Terms {
+			t := &ix.ont.Terms[i]
+			if !t.IsObsolete && len(ix.Parents(t.ID)) == 0 {
+				tops = append(tops, t.ID)
+			}
+		}
+	} else {
+		tops = ix.Children(ix.Primary(root))
+	}
+	sort.Strings(tops)
+	under := make(map[string][]string, len(ix.ont.Terms))
+	for _, top := range tops {
+		m.Tops = append(m.Tops, RelationTop{ID: top, Name: ix.ref(top).Name})
+		for id := range ix.descendants(top) {
+			under[id] = append(under[id], top)
+		}
+	}
+	other := []string{RelationOther}
+	topsOf := func(id string) []string {
+		if ts := under[ix.Primary(id)]; len(ts) > 0 {
+			return ts
+		}
+		return other
+	}
+
+	type cellKey struct{ source, target string }
+	type usage struct {
+		edges, sources int
+		cells          map[cellKey]int
+	}
+	byType := make(map[string]*usage)
+	for i := range ix.ont.Terms {
+		t := &ix.ont.Terms[i]
+		seen := make(map[string]bool, len(t.Relationships))
+		for _, rel := range t.Relationships {
+			u := byType[rel.Type]
+			if u == nil {
+				u = &usage{cells: make(map[cellKey]int)}
+				byType[rel.Type] = u
+			}
+			u.edges++
+			if !seen[rel.Type] {
+				seen[rel.Type] = true
+				u.sources++
+			}
+			for _, s := range topsOf(t.ID) {
+				for _, o := range topsOf(rel.TargetID) {
+					u.cells[cellKey{s, o}]++
+				}
+			}
+		}
+	}
+
+	names := make(map[string]string, len(ix.ont.TypeDefs))
+	for _, td := range ix.ont.TypeDefs {
+		names[td.ID] = td.Name
+	}
+	for typ, u := range byType {
+		ru := RelationUsage{Type: typ, Name: names[typ], Edges: u.edges, Sources: u.sources}
+		for k, n := range u.cells {
+			ru.Cells = append(ru.Cells, RelationCell{Source: k.source, Target: k.target, Edges: n})
+		}
+		sort.Slice(ru.Cells, func(i, j int) bool {
+			a, b := ru.Cells[i], ru.Cells[j]
+			if a.Source != b.Source {
+				return a.Source < b.Source
+			}
+			return a.Target < b.Target
+		})
+		m.Types = append(m.Types, ru)
+	}
+	sort.Slice(m.Types, func(i, j int) bool {
+		a, b := m.Types[i], m.Types[j]
+		if a.Edges != b.Edges {
+			return a.Edges > b.Edges
+		}
+		return a.Type < b.Type
+	})
+	return m
+}
+
+// TypeChanges returns the relationship types used in m but not in old,
+// and those used in old but no longer in m, both sorted.
+func (m *RelationMatrix) TypeChanges(old *RelationMatrix) (added, removed []string) {
+	return diffSets(old.typeNames(), m.typeNames())
+}
+
+func (m *RelationMatrix) typeNames() []string {
+	out := make([]string, len(m.Types))
+	for i, u := range m.Types {
+		out[i] = u.Type
+	}
+	return out
+}
@@ -0,0 +1,305 @@
+package ontology
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// ReleaseStats summarizes an ontology release for reports.
+type ReleaseStats struct {
+	Ontology      string
+	DataVersion   string
+	FormatVersion string
+	Metadata      *OntologyMetadata
+	Terms         int
+	Obsolete      int
+	Synonyms      int
+	Xrefs         int
+	Definitions   int
+	TypeDefs      int
+	Namespaces    []CountEntry
+	Relationships []CountEntry
+	Subsets       []CountEntry
+}
+
+// CountEntry is a labelled count, sorted by descending count in reports.
+type CountEntry struct {
+	Key   string
+	Count int
+}
+
+// ComputeStats gathers release-level counts.
+func ComputeStats(ont *Ontology) ReleaseStats {
+	s := ReleaseStats{
+		Ontology:      ont.Ontology,
+		DataVersion:   ont.DataVersion,
+		FormatVersion: ont.FormatVersion,
+		Metadata:      ont.Metadata,
+		Terms:         len(ont.Terms),
+		TypeDefs:      len(ont.TypeDefs),
+	}
+	namespaces := make(map[string]int)
+	rels := make(map[string]int)
+	subsets := make(map[string]int)
+	for i := range ont.Terms {
+		t := &ont.Terms[i]
+		if t.IsObsolete {
+			s.Obsolete++
+		}
+		if t.Definition != "" {
+			s.Definitions++
+		}
+		s.Synonyms += len(t.Synonyms)
+		s.Xrefs += len(t.Xrefs)
+		if t.Namespace != "" {
+			namespaces[t.Namespace]++
+		}
+		for _, rel := range t.Relationships {
+			rels[rel.Type]++
+		}
+		for _, sub := range t.Subsets {
+			subsets[sub]++
+		}
+	}
+	s.Namespaces = sortedCounts(namespaces)
+	s.Relationships = sortedCounts(rels)
+	s.Subsets = sortedCounts(subsets)
+	return s
+}
+
+func sortedCounts(m map[string]int) []CountEntry {
+	out := make([]CountEntry, 0, len(m))
+	for k, v := range m {
+		out = append(out, CountEntry{k, v})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Key < out[j].Key
+	})
+	return out
+}
+
+// TermReport is the data rendered on a per-term page.
+type TermReport struct {
+	Term     *Term
+	Name     string // the term's Label
+	Parents  []TermRef
+	Children []TermRef
+	Other    []RelRef // non-is_a relationships
+}
+
+// TermRef is a term ID with its label, if known.
+type TermRef struct {
+	ID   string
+	Name string
+}
+
+// RelRef is a typed relationship with a resolved target label.
+type RelRef struct {
+	Type   string
+	Target TermRef
+}
+
+// NewTermReport assembles the report data for one term, or returns nil if
+// the term is not in the index.
+func (ix *Index) NewTermReport(id string) *TermReport {
+	t := ix.Term(id)
+	if t == nil {
+		return nil
+	}
+	r := &TermReport{Term: t, Name: ix.Label(t.ID)}
+	for _, rel := range t.Relationships {
+		ref := ix.ref(rel.TargetID)
+		if rel.Type == "is_a" {
+			r.Parents = append(r.Parents, ref)
+		} else {
+			r.Other = append(r.Other, RelRef{Type: rel.Type, Target: ref})
+		}
+	}
+	for _, c := range ix.Children(id) {
+		r.Children = append(r.Children, ix.ref(c))
+	}
+	return r
+}
+
+func (ix *Index) ref(id string) TermRef {
+	return TermRef{ID: id, Name: ix.Label(id)}
+}
+
+// Report output formats.
+const (
+	ReportMarkdown = "markdown"
+	ReportHTML     = "html"
+)
+
+var reportFuncs = map[string]any{
+	"md": mdEscape,
+	"label": func(r TermRef) string {
+		if r.Name == "" {
+			return r.ID
+		}
+		return r.Name + " (" + r.ID + ")"
+	},
+	"pct": func(n, total int) string {
+		if total == 0 {
+			return "0.0%"
+		}
+		return fmt.Sprintf("%.1f%%", 100*float64(n)/float64(total))
+	},
+}
+
+const mdReleaseTmpl = `# {{if .Ontology}}{{md .Ontology}}{{else}}Ontology{{end}} release report
+
+| | |
+|---|---|
+| Data version | {{md .DataVersion}} |
+| Format version | {{md .FormatVersion}} |
+{{with .Metadata}}{{if .Title}}| Title | {{md .Title}} |
+{{end}}{{range .Licenses}}| License | {{md .}} |
+{{end}}{{end}}| Terms | {{.Terms}} |
+| Obsolete terms | {{.Obsolete}} ({{pct .Obsolete .Terms}}) |
+| Terms with definitions | {{.Definitions}} ({{pct .Definitions .Terms}}) |
+| Synonyms | {{.Synonyms}} |
+| Xrefs | {{.Xrefs}} |
+| Relation types (Typedefs) | {{.TypeDefs}} |
+{{if .Namespaces}}
+## Namespaces
+
+| Namespace | Terms |
+|---|---|
+{{range .Namespaces}}| {{md .Key}} | {{.Count}} |
+{{end}}{{end}}{{if .Relationships}}
+## Relationships
+
+| Type | Edges |
+|---|---|
+{{range .Relationships}}| {{md .Key}} | {{.Count}} |
+{{end}}{{end}}{{if .Subsets}}
+## Subsets
+
+| Subset | Terms |
+|---|---|
+{{range .Subsets}}| {{md .Key}} | {{.Count}} |
+{{end}}{{end}}`
+
+const mdTermTmpl = `{{with .Term}}# {{md $.Name}} ({{.ID}})
+{{if .IsObsolete}}
+**Obsolete.**
+{{end}}{{if .Definition}}
+{{md .Definition}}
+{{end}}{{if .Comment}}
+_{{md .Comment}}_
+{{end}}{{end}}{{if or .Parents .Children}}
+## Hierarchy
+{{range .Parents}}
+- {{md (label .)}}{{end}}
+  - **{{md .Name}}** ({{.Term.ID}}){{range .Children}}
+    - {{md (label .)}}{{end}}
+{{end}}{{if .Other}}
+## Relationships
+{{range .Other}}
+- {{md .Type}} {{md (label .Target)}}{{end}}
+{{end}}{{with .Term}}{{if .Synonyms}}
+## Synonyms
+
+| Synonym | Scope | Type |
+|---|---|---|
+{{range .Synonyms}}| {{md .Text}} | {{.Scope}} | {{md .Type}} |
+{{end}}{{end}}{{if .Xrefs}}
+## Cross-references
+{{range .Xrefs}}
+- {{md .}}{{end}}
+{{end}}{{end}}`
+
+const htmlReleaseTmpl = `<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>{{.Ontology}} {{.DataVersion}}</title></head>
+<body>
+<h1>{{if .Ontology}}{{.Ontology}}{{else}}Ontology{{end}} release report</h1>
+<table>
+<tr><th>Data version</th><td>{{.DataVersion}}</td></tr>
+<tr><th>Format version</th><td>{{.FormatVersion}}</td></tr>
+{{with .Metadata}}{{if .Title}}<tr><th>Title</th><td>{{.Title}}</td></tr>
+{{end}}{{range .Licenses}}<tr><th>License</th><td>{{.}}</td></tr>
+{{end}}{{end}}<tr><th>Terms</th><td>{{.Terms}}</td></tr>
+<tr><th>Obsolete terms</th><td>{{.Obsolete}} ({{pct .Obsolete .Terms}})</td></tr>
+<tr><th>Terms with definitions</th><td>{{.Definitions}} ({{pct .Definitions .Terms}})</td></tr>
+<tr><th>Synonyms</th><td>{{.Synonyms}}</td></tr>
+<tr><th>Xrefs</th><td>{{.Xrefs}}</td></tr>
+<tr><th>Relation types (Typedefs)</th><td>{{.TypeDefs}}</td></tr>
+</table>
+{{if .Namespaces}}<h2>Namespaces</h2>
+<table><tr><th>Namespace</th><th>Terms</th></tr>
+{{range .Namespaces}}<tr><td>{{.Key}}</td><td>{{.Count}}</td></tr>
+{{end}}</table>
+{{end}}{{if .Relationships}}<h2>Relationships</h2>
+<table><tr><th>Type</th><th>Edges</th></tr>
+{{range .Relationships}}<tr><td>{{.Key}}</td><td>{{.Count}}</td></tr>
+{{end}}</table>
+{{end}}{{if .Subsets}}<h2>Subsets</h2>
+<table><tr><th>Subset</th><th>Terms</th></tr>
+{{range .Subsets}}<tr><td>{{.Key}}</td><td>{{.Count}}</td></tr>
+{{end}}</table>
+{{end}}</body></html>
+`
+
+const htmlTermTmpl = `<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>{{.Name}} ({{.Term.ID}})</title></head>
+<body>
+{{with .Term}}<h1>{{$.Name}} <small>{{.ID}}</small></h1>
+{{if .IsObsolete}}<p><strong>Obsolete.</strong></p>
+{{end}}{{if .Definition}}<p>{{.Definition}}</p>
+{{end}}{{if .Comment}}<p><em>{{.Comment}}</em></p>
+{{end}}{{end}}{{if or .Parents .Children}}<h2>Hierarchy</h2>
+<ul>{{range .Parents}}<li>{{label .}}</li>{{end}}
+<li><ul><li><strong>{{.Name}}</strong> ({{.Term.ID}})<ul>{{range .Children}}<li>{{label .}}</li>{{end}}</ul></li></ul></li>
+</ul>
+{{end}}{{if .Other}}<h2>Relationships</h2>
+<ul>{{range .Other}}<li>{{.Type}} {{label .Target}}</li>{{end}}</ul>
+{{end}}{{with .Term}}{{if .Synonyms}}<h2>Synonyms</h2>
+<table><tr><th>Synonym</th><th>Scope</th><th>Type</th></tr>
+{{range .Synonyms}}<tr><td>{{.Text}}</td><td>{{.Scope}}</td><td>{{.Type}}</td></tr>
+{{end}}</table>
+{{end}}{{if .Xrefs}}<h2>Cross-references</h2>
+<ul>{{range .Xrefs}}<li>{{.}}</li>{{end}}</ul>
+{{end}}{{end}}</body></html>
+`
+
+var (
+	mdRelease   = template.Must(template.New("release").Funcs(reportFuncs).Parse(mdReleaseTmpl))
+	mdTerm      = template.Must(template.New("term").Funcs(reportFuncs).Parse(mdTermTmpl))
+	htmlRelease = htmltemplate.Must(htmltemplate.New("release").Funcs(reportFuncs).Parse(htmlReleaseTmpl))
+	htmlTerm    = htmltemplate.Must(htmltemplate.New("term").Funcs(reportFuncs).Parse(htmlTermTmpl))
+)
+
+// WriteReleaseReport renders the release statistics page.
+func WriteReleaseReport(stats ReleaseStats, w io.Writer, format string) error {
+	switch format {
+	case ReportMarkdown:
+		return mdRelease.Execute(w, stats)
+	case ReportHTML:
+		return htmlRelease.Execute(w, stats)
+	}
+	return fmt.Errorf("unknown report format %q", format)
+}
+
+// WriteTermReport renders a per-term page.
+func WriteTermReport(r *TermReport, w io.Writer, format string) error {
+	switch format {
+	case ReportMarkdown:
+		return mdTerm.Execute(w, r)
+	case ReportHTML:
+		return htmlTerm.Execute(w, r)
+	}
+	return fmt.Errorf("unknown report format %q", format)
+}
+
+// mdEscape escapes characters that would break Markdown tables or emphasis.
+func mdEscape(s string) string {
+	return strings.NewReplacer("|", `\|`, "*", `\*`, "_", `\_`, "\n", " ").Replace(s)
+}
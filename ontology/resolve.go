@@ -0,0 +1,459 @@
+package ontology
+
+import (
+	"math"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Match tiers reported by Index.Resolve, strongest first.
+const (
+	MatchExact      = "exact"      // ID, alt ID or label, ignoring case
+	MatchSynonym    = "synonym"    // synonym text, ignoring case
+	MatchNormalized = "normalized" // label or synonym after foldName
+	MatchToken      = "token"      // label or synonym containing every TokenizeName token
+	MatchFuzzy      = "fuzzy"      // folded label or synonym within MaxEdits
+)
+
+// Fields a Match can come from.
+const (
+	FieldID      = "id"
+	FieldAltID   = "alt_id"
+	FieldLabel   = "label"
+	FieldSynonym = "synonym"
+)
+
+// Match is one candidate term for a name, with how it matched.
+type Match struct {
+	ID       string  `json:"id"`
+	Name     string  `json:"name,omitempty"`
+	Score    float64 `json:"score"`
+	Tier     string  `json:"tier"`
+	Field    string  `json:"field"`
+	Text     string  `json:"text"`            // the ID, label or synonym matched
+	Scope    string  `json:"scope,omitempty"` // synonym scope
+	Type     string  `json:"type,omitempty"`  // synonym type
+	Edits    int     `json:"edits,omitempty"` // edit distance for fuzzy matches
+	Obsolete bool    `json:"obsolete,omitempty"`
+}
+
+// ResolveOptions tunes Index.Resolve. The zero value returns up to 10
+// candidates with fuzzy matching sized to the query and the default
+// weights.
+type ResolveOptions struct {
+	Limit int // maximum candidates (default 10)
+	// MaxEdits bounds fuzzy matching: 0 allows one edit for folded names
+	// of 4 or more characters and two from 8; negative disables it.
+	MaxEdits int
+	Weights  *ResolveWeights // nil for DefaultResolveWeights
+}
+
+// ResolveWeights scales candidate scores by what matched. A synonym is
+// weighted by its scope times its type (types missing from Types weigh 1;
+// scopes missing from Scopes weigh 1). Obsolete scales obsolete terms; 0
+// leaves them out of the results entirely.
+type ResolveWeights struct {
+	Label    float64            `json:"label"`
+	Scopes   map[string]float64 `json:"scopes,omitempty"`
+	Types    map[string]float64 `json:"types,omitempty"`
+	Obsolete float64            `json:"obsolete"`
+}
+
+// DefaultResolveWeights returns the default weights: labels first, then
+// EXACT synonyms, IUPAC and INN names just below them, RELATED and NARROW
+// lower and BROAD synonyms and formulae well down; obsolete terms are
+// searchable at half weight.
+func DefaultResolveWeights() *ResolveWeights {
+	return &ResolveWeights{
+		Label: 1,
+		Scopes: map[string]float64{
+			"EXACT":   1,
+			"RELATED": 0.8,
+			"NARROW":  0.7,
+			"BROAD":   0.5,
+		},
+		Types: map[string]float64{
+			"IUPAC_NAME": 0.95,
+			"INN":        0.95,
+			"BRAND_NAME": 0.9,
+			"FORMULA":    0.6,
+		},
+		Obsolete: 0.5,
+	}
+}
+
+func (w *ResolveWeights) synonym(syn *Synonym) float64 {
+	weight := 1.0
+	if v, ok := w.Scopes[syn.Scope]; ok {
+		weight = v
+	}
+	if v, ok := w.Types[syn.Type]; ok {
+		weight *= v
+	}
+	return weight
+}
+
+// Tier scores: a candidate scores its tier's base times the weight of the
+// label or synonym it matched, times the similarity for fuzzy matches or
+// the share of its tokens the query covers for token matches.
+var tierScores = map[string]float64{
+	MatchExact:      1.0,
+	MatchSynonym:    0.9,
+	MatchNormalized: 0.8,
+	MatchToken:      0.7,
+	MatchFuzzy:      0.6,
+}
+
+const (
+	defaultLimit    = 10
+	fuzzyOneEditMin = 4
+	fuzzyTwoEditMin = 8
+)
+
+// searchIndex holds the image behind Resolve: built on first use, or
+// mapped from disk with UseSearchIndex.
+type searchIndex struct {
+	once sync.Once
+	img  *searchImage
+}
+
+// uniqueTokens returns the distinct tokens of TokenizeName(s).
+func uniqueTokens(s string) []string {
+	toks := TokenizeName(s)
+	out := toks[:0]
+	seen := make(map[string]bool, len(toks))
+	for _, t := range toks {
+		if !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// greekLetters spells out Greek letters so "α-D-glucose" and
+// "alpha-D-glucose" fold to the same key.
+var greekLetters = map[rune]string{
+	'α': "alpha", 'β': "beta", 'γ': "gamma", 'δ': "delta", 'ε': "epsilon",
+	'ζ': "zeta", 'η': "eta", 'θ': "theta", 'ι': "iota", 'κ': "kappa",
+	'λ': "lambda", 'μ': "mu", 'ν': "nu", 'ξ': "xi", 'ο': "omicron",
+	'π': "pi", 'ρ': "rho", 'σ': "sigma", 'τ': "tau", 'υ': "upsilon",
+	'φ': "phi", 'χ': "chi", 'ψ': "psi", 'ω': "omega",
+}
+
+// FoldName returns the key of Resolve's normalized tier for s: s
+// lower-cased, with Greek letters spelled out and everything but letters,
+// digits and "+" dropped, so spacing, hyphenation and brackets don't
+// matter. "+" is kept so charge states (1+) and (1-) stay distinct.
+func FoldName(s string) string { return foldName(s) }
+
+func foldName(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case greekLetters[r] != "":
+			b.WriteString(greekLetters[r])
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '+':
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Resolve returns the terms name may refer to, best first. Candidates are
+// gathered from the exact (ID, alt ID or label), synonym and normalized
+// (see foldName) tiers. Only when those find nothing are the recall tiers
+// tried: token (names containing every query token in any order, see
+// TokenizeName) and then fuzzy (folded names within a small edit
+// distance, counting transpositions).
+// Each term appears once with its best-scoring match, scored with
+// opts.Weights; ties are broken by ID.
+func (ix *Index) Resolve(name string, opts ResolveOptions) []Match {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil
+	}
+	ix.search.once.Do(ix.buildSearch)
+	img := ix.search.img
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+	weights := opts.Weights
+	if weights == nil {
+		weights = DefaultResolveWeights()
+	}
+
+	best := make(map[string]Match)
+	keep := func(m Match) {
+		t := ix.Term(m.ID)
+		m.Name = ix.Label(t.ID)
+		if t.IsObsolete {
+			if weights.Obsolete <= 0 {
+				return
+			}
+			m.Obsolete = true
+			m.Score *= weights.Obsolete
+		}
+		m.Score = math.Round(m.Score*1000) / 1000
+		if have, ok := best[m.ID]; !ok || m.Score > have.Score {
+			best[m.ID] = m
+		}
+	}
+	entry := func(e searchEntry, tier string, similarity float64, edits int) {
+		t := &ix.ont.Terms[e.term]
+		m := Match{ID: t.ID, Tier: tier, Field: FieldLabel, Text: t.Name, Edits: edits}
+		weight := weights.Label
+		if e.syn >= 0 {
+			syn := &t.Synonyms[e.syn]
+			m.Field, m.Text, m.Scope, m.Type = FieldSynonym, syn.Text, syn.Scope, syn.Type
+			if tier == MatchExact {
+				m.Tier = MatchSynonym
+			}
+			weight = weights.synonym(syn)
+		}
+		m.Score = tierScores[m.Tier] * weight * similarity
+		keep(m)
+	}
+
+	if id := ix.Primary(name); id != "" {
+		field := FieldID
+		if id != name {
+			field = FieldAltID
+		}
+		keep(Match{ID: id, Score: tierScores[MatchExact], Tier: MatchExact, Field: field, Text: name})
+	}
+	for _, e := range img.exact.lookup(normalizeRef(name)) {
+		entry(img.entry(e), MatchExact, 1, 0)
+	}
+	folded := foldName(name)
+	for _, e := range img.folded.lookup(folded) {
+		entry(img.entry(e), MatchNormalized, 1, 0)
+	}
+	if len(best) == 0 {
+		img.tokenMatches(name, func(e int32, coverage float64) {
+			entry(img.entry(e), MatchToken, coverage, 0)
+		})
+	}
+	if len(best) == 0 {
+		img.fuzzy(folded, opts.MaxEdits, func(key, edits int, similarity float64) {
+			for _, e := range img.folded.values(key) {
+				entry(img.entry(e), MatchFuzzy, similarity, edits)
+			}
+		})
+	}
+
+	out := make([]Match, 0, len(best))
+	for _, m := range best {
+		out = append(out, m)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Score != out[j].Score {
+			return out[i].Score > out[j].Score
+		}
+		return out[i].ID < out[j].ID
+	})
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+// tokenMatches calls fn for every entry containing all tokens of name, with
+// the share of the entry's tokens that the query covers.
+func (img *searchImage) tokenMatches(name string, fn func(e int32, coverage float64)) {
+	toks := uniqueTokens(name)
+	if len(toks) == 0 {
+		return
+	}
+	lists := make([][]int32, len(toks))
+	for i, tok := range toks {
+		if lists[i] = img.tokens.lookup(tok); len(lists[i]) == 0 {
+			return
+		}
+	}
+	sort.Slice(lists, func(i, j int) bool { return len(lists[i]) < len(lists[j]) })
+	hits := lists[0]
+	for _, l := range lists[1:] {
+		hits = intersectSorted(hits, l)
+		if len(hits) == 0 {
+			return
+		}
+	}
+	for _, e := range hits {
+		fn(e, float64(len(toks))/float64(img.tokenCount(e)))
+	}
+}
+
+// intersectSorted returns the values in both ascending lists.
+func intersectSorted(a, b []int32) []int32 {
+	var out []int32
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			out = append(out, a[i])
+			i++
+			j++
+		}
+	}
+	return out
+}
+
+// fuzzy calls fn with the index of every folded key within maxEdits of
+// folded, its distance and its similarity (1 - edits/longer length).
+func (img *searchImage) fuzzy(folded string, maxEdits int, fn func(key, edits int, similarity float64)) {
+	q := []rune(folded)
+	if maxEdits = FuzzyEdits(folded, maxEdits); maxEdits <= 0 {
+		return
+	}
+	for i := 0; i < img.folded.n; i++ {
+		n := img.foldedLen(i)
+		if n < len(q)-maxEdits || n > len(q)+maxEdits {
+			continue
+		}
+		if d := editDistance(q, []rune(string(img.folded.key(i))), maxEdits); d <= maxEdits {
+			fn(i, d, 1-float64(d)/float64(max(n, len(q))))
+		}
+	}
+}
+
+// FuzzyEdits returns the edit distance Resolve's fuzzy tier allows for
+// a folded query under ResolveOptions.MaxEdits, or 0 if the tier is off.
+func FuzzyEdits(folded string, maxEdits int) int {
+	if maxEdits != 0 {
+		return max(maxEdits, 0)
+	}
+	switch n := utf8.RuneCountInString(folded); {
+	case n >= fuzzyTwoEditMin:
+		return 2
+	case n >= fuzzyOneEditMin:
+		return 1
+	}
+	return 0
+}
+
+// EditDistance is editDistance over strings.
+func EditDistance(a, b string, limit int) int {
+	return editDistance([]rune(a), []rune(b), limit)
+}
+
+// editDistance returns the optimal string alignment distance between a
+// and b (insertions, deletions, substitutions and adjacent
+// transpositions), or limit+1 once it is certain to exceed limit.
+func editDistance(a, b []rune, limit int) int {
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d := min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d = min(d, prev2[j-2]+1)
+			}
+			cur[j] = d
+			rowMin = min(rowMin, d)
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(b)]
+}
+
+// Batch resolution statuses.
+const (
+	ResolveMatched   = "matched"   // one best candidate
+	ResolveAmbiguous = "ambiguous" // several candidates share the best score
+	ResolveUnmatched = "unmatched"
+)
+
+// ResolveResult is the outcome of resolving one name in a batch. ID is the
+// best candidate when Status is ResolveMatched.
+type ResolveResult struct {
+	Input   string  `json:"input"`
+	Status  string  `json:"status"`
+	ID      string  `json:"id,omitempty"`
+	Matches []Match `json:"matches,omitempty"`
+}
+
+// ResolveBatch resolves every name with Resolve on up to workers
+// goroutines (default: number of CPUs), returning results in input order.
+// Repeated names are resolved once.
+func (ix *Index) ResolveBatch(names []string, opts ResolveOptions, workers int) []ResolveResult {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	ix.search.once.Do(ix.buildSearch)
+
+	first := make(map[string]int, len(names)) // name → index of its first occurrence
+	var todo []int
+	for i, n := range names {
+		if _, ok := first[n]; !ok {
+			first[n] = i
+			todo = append(todo, i)
+		}
+	}
+
+	out := make([]ResolveResult, len(names))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(workers, len(todo)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				out[i] = ix.resolveOne(names[i], opts)
+			}
+		}()
+	}
+	for _, i := range todo {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	for i, n := range names {
+		if j := first[n]; j != i {
+			out[i] = out[j]
+		}
+	}
+	return out
+}
+
+func (ix *Index) resolveOne(name string, opts ResolveOptions) ResolveResult {
+	return NewResolveResult(name, ix.Resolve(name, opts))
+}
+
+// NewResolveResult classifies the candidates Resolve returned for name:
+// matched if one scores best, ambiguous if several share the best score.
+func NewResolveResult(name string, matches []Match) ResolveResult {
+	r := ResolveResult{Input: name, Status: ResolveUnmatched, Matches: matches}
+	switch {
+	case len(r.Matches) == 0:
+	case len(r.Matches) > 1 && r.Matches[1].Score == r.Matches[0].Score:
+		r.Status = ResolveAmbiguous
+	default:
+		r.Status = ResolveMatched
+		r.ID = r.Matches[0].ID
+	}
+	return r
+}
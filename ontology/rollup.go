@@ -0,0 +1,264 @@
+package ontology
+
+import "sort"
+
+// RollupBin is one grouping ancestor and the input terms mapped onto it.
+type RollupBin struct {
+	ID    string   `json:"id"`
+	Name  string   `json:"name,omitempty"`
+	Count int      `json:"count"`
+	Terms []string `json:"terms,omitempty"`
+}
+
+// RollupResult is the outcome of Index.Rollup. Bins are in the order they
+// were given. Unbinned holds known terms that fall under no bin; Excluded
+// holds those under IndexOptions.ExcludeBranches; Unknown holds inputs
+// that resolve to no term or, for names, to several.
+type RollupResult struct {
+	Bins     []RollupBin `json:"bins"`
+	Unbinned []string    `json:"unbinned,omitempty"`
+	Excluded []string    `json:"excluded,omitempty"`
+	Unknown  []string    `json:"unknown,omitempty"`
+}
+
+// Rollup maps each input ID onto the bins that are it or one of its is_a
+// ancestors. Inputs and bins are resolved with Lookup, so alt IDs, labels
+// and synonyms are accepted, and duplicate inputs are counted once. A term may land in several bins; with mostSpecific set,
+// bins that are ancestors of another matching bin are dropped for that term.
+func (ix *Index) Rollup(ids, bins []string, mostSpecific bool) *RollupResult {
+	res := &RollupResult{Bins: make([]RollupBin, len(bins))}
+	binIndex := make(map[string]int, len(bins))
+	for i, b := range bins {
+		id, err := ix.Lookup(b)
+		if err != nil {
+			id = b
+		}
+		res.Bins[i].ID = id
+		res.Bins[i].Name = ix.Label(id)
+		binIndex[id] = i
+	}
+
+	seen := make(map[string]bool, len(ids))
+	for _, raw := range ids {
+		id, err := ix.Lookup(raw)
+		if err != nil {
+			res.Unknown = append(res.Unknown, raw)
+			continue
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if ix.Excluded(id) {
+			res.Excluded = append(res.Excluded, id)
+			continue
+		}
+
+		var hits []int
+		if i, ok := binIndex[id]; ok {
+			hits = append(hits, i)
+		}
+		for _, a := range ix.includedAncestors(id) {
+			if i, ok := binIndex[a]; ok {
+				hits = append(hits, i)
+			}
+		}
+		if mostSpecific {
+			hits = ix.mostSpecificBins(res.Bins, hits)
+		}
+		if len(hits) == 0 {
+			res.Unbinned = append(res.Unbinned, id)
+			continue
+		}
+		for _, i := range hits {
+			res.Bins[i].Count++
+			res.Bins[i].Terms = append(res.Bins[i].Terms, id)
+		}
+	}
+	return res
+}
+
+// mostSpecificBins drops every hit that is an ancestor of another hit.
+func (ix *Index) mostSpecificBins(bins []RollupBin, hits []int) []int {
+	if len(hits) < 2 {
+		return hits
+	}
+	covered := make(map[string]bool)
+	for _, i := range hits {
+		for _, a := range ix.Ancestors(bins[i].ID) {
+			covered[a] = true
+		}
+	}
+	out := hits[:0]
+	for _, i := range hits {
+		if !covered[bins[i].ID] {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+// InformativeAncestors selects grouping ancestors for a set of terms, to
+// build a slim automatically. The result has at most maxBins bins, each an
+// is_a ancestor-or-self of at least minTerms of the inputs, none an
+// ancestor of another, and is as specific as those limits allow: starting
+// from the roots above the inputs, the bin covering the most inputs is
+// repeatedly replaced by its children that cover at least minTerms, as
+// long as no input loses its last bin and the total stays within maxBins.
+// Bins whose inputs all fall under other bins are dropped along the way.
+// If more than maxBins roots cover minTerms inputs, the largest are kept.
+// Inputs are resolved with Lookup; unknown ones, and those under
+// IndexOptions.ExcludeBranches, are ignored, and no bin is taken from
+// those branches. Bins are returned largest first, ties by ID.
+func (ix *Index) InformativeAncestors(ids []string, maxBins, minTerms int) []string {
+	minTerms = max(minTerms, 1)
+
+	// cover[a] lists the inputs a is an ancestor-or-self of.
+	cover := make(map[string][]int)
+	n := 0
+	seen := make(map[string]bool, len(ids))
+	for _, raw := range ids {
+		id, err := ix.Lookup(raw)
+		if err != nil || seen[id] || ix.Excluded(id) {
+			continue
+		}
+		seen[id] = true
+		cover[id] = append(cover[id], n)
+		for _, a := range ix.includedAncestors(id) {
+			cover[a] = append(cover[a], n)
+		}
+		n++
+	}
+	larger := func(bins []string) {
+		sort.Slice(bins, func(i, j int) bool {
+			if len(cover[bins[i]]) != len(cover[bins[j]]) {
+				return len(cover[bins[i]]) > len(cover[bins[j]])
+			}
+			return bins[i] < bins[j]
+		})
+	}
+
+	var bins []string
+	for a, in := range cover {
+		if len(in) >= minTerms && ix.topLevel(a) {
+			bins = append(bins, a)
+		}
+	}
+	larger(bins)
+	count := make([]int, n) // bins covering each input
+	for _, b := range bins {
+		for _, t := range cover[b] {
+			count[t]++
+		}
+	}
+	// prune drops bins, smallest first, whose inputs all have another bin.
+	prune := func() {
+		for i := len(bins) - 1; i >= 0; i-- {
+			redundant := true
+			for _, t := range cover[bins[i]] {
+				if count[t] < 2 {
+					redundant = false
+					break
+				}
+			}
+			if redundant {
+				for _, t := range cover[bins[i]] {
+					count[t]--
+				}
+				bins = append(bins[:i], bins[i+1:]...)
+			}
+		}
+	}
+	prune()
+	if len(bins) > maxBins {
+		for _, b := range bins[maxBins:] {
+			for _, t := range cover[b] {
+				count[t]--
+			}
+		}
+		bins = bins[:maxBins]
+	}
+
+	for {
+		split := false
+		for i, b := range bins {
+			others := make(map[string]bool) // the other bins
+			above := make(map[string]bool)  // and their ancestors
+			for j, o := range bins {
+				if j != i {
+					others[o] = true
+					for _, a := range ix.Ancestors(o) {
+						above[a] = true
+					}
+				}
+			}
+			// Children big enough that would not nest with another bin,
+			// without those under a sibling kept instead.
+			var kids []string
+			for _, c := range ix.Children(b) {
+				if len(cover[c]) >= minTerms && !others[c] && !above[c] && !ix.underAny(c, others) {
+					kids = append(kids, c)
+				}
+			}
+			kids = ix.dropNested(kids)
+			if len(kids) == 0 || len(bins)-1+len(kids) > maxBins {
+				continue
+			}
+			after := make(map[int]int)
+			for _, c := range kids {
+				for _, t := range cover[c] {
+					after[t]++
+				}
+			}
+			lossless := true
+			for _, t := range cover[b] {
+				if count[t]-1+after[t] == 0 {
+					lossless = false
+					break
+				}
+			}
+			if !lossless {
+				continue
+			}
+			for _, t := range cover[b] {
+				count[t]--
+			}
+			for t, k := range after {
+				count[t] += k
+			}
+			bins = append(append(bins[:i:i], bins[i+1:]...), kids...)
+			larger(bins)
+			prune()
+			split = true
+			break
+		}
+		if !split {
+			return bins
+		}
+	}
+}
+
+// underAny reports whether any is_a ancestor of id is in set.
+func (ix *Index) underAny(id string, set map[string]bool) bool {
+	for _, a := range ix.Ancestors(id) {
+		if set[a] {
+			return true
+		}
+	}
+	return false
+}
+
+// dropNested removes the IDs that have an ancestor in ids, keeping order.
+func (ix *Index) dropNested(ids []string) []string {
+	set := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	out := ids[:0]
+	for _, id := range ids {
+		if !ix.underAny(id, set) {
+			out = append(out, id)
+		}
+	}
+	return out
+}
@@ -0,0 +1,310 @@
+package ontology
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Rule derives relationship edges from existing ones, for example
+//
+//	X functionally_related_to Y if X has_role R and Y has_role R and X != Y
+//
+// Each atom is "subject relation object". Names containing a colon
+// (CHEBI:50906, owl:Thing) are term IDs; other names are variables. Every
+// variable of the head must occur in the body.
+type Rule struct {
+	Head     RuleAtom
+	Body     []RuleAtom
+	Distinct [][2]string // pairs of variables or IDs that must differ
+	Line     int
+	Text     string
+}
+
+// RuleAtom is one "subject relation object" pattern.
+type RuleAtom struct {
+	Subject, Relation, Object string
+}
+
+// RuleOptions configure ApplyRules.
+type RuleOptions struct {
+	// MaxEdges stops evaluation with an error once this many edges have
+	// been derived; 0 means unlimited. Rules joining on a hub such as a
+	// widely used role grow quadratically.
+	MaxEdges int
+}
+
+// RuleReport counts the edges each rule added, in rule order.
+type RuleReport struct {
+	Derived []int
+	Rounds  int
+}
+
+// Total returns the number of edges derived by all rules.
+func (r *RuleReport) Total() int {
+	n := 0
+	for _, d := range r.Derived {
+		n += d
+	}
+	return n
+}
+
+// ParseRules reads one rule per line. Blank lines and lines starting with
+// # are ignored.
+func ParseRules(r io.Reader) ([]Rule, error) {
+	var rules []Rule
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || text[0] == '#' {
+			continue
+		}
+		rule, err := parseRule(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rule.Line = line
+		rules = append(rules, *rule)
+	}
+	return rules, sc.Err()
+}
+
+func parseRule(text string) (*Rule, error) {
+	fields := strings.Fields(text)
+	if len(fields) < 7 || fields[3] != "if" {
+		return nil, fmt.Errorf("expected \"S relation O if S relation O [and ...]\"")
+	}
+	rule := &Rule{Head: RuleAtom{fields[0], fields[1], fields[2]}, Text: text}
+	rest := fields[4:]
+	for {
+		if len(rest) < 3 {
+			return nil, fmt.Errorf("incomplete condition %q", strings.Join(rest, " "))
+		}
+		if rest[1] == "!=" {
+			rule.Distinct = append(rule.Distinct, [2]string{rest[0], rest[2]})
+		} else {
+			rule.Body = append(rule.Body, RuleAtom{rest[0], rest[1], rest[2]})
+		}
+		rest = rest[3:]
+		if len(rest) == 0 {
+			break
+		}
+		if rest[0] != "and" {
+			return nil, fmt.Errorf("expected \"and\", found %q", rest[0])
+		}
+		rest = rest[1:]
+	}
+	if len(rule.Body) == 0 {
+		return nil, fmt.Errorf("rule has no relationship condition")
+	}
+
+	bound := make(map[string]bool)
+	for _, a := range rule.Body {
+		bound[a.Subject], bound[a.Object] = true, true
+	}
+	for _, v := range []string{rule.Head.Subject, rule.Head.Object} {
+		if isRuleVar(v) && !bound[v] {
+			return nil, fmt.Errorf("head variable %s does not occur in the conditions", v)
+		}
+	}
+	for _, d := range rule.Distinct {
+		for _, v := range d {
+			if isRuleVar(v) && !bound[v] {
+				return nil, fmt.Errorf("variable %s in != does not occur in the conditions", v)
+			}
+		}
+	}
+	return rule, nil
+}
+
+func isRuleVar(name string) bool {
+	return !strings.Contains(name, ":")
+}
+
+// ApplyRules evaluates the rules over the asserted relationships of the
+// non-obsolete terms (is_a included), to a fixpoint so rules may use each
+// other's edges, and adds every new edge to its subject term with the
+// qualifiers is_inferred="true" and source="rule:<line>". Edges whose
+// subject is not a term of the ontology are not added.
+func ApplyRules(ont *Ontology, rules []Rule, opts RuleOptions) (*RuleReport, error) {
+	terms := make(map[string]int, len(ont.Terms))
+	facts := make(map[string]*ruleTable)
+	for i := range ont.Terms {
+		t := &ont.Terms[i]
+		if t.IsObsolete {
+			continue
+		}
+		if _, ok := terms[t.ID]; !ok {
+			terms[t.ID] = i
+		}
+		for _, rel := range t.Relationships {
+			if !rel.Self {
+				ruleFacts(facts, rel.Type).add(t.ID, rel.TargetID)
+			}
+		}
+	}
+
+	rep := &RuleReport{Derived: make([]int, len(rules))}
+	total := 0
+	for {
+		rep.Rounds++
+		type edge struct {
+			rule int
+			s, o string
+		}
+		var derived []edge
+		pending := make(map[[3]string]bool)
+		for ri := range rules {
+			rule := &rules[ri]
+			head := facts[rule.Head.Relation]
+			evalRule(facts, rule, func(b map[string]string) {
+				s, o := ruleValue(b, rule.Head.Subject), ruleValue(b, rule.Head.Object)
+				key := [3]string{rule.Head.Relation, s, o}
+				if _, ok := terms[s]; !ok || pending[key] || head != nil && head.has(s, o) {
+					return
+				}
+				pending[key] = true
+				derived = append(derived, edge{ri, s, o})
+			})
+		}
+		if len(derived) == 0 {
+			return rep, nil
+		}
+		for _, e := range derived {
+			if total++; opts.MaxEdges > 0 && total > opts.MaxEdges {
+				return rep, fmt.Errorf("rules derived more than %d edges", opts.MaxEdges)
+			}
+			rule := &rules[e.rule]
+			ruleFacts(facts, rule.Head.Relation).add(e.s, e.o)
+			t := &ont.Terms[terms[e.s]]
+			t.Relationships = append(t.Relationships, Relationship{
+				Type:       rule.Head.Relation,
+				TargetID:   e.o,
+				Qualifiers: map[string]string{"is_inferred": "true", "source": fmt.Sprintf("rule:%d", rule.Line)},
+			})
+			rep.Derived[e.rule]++
+		}
+	}
+}
+
+// ruleTable holds the edges of one relation, indexed both ways.
+type ruleTable struct {
+	pairs     [][2]string
+	bySubject map[string][]string
+	byObject  map[string][]string
+	set       map[[2]string]bool
+}
+
+func ruleFacts(facts map[string]*ruleTable, rel string) *ruleTable {
+	tab := facts[rel]
+	if tab == nil {
+		tab = &ruleTable{
+			bySubject: make(map[string][]string),
+			byObject:  make(map[string][]string),
+			set:       make(map[[2]string]bool),
+		}
+		facts[rel] = tab
+	}
+	return tab
+}
+
+func (tab *ruleTable) add(s, o string) {
+	if tab.set[[2]string{s, o}] {
+		return
+	}
+	tab.set[[2]string{s, o}] = true
+	tab.pairs = append(tab.pairs, [2]string{s, o})
+	tab.bySubject[s] = append(tab.bySubject[s], o)
+	tab.byObject[o] = append(tab.byObject[o], s)
+}
+
+func (tab *ruleTable) has(s, o string) bool {
+	return tab.set[[2]string{s, o}]
+}
+
+// ruleValue returns the binding of a variable, or the ID itself.
+func ruleValue(b map[string]string, name string) string {
+	if isRuleVar(name) {
+		return b[name]
+	}
+	return name
+}
+
+// evalRule calls fn with every binding of the rule's variables that
+// satisfies its conditions. The body is matched atom by atom, depth
+// first, looking up bound ends in the relation indexes.
+func evalRule(facts map[string]*ruleTable, rule *Rule, fn func(map[string]string)) {
+	b := make(map[string]string)
+	var match func(i int)
+	match = func(i int) {
+		if i == len(rule.Body) {
+			for _, d := range rule.Distinct {
+				if ruleValue(b, d[0]) == ruleValue(b, d[1]) {
+					return
+				}
+			}
+			fn(b)
+			return
+		}
+		a := rule.Body[i]
+		tab := facts[a.Relation]
+		if tab == nil {
+			return
+		}
+		s, sBound := b[a.Subject], !isRuleVar(a.Subject)
+		if sBound {
+			s = a.Subject
+		} else {
+			_, sBound = b[a.Subject]
+		}
+		o, oBound := b[a.Object], !isRuleVar(a.Object)
+		if oBound {
+			o = a.Object
+		} else {
+			_, oBound = b[a.Object]
+		}
+		bind := func(s, o string) {
+			var set []string
+			if !sBound {
+				b[a.Subject] = s
+				set = append(set, a.Subject)
+			}
+			if !oBound {
+				if v, ok := b[a.Object]; ok && v != o { // X r X
+					for _, name := range set {
+						delete(b, name)
+					}
+					return
+				}
+				if _, ok := b[a.Object]; !ok {
+					b[a.Object] = o
+					set = append(set, a.Object)
+				}
+			}
+			match(i + 1)
+			for _, name := range set {
+				delete(b, name)
+			}
+		}
+		switch {
+		case sBound && oBound:
+			if tab.has(s, o) {
+				match(i + 1)
+			}
+		case sBound:
+			for _, o := range tab.bySubject[s] {
+				bind(s, o)
+			}
+		case oBound:
+			for _, s := range tab.byObject[o] {
+				bind(s, o)
+			}
+		default:
+			for _, p := range tab.pairs {
+				bind(p[0], p[1])
+			}
+		}
+	}
+	match(0)
+}
@@ -0,0 +1,156 @@
+package ontology
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"strconv"
+)
+
+// Stratification keys for SampleTerms.
+const (
+	StratifyNone      = ""
+	StratifyDepth     = "depth"     // shortest is_a distance from a root
+	StratifyNamespace = "namespace" // Term.Namespace
+	StratifySubset    = "subset"    // first subset, "" for none
+)
+
+// SampleOptions configures SampleTerms. The same options and ontology
+// always give the same sample.
+type SampleOptions struct {
+	Seed            uint64
+	Stratify        string // one of the Stratify* keys
+	Root            string // only sample Root and its is_a descendants
+	IncludeObsolete bool
+}
+
+// SampledTerm is one sampled term and the stratum it was drawn from.
+type SampledTerm struct {
+	ID      string `json:"id"`
+	Name    string `json:"name,omitempty"`
+	Stratum string `json:"stratum,omitempty"`
+}
+
+// SampleTerms draws n distinct terms at random. With stratification the
+// terms are grouped by the chosen key and n is split as evenly as the
+// strata allow: a stratum smaller than its share contributes all its
+// terms and the rest is spread over the others. The result is grouped by
+// stratum (depths in numeric order, other keys sorted), in draw order
+// within each stratum. It returns fewer than n terms if fewer qualify.
+func (ix *Index) SampleTerms(n int, opts SampleOptions) ([]SampledTerm, error) {
+	var key func(t *Term) string
+	switch opts.Stratify {
+	case StratifyNone:
+		key = func(*Term) string { return "" }
+	case StratifyNamespace:
+		key = func(t *Term) string { return t.Namespace }
+	case StratifySubset:
+		key = func(t *Term) string {
+			if len(t.Subsets) > 0 {
+				return t.Subsets[0]
+			}
+			return ""
+		}
+	case StratifyDepth:
+		depth := ix.depths()
+		key = func(t *Term) string { return strconv.Itoa(depth[t.ID]) }
+	default:
+		return nil, fmt.Errorf("unknown stratification %q (use depth, namespace or subset)", opts.Stratify)
+	}
+
+	var inRoot map[string]bool
+	if opts.Root != "" {
+		root := ix.Primary(opts.Root)
+		if root == "" {
+			return nil, fmt.Errorf("unknown root %q", opts.Root)
+		}
+		inRoot = ix.descendants(root)
+	}
+
+	strata := make(map[string][]string)
+	for i := range ix.ont.Terms {
+		t := &ix.ont.Terms[i]
+		if (t.IsObsolete && !opts.IncludeObsolete) || (inRoot != nil && !inRoot[t.ID]) {
+			continue
+		}
+		k := key(t)
+		strata[k] = append(strata[k], t.ID)
+	}
+	keys := make([]string, 0, len(strata))
+	for k := range strata {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if opts.Stratify == StratifyDepth {
+			a, _ := strconv.Atoi(keys[i])
+			b, _ := strconv.Atoi(keys[j])
+			return a < b
+		}
+		return keys[i] < keys[j]
+	})
+
+	// Fill the smallest strata first so their unused share moves on.
+	bySize := append([]string(nil), keys...)
+	sort.SliceStable(bySize, func(i, j int) bool { return len(strata[bySize[i]]) < len(strata[bySize[j]]) })
+	quota := make(map[string]int, len(keys))
+	left := n
+	for i, k := range bySize {
+		q := min(left/(len(bySize)-i), len(strata[k]))
+		quota[k] = q
+		left -= q
+	}
+
+	rng := rand.New(rand.NewPCG(opts.Seed, opts.Seed^0x9e3779b97f4a7c15))
+	var out []SampledTerm
+	for _, k := range keys {
+		ids := strata[k]
+		// Partial Fisher-Yates: the first quota[k] slots become the draw.
+		for i := 0; i < quota[k]; i++ {
+			j := i + rng.IntN(len(ids)-i)
+			ids[i], ids[j] = ids[j], ids[i]
+			out = append(out, SampledTerm{ID: ids[i], Name: ix.Term(ids[i]).Name, Stratum: k})
+		}
+	}
+	return out, nil
+}
+
+// depths returns each term's shortest is_a distance from a term without
+// is_a parents.
+func (ix *Index) depths() map[string]int {
+	depth := make(map[string]int, len(ix.ont.Terms))
+	var queue []string
+	for i := range ix.ont.Terms {
+		if id := ix.ont.Terms[i].ID; len(ix.Parents(id)) == 0 {
+			depth[id] = 0
+			queue = append(queue, id)
+		}
+	}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, k := range ix.Children(cur) {
+			if _, ok := depth[k]; !ok {
+				depth[k] = depth[cur] + 1
+				queue = append(queue, k)
+			}
+		}
+	}
+	return depth
+}
+
+// descendants returns root and all its asserted is_a descendants.
+func (ix *Index) descendants(root string) map[string]bool {
+	seen := map[string]bool{root: true}
+	stack := []string{root}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, k := range ix.Children(id) {
+			if !seen[k] {
+				seen[k] = true
+				stack = append(stack, k)
+			}
+		}
+	}
+	return seen
+}
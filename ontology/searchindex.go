@@ -0,0 +1,348 @@
+package ontology
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"sort"
+	"unicode/utf8"
+)
+
+// The search index behind Resolve is a flat little-endian image of sorted
+// tables, so it can be written to disk once and memory-mapped at startup
+// instead of being rebuilt:
+//
+//	magic      "CHEBISX1"
+//	fingerprint u64   (see searchFingerprint)
+//	entries     u32 count, then (term u32, synonym u32) pairs; synonym
+//	            0xFFFFFFFF marks a label
+//	ntokens     u16 per entry, padded to 4 bytes
+//	tables      exact, folded, tokens; each: n u32, n+1 key offsets u32,
+//	            n+1 value offsets u32, key bytes padded to 4, values u32
+//	lengths     u16 rune count per folded key
+//
+// Keys are sorted bytewise and looked up by binary search; values are
+// ascending entry numbers.
+const searchMagic = "CHEBISX1"
+
+const labelSyn = math.MaxUint32
+
+// ErrStaleSearchIndex is returned by UseSearchIndex when the image was
+// built from a different ontology.
+var ErrStaleSearchIndex = errors.New("search index does not match the ontology")
+
+// searchEntry is a label (syn < 0) or synonym of ont.Terms[term].
+type searchEntry struct {
+	term int32
+	syn  int32
+}
+
+// searchTable is one sorted key → entries table inside an image.
+type searchTable struct {
+	n      int
+	keyOff []byte
+	valOff []byte
+	keys   []byte
+	vals   []byte
+}
+
+func u32(b []byte, i int) int { return int(binary.LittleEndian.Uint32(b[4*i:])) }
+
+func (t *searchTable) key(i int) []byte {
+	return t.keys[u32(t.keyOff, i):u32(t.keyOff, i+1)]
+}
+
+func (t *searchTable) values(i int) []int32 {
+	lo, hi := u32(t.valOff, i), u32(t.valOff, i+1)
+	out := make([]int32, hi-lo)
+	for j := range out {
+		out[j] = int32(u32(t.vals, lo+j))
+	}
+	return out
+}
+
+func (t *searchTable) lookup(key string) []int32 {
+	kb := []byte(key)
+	i := sort.Search(t.n, func(i int) bool { return bytes.Compare(t.key(i), kb) >= 0 })
+	if i == t.n || !bytes.Equal(t.key(i), kb) {
+		return nil
+	}
+	return t.values(i)
+}
+
+// searchImage is a parsed image; it aliases the bytes it was parsed from.
+type searchImage struct {
+	fingerprint uint64
+	nEntries    int
+	entries     []byte
+	ntokens     []byte
+	exact       searchTable // normalizeRef(text)
+	folded      searchTable // foldName(text)
+	tokens      searchTable // TokenizeName token
+	foldedLens  []byte
+}
+
+func (img *searchImage) entry(e int32) searchEntry {
+	syn := int32(-1)
+	if s := binary.LittleEndian.Uint32(img.entries[8*int(e)+4:]); s != labelSyn {
+		syn = int32(s)
+	}
+	return searchEntry{term: int32(u32(img.entries, 2*int(e))), syn: syn}
+}
+
+func (img *searchImage) tokenCount(e int32) int {
+	return int(binary.LittleEndian.Uint16(img.ntokens[2*int(e):]))
+}
+
+func (img *searchImage) foldedLen(i int) int {
+	return int(binary.LittleEndian.Uint16(img.foldedLens[2*i:]))
+}
+
+// buildSearchImage indexes every label and synonym of ont.
+func buildSearchImage(ont *Ontology, fingerprint uint64) []byte {
+	var entries []searchEntry
+	var ntokens []uint16
+	exact := make(map[string][]int32, len(ont.Terms))
+	folded := make(map[string][]int32, len(ont.Terms))
+	postings := make(map[string][]int32)
+	add := func(text string, e searchEntry) {
+		n := int32(len(entries))
+		entries = append(entries, e)
+		toks := uniqueTokens(text)
+		ntokens = append(ntokens, uint16(min(len(toks), math.MaxUint16)))
+		for _, tok := range toks {
+			postings[tok] = append(postings[tok], n)
+		}
+		key := normalizeRef(text)
+		exact[key] = append(exact[key], n)
+		if f := foldName(text); f != "" {
+			folded[f] = append(folded[f], n)
+		}
+	}
+	for i := range ont.Terms {
+		t := &ont.Terms[i]
+		if t.Name != "" {
+			add(t.Name, searchEntry{term: int32(i), syn: -1})
+		}
+		for j := range t.Synonyms {
+			add(t.Synonyms[j].Text, searchEntry{term: int32(i), syn: int32(j)})
+		}
+	}
+
+	var buf bytes.Buffer
+	le := binary.LittleEndian
+	buf.WriteString(searchMagic)
+	buf.Write(le.AppendUint64(nil, fingerprint))
+	buf.Write(le.AppendUint32(nil, uint32(len(entries))))
+	for _, e := range entries {
+		syn := uint32(labelSyn)
+		if e.syn >= 0 {
+			syn = uint32(e.syn)
+		}
+		buf.Write(le.AppendUint32(le.AppendUint32(nil, uint32(e.term)), syn))
+	}
+	for _, n := range ntokens {
+		buf.Write(le.AppendUint16(nil, n))
+	}
+	pad4(&buf)
+	writeSearchTable(&buf, exact)
+	foldedKeys := writeSearchTable(&buf, folded)
+	writeSearchTable(&buf, postings)
+	for _, k := range foldedKeys {
+		buf.Write(le.AppendUint16(nil, uint16(min(utf8.RuneCountInString(k), math.MaxUint16))))
+	}
+	return buf.Bytes()
+}
+
+// writeSearchTable appends m as a table and returns its keys in order.
+func writeSearchTable(buf *bytes.Buffer, m map[string][]int32) []string {
+	le := binary.LittleEndian
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	buf.Write(le.AppendUint32(nil, uint32(len(keys))))
+	off := 0
+	for _, k := range keys {
+		buf.Write(le.AppendUint32(nil, uint32(off)))
+		off += len(k)
+	}
+	buf.Write(le.AppendUint32(nil, uint32(off)))
+	off = 0
+	for _, k := range keys {
+		buf.Write(le.AppendUint32(nil, uint32(off)))
+		off += len(m[k])
+	}
+	buf.Write(le.AppendUint32(nil, uint32(off)))
+	for _, k := range keys {
+		buf.WriteString(k)
+	}
+	pad4(buf)
+	for _, k := range keys {
+		for _, v := range m[k] {
+			buf.Write(le.AppendUint32(nil, uint32(v)))
+		}
+	}
+	return keys
+}
+
+func pad4(buf *bytes.Buffer) {
+	for buf.Len()%4 != 0 {
+		buf.WriteByte(0)
+	}
+}
+
+// parseSearchImage checks an image's layout and returns a view of it.
+func parseSearchImage(data []byte) (*searchImage, error) {
+	r := imageReader{data: data}
+	if string(r.take(len(searchMagic))) != searchMagic {
+		return nil, errors.New("not a search index file")
+	}
+	img := &searchImage{}
+	img.fingerprint = binary.LittleEndian.Uint64(r.take(8))
+	img.nEntries = r.count()
+	img.entries = r.take(8 * img.nEntries)
+	img.ntokens = r.take(2 * img.nEntries)
+	r.align()
+	for _, t := range []*searchTable{&img.exact, &img.folded, &img.tokens} {
+		t.n = r.count()
+		t.keyOff = r.take(4 * (t.n + 1))
+		t.valOff = r.take(4 * (t.n + 1))
+		if r.err != nil {
+			return nil, r.err
+		}
+		t.keys = r.take(u32(t.keyOff, t.n))
+		r.align()
+		t.vals = r.take(4 * u32(t.valOff, t.n))
+		if r.err != nil {
+			return nil, r.err
+		}
+		if err := t.check(img.nEntries); err != nil {
+			return nil, err
+		}
+	}
+	img.foldedLens = r.take(2 * img.folded.n)
+	if r.err != nil {
+		return nil, r.err
+	}
+	return img, nil
+}
+
+// check verifies that t's key and value offsets start at 0, never
+// decrease and end at the length of their section, and that its values
+// are entry numbers below nEntries, so lookups cannot slice out of range.
+func (t *searchTable) check(nEntries int) error {
+	for _, offs := range []struct {
+		name string
+		off  []byte
+		end  int
+	}{{"key", t.keyOff, len(t.keys)}, {"value", t.valOff, len(t.vals) / 4}} {
+		prev := 0
+		for i := 0; i <= t.n; i++ {
+			o := u32(offs.off, i)
+			if o < prev || i == 0 && o != 0 {
+				return fmt.Errorf("search index: bad %s offset %d at %d", offs.name, o, i)
+			}
+			prev = o
+		}
+		if prev != offs.end {
+			return fmt.Errorf("search index: %s offsets end at %d, not %d", offs.name, prev, offs.end)
+		}
+	}
+	for i := 0; i < len(t.vals)/4; i++ {
+		if e := u32(t.vals, i); e >= nEntries {
+			return fmt.Errorf("search index: entry %d out of range (%d entries)", e, nEntries)
+		}
+	}
+	return nil
+}
+
+// checkEntries verifies that every entry names a term of ont and, for a
+// synonym, one of that term's synonyms. The fingerprint matching does not
+// rule out a hand-edited image.
+func (img *searchImage) checkEntries(ont *Ontology) error {
+	for e := 0; e < img.nEntries; e++ {
+		ent := img.entry(int32(e))
+		if ent.term < 0 || int(ent.term) >= len(ont.Terms) {
+			return fmt.Errorf("search index: entry %d names term %d of %d", e, ent.term, len(ont.Terms))
+		}
+		if ent.syn >= 0 && int(ent.syn) >= len(ont.Terms[ent.term].Synonyms) {
+			return fmt.Errorf("search index: entry %d names synonym %d of %s", e, ent.syn, ont.Terms[ent.term].ID)
+		}
+	}
+	return nil
+}
+
+// imageReader slices sections off an image. After the first short read it
+// records an error and returns zeroed slices of at most 8 bytes, enough
+// for the fixed-size reads that follow before the caller checks err.
+type imageReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (r *imageReader) take(n int) []byte {
+	if r.err != nil || n < 0 || n > len(r.data)-r.pos {
+		if r.err == nil {
+			r.err = fmt.Errorf("search index truncated at byte %d", r.pos)
+		}
+		return make([]byte, 8)
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *imageReader) count() int {
+	n := int(binary.LittleEndian.Uint32(r.take(4)))
+	if r.err != nil {
+		return 0
+	}
+	return n
+}
+
+func (r *imageReader) align() {
+	if rem := r.pos % 4; rem != 0 {
+		r.take(4 - rem)
+	}
+}
+
+// searchFingerprint hashes what the image's entry numbers refer to: term
+// IDs, labels and synonym texts in ontology order.
+func (ix *Index) searchFingerprint() uint64 {
+	h := fnv.New64a()
+	for i := range ix.ont.Terms {
+		t := &ix.ont.Terms[i]
+		io.WriteString(h, t.ID)
+		h.Write([]byte{0})
+		io.WriteString(h, t.Name)
+		for j := range t.Synonyms {
+			h.Write([]byte{0})
+			io.WriteString(h, t.Synonyms[j].Text)
+		}
+		h.Write([]byte{1})
+	}
+	return h.Sum64()
+}
+
+// buildSearch builds the search index in memory on first use.
+func (ix *Index) buildSearch() {
+	img, err := parseSearchImage(buildSearchImage(ix.ont, ix.searchFingerprint()))
+	if err != nil {
+		panic("ontology: malformed search index: " + err.Error())
+	}
+	ix.search.img = img
+}
+
+// WriteSearchIndex writes the index's search tables as an image that
+// OpenSearchIndex can map later.
+func (ix *Index) WriteSearchIndex(w io.Writer) error {
+	_, err := w.Write(buildSearchImage(ix.ont, ix.searchFingerprint()))
+	return err
+}
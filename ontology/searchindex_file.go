@@ -0,0 +1,75 @@
+//go:build !js
+
+package ontology
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+)
+
+// SearchIndexFile is a search index image opened with OpenSearchIndex. It
+// must stay open while an Index uses it.
+type SearchIndexFile struct {
+	data  []byte
+	unmap func() error
+}
+
+// OpenSearchIndex maps a file written by WriteSearchIndex into memory (or
+// reads it where mapping is unavailable).
+func OpenSearchIndex(path string) (*SearchIndexFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if st.Size() == 0 || st.Size() > math.MaxInt32 {
+		return nil, fmt.Errorf("%s: not a search index file", path)
+	}
+	data, unmap, err := mapFile(f, int(st.Size()))
+	if err != nil {
+		return nil, err
+	}
+	return &SearchIndexFile{data: data, unmap: unmap}, nil
+}
+
+// Close unmaps the file.
+func (f *SearchIndexFile) Close() error {
+	if f.unmap == nil {
+		return nil
+	}
+	err := f.unmap()
+	f.unmap, f.data = nil, nil
+	return err
+}
+
+// UseSearchIndex makes Resolve answer from an image opened with
+// OpenSearchIndex instead of building one. It must be called before the
+// first Resolve, and returns ErrStaleSearchIndex if the image was built
+// from a different ontology.
+func (ix *Index) UseSearchIndex(f *SearchIndexFile) error {
+	img, err := parseSearchImage(f.data)
+	if err != nil {
+		return err
+	}
+	if img.fingerprint != ix.searchFingerprint() {
+		return ErrStaleSearchIndex
+	}
+	if err := img.checkEntries(ix.ont); err != nil {
+		return err
+	}
+	used := false
+	ix.search.once.Do(func() {
+		ix.search.img = img
+		used = true
+	})
+	if !used {
+		return errors.New("search index already built")
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package ontology
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func sampleSearchImage(t *testing.T) (*Index, []byte) {
+	t.Helper()
+	f, err := os.Open("../testdata/sample.obo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	ont, err := ParseOBO(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ix := NewIndex(ont)
+	var buf bytes.Buffer
+	if err := ix.WriteSearchIndex(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return ix, buf.Bytes()
+}
+
+// useImage writes data to a file and installs it in a fresh index over
+// ix's ontology.
+func useImage(t *testing.T, ix *Index, data []byte) error {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "sample.idx")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := OpenSearchIndex(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return NewIndex(ix.ont).UseSearchIndex(f)
+}
+
+func TestSearchIndexRoundTrip(t *testing.T) {
+	ix, data := sampleSearchImage(t)
+	if err := useImage(t, ix, data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// A truncated or scrambled image is refused with an error; it must never
+// be installed and panic in a later lookup.
+func TestSearchIndexCorrupt(t *testing.T) {
+	ix, data := sampleSearchImage(t)
+	le := binary.LittleEndian
+	nEntries := int(le.Uint32(data[16:]))
+	entries := 20
+	exact := entries + 8*nEntries + 2*nEntries
+	exact += (4 - exact%4) % 4
+	nExact := int(le.Uint32(data[exact:]))
+	keyOff := exact + 4
+	valOff := keyOff + 4*(nExact+1)
+	keysLen := int(le.Uint32(data[valOff-4:]))
+	vals := valOff + 4*(nExact+1) + keysLen + pad(keysLen)
+	if nExact < 2 {
+		t.Fatalf("sample image has %d exact keys, want at least 2", nExact)
+	}
+
+	edit := func(off int, v uint32) []byte {
+		b := bytes.Clone(data)
+		le.PutUint32(b[off:], v)
+		return b
+	}
+	for _, c := range []struct {
+		name string
+		data []byte
+	}{
+		{"truncated", data[:len(data)/2]},
+		{"missing magic", append([]byte("CHEBISX0"), data[8:]...)},
+		{"first key offset not 0", edit(keyOff, 1)},
+		{"decreasing key offsets", edit(keyOff+4, 1<<30)},
+		{"decreasing value offsets", edit(valOff+4, 1<<30)},
+		{"entry number out of range", edit(vals, uint32(nEntries))},
+		{"term number out of range", edit(entries, uint32(len(ix.ont.Terms)))},
+	} {
+		if err := useImage(t, ix, c.data); err == nil {
+			t.Errorf("%s: UseSearchIndex accepted the image", c.name)
+		} else {
+			t.Logf("%s: %v", c.name, err)
+		}
+	}
+}
+
+// pad returns the padding that aligns n bytes to 4.
+func pad(n int) int { return (4 - n%4) % 4 }
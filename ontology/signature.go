@@ -0,0 +1,169 @@
+package ontology
+
+import (
+	"sort"
+	"strings"
+)
+
+// SignatureReport is the signature of an ontology, the entities it uses:
+// the classes, relations and individuals it defines or mentions in its
+// axioms. Xrefs are database cross-references, not entities, and are left
+// out.
+type SignatureReport struct {
+	Classes     []string `json:"classes"`               // IRIs, sorted
+	Relations   []string `json:"relations"`             // IDs, sorted; is_a is built in and left out
+	Individuals []string `json:"individuals,omitempty"` // IDs, sorted
+	// External maps each prefix the ontology defines no term under to the
+	// IDs it references with that prefix, for an import catalog.
+	External map[string][]string `json:"external,omitempty"`
+
+	// referenced holds the IDs used but not defined, for Missing.
+	referenced map[string]sigKind
+}
+
+type sigKind uint8
+
+const (
+	sigClass sigKind = iota
+	sigRelation
+	sigIndividual
+)
+
+// Signature returns the signature of ont.
+func Signature(ont *Ontology) *SignatureReport {
+	short := ShortName(ont)
+	defined := make(map[string]bool, len(ont.Terms)+len(ont.TypeDefs)+len(ont.Individuals))
+	own := make(map[string]bool)
+	for i := range ont.Terms {
+		defined[ont.Terms[i].ID] = true
+		if prefix, _, ok := strings.Cut(ont.Terms[i].ID, ":"); ok {
+			own[prefix] = true
+		}
+	}
+	for i := range ont.TypeDefs {
+		defined[ont.TypeDefs[i].ID] = true
+	}
+	for i := range ont.Individuals {
+		defined[ont.Individuals[i].ID] = true
+	}
+
+	used := make(map[string]sigKind, len(defined))
+	use := func(id string, k sigKind) {
+		if id != "" && id != "is_a" {
+			used[id] = k
+		}
+	}
+	for i := range ont.Terms {
+		t := &ont.Terms[i]
+		use(t.ID, sigClass)
+		for _, rel := range t.Relationships {
+			use(rel.Type, sigRelation)
+			switch {
+			case rel.HasValue:
+				use(rel.TargetID, sigIndividual)
+			case !rel.Self:
+				use(rel.TargetID, sigClass)
+			}
+		}
+		for _, p := range t.IntersectionOf {
+			use(p.Relationship, sigRelation)
+			use(p.TargetID, sigClass)
+		}
+		for _, ids := range [][]string{t.UnionOf, t.ReplacedBy, t.Consider} {
+			for _, id := range ids {
+				use(id, sigClass)
+			}
+		}
+		for _, id := range t.OneOf {
+			use(id, sigIndividual)
+		}
+	}
+	for i := range ont.TypeDefs {
+		td := &ont.TypeDefs[i]
+		use(td.ID, sigRelation)
+		use(td.InverseOf, sigRelation)
+		for _, chain := range td.HoldsOverChain {
+			for _, r := range strings.Fields(chain) {
+				use(r, sigRelation)
+			}
+		}
+	}
+	for i := range ont.Individuals {
+		ind := &ont.Individuals[i]
+		use(ind.ID, sigIndividual)
+		for _, c := range ind.Types {
+			use(c, sigClass)
+		}
+	}
+
+	s := &SignatureReport{referenced: make(map[string]sigKind)}
+	for id, k := range used {
+		switch k {
+		case sigClass:
+			s.Classes = append(s.Classes, idIRI(id, short))
+		case sigRelation:
+			s.Relations = append(s.Relations, id)
+		case sigIndividual:
+			s.Individuals = append(s.Individuals, id)
+		}
+		if defined[id] {
+			continue
+		}
+		s.referenced[id] = k
+		if prefix, _, ok := strings.Cut(id, ":"); ok && !own[prefix] && !strings.Contains(id, "://") {
+			if s.External == nil {
+				s.External = make(map[string][]string)
+			}
+			s.External[prefix] = append(s.External[prefix], id)
+		}
+	}
+	sort.Strings(s.Classes)
+	sort.Strings(s.Relations)
+	sort.Strings(s.Individuals)
+	for _, ids := range s.External {
+		sort.Strings(ids)
+	}
+	return s
+}
+
+// Missing returns the IDs the ontology references without defining that
+// target does not define either, sorted: for an extension ontology, the
+// entities it expects from a ChEBI release that the release lacks. A class
+// only found obsolete in target, or under an alt ID of an obsolete term,
+// counts as missing.
+func (s *SignatureReport) Missing(target *Ontology) []string {
+	ix := NewIndex(target)
+	// Relations are known from typedefs and from relationships using
+	// them, since OBO files need not declare every relation.
+	relations := make(map[string]bool, len(target.TypeDefs))
+	for i := range target.TypeDefs {
+		relations[target.TypeDefs[i].ID] = true
+	}
+	for i := range target.Terms {
+		for _, rel := range target.Terms[i].Relationships {
+			relations[rel.Type] = true
+		}
+	}
+	individuals := make(map[string]bool, len(target.Individuals))
+	for i := range target.Individuals {
+		individuals[target.Individuals[i].ID] = true
+	}
+	var out []string
+	for id, k := range s.referenced {
+		var ok bool
+		switch k {
+		case sigClass:
+			t := ix.Term(ix.Primary(id))
+			ok = t != nil && !t.IsObsolete
+		case sigRelation:
+			ok = relations[id]
+		case sigIndividual:
+			ok = individuals[id]
+		}
+		if !ok {
+			out = append(out, id)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,125 @@
+package ontology
+
+import (
+	"bufio"
+	"io"
+)
+
+const nsSKOS = "http://www.w3.org/2004/02/skos/core#"
+
+// WriteSKOS writes the live terms as a SKOS concept scheme in Turtle, for
+// taxonomy tools that ingest SKOS rather than OWL. Each term is a
+// skos:Concept with its label as skos:prefLabel, its synonyms as
+// skos:altLabel, its definition and ID (skos:notation), and is_a as
+// skos:broader and skos:narrower between live terms; terms with no live
+// parent are the scheme's top concepts. Xrefs become skos:exactMatch to
+// the URLs in the term's Links, or else from DefaultLinkTemplates; xrefs
+// with no URL template are left out, as are other relationships.
+func WriteSKOS(ont *Ontology, w io.Writer) error {
+	return WriteSKOSWithOptions(ont, w, SKOSOptions{})
+}
+
+// SKOSOptions configure WriteSKOSWithOptions.
+type SKOSOptions struct {
+	// Obsolete terms are left out unless this is ObsoleteInclude. They
+	// are then written as concepts marked owl:deprecated, linked by
+	// skos:broader and skos:narrower as asserted but never top concepts.
+	Obsolete ObsoletePolicy
+}
+
+// WriteSKOSWithOptions is WriteSKOS with an obsolete-term policy.
+func WriteSKOSWithOptions(ont *Ontology, w io.Writer, opts SKOSOptions) error {
+	bw := bufio.NewWriterSize(w, writerBufferSize)
+	keepObsolete := opts.Obsolete.Keep(false)
+	short := ShortName(ont)
+	prefixes := []struct{ prefix, ns string }{
+		{"dcterms", nsDCTerms},
+		{"obo", nsOBO},
+		{"rdf", nsRDF},
+		{"rdfs", nsRDFS},
+		{"skos", nsSKOS},
+	}
+	if keepObsolete {
+		prefixes = append(prefixes, struct{ prefix, ns string }{"owl", nsOWL}, struct{ prefix, ns string }{"xsd", nsXSD})
+	}
+	tw := &turtleWriter{w: bw, prefixes: make(map[string]string, len(prefixes))}
+	for _, p := range prefixes {
+		tw.prefixes[p.ns] = p.prefix
+		bw.WriteString("@prefix " + p.prefix + ": <" + p.ns + "> .\n")
+	}
+
+	ix := NewIndex(ont)
+	live := func(id string) bool {
+		t := ix.Term(id)
+		return t != nil && !t.IsObsolete
+	}
+	// inScheme is whether a term is written as a concept.
+	inScheme := func(id string) bool {
+		t := ix.Term(id)
+		return t != nil && (keepObsolete || !t.IsObsolete)
+	}
+	topConcept := func(t *Term) bool {
+		for _, rel := range t.Relationships {
+			if rel.Type == "is_a" && live(rel.TargetID) {
+				return false
+			}
+		}
+		return true
+	}
+	scheme, _ := ontologyIRI(ont)
+	top := &rdfNode{iri: scheme, types: []string{nsSKOS + "ConceptScheme"}}
+	top.addLit(nsRDFS+"label", ont.Ontology)
+	addMetadataRDF(top, ont.Metadata)
+	for i := range ont.Terms {
+		if t := &ont.Terms[i]; !t.IsObsolete && topConcept(t) {
+			top.add(nsSKOS+"hasTopConcept", rdfIRI(idIRI(t.ID, short)))
+		}
+	}
+	tw.node(top)
+
+	for i := range ont.Terms {
+		t := &ont.Terms[i]
+		if t.IsObsolete && !keepObsolete {
+			continue
+		}
+		n := &rdfNode{iri: idIRI(t.ID, short), types: []string{nsSKOS + "Concept"}}
+		n.add(nsSKOS+"inScheme", rdfIRI(scheme))
+		if t.IsObsolete {
+			n.add(nsOWL+"deprecated", rdfTyped("true", nsXSD+"boolean"))
+		} else if topConcept(t) {
+			n.add(nsSKOS+"topConceptOf", rdfIRI(scheme))
+		}
+		n.addLit(nsSKOS+"prefLabel", t.Name)
+		// SKOS labels are disjoint and a concept's altLabels distinct.
+		labels := map[string]bool{t.Name: true}
+		for _, s := range t.Synonyms {
+			if !labels[s.Text] {
+				labels[s.Text] = true
+				n.addLit(nsSKOS+"altLabel", s.Text)
+			}
+		}
+		n.addLit(nsSKOS+"definition", t.Definition)
+		n.addLit(nsSKOS+"notation", t.ID)
+		for _, rel := range t.Relationships {
+			if rel.Type == "is_a" && inScheme(rel.TargetID) {
+				n.add(nsSKOS+"broader", rdfIRI(idIRI(rel.TargetID, short)))
+			}
+		}
+		for _, c := range ix.Children(t.ID) {
+			if inScheme(c) {
+				n.add(nsSKOS+"narrower", rdfIRI(idIRI(c, short)))
+			}
+		}
+		links := t.Links
+		if links == nil {
+			links = DefaultLinkTemplates.Resolve(t)
+		}
+		if links != nil {
+			for _, x := range links.Xrefs {
+				n.add(nsSKOS+"exactMatch", rdfIRI(x.URL))
+			}
+		}
+		tw.node(n)
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,94 @@
+package ontology
+
+import (
+	"sort"
+	"strings"
+)
+
+// Sort puts ont in a canonical order so that serializations are byte-stable
+// across runs and releases diff cleanly: terms, typedefs and individuals by
+// ID, and every list inside them by value. Relationships are ordered is_a
+// first, then by type, target and filler kind; intersection_of puts the
+// genus first. Property chains keep their internal order, since it is
+// meaningful, but the list of chains is sorted. Maps need no sorting as
+// every encoder writes their keys in order. It modifies ont in place.
+func Sort(ont *Ontology) {
+	sort.SliceStable(ont.Terms, func(i, j int) bool { return ont.Terms[i].ID < ont.Terms[j].ID })
+	for i := range ont.Terms {
+		sortTerm(&ont.Terms[i])
+	}
+	sort.SliceStable(ont.TypeDefs, func(i, j int) bool { return ont.TypeDefs[i].ID < ont.TypeDefs[j].ID })
+	for i := range ont.TypeDefs {
+		sort.Strings(ont.TypeDefs[i].HoldsOverChain)
+	}
+	sort.SliceStable(ont.Individuals, func(i, j int) bool { return ont.Individuals[i].ID < ont.Individuals[j].ID })
+	for i := range ont.Individuals {
+		sort.Strings(ont.Individuals[i].Types)
+	}
+}
+
+func sortTerm(t *Term) {
+	for _, list := range [][]string{t.ReplacedBy, t.Consider, t.Subsets, t.Xrefs, t.AltIDs, t.UnionOf, t.OneOf} {
+		sort.Strings(list)
+	}
+
+	for i := range t.Synonyms {
+		sort.Strings(t.Synonyms[i].Xrefs)
+	}
+	sort.SliceStable(t.Synonyms, func(i, j int) bool {
+		a, b := &t.Synonyms[i], &t.Synonyms[j]
+		if a.Text != b.Text {
+			return a.Text < b.Text
+		}
+		if a.Scope != b.Scope {
+			return a.Scope < b.Scope
+		}
+		return a.Type < b.Type
+	})
+
+	sort.SliceStable(t.Relationships, func(i, j int) bool {
+		a, b := &t.Relationships[i], &t.Relationships[j]
+		if (a.Type == "is_a") != (b.Type == "is_a") {
+			return a.Type == "is_a"
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if ka, kb := relationshipSortKey(a), relationshipSortKey(b); ka != kb {
+			return ka < kb
+		}
+		return qualifierKey(a.Qualifiers) < qualifierKey(b.Qualifiers)
+	})
+
+	sort.SliceStable(t.IntersectionOf, func(i, j int) bool {
+		a, b := &t.IntersectionOf[i], &t.IntersectionOf[j]
+		if a.Relationship != b.Relationship {
+			return a.Relationship < b.Relationship // "" (genus) first
+		}
+		return fillerKey(a.TargetID, false, a.Self) < fillerKey(b.TargetID, false, b.Self)
+	})
+
+	if t.Links != nil {
+		sort.SliceStable(t.Links.Xrefs, func(i, j int) bool {
+			a, b := t.Links.Xrefs[i], t.Links.Xrefs[j]
+			if a.Xref != b.Xref {
+				return a.Xref < b.Xref
+			}
+			return a.URL < b.URL
+		})
+	}
+}
+
+// relationshipSortKey orders relationships of one type by target, then
+// filler kind and cardinality.
+func relationshipSortKey(rel *Relationship) string {
+	var b strings.Builder
+	b.WriteString(rel.TargetID)
+	b.WriteByte(0)
+	b.WriteString(fillerKey(rel.TargetID, rel.HasValue, rel.Self))
+	if rel.Cardinality != nil {
+		b.WriteByte(0)
+		b.WriteString(rel.Cardinality.String())
+	}
+	return b.String()
+}
@@ -0,0 +1,245 @@
+package ontology
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// ParseOptions configure ParseOBOWithOptions and ParseOWLWithOptions.
+type ParseOptions struct {
+	// MaxMemory is a heap budget in bytes; 0 means unlimited. Once the heap
+	// grows past it, the bodies of the terms parsed so far and of every
+	// later term are moved to Bodies, keeping only the structural graph
+	// (IDs, names, namespaces, relationships, logical definitions) in RAM.
+	MaxMemory int64
+	// Bodies receives spilled term bodies. Parsing never spills if it is nil.
+	Bodies *BodyStore
+	// Warn, if set, receives each problem the parser recovers from (see
+	// Warning), so callers can count them and set their own thresholds.
+	Warn func(Warning)
+	// IDPrefix is the term ID prefix not reported as
+	// WarnForeignNamespace; "CHEBI" if empty.
+	IDPrefix string
+	// Obsolete terms are kept unless this is ObsoleteExclude, in which
+	// case they are dropped as they are parsed.
+	Obsolete ObsoletePolicy
+	// Charset says what the OBO parser does with lines that are not
+	// valid UTF-8: report them (the default) or transcode them.
+	Charset CharsetPolicy
+	// Synonyms says whether duplicate synonyms and synonyms repeating
+	// the name are dropped as terms are parsed.
+	Synonyms SynonymPolicy
+}
+
+// ParseByteSize parses a size such as 100MB, 1.5GB, 512KB or 1048576, as
+// given to -max-memory and -chunk-size. Units are powers of 1024.
+func ParseByteSize(s string) (int64, error) {
+	num := strings.ToUpper(strings.TrimSpace(s))
+	mult := 1.0
+	for _, u := range []struct {
+		suffix string
+		mult   float64
+	}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}, {"B", 1}} {
+		if strings.HasSuffix(num, u.suffix) {
+			num, mult = strings.TrimSpace(strings.TrimSuffix(num, u.suffix)), u.mult
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(n * mult), nil
+}
+
+// budgetCheckInterval is how many terms are parsed between heap checks.
+// Reading the heap size stops the world briefly, so it is not done per term.
+const budgetCheckInterval = 5000
+
+// memoryBudget enforces ParseOptions.MaxMemory as terms are appended.
+type memoryBudget struct {
+	opts     ParseOptions
+	spilling bool
+	since    int
+	err      error
+}
+
+// added is called after each term is appended to ont.Terms.
+func (b *memoryBudget) added(ont *Ontology) {
+	if b.opts.MaxMemory <= 0 || b.opts.Bodies == nil || b.err != nil {
+		return
+	}
+	if b.spilling {
+		b.err = b.opts.Bodies.Spill(ont, len(ont.Terms)-1)
+		return
+	}
+	if b.since++; b.since < budgetCheckInterval {
+		return
+	}
+	b.since = 0
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	if int64(ms.HeapAlloc) < b.opts.MaxMemory {
+		return
+	}
+	b.spilling = true
+	b.err = b.opts.Bodies.SpillAll(ont)
+	runtime.GC()
+}
+
+// BodyStore is a temporary on-disk store of term bodies (definition,
+// comment, synonyms, xrefs, properties, links), for ontologies that do not
+// fit in memory whole. Bodies are written in the msgpack term encoding.
+//
+// Bodies are keyed by the term's position in Ontology.Terms, not its ID, so
+// stanzas sharing an ID each get their own body back. The terms must not be
+// reordered or removed between Spill and Fill; restore them first.
+type BodyStore struct {
+	f     bodyFile
+	out   *countingWriter // counts bytes the buffered writer has flushed to f
+	mw    msgpackWriter
+	index []bodyRef // by term position; n == 0 if not spilled
+	count int
+
+	// Reused by Fill.
+	buf []byte
+	rec bytes.Reader
+	mr  msgpackReader
+}
+
+// bodyFile is the file a BodyStore spills to, an *os.File created by
+// NewBodyStore.
+type bodyFile interface {
+	io.Writer
+	io.ReaderAt
+	io.Closer
+	Name() string
+}
+
+type bodyRef struct {
+	off int64
+	n   int64
+}
+
+// Len returns the number of spilled term bodies.
+func (s *BodyStore) Len() int { return s.count }
+
+// Size returns the number of bytes spilled to disk.
+func (s *BodyStore) Size() int64 { return s.out.n + int64(s.mw.w.Buffered()) }
+
+// Spilled reports whether the body of the term at position i is on disk.
+func (s *BodyStore) Spilled(i int) bool {
+	return i < len(s.index) && s.index[i].n > 0
+}
+
+// Spill writes the body of ont.Terms[i] to disk and clears it from the
+// term. A term already spilled is left as it is.
+func (s *BodyStore) Spill(ont *Ontology, i int) error {
+	if s.Spilled(i) {
+		return nil
+	}
+	t := &ont.Terms[i]
+	body := Term{
+		ID:             t.ID,
+		Definition:     t.Definition,
+		Comment:        t.Comment,
+		Synonyms:       t.Synonyms,
+		Xrefs:          t.Xrefs,
+		Properties:     t.Properties,
+		PropertyTypes:  t.PropertyTypes,
+		XrefQualifiers: t.XrefQualifiers,
+		Links:          t.Links,
+	}
+	off := s.Size()
+	s.mw.term(&body)
+	if s.mw.err != nil {
+		return s.mw.err
+	}
+	if i >= len(s.index) {
+		s.index = append(s.index, make([]bodyRef, i+1-len(s.index))...)
+	}
+	s.index[i] = bodyRef{off: off, n: s.Size() - off}
+	s.count++
+
+	t.Definition, t.Comment = "", ""
+	t.Synonyms, t.Xrefs = nil, nil
+	t.Properties, t.PropertyTypes, t.XrefQualifiers = nil, nil, nil
+	t.Links = nil
+	return nil
+}
+
+// SpillAll spills the body of every term in ont.
+func (s *BodyStore) SpillAll(ont *Ontology) error {
+	for i := range ont.Terms {
+		if err := s.Spill(ont, i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Fill reads the body spilled from the term at position i back into t,
+// which is that term or a copy of it. The store keeps its copy, so a copy
+// can be filled for output without growing the in-memory ontology. t is
+// left unchanged if term i was never spilled.
+func (s *BodyStore) Fill(i int, t *Term) error {
+	if !s.Spilled(i) {
+		return nil
+	}
+	ref := s.index[i]
+	if ref.off+ref.n > s.out.n {
+		if err := s.mw.w.Flush(); err != nil {
+			return err
+		}
+	}
+	if int64(cap(s.buf)) < ref.n {
+		s.buf = make([]byte, ref.n)
+	}
+	s.buf = s.buf[:ref.n]
+	if _, err := s.f.ReadAt(s.buf, ref.off); err != nil {
+		return err
+	}
+	s.rec.Reset(s.buf)
+	if s.mr.r == nil {
+		s.mr.r = bufio.NewReader(&s.rec)
+	} else {
+		s.mr.r.Reset(&s.rec)
+	}
+	s.mr.err = nil
+	var body Term
+	s.mr.term(&body)
+	if s.mr.err != nil {
+		return s.mr.err
+	}
+	t.Definition, t.Comment = body.Definition, body.Comment
+	t.Synonyms, t.Xrefs = body.Synonyms, body.Xrefs
+	t.Properties, t.PropertyTypes, t.XrefQualifiers = body.Properties, body.PropertyTypes, body.XrefQualifiers
+	t.Links = body.Links
+	return nil
+}
+
+// RestoreAll fills every spilled term of ont back in memory.
+func (s *BodyStore) RestoreAll(ont *Ontology) error {
+	for i := range ont.Terms {
+		if err := s.Fill(i, &ont.Terms[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
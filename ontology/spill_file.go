@@ -0,0 +1,29 @@
+//go:build !js
+
+package ontology
+
+import (
+	"bufio"
+	"os"
+)
+
+// NewBodyStore creates a store in a new temporary file in dir (the system
+// temporary directory if dir is ""). Close removes the file.
+func NewBodyStore(dir string) (*BodyStore, error) {
+	f, err := os.CreateTemp(dir, "chebi-bodies-*")
+	if err != nil {
+		return nil, err
+	}
+	s := &BodyStore{f: f, out: &countingWriter{w: f}}
+	s.mw.w = bufio.NewWriterSize(s.out, writerBufferSize)
+	return s, nil
+}
+
+// Close closes and removes the store's file.
+func (s *BodyStore) Close() error {
+	err := s.f.Close()
+	if rerr := os.Remove(s.f.Name()); err == nil {
+		err = rerr
+	}
+	return err
+}
@@ -0,0 +1,74 @@
+package ontology
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const duplicateStanzas = `format-version: 1.4
+
+[Term]
+id: CHEBI:1
+name: first
+def: "First stanza." []
+synonym: "one" EXACT []
+
+[Term]
+id: CHEBI:1
+name: second
+def: "Second stanza." []
+synonym: "two" EXACT []
+`
+
+// Stanzas sharing an ID each keep their own body through spilling, both
+// when streamed by WriteJSONWithBodies and when restored.
+func TestBodyStoreDuplicateIDs(t *testing.T) {
+	ont, err := ParseOBO(strings.NewReader(duplicateStanzas))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ont.Terms) != 2 {
+		t.Fatalf("parsed %d terms, want 2", len(ont.Terms))
+	}
+	bodies, err := NewBodyStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bodies.Close()
+	if err := bodies.SpillAll(ont); err != nil {
+		t.Fatal(err)
+	}
+	if bodies.Len() != 2 {
+		t.Fatalf("spilled %d bodies, want 2", bodies.Len())
+	}
+	if ont.Terms[1].Definition != "" {
+		t.Fatalf("second stanza kept its definition in memory")
+	}
+
+	check := func(how string, terms []Term) {
+		t.Helper()
+		for i, want := range []struct{ def, syn string }{{"First stanza.", "one"}, {"Second stanza.", "two"}} {
+			got := terms[i]
+			if got.Definition != want.def || len(got.Synonyms) != 1 || got.Synonyms[0].Text != want.syn {
+				t.Errorf("%s: term %d has definition %q, synonyms %v; want %q, %q", how, i, got.Definition, got.Synonyms, want.def, want.syn)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := WriteJSONWithBodies(ont, bodies, &buf); err != nil {
+		t.Fatal(err)
+	}
+	var streamed Ontology
+	if err := json.Unmarshal(buf.Bytes(), &streamed); err != nil {
+		t.Fatal(err)
+	}
+	check("WriteJSONWithBodies", streamed.Terms)
+
+	if err := bodies.RestoreAll(ont); err != nil {
+		t.Fatal(err)
+	}
+	check("RestoreAll", ont.Terms)
+}
@@ -0,0 +1,272 @@
+package ontology
+
+import (
+	"sort"
+	"strings"
+)
+
+// SplitPart is one slice of a split ontology. Key is safe to use in a file
+// name ("chemical_entity", "role"). Ontology shares the source's header,
+// typedefs and individuals; its terms are shallow copies.
+type SplitPart struct {
+	Key      string
+	Ontology *Ontology
+}
+
+// SplitByNamespace groups terms by namespace, in order of first
+// appearance. Terms without a namespace go to the part keyed "other".
+func SplitByNamespace(ont *Ontology) []SplitPart {
+	var parts []SplitPart
+	pos := make(map[string]int)
+	for i := range ont.Terms {
+		ns := ont.Terms[i].Namespace
+		if ns == "" {
+			ns = "other"
+		}
+		j, ok := pos[ns]
+		if !ok {
+			j = len(parts)
+			pos[ns] = j
+			parts = append(parts, SplitPart{Key: splitKey(ns), Ontology: splitShell(ont)})
+		}
+		parts[j].Ontology.Terms = append(parts[j].Ontology.Terms, ont.Terms[i])
+	}
+	return parts
+}
+
+// SplitBySubtree groups terms by the asserted is_a subtree they belong to.
+// With root "" the subtrees are those of the non-obsolete terms without
+// is_a parents (chemical entity, role, subatomic particle in ChEBI);
+// otherwise they are those of root's children. A term under several
+// subtrees is written to each. Terms under none, such as obsolete terms,
+// go to the part keyed "other". Parts are keyed by the subtree root's
+// Label and ordered by key.
+func (ix *Index) SplitBySubtree(root string) []SplitPart {
+	var tops []string
+	if root == "" {
+		for i := range ix.ont.Terms {
+			t := &ix.ont.Terms[i]
+			if !t.IsObsolete && len(ix.Parents(t.ID)) == 0 {
+				tops = append(tops, t.ID)
+			}
+		}
+	} else {
+		tops = ix.Children(ix.Primary(root))
+	}
+
+	var parts []SplitPart
+	keys := make(map[string]bool)
+	member := make(map[string][]int, len(ix.ont.Terms))
+	for _, top := range tops {
+		key := top
+		if label := ix.Label(top); label != "" {
+			key = label
+		}
+		key = splitKey(key)
+		if keys[key] {
+			key += "_" + splitKey(top)
+		}
+		keys[key] = true
+		for id := range ix.descendants(top) {
+			member[id] = append(member[id], len(parts))
+		}
+		parts = append(parts, SplitPart{Key: key, Ontology: splitShell(ix.ont)})
+	}
+
+	var other *Ontology
+	for i := range ix.ont.Terms {
+		t := &ix.ont.Terms[i]
+		in := member[t.ID]
+		if len(in) == 0 {
+			if other == nil {
+				other = splitShell(ix.ont)
+			}
+			other.Terms = append(other.Terms, *t)
+			continue
+		}
+		for _, j := range in {
+			parts[j].Ontology.Terms = append(parts[j].Ontology.Terms, *t)
+		}
+	}
+	sort.SliceStable(parts, func(i, j int) bool { return parts[i].Key < parts[j].Key })
+	if other != nil {
+		parts = append(parts, SplitPart{Key: "other", Ontology: other})
+	}
+	return parts
+}
+
+// PartitionNamespaces groups namespaces into independent parts: two
+// namespaces share a part when a non-obsolete term of one references
+// (is_a, relationship, intersection_of, union_of, one_of) a term, an
+// individual or an undeclared ID that the other also reaches. Each part
+// holds its terms and the individuals it references, and no part refers
+// to another part's terms, so the parts can be classified separately and
+// their hierarchies concatenated. The rare constructs that relate every
+// class, ∃R.owl:Thing fillers and ∃R.Self in intersection_of, make the
+// whole ontology one part. Parts are in order of first term and keyed by
+// their namespaces joined with "+".
+func PartitionNamespaces(ont *Ontology) []SplitPart {
+	uf := newUnionFind()
+	termNS := make(map[string]string, len(ont.Terms))
+	for i := range ont.Terms {
+		termNS[ont.Terms[i].ID] = ont.Terms[i].Namespace
+	}
+	node := func(id string) int {
+		if ns, ok := termNS[id]; ok {
+			return uf.node("ns:" + ns)
+		}
+		return uf.node("id:" + id)
+	}
+	for i := range ont.Individuals {
+		ind := &ont.Individuals[i]
+		n := node(ind.ID)
+		for _, typ := range ind.Types {
+			uf.union(n, node(typ))
+		}
+	}
+	for i := range ont.Terms {
+		t := &ont.Terms[i]
+		n := node(t.ID)
+		if t.IsObsolete {
+			continue
+		}
+		refs := append(append([]string(nil), t.UnionOf...), t.OneOf...)
+		for _, ax := range t.NonEL {
+			refs = append(refs, ax.Targets...)
+		}
+		for _, rel := range t.Relationships {
+			if !rel.Self {
+				refs = append(refs, rel.TargetID)
+			}
+		}
+		for _, part := range t.IntersectionOf {
+			if part.Self {
+				return []SplitPart{{Key: "all", Ontology: ont}}
+			}
+			refs = append(refs, part.TargetID)
+		}
+		for _, ref := range refs {
+			if ref == "owl:Thing" {
+				return []SplitPart{{Key: "all", Ontology: ont}}
+			}
+			uf.union(n, node(ref))
+		}
+	}
+
+	var parts []SplitPart
+	var names [][]string
+	pos := make(map[int]int)
+	seenNS := make(map[string]bool)
+	partOf := func(n int) int {
+		r := uf.find(n)
+		j, ok := pos[r]
+		if !ok {
+			j = len(parts)
+			pos[r] = j
+			parts = append(parts, SplitPart{Ontology: splitShell(ont)})
+			parts[j].Ontology.Individuals = nil
+			names = append(names, nil)
+		}
+		return j
+	}
+	for i := range ont.Terms {
+		t := &ont.Terms[i]
+		j := partOf(node(t.ID))
+		parts[j].Ontology.Terms = append(parts[j].Ontology.Terms, *t)
+		if !seenNS[t.Namespace] {
+			seenNS[t.Namespace] = true
+			ns := t.Namespace
+			if ns == "" {
+				ns = "other"
+			}
+			names[j] = append(names[j], splitKey(ns))
+		}
+	}
+	for i := range ont.Individuals {
+		ind := ont.Individuals[i]
+		j, ok := pos[uf.find(node(ind.ID))]
+		if !ok { // referencing no term and referenced by none
+			if len(parts) == 0 {
+				partOf(node(ind.ID))
+			}
+			j = 0
+		}
+		parts[j].Ontology.Individuals = append(parts[j].Ontology.Individuals, ind)
+	}
+	for j := range parts {
+		parts[j].Key = strings.Join(names[j], "+")
+		if parts[j].Key == "" {
+			parts[j].Key = "other"
+		}
+	}
+	return parts
+}
+
+// unionFind is a disjoint-set forest over string keys.
+type unionFind struct {
+	ids    map[string]int
+	parent []int
+}
+
+func newUnionFind() *unionFind {
+	return &unionFind{ids: make(map[string]int)}
+}
+
+func (uf *unionFind) node(key string) int {
+	n, ok := uf.ids[key]
+	if !ok {
+		n = len(uf.parent)
+		uf.ids[key] = n
+		uf.parent = append(uf.parent, n)
+	}
+	return n
+}
+
+func (uf *unionFind) find(n int) int {
+	for uf.parent[n] != n {
+		uf.parent[n] = uf.parent[uf.parent[n]]
+		n = uf.parent[n]
+	}
+	return n
+}
+
+func (uf *unionFind) union(a, b int) {
+	if ra, rb := uf.find(a), uf.find(b); ra != rb {
+		uf.parent[rb] = ra
+	}
+}
+
+// splitShell returns an ontology with ont's header, typedefs and
+// individuals and no terms.
+func splitShell(ont *Ontology) *Ontology {
+	return &Ontology{
+		FormatVersion: ont.FormatVersion,
+		DataVersion:   ont.DataVersion,
+		Ontology:      ont.Ontology,
+		Metadata:      ont.Metadata,
+		TypeDefs:      ont.TypeDefs,
+		Individuals:   ont.Individuals,
+	}
+}
+
+// splitKey lower-cases s and replaces every run of characters other than
+// letters and digits with a single '_'.
+func splitKey(s string) string {
+	var b strings.Builder
+	sep := false
+	for _, r := range strings.ToLower(s) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			if sep && b.Len() > 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r)
+			sep = false
+			continue
+		}
+		sep = true
+	}
+	if b.Len() == 0 {
+		return "other"
+	}
+	return b.String()
+}
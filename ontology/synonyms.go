@@ -0,0 +1,109 @@
+package ontology
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// SynonymPolicy says whether the parsers clean up synonyms that are noise
+// to dictionary consumers: repeats of one synonym that differ only in
+// whitespace (or case), and synonyms that just repeat the term's name.
+// Exporters and indexes see what was parsed, so the policy reaches them
+// all. Structure synonyms (SMILES, InChI, InChIKey) are compared exactly
+// whatever the policy, since their case is meaningful.
+type SynonymPolicy string
+
+const (
+	// SynonymsKeep keeps every synonym as the source has it.
+	SynonymsKeep SynonymPolicy = ""
+	// SynonymsCollapse treats synonyms of the same type whose texts differ
+	// only in whitespace as one, drops synonyms equal to the name, and
+	// trims and single-spaces the rest.
+	SynonymsCollapse SynonymPolicy = "collapse"
+	// SynonymsFold is SynonymsCollapse ignoring case too, so "Aspirin" and
+	// "aspirin" are one synonym.
+	SynonymsFold SynonymPolicy = "fold"
+)
+
+// ParseSynonymPolicy reads a -synonyms flag value: keep (or ""), collapse
+// or fold.
+func ParseSynonymPolicy(s string) (SynonymPolicy, error) {
+	switch p := SynonymPolicy(s); p {
+	case SynonymsKeep, SynonymsCollapse, SynonymsFold:
+		return p, nil
+	case "keep":
+		return SynonymsKeep, nil
+	}
+	return "", fmt.Errorf("invalid synonym policy %q (want keep, collapse or fold)", s)
+}
+
+// CollapseSynonyms applies p to every term of ont in place and returns the
+// number of synonyms dropped.
+func CollapseSynonyms(ont *Ontology, p SynonymPolicy) int {
+	if p == SynonymsKeep {
+		return 0
+	}
+	n := 0
+	for i := range ont.Terms {
+		n += p.apply(&ont.Terms[i])
+	}
+	return n
+}
+
+// apply cleans up t's synonyms under p and returns how many it dropped. Of
+// synonyms that are the same under p the first is kept, in the strongest
+// scope among them (EXACT before the rest) and with all their xrefs. Kept
+// texts have their whitespace runs made single spaces.
+func (p SynonymPolicy) apply(t *Term) int {
+	if p == SynonymsKeep || len(t.Synonyms) == 0 {
+		return 0
+	}
+	name := p.normalize(t.Name, false)
+	pos := make(map[string]int, len(t.Synonyms))
+	syns := t.Synonyms[:0]
+	for _, syn := range t.Synonyms {
+		structure := structureSynonymTypes[strings.ToUpper(syn.Type)]
+		text := p.normalize(syn.Text, structure)
+		if text == name && !structure {
+			continue
+		}
+		if !structure {
+			syn.Text = strings.Join(strings.Fields(syn.Text), " ")
+		}
+		key := text + "\x00" + syn.Type
+		j, ok := pos[key]
+		if !ok {
+			pos[key] = len(syns)
+			syns = append(syns, syn)
+			continue
+		}
+		kept := &syns[j]
+		if syn.Scope == "EXACT" {
+			kept.Scope = "EXACT"
+		}
+		for _, x := range syn.Xrefs {
+			if !slices.Contains(kept.Xrefs, x) {
+				kept.Xrefs = append(kept.Xrefs, x)
+			}
+		}
+	}
+	n := len(t.Synonyms) - len(syns)
+	clear(t.Synonyms[len(syns):])
+	t.Synonyms = syns
+	return n
+}
+
+// normalize returns the text that synonyms are compared by under p:
+// whitespace runs as one space, lower-cased under SynonymsFold unless exact
+// is set.
+func (p SynonymPolicy) normalize(s string, exact bool) string {
+	if exact {
+		return s
+	}
+	s = strings.Join(strings.Fields(s), " ")
+	if p == SynonymsFold {
+		s = strings.ToLower(s)
+	}
+	return s
+}
@@ -0,0 +1,138 @@
+package ontology
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"text/template"
+)
+
+// TemplateTerm is the data a term template is executed with: the term's
+// fields, such as {{.ID}} and {{.Synonyms}}, and methods for what a
+// template cannot easily work out itself.
+type TemplateTerm struct {
+	*Term
+	ix *Index
+}
+
+// Chem returns a chemistry property ("formula", "charge", "mass",
+// "monoisotopicmass", "inchikey", "smiles") under whichever key the
+// release uses, as written there, or "". Pipe a charge to the charge
+// function for one consistent form.
+func (t TemplateTerm) Chem(name string) string {
+	v, _ := chemProperty(t.Term, name)
+	return v
+}
+
+// Prop returns the property with the given key, or with a key ending in
+// "/key" or "#key", so {{.Prop "charge"}} finds a full IRI key. It returns
+// "" if there is none.
+func (t TemplateTerm) Prop(key string) string {
+	if v, ok := t.Properties[key]; ok {
+		return v
+	}
+	for k, v := range t.Properties {
+		if strings.HasSuffix(k, "/"+key) || strings.HasSuffix(k, "#"+key) {
+			return v
+		}
+	}
+	return ""
+}
+
+// Parents returns the IDs of the term's asserted is_a parents.
+func (t TemplateTerm) Parents() []string {
+	return t.Related("is_a")
+}
+
+// Related returns the targets of the term's relationships of one type.
+func (t TemplateTerm) Related(relType string) []string {
+	var out []string
+	for _, rel := range t.Relationships {
+		if rel.Type == relType {
+			out = append(out, rel.TargetID)
+		}
+	}
+	return out
+}
+
+// Label returns the name of another term, or id if it has none.
+func (t TemplateTerm) Label(id string) string {
+	if u := t.ix.Term(id); u != nil && u.Name != "" {
+		return u.Name
+	}
+	return id
+}
+
+// SynonymTexts returns the text of the term's synonyms with the given
+// scope (EXACT, RELATED, ...), or of all of them if scope is "".
+func (t TemplateTerm) SynonymTexts(scope string) []string {
+	var out []string
+	for _, s := range t.Synonyms {
+		if scope == "" || s.Scope == scope {
+			out = append(out, s.Text)
+		}
+	}
+	return out
+}
+
+// templateFuncs are the functions term templates may call besides the
+// text/template builtins. Their last argument is the piped value, so
+// {{.Xrefs | join ", "}} works.
+var templateFuncs = template.FuncMap{
+	"join":    func(sep string, s []string) string { return strings.Join(s, sep) },
+	"upper":   strings.ToUpper,
+	"lower":   strings.ToLower,
+	"trim":    strings.TrimSpace,
+	"replace": func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	"default": func(def, s string) string {
+		if s == "" {
+			return def
+		}
+		return s
+	},
+	// charge and chargeLabel normalize a charge value to "+1" or "1+"
+	// form; values that do not parse pass through.
+	"charge": NormalizeCharge,
+	"chargeLabel": func(s string) string {
+		if q, err := ParseCharge(s); err == nil {
+			return ChargeLabel(q)
+		}
+		return s
+	},
+	// tsv makes a value safe for one TSV cell.
+	"tsv": func(s string) string {
+		return strings.NewReplacer("\t", " ", "\r\n", " ", "\n", " ", "\r", " ").Replace(s)
+	},
+}
+
+// ParseTemplate parses a term template. Besides the template itself,
+// written once per term with a TemplateTerm, the text may define "header"
+// and "footer" templates, written once before and after the terms with
+// the Ontology.
+func ParseTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+}
+
+// WriteTemplate writes tmpl once for every term, in order and obsolete
+// terms included (test {{.IsObsolete}} to leave them out), between the
+// header and footer if the template defines them.
+func WriteTemplate(ont *Ontology, tmpl *template.Template, w io.Writer) error {
+	bw := bufio.NewWriterSize(w, writerBufferSize)
+	if h := tmpl.Lookup("header"); h != nil {
+		if err := h.Execute(bw, ont); err != nil {
+			return err
+		}
+	}
+	ix := NewIndex(ont)
+	for i := range ont.Terms {
+		if err := tmpl.Execute(bw, TemplateTerm{Term: &ont.Terms[i], ix: ix}); err != nil {
+			return err
+		}
+	}
+	if f := tmpl.Lookup("footer"); f != nil {
+		if err := f.Execute(bw, ont); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,106 @@
+package ontology
+
+import (
+	"strings"
+	"unicode"
+)
+
+// TokenizeName splits a chemical name into search tokens without stemming.
+// It splits on whitespace, hyphens, commas and brackets, so locants become
+// tokens of their own ("1,2-diol" → "1", "2", "diol"), but keeps
+// parenthesized stereo-descriptors and charges whole and case-sensitive:
+//
+//	"(S)-2-aminobutanoate"   → "(S)", "2", "aminobutanoate"
+//	"(2R,3S)-tartrate(2-)"   → "(2R,3S)", "tartrate", "(2-)"
+//	"α-D-glucose"            → "alpha", "d", "glucose"
+//
+// Every other token is lower-cased, with Greek letters spelled out as in
+// foldName; "+" stays attached to its word so "Na+" is one token.
+func TokenizeName(s string) []string {
+	var toks []string
+	var word strings.Builder
+	flush := func() {
+		if word.Len() == 0 {
+			return
+		}
+		toks = append(toks, strings.ToLower(word.String()))
+		word.Reset()
+	}
+
+	rs := []rune(s)
+	for i := 0; i < len(rs); i++ {
+		r := rs[i]
+		if r == '(' {
+			if j := indexRune(rs[i+1:], ')'); j >= 0 {
+				if inner := string(rs[i+1 : i+1+j]); stereoOrCharge(inner) {
+					flush()
+					toks = append(toks, "("+inner+")")
+					i += j + 1
+					continue
+				}
+			}
+		}
+		switch {
+		case greekLetters[r] != "":
+			word.WriteString(greekLetters[r])
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '\'' || r == '′' || r == '+':
+			word.WriteRune(r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return toks
+}
+
+func indexRune(rs []rune, r rune) int {
+	for i, c := range rs {
+		if c == r {
+			return i
+		}
+	}
+	return -1
+}
+
+// stereoOrCharge reports whether the text inside a pair of parentheses is
+// a stereo-descriptor list such as "S", "2R,3S", "4aR", "E", "RS" or "±",
+// or a charge such as "1+" or "2-".
+func stereoOrCharge(s string) bool {
+	switch s {
+	case "+", "-", "±", "+/-", "RS", "SR":
+		return true
+	}
+	if s == "" {
+		return false
+	}
+	// Charge: optional digits then a sign.
+	if last := s[len(s)-1]; last == '+' || last == '-' {
+		return strings.Trim(s[:len(s)-1], "0123456789") == ""
+	}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		switch {
+		case strings.HasSuffix(part, "RS"), strings.HasSuffix(part, "SR"):
+			part = part[:len(part)-2]
+		case part != "" && strings.ContainsRune("RSEZrsMP*", rune(part[len(part)-1])):
+			part = part[:len(part)-1]
+		default:
+			return false
+		}
+		if !isLocant(part) {
+			return false
+		}
+	}
+	return true
+}
+
+// isLocant reports whether s is empty or a locant such as "2", "4a" or
+// "3'".
+func isLocant(s string) bool {
+	s = strings.TrimRight(s, "'′")
+	digits := strings.TrimLeft(s, "0123456789")
+	if len(digits) == len(s) {
+		return s == ""
+	}
+	return digits == "" || len(digits) == 1 && digits[0] >= 'a' && digits[0] <= 'z'
+}
@@ -0,0 +1,72 @@
+package ontology
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+)
+
+// TreeNode is one node of a nested tree view of the is_a hierarchy. Terms
+// with several parents appear once under each parent, so the tree can be
+// handed directly to d3.hierarchy or ELK.js.
+type TreeNode struct {
+	ID       string      `json:"id"`
+	Name     string      `json:"name,omitempty"`
+	Children []*TreeNode `json:"children,omitempty"`
+
+	// Cycle is set on a node whose ID already appears on the path from the
+	// root; its children are not expanded.
+	Cycle bool `json:"cycle,omitempty"`
+
+	// Truncated is set when the node has children that were cut off by the
+	// depth limit.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// Tree builds a nested tree of is_a descendants rooted at rootID. maxDepth
+// limits the number of levels below the root; 0 means unlimited. Returns
+// nil if rootID is not a term in the index.
+func (ix *Index) Tree(rootID string, maxDepth int) *TreeNode {
+	root := ix.Term(rootID)
+	if root == nil {
+		return nil
+	}
+	onPath := make(map[string]bool)
+	return ix.treeNode(root.ID, 0, maxDepth, onPath)
+}
+
+func (ix *Index) treeNode(id string, depth, maxDepth int, onPath map[string]bool) *TreeNode {
+	node := &TreeNode{ID: id}
+	node.Name = ix.Label(id)
+	if onPath[id] {
+		node.Cycle = true
+		return node
+	}
+	kids := ix.Children(id)
+	if len(kids) == 0 {
+		return node
+	}
+	if maxDepth > 0 && depth >= maxDepth {
+		node.Truncated = true
+		return node
+	}
+
+	onPath[id] = true
+	node.Children = make([]*TreeNode, 0, len(kids))
+	for _, child := range kids {
+		node.Children = append(node.Children, ix.treeNode(child, depth+1, maxDepth, onPath))
+	}
+	delete(onPath, id)
+	return node
+}
+
+// WriteTreeJSON writes a tree as JSON.
+func WriteTreeJSON(node *TreeNode, w io.Writer) error {
+	bw := bufio.NewWriterSize(w, writerBufferSize)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(node); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
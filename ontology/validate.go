@@ -0,0 +1,78 @@
+package ontology
+
+// ID validation statuses reported by Index.Validate.
+const (
+	IDValid     = "valid"
+	IDUnknown   = "unknown"
+	IDObsolete  = "obsolete"
+	IDAltID     = "alt_id"
+	IDName      = "name"      // a label or synonym of a live term
+	IDAmbiguous = "ambiguous" // a label or synonym of several terms
+)
+
+// IDCheck is the result of validating one identifier against a release.
+type IDCheck struct {
+	ID         string   `json:"id"`
+	Status     string   `json:"status"`
+	Primary    string   `json:"primary,omitempty"`     // primary ID for alt IDs
+	Replaced   []string `json:"replaced_by,omitempty"` // replacements for obsolete terms
+	Consider   []string `json:"consider,omitempty"`
+	Ancestor   string   `json:"ancestor,omitempty"`   // nearest non-obsolete ancestor
+	Candidates []string `json:"candidates,omitempty"` // terms an ambiguous name matches
+}
+
+// Validate classifies id as valid, unknown, obsolete or an alt ID. An alt
+// ID of an obsolete term is reported as obsolete with Primary set. Labels
+// and synonyms are resolved with Lookup: a name of a live term is reported
+// as IDName with Primary set, one matching several terms as IDAmbiguous
+// with Candidates. If
+// ancestor is true, obsolete terms also get the nearest non-obsolete
+// is_a ancestor, falling back to the first live replaced_by target.
+func (ix *Index) Validate(id string, ancestor bool) IDCheck {
+	c := IDCheck{ID: id, Status: IDValid}
+	primary := ix.Primary(id)
+	if primary == "" {
+		var err error
+		primary, err = ix.Lookup(id)
+		if re, ok := err.(*RefError); ok {
+			c.Status = IDUnknown
+			if len(re.Candidates) > 0 {
+				c.Status = IDAmbiguous
+				c.Candidates = re.Candidates
+			}
+			return c
+		}
+		c.Status = IDName
+		c.Primary = primary
+	} else if primary != id {
+		c.Status = IDAltID
+		c.Primary = primary
+	}
+	t := ix.Term(primary)
+	if !t.IsObsolete {
+		return c
+	}
+	c.Status = IDObsolete
+	c.Replaced = t.ReplacedBy
+	c.Consider = t.Consider
+	if ancestor {
+		c.Ancestor = ix.liveAncestor(primary)
+	}
+	return c
+}
+
+// liveAncestor returns the nearest non-obsolete is_a ancestor of id, or
+// the first non-obsolete replaced_by target if it has none.
+func (ix *Index) liveAncestor(id string) string {
+	for _, a := range ix.Ancestors(id) {
+		if t := ix.Term(a); t != nil && !t.IsObsolete {
+			return a
+		}
+	}
+	for _, r := range ix.Term(id).ReplacedBy {
+		if t := ix.Term(ix.Primary(r)); t != nil && !t.IsObsolete {
+			return t.ID
+		}
+	}
+	return ""
+}
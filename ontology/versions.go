@@ -0,0 +1,274 @@
+package ontology
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// VersionedStore holds several releases of an ontology and answers
+// cross-version questions about individual terms.
+type VersionedStore struct {
+	versions []string // in the order added, oldest first
+	indexes  map[string]*Index
+}
+
+// NewVersionedStore returns an empty store.
+func NewVersionedStore() *VersionedStore {
+	return &VersionedStore{indexes: make(map[string]*Index)}
+}
+
+// Add registers a release. Releases should be added oldest first; History
+// reports changes in that order. Adding an existing version replaces it.
+func (vs *VersionedStore) Add(version string, ont *Ontology) {
+	if _, ok := vs.indexes[version]; !ok {
+		vs.versions = append(vs.versions, version)
+	}
+	vs.indexes[version] = NewIndex(ont)
+}
+
+// Versions returns the registered versions, oldest first.
+func (vs *VersionedStore) Versions() []string {
+	return vs.versions
+}
+
+// Index returns the index for a version, or nil.
+func (vs *VersionedStore) Index(version string) *Index {
+	return vs.indexes[version]
+}
+
+// Lookup returns the term as it was in the given version, resolving alt
+// IDs. It returns nil if the version is unknown or the term is absent.
+func (vs *VersionedStore) Lookup(version, id string) *Term {
+	ix := vs.indexes[version]
+	if ix == nil {
+		return nil
+	}
+	return ix.Term(ix.Primary(id))
+}
+
+// FieldChange describes how one field of a term differs between two
+// versions. Scalar fields set Old and New; list fields set Added and
+// Removed. The pseudo-field "exists" records a term appearing or
+// disappearing.
+type FieldChange struct {
+	From    string   `json:"from"`
+	To      string   `json:"to"`
+	Field   string   `json:"field"`
+	Old     string   `json:"old,omitempty"`
+	New     string   `json:"new,omitempty"`
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// Compare reports the per-field differences of a term between two
+// versions. It returns nil if either version is unknown.
+func (vs *VersionedStore) Compare(id, from, to string) []FieldChange {
+	if vs.indexes[from] == nil || vs.indexes[to] == nil {
+		return nil
+	}
+	return diffTerms(from, to, vs.Lookup(from, id), vs.Lookup(to, id))
+}
+
+// History reports the changes to a term between each pair of consecutive
+// versions, oldest first.
+func (vs *VersionedStore) History(id string) []FieldChange {
+	var out []FieldChange
+	for i := 1; i < len(vs.versions); i++ {
+		out = append(out, vs.Compare(id, vs.versions[i-1], vs.versions[i])...)
+	}
+	return out
+}
+
+// ReleaseChanges summarizes the differences between two releases.
+type ReleaseChanges struct {
+	Added     int            `json:"added"`     // terms only in the new release
+	Removed   int            `json:"removed"`   // terms only in the old release
+	Obsoleted int            `json:"obsoleted"` // terms that became obsolete
+	Changed   int            `json:"changed"`   // terms in both with any field changed
+	Fields    map[string]int `json:"fields"`    // changed terms per field
+}
+
+// CompareReleases counts the terms added, removed, obsoleted and changed
+// from old to new, matching terms by primary ID.
+func CompareReleases(old, new *Ontology) ReleaseChanges {
+	c := ReleaseChanges{Fields: make(map[string]int)}
+	before := make(map[string]*Term, len(old.Terms))
+	for i := range old.Terms {
+		before[old.Terms[i].ID] = &old.Terms[i]
+	}
+	seen := make(map[string]bool, len(new.Terms))
+	for i := range new.Terms {
+		b := &new.Terms[i]
+		if seen[b.ID] {
+			continue
+		}
+		seen[b.ID] = true
+		a := before[b.ID]
+		if a == nil {
+			c.Added++
+			continue
+		}
+		if b.IsObsolete && !a.IsObsolete {
+			c.Obsoleted++
+		}
+		changes := diffTerms("", "", a, b)
+		if len(changes) > 0 {
+			c.Changed++
+		}
+		for _, ch := range changes {
+			c.Fields[ch.Field]++
+		}
+	}
+	for id := range before {
+		if !seen[id] {
+			c.Removed++
+		}
+	}
+	return c
+}
+
+func diffTerms(from, to string, a, b *Term) []FieldChange {
+	if a == nil && b == nil {
+		return nil
+	}
+	if a == nil || b == nil {
+		return []FieldChange{{
+			From: from, To: to, Field: "exists",
+			Old: strconv.FormatBool(a != nil), New: strconv.FormatBool(b != nil),
+		}}
+	}
+
+	var out []FieldChange
+	scalar := func(field, old, new string) {
+		if old != new {
+			out = append(out, FieldChange{From: from, To: to, Field: field, Old: old, New: new})
+		}
+	}
+	list := func(field string, old, new []string) {
+		added, removed := diffSets(old, new)
+		if len(added) > 0 || len(removed) > 0 {
+			out = append(out, FieldChange{From: from, To: to, Field: field, Added: added, Removed: removed})
+		}
+	}
+
+	scalar("id", a.ID, b.ID)
+	scalar("name", a.Name, b.Name)
+	scalar("namespace", a.Namespace, b.Namespace)
+	scalar("definition", a.Definition, b.Definition)
+	scalar("is_obsolete", strconv.FormatBool(a.IsObsolete), strconv.FormatBool(b.IsObsolete))
+	scalar("comment", a.Comment, b.Comment)
+	list("replaced_by", a.ReplacedBy, b.ReplacedBy)
+	list("consider", a.Consider, b.Consider)
+	list("subsets", a.Subsets, b.Subsets)
+	list("synonyms", synonymKeys(a), synonymKeys(b))
+	list("xrefs", xrefKeys(a), xrefKeys(b))
+	list("alt_ids", a.AltIDs, b.AltIDs)
+	list("relationships", relationshipKeys(a), relationshipKeys(b))
+	list("intersection_of", intersectionKeys(a), intersectionKeys(b))
+	list("union_of", a.UnionOf, b.UnionOf)
+	list("one_of", a.OneOf, b.OneOf)
+	list("properties", propertyKeys(a), propertyKeys(b))
+	return out
+}
+
+// diffSets returns the sorted elements only in new (added) and only in
+// old (removed).
+func diffSets(old, new []string) (added, removed []string) {
+	inOld := make(map[string]bool, len(old))
+	for _, s := range old {
+		inOld[s] = true
+	}
+	inNew := make(map[string]bool, len(new))
+	for _, s := range new {
+		inNew[s] = true
+		if !inOld[s] {
+			added = append(added, s)
+		}
+	}
+	for _, s := range old {
+		if !inNew[s] {
+			removed = append(removed, s)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
+
+func synonymKeys(t *Term) []string {
+	keys := make([]string, len(t.Synonyms))
+	for i, syn := range t.Synonyms {
+		keys[i] = syn.Text + " [" + syn.Scope
+		if syn.Type != "" {
+			keys[i] += " " + syn.Type
+		}
+		keys[i] += "]"
+	}
+	return keys
+}
+
+func relationshipKeys(t *Term) []string {
+	keys := make([]string, len(t.Relationships))
+	for i, rel := range t.Relationships {
+		keys[i] = rel.Type + " "
+		if rel.Cardinality != nil {
+			keys[i] += rel.Cardinality.String() + " "
+		}
+		keys[i] += fillerKey(rel.TargetID, rel.HasValue, rel.Self) + qualifierKey(rel.Qualifiers)
+	}
+	return keys
+}
+
+func intersectionKeys(t *Term) []string {
+	keys := make([]string, len(t.IntersectionOf))
+	for i, part := range t.IntersectionOf {
+		keys[i] = part.TargetID
+		if part.Relationship != "" {
+			keys[i] = part.Relationship + " " + fillerKey(part.TargetID, false, part.Self)
+		}
+	}
+	return keys
+}
+
+// fillerKey renders the filler of an existential the way Manchester syntax
+// writes it: a class ID, {individual} or Self.
+func fillerKey(target string, hasValue, self bool) string {
+	switch {
+	case self:
+		return "Self"
+	case hasValue:
+		return "{" + target + "}"
+	}
+	return target
+}
+
+func xrefKeys(t *Term) []string {
+	keys := make([]string, len(t.Xrefs))
+	for i, x := range t.Xrefs {
+		keys[i] = x + qualifierKey(t.XrefQualifiers[x])
+	}
+	return keys
+}
+
+// qualifierKey renders qualifiers as OBO writes them, ` {a="1", b="2"}`,
+// or "" if there are none.
+func qualifierKey(q map[string]string) string {
+	if len(q) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(q))
+	for _, k := range sortedKeys(q) {
+		parts = append(parts, k+"="+strconv.Quote(q[k]))
+	}
+	return " {" + strings.Join(parts, ", ") + "}"
+}
+
+func propertyKeys(t *Term) []string {
+	keys := make([]string, 0, len(t.Properties))
+	for k := range t.Properties {
+		v, _ := t.Property(k)
+		keys = append(keys, k+"="+v.String())
+	}
+	return keys
+}
@@ -0,0 +1,156 @@
+package ontology
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Warning categories.
+const (
+	WarnUnknownTag       = "unknown_tag"         // an OBO tag the parser does not read
+	WarnMalformedSynonym = "malformed_synonym"   // no quoted text, or an unknown scope
+	WarnDuplicateID      = "duplicate_id"        // a second stanza or class with the same ID
+	WarnForeignNamespace = "non_chebi_namespace" // a term ID outside ParseOptions.IDPrefix
+	WarnDialect          = "obo_dialect"         // a construct the declared format-version does not allow
+	WarnEncoding         = "encoding"            // an OBO line that is not valid UTF-8
+)
+
+// Warning is a problem a parser recovered from. TermID is the stanza or
+// class it was found in, empty in the header.
+type Warning struct {
+	Category string `json:"category"`
+	TermID   string `json:"term_id,omitempty"`
+	Message  string `json:"message"`
+}
+
+// String formats the warning as "category: term: message".
+func (w Warning) String() string {
+	if w.TermID == "" {
+		return w.Category + ": " + w.Message
+	}
+	return w.Category + ": " + w.TermID + ": " + w.Message
+}
+
+// WarningLog collects parser warnings. Pass its Add method as
+// ParseOptions.Warn.
+type WarningLog struct {
+	Warnings []Warning
+	counts   map[string]int
+}
+
+// Add records a warning.
+func (l *WarningLog) Add(w Warning) {
+	if l.counts == nil {
+		l.counts = make(map[string]int)
+	}
+	l.counts[w.Category]++
+	l.Warnings = append(l.Warnings, w)
+}
+
+// Count returns the number of warnings in the category, or of all
+// warnings if category is "".
+func (l *WarningLog) Count(category string) int {
+	if category == "" {
+		return len(l.Warnings)
+	}
+	return l.counts[category]
+}
+
+// Summary formats the counts per category, largest first, as
+// "3 duplicate_id, 1 unknown_tag"; "" if there are none.
+func (l *WarningLog) Summary() string {
+	cats := make([]string, 0, len(l.counts))
+	for c := range l.counts {
+		cats = append(cats, c)
+	}
+	sort.Slice(cats, func(i, j int) bool {
+		if l.counts[cats[i]] != l.counts[cats[j]] {
+			return l.counts[cats[i]] > l.counts[cats[j]]
+		}
+		return cats[i] < cats[j]
+	})
+	parts := make([]string, len(cats))
+	for i, c := range cats {
+		parts[i] = fmt.Sprintf("%d %s", l.counts[c], c)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// oboIgnoredTags are OBO 1.4 term tags the parser knowingly skips; they
+// are not reported as unknown.
+var oboIgnoredTags = map[string]bool{
+	"is_anonymous": true, "builtin": true, "created_by": true,
+	"creation_date": true, "disjoint_from": true, "equivalent_to": true,
+}
+
+// synonymScopes are the scopes an OBO synonym may have.
+var synonymScopes = map[string]bool{
+	"EXACT": true, "BROAD": true, "NARROW": true, "RELATED": true,
+}
+
+// warner reports warnings to ParseOptions.Warn. Its methods do nothing
+// when Warn is nil, so parsers call them unconditionally.
+type warner struct {
+	warn   func(Warning)
+	prefix string
+	seen   map[string]bool
+}
+
+func newWarner(opts ParseOptions) *warner {
+	w := &warner{warn: opts.Warn, prefix: opts.IDPrefix}
+	if w.prefix == "" {
+		w.prefix = "CHEBI"
+	}
+	if w.warn != nil {
+		w.seen = make(map[string]bool)
+	}
+	return w
+}
+
+func (w *warner) add(category, termID, format string, args ...any) {
+	if w.warn != nil {
+		w.warn(Warning{Category: category, TermID: termID, Message: fmt.Sprintf(format, args...)})
+	}
+}
+
+// term checks a parsed term's ID against earlier ones and the prefix.
+func (w *warner) term(id string) {
+	if w.warn == nil || id == "" {
+		return
+	}
+	if w.seen[id] {
+		w.add(WarnDuplicateID, id, "ID already defined")
+	}
+	w.seen[id] = true
+	if prefix, _, _ := strings.Cut(id, ":"); prefix != w.prefix {
+		w.add(WarnForeignNamespace, id, "ID prefix is not %s", w.prefix)
+	}
+}
+
+// synonym checks the value of an OBO synonym tag. OBO 1.2 synonyms may
+// leave out the scope.
+func (w *warner) synonym(termID, val string, scopeOptional bool) {
+	if w.warn == nil {
+		return
+	}
+	text, rest := splitQuoted(val)
+	switch scope, _, _ := strings.Cut(strings.TrimSpace(rest), " "); {
+	case !strings.HasPrefix(strings.TrimSpace(val), `"`) || text == "":
+		w.add(WarnMalformedSynonym, termID, "no quoted text in %q", val)
+	case strings.Count(val, `"`) < 2:
+		w.add(WarnMalformedSynonym, termID, "unterminated quote in %q", val)
+	case scopeOptional && (scope == "" || strings.HasPrefix(scope, "[")):
+	case scope == "":
+		w.add(WarnMalformedSynonym, termID, "no scope in %q", val)
+	case !synonymScopes[scope]:
+		w.add(WarnMalformedSynonym, termID, "unknown scope %q", scope)
+	}
+}
+
+// tag reports a term tag the parser does not read.
+func (w *warner) tag(termID, key string) {
+	if w.warn != nil && !oboIgnoredTags[key] {
+		w.add(WarnUnknownTag, termID, "tag %q ignored", key)
+	}
+}
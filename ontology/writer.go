@@ -4,30 +4,32 @@ import (
 	"bufio"
 	"encoding/json"
 	"io"
-	"os"
 )
 
 const writerBufferSize = 256 * 1024 // 256 KB
 
-// WriteJSON writes the ontology as JSON to the given writer.
+// WriteJSON writes the ontology as JSON to the given writer. Fields are
+// written in the fixed order documented on jsonWriter and property keys
+// are sorted, so the output is stable across runs.
 func WriteJSON(ont *Ontology, w io.Writer) error {
 	bw := bufio.NewWriterSize(w, writerBufferSize)
-	enc := json.NewEncoder(bw)
-	enc.SetEscapeHTML(false)
-	if err := enc.Encode(ont); err != nil {
+	jw := &jsonWriter{w: bw, buf: make([]byte, 0, 256)}
+	if err := jw.ontology(ont); err != nil {
 		return err
 	}
 	return bw.Flush()
 }
 
-// WriteJSONFile writes the ontology as JSON to the given file path.
-func WriteJSONFile(ont *Ontology, path string) error {
-	f, err := os.Create(path)
-	if err != nil {
+// WriteJSONWithBodies is WriteJSON for an ontology parsed under a memory
+// budget: spilled term bodies are read back from bodies one term at a
+// time, so the ontology never has to be restored in memory whole.
+func WriteJSONWithBodies(ont *Ontology, bodies *BodyStore, w io.Writer) error {
+	bw := bufio.NewWriterSize(w, writerBufferSize)
+	jw := &jsonWriter{w: bw, buf: make([]byte, 0, 256), bodies: bodies}
+	if err := jw.ontology(ont); err != nil {
 		return err
 	}
-	defer f.Close()
-	return WriteJSON(ont, f)
+	return bw.Flush()
 }
 
 // WriteJSONPretty writes indented JSON to the given writer.
@@ -41,3 +43,12 @@ func WriteJSONPretty(ont *Ontology, w io.Writer) error {
 	}
 	return bw.Flush()
 }
+
+// ReadJSON reads an ontology written by WriteJSON or WriteJSONPretty.
+func ReadJSON(r io.Reader) (*Ontology, error) {
+	var ont Ontology
+	if err := json.NewDecoder(bufio.NewReaderSize(r, writerBufferSize)).Decode(&ont); err != nil {
+		return nil, err
+	}
+	return &ont, nil
+}
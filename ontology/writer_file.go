@@ -0,0 +1,15 @@
+//go:build !js
+
+package ontology
+
+import "os"
+
+// WriteJSONFile writes the ontology as JSON to the given file path.
+func WriteJSONFile(ont *Ontology, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return WriteJSON(ont, f)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nodeadmin/chebi-parser/internal/exitcode"
+	"github.com/nodeadmin/chebi-parser/ontology"
+)
+
+// pathCommand prints the shortest relationship path between two terms, as
+// labeled text or as JSON edges.
+func pathCommand() (*flag.FlagSet, func() error) {
+	fs := newFlagSet("path")
+	input := fs.String("input", "", "Ontology file (.obo, .owl or .msgpack)")
+	format := fs.String("format", "auto", "Input format: auto, obo, owl, msgpack")
+	from := fs.String("from", "", "Start term ID or name")
+	to := fs.String("to", "", "End term ID or name")
+	relations := fs.String("relations", "", "Comma-separated relation types to follow (default: all)")
+	labels := fs.String("label-prefs", "", labelPrefsUsage)
+	asJSON := fs.Bool("json", false, "Write the edges as JSON")
+	return fs, func() error {
+		if *input == "" || *from == "" || *to == "" {
+			return exitcode.Errorf(exitcode.Usage, "usage: chebi-parser path -input <file> -from TERM -to TERM [-relations is_a,has_role] [-label-prefs INN,name] [-json]")
+		}
+		prefs, err := labelPrefs(*labels)
+		if err != nil {
+			return err
+		}
+		ont, err := loadOntology(*input, *format)
+		if err != nil {
+			return err
+		}
+		ix := ontology.NewIndexWithOptions(ont, ontology.IndexOptions{Labels: prefs})
+		a, err := ix.Lookup(*from)
+		if err != nil {
+			return err
+		}
+		b, err := ix.Lookup(*to)
+		if err != nil {
+			return err
+		}
+		var rels []string
+		if *relations != "" {
+			rels = strings.Split(*relations, ",")
+		}
+		path := ix.Path(a, b, rels)
+		if path == nil {
+			return fmt.Errorf("no path from %s to %s", a, b)
+		}
+		if *asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetEscapeHTML(false)
+			enc.SetIndent("", "  ")
+			return enc.Encode(path)
+		}
+		fmt.Println(ix.FormatPath(path))
+		return nil
+	}
+}
@@ -0,0 +1,514 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nodeadmin/chebi-parser/internal/exitcode"
+	"github.com/nodeadmin/chebi-parser/ontology"
+	"github.com/nodeadmin/chebi-parser/reasoner"
+)
+
+// recipe is a pipeline definition: steps run in order, each reading the
+// results of earlier steps named in its inputs.
+type recipe struct {
+	CacheDir string       `json:"cache_dir"` // default: .pipeline-cache beside the recipe
+	Steps    []recipeStep `json:"steps"`
+}
+
+// recipeStep is one step. Run selects what it does:
+//
+//	fetch     copy a file or download a URL (source, format); with
+//	          max_warnings, fail if parsing it reports more warnings
+//	merge     combine the inputs' ontologies (ontology.Merge); with
+//	          manifest, also write a CycloneDX-style list of the fetched
+//	          sources behind them, with checksums and header licenses
+//	filter    keep selected terms (namespaces, subsets, roots)
+//	classify  add inferred is_a edges (reasoner.Materialize)
+//	export    write the input to output (format, default from the extension)
+//	publish   copy the inputs' files into dir with a manifest.json
+//
+// filter, classify and export take obsolete, the ontology.ObsoletePolicy of
+// the -obsolete flags, with the same stage defaults: filter and export keep
+// obsolete terms, classify leaves them out, and SKOS export writes them only
+// under include. The older drop_obsolete: true is read as obsolete:
+// "exclude" with a warning.
+type recipeStep struct {
+	Name         string                  `json:"name"`
+	Run          string                  `json:"run"`
+	Inputs       []string                `json:"inputs,omitempty"`
+	Source       string                  `json:"source,omitempty"`
+	Format       string                  `json:"format,omitempty"`
+	Namespaces   []string                `json:"namespaces,omitempty"`
+	Subsets      []string                `json:"subsets,omitempty"`
+	Roots        []string                `json:"roots,omitempty"`
+	Obsolete     ontology.ObsoletePolicy `json:"obsolete,omitempty"`
+	DropObsolete bool                    `json:"drop_obsolete,omitempty"` // deprecated: obsolete "exclude"
+	Output       string                  `json:"output,omitempty"`
+	Dir          string                  `json:"dir,omitempty"`
+	Manifest     string                  `json:"manifest,omitempty"`
+	MaxWarnings  *int                    `json:"max_warnings,omitempty"`
+}
+
+// stepState is what a step produced, kept in the cache directory's
+// state.json between runs. A step whose key is unchanged and whose file
+// still has the recorded hash is skipped.
+type stepState struct {
+	Key  string `json:"key"`  // hash of the step definition and its inputs' hashes
+	File string `json:"file"` // the step's result
+	Hash string `json:"hash"` // SHA-256 of File (for publish, of the manifest)
+}
+
+// pipelineCacheVersion is part of every step key; bump it when a step's
+// output changes for the same definition.
+const pipelineCacheVersion = "1"
+
+const pipelineStateFile = "state.json"
+
+// pipelineCommand executes a recipe, skipping steps whose definition and
+// inputs are unchanged since the last run.
+func pipelineCommand() (*flag.FlagSet, func() error) {
+	fs := newFlagSet("pipeline")
+	recipePath := fs.String("recipe", "", "Pipeline recipe (JSON)")
+	force := fs.Bool("force", false, "Run every step, ignoring the cache")
+	dryRun := fs.Bool("dry-run", false, "Validate the recipe and list the steps without running them")
+	workers := fs.Int("workers", 0, "Saturation workers for classify steps (default: number of CPUs)")
+	return fs, func() error {
+		if *recipePath == "" {
+			return exitcode.Errorf(exitcode.Usage, "usage: chebi-parser pipeline -recipe recipe.json [-force] [-dry-run]")
+		}
+		rc, err := loadRecipe(*recipePath)
+		if err != nil {
+			return err
+		}
+		if *dryRun {
+			for _, st := range rc.Steps {
+				fmt.Printf("%s\t%s\t%s\n", st.Name, st.Run, strings.Join(st.Inputs, ","))
+			}
+			return nil
+		}
+		if err := os.MkdirAll(rc.CacheDir, 0o755); err != nil {
+			return err
+		}
+		state := make(map[string]stepState)
+		statePath := filepath.Join(rc.CacheDir, pipelineStateFile)
+		if data, err := os.ReadFile(statePath); err == nil && !*force {
+			if err := json.Unmarshal(data, &state); err != nil {
+				return fmt.Errorf("parsing %s: %w", statePath, err)
+			}
+		}
+
+		p := &pipeline{recipe: rc, state: state, results: make(map[string]stepState), workers: *workers}
+		total := time.Now()
+		ran := 0
+		for i := range rc.Steps {
+			st := &rc.Steps[i]
+			start := time.Now()
+			res, skipped, err := p.run(st)
+			if err != nil {
+				return fmt.Errorf("step %s (%s): %w", st.Name, st.Run, err)
+			}
+			p.results[st.Name] = res
+			state[st.Name] = res
+			status := "cached"
+			if !skipped {
+				status = "ran"
+				ran++
+			}
+			fmt.Fprintf(os.Stderr, "%-12s %-8s %-6s %s %v\n", st.Name, st.Run, status, res.File, time.Since(start).Round(time.Millisecond))
+			// Save after every step so a failure keeps the work done so far.
+			if err := writePipelineState(statePath, state); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(os.Stderr, "%d of %d steps ran in %v\n", ran, len(rc.Steps), time.Since(total).Round(time.Millisecond))
+		return p.pruneCache()
+	}
+}
+
+// loadRecipe reads and checks a recipe. Relative paths in it are relative
+// to the recipe file.
+func loadRecipe(path string) (*recipe, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rc := &recipe{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(rc); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	base := filepath.Dir(path)
+	rel := func(p string) string {
+		if p == "" || filepath.IsAbs(p) || strings.Contains(p, "://") {
+			return p
+		}
+		return filepath.Join(base, p)
+	}
+	if rc.CacheDir == "" {
+		rc.CacheDir = ".pipeline-cache"
+	}
+	rc.CacheDir = rel(rc.CacheDir)
+
+	defined := make(map[string]bool)
+	for i := range rc.Steps {
+		st := &rc.Steps[i]
+		if st.Name == "" {
+			return nil, fmt.Errorf("step %d has no name", i+1)
+		}
+		if defined[st.Name] {
+			return nil, fmt.Errorf("step name %q is used twice", st.Name)
+		}
+		for _, in := range st.Inputs {
+			if !defined[in] {
+				return nil, fmt.Errorf("step %s: input %q is not an earlier step", st.Name, in)
+			}
+		}
+		st.Source, st.Output, st.Dir = rel(st.Source), rel(st.Output), rel(st.Dir)
+		if st.Manifest != "" && st.Run != "merge" {
+			return nil, fmt.Errorf("step %s: only merge steps take a manifest", st.Name)
+		}
+		st.Manifest = rel(st.Manifest)
+		if st.MaxWarnings != nil && st.Run != "fetch" {
+			return nil, fmt.Errorf("step %s: only fetch steps take max_warnings", st.Name)
+		}
+		if st.DropObsolete {
+			if st.Obsolete == ontology.ObsoleteInclude {
+				return nil, fmt.Errorf("step %s: drop_obsolete contradicts obsolete %q", st.Name, st.Obsolete)
+			}
+			fmt.Fprintf(os.Stderr, "Warning: step %s: drop_obsolete is deprecated; use \"obsolete\": \"exclude\"\n", st.Name)
+			st.Obsolete, st.DropObsolete = ontology.ObsoleteExclude, false
+		}
+		var err error
+		if st.Obsolete, err = ontology.ParseObsoletePolicy(string(st.Obsolete)); err != nil {
+			return nil, fmt.Errorf("step %s: %w", st.Name, err)
+		}
+		if st.Obsolete != ontology.ObsoleteDefault && st.Run != "filter" && st.Run != "classify" && st.Run != "export" {
+			return nil, fmt.Errorf("step %s: only filter, classify and export steps take obsolete", st.Name)
+		}
+		var ok bool
+		var want string
+		switch st.Run {
+		case "fetch":
+			ok, want = st.Source != "", "source"
+		case "merge":
+			ok, want = len(st.Inputs) > 0, "inputs"
+		case "filter", "classify":
+			ok, want = len(st.Inputs) == 1, "one input"
+		case "export":
+			ok, want = len(st.Inputs) == 1 && st.Output != "", "one input and output"
+		case "publish":
+			ok, want = len(st.Inputs) > 0 && st.Dir != "", "inputs and dir"
+		default:
+			return nil, fmt.Errorf("step %s: unknown run %q (want fetch, merge, filter, classify, export or publish)", st.Name, st.Run)
+		}
+		if !ok {
+			return nil, fmt.Errorf("step %s: %s needs %s", st.Name, st.Run, want)
+		}
+		if st.Run == "export" {
+			if st.Format == "" {
+				st.Format = outputFormat(st.Output)
+			}
+			if _, ok := ontologyWriter(st.Format, st.Obsolete); !ok {
+				return nil, fmt.Errorf("step %s: unknown export format %q", st.Name, st.Format)
+			}
+		}
+		defined[st.Name] = true
+	}
+	return rc, nil
+}
+
+type pipeline struct {
+	recipe  *recipe
+	state   map[string]stepState // from the previous run
+	results map[string]stepState // of this run's steps so far
+	workers int
+}
+
+// run executes one step unless its previous result is still valid.
+func (p *pipeline) run(st *recipeStep) (res stepState, skipped bool, err error) {
+	h := sha256.New()
+	def, _ := json.Marshal(st)
+	fmt.Fprintf(h, "%s\n%s\n", pipelineCacheVersion, def)
+	for _, in := range st.Inputs {
+		fmt.Fprintf(h, "%s %s\n", in, p.results[in].Hash)
+	}
+
+	if st.Run == "fetch" {
+		// The source's content is the input, so it is always read.
+		return p.fetch(st, h)
+	}
+	key := hex.EncodeToString(h.Sum(nil))
+	if prev, ok := p.state[st.Name]; ok && prev.Key == key && fileHash(prev.File) == prev.Hash && (st.Manifest == "" || fileHash(st.Manifest) != "") {
+		return prev, true, nil
+	}
+
+	var file string
+	switch st.Run {
+	case "merge", "filter", "classify":
+		file = filepath.Join(p.recipe.CacheDir, st.Name+"-"+key[:16]+".msgpack")
+		err = p.transform(st, file)
+		if err == nil && st.Manifest != "" {
+			err = p.writeSourceManifest(st, file)
+		}
+	case "export":
+		file = st.Output
+		err = p.export(st)
+	case "publish":
+		file = filepath.Join(st.Dir, "manifest.json")
+		err = p.publish(st, file)
+	}
+	if err != nil {
+		return res, false, err
+	}
+	return stepState{Key: key, File: file, Hash: fileHash(file)}, false, nil
+}
+
+// fetch copies or downloads the source into the cache directory, keyed
+// by its content.
+func (p *pipeline) fetch(st *recipeStep, h hash.Hash) (stepState, bool, error) {
+	src, cleanup, err := fetchSource(st.Source)
+	if err != nil {
+		return stepState{}, false, err
+	}
+	defer cleanup()
+	content := fileHash(src)
+	if content == "" {
+		return stepState{}, false, fmt.Errorf("cannot read %s", st.Source)
+	}
+	fmt.Fprintf(h, "content %s\n", content)
+	key := hex.EncodeToString(h.Sum(nil))
+	if prev, ok := p.state[st.Name]; ok && prev.Key == key && fileHash(prev.File) == prev.Hash {
+		return prev, true, nil
+	}
+	ext := filepath.Ext(st.Source)
+	if strings.HasSuffix(strings.ToLower(st.Source), ".obographs.json") {
+		ext = ".obographs.json"
+	}
+	file := filepath.Join(p.recipe.CacheDir, st.Name+"-"+key[:16]+ext)
+	if st.MaxWarnings != nil {
+		if err := checkSourceWarnings(src, st.Format, *st.MaxWarnings); err != nil {
+			return stepState{}, false, err
+		}
+	}
+	if err := copyFile(src, file); err != nil {
+		return stepState{}, false, err
+	}
+	return stepState{Key: key, File: file, Hash: content}, false, nil
+}
+
+// checkSourceWarnings parses a fetched OBO or OWL file for its warnings
+// and fails if there are more than max. A source that passed is not
+// checked again until its content or the step changes.
+func checkSourceWarnings(src, format string, max int) error {
+	if format == "" {
+		format = "auto"
+	}
+	inputFmt := detectFormat(src, format)
+	if inputFmt != "obo" && inputFmt != "owl" {
+		return exitcode.Errorf(exitcode.Usage, "max_warnings needs an OBO or OWL source")
+	}
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var warnings ontology.WarningLog
+	if _, err := parseInputWithOptions(f, inputFmt, ontology.ParseOptions{Warn: warnings.Add}); err != nil {
+		return exitcode.Wrap(exitcode.Parse, err)
+	}
+	return checkWarnings(&warnings, max)
+}
+
+// load parses the result of an earlier step. fetch results keep their
+// source format, which the step's format overrides.
+func (p *pipeline) load(name string) (*ontology.Ontology, error) {
+	format := "auto"
+	for _, st := range p.recipe.Steps {
+		if st.Name == name && st.Run == "fetch" && st.Format != "" {
+			format = st.Format
+		}
+	}
+	return loadOntology(p.results[name].File, format)
+}
+
+// transform runs a merge, filter or classify step and writes the result
+// to file as msgpack.
+func (p *pipeline) transform(st *recipeStep, file string) error {
+	var ont *ontology.Ontology
+	switch st.Run {
+	case "merge":
+		var onts []*ontology.Ontology
+		for _, in := range st.Inputs {
+			o, err := p.load(in)
+			if err != nil {
+				return err
+			}
+			onts = append(onts, o)
+		}
+		ont, _ = ontology.Merge(onts...)
+	case "filter":
+		in, err := p.load(st.Inputs[0])
+		if err != nil {
+			return err
+		}
+		roots := make([]string, len(st.Roots))
+		ix := ontology.NewIndex(in)
+		for i, r := range st.Roots {
+			if roots[i], err = ix.Lookup(r); err != nil {
+				return fmt.Errorf("root: %w", err)
+			}
+		}
+		ont = ontology.Filter(in, ontology.FilterOptions{
+			Namespaces: st.Namespaces, Subsets: st.Subsets, Roots: roots, DropObsolete: !st.Obsolete.Keep(true),
+		})
+	case "classify":
+		var err error
+		if ont, err = p.load(st.Inputs[0]); err != nil {
+			return err
+		}
+		n := reasoner.Materialize(ont, reasoner.NormalizeOptions{Obsolete: st.Obsolete}, p.workers)
+		fmt.Fprintf(os.Stderr, "%-12s added %d inferred is_a edges\n", st.Name, n)
+	}
+	return writeFileWith(file, func(w io.Writer) error { return ontology.WriteMsgpack(ont, w) })
+}
+
+func (p *pipeline) export(st *recipeStep) error {
+	ont, err := p.load(st.Inputs[0])
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(st.Output), 0o755); err != nil {
+		return err
+	}
+	if !st.Obsolete.Keep(true) {
+		ont = ontology.Filter(ont, ontology.FilterOptions{DropObsolete: true})
+	}
+	write, _ := ontologyWriter(st.Format, st.Obsolete)
+	return writeFileWith(st.Output, func(w io.Writer) error { return write(ont, w) })
+}
+
+// publishedFile is one entry of a publish step's manifest.json.
+type publishedFile struct {
+	Name   string `json:"name"`
+	Bytes  int64  `json:"bytes"`
+	SHA256 string `json:"sha256"`
+}
+
+// publish copies the inputs' files into the step's directory and writes a
+// manifest listing them.
+func (p *pipeline) publish(st *recipeStep, manifest string) error {
+	if err := os.MkdirAll(st.Dir, 0o755); err != nil {
+		return err
+	}
+	var files []publishedFile
+	for _, in := range st.Inputs {
+		src := p.results[in].File
+		dst := filepath.Join(st.Dir, filepath.Base(src))
+		if err := copyFile(src, dst); err != nil {
+			return err
+		}
+		fi, err := os.Stat(dst)
+		if err != nil {
+			return err
+		}
+		files = append(files, publishedFile{Name: filepath.Base(dst), Bytes: fi.Size(), SHA256: p.results[in].Hash})
+	}
+	data, err := json.MarshalIndent(struct {
+		Files []publishedFile `json:"files"`
+	}{files}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(manifest, append(data, '\n'), 0o644)
+}
+
+// pruneCache removes the results of earlier runs that this run replaced.
+func (p *pipeline) pruneCache() error {
+	entries, err := os.ReadDir(p.recipe.CacheDir)
+	if err != nil {
+		return err
+	}
+	keep := make(map[string]bool)
+	for _, res := range p.results {
+		keep[filepath.Base(res.File)] = true
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if keep[name] || e.IsDir() {
+			continue
+		}
+		for step := range p.results {
+			if strings.HasPrefix(name, step+"-") {
+				if err := os.Remove(filepath.Join(p.recipe.CacheDir, name)); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func writePipelineState(path string, state map[string]stepState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// fileHash returns the hex SHA-256 of a file, or "" if it cannot be read.
+func fileHash(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	return writeFileWith(dst, func(w io.Writer) error {
+		_, err := io.Copy(w, in)
+		return err
+	})
+}
+
+// writeFileWith writes a file through a temporary file renamed into
+// place, so an interrupted step never leaves a partial result.
+func writeFileWith(path string, write func(io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	err = write(tmp)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
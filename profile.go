@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nodeadmin/chebi-parser/internal/exitcode"
+	"github.com/nodeadmin/chebi-parser/ontology"
+)
+
+// profileCheckCommand reports axioms in an OBO or OWL file that the EL
+// reasoner will not see: constructs outside OWL 2 EL and EL constructs the
+// parsers drop.
+func profileCheckCommand() (*flag.FlagSet, func() error) {
+	fs := newFlagSet("profile-check")
+	input := fs.String("input", "", "Ontology file (.obo or .owl)")
+	format := fs.String("format", "auto", "Input format: auto, obo, owl")
+	examples := fs.Int("examples", 5, "Example entity IDs to show per construct")
+	asJSON := fs.Bool("json", false, "Write the report as JSON")
+	strict := fs.Bool("strict", false, "Exit with an error if any axiom is outside OWL 2 EL")
+	return fs, func() error {
+		if *input == "" {
+			return exitcode.Errorf(exitcode.Usage, "usage: chebi-parser profile-check -input <file.obo|file.owl> [-examples N] [-json] [-strict]")
+		}
+		inputFmt := detectFormat(*input, *format)
+		if inputFmt != "obo" && inputFmt != "owl" {
+			return fmt.Errorf("profile-check needs an OBO or OWL source; use -format obo or -format owl")
+		}
+		f, err := os.Open(*input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		rep, err := ontology.CheckProfile(f, inputFmt, *examples)
+		if err != nil {
+			return err
+		}
+
+		if *asJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetEscapeHTML(false)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(rep); err != nil {
+				return err
+			}
+		} else if len(rep.Findings) == 0 {
+			fmt.Println("No axioms outside OWL 2 EL or unsupported by the reasoner.")
+		} else {
+			fmt.Printf("%-12s %8s  %s\n", "CATEGORY", "COUNT", "CONSTRUCT")
+			for _, f := range rep.Findings {
+				fmt.Printf("%-12s %8d  %s\n", f.Category, f.Count, f.Construct)
+				if len(f.Examples) > 0 {
+					fmt.Printf("%-12s %8s  e.g. %s\n", "", "", strings.Join(f.Examples, ", "))
+				}
+			}
+		}
+
+		if *strict && rep.NonEL() {
+			return exitcode.Errorf(exitcode.Validation, "ontology contains axioms outside OWL 2 EL")
+		}
+		return nil
+	}
+}
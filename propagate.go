@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/nodeadmin/chebi-parser/internal/exitcode"
+	"github.com/nodeadmin/chebi-parser/ontology"
+)
+
+// propagateCommand expands an annotation table (subject, term[, kind]) upward
+// along is_a and the relations named in a rules file, and writes the
+// expanded table or per-term counts as TSV or JSON.
+func propagateCommand() (*flag.FlagSet, func() error) {
+	fs := newFlagSet("propagate")
+	input := fs.String("input", "", "Ontology file (.obo, .owl or .msgpack)")
+	format := fs.String("format", "auto", "Input format: auto, obo, owl, msgpack")
+	annotations := fs.String("annotations", "", "TSV of subject, term ID or name and optional kind (- for stdin)")
+	header := fs.Bool("header", false, "Skip the first line of the annotation file")
+	rulesFile := fs.String("rules", "", `JSON propagation rules, e.g. [{"relations": ["is_a", "has_functional_parent"]}, {"kind": "measured", "relations": ["is_a", "^has_part"]}] (default: is_a only)`)
+	counts := fs.Bool("counts", false, "Write per-term subject counts instead of the expanded table")
+	asJSON := fs.Bool("json", false, "Write JSON instead of TSV")
+	output := fs.String("output", "", "Output file (default: stdout)")
+	return fs, func() error {
+		if *input == "" || *annotations == "" {
+			return exitcode.Errorf(exitcode.Usage, "usage: chebi-parser propagate -input <file> -annotations <file> [-rules rules.json] [-counts] [-json] [-output expanded.tsv]")
+		}
+		rules := ontology.DefaultPropagationRules
+		if *rulesFile != "" {
+			data, err := os.ReadFile(*rulesFile)
+			if err != nil {
+				return err
+			}
+			rules = nil
+			if err := json.Unmarshal(data, &rules); err != nil {
+				return exitcode.Errorf(exitcode.Usage, "parsing %s: %w", *rulesFile, err)
+			}
+		}
+		anns, err := readAnnotations(*annotations, *header)
+		if err != nil {
+			return err
+		}
+		ont, err := loadOntology(*input, *format)
+		if err != nil {
+			return err
+		}
+		res := ontology.NewIndex(ont).Propagate(anns, rules)
+
+		out := os.Stdout
+		if *output != "" {
+			f, err := os.Create(*output)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			out = f
+		}
+		bw := bufio.NewWriter(out)
+		enc := json.NewEncoder(bw)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		switch {
+		case *counts && *asJSON:
+			err = enc.Encode(res.Counts())
+		case *counts:
+			fmt.Fprintln(bw, "term\tname\tkind\tsubjects\tdirect")
+			for _, c := range res.Counts() {
+				fmt.Fprintf(bw, "%s\t%s\t%s\t%d\t%d\n", c.Term, c.Name, c.Kind, c.Subjects, c.Direct)
+			}
+		case *asJSON:
+			err = enc.Encode(res)
+		default:
+			fmt.Fprintln(bw, "subject\tterm\tname\tkind\tdirect\tfrom")
+			for _, r := range res.Rows {
+				fmt.Fprintf(bw, "%s\t%s\t%s\t%s\t%t\t%s\n", r.Subject, r.Term, r.Name, r.Kind, r.Direct, strings.Join(r.From, ","))
+			}
+		}
+		if err != nil {
+			return err
+		}
+		if err := bw.Flush(); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "%d annotations expanded to %d rows, %d unknown terms\n", len(anns), len(res.Rows), len(res.Unknown))
+		return nil
+	}
+}
+
+// readAnnotations reads subject, term and optional kind columns from a
+// TSV file, or stdin for "-". Blank lines and lines starting with # are
+// skipped.
+func readAnnotations(path string, header bool) ([]ontology.Annotation, error) {
+	var in io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		in = f
+	}
+	var anns []ontology.Annotation
+	sc := bufio.NewScanner(in)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		if line == 1 && header {
+			continue
+		}
+		text := sc.Text()
+		if strings.TrimSpace(text) == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Split(text, "\t")
+		if len(fields) < 2 {
+			return nil, exitcode.Errorf(exitcode.Parse, "%s:%d: expected subject<TAB>term[<TAB>kind]", path, line)
+		}
+		a := ontology.Annotation{Subject: strings.TrimSpace(fields[0]), Term: strings.TrimSpace(fields[1])}
+		if len(fields) > 2 {
+			a.Kind = strings.TrimSpace(fields[2])
+		}
+		anns = append(anns, a)
+	}
+	return anns, sc.Err()
+}
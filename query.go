@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/nodeadmin/chebi-parser/internal/exitcode"
+	"github.com/nodeadmin/chebi-parser/ontology"
+	"github.com/nodeadmin/chebi-parser/reasoner"
+	"github.com/nodeadmin/chebi-parser/server"
+)
+
+// queryCommand classifies an ontology and lists the subclasses or instances
+// of a class expression, as a DL query in Protégé would. With -server it asks
+// a remote serve instance instead, printing the same output.
+func queryCommand() (*flag.FlagSet, func() error) {
+	fs := newFlagSet("query")
+	input := fs.String("input", "", "Ontology file (.obo, .owl or .msgpack)")
+	format := fs.String("format", "auto", "Input format: auto, obo, owl, msgpack")
+	expr := fs.String("expr", "", `Class expression by ID or label, e.g. "has_role some 'antimicrobial agent' and is_a CHEBI:24431"`)
+	instances := fs.Bool("instances", false, "List individuals instead of subclasses")
+	workers := fs.Int("workers", 0, "Saturation workers (default: number of CPUs)")
+	obsoleteFlag := fs.String("obsolete", "", obsoleteUsage)
+	serverURL := fs.String("server", "", "Query a running `chebi-parser serve` at this URL instead of loading -input")
+	version := fs.String("version", "", "Release to query with -server (default: the server's default)")
+	apiKey := fs.String("api-key", os.Getenv("CHEBI_API_KEY"), "API key for -server (default: $CHEBI_API_KEY)")
+	return fs, func() error {
+		if (*input == "") == (*serverURL == "") || *expr == "" {
+			return exitcode.Errorf(exitcode.Usage, "usage: chebi-parser query (-input <file> | -server URL [-version v] [-api-key KEY]) -expr EXPRESSION [-instances] [-obsolete include|exclude]")
+		}
+		obsolete, err := ontology.ParseObsoletePolicy(*obsoleteFlag)
+		if err != nil {
+			return exitcode.Errorf(exitcode.Usage, "-obsolete: %v", err)
+		}
+		if *serverURL != "" {
+			c := server.NewClient(*serverURL)
+			c.Version = *version
+			c.APIKey = *apiKey
+			resp, err := c.Query(context.Background(), *expr, *instances)
+			if err != nil {
+				return err
+			}
+			return writeQueryMatches(resp.Matches)
+		}
+		ont, err := loadOntology(*input, *format)
+		if err != nil {
+			return err
+		}
+		r := reasoner.New(ont, reasoner.NormalizeOptions{Obsolete: obsolete}, *workers)
+
+		var ids []string
+		if *instances {
+			ids, err = r.Instances(*expr)
+		} else {
+			ids, err = r.Subclasses(*expr)
+		}
+		if err != nil {
+			return err
+		}
+
+		names := make(map[string]string, len(ont.Individuals))
+		for i := range ont.Individuals {
+			names[ont.Individuals[i].ID] = ont.Individuals[i].Name
+		}
+		ix := ontology.NewIndex(ont)
+		matches := make([]server.QueryMatch, len(ids))
+		for i, id := range ids {
+			matches[i] = server.QueryMatch{ID: id, Name: names[id]}
+			if t := ix.Term(id); t != nil {
+				matches[i].Name = t.Name
+			}
+		}
+		return writeQueryMatches(matches)
+	}
+}
+
+// writeQueryMatches prints one "id<TAB>name" line per match and the count
+// on stderr.
+func writeQueryMatches(matches []server.QueryMatch) error {
+	bw := bufio.NewWriter(os.Stdout)
+	for _, m := range matches {
+		fmt.Fprintf(bw, "%s\t%s\n", m.ID, m.Name)
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%d matches\n", len(matches))
+	return nil
+}
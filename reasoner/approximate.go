@@ -0,0 +1,261 @@
+package reasoner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nodeadmin/chebi-parser/ontology"
+)
+
+// NormalizeOptions controls how NormalizeWithOptions treats axioms that
+// fall outside EL.
+type NormalizeOptions struct {
+	// Approximate rewrites non-EL axioms into EL axioms they entail instead
+	// of dropping them. The rewriting is sound but incomplete: every added
+	// subsumption holds in the original ontology, but not every consequence
+	// of the original axiom is recovered.
+	Approximate bool
+
+	// OneOf selects how owl:oneOf enumerations (Term.OneOf) are brought
+	// into EL, which only allows an enumeration of a single individual.
+	// The zero value is OneOfSkip.
+	OneOf OneOfStrategy
+
+	// Provenance records which term, typedef or individual each axiom
+	// came from, for TaxonomyOptions.Provenance.
+	Provenance bool
+
+	// Obsolete terms are left out unless this is ObsoleteInclude; they
+	// are then classified like any other term, from whatever axioms they
+	// still assert.
+	Obsolete ontology.ObsoletePolicy
+}
+
+// OneOfStrategy is a way of handling C ≡ {a₁, ..., aₙ}.
+type OneOfStrategy string
+
+const (
+	// OneOfSkip drops the axiom; it is still listed in the report.
+	OneOfSkip OneOfStrategy = "skip"
+	// OneOfFresh treats C as an ordinary class that the listed
+	// individuals belong to: {aᵢ} ⊑ C. Nothing follows about C itself.
+	OneOfFresh OneOfStrategy = "fresh"
+	// OneOfExpand adds {aᵢ} ⊑ C and, since C has no other members,
+	// C ⊑ D for the most specific classes D every aᵢ is asserted to be
+	// an instance of.
+	OneOfExpand OneOfStrategy = "expand"
+)
+
+// ParseOneOfStrategy parses a strategy name as used on the command line.
+func ParseOneOfStrategy(s string) (OneOfStrategy, error) {
+	switch OneOfStrategy(s) {
+	case OneOfSkip, OneOfFresh, OneOfExpand:
+		return OneOfStrategy(s), nil
+	case "":
+		return OneOfSkip, nil
+	}
+	return "", fmt.Errorf("unknown oneOf strategy %q: use skip, fresh or expand", s)
+}
+
+// Approximation actions.
+const (
+	ApproxDropped   = "dropped"
+	ApproxRewritten = "rewritten"
+)
+
+// ApproxEntry records how one non-EL axiom was handled.
+type ApproxEntry struct {
+	TermID    string   `json:"term_id"`
+	Construct string   `json:"construct"` // e.g. "union_of"
+	Action    string   `json:"action"`    // ApproxDropped or ApproxRewritten
+	Axioms    []string `json:"axioms,omitempty"`
+}
+
+// ApproxReport lists the non-EL axioms met during normalization, and the
+// redundant asserted axioms it dropped.
+type ApproxReport struct {
+	Entries []ApproxEntry  `json:"entries"`
+	Cleanup []CleanupEntry `json:"cleanup,omitempty"`
+}
+
+// Counts returns the number of rewritten and dropped axioms.
+func (r *ApproxReport) Counts() (rewritten, dropped int) {
+	for _, e := range r.Entries {
+		if e.Action == ApproxRewritten {
+			rewritten++
+		} else {
+			dropped++
+		}
+	}
+	return rewritten, dropped
+}
+
+type approximator struct {
+	ont    *ontology.Ontology
+	opts   NormalizeOptions
+	index  *ontology.Index     // built on first use
+	types  map[string][]string // individual → asserted classes, built on first use
+	report *ApproxReport
+}
+
+func newApproximator(ont *ontology.Ontology, opts NormalizeOptions) *approximator {
+	return &approximator{ont: ont, opts: opts, report: &ApproxReport{}}
+}
+
+// union handles C ≡ B₁ ⊔ ... ⊔ Bₙ. The rewriting keeps the exact EL half,
+// Bᵢ ⊑ C, and replaces C ⊑ B₁ ⊔ ... ⊔ Bₙ by C ⊑ D for the most specific
+// named classes D that every Bᵢ is asserted (is_a) to be subsumed by.
+func (a *approximator) union(st *SymbolTable, store *AxiomStore, cid ConceptID, t *ontology.Term) {
+	entry := ApproxEntry{TermID: t.ID, Construct: "union_of", Action: ApproxDropped}
+	if !a.opts.Approximate {
+		a.report.Entries = append(a.report.Entries, entry)
+		return
+	}
+	entry.Action = ApproxRewritten
+
+	for _, b := range t.UnionOf {
+		store.AddSubsumption(st.InternConcept(b), cid)
+		entry.Axioms = append(entry.Axioms, b+" SubClassOf "+t.ID)
+	}
+	ups := make([]map[string]bool, len(t.UnionOf))
+	for i, b := range t.UnionOf {
+		ups[i] = a.classUp(b)
+	}
+	for _, d := range a.commonAncestors(t.ID, ups) {
+		store.AddSubsumption(cid, st.InternConcept(d))
+		entry.Axioms = append(entry.Axioms, t.ID+" SubClassOf "+d)
+	}
+	store.Grow(st.ConceptCount())
+	a.report.Entries = append(a.report.Entries, entry)
+}
+
+// oneOf handles C ≡ {a₁, ..., aₙ} according to opts.OneOf.
+func (a *approximator) oneOf(st *SymbolTable, store *AxiomStore, cid ConceptID, t *ontology.Term) {
+	entry := ApproxEntry{TermID: t.ID, Construct: "one_of", Action: ApproxDropped}
+	if a.opts.OneOf == "" || a.opts.OneOf == OneOfSkip {
+		a.report.Entries = append(a.report.Entries, entry)
+		return
+	}
+	entry.Action = ApproxRewritten
+
+	for _, m := range t.OneOf {
+		store.AddSubsumption(st.NominalConcept(m), cid)
+		entry.Axioms = append(entry.Axioms, "{"+m+"} SubClassOf "+t.ID)
+	}
+	if a.opts.OneOf == OneOfExpand {
+		ups := make([]map[string]bool, len(t.OneOf))
+		for i, m := range t.OneOf {
+			ups[i] = a.individualUp(m)
+		}
+		for _, d := range a.commonAncestors(t.ID, ups) {
+			store.AddSubsumption(cid, st.InternConcept(d))
+			entry.Axioms = append(entry.Axioms, t.ID+" SubClassOf "+d)
+		}
+	}
+	store.Grow(st.ConceptCount())
+	a.report.Entries = append(a.report.Entries, entry)
+}
+
+// nonEL handles the subClassOf axioms whose superclass is outside EL
+// (Term.NonEL). Only C ⊑ B₁ ⊔ ... ⊔ Bₙ over named classes is rewritten,
+// the same way as the matching half of union, to C ⊑ D for the most
+// specific named classes D that every Bᵢ is asserted under. The others,
+// such as C ⊑ ∀R.B, entail nothing EL can say about C and are dropped.
+func (a *approximator) nonEL(st *SymbolTable, store *AxiomStore, cid ConceptID, t *ontology.Term) {
+	for _, ax := range t.NonEL {
+		entry := ApproxEntry{TermID: t.ID, Construct: ax.Construct, Action: ApproxDropped}
+		if a.opts.Approximate && ax.Construct == ontology.NonELUnionOf && len(ax.Targets) > 0 {
+			ups := make([]map[string]bool, len(ax.Targets))
+			for i, b := range ax.Targets {
+				ups[i] = a.classUp(b)
+			}
+			for _, d := range a.commonAncestors(t.ID, ups) {
+				store.AddSubsumption(cid, st.InternConcept(d))
+				entry.Axioms = append(entry.Axioms, t.ID+" SubClassOf "+d)
+			}
+			if len(entry.Axioms) > 0 {
+				entry.Action = ApproxRewritten
+			}
+		}
+		a.report.Entries = append(a.report.Entries, entry)
+	}
+	store.Grow(st.ConceptCount())
+}
+
+// classUp returns a class and its asserted ancestors.
+func (a *approximator) classUp(id string) map[string]bool {
+	if a.index == nil {
+		a.index = ontology.NewIndex(a.ont)
+	}
+	up := map[string]bool{id: true}
+	for _, anc := range a.index.Ancestors(id) {
+		up[anc] = true
+	}
+	return up
+}
+
+// individualUp returns the asserted types of an individual and their
+// ancestors.
+func (a *approximator) individualUp(id string) map[string]bool {
+	if a.types == nil {
+		a.types = make(map[string][]string, len(a.ont.Individuals))
+		for i := range a.ont.Individuals {
+			ind := &a.ont.Individuals[i]
+			a.types[ind.ID] = ind.Types
+		}
+	}
+	up := make(map[string]bool)
+	for _, typ := range a.types[id] {
+		for c := range a.classUp(typ) {
+			up[c] = true
+		}
+	}
+	return up
+}
+
+// commonAncestors returns the most specific classes present in every one
+// of ups (each a member's ancestors-or-self), excluding self and the
+// classes self is already asserted under.
+func (a *approximator) commonAncestors(self string, ups []map[string]bool) []string {
+	if a.index == nil {
+		a.index = ontology.NewIndex(a.ont)
+	}
+	var common map[string]bool
+	for _, up := range ups {
+		if common == nil {
+			common = up
+			continue
+		}
+		for c := range common {
+			if !up[c] {
+				delete(common, c)
+			}
+		}
+	}
+	delete(common, self)
+	for _, anc := range a.index.Ancestors(self) {
+		delete(common, anc)
+	}
+
+	// Keep only classes that are not ancestors of another common class.
+	covered := make(map[string]bool)
+	for c := range common {
+		for _, anc := range a.index.Ancestors(c) {
+			covered[anc] = true
+		}
+	}
+	var out []string
+	for c := range common {
+		if !covered[c] {
+			out = append(out, c)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// String renders an entry as a single report line.
+func (e ApproxEntry) String() string {
+	return e.TermID + "\t" + e.Construct + "\t" + e.Action + "\t" + strings.Join(e.Axioms, "; ")
+}
@@ -15,6 +15,8 @@ type RoleFiller struct {
 //   NF4: ∃R.A ⊑ B         (existential on the left)
 //   NF5: R ⊑ S            (role subsumption)
 //   NF6: R₁ ∘ R₂ ⊑ S     (role composition / property chain)
+//
+// plus the two local reflexivity forms A ⊑ ∃R.Self and ∃R.Self ⊑ B.
 type AxiomStore struct {
 	// NF1: subToSups[A] = list of B where A ⊑ B. Triggers CR1.
 	subToSups [][]ConceptID
@@ -35,9 +37,21 @@ type AxiomStore struct {
 	// NF6: roleChains[R1][R2] = list of S where R1 ∘ R2 ⊑ S. Triggers CR11.
 	roleChains []map[RoleID][]RoleID
 
+	// selfRight[A] = list of R where A ⊑ ∃R.Self. Triggers CR-Self.
+	selfRight [][]RoleID
+
+	// selfLeft[R] = list of B where ∃R.Self ⊑ B. Fired by CR-Self.
+	selfLeft [][]ConceptID
+
 	// Role properties.
 	transitive []bool
 	reflexive  []bool
+
+	// origins maps each axiom to the IDs of the stanzas it came from, when
+	// NormalizeOptions.Provenance is set; origin is the stanza being
+	// normalized.
+	origins map[axiomKey][]string
+	origin  string
 }
 
 // NewAxiomStore allocates an AxiomStore sized for the given symbol table.
@@ -49,9 +63,11 @@ func NewAxiomStore(st *SymbolTable) *AxiomStore {
 		subToSups:  make([][]ConceptID, nc),
 		conjIndex:  make([]map[ConceptID][]ConceptID, nc),
 		existRight: make([][]RoleFiller, nc),
+		selfRight:  make([][]RoleID, nc),
 		existLeft:  make([]map[ConceptID][]ConceptID, nr),
 		roleSubs:   make([][]RoleID, nr),
 		roleChains: make([]map[RoleID][]RoleID, nr),
+		selfLeft:   make([][]ConceptID, nr),
 		transitive: make([]bool, nr),
 		reflexive:  make([]bool, nr),
 	}
@@ -69,6 +85,9 @@ func (s *AxiomStore) Grow(nc int) {
 	for len(s.existRight) < nc {
 		s.existRight = append(s.existRight, nil)
 	}
+	for len(s.selfRight) < nc {
+		s.selfRight = append(s.selfRight, nil)
+	}
 }
 
 // GrowRoles expands all role-indexed slices.
@@ -82,6 +101,9 @@ func (s *AxiomStore) GrowRoles(nr int) {
 	for len(s.roleChains) < nr {
 		s.roleChains = append(s.roleChains, nil)
 	}
+	for len(s.selfLeft) < nr {
+		s.selfLeft = append(s.selfLeft, nil)
+	}
 	for len(s.transitive) < nr {
 		s.transitive = append(s.transitive, false)
 	}
@@ -93,6 +115,7 @@ func (s *AxiomStore) GrowRoles(nr int) {
 // AddSubsumption adds NF1: sub ⊑ sup.
 func (s *AxiomStore) AddSubsumption(sub, sup ConceptID) {
 	s.subToSups[sub] = append(s.subToSups[sub], sup)
+	s.record(axSub, uint32(sub), uint32(sup), 0)
 }
 
 // AddConjunction adds NF2: left1 ⊓ left2 ⊑ right (stored symmetrically).
@@ -108,11 +131,13 @@ func (s *AxiomStore) AddConjunction(left1, left2, right ConceptID) {
 		}
 		s.conjIndex[left2][left1] = append(s.conjIndex[left2][left1], right)
 	}
+	s.record(axConj, uint32(min(left1, left2)), uint32(max(left1, left2)), uint32(right))
 }
 
 // AddExistRight adds NF3: sub ⊑ ∃role.fill.
 func (s *AxiomStore) AddExistRight(sub ConceptID, role RoleID, fill ConceptID) {
 	s.existRight[sub] = append(s.existRight[sub], RoleFiller{Role: role, Fill: fill})
+	s.record(axExistRight, uint32(sub), uint32(role), uint32(fill))
 }
 
 // AddExistLeft adds NF4: ∃role.fill ⊑ sup.
@@ -121,11 +146,25 @@ func (s *AxiomStore) AddExistLeft(role RoleID, fill ConceptID, sup ConceptID) {
 		s.existLeft[role] = make(map[ConceptID][]ConceptID, 4)
 	}
 	s.existLeft[role][fill] = append(s.existLeft[role][fill], sup)
+	s.record(axExistLeft, uint32(role), uint32(fill), uint32(sup))
+}
+
+// AddSelfRight adds sub ⊑ ∃role.Self.
+func (s *AxiomStore) AddSelfRight(sub ConceptID, role RoleID) {
+	s.selfRight[sub] = append(s.selfRight[sub], role)
+	s.record(axSelfRight, uint32(sub), uint32(role), 0)
+}
+
+// AddSelfLeft adds ∃role.Self ⊑ sup.
+func (s *AxiomStore) AddSelfLeft(role RoleID, sup ConceptID) {
+	s.selfLeft[role] = append(s.selfLeft[role], sup)
+	s.record(axSelfLeft, uint32(role), uint32(sup), 0)
 }
 
 // AddRoleSub adds NF5: sub ⊑ sup.
 func (s *AxiomStore) AddRoleSub(sub, sup RoleID) {
 	s.roleSubs[sub] = append(s.roleSubs[sub], sup)
+	s.record(axRoleSub, uint32(sub), uint32(sup), 0)
 }
 
 // AddRoleChain adds NF6: left1 ∘ left2 ⊑ right.
@@ -134,6 +173,7 @@ func (s *AxiomStore) AddRoleChain(left1, left2, right RoleID) {
 		s.roleChains[left1] = make(map[RoleID][]RoleID, 4)
 	}
 	s.roleChains[left1][left2] = append(s.roleChains[left1][left2], right)
+	s.record(axRoleChain, uint32(left1), uint32(left2), uint32(right))
 }
 
 // SetTransitive marks a role as transitive (equivalent to R ∘ R ⊑ R).
@@ -142,7 +182,8 @@ func (s *AxiomStore) SetTransitive(r RoleID) {
 	s.AddRoleChain(r, r, r)
 }
 
-// SetReflexive marks a role as reflexive.
+// SetReflexive marks a role as reflexive: Saturate gives every concept a
+// self link through it.
 func (s *AxiomStore) SetReflexive(r RoleID) {
 	s.reflexive[r] = true
 }
@@ -0,0 +1,58 @@
+package reasoner
+
+import "slices"
+
+// Cleanup reasons.
+const (
+	CleanupDuplicate = "duplicate"
+	CleanupSelfIsA   = "self_is_a"
+)
+
+// CleanupEntry records an asserted axiom normalization dropped as
+// redundant: an exact repeat of another axiom of the same term or
+// individual, or an is_a edge from a term to itself. ChEBI exports contain
+// both occasionally; neither changes the classification, but every copy
+// costs a store entry and a worklist push.
+type CleanupEntry struct {
+	TermID string `json:"term_id"`
+	Reason string `json:"reason"` // CleanupDuplicate or CleanupSelfIsA
+	Axiom  string `json:"axiom"`  // e.g. "is_a CHEBI:33285"
+}
+
+// String formats the entry in the columns of ApproxEntry.String.
+func (e CleanupEntry) String() string {
+	return e.TermID + "\t" + e.Reason + "\t" + ApproxDropped + "\t" + e.Axiom
+}
+
+// CleanupCounts returns the number of duplicate axioms and self is_a edges
+// dropped.
+func (r *ApproxReport) CleanupCounts() (duplicates, selfIsA int) {
+	for _, e := range r.Cleanup {
+		if e.Reason == CleanupSelfIsA {
+			selfIsA++
+		} else {
+			duplicates++
+		}
+	}
+	return duplicates, selfIsA
+}
+
+// termAxioms holds the axioms already added for the term or individual
+// being normalized. A term carries a handful of axioms, so a linear scan
+// is cheaper than a map over the whole ontology.
+type termAxioms struct {
+	seen []axiomKey
+}
+
+func (ta *termAxioms) reset() {
+	ta.seen = ta.seen[:0]
+}
+
+// add reports whether k is new for the current term, remembering it.
+func (ta *termAxioms) add(k axiomKey) bool {
+	if slices.Contains(ta.seen, k) {
+		return false
+	}
+	ta.seen = append(ta.seen, k)
+	return true
+}
@@ -0,0 +1,67 @@
+package reasoner
+
+import (
+	"slices"
+
+	"github.com/nodeadmin/chebi-parser/internal/intsets"
+)
+
+// ConceptSets is one sorted set of concepts per concept, stored as CSR:
+// the set of c is Members[Offsets[c]:Offsets[c+1]].
+type ConceptSets struct {
+	Offsets []uint32
+	Members []ConceptID
+}
+
+// Of returns the set of c, sorted by ID. The slice aliases the sets and
+// must not be modified.
+func (cs *ConceptSets) Of(c ConceptID) []ConceptID {
+	return cs.Members[cs.Offsets[c]:cs.Offsets[c+1]]
+}
+
+// Contains reports whether a is in the set of c.
+func (cs *ConceptSets) Contains(c, a ConceptID) bool {
+	return intsets.Contains(cs.Of(c), a)
+}
+
+// Common appends the members shared by the sets of a and b to dst, sorted,
+// and returns the extended slice. Over Ancestors these are the common
+// subsumers of a and b.
+func (cs *ConceptSets) Common(dst []ConceptID, a, b ConceptID) []ConceptID {
+	return intsets.Intersect(dst, cs.Of(a), cs.Of(b))
+}
+
+// Ancestors computes the transitive closure of the taxonomy: for every
+// concept, all concepts above it, excluding itself, owl:Thing and
+// owl:Nothing. Equivalent concepts are ancestors of each other. Sorted
+// sets suit the merge-based comparisons of LCA and similarity measures.
+func (tax *Taxonomy) Ancestors(st *SymbolTable) *ConceptSets {
+	n := st.ConceptCount()
+	cs := &ConceptSets{Offsets: make([]uint32, n+1)}
+	stamp := make([]uint32, n) // stamp[a] == c+1 once a is seen from c
+	queue := make([]ConceptID, 0, 64)
+	for c := ConceptID(0); c < ConceptID(n); c++ {
+		start := len(cs.Members)
+		if c >= 2 {
+			stamp[c] = uint32(c) + 1
+			queue = append(queue[:0], c)
+			for len(queue) > 0 {
+				cur := queue[0]
+				queue = queue[1:]
+				for _, p := range tax.DirectParents(cur) {
+					if stamp[p] == uint32(c)+1 {
+						continue
+					}
+					stamp[p] = uint32(c) + 1
+					queue = append(queue, p)
+					if p != Top && p != Bottom {
+						cs.Members = append(cs.Members, p)
+					}
+				}
+			}
+			slices.Sort(cs.Members[start:])
+		}
+		cs.Offsets[c+1] = uint32(len(cs.Members))
+	}
+	return cs
+}
@@ -0,0 +1,101 @@
+package reasoner
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Subsumption is an entailed Sub ⊑ Super between named concepts.
+type Subsumption struct {
+	Sub, Super string
+}
+
+// Subsumptions returns every entailed subsumption between named concepts,
+// direct or indirect, sorted. Reflexive pairs and owl:Thing are omitted;
+// unsatisfiable concepts are reported as subsumed by owl:Nothing. This is
+// the same set ELK produces with indirect SubClassOf axioms included.
+func Subsumptions(contexts []Context, st *SymbolTable) []Subsumption {
+	var out []Subsumption
+	for c := ConceptID(2); c < ConceptID(st.ConceptCount()); c++ {
+		if !st.IsClass(c) {
+			continue
+		}
+		name := st.ConceptName(c)
+		for s := range contexts[c].superSet {
+			if s == c || s == Top {
+				continue
+			}
+			if st.IsClass(s) {
+				out = append(out, Subsumption{Sub: name, Super: st.ConceptName(s)})
+			}
+		}
+	}
+	sortSubsumptions(out)
+	return out
+}
+
+// ReadSubsumptions reads reference subsumptions as TSV lines of
+// "sub<TAB>super". Blank lines and lines starting with # are ignored, as are
+// reflexive pairs and owl:Thing superclasses, which reasoners differ on
+// reporting.
+func ReadSubsumptions(r io.Reader) ([]Subsumption, error) {
+	var out []Subsumption
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || text[0] == '#' {
+			continue
+		}
+		sub, super, ok := strings.Cut(text, "\t")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected sub<TAB>super", line)
+		}
+		sub, super = strings.TrimSpace(sub), strings.TrimSpace(super)
+		if sub == super || super == "owl:Thing" {
+			continue
+		}
+		out = append(out, Subsumption{Sub: sub, Super: super})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	sortSubsumptions(out)
+	return out, nil
+}
+
+// CompareSubsumptions reports the reference subsumptions the reasoner
+// missed and the ones it inferred that the reference lacks. Both results
+// are sorted.
+func CompareSubsumptions(got, want []Subsumption) (missing, unexpected []Subsumption) {
+	have := make(map[Subsumption]bool, len(got))
+	for _, s := range got {
+		have[s] = true
+	}
+	expected := make(map[Subsumption]bool, len(want))
+	for _, s := range want {
+		expected[s] = true
+		if !have[s] {
+			missing = append(missing, s)
+		}
+	}
+	for _, s := range got {
+		if !expected[s] {
+			unexpected = append(unexpected, s)
+		}
+	}
+	sortSubsumptions(missing)
+	sortSubsumptions(unexpected)
+	return missing, unexpected
+}
+
+func sortSubsumptions(s []Subsumption) {
+	sort.Slice(s, func(i, j int) bool {
+		if s[i].Sub != s[j].Sub {
+			return s[i].Sub < s[j].Sub
+		}
+		return s[i].Super < s[j].Super
+	})
+}
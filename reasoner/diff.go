@@ -0,0 +1,150 @@
+package reasoner
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// ReadClassifiedJSON reads a hierarchy written by WriteClassifiedJSON.
+func ReadClassifiedJSON(r io.Reader) (*ClassifiedHierarchy, error) {
+	var h ClassifiedHierarchy
+	if err := json.NewDecoder(r).Decode(&h); err != nil {
+		return nil, fmt.Errorf("classified hierarchy: %w", err)
+	}
+	return &h, nil
+}
+
+// Subsumptions returns every subsumption the hierarchy entails, the
+// transitive closure of its direct parents, sorted. As with the
+// package-level Subsumptions, reflexive pairs and owl:Thing are omitted.
+func (h *ClassifiedHierarchy) Subsumptions() []Subsumption {
+	index := make(map[string]int32, len(h.Concepts))
+	for i := range h.Concepts {
+		index[h.Concepts[i].ID] = int32(i)
+	}
+	parents := make([][]int32, len(h.Concepts))
+	var names []string // supers that are not concepts, such as owl:Nothing
+	for i := range h.Concepts {
+		for _, p := range h.Concepts[i].DirectParents {
+			if p == "owl:Thing" {
+				continue
+			}
+			j, ok := index[p]
+			if !ok {
+				j = int32(len(h.Concepts) + len(names))
+				index[p] = j
+				names = append(names, p)
+			}
+			parents[i] = append(parents[i], j)
+		}
+	}
+	name := func(j int32) string {
+		if int(j) < len(h.Concepts) {
+			return h.Concepts[j].ID
+		}
+		return names[int(j)-len(h.Concepts)]
+	}
+
+	var out []Subsumption
+	stamp := make([]int32, len(h.Concepts)+len(names))
+	var stack []int32
+	for i := range h.Concepts {
+		sub := h.Concepts[i].ID
+		mark := int32(i) + 1
+		stamp[i] = mark
+		stack = append(stack[:0], parents[i]...)
+		for len(stack) > 0 {
+			j := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if stamp[j] == mark {
+				continue
+			}
+			stamp[j] = mark
+			out = append(out, Subsumption{Sub: sub, Super: name(j)})
+			if int(j) < len(h.Concepts) {
+				stack = append(stack, parents[j]...)
+			}
+		}
+	}
+	sortSubsumptions(out)
+	return out
+}
+
+// HierarchyDiff is how the entailed subsumptions changed between two
+// classifications, grouped by subclass.
+type HierarchyDiff struct {
+	Terms        []SubsumptionChange `json:"terms"`
+	AddedTerms   []string            `json:"added_terms,omitempty"`   // classes only the new hierarchy has
+	RemovedTerms []string            `json:"removed_terms,omitempty"` // classes only the old hierarchy has
+	Gained       int                 `json:"gained"`
+	Lost         int                 `json:"lost"`
+}
+
+// SubsumptionChange lists the superclasses a class gained and lost.
+type SubsumptionChange struct {
+	ID     string   `json:"id"`
+	Name   string   `json:"name,omitempty"`
+	Gained []string `json:"gained,omitempty"`
+	Lost   []string `json:"lost,omitempty"`
+}
+
+// DiffHierarchies compares the subsumptions two classifications entail,
+// indirect ones included, so a changed axiom shows up on every class whose
+// ancestry it changes, not only where the direct parents moved. Classes in
+// only one hierarchy are listed as added or removed rather than with their
+// whole ancestry. Terms are sorted by ID.
+func DiffHierarchies(old, new *ClassifiedHierarchy) *HierarchyDiff {
+	inOld := make(map[string]bool, len(old.Concepts))
+	for i := range old.Concepts {
+		inOld[old.Concepts[i].ID] = true
+	}
+	inNew := make(map[string]bool, len(new.Concepts))
+	for i := range new.Concepts {
+		inNew[new.Concepts[i].ID] = true
+	}
+	d := &HierarchyDiff{Terms: []SubsumptionChange{}}
+	for id := range inNew {
+		if !inOld[id] {
+			d.AddedTerms = append(d.AddedTerms, id)
+		}
+	}
+	for id := range inOld {
+		if !inNew[id] {
+			d.RemovedTerms = append(d.RemovedTerms, id)
+		}
+	}
+	sort.Strings(d.AddedTerms)
+	sort.Strings(d.RemovedTerms)
+
+	lost, gained := CompareSubsumptions(new.Subsumptions(), old.Subsumptions())
+	byID := make(map[string]*SubsumptionChange)
+	change := func(id string) *SubsumptionChange {
+		c := byID[id]
+		if c == nil {
+			c = &SubsumptionChange{ID: id}
+			byID[id] = c
+		}
+		return c
+	}
+	for _, s := range gained {
+		if inOld[s.Sub] {
+			c := change(s.Sub)
+			c.Gained = append(c.Gained, s.Super)
+			d.Gained++
+		}
+	}
+	for _, s := range lost {
+		if inNew[s.Sub] {
+			c := change(s.Sub)
+			c.Lost = append(c.Lost, s.Super)
+			d.Lost++
+		}
+	}
+	for _, c := range byID {
+		d.Terms = append(d.Terms, *c)
+	}
+	sort.Slice(d.Terms, func(i, j int) bool { return d.Terms[i].ID < d.Terms[j].ID })
+	return d
+}
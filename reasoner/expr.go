@@ -0,0 +1,326 @@
+package reasoner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/nodeadmin/chebi-parser/ontology"
+)
+
+// ExprError is a syntax or name error in a class expression. Col is the
+// 1-based column where the offending token starts.
+type ExprError struct {
+	Col int
+	Msg string
+}
+
+func (e *ExprError) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Col, e.Msg)
+}
+
+// Resolver maps class and relation names as written in an expression to
+// their IDs.
+type Resolver interface {
+	ResolveClass(name string) (string, error)
+	ResolveRelation(name string) (string, error)
+}
+
+// ParseExpression parses a class expression in a Manchester syntax subset:
+//
+//	expr    = conj { "and" conj }
+//	conj    = primary | name "some" primary | "is_a" primary
+//	primary = name | "(" expr ")"
+//
+// A name is an ID or label; labels containing spaces or parentheses are
+// quoted as 'alpha-amino acid'. Keywords are case-insensitive. If resolve
+// is nil, names are taken as IDs unchanged.
+func ParseExpression(s string, resolve Resolver) (*Expr, error) {
+	toks, err := lexExpr(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{toks: toks, resolve: resolve, end: len(s) + 1}
+	e, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t != nil {
+		if t.text == ")" && !t.quoted {
+			return nil, p.errorf(t, "unmatched \")\"")
+		}
+		if msg, ok := unsupported[strings.ToLower(t.text)]; ok && !t.quoted {
+			return nil, p.errorf(t, "%s", msg)
+		}
+		return nil, p.errorf(t, "expected \"and\" or end of expression, found %q", t.text)
+	}
+	return e, nil
+}
+
+type exprToken struct {
+	text   string
+	col    int
+	quoted bool
+}
+
+func lexExpr(s string) ([]exprToken, error) {
+	var toks []exprToken
+	rs := []rune(s)
+	for i := 0; i < len(rs); {
+		switch r := rs[i]; {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')':
+			toks = append(toks, exprToken{text: string(r), col: i + 1})
+			i++
+		case r == '\'' || r == '"':
+			j := i + 1
+			for j < len(rs) && rs[j] != r {
+				j++
+			}
+			if j == len(rs) {
+				return nil, &ExprError{Col: i + 1, Msg: "unterminated quoted name"}
+			}
+			toks = append(toks, exprToken{text: string(rs[i+1 : j]), col: i + 1, quoted: true})
+			i = j + 1
+		default:
+			j := i
+			for j < len(rs) && !unicode.IsSpace(rs[j]) && rs[j] != '(' && rs[j] != ')' {
+				j++
+			}
+			toks = append(toks, exprToken{text: string(rs[i:j]), col: i + 1})
+			i = j
+		}
+	}
+	return toks, nil
+}
+
+// unsupported maps Manchester keywords outside EL to an explanation.
+var unsupported = map[string]string{
+	"or":      "\"or\" (union) is not supported: EL queries are conjunctions",
+	"not":     "\"not\" (complement) is not supported in EL",
+	"only":    "\"only\" (universal restriction) is not supported in EL",
+	"min":     "cardinality restrictions are not supported in EL",
+	"max":     "cardinality restrictions are not supported in EL",
+	"exactly": "cardinality restrictions are not supported in EL",
+	"value":   "\"value\" restrictions are not supported in queries",
+	"that":    "\"that\" is not supported; use \"and\"",
+}
+
+type exprParser struct {
+	toks    []exprToken
+	pos     int
+	resolve Resolver
+	end     int // column reported for errors at end of input
+}
+
+func (p *exprParser) peek() *exprToken {
+	if p.pos < len(p.toks) {
+		return &p.toks[p.pos]
+	}
+	return nil
+}
+
+func (p *exprParser) errorf(t *exprToken, format string, args ...any) error {
+	col := p.end
+	if t != nil {
+		col = t.col
+	}
+	return &ExprError{Col: col, Msg: fmt.Sprintf(format, args...)}
+}
+
+// keyword reports whether t is the unquoted keyword kw.
+func keyword(t *exprToken, kw string) bool {
+	return t != nil && !t.quoted && strings.EqualFold(t.text, kw)
+}
+
+func (p *exprParser) expr() (*Expr, error) {
+	e := &Expr{}
+	for {
+		if err := p.conj(e); err != nil {
+			return nil, err
+		}
+		if !keyword(p.peek(), "and") {
+			return e, nil
+		}
+		p.pos++
+	}
+}
+
+// conj parses one conjunct and adds it to e.
+func (p *exprParser) conj(e *Expr) error {
+	t := p.peek()
+	if keyword(t, "is_a") {
+		p.pos++
+		return p.primary(e, "is_a")
+	}
+	if t != nil && (t.quoted || t.text != "(" && t.text != ")") {
+		if next := p.lookahead(1); keyword(next, "some") {
+			if err := p.checkName(t); err != nil {
+				return err
+			}
+			role := t.text
+			if p.resolve != nil {
+				id, err := p.resolve.ResolveRelation(role)
+				if err != nil {
+					return p.errorf(t, "%v", err)
+				}
+				role = id
+			}
+			p.pos += 2
+			filler := &Expr{}
+			if err := p.primary(filler, "some"); err != nil {
+				return err
+			}
+			e.Some = append(e.Some, Restriction{Role: role, Filler: filler})
+			return nil
+		}
+	}
+	// Report "R only C" and the like at the keyword, not as an unknown class R.
+	if next := p.lookahead(1); next != nil && !next.quoted {
+		if msg, ok := unsupported[strings.ToLower(next.text)]; ok {
+			return p.errorf(next, "%s", msg)
+		}
+	}
+	return p.primary(e, "")
+}
+
+func (p *exprParser) lookahead(n int) *exprToken {
+	if p.pos+n < len(p.toks) {
+		return &p.toks[p.pos+n]
+	}
+	return nil
+}
+
+// primary parses a class name or a parenthesized expression and adds it
+// to e. after names the keyword that preceded it, for error messages.
+func (p *exprParser) primary(e *Expr, after string) error {
+	t := p.peek()
+	switch {
+	case t == nil:
+		if after == "" {
+			return p.errorf(nil, "expected a class, found end of expression")
+		}
+		return p.errorf(nil, "expected a class after %q, found end of expression", after)
+	case !t.quoted && t.text == "(":
+		p.pos++
+		inner, err := p.expr()
+		if err != nil {
+			return err
+		}
+		if c := p.peek(); c == nil || c.quoted || c.text != ")" {
+			return p.errorf(c, "missing \")\" to close \"(\" at column %d", t.col)
+		}
+		p.pos++
+		e.Classes = append(e.Classes, inner.Classes...)
+		e.Some = append(e.Some, inner.Some...)
+		return nil
+	case !t.quoted && t.text == ")":
+		return p.errorf(t, "expected a class, found \")\"")
+	}
+	if err := p.checkName(t); err != nil {
+		return err
+	}
+	id := t.text
+	if p.resolve != nil {
+		var err error
+		if id, err = p.resolve.ResolveClass(t.text); err != nil {
+			return p.errorf(t, "%v", err)
+		}
+	}
+	p.pos++
+	e.Classes = append(e.Classes, id)
+	return nil
+}
+
+// checkName rejects keywords where a name is expected.
+func (p *exprParser) checkName(t *exprToken) error {
+	if t.quoted {
+		return nil
+	}
+	lower := strings.ToLower(t.text)
+	if msg, ok := unsupported[lower]; ok {
+		return p.errorf(t, "%s", msg)
+	}
+	if lower == "and" || lower == "some" {
+		return p.errorf(t, "expected a name, found %q", t.text)
+	}
+	return nil
+}
+
+// LabelResolver resolves class names through ontology.Index.Lookup (IDs,
+// alt IDs, labels and synonyms, rejecting obsolete terms unless its
+// policy keeps them) and relation names by ID or, case-insensitively, by
+// typedef label.
+type LabelResolver struct {
+	ix           *ontology.Index
+	keepObsolete bool
+	relIDs       map[string]bool
+	relations    map[string][]string // lower-cased label → IDs
+}
+
+// NewLabelResolver indexes the class and relation names of ont, leaving
+// obsolete terms out as classification does by default.
+func NewLabelResolver(ont *ontology.Ontology) *LabelResolver {
+	return NewLabelResolverWithOptions(ont, ontology.ObsoleteDefault)
+}
+
+// NewLabelResolverWithOptions is NewLabelResolver under an obsolete-term
+// policy, which should be the one the ontology was normalized with
+// (NormalizeOptions.Obsolete).
+func NewLabelResolverWithOptions(ont *ontology.Ontology, obsolete ontology.ObsoletePolicy) *LabelResolver {
+	lr := &LabelResolver{
+		ix:           ontology.NewIndex(ont),
+		keepObsolete: obsolete.Keep(false),
+		relIDs:       make(map[string]bool, len(ont.TypeDefs)),
+		relations:    make(map[string][]string, len(ont.TypeDefs)),
+	}
+	for i := range ont.Terms {
+		t := &ont.Terms[i]
+		if t.IsObsolete && !lr.keepObsolete {
+			continue
+		}
+		for _, rel := range t.Relationships {
+			if rel.Type != "is_a" {
+				lr.relIDs[rel.Type] = true
+			}
+		}
+	}
+	for i := range ont.TypeDefs {
+		td := &ont.TypeDefs[i]
+		lr.relIDs[td.ID] = true
+		if td.Name != "" {
+			key := strings.ToLower(td.Name)
+			lr.relations[key] = append(lr.relations[key], td.ID)
+		}
+	}
+	return lr
+}
+
+func (lr *LabelResolver) ResolveClass(name string) (string, error) {
+	id, err := lr.ix.Lookup(name)
+	if err != nil {
+		return "", err
+	}
+	if lr.ix.Term(id).IsObsolete && !lr.keepObsolete {
+		return "", fmt.Errorf("class %q is obsolete (%s)", name, id)
+	}
+	return id, nil
+}
+
+func (lr *LabelResolver) ResolveRelation(name string) (string, error) {
+	if lr.relIDs[name] {
+		return name, nil
+	}
+	switch matches := lr.relations[strings.ToLower(name)]; len(matches) {
+	case 0:
+		return "", fmt.Errorf("unknown relation %q", name)
+	case 1:
+		return matches[0], nil
+	default:
+		sorted := append([]string(nil), matches...)
+		sort.Strings(sorted)
+		return "", fmt.Errorf("relation label %q is ambiguous: %s", name, strings.Join(sorted, ", "))
+	}
+}
@@ -0,0 +1,55 @@
+package reasoner
+
+import (
+	"fmt"
+	"slices"
+)
+
+// ResolveRoles looks up relations by ID, for TaxonomyOptions.Fillers.
+func ResolveRoles(st *SymbolTable, ids []string) ([]RoleID, error) {
+	roles := make([]RoleID, 0, len(ids))
+	for _, id := range ids {
+		r, ok := st.LookupRole(id)
+		if !ok {
+			return nil, fmt.Errorf("unknown relation %q", id)
+		}
+		roles = append(roles, r)
+	}
+	return roles, nil
+}
+
+// fillers returns the named classes E with C ⊑ ∃R.E after saturation, in
+// concept order. The R-links of C (told, or derived through subproperties
+// and chains) reach concepts D, often fresh ones, and every named class in
+// S(D) is a filler. If direct is set, those with a more specific filler
+// below them are left out, as direct_parents leaves out indirect
+// ancestors; equivalent fillers are all kept.
+func fillers(contexts []Context, st *SymbolTable, c ConceptID, r RoleID, direct bool) []string {
+	ctx := &contexts[c]
+	if _, unsat := ctx.superSet[Bottom]; unsat || int(r) >= len(ctx.linkMap) {
+		return nil
+	}
+	var cands []ConceptID
+	for _, d := range ctx.linkMap[r] {
+		for e := range contexts[d].superSet {
+			if e != Top && st.IsClass(e) && !slices.Contains(cands, e) {
+				cands = append(cands, e)
+			}
+		}
+	}
+	slices.Sort(cands)
+	// below reports whether f is a filler strictly more specific than e.
+	below := func(f, e ConceptID) bool {
+		_, sub := contexts[f].superSet[e]
+		_, sup := contexts[e].superSet[f]
+		return f != e && sub && !sup
+	}
+	var out []string
+	for _, e := range cands {
+		if direct && slices.ContainsFunc(cands, func(f ConceptID) bool { return below(f, e) }) {
+			continue
+		}
+		out = append(out, st.ConceptName(e))
+	}
+	return out
+}
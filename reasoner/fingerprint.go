@@ -0,0 +1,140 @@
+package reasoner
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+	"slices"
+)
+
+// Fingerprint returns a hex SHA-256 of the normalized axiom set together
+// with the symbol table it is numbered against. Concepts and roles are
+// hashed in ID order with their names, and every index in a fixed order
+// with its map keys and lists sorted, so the same ontology always gives
+// the same fingerprint and anything that changes the classification
+// output, including the order of concepts in it, changes the fingerprint.
+func (s *AxiomStore) Fingerprint(st *SymbolTable) string {
+	f := fingerprinter{h: sha256.New()}
+	f.int(st.ConceptCount())
+	for c := 0; c < st.ConceptCount(); c++ {
+		f.str(st.ConceptName(ConceptID(c)))
+	}
+	f.int(st.RoleCount())
+	for r := 0; r < st.RoleCount(); r++ {
+		f.str(st.RoleName(RoleID(r)))
+	}
+
+	f.int(len(s.subToSups))
+	for _, sups := range s.subToSups {
+		f.concepts(sups)
+	}
+	f.int(len(s.conjIndex))
+	for _, m := range s.conjIndex {
+		f.conceptMap(m)
+	}
+	f.int(len(s.existRight))
+	for _, rfs := range s.existRight {
+		rfs = slices.Clone(rfs)
+		slices.SortFunc(rfs, func(a, b RoleFiller) int {
+			if a.Role != b.Role {
+				return int(a.Role) - int(b.Role)
+			}
+			return int(a.Fill) - int(b.Fill)
+		})
+		f.int(len(rfs))
+		for _, rf := range rfs {
+			f.int(int(rf.Role))
+			f.int(int(rf.Fill))
+		}
+	}
+	f.int(len(s.existLeft))
+	for _, m := range s.existLeft {
+		f.conceptMap(m)
+	}
+	f.int(len(s.roleSubs))
+	for _, subs := range s.roleSubs {
+		f.roles(subs)
+	}
+	f.int(len(s.roleChains))
+	for _, m := range s.roleChains {
+		keys := make([]RoleID, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		f.int(len(keys))
+		for _, k := range keys {
+			f.int(int(k))
+			f.roles(m[k])
+		}
+	}
+	f.int(len(s.selfRight))
+	for _, rs := range s.selfRight {
+		f.roles(rs)
+	}
+	f.int(len(s.selfLeft))
+	for _, cs := range s.selfLeft {
+		f.concepts(cs)
+	}
+	f.int(len(s.transitive))
+	for r := range s.transitive {
+		f.bool(s.transitive[r])
+		f.bool(r < len(s.reflexive) && s.reflexive[r])
+	}
+	return hex.EncodeToString(f.h.Sum(nil))
+}
+
+type fingerprinter struct {
+	h   hash.Hash
+	buf [8]byte
+}
+
+func (f *fingerprinter) int(n int) {
+	binary.LittleEndian.PutUint64(f.buf[:], uint64(n))
+	f.h.Write(f.buf[:])
+}
+
+func (f *fingerprinter) bool(b bool) {
+	if b {
+		f.int(1)
+	} else {
+		f.int(0)
+	}
+}
+
+func (f *fingerprinter) str(s string) {
+	f.int(len(s))
+	f.h.Write([]byte(s))
+}
+
+func (f *fingerprinter) concepts(ids []ConceptID) {
+	ids = slices.Clone(ids)
+	slices.Sort(ids)
+	f.int(len(ids))
+	for _, id := range ids {
+		f.int(int(id))
+	}
+}
+
+func (f *fingerprinter) roles(ids []RoleID) {
+	ids = slices.Clone(ids)
+	slices.Sort(ids)
+	f.int(len(ids))
+	for _, id := range ids {
+		f.int(int(id))
+	}
+}
+
+func (f *fingerprinter) conceptMap(m map[ConceptID][]ConceptID) {
+	keys := make([]ConceptID, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	f.int(len(keys))
+	for _, k := range keys {
+		f.int(int(k))
+		f.concepts(m[k])
+	}
+}
@@ -0,0 +1,26 @@
+package reasoner
+
+import "github.com/nodeadmin/chebi-parser/ontology"
+
+// NewGraph classifies ont and builds its ontology.Graph: the asserted
+// edges plus the inferred direct superclasses.
+func NewGraph(ont *ontology.Ontology, opts NormalizeOptions, workers int) *ontology.Graph {
+	st, store, _ := NormalizeWithOptions(ont, opts)
+	contexts := SaturateParallel(st, store, workers)
+	return BuildGraph(ont, st, contexts, BuildTaxonomy(contexts, st), ontology.GraphOptions{Obsolete: opts.Obsolete})
+}
+
+// BuildGraph builds the ontology.Graph of ont from a classification of
+// it: each term's direct superclasses it does not assert (newParents)
+// become Inferred is_a edges. With a nil tax, only the asserted edges
+// (and those qualified is_inferred) are included. opts.InferredParents
+// is replaced.
+func BuildGraph(ont *ontology.Ontology, st *SymbolTable, contexts []Context, tax *Taxonomy, opts ontology.GraphOptions) *ontology.Graph {
+	opts.InferredParents = nil
+	if tax != nil {
+		opts.InferredParents = func(t *ontology.Term) []string {
+			return newParents(st, contexts, tax, t)
+		}
+	}
+	return ontology.NewGraphWithOptions(ont, opts)
+}
@@ -57,6 +57,18 @@ func (st *SymbolTable) InternRole(name string) RoleID {
 	return id
 }
 
+// LookupConcept returns the ConceptID for name without creating one.
+func (st *SymbolTable) LookupConcept(name string) (ConceptID, bool) {
+	id, ok := st.conceptToID[name]
+	return id, ok
+}
+
+// LookupRole returns the RoleID for name without creating one.
+func (st *SymbolTable) LookupRole(name string) (RoleID, bool) {
+	id, ok := st.roleToID[name]
+	return id, ok
+}
+
 func (st *SymbolTable) ConceptCount() int { return len(st.idToConcept) }
 func (st *SymbolTable) RoleCount() int    { return len(st.idToRole) }
 
@@ -76,6 +88,19 @@ func (st *SymbolTable) RoleName(id RoleID) string {
 	return ""
 }
 
+// NominalConcept returns the ConceptID for the nominal {individual}, the
+// class whose only member is the named individual.
+func (st *SymbolTable) NominalConcept(individual string) ConceptID {
+	return st.InternConcept("{" + individual + "}")
+}
+
+// IsClass reports whether id is a named class, as opposed to a fresh
+// concept introduced by normalization or a nominal.
+func (st *SymbolTable) IsClass(id ConceptID) bool {
+	name := st.ConceptName(id)
+	return name != "" && name[0] != '{'
+}
+
 // FreshConcept creates a new anonymous concept with a generated name.
 func (st *SymbolTable) FreshConcept() ConceptID {
 	id := ConceptID(len(st.idToConcept))
@@ -0,0 +1,264 @@
+package reasoner
+
+import (
+	"slices"
+	"sort"
+
+	"github.com/nodeadmin/chebi-parser/ontology"
+)
+
+// IntervalLabels answers inferred subsumption between named classes from a
+// compressed transitive closure (Agrawal, Borgida and Jagadish, 1989),
+// so a classification can be queried after its contexts are released.
+//
+// The taxonomy is walked downwards depth first, numbering concepts in
+// post-order; a concept's spanning-tree descendants then have numbers in
+// one interval [low, post]. Descendants reached through a second parent
+// add intervals, merged where they touch, so a concept carries two
+// integers plus one pair per extra run of descendants. In a mostly
+// tree-shaped hierarchy like ChEBI most concepts keep a single interval.
+// Equivalent classes, which the taxonomy lists as parents of each other,
+// are collapsed first and share their labels.
+type IntervalLabels struct {
+	classes map[string]uint32 // named class → concept index
+	comp    []uint32          // concept → component of equivalent concepts
+	post    []uint32          // component → post-order number
+	offsets []uint32          // component → its intervals in spans
+	spans   []uint32          // lo, hi pairs, sorted and disjoint
+}
+
+// ClassifyIntervals classifies ont and labels the result, keeping only the
+// labels.
+func ClassifyIntervals(ont *ontology.Ontology, opts NormalizeOptions, workers int) *IntervalLabels {
+	st, store, _ := NormalizeWithOptions(ont, opts)
+	contexts := SaturateParallel(st, store, workers)
+	return BuildTaxonomy(contexts, st).IntervalLabels(st)
+}
+
+// IntervalLabels builds the interval labels of the taxonomy. owl:Thing and
+// owl:Nothing are left out, as in Ancestors.
+func (tax *Taxonomy) IntervalLabels(st *SymbolTable) *IntervalLabels {
+	n := st.ConceptCount()
+	l := &IntervalLabels{classes: make(map[string]uint32, n)}
+	for c := ConceptID(2); c < ConceptID(n); c++ {
+		if st.IsClass(c) {
+			l.classes[st.ConceptName(c)] = uint32(c)
+		}
+	}
+	parents := func(c ConceptID) []ConceptID { return tax.DirectParents(c) }
+	l.comp = components(n, parents)
+	ncomp := 0
+	for _, k := range l.comp {
+		ncomp = max(ncomp, int(k)+1)
+	}
+
+	// Component DAG, edges from parent to child.
+	childOffsets := make([]uint32, ncomp+1)
+	hasParent := make([]bool, ncomp)
+	type edge struct{ parent, child uint32 }
+	var edges []edge
+	for c := ConceptID(2); c < ConceptID(n); c++ {
+		for _, p := range parents(c) {
+			if p == Top || p == Bottom || l.comp[p] == l.comp[c] {
+				continue
+			}
+			edges = append(edges, edge{l.comp[p], l.comp[c]})
+		}
+	}
+	slices.SortFunc(edges, func(a, b edge) int {
+		if a.parent != b.parent {
+			return int(a.parent) - int(b.parent)
+		}
+		return int(a.child) - int(b.child)
+	})
+	edges = slices.Compact(edges)
+	children := make([]uint32, len(edges))
+	for i, e := range edges {
+		childOffsets[e.parent+1]++
+		children[i] = e.child
+		hasParent[e.child] = true
+	}
+	for k := 0; k < ncomp; k++ {
+		childOffsets[k+1] += childOffsets[k]
+	}
+
+	// Post-order numbering from the roots; low is the smallest number in
+	// a component's spanning subtree.
+	l.post = make([]uint32, ncomp)
+	low := make([]uint32, ncomp)
+	visited := make([]bool, ncomp)
+	var order []uint32 // components in post-order
+	type frame struct{ k, next uint32 }
+	var stack []frame
+	for root := 0; root < ncomp; root++ {
+		if hasParent[root] || visited[root] {
+			continue
+		}
+		visited[root] = true
+		low[root] = uint32(len(order))
+		stack = append(stack[:0], frame{uint32(root), childOffsets[root]})
+		for len(stack) > 0 {
+			f := &stack[len(stack)-1]
+			if f.next < childOffsets[f.k+1] {
+				ch := children[f.next]
+				f.next++
+				if !visited[ch] {
+					visited[ch] = true
+					low[ch] = uint32(len(order))
+					stack = append(stack, frame{ch, childOffsets[ch]})
+				}
+				continue
+			}
+			l.post[f.k] = uint32(len(order))
+			order = append(order, f.k)
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	// Children come before parents in post-order, so each component can
+	// merge its children's finished intervals into its own.
+	intervals := make([][]uint32, ncomp)
+	var buf [][2]uint32
+	for _, k := range order {
+		buf = append(buf[:0], [2]uint32{low[k], l.post[k]})
+		for _, ch := range children[childOffsets[k]:childOffsets[k+1]] {
+			iv := intervals[ch]
+			for i := 0; i < len(iv); i += 2 {
+				buf = append(buf, [2]uint32{iv[i], iv[i+1]})
+			}
+		}
+		intervals[k] = mergeIntervals(buf)
+	}
+	l.offsets = make([]uint32, ncomp+1)
+	for k := 0; k < ncomp; k++ {
+		l.spans = append(l.spans, intervals[k]...)
+		l.offsets[k+1] = uint32(len(l.spans))
+		intervals[k] = nil
+	}
+	return l
+}
+
+// mergeIntervals sorts closed intervals and coalesces those that overlap
+// or touch, returning them as flat lo, hi pairs.
+func mergeIntervals(iv [][2]uint32) []uint32 {
+	sort.Slice(iv, func(i, j int) bool { return iv[i][0] < iv[j][0] })
+	out := make([]uint32, 0, 2)
+	for _, r := range iv {
+		if m := len(out); m > 0 && r[0] <= out[m-1]+1 {
+			out[m-1] = max(out[m-1], r[1])
+			continue
+		}
+		out = append(out, r[0], r[1])
+	}
+	return out
+}
+
+// components returns, for each of n concepts, the index of its strongly
+// connected component under the parent edges, skipping owl:Thing and
+// owl:Nothing. It is Tarjan's algorithm, made iterative for deep
+// hierarchies.
+func components(n int, parents func(ConceptID) []ConceptID) []uint32 {
+	const unvisited = ^uint32(0)
+	index := make([]uint32, n)
+	lowlink := make([]uint32, n)
+	onStack := make([]bool, n)
+	comp := make([]uint32, n)
+	for i := range index {
+		index[i] = unvisited
+	}
+	var sccStack []ConceptID
+	type frame struct {
+		c    ConceptID
+		next int
+	}
+	var call []frame
+	counter, ncomp := uint32(0), uint32(0)
+	for start := ConceptID(0); start < ConceptID(n); start++ {
+		if index[start] != unvisited {
+			continue
+		}
+		call = append(call[:0], frame{start, 0})
+		index[start], lowlink[start] = counter, counter
+		counter++
+		sccStack = append(sccStack, start)
+		onStack[start] = true
+		for len(call) > 0 {
+			f := &call[len(call)-1]
+			ps := parents(f.c)
+			if f.c < 2 {
+				ps = nil
+			}
+			if f.next < len(ps) {
+				p := ps[f.next]
+				f.next++
+				if p == Top || p == Bottom {
+					continue
+				}
+				if index[p] == unvisited {
+					index[p], lowlink[p] = counter, counter
+					counter++
+					sccStack = append(sccStack, p)
+					onStack[p] = true
+					call = append(call, frame{p, 0})
+				} else if onStack[p] {
+					lowlink[f.c] = min(lowlink[f.c], index[p])
+				}
+				continue
+			}
+			c := f.c
+			call = call[:len(call)-1]
+			if len(call) > 0 {
+				parent := call[len(call)-1].c
+				lowlink[parent] = min(lowlink[parent], lowlink[c])
+			}
+			if lowlink[c] == index[c] {
+				for {
+					m := sccStack[len(sccStack)-1]
+					sccStack = sccStack[:len(sccStack)-1]
+					onStack[m] = false
+					comp[m] = ncomp
+					if m == c {
+						break
+					}
+				}
+				ncomp++
+			}
+		}
+	}
+	return comp
+}
+
+// IsDescendantOf reports whether the named class a is subsumed by the
+// named class b, a ≠ b. Equivalent classes are descendants of each other;
+// unknown names are descendants of nothing.
+func (l *IntervalLabels) IsDescendantOf(a, b string) bool {
+	ca, ok := l.classes[a]
+	if !ok || a == b {
+		return false
+	}
+	cb, ok := l.classes[b]
+	if !ok {
+		return false
+	}
+	ka, kb := l.comp[ca], l.comp[cb]
+	if ka == kb {
+		return true
+	}
+	p := l.post[ka]
+	spans := l.spans[l.offsets[kb]:l.offsets[kb+1]]
+	// The last interval starting at or before p is the only candidate.
+	i := sort.Search(len(spans)/2, func(i int) bool { return spans[2*i] > p })
+	return i > 0 && p <= spans[2*(i-1)+1]
+}
+
+// Has reports whether name is a labelled class.
+func (l *IntervalLabels) Has(name string) bool {
+	_, ok := l.classes[name]
+	return ok
+}
+
+// Size returns the number of labelled components and the number of
+// intervals they carry, one per component in a tree.
+func (l *IntervalLabels) Size() (components, intervals int) {
+	return len(l.post), len(l.spans) / 2
+}
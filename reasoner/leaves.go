@@ -0,0 +1,71 @@
+package reasoner
+
+import "sort"
+
+// classChildren returns the named direct subclasses of c.
+func (tax *Taxonomy) classChildren(st *SymbolTable, c ConceptID) []ConceptID {
+	var out []ConceptID
+	for _, ch := range tax.DirectChildren(c) {
+		if st.IsClass(ch) {
+			out = append(out, ch)
+		}
+	}
+	return out
+}
+
+// IsLeaf reports whether the named class id has no inferred named
+// subclasses. Unknown IDs are not leaves.
+func (tax *Taxonomy) IsLeaf(st *SymbolTable, id string) bool {
+	c, ok := st.LookupConcept(id)
+	return ok && st.IsClass(c) && len(tax.classChildren(st, c)) == 0
+}
+
+// Leaves returns the inferred named descendants of rootID that have no
+// named subclasses, sorted; rootID itself if it is a leaf.
+func (tax *Taxonomy) Leaves(st *SymbolTable, rootID string) []string {
+	root, ok := st.LookupConcept(rootID)
+	if !ok || !st.IsClass(root) {
+		return nil
+	}
+	seen := map[ConceptID]bool{root: true}
+	var out []string
+	stack := []ConceptID{root}
+	for len(stack) > 0 {
+		c := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		kids := tax.classChildren(st, c)
+		if len(kids) == 0 {
+			out = append(out, st.ConceptName(c))
+		}
+		for _, k := range kids {
+			if !seen[k] {
+				seen[k] = true
+				stack = append(stack, k)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Siblings returns the named classes sharing an inferred direct parent
+// with id, excluding id, sorted. Classes directly under owl:Thing are
+// siblings of each other.
+func (tax *Taxonomy) Siblings(st *SymbolTable, id string) []string {
+	c, ok := st.LookupConcept(id)
+	if !ok {
+		return nil
+	}
+	seen := map[ConceptID]bool{c: true}
+	var out []string
+	for _, p := range tax.DirectParents(c) {
+		for _, k := range tax.classChildren(st, p) {
+			if !seen[k] {
+				seen[k] = true
+				out = append(out, st.ConceptName(k))
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
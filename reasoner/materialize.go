@@ -0,0 +1,82 @@
+package reasoner
+
+import "github.com/nodeadmin/chebi-parser/ontology"
+
+// Materialize classifies ont and adds to each term the inferred direct
+// superclasses it does not already assert as is_a, qualified
+// is_inferred="true", so exports of the result carry the classified
+// hierarchy: the Inferred is_a edges of its BuildGraph. owl:Thing is not
+// added, and unsatisfiable terms get nothing. It returns the number of
+// edges added.
+func Materialize(ont *ontology.Ontology, opts NormalizeOptions, workers int) int {
+	g := NewGraph(ont, opts, workers)
+	inferred := ontology.EdgeFilter{Relations: []string{"is_a"}, Origin: ontology.Inferred}
+
+	added := 0
+	seen := make(map[string]bool, len(ont.Terms))
+	for i := range ont.Terms {
+		t := &ont.Terms[i]
+		if t.IsObsolete && !opts.Obsolete.Keep(false) || seen[t.ID] {
+			continue
+		}
+		seen[t.ID] = true
+		asserted := make(map[string]bool)
+		for _, rel := range t.Relationships {
+			if rel.Type == "is_a" {
+				asserted[rel.TargetID] = true
+			}
+		}
+		for _, e := range g.Out(t.ID, inferred) {
+			if asserted[e.To] {
+				continue // qualified is_inferred already
+			}
+			t.Relationships = append(t.Relationships, ontology.Relationship{
+				Type:       "is_a",
+				TargetID:   e.To,
+				Qualifiers: map[string]string{"is_inferred": "true"},
+			})
+			added++
+		}
+	}
+	return added
+}
+
+// newParents returns the inferred direct superclasses of t that it does
+// not assert as is_a, leaving out owl:Thing and fresh concepts. An
+// unsatisfiable or unclassified term has none.
+func newParents(st *SymbolTable, contexts []Context, tax *Taxonomy, t *ontology.Term) []string {
+	c, ok := st.LookupConcept(t.ID)
+	if !ok || !st.IsClass(c) {
+		return nil
+	}
+	if _, unsat := contexts[c].superSet[Bottom]; unsat {
+		return nil
+	}
+	asserted := make(map[string]bool)
+	for _, rel := range t.Relationships {
+		if rel.Type == "is_a" {
+			asserted[rel.TargetID] = true
+		}
+	}
+	var out []string
+	for _, p := range tax.DirectParents(c) {
+		if p == Top || p == Bottom {
+			continue
+		}
+		name := st.ConceptName(p)
+		if name == "" || asserted[name] {
+			continue
+		}
+		out = append(out, name)
+	}
+	return out
+}
+
+// Classify classifies ont and returns its hierarchy as the classify
+// command writes it, without timings.
+func Classify(ont *ontology.Ontology, opts NormalizeOptions, workers int) *ClassifiedHierarchy {
+	st, store, _ := NormalizeWithOptions(ont, opts)
+	contexts := SaturateParallel(st, store, workers)
+	tax := BuildTaxonomy(contexts, st)
+	return tax.ToJSON(contexts, st, MakeStats(st, 0, 0, 0, 0))
+}
@@ -1,68 +1,162 @@
 package reasoner
 
 import (
+	"strings"
+
 	"github.com/nodeadmin/chebi-parser/ontology"
 )
 
 // Normalize converts a parsed ontology into a SymbolTable and AxiomStore
 // suitable for EL saturation. It extracts all axioms from the parsed terms
-// and normalizes them into the six canonical forms.
+// and normalizes them into the six canonical forms. Axioms outside EL are
+// dropped; use NormalizeWithOptions to approximate them or to see which.
 func Normalize(ont *ontology.Ontology) (*SymbolTable, *AxiomStore) {
+	st, store, _ := NormalizeWithOptions(ont, NormalizeOptions{})
+	return st, store
+}
+
+// NormalizeWithOptions is Normalize with control over non-EL axioms. The
+// report lists every non-EL axiom and whether it was dropped or rewritten,
+// and every asserted axiom dropped as an exact duplicate or a self is_a.
+func NormalizeWithOptions(ont *ontology.Ontology, opts NormalizeOptions) (*SymbolTable, *AxiomStore, *ApproxReport) {
 	st := NewSymbolTable()
+	approx := newApproximator(ont, opts)
+	keepObsolete := opts.Obsolete.Keep(false)
+	nominals := make(nominalSet, len(ont.Individuals))
+	for i := range ont.Individuals {
+		nominals[ont.Individuals[i].ID] = true
+	}
 
 	// First pass: register all concept and role IDs.
 	for i := range ont.Terms {
 		t := &ont.Terms[i]
-		if t.IsObsolete {
+		if t.IsObsolete && !keepObsolete {
 			continue
 		}
 		st.InternConcept(t.ID)
 		for _, rel := range t.Relationships {
 			if rel.Type != "is_a" {
 				st.InternRole(rel.Type)
+				if !rel.Self {
+					nominals.filler(st, rel.TargetID, rel.HasValue)
+				}
+			} else {
+				st.InternConcept(rel.TargetID)
+			}
+		}
+		for _, part := range t.IntersectionOf {
+			if part.Relationship != "" {
+				st.InternRole(part.Relationship)
 			}
-			st.InternConcept(rel.TargetID)
 		}
+		for _, m := range t.OneOf {
+			st.NominalConcept(m)
+		}
+	}
+	for i := range ont.Individuals {
+		st.NominalConcept(ont.Individuals[i].ID)
 	}
 
 	// Register roles from TypeDefs and their properties.
 	for i := range ont.TypeDefs {
 		st.InternRole(ont.TypeDefs[i].ID)
+		for _, chain := range ont.TypeDefs[i].HoldsOverChain {
+			for _, r := range strings.Fields(chain) {
+				st.InternRole(r)
+			}
+		}
 	}
 
 	// Second pass: create axiom store and populate it.
 	store := NewAxiomStore(st)
+	if opts.Provenance {
+		store.trackOrigins()
+	}
 
 	// Set role properties from TypeDefs.
 	for i := range ont.TypeDefs {
 		td := &ont.TypeDefs[i]
 		rid := st.InternRole(td.ID)
+		store.setOrigin(td.ID)
 		if td.IsTransitive {
 			store.SetTransitive(rid)
 		}
 		if td.IsReflexive {
 			store.SetReflexive(rid)
 		}
+		// NF6 is binary; longer chains are not supported and are skipped.
+		for _, chain := range td.HoldsOverChain {
+			parts := strings.Fields(chain)
+			if len(parts) == 2 {
+				store.AddRoleChain(st.InternRole(parts[0]), st.InternRole(parts[1]), rid)
+			}
+		}
+	}
+
+	// Class assertions: a : T becomes {a} ⊑ T.
+	var seen termAxioms
+	dropped := func(id, reason, axiom string) {
+		approx.report.Cleanup = append(approx.report.Cleanup, CleanupEntry{TermID: id, Reason: reason, Axiom: axiom})
+	}
+	for i := range ont.Individuals {
+		ind := &ont.Individuals[i]
+		a := st.NominalConcept(ind.ID)
+		store.setOrigin(ind.ID)
+		seen.reset()
+		for _, typ := range ind.Types {
+			sup := st.InternConcept(typ)
+			if !seen.add(axiomKey{axSub, uint32(a), uint32(sup), 0}) {
+				dropped(ind.ID, CleanupDuplicate, "type "+typ)
+				continue
+			}
+			store.AddSubsumption(a, sup)
+		}
 	}
 
 	// Extract axioms from terms.
 	for i := range ont.Terms {
 		t := &ont.Terms[i]
-		if t.IsObsolete {
+		if t.IsObsolete && !keepObsolete {
 			continue
 		}
 		cid := st.InternConcept(t.ID)
+		store.setOrigin(t.ID)
 
+		// Exact repeats and self is_a edges are dropped and reported.
+		seen.reset()
 		for _, rel := range t.Relationships {
-			targetID := st.InternConcept(rel.TargetID)
-
 			if rel.Type == "is_a" {
 				// NF1: C ⊑ Target
-				store.AddSubsumption(cid, targetID)
+				if rel.TargetID == t.ID {
+					dropped(t.ID, CleanupSelfIsA, "is_a "+rel.TargetID)
+					continue
+				}
+				sup := st.InternConcept(rel.TargetID)
+				if !seen.add(axiomKey{axSub, uint32(cid), uint32(sup), 0}) {
+					dropped(t.ID, CleanupDuplicate, "is_a "+rel.TargetID)
+					continue
+				}
+				store.AddSubsumption(cid, sup)
+			} else if rel.Cardinality != nil && rel.Cardinality.Min == 0 {
+				// A max-only bound does not imply any filler exists.
+				continue
+			} else if rel.Self {
+				// C ⊑ ∃R.Self
+				rid := st.InternRole(rel.Type)
+				if !seen.add(axiomKey{axSelfRight, uint32(cid), uint32(rid), 0}) {
+					dropped(t.ID, CleanupDuplicate, rel.Type+" self")
+					continue
+				}
+				store.AddSelfRight(cid, rid)
 			} else {
-				// NF3: C ⊑ ∃R.Target
+				// NF3: C ⊑ ∃R.Target, or C ⊑ ∃R.{a} for a value restriction
 				rid := st.InternRole(rel.Type)
-				store.AddExistRight(cid, rid, targetID)
+				fill := nominals.filler(st, rel.TargetID, rel.HasValue)
+				if !seen.add(axiomKey{axExistRight, uint32(cid), uint32(rid), uint32(fill)}) {
+					dropped(t.ID, CleanupDuplicate, rel.Type+" "+rel.TargetID)
+					continue
+				}
+				store.AddExistRight(cid, rid, fill)
 			}
 		}
 
@@ -70,24 +164,38 @@ func Normalize(ont *ontology.Ontology) (*SymbolTable, *AxiomStore) {
 		// In OBO: intersection_of lines define an equivalence:
 		//   C ≡ A₁ ⊓ A₂ ⊓ ... ⊓ ∃R.B ⊓ ...
 		// This decomposes to:
-		//   C ⊑ A₁, C ⊑ A₂, C ⊑ ∃R.B (already handled by is_a/relationship)
+		//   C ⊑ A₁, C ⊑ A₂, C ⊑ ∃R.B (NF1/NF3)
 		//   A₁ ⊓ A₂ ⊓ ... ⊑ C (GCI conjunctions)
 		if len(t.IntersectionOf) > 0 {
-			normalizeIntersection(st, store, cid, t.IntersectionOf)
+			normalizeIntersection(st, store, nominals, &seen, cid, t.IntersectionOf)
+		}
+
+		if len(t.UnionOf) > 0 {
+			approx.union(st, store, cid, t)
+		}
+		if len(t.OneOf) > 0 {
+			approx.oneOf(st, store, cid, t)
+		}
+		if len(t.NonEL) > 0 {
+			approx.nonEL(st, store, cid, t)
 		}
 	}
 
+	store.setOrigin("")
+
 	// Grow store to accommodate any fresh concepts created during normalization.
 	store.Grow(st.ConceptCount())
 	store.GrowRoles(st.RoleCount())
 
-	return st, store
+	return st, store, approx.report
 }
 
 // normalizeIntersection handles intersection_of axioms (equivalence decomposition).
-// The forward direction (C ⊑ each conjunct) is already handled by is_a/relationship.
-// This adds the reverse: conjunct₁ ⊓ conjunct₂ ⊓ ... ⊑ C.
-func normalizeIntersection(st *SymbolTable, store *AxiomStore, cid ConceptID, parts []ontology.IntersectionPart) {
+// It adds the forward direction (C ⊑ each conjunct), which OBO files usually
+// but not always repeat as is_a/relationship lines, and the reverse:
+// conjunct₁ ⊓ conjunct₂ ⊓ ... ⊑ C. Forward axioms already in seen are
+// expected repeats and skipped without being reported.
+func normalizeIntersection(st *SymbolTable, store *AxiomStore, nominals nominalSet, seen *termAxioms, cid ConceptID, parts []ontology.IntersectionPart) {
 	// Collect the concept IDs for each conjunct.
 	// For genus (plain class), it's the class ID directly.
 	// For differentia (∃R.F), create a fresh concept X, add ∃R.F ⊑ X (NF4).
@@ -96,11 +204,28 @@ func normalizeIntersection(st *SymbolTable, store *AxiomStore, cid ConceptID, pa
 	for _, part := range parts {
 		if part.Relationship == "" {
 			// Genus: plain concept
-			conjuncts = append(conjuncts, st.InternConcept(part.TargetID))
+			genus := st.InternConcept(part.TargetID)
+			if seen.add(axiomKey{axSub, uint32(cid), uint32(genus), 0}) {
+				store.AddSubsumption(cid, genus)
+			}
+			conjuncts = append(conjuncts, genus)
+		} else if part.Self {
+			// ∃R.Self — C ⊑ ∃R.Self, and a fresh X with ∃R.Self ⊑ X
+			rid := st.InternRole(part.Relationship)
+			if seen.add(axiomKey{axSelfRight, uint32(cid), uint32(rid), 0}) {
+				store.AddSelfRight(cid, rid)
+			}
+			fresh := st.FreshConcept()
+			store.Grow(st.ConceptCount())
+			store.AddSelfLeft(rid, fresh)
+			conjuncts = append(conjuncts, fresh)
 		} else {
 			// Differentia: ∃R.F — introduce fresh concept X, add NF4: ∃R.F ⊑ X
 			rid := st.InternRole(part.Relationship)
-			fill := st.InternConcept(part.TargetID)
+			fill := nominals.filler(st, part.TargetID, false)
+			if seen.add(axiomKey{axExistRight, uint32(cid), uint32(rid), uint32(fill)}) {
+				store.AddExistRight(cid, rid, fill)
+			}
 			fresh := st.FreshConcept()
 			store.Grow(st.ConceptCount())
 			store.AddExistLeft(rid, fill, fresh)
@@ -132,3 +257,24 @@ func normalizeIntersection(st *SymbolTable, store *AxiomStore, cid ConceptID, pa
 		acc = result
 	}
 }
+
+// nominalSet holds the IDs of the ontology's named individuals.
+//
+// An individual a is represented by the nominal concept {a}, so ∃R.{a}
+// (ObjectHasValue) is an ordinary existential whose filler is subsumed by
+// a's asserted types. Nominals only ever appear as fillers and on the left
+// of class assertions, never as superclasses of a named class, so the
+// nominal-merging completion rule is not needed and saturation is
+// unchanged. ObjectOneOf in superclass position would break that
+// invariant and is not supported.
+type nominalSet map[string]bool
+
+// filler returns the concept for the filler of an existential restriction:
+// the nominal {id} for a value restriction or a target that names an
+// individual, and the class id otherwise.
+func (ns nominalSet) filler(st *SymbolTable, id string, hasValue bool) ConceptID {
+	if hasValue || ns[id] {
+		return st.NominalConcept(id)
+	}
+	return st.InternConcept(id)
+}
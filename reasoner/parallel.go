@@ -4,6 +4,8 @@ import (
 	"runtime"
 )
 
+// SaturateParallel is a placeholder for a concurrent saturation: it runs
+// Saturate whatever workers is, so its results are Saturate's.
 func SaturateParallel(st *SymbolTable, store *AxiomStore, workers int) []Context {
 	if workers <= 0 {
 		workers = runtime.NumCPU()
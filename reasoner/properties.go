@@ -0,0 +1,71 @@
+package reasoner
+
+import (
+	"fmt"
+
+	"github.com/nodeadmin/chebi-parser/internal/intsets"
+)
+
+// CheckProperties verifies invariants every correct classification
+// satisfies, independently of any reference output: subsumption is
+// reflexive (C ∈ S(C)) and transitive (S(D) ⊆ S(C) for every D ∈ S(C)),
+// and no direct parent in the taxonomy subsumes another direct parent of
+// the same concept. The taxonomy's IntervalLabels must also agree with its
+// Ancestors on every pair of named classes. It returns one message per
+// violation, or nil.
+// Monotonicity under axiom addition involves two classifications and is
+// checked by comparing their Subsumptions.
+func CheckProperties(contexts []Context, st *SymbolTable, tax *Taxonomy) []string {
+	var out []string
+	name := func(c ConceptID) string {
+		if n := st.ConceptName(c); n != "" {
+			return n
+		}
+		return fmt.Sprintf("#%d", c)
+	}
+
+	supers := superSets(contexts)
+	for c := ConceptID(2); c < ConceptID(st.ConceptCount()); c++ {
+		all := supers.Of(c)
+		if len(all) == 0 {
+			continue // never reached by saturation
+		}
+		if !intsets.Contains(all, c) {
+			out = append(out, fmt.Sprintf("not reflexive: %s ∉ S(%s)", name(c), name(c)))
+		}
+		for _, d := range all {
+			if d == Bottom {
+				continue
+			}
+			if !intsets.Subset(supers.Of(d), all) {
+				out = append(out, fmt.Sprintf("not transitive: %s ⊑ %s but S(%s) ⊄ S(%s)", name(c), name(d), name(d), name(c)))
+			}
+		}
+		parents := tax.DirectParents(c)
+		for _, p := range parents {
+			for _, q := range parents {
+				if p != q && intsets.Contains(supers.Of(p), q) {
+					out = append(out, fmt.Sprintf("redundant parent: %s has direct parents %s ⊑ %s", name(c), name(p), name(q)))
+				}
+			}
+		}
+	}
+
+	labels := tax.IntervalLabels(st)
+	ancestors := tax.Ancestors(st)
+	n := ConceptID(st.ConceptCount())
+	for c := ConceptID(2); c < n; c++ {
+		if !st.IsClass(c) {
+			continue
+		}
+		for d := ConceptID(2); d < n; d++ {
+			if !st.IsClass(d) {
+				continue
+			}
+			if got, want := labels.IsDescendantOf(name(c), name(d)), ancestors.Contains(c, d); got != want {
+				out = append(out, fmt.Sprintf("interval labels: %s ⊑ %s is %v, taxonomy says %v", name(c), name(d), got, want))
+			}
+		}
+	}
+	return out
+}
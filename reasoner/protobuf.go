@@ -0,0 +1,62 @@
+package reasoner
+
+import (
+	"bufio"
+	"io"
+	"sort"
+
+	"github.com/nodeadmin/chebi-parser/internal/protowire"
+)
+
+// WriteClassifiedProto writes the classified hierarchy as a length-delimited
+// protobuf stream: one ClassificationStats message followed by one
+// ClassifiedConcept message per concept. See proto/chebi.proto.
+func WriteClassifiedProto(w io.Writer, hierarchy *ClassifiedHierarchy) error {
+	bw := bufio.NewWriterSize(w, 256*1024)
+
+	s := &hierarchy.Stats
+	var buf []byte
+	buf = protowire.AppendInt64(buf, 1, int64(s.ConceptCount))
+	buf = protowire.AppendInt64(buf, 2, int64(s.RoleCount))
+	buf = protowire.AppendInt64(buf, 3, int64(s.InferredSubsumptions))
+	buf = protowire.AppendInt64(buf, 4, s.ParseTimeMs)
+	buf = protowire.AppendInt64(buf, 5, s.NormalizeTimeMs)
+	buf = protowire.AppendInt64(buf, 6, s.SaturateTimeMs)
+	buf = protowire.AppendInt64(buf, 7, s.ReductionTimeMs)
+	buf = protowire.AppendInt64(buf, 8, s.TotalTimeMs)
+	if err := protowire.WriteDelimited(bw, buf); err != nil {
+		return err
+	}
+
+	for i := range hierarchy.Concepts {
+		cc := &hierarchy.Concepts[i]
+		buf = protowire.AppendString(buf[:0], 1, cc.ID)
+		buf = protowire.AppendString(buf, 2, cc.Name)
+		buf = protowire.AppendStrings(buf, 3, cc.DirectParents)
+		buf = protowire.AppendStrings(buf, 4, cc.DirectChildren)
+		for _, p := range cc.Provenance {
+			var msg []byte
+			msg = protowire.AppendString(msg, 1, p.Parent)
+			msg = protowire.AppendString(msg, 2, p.Rule)
+			msg = protowire.AppendStrings(msg, 3, p.Via)
+			msg = protowire.AppendStrings(msg, 4, p.Axioms)
+			buf = protowire.AppendMessage(buf, 5, msg)
+		}
+		rels := make([]string, 0, len(cc.Fillers))
+		for rel := range cc.Fillers {
+			rels = append(rels, rel)
+		}
+		sort.Strings(rels)
+		for _, rel := range rels {
+			// map<string, Fillers>: the value is a Fillers message.
+			var msg []byte
+			msg = protowire.AppendString(msg, 1, rel)
+			msg = protowire.AppendMessage(msg, 2, protowire.AppendStrings(nil, 1, cc.Fillers[rel]))
+			buf = protowire.AppendMessage(buf, 6, msg)
+		}
+		if err := protowire.WriteDelimited(bw, buf); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,223 @@
+package reasoner
+
+import (
+	"slices"
+	"sort"
+)
+
+// Provenance explains one direct parent of a classified concept: the
+// completion rule that put the parent in S(C), the members of S(C) (or the
+// link) it fired on, and the terms whose axioms it used. It is one step of
+// a derivation, not a full justification: the premises in Via may
+// themselves be inferred.
+type Provenance struct {
+	Parent string   `json:"parent"`
+	Rule   string   `json:"rule"`             // CR1, CR2, CR4 or CR-Self
+	Via    []string `json:"via,omitempty"`    // premises other than the concept itself
+	Axioms []string `json:"axioms,omitempty"` // IDs of the terms, typedefs or individuals the axioms came from
+}
+
+// axiomKind identifies a normal form in an axiomKey.
+type axiomKind uint8
+
+const (
+	axSub axiomKind = iota
+	axConj
+	axExistRight
+	axExistLeft
+	axSelfRight
+	axSelfLeft
+	axRoleSub
+	axRoleChain
+)
+
+// axiomKey identifies one normalized axiom by its form and operands.
+type axiomKey struct {
+	kind    axiomKind
+	a, b, c uint32
+}
+
+// trackOrigins makes the store remember, for every axiom added from now
+// on, the ID set with setOrigin at the time.
+func (s *AxiomStore) trackOrigins() {
+	s.origins = make(map[axiomKey][]string)
+}
+
+func (s *AxiomStore) setOrigin(id string) {
+	s.origin = id
+}
+
+func (s *AxiomStore) record(kind axiomKind, a, b, c uint32) {
+	if s.origins == nil || s.origin == "" {
+		return
+	}
+	k := axiomKey{kind, a, b, c}
+	if slices.Contains(s.origins[k], s.origin) {
+		return
+	}
+	s.origins[k] = append(s.origins[k], s.origin)
+}
+
+// provenancer finds the provenance of direct parents from the saturated
+// contexts and the axioms they were saturated with.
+type provenancer struct {
+	st       *SymbolTable
+	store    *AxiomStore
+	contexts []Context
+
+	// fresh describes the concepts normalization introduced, as the
+	// expressions they stand for.
+	fresh map[ConceptID]string
+}
+
+func newProvenancer(contexts []Context, st *SymbolTable, store *AxiomStore) *provenancer {
+	p := &provenancer{st: st, store: store, contexts: contexts, fresh: make(map[ConceptID]string)}
+	// Existential fillers are never fresh, so those descriptions come
+	// first; a conjunction's operands are existentials or conjunctions
+	// made before it, with lower IDs.
+	for r, m := range store.existLeft {
+		for fill, sups := range m {
+			for _, x := range sups {
+				if st.ConceptName(x) == "" {
+					p.fresh[x] = st.RoleName(RoleID(r)) + " some " + p.name(fill)
+				}
+			}
+		}
+	}
+	for r, sups := range store.selfLeft {
+		for _, x := range sups {
+			if st.ConceptName(x) == "" {
+				p.fresh[x] = st.RoleName(RoleID(r)) + " some Self"
+			}
+		}
+	}
+	var conj []axiomKey
+	for a, m := range store.conjIndex {
+		for b, results := range m {
+			for _, x := range results {
+				if ConceptID(a) <= b && st.ConceptName(x) == "" {
+					conj = append(conj, axiomKey{axConj, uint32(a), uint32(b), uint32(x)})
+				}
+			}
+		}
+	}
+	sort.Slice(conj, func(i, j int) bool { return conj[i].c < conj[j].c })
+	for _, k := range conj {
+		p.fresh[ConceptID(k.c)] = "(" + p.name(ConceptID(k.a)) + " and " + p.name(ConceptID(k.b)) + ")"
+	}
+	return p
+}
+
+// name returns a concept's ID, or the expression a fresh concept stands
+// for.
+func (p *provenancer) name(c ConceptID) string {
+	if n := p.st.ConceptName(c); n != "" {
+		return n
+	}
+	if d, ok := p.fresh[c]; ok {
+		return d
+	}
+	return "_"
+}
+
+func (p *provenancer) origins(keys ...axiomKey) []string {
+	var ids []string
+	for _, k := range keys {
+		for _, id := range p.store.origins[k] {
+			if !slices.Contains(ids, id) {
+				ids = append(ids, id)
+			}
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// explain returns the provenance of d ∈ S(c). The rules are tried in
+// order CR1, CR2, CR4, CR-Self, and premises in ID order with c itself
+// first, so the explanation favours the told axiom when there is one.
+func (p *provenancer) explain(c, d ConceptID) Provenance {
+	pr := Provenance{Parent: p.st.ConceptName(d)}
+	ctx := &p.contexts[c]
+	supers := []ConceptID{c}
+	for _, a := range sortedSet(ctx.superSet) {
+		if a != c {
+			supers = append(supers, a)
+		}
+	}
+	via := func(a ConceptID) []string {
+		if a == c {
+			return nil
+		}
+		return []string{p.name(a)}
+	}
+
+	for _, a := range supers {
+		if int(a) < len(p.store.subToSups) && slices.Contains(p.store.subToSups[a], d) {
+			pr.Rule, pr.Via = "CR1", via(a)
+			pr.Axioms = p.origins(axiomKey{axSub, uint32(a), uint32(d), 0})
+			return pr
+		}
+	}
+	for _, a := range supers {
+		if int(a) >= len(p.store.conjIndex) {
+			continue
+		}
+		for _, b := range supers {
+			if !slices.Contains(p.store.conjIndex[a][b], d) {
+				continue
+			}
+			lo, hi := min(a, b), max(a, b)
+			pr.Rule, pr.Via = "CR2", append(via(a), via(b)...)
+			pr.Axioms = p.origins(axiomKey{axConj, uint32(lo), uint32(hi), uint32(d)})
+			return pr
+		}
+	}
+	for r, targets := range ctx.linkMap {
+		if r >= len(p.store.existLeft) || p.store.existLeft[r] == nil {
+			continue
+		}
+		targets = slices.Clone(targets)
+		slices.Sort(targets)
+		for _, e := range targets {
+			for _, a := range sortedSet(p.contexts[e].superSet) {
+				if !slices.Contains(p.store.existLeft[r][a], d) {
+					continue
+				}
+				keys := []axiomKey{{axExistLeft, uint32(r), uint32(a), uint32(d)}}
+				// The link itself, when a told C ⊑ ∃R.E made it.
+				for _, b := range supers {
+					if int(b) < len(p.store.existRight) && slices.Contains(p.store.existRight[b], RoleFiller{RoleID(r), e}) {
+						keys = append(keys, axiomKey{axExistRight, uint32(b), uint32(r), uint32(e)})
+						break
+					}
+				}
+				pr.Rule = "CR4"
+				pr.Via = []string{p.st.RoleName(RoleID(r)) + " some " + p.name(e)}
+				if a != e {
+					pr.Via = append(pr.Via, p.name(e)+" ⊑ "+p.name(a))
+				}
+				pr.Axioms = p.origins(keys...)
+				return pr
+			}
+		}
+	}
+	for _, r := range ctx.selfRoles {
+		if int(r) < len(p.store.selfLeft) && slices.Contains(p.store.selfLeft[r], d) {
+			pr.Rule = "CR-Self"
+			pr.Via = []string{p.st.RoleName(r) + " some Self"}
+			pr.Axioms = p.origins(axiomKey{axSelfLeft, uint32(r), uint32(d), 0})
+			return pr
+		}
+	}
+	return pr
+}
+
+func sortedSet(set map[ConceptID]struct{}) []ConceptID {
+	ids := make([]ConceptID, 0, len(set))
+	for id := range set {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
@@ -0,0 +1,219 @@
+package reasoner
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/nodeadmin/chebi-parser/ontology"
+)
+
+// Expr is an EL class expression: the conjunction of named classes and
+// existential restrictions. An empty Expr is owl:Thing.
+type Expr struct {
+	Classes []string
+	Some    []Restriction
+}
+
+// Restriction is ∃Role.Filler.
+type Restriction struct {
+	Role   string
+	Filler *Expr
+}
+
+// String renders the expression in Manchester syntax.
+func (e *Expr) String() string {
+	parts := make([]string, 0, len(e.Classes)+len(e.Some))
+	parts = append(parts, e.Classes...)
+	for _, r := range e.Some {
+		filler := r.Filler.String()
+		if len(r.Filler.Classes)+len(r.Filler.Some) > 1 {
+			filler = "(" + filler + ")"
+		}
+		parts = append(parts, r.Role+" some "+filler)
+	}
+	if len(parts) == 0 {
+		return "owl:Thing"
+	}
+	return strings.Join(parts, " and ")
+}
+
+// Reasoner holds a classified ontology for answering class expression
+// queries.
+type Reasoner struct {
+	st       *SymbolTable
+	contexts []Context
+	nominals map[ConceptID]string // nominal concept → individual ID
+	names    *LabelResolver
+}
+
+// New normalizes and saturates ont.
+func New(ont *ontology.Ontology, opts NormalizeOptions, workers int) *Reasoner {
+	st, store, _ := NormalizeWithOptions(ont, opts)
+	r := &Reasoner{
+		st:       st,
+		contexts: SaturateParallel(st, store, workers),
+		nominals: make(map[ConceptID]string, len(ont.Individuals)),
+		names:    NewLabelResolverWithOptions(ont, opts.Obsolete),
+	}
+	for i := range ont.Individuals {
+		id := ont.Individuals[i].ID
+		r.nominals[st.NominalConcept(id)] = id
+	}
+	return r
+}
+
+// Subclasses returns the named classes subsumed by the class expression
+// (see ParseExpression; names may be IDs or labels), sorted. Classes
+// equivalent to it are included; unsatisfiable classes are not.
+//
+// Answering a query is equivalent to adding a fresh concept Q ≡ expr and
+// saturating incrementally. Only the direction expr ⊑ Q can add
+// subsumptions to existing concepts, and its normalized axioms all have a
+// query concept on the right, so incremental saturation only ever adds
+// query concepts to the existing contexts. match computes exactly those
+// additions bottom-up over the saturated state, leaving it untouched so
+// queries can run concurrently.
+func (r *Reasoner) Subclasses(expr string) ([]string, error) {
+	e, err := ParseExpression(expr, r.names)
+	if err != nil {
+		return nil, err
+	}
+	m, err := r.match(e)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for c, ok := range m {
+		if ok && r.st.IsClass(ConceptID(c)) && !r.unsatisfiable(ConceptID(c)) {
+			out = append(out, r.st.ConceptName(ConceptID(c)))
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// Instances returns the named individuals that are instances of the class
+// expression, sorted.
+func (r *Reasoner) Instances(expr string) ([]string, error) {
+	e, err := ParseExpression(expr, r.names)
+	if err != nil {
+		return nil, err
+	}
+	m, err := r.match(e)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for c, id := range r.nominals {
+		if m[c] {
+			out = append(out, id)
+		}
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// Symbols returns the symbol table the reasoner's concept and role IDs
+// refer to, for use with RelatedVia.
+func (r *Reasoner) Symbols() *SymbolTable {
+	return r.st
+}
+
+// RelatedVia returns the concepts D with (C, D) ∈ R(role) after
+// saturation, sorted: the targets of C's told links and of those derived
+// through subproperties and property chains. Targets may be fresh
+// concepts introduced by normalization; see Related for named classes.
+func (r *Reasoner) RelatedVia(c ConceptID, role RoleID) []ConceptID {
+	if int(c) >= len(r.contexts) || int(role) >= len(r.contexts[c].linkMap) {
+		return nil
+	}
+	out := slices.Clone(r.contexts[c].linkMap[role])
+	slices.Sort(out)
+	return out
+}
+
+// Related returns the named classes E such that every instance of the
+// class is related by relation to some E, sorted. Class and relation are
+// resolved like the names in an expression (IDs or labels). With direct,
+// only the most specific targets are returned, otherwise their
+// superclasses too. An unsatisfiable class has no targets.
+func (r *Reasoner) Related(class, relation string, direct bool) ([]string, error) {
+	id, err := r.names.ResolveClass(class)
+	if err != nil {
+		return nil, err
+	}
+	c, ok := r.st.LookupConcept(id)
+	if !ok {
+		return nil, fmt.Errorf("unknown class %q", class)
+	}
+	relID, err := r.names.ResolveRelation(relation)
+	if err != nil {
+		return nil, err
+	}
+	role, ok := r.st.LookupRole(relID)
+	if !ok {
+		return nil, nil // a typedef no axiom uses
+	}
+	out := fillers(r.contexts, r.st, c, role, direct)
+	sort.Strings(out)
+	return out, nil
+}
+
+func (r *Reasoner) unsatisfiable(c ConceptID) bool {
+	_, ok := r.contexts[c].superSet[Bottom]
+	return ok
+}
+
+// match returns, for every concept C, whether C ⊑ e follows from the
+// saturated state: each named class of e is in S(C), and each ∃R.F has an
+// R-link from C to a concept matching F. Unsatisfiable concepts match
+// everything.
+func (r *Reasoner) match(e *Expr) ([]bool, error) {
+	n := len(r.contexts)
+	m := make([]bool, n)
+	for c := range m {
+		m[c] = true
+	}
+	for _, name := range e.Classes {
+		a, ok := r.st.LookupConcept(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown class %q", name)
+		}
+		for c := range m {
+			if m[c] {
+				_, m[c] = r.contexts[c].superSet[a]
+			}
+		}
+	}
+	for _, rs := range e.Some {
+		role, ok := r.st.LookupRole(rs.Role)
+		if !ok {
+			return nil, fmt.Errorf("unknown relation %q", rs.Role)
+		}
+		fill, err := r.match(rs.Filler)
+		if err != nil {
+			return nil, err
+		}
+		for c := range m {
+			if !m[c] {
+				continue
+			}
+			found := false
+			for _, d := range r.contexts[c].linkMap[role] {
+				if fill[d] {
+					found = true
+					break
+				}
+			}
+			m[c] = found
+		}
+	}
+	for c := range m {
+		if !m[c] && r.unsatisfiable(ConceptID(c)) {
+			m[c] = true
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,81 @@
+package reasoner
+
+import "fmt"
+
+// TaxonomyOptions configure ToJSONWithOptions.
+type TaxonomyOptions struct {
+	// Roots are designated root classes, as returned by ResolveRoots. They
+	// are reported with no direct parents, so the hierarchy is rooted at
+	// them rather than at owl:Thing.
+	Roots []ConceptID
+
+	// Provenance, the store the contexts were saturated from, adds to
+	// each concept the provenance of its direct parents. Axiom origins are
+	// only known if it was normalized with NormalizeOptions.Provenance.
+	Provenance *AxiomStore
+
+	// Fillers are relations, as returned by ResolveRoles, whose inferred
+	// targets are added to each concept: for has_part, the most specific
+	// classes every instance is inferred to have a part of.
+	Fillers []RoleID
+}
+
+// ResolveRoots looks up designated root classes by ID.
+func ResolveRoots(st *SymbolTable, ids []string) ([]ConceptID, error) {
+	roots := make([]ConceptID, 0, len(ids))
+	for _, id := range ids {
+		c, ok := st.LookupConcept(id)
+		if !ok || !st.IsClass(c) {
+			return nil, fmt.Errorf("unknown root class %q", id)
+		}
+		roots = append(roots, c)
+	}
+	return roots, nil
+}
+
+// Unrooted returns the named classes that are not inferred to be
+// subclasses of any of roots, in concept order. The roots themselves and
+// unsatisfiable classes are not reported. With ChEBI's chemical entity as
+// the root these are the terms that fail to connect to the main hierarchy.
+func Unrooted(contexts []Context, st *SymbolTable, roots []ConceptID) []string {
+	isRoot := make(map[ConceptID]bool, len(roots))
+	for _, r := range roots {
+		isRoot[r] = true
+	}
+	var out []string
+	for c := ConceptID(2); c < ConceptID(st.ConceptCount()); c++ {
+		if !st.IsClass(c) || isRoot[c] {
+			continue
+		}
+		supers := contexts[c].superSet
+		if _, ok := supers[Bottom]; ok {
+			continue
+		}
+		connected := false
+		for _, r := range roots {
+			if _, ok := supers[r]; ok {
+				connected = true
+				break
+			}
+		}
+		if !connected {
+			out = append(out, st.ConceptName(c))
+		}
+	}
+	return out
+}
+
+// Unsatisfiable returns the named classes inferred to be subclasses of
+// owl:Nothing, in concept order.
+func Unsatisfiable(contexts []Context, st *SymbolTable) []string {
+	var out []string
+	for c := ConceptID(2); c < ConceptID(st.ConceptCount()); c++ {
+		if !st.IsClass(c) {
+			continue
+		}
+		if _, ok := contexts[c].superSet[Bottom]; ok {
+			out = append(out, st.ConceptName(c))
+		}
+	}
+	return out
+}
@@ -12,6 +12,18 @@ type Context struct {
 
 	// Reverse links: predMap[r] = list of concepts E such that (E, C) ∈ R(r).
 	predMap [][]ConceptID
+
+	// Roles R with C ⊑ ∃R.Self. Each also has the link (C, C) ∈ R(R).
+	selfRoles []RoleID
+}
+
+func (c *Context) hasSelf(r RoleID) bool {
+	for _, s := range c.selfRoles {
+		if s == r {
+			return true
+		}
+	}
+	return false
 }
 
 // workItem represents a pending inference to process.
@@ -28,7 +40,8 @@ type linkItem struct {
 }
 
 // Saturate runs the single-threaded EL saturation algorithm.
-// It applies completion rules CR1–CR5, CR10, CR11 until no new inferences can be derived.
+// It applies completion rules CR1–CR5, CR10, CR11 and CR-Self until no new
+// inferences can be derived. Reflexive roles are handled as ⊤ ⊑ ∃R.Self.
 func Saturate(st *SymbolTable, store *AxiomStore) []Context {
 	n := st.ConceptCount()
 	nr := st.RoleCount()
@@ -47,12 +60,57 @@ func Saturate(st *SymbolTable, store *AxiomStore) []Context {
 	// Link worklist for link-triggered rules (CR4, CR5, CR10, CR11).
 	linkWorklist := make([]linkItem, 0, n)
 
-	// Initialize: S(C) = {C, Top} for each named concept.
+	// CR-Self: C ⊑ ∃R.Self. Record R as a self role of C, add the link
+	// (C, C) ∈ R(R) so CR4/CR10/CR11 see it, and fire ∃R.Self ⊑ E. Self
+	// roles propagate up the role hierarchy and through chains R₁ ∘ R₂ ⊑ S
+	// whose parts are both self roles of C.
+	addSelf := func(c ConceptID, r RoleID) {
+		pending := []RoleID{r}
+		for len(pending) > 0 {
+			r := pending[len(pending)-1]
+			pending = pending[:len(pending)-1]
+			ctx := &contexts[c]
+			if ctx.hasSelf(r) {
+				continue
+			}
+			ctx.selfRoles = append(ctx.selfRoles, r)
+			if addLink(ctx, ctx, r) {
+				linkWorklist = append(linkWorklist, linkItem{c, r, c})
+			}
+			if int(r) < len(store.selfLeft) {
+				for _, e := range store.selfLeft[r] {
+					if _, exists := ctx.superSet[e]; !exists {
+						ctx.superSet[e] = struct{}{}
+						worklist = append(worklist, workItem{c, e})
+					}
+				}
+			}
+			if int(r) < len(store.roleSubs) {
+				pending = append(pending, store.roleSubs[r]...)
+			}
+			for _, r2 := range ctx.selfRoles {
+				if int(r) < len(store.roleChains) && store.roleChains[r] != nil {
+					pending = append(pending, store.roleChains[r][r2]...)
+				}
+				if int(r2) < len(store.roleChains) && store.roleChains[r2] != nil {
+					pending = append(pending, store.roleChains[r2][r]...)
+				}
+			}
+		}
+	}
+
+	// Initialize: S(C) = {C, Top} for each named concept. A reflexive role
+	// R means ⊤ ⊑ ∃R.Self, so every concept gets R as a self role.
 	for c := ConceptID(0); c < ConceptID(n); c++ {
 		contexts[c].superSet[c] = struct{}{}
 		contexts[c].superSet[Top] = struct{}{}
 		worklist = append(worklist, workItem{c, c})
 		worklist = append(worklist, workItem{c, Top})
+		for r, reflexive := range store.reflexive {
+			if reflexive {
+				addSelf(c, RoleID(r))
+			}
+		}
 	}
 
 	// Main saturation loop.
@@ -98,6 +156,13 @@ func Saturate(st *SymbolTable, store *AxiomStore) []Context {
 				}
 			}
 
+			// CR-Self: If D ⊑ ∃R.Self, C has a self link via R.
+			if int(d) < len(store.selfRight) {
+				for _, r := range store.selfRight[d] {
+					addSelf(c, r)
+				}
+			}
+
 			// CR4 backward: D was added to S(C). For each predecessor E
 			// that has a link (E, C) via role R, check if ∃R.D ⊑ F.
 			for r := RoleID(0); r < RoleID(nr); r++ {
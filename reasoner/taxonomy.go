@@ -3,13 +3,33 @@ package reasoner
 import (
 	"encoding/json"
 	"io"
+	"slices"
 	"time"
+
+	"github.com/nodeadmin/chebi-parser/internal/intsets"
 )
 
-// Taxonomy holds the classified hierarchy after transitive reduction.
+// Taxonomy holds the classified hierarchy after transitive reduction. Both
+// directions are stored as CSR: the direct parents of c are
+// parents[parentOffsets[c]:parentOffsets[c+1]], in one flat array, so the
+// hierarchy costs two allocations per direction instead of one per concept.
 type Taxonomy struct {
-	DirectParents  [][]ConceptID
-	DirectChildren [][]ConceptID
+	parentOffsets []uint32
+	parents       []ConceptID
+	childOffsets  []uint32
+	children      []ConceptID
+}
+
+// DirectParents returns the direct subsumers of c. The slice aliases the
+// taxonomy and must not be modified.
+func (tax *Taxonomy) DirectParents(c ConceptID) []ConceptID {
+	return tax.parents[tax.parentOffsets[c]:tax.parentOffsets[c+1]]
+}
+
+// DirectChildren returns the concepts c directly subsumes, in ID order.
+// The slice aliases the taxonomy and must not be modified.
+func (tax *Taxonomy) DirectChildren(c ConceptID) []ConceptID {
+	return tax.children[tax.childOffsets[c]:tax.childOffsets[c+1]]
 }
 
 // BuildTaxonomy extracts the direct (non-redundant) subsumption hierarchy
@@ -17,20 +37,27 @@ type Taxonomy struct {
 func BuildTaxonomy(contexts []Context, st *SymbolTable) *Taxonomy {
 	n := st.ConceptCount()
 	tax := &Taxonomy{
-		DirectParents:  make([][]ConceptID, n),
-		DirectChildren: make([][]ConceptID, n),
+		parentOffsets: make([]uint32, n+1),
+		parents:       make([]ConceptID, 0, n),
+		childOffsets:  make([]uint32, n+1),
 	}
 
+	supers := superSets(contexts)
+	candidates := make([]ConceptID, 0, 64)
+	common := make([]ConceptID, 0, 64)
+	redundant := make([]uint32, n) // redundant[b] == c+1 once b is ruled out for c
 	for c := ConceptID(2); c < ConceptID(n); c++ {
-		supers := contexts[c].superSet
-		if len(supers) == 0 {
+		tax.parentOffsets[c] = uint32(len(tax.parents))
+		all := supers.Of(c)
+		if len(all) == 0 {
 			continue
 		}
 
-		// Collect candidate parents (everything in S(C) except C itself and Top).
-		candidates := make([]ConceptID, 0, len(supers))
+		// Collect candidate parents (everything in S(C) except C itself and
+		// Top), in ID order since S(C) is sorted.
+		candidates = candidates[:0]
 		hasTop := false
-		for s := range supers {
+		for _, s := range all {
 			if s == c {
 				continue
 			}
@@ -45,44 +72,78 @@ func BuildTaxonomy(contexts []Context, st *SymbolTable) *Taxonomy {
 		}
 
 		// Transitive reduction: B is a direct parent of C iff no other
-		// candidate S also subsumes B (i.e., B ∈ S(S)).
-		direct := make([]ConceptID, 0, 4)
-		for _, b := range candidates {
-			isDirect := true
-			for _, s := range candidates {
-				if s == b {
-					continue
-				}
-				if _, ok := contexts[s].superSet[b]; ok {
-					isDirect = false
-					break
+		// candidate S also subsumes B (i.e., B ∈ S(S)). Intersecting the
+		// candidates with each S(S) rules out all such B at once.
+		for _, s := range candidates {
+			common = intsets.Intersect(common[:0], candidates, supers.Of(s))
+			for _, b := range common {
+				if b != s {
+					redundant[b] = uint32(c) + 1
 				}
 			}
-			if isDirect {
-				direct = append(direct, b)
+		}
+		direct := 0
+		for _, b := range candidates {
+			if redundant[b] != uint32(c)+1 {
+				tax.parents = append(tax.parents, b)
+				direct++
 			}
 		}
 
 		// If no direct parents found but Top was in S(C), Top is the direct parent.
-		if len(direct) == 0 && hasTop {
-			direct = append(direct, Top)
+		if direct == 0 && hasTop {
+			tax.parents = append(tax.parents, Top)
 		}
+	}
+	tax.parentOffsets[n] = uint32(len(tax.parents))
 
-		tax.DirectParents[c] = direct
-		for _, p := range direct {
-			tax.DirectChildren[p] = append(tax.DirectChildren[p], c)
+	// Invert: count children per parent, prefix-sum, then fill in
+	// concept order so each child list is sorted.
+	for _, p := range tax.parents {
+		tax.childOffsets[p+1]++
+	}
+	for c := 0; c < n; c++ {
+		tax.childOffsets[c+1] += tax.childOffsets[c]
+	}
+	tax.children = make([]ConceptID, len(tax.parents))
+	next := append([]uint32(nil), tax.childOffsets[:n]...)
+	for c := ConceptID(2); c < ConceptID(n); c++ {
+		for _, p := range tax.DirectParents(c) {
+			tax.children[next[p]] = c
+			next[p]++
 		}
 	}
 
 	return tax
 }
 
+// superSets copies every S(C) into sorted sets, so the reduction can
+// intersect them instead of probing the context maps pairwise.
+func superSets(contexts []Context) *ConceptSets {
+	total := 0
+	for c := range contexts {
+		total += len(contexts[c].superSet)
+	}
+	cs := &ConceptSets{Offsets: make([]uint32, len(contexts)+1), Members: make([]ConceptID, 0, total)}
+	for c := range contexts {
+		start := len(cs.Members)
+		for s := range contexts[c].superSet {
+			cs.Members = append(cs.Members, s)
+		}
+		slices.Sort(cs.Members[start:])
+		cs.Offsets[c+1] = uint32(len(cs.Members))
+	}
+	return cs
+}
+
 // ClassifiedConcept represents a concept in the classified hierarchy.
 type ClassifiedConcept struct {
-	ID             string   `json:"id"`
-	Name           string   `json:"name,omitempty"`
-	DirectParents  []string `json:"direct_parents"`
-	DirectChildren []string `json:"direct_children,omitempty"`
+	ID             string              `json:"id"`
+	Name           string              `json:"name,omitempty"`
+	DirectParents  []string            `json:"direct_parents"`
+	DirectChildren []string            `json:"direct_children,omitempty"`
+	Provenance     []Provenance        `json:"provenance,omitempty"` // with TaxonomyOptions.Provenance
+	Fillers        map[string][]string `json:"fillers,omitempty"`    // relation → targets, with TaxonomyOptions.Fillers
 }
 
 // ClassificationStats holds timing and size metrics.
@@ -105,16 +166,29 @@ type ClassifiedHierarchy struct {
 
 // ToJSON converts the taxonomy to a ClassifiedHierarchy for JSON output.
 func (tax *Taxonomy) ToJSON(contexts []Context, st *SymbolTable, stats ClassificationStats) *ClassifiedHierarchy {
+	return tax.ToJSONWithOptions(contexts, st, stats, TaxonomyOptions{})
+}
+
+// ToJSONWithOptions is ToJSON with designated roots, provenance and role
+// fillers.
+func (tax *Taxonomy) ToJSONWithOptions(contexts []Context, st *SymbolTable, stats ClassificationStats, opts TaxonomyOptions) *ClassifiedHierarchy {
+	isRoot := make(map[ConceptID]bool, len(opts.Roots))
+	for _, r := range opts.Roots {
+		isRoot[r] = true
+	}
 	result := &ClassifiedHierarchy{
 		Stats: stats,
 	}
+	var prov *provenancer
+	if opts.Provenance != nil {
+		prov = newProvenancer(contexts, st, opts.Provenance)
+	}
 
 	// Count inferred subsumptions (total S(C) entries beyond self and Top).
 	inferred := 0
 	for c := ConceptID(2); c < ConceptID(st.ConceptCount()); c++ {
-		// Only count named concepts (non-empty name in symbol table).
-		name := st.ConceptName(c)
-		if name == "" {
+		// Only count named classes.
+		if !st.IsClass(c) {
 			continue
 		}
 		inferred += len(contexts[c].superSet) - 2 // subtract self and Top
@@ -124,32 +198,47 @@ func (tax *Taxonomy) ToJSON(contexts []Context, st *SymbolTable, stats Classific
 	}
 	result.Stats.InferredSubsumptions = inferred
 
-	// Build concept list (only named concepts).
+	// Build concept list (only named classes).
 	for c := ConceptID(2); c < ConceptID(st.ConceptCount()); c++ {
-		name := st.ConceptName(c)
-		if name == "" {
-			continue // skip fresh/anonymous concepts
+		if !st.IsClass(c) {
+			continue // skip fresh/anonymous concepts and nominals
 		}
+		name := st.ConceptName(c)
 
+		parents := tax.DirectParents(c)
+		if isRoot[c] {
+			parents = nil
+		}
 		cc := ClassifiedConcept{
 			ID:            name,
-			DirectParents: make([]string, 0, len(tax.DirectParents[c])),
+			DirectParents: make([]string, 0, len(parents)),
 		}
 
-		for _, p := range tax.DirectParents[c] {
+		for _, p := range parents {
 			pname := st.ConceptName(p)
 			if pname != "" {
 				cc.DirectParents = append(cc.DirectParents, pname)
+				if prov != nil && p != Top && p != Bottom {
+					cc.Provenance = append(cc.Provenance, prov.explain(c, p))
+				}
+			}
+		}
+
+		if kids := tax.DirectChildren(c); len(kids) > 0 {
+			cc.DirectChildren = make([]string, 0, len(kids))
+			for _, ch := range kids {
+				if st.IsClass(ch) {
+					cc.DirectChildren = append(cc.DirectChildren, st.ConceptName(ch))
+				}
 			}
 		}
 
-		if len(tax.DirectChildren[c]) > 0 {
-			cc.DirectChildren = make([]string, 0, len(tax.DirectChildren[c]))
-			for _, ch := range tax.DirectChildren[c] {
-				chname := st.ConceptName(ch)
-				if chname != "" {
-					cc.DirectChildren = append(cc.DirectChildren, chname)
+		for _, r := range opts.Fillers {
+			if fs := fillers(contexts, st, c, r, true); len(fs) > 0 {
+				if cc.Fillers == nil {
+					cc.Fillers = make(map[string][]string, len(opts.Fillers))
 				}
+				cc.Fillers[st.RoleName(r)] = fs
 			}
 		}
 
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/nodeadmin/chebi-parser/internal/exitcode"
+	"github.com/nodeadmin/chebi-parser/ontology"
+)
+
+// referencesCommand lists every term that mentions a given term, for impact
+// analysis before obsoleting it, as TSV (id, name, field, relation, via)
+// or JSON.
+func referencesCommand() (*flag.FlagSet, func() error) {
+	fs := newFlagSet("references")
+	input := fs.String("input", "", "Ontology file (.obo, .owl or .msgpack)")
+	format := fs.String("format", "auto", "Input format: auto, obo, owl, msgpack")
+	id := fs.String("id", "", "Term ID or name (IDs outside the ontology, such as xrefs, are accepted)")
+	asJSON := fs.Bool("json", false, "Write JSON instead of TSV")
+	return fs, func() error {
+		if *input == "" || *id == "" {
+			return exitcode.Errorf(exitcode.Usage, "usage: chebi-parser references -input <file> -id TERM [-json]")
+		}
+		ont, err := loadOntology(*input, *format)
+		if err != nil {
+			return err
+		}
+		ix := ontology.NewIndex(ont)
+		// IDs the ontology doesn't define (an external xref, a deleted term)
+		// are searched for as given.
+		target, err := ix.Lookup(*id)
+		if re, ok := err.(*ontology.RefError); ok {
+			if len(re.Candidates) > 0 {
+				return err
+			}
+			target = *id
+		}
+		refs := ix.ReferencedBy(target)
+
+		bw := bufio.NewWriter(os.Stdout)
+		if *asJSON {
+			enc := json.NewEncoder(bw)
+			enc.SetEscapeHTML(false)
+			enc.SetIndent("", "  ")
+			if refs == nil {
+				refs = []ontology.Reference{}
+			}
+			if err := enc.Encode(refs); err != nil {
+				return err
+			}
+		} else {
+			fmt.Fprintln(bw, "id\tname\tfield\trelation\tvia")
+			for _, r := range refs {
+				name := ""
+				if t := ix.Term(r.ID); t != nil {
+					name = t.Name
+				}
+				fmt.Fprintf(bw, "%s\t%s\t%s\t%s\t%s\n", r.ID, name, r.Field, r.Relation, r.Via)
+			}
+		}
+		if err := bw.Flush(); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "%d references to %s\n", len(refs), target)
+		return nil
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nodeadmin/chebi-parser/internal/exitcode"
+	"github.com/nodeadmin/chebi-parser/ontology"
+)
+
+// relationsCommand writes the relation usage matrix of an ontology: edges per
+// relationship type, overall and by the top-level classes of source and
+// target, as TSV (type, name, source, target, edges; source and target *
+// for a type's total) or JSON. With -against it instead lists the types
+// added and removed since an older release, and fails if any were added.
+func relationsCommand() (*flag.FlagSet, func() error) {
+	fs := newFlagSet("relations")
+	input := fs.String("input", "", "Ontology file (.obo, .owl or .msgpack)")
+	format := fs.String("format", "auto", "Input format: auto, obo, owl, msgpack")
+	root := fs.String("root", "", "Break counts down by this term's children instead of the terms without is_a parents")
+	against := fs.String("against", "", "Older release to compare relationship types with")
+	labels := fs.String("label-prefs", "", labelPrefsUsage)
+	asJSON := fs.Bool("json", false, "Write JSON instead of TSV")
+	return fs, func() error {
+		if *input == "" {
+			return exitcode.Errorf(exitcode.Usage, "usage: chebi-parser relations -input <file> [-root TERM] [-against <release>] [-label-prefs INN,name] [-json]")
+		}
+		prefs, err := labelPrefs(*labels)
+		if err != nil {
+			return err
+		}
+		ont, err := loadOntology(*input, *format)
+		if err != nil {
+			return err
+		}
+		ix := ontology.NewIndexWithOptions(ont, ontology.IndexOptions{Labels: prefs})
+		if *root != "" {
+			if *root, err = ix.Lookup(*root); err != nil {
+				return exitcode.Wrap(exitcode.Usage, fmt.Errorf("-root: %w", err))
+			}
+		}
+		m := ix.RelationMatrix(*root)
+
+		var added, removed []string
+		if *against != "" {
+			old, err := loadOntology(*against, "auto")
+			if err != nil {
+				return err
+			}
+			added, removed = m.TypeChanges(ontology.NewIndex(old).RelationMatrix(""))
+		}
+
+		bw := bufio.NewWriter(os.Stdout)
+		switch {
+		case *asJSON:
+			enc := json.NewEncoder(bw)
+			enc.SetEscapeHTML(false)
+			enc.SetIndent("", "  ")
+			var v any = m
+			if *against != "" {
+				v = struct {
+					Added   []string `json:"added"`
+					Removed []string `json:"removed"`
+				}{append([]string{}, added...), append([]string{}, removed...)}
+			}
+			if err := enc.Encode(v); err != nil {
+				return err
+			}
+		case *against != "":
+			for _, typ := range added {
+				fmt.Fprintf(bw, "added\t%s\n", typ)
+			}
+			for _, typ := range removed {
+				fmt.Fprintf(bw, "removed\t%s\n", typ)
+			}
+		default:
+			label := map[string]string{ontology.RelationOther: ontology.RelationOther}
+			for _, top := range m.Tops {
+				label[top.ID] = top.ID
+				if top.Name != "" {
+					label[top.ID] = top.Name
+				}
+			}
+			fmt.Fprintln(bw, "type\tname\tsource\ttarget\tedges")
+			for _, u := range m.Types {
+				fmt.Fprintf(bw, "%s\t%s\t*\t*\t%d\n", u.Type, u.Name, u.Edges)
+				for _, c := range u.Cells {
+					fmt.Fprintf(bw, "%s\t%s\t%s\t%s\t%d\n", u.Type, u.Name, label[c.Source], label[c.Target], c.Edges)
+				}
+			}
+		}
+		if err := bw.Flush(); err != nil {
+			return err
+		}
+
+		if *against != "" {
+			if len(added) > 0 {
+				return exitcode.Errorf(exitcode.Validation, "%d relationship types not used in %s: %s", len(added), *against, strings.Join(added, ", "))
+			}
+			fmt.Fprintf(os.Stderr, "No new relationship types since %s (%d no longer used)\n", *against, len(removed))
+			return nil
+		}
+		edges := 0
+		for _, u := range m.Types {
+			edges += u.Edges
+		}
+		fmt.Fprintf(os.Stderr, "%d relationship types, %d edges, %d top-level classes\n", len(m.Types), edges, len(m.Tops))
+		return nil
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nodeadmin/chebi-parser/internal/exitcode"
+	"github.com/nodeadmin/chebi-parser/ontology"
+)
+
+// resolveCommand lists the candidate terms for a chemical name with their
+// scores and how they matched, as TSV (id, name, score, tier, field, text)
+// or JSON. With -file it resolves a list of names instead and writes one
+// row per name: input, status, id, name, score, tier, candidates.
+func resolveCommand() (*flag.FlagSet, func() error) {
+	fs := newFlagSet("resolve")
+	input := fs.String("input", "", "Ontology file (.obo, .owl or .msgpack)")
+	format := fs.String("format", "auto", "Input format: auto, obo, owl, msgpack")
+	name := fs.String("name", "", "Name, synonym or ID to resolve")
+	file := fs.String("file", "", "File of names, one per line or in a TSV column (- for stdin)")
+	column := fs.Int("column", 1, "1-based TSV column holding the names (-file)")
+	header := fs.Bool("header", false, "Skip the first line of the names file (-file)")
+	limit := fs.Int("limit", 10, "Maximum candidates")
+	maxEdits := fs.Int("max-edits", 0, "Edit distance for fuzzy matches (0 = by length, -1 = off)")
+	workers := fs.Int("workers", 0, "Resolver goroutines for -file (default: number of CPUs)")
+	weightsFile := fs.String("weights", "", "JSON file of ranking weights overriding the defaults")
+	noObsolete := fs.Bool("no-obsolete", false, "Leave obsolete terms out of the candidates")
+	labels := fs.String("label-prefs", "", labelPrefsUsage)
+	asJSON := fs.Bool("json", false, "Write JSON instead of TSV")
+	output := fs.String("output", "", "Output file (default: stdout)")
+	return fs, func() error {
+		if *input == "" || (*name == "") == (*file == "") {
+			return exitcode.Errorf(exitcode.Usage, "usage: chebi-parser resolve -input <file> (-name NAME | -file names.txt) [-limit N] [-max-edits N] [-weights file.json] [-no-obsolete] [-label-prefs INN,name] [-json] [-output file]")
+		}
+		weights, err := loadResolveWeights(*weightsFile)
+		if err != nil {
+			return err
+		}
+		if *noObsolete {
+			weights.Obsolete = 0
+		}
+		prefs, err := labelPrefs(*labels)
+		if err != nil {
+			return err
+		}
+		ont, err := loadOntology(*input, *format)
+		if err != nil {
+			return err
+		}
+		ix := ontology.NewIndexWithOptions(ont, ontology.IndexOptions{Labels: prefs})
+		opts := ontology.ResolveOptions{Limit: *limit, MaxEdits: *maxEdits, Weights: weights}
+
+		out := os.Stdout
+		if *output != "" {
+			f, err := os.Create(*output)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			out = f
+		}
+		bw := bufio.NewWriter(out)
+		enc := json.NewEncoder(bw)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+
+		if *file != "" {
+			names, err := readIDs(*file, *column, *header)
+			if err != nil {
+				return err
+			}
+			results := ix.ResolveBatch(names, opts, *workers)
+			if *asJSON {
+				err = enc.Encode(results)
+			} else {
+				err = writeResolveTSV(bw, results)
+			}
+			if err != nil {
+				return err
+			}
+			if err := bw.Flush(); err != nil {
+				return err
+			}
+			counts := make(map[string]int)
+			for _, r := range results {
+				counts[r.Status]++
+			}
+			fmt.Fprintf(os.Stderr, "%d names: %d matched, %d ambiguous, %d unmatched\n", len(results),
+				counts[ontology.ResolveMatched], counts[ontology.ResolveAmbiguous], counts[ontology.ResolveUnmatched])
+			return nil
+		}
+
+		matches := ix.Resolve(*name, opts)
+		if *asJSON {
+			if matches == nil {
+				matches = []ontology.Match{}
+			}
+			if err := enc.Encode(matches); err != nil {
+				return err
+			}
+		} else {
+			fmt.Fprintln(bw, "id\tname\tscore\ttier\tfield\ttext")
+			for _, m := range matches {
+				fmt.Fprintf(bw, "%s\t%s\t%.3f\t%s\t%s\t%s\n", m.ID, m.Name, m.Score, m.Tier, m.Field, m.Text)
+			}
+		}
+		if err := bw.Flush(); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "%d candidates\n", len(matches))
+		return nil
+	}
+}
+
+// writeResolveTSV writes one row per batch result. id and name are set for
+// matched rows only; score and tier describe the best candidate and
+// candidates lists every returned ID.
+func writeResolveTSV(bw *bufio.Writer, results []ontology.ResolveResult) error {
+	fmt.Fprintln(bw, "input\tstatus\tid\tname\tscore\ttier\tcandidates")
+	for _, r := range results {
+		if len(r.Matches) == 0 {
+			fmt.Fprintf(bw, "%s\t%s\t\t\t\t\t\n", r.Input, r.Status)
+			continue
+		}
+		top := r.Matches[0]
+		label := ""
+		if r.ID != "" {
+			label = top.Name
+		}
+		ids := make([]string, len(r.Matches))
+		for i, m := range r.Matches {
+			ids[i] = m.ID
+		}
+		if _, err := fmt.Fprintf(bw, "%s\t%s\t%s\t%s\t%.3f\t%s\t%s\n", r.Input, r.Status, r.ID, label,
+			top.Score, top.Tier, strings.Join(ids, ",")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadResolveWeights returns the default weights overlaid with any entries
+// from the given JSON file, e.g. {"scopes": {"BROAD": 0.2}, "obsolete": 0}.
+func loadResolveWeights(path string) (*ontology.ResolveWeights, error) {
+	w := ontology.DefaultResolveWeights()
+	if path == "" {
+		return w, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, w); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return w, nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nodeadmin/chebi-parser/internal/exitcode"
+	"github.com/nodeadmin/chebi-parser/ontology"
+)
+
+// rollupCommand maps a list of CHEBI IDs or names onto grouping ancestors and
+// writes the per-bin counts as TSV (bin, name, count, terms) or JSON.
+func rollupCommand() (*flag.FlagSet, func() error) {
+	fs := newFlagSet("rollup")
+	input := fs.String("input", "", "Ontology file (.obo, .owl or .msgpack)")
+	format := fs.String("format", "auto", "Input format: auto, obo, owl, msgpack")
+	ids := fs.String("ids", "", "File of IDs or names to roll up, one per line or in a TSV column (- for stdin)")
+	column := fs.Int("column", 1, "1-based TSV column holding the IDs")
+	header := fs.Bool("header", false, "Skip the first line of the ID file")
+	bins := fs.String("bins", "", "File of grouping ancestor IDs or names, one per line")
+	subset := fs.String("subset", "", "Use the terms of this subset (e.g. a slim) as bins")
+	auto := fs.Int("auto", 0, "Select at most this many bins automatically: the most specific ancestors covering every ID within -min-terms")
+	minTerms := fs.Int("min-terms", 5, "With -auto, the fewest IDs each selected bin must cover")
+	binsOut := fs.String("bins-out", "", "Write the bin IDs, one per line, to this file (reusable with -bins)")
+	specific := fs.Bool("most-specific", false, "Count each term only in its most specific matching bins")
+	excludeBranch := fs.String("exclude-branch", "", excludeBranchUsage)
+	labels := fs.String("label-prefs", "", labelPrefsUsage)
+	asJSON := fs.Bool("json", false, "Write JSON instead of TSV")
+	output := fs.String("output", "", "Report file (default: stdout)")
+	return fs, func() error {
+		sources := 0
+		for _, set := range []bool{*bins != "", *subset != "", *auto > 0} {
+			if set {
+				sources++
+			}
+		}
+		if *input == "" || *ids == "" || sources != 1 {
+			return exitcode.Errorf(exitcode.Usage, "usage: chebi-parser rollup -input <file> -ids <file> (-bins <file> | -subset NAME | -auto K [-min-terms M]) [-bins-out bins.txt] [-most-specific] [-exclude-branch ROOTS] [-label-prefs INN,name] [-json] [-output report.tsv]")
+		}
+		prefs, err := labelPrefs(*labels)
+		if err != nil {
+			return err
+		}
+		ont, err := loadOntology(*input, *format)
+		if err != nil {
+			return err
+		}
+		ix := ontology.NewIndexWithOptions(ont, ontology.IndexOptions{Labels: prefs})
+		if *excludeBranch != "" {
+			roots, err := excludedBranches(ix, *excludeBranch)
+			if err != nil {
+				return err
+			}
+			ix = ontology.NewIndexWithOptions(ont, ontology.IndexOptions{ExcludeBranches: roots, Labels: prefs})
+		}
+
+		list, err := readIDs(*ids, *column, *header)
+		if err != nil {
+			return err
+		}
+		var binIDs []string
+		if *bins != "" {
+			if binIDs, err = readIDs(*bins, 1, false); err != nil {
+				return err
+			}
+			if binIDs, err = ix.LookupAll(binIDs); err != nil {
+				return fmt.Errorf("bins: %w", err)
+			}
+		} else if *auto > 0 {
+			binIDs = ix.InformativeAncestors(list, *auto, *minTerms)
+			if len(binIDs) == 0 {
+				return fmt.Errorf("no ancestor covers %d of the IDs; lower -min-terms", *minTerms)
+			}
+		} else {
+			for i := range ont.Terms {
+				for _, s := range ont.Terms[i].Subsets {
+					if s == *subset {
+						binIDs = append(binIDs, ont.Terms[i].ID)
+						break
+					}
+				}
+			}
+			if len(binIDs) == 0 {
+				return fmt.Errorf("subset %q has no terms", *subset)
+			}
+		}
+
+		if *binsOut != "" {
+			if err := os.WriteFile(*binsOut, []byte(strings.Join(binIDs, "\n")+"\n"), 0o644); err != nil {
+				return err
+			}
+		}
+
+		res := ix.Rollup(list, binIDs, *specific)
+
+		out := os.Stdout
+		if *output != "" {
+			f, err := os.Create(*output)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			out = f
+		}
+		bw := bufio.NewWriter(out)
+		if *asJSON {
+			enc := json.NewEncoder(bw)
+			enc.SetEscapeHTML(false)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(res); err != nil {
+				return err
+			}
+		} else {
+			fmt.Fprintln(bw, "bin\tname\tcount\tterms")
+			for _, b := range res.Bins {
+				fmt.Fprintf(bw, "%s\t%s\t%d\t%s\n", b.ID, b.Name, b.Count, strings.Join(b.Terms, ","))
+			}
+		}
+		if err := bw.Flush(); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(os.Stderr, "%d IDs into %d bins: %d unbinned, %d unknown\n",
+			len(list), len(res.Bins), len(res.Unbinned), len(res.Unknown))
+		if len(res.Excluded) > 0 {
+			fmt.Fprintf(os.Stderr, "%d IDs left out under -exclude-branch\n", len(res.Excluded))
+		}
+		return nil
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/nodeadmin/chebi-parser/internal/exitcode"
+	"github.com/nodeadmin/chebi-parser/ontology"
+)
+
+// sampleCommand writes a reproducible random sample of terms as TSV (id,
+// name, stratum), optionally balanced across depths, namespaces or subsets.
+func sampleCommand() (*flag.FlagSet, func() error) {
+	fs := newFlagSet("sample")
+	input := fs.String("input", "", "Ontology file (.obo, .owl or .msgpack)")
+	format := fs.String("format", "auto", "Input format: auto, obo, owl, msgpack")
+	n := fs.Int("n", 100, "Number of terms to sample")
+	seed := fs.Uint64("seed", 1, "Random seed")
+	stratify := fs.String("stratify", "", "Balance the sample across: depth, namespace, subset")
+	root := fs.String("root", "", "Only sample this term (ID or name) and its is_a descendants")
+	obsolete := fs.Bool("obsolete", false, "Include obsolete terms")
+	output := fs.String("output", "", "Output file (default: stdout)")
+	return fs, func() error {
+		if *input == "" || *n < 1 {
+			return exitcode.Errorf(exitcode.Usage, "usage: chebi-parser sample -input <file> [-n 100] [-seed N] [-stratify depth|namespace|subset] [-root TERM] [-obsolete] [-output file]")
+		}
+		ont, err := loadOntology(*input, *format)
+		if err != nil {
+			return err
+		}
+		ix := ontology.NewIndex(ont)
+		opts := ontology.SampleOptions{Seed: *seed, Stratify: *stratify, IncludeObsolete: *obsolete}
+		if *root != "" {
+			if opts.Root, err = ix.Lookup(*root); err != nil {
+				return err
+			}
+		}
+		sample, err := ix.SampleTerms(*n, opts)
+		if err != nil {
+			return err
+		}
+
+		out := os.Stdout
+		if *output != "" {
+			f, err := os.Create(*output)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			out = f
+		}
+		bw := bufio.NewWriter(out)
+		fmt.Fprintln(bw, "id\tname\tstratum")
+		for _, s := range sample {
+			fmt.Fprintf(bw, "%s\t%s\t%s\n", s.ID, s.Name, s.Stratum)
+		}
+		if err := bw.Flush(); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Sampled %d terms\n", len(sample))
+		return nil
+	}
+}
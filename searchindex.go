@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/nodeadmin/chebi-parser/internal/exitcode"
+	"github.com/nodeadmin/chebi-parser/ontology"
+)
+
+// searchIndexCommand builds the resolve search index for an ontology and
+// writes it to a file that `serve -index-dir` can memory-map.
+func searchIndexCommand() (*flag.FlagSet, func() error) {
+	fs := newFlagSet("search-index")
+	input := fs.String("input", "", "Ontology file (.obo, .owl or .msgpack)")
+	format := fs.String("format", "auto", "Input format: auto, obo, owl, msgpack")
+	output := fs.String("output", "", "Index file to write")
+	synonymsFlag := fs.String("synonyms", "keep", synonymsUsage)
+	return fs, func() error {
+		if *input == "" || *output == "" {
+			return exitcode.Errorf(exitcode.Usage, "usage: chebi-parser search-index -input <file> -output <file.idx> [-synonyms keep|collapse|fold]")
+		}
+		synonyms, err := ontology.ParseSynonymPolicy(*synonymsFlag)
+		if err != nil {
+			return exitcode.Errorf(exitcode.Usage, "-synonyms: %v", err)
+		}
+		ont, err := loadOntologyWithOptions(*input, *format, ontology.ParseOptions{Synonyms: synonyms})
+		if err != nil {
+			return err
+		}
+		start := time.Now()
+		if err := writeSearchIndex(ontology.NewIndex(ont), *output); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Wrote search index to %s in %v\n", *output, time.Since(start))
+		return nil
+	}
+}
+
+// writeSearchIndex writes ix's search index to path via a temporary file,
+// so a concurrent reader never maps a partial index.
+func writeSearchIndex(ix *ontology.Index, path string) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriterSize(f, 256*1024)
+	err = ix.WriteSearchIndex(bw)
+	if err == nil {
+		err = bw.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// attachSearchIndex maps the search index for ix from path, first
+// (re)building the file if it is missing or was built from another
+// ontology.
+func attachSearchIndex(ix *ontology.Index, path string) error {
+	f, err := ontology.OpenSearchIndex(path)
+	if err == nil {
+		if err = ix.UseSearchIndex(f); err == nil {
+			return nil
+		}
+		f.Close()
+	}
+	fmt.Fprintf(os.Stderr, "Building search index %s (%v)\n", path, err)
+	if err := writeSearchIndex(ix, path); err != nil {
+		return err
+	}
+	if f, err = ontology.OpenSearchIndex(path); err != nil {
+		return err
+	}
+	return ix.UseSearchIndex(f)
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/nodeadmin/chebi-parser/internal/exitcode"
+	"github.com/nodeadmin/chebi-parser/ontology"
+	"github.com/nodeadmin/chebi-parser/server"
+)
+
+// stringList is a repeatable string flag.
+type stringList []string
+
+func (l *stringList) String() string     { return strings.Join(*l, ",") }
+func (l *stringList) Set(v string) error { *l = append(*l, v); return nil }
+func (l *stringList) Get() any           { return []string(*l) }
+
+func serveCommand() (*flag.FlagSet, func() error) {
+	fs := newFlagSet("serve")
+	var inputs stringList
+	fs.Var(&inputs, "input", "Ontology file to host, optionally as version=path (repeatable)")
+	format := fs.String("format", "auto", "Input format: auto, obo, owl, msgpack")
+	addr := fs.String("addr", ":8080", "Listen address")
+	defaultVersion := fs.String("default", "", "Version answered by unprefixed routes (default: first input)")
+	weightsFile := fs.String("resolve-weights", "", "JSON file of ranking weights for /resolve")
+	columnar := fs.Bool("columnar", false, "Keep the is_a graph in flat columnar arrays, using less memory per release")
+	labels := fs.String("label-prefs", "", labelPrefsUsage+" in term display_name, resolve and path responses")
+	linkTemplates := fs.String("link-templates", "", "JSON file of URL templates overriding the defaults of the links in term responses")
+	indexDir := fs.String("index-dir", "", "Directory of persisted search indexes, one <version>.idx per release, built when missing or stale")
+	tlsCert := fs.String("tls-cert", "", "TLS certificate file (PEM); serves HTTPS and HTTP/2 with -tls-key")
+	tlsKey := fs.String("tls-key", "", "TLS private key file (PEM)")
+	drainDelay := fs.Duration("drain-delay", 5*time.Second, "On SIGTERM, time to keep serving with /readyz failing before closing the listener")
+	shutdownTimeout := fs.Duration("shutdown-timeout", 30*time.Second, "Time allowed for in-flight requests to finish during shutdown")
+	reload := fs.Bool("reload", false, "Enable the admin routes POST /admin/reload and /admin/default (needs an admin key in -api-keys)")
+	keepReleases := fs.Int("keep-releases", 3, "Releases kept besides the default after a reload, for rollback (0 keeps all)")
+	maxFindings := fs.Int("reload-max-findings", -1, "Reject a reloaded release with more lint findings (-1: no limit)")
+	watch := fs.String("watch", "", "Poll this release file or URL and reload it when its data-version changes (needs -reload)")
+	watchInterval := fs.Duration("watch-interval", time.Hour, "How often -watch polls")
+	var webhooks stringList
+	fs.Var(&webhooks, "webhook", "URL to POST a JSON release event to when a reload changes the default data-version (repeatable)")
+	webhookSecret := fs.String("webhook-secret", "", "Sign webhook bodies with HMAC-SHA256 in X-Signature-256")
+	keysFile := fs.String("api-keys", "", "JSON file of API keys (name, key, role read|admin, rate_per_minute); requires a key on every route")
+	return fs, func() error {
+		if len(inputs) == 0 {
+			return exitcode.Errorf(exitcode.Usage, "usage: chebi-parser serve -input [version=]<file> [-input ...] [-addr :8080] [-default version] [-resolve-weights file.json] [-index-dir dir] [-api-keys keys.json [-reload [-keep-releases 3] [-reload-max-findings N] [-watch file|URL] [-webhook URL]]] [-tls-cert cert.pem -tls-key key.pem] [-columnar] [-label-prefs INN,name] [-link-templates file.json]")
+		}
+		if (*watch != "" || len(webhooks) > 0) && !*reload {
+			return fmt.Errorf("-watch and -webhook need -reload")
+		}
+		if (*tlsCert == "") != (*tlsKey == "") {
+			return fmt.Errorf("-tls-cert and -tls-key must be given together")
+		}
+
+		weights, err := loadResolveWeights(*weightsFile)
+		if err != nil {
+			return err
+		}
+		prefs, err := labelPrefs(*labels)
+		if err != nil {
+			return err
+		}
+		links, err := loadLinkTemplates(*linkTemplates)
+		if err != nil {
+			return err
+		}
+		srv := server.New()
+		srv.SetResolveWeights(weights)
+		srv.SetLinkTemplates(links)
+		if *keysFile != "" {
+			keys, err := loadAPIKeys(*keysFile)
+			if err != nil {
+				return err
+			}
+			if err := srv.SetAPIKeys(keys); err != nil {
+				return err
+			}
+			if *reload && !hasAdminKey(keys) {
+				return exitcode.Errorf(exitcode.Usage, "-reload needs an admin key in %s", *keysFile)
+			}
+			fmt.Fprintf(os.Stderr, "Requiring API keys: %d configured\n", len(keys))
+		} else if *reload {
+			return exitcode.Errorf(exitcode.Usage, "-reload needs -api-keys with at least one admin key")
+		}
+		// Build every sub-index concurrently while loading, so neither the
+		// first requests nor those after a reload pay for it. With
+		// -index-dir the search tables come from the mapped image instead.
+		build := ontology.IndexAll
+		if *indexDir != "" {
+			build &^= ontology.IndexSearch
+		}
+		load := func(file, version string) (*server.Release, error) {
+			r, err := loadRelease(file, *format, version, ontology.IndexOptions{Columnar: *columnar, Labels: prefs, Build: build})
+			if err != nil {
+				return nil, fmt.Errorf("loading %s: %w", file, err)
+			}
+			if *indexDir != "" {
+				start := time.Now()
+				if err := attachSearchIndex(r.Index, filepath.Join(*indexDir, r.Version+".idx")); err != nil {
+					return nil, fmt.Errorf("search index for %s: %w", r.Version, err)
+				}
+				fmt.Fprintf(os.Stderr, "Mapped search index for %q in %v\n", r.Version, time.Since(start))
+			}
+			fmt.Fprintf(os.Stderr, "Loaded %s as version %q: %d terms in %v\n",
+				filepath.Base(file), r.Version, len(r.Ontology.Terms), r.LoadDuration)
+			return r, nil
+		}
+		for _, in := range inputs {
+			version, file, ok := strings.Cut(in, "=")
+			if !ok {
+				version, file = "", in
+			}
+			r, err := load(file, version)
+			if err != nil {
+				return err
+			}
+			srv.Add(r)
+		}
+		if *reload {
+			srv.EnableReload(server.ReloadOptions{
+				Load: func(source, version string) (*server.Release, error) {
+					file, cleanup, err := fetchSource(source)
+					if err != nil {
+						return nil, err
+					}
+					defer cleanup()
+					r, err := load(file, version)
+					if err != nil {
+						return nil, err
+					}
+					r.Source = source
+					return r, nil
+				},
+				Keep:            *keepReleases,
+				MaxLintFindings: *maxFindings,
+				Webhooks:        webhooks,
+				WebhookSecret:   *webhookSecret,
+				Logf: func(format string, args ...any) {
+					fmt.Fprintf(os.Stderr, format+"\n", args...)
+				},
+			})
+		}
+		ctx, stopWatch := context.WithCancel(context.Background())
+		defer stopWatch()
+		if *watch != "" {
+			go srv.Watch(ctx, *watch, *watchInterval)
+			fmt.Fprintf(os.Stderr, "Watching %s every %v\n", *watch, *watchInterval)
+		}
+		if *defaultVersion != "" && !srv.SetDefault(*defaultVersion) {
+			return fmt.Errorf("default version %q is not loaded", *defaultVersion)
+		}
+
+		hs := &http.Server{
+			Addr:              *addr,
+			Handler:           srv.Handler(),
+			ReadHeaderTimeout: 10 * time.Second,
+			TLSConfig:         &tls.Config{MinVersion: tls.VersionTLS12},
+		}
+		errc := make(chan error, 1)
+		go func() {
+			if *tlsCert != "" {
+				fmt.Fprintf(os.Stderr, "Listening on %s (HTTPS)\n", *addr)
+				errc <- hs.ListenAndServeTLS(*tlsCert, *tlsKey)
+			} else {
+				fmt.Fprintf(os.Stderr, "Listening on %s\n", *addr)
+				errc <- hs.ListenAndServe()
+			}
+		}()
+
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, syscall.SIGTERM, os.Interrupt)
+		select {
+		case err := <-errc:
+			return err
+		case s := <-sig:
+			fmt.Fprintf(os.Stderr, "Received %v; draining for %v\n", s, *drainDelay)
+		}
+		signal.Stop(sig)
+		stopWatch()
+		srv.SetDraining(true)
+		time.Sleep(*drainDelay)
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+		defer cancel()
+		if err := hs.Shutdown(shutdownCtx); err != nil {
+			return fmt.Errorf("shutdown: %w", err)
+		}
+		fmt.Fprintln(os.Stderr, "Shut down cleanly")
+		return nil
+	}
+}
+
+// loadAPIKeys reads a JSON array of server.APIKey.
+func loadAPIKeys(path string) ([]server.APIKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var keys []server.APIKey
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%s lists no API keys", path)
+	}
+	return keys, nil
+}
+
+// hasAdminKey reports whether keys include one with the admin role.
+func hasAdminKey(keys []server.APIKey) bool {
+	for _, k := range keys {
+		if k.Role == server.RoleAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchTimeout bounds a whole download, so a stalled server cannot hold a
+// reload (and every reload after it) forever.
+const fetchTimeout = 15 * time.Minute
+
+var fetchClient = &http.Client{Timeout: fetchTimeout}
+
+// fetchSource returns a local file for source, downloading it to a
+// temporary file (removed by cleanup) if it is an http or https URL.
+func fetchSource(source string) (file string, cleanup func(), err error) {
+	u, err := url.Parse(source)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return source, func() {}, nil
+	}
+	resp, err := fetchClient.Get(source)
+	if err != nil {
+		return "", nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", nil, fmt.Errorf("fetching %s: %s", source, resp.Status)
+	}
+	// Keep the extension so the format can still be detected.
+	f, err := os.CreateTemp("", "chebi-*"+path.Ext(u.Path))
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup = func() { os.Remove(f.Name()) }
+	_, err = io.Copy(f, resp.Body)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("fetching %s: %w", source, err)
+	}
+	return f.Name(), cleanup, nil
+}
+
+// loadRelease parses file while computing its SHA-256. If version is empty
+// it is derived from the ontology's data-version.
+func loadRelease(file, format, version string, opts ontology.IndexOptions) (*server.Release, error) {
+	inputFmt := detectFormat(file, format)
+	if inputFmt == "" {
+		return nil, fmt.Errorf("cannot detect format; use -format obo or -format owl")
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	start := time.Now()
+	h := sha256.New()
+	tee := io.TeeReader(f, h)
+	ont, err := parseInput(tee, inputFmt)
+	if err != nil {
+		return nil, exitcode.Wrap(exitcode.Parse, err)
+	}
+	// Hash any trailing bytes the parser did not consume.
+	if _, err := io.Copy(io.Discard, tee); err != nil {
+		return nil, err
+	}
+
+	if version == "" {
+		version = versionFromDataVersion(ont.DataVersion)
+	}
+	if version == "" {
+		version = strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	}
+	r := server.NewReleaseWithOptions(version, ont, opts)
+	r.Source = file
+	r.SHA256 = hex.EncodeToString(h.Sum(nil))
+	r.LoadDuration = time.Since(start)
+	return r, nil
+}
+
+// versionFromDataVersion shortens a data-version such as
+// "releases/2024-01-01" or an OWL versionIRI ending in
+// ".../releases/239/chebi.owl" to the release name ("2024-01-01", "239").
+func versionFromDataVersion(dv string) string {
+	if dv == "" {
+		return ""
+	}
+	if _, after, ok := strings.Cut(dv, "releases/"); ok {
+		dv = after
+	}
+	dv, _, _ = strings.Cut(dv, "/")
+	return path.Base(dv)
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Roles an API key can have. Read keys can use every query route; admin
+// keys can also use the routes that change what the server hosts.
+const (
+	RoleRead  = "read"
+	RoleAdmin = "admin"
+)
+
+// APIKey is a client credential, sent as "Authorization: Bearer KEY" or
+// "X-API-Key: KEY".
+type APIKey struct {
+	Name string `json:"name"` // for logs and error messages
+	Key  string `json:"key"`
+	Role string `json:"role"` // RoleRead if empty
+	// RatePerMinute is the number of requests the key may make per minute,
+	// with bursts of up to as many; 0 means unlimited.
+	RatePerMinute int `json:"rate_per_minute,omitempty"`
+}
+
+// apiKey is a registered key with its rate limiter state.
+type apiKey struct {
+	APIKey
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// allow takes one request from the key's budget, or returns how long until
+// one is available.
+func (k *apiKey) allow(now time.Time) (bool, time.Duration) {
+	if k.RatePerMinute <= 0 {
+		return true, 0
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	burst := float64(k.RatePerMinute)
+	perSec := burst / 60
+	if k.last.IsZero() {
+		k.tokens = burst
+	} else {
+		k.tokens = math.Min(burst, k.tokens+now.Sub(k.last).Seconds()*perSec)
+	}
+	k.last = now
+	if k.tokens < 1 {
+		return false, time.Duration((1 - k.tokens) / perSec * float64(time.Second))
+	}
+	k.tokens--
+	return true, 0
+}
+
+// SetAPIKeys turns on authentication: every route then requires one of
+// keys. With no keys (the default) the server is open.
+func (s *Server) SetAPIKeys(keys []APIKey) error {
+	m := make(map[[sha256.Size]byte]*apiKey, len(keys))
+	for i, k := range keys {
+		if k.Key == "" {
+			return fmt.Errorf("API key %d (%q) is empty", i+1, k.Name)
+		}
+		if k.Role == "" {
+			k.Role = RoleRead
+		}
+		if k.Role != RoleRead && k.Role != RoleAdmin {
+			return fmt.Errorf("API key %q: unknown role %q (want %s or %s)", k.Name, k.Role, RoleRead, RoleAdmin)
+		}
+		h := sha256.Sum256([]byte(k.Key))
+		if _, dup := m[h]; dup {
+			return fmt.Errorf("API key %q is listed twice", k.Name)
+		}
+		m[h] = &apiKey{APIKey: k}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = m
+	return nil
+}
+
+type keyContext struct{}
+
+// authenticate checks the request's API key and rate limit when keys are
+// configured, recording the key in the request context.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		s.mu.RLock()
+		keys := s.keys
+		s.mu.RUnlock()
+		if len(keys) == 0 || req.URL.Path == healthPath || req.URL.Path == readyPath {
+			next.ServeHTTP(w, req)
+			return
+		}
+		secret := req.Header.Get("X-API-Key")
+		if v, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer "); ok {
+			secret = strings.TrimSpace(v)
+		}
+		k := keys[sha256.Sum256([]byte(secret))]
+		if secret == "" || k == nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="chebi-parser"`)
+			writeError(w, http.StatusUnauthorized, "missing or unknown API key")
+			return
+		}
+		if ok, wait := k.allow(time.Now()); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			writeError(w, http.StatusTooManyRequests, "rate limit exceeded for API key "+k.Name)
+			return
+		}
+		next.ServeHTTP(w, req.WithContext(context.WithValue(req.Context(), keyContext{}, k)))
+	})
+}
+
+// admin restricts a route to admin keys. Without configured keys there is
+// no admin, and admin routes are refused.
+func (s *Server) admin(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		k, ok := req.Context().Value(keyContext{}).(*apiKey)
+		if !ok {
+			writeError(w, http.StatusForbidden, "admin routes need an admin API key")
+			return
+		}
+		if k.Role != RoleAdmin {
+			writeError(w, http.StatusForbidden, "API key "+k.Name+" is read-only")
+			return
+		}
+		h(w, req)
+	}
+}
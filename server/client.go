@@ -0,0 +1,223 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/nodeadmin/chebi-parser/ontology"
+)
+
+// Client calls the HTTP API of a remote `chebi-parser serve`.
+type Client struct {
+	BaseURL string       // such as "http://localhost:8080"
+	Version string       // release to query; the server's default if empty
+	HTTP    *http.Client // http.DefaultClient if nil
+	APIKey  string       // sent as a bearer token if set
+}
+
+// NewClient returns a client for the server at baseURL, answering from its
+// default release.
+func NewClient(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// APIError is an error response from the server.
+type APIError struct {
+	Status  int
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("server: %s (HTTP %d)", e.Message, e.Status)
+}
+
+// Versions lists the hosted releases.
+func (c *Client) Versions(ctx context.Context) ([]Metadata, error) {
+	var out []Metadata
+	return out, c.do(ctx, http.MethodGet, "/versions", nil, nil, &out)
+}
+
+// Ontology returns the metadata of the client's release.
+func (c *Client) Ontology(ctx context.Context) (*Metadata, error) {
+	var out Metadata
+	return &out, c.do(ctx, http.MethodGet, c.route("/ontology"), nil, nil, &out)
+}
+
+// Term returns a term by ID, alt ID or name.
+func (c *Client) Term(ctx context.Context, ref string) (*ontology.Term, error) {
+	var out ontology.Term
+	return &out, c.do(ctx, http.MethodGet, c.route("/terms/"+url.PathEscape(ref)), nil, nil, &out)
+}
+
+// DisplayName returns a term's display_name, its label under the label
+// preferences the release is served with, or its name if the release has
+// none.
+func (c *Client) DisplayName(ctx context.Context, ref string) (string, error) {
+	var out struct {
+		Name        string `json:"name"`
+		DisplayName string `json:"display_name"`
+	}
+	if err := c.do(ctx, http.MethodGet, c.route("/terms/"+url.PathEscape(ref)), nil, nil, &out); err != nil {
+		return "", err
+	}
+	if out.DisplayName != "" {
+		return out.DisplayName, nil
+	}
+	return out.Name, nil
+}
+
+// Parents returns the asserted is_a parents of a term.
+func (c *Client) Parents(ctx context.Context, ref string) ([]string, error) {
+	var out []string
+	return out, c.do(ctx, http.MethodGet, c.route("/terms/"+url.PathEscape(ref)+"/parents"), nil, nil, &out)
+}
+
+// Children returns the asserted is_a children of a term.
+func (c *Client) Children(ctx context.Context, ref string) ([]string, error) {
+	var out []string
+	return out, c.do(ctx, http.MethodGet, c.route("/terms/"+url.PathEscape(ref)+"/children"), nil, nil, &out)
+}
+
+// Ancestors returns the transitive is_a ancestors of a term.
+func (c *Client) Ancestors(ctx context.Context, ref string) ([]string, error) {
+	var out []string
+	return out, c.do(ctx, http.MethodGet, c.route("/terms/"+url.PathEscape(ref)+"/ancestors"), nil, nil, &out)
+}
+
+// References returns the terms mentioning a term.
+func (c *Client) References(ctx context.Context, ref string) ([]ontology.Reference, error) {
+	var out []ontology.Reference
+	return out, c.do(ctx, http.MethodGet, c.route("/terms/"+url.PathEscape(ref)+"/references"), nil, nil, &out)
+}
+
+// Edges returns the relationships of a term read in direction d: as it
+// asserts them (ontology.DirectionUp) or as their targets see them
+// (ontology.DirectionDown).
+func (c *Client) Edges(ctx context.Context, ref string, d ontology.Direction) ([]ontology.DirectedEdge, error) {
+	var out []ontology.DirectedEdge
+	q := url.Values{"direction": {string(d)}}
+	return out, c.do(ctx, http.MethodGet, c.route("/terms/"+url.PathEscape(ref)+"/edges"), q, nil, &out)
+}
+
+// Path returns the shortest relationship path between two terms, over the
+// given relations or all of them.
+func (c *Client) Path(ctx context.Context, from, to string, relations []string) (*PathResponse, error) {
+	q := url.Values{"from": {from}, "to": {to}}
+	if len(relations) > 0 {
+		q.Set("relations", strings.Join(relations, ","))
+	}
+	var out PathResponse
+	return &out, c.do(ctx, http.MethodGet, c.route("/path"), q, nil, &out)
+}
+
+// Subsumes reports whether sub is an inferred subclass of super.
+func (c *Client) Subsumes(ctx context.Context, sub, super string) (*SubsumesResponse, error) {
+	var out SubsumesResponse
+	return &out, c.do(ctx, http.MethodGet, c.route("/subsumes"), url.Values{"sub": {sub}, "super": {super}}, nil, &out)
+}
+
+// Resolve returns the ranked candidate terms for a name; limit 0 uses the
+// server's default.
+func (c *Client) Resolve(ctx context.Context, name string, limit int) (*ResolveResponse, error) {
+	q := url.Values{"q": {name}}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	var out ResolveResponse
+	return &out, c.do(ctx, http.MethodGet, c.route("/resolve"), q, nil, &out)
+}
+
+// ResolveBatch resolves many names in one request.
+func (c *Client) ResolveBatch(ctx context.Context, req ResolveBatchRequest) (*ResolveBatchResponse, error) {
+	var out ResolveBatchResponse
+	return &out, c.do(ctx, http.MethodPost, c.route("/resolve"), nil, req, &out)
+}
+
+// Query returns the subclasses, or with instances the individuals, of a
+// class expression.
+func (c *Client) Query(ctx context.Context, expr string, instances bool) (*QueryResponse, error) {
+	q := url.Values{"expr": {expr}}
+	if instances {
+		q.Set("instances", "true")
+	}
+	var out QueryResponse
+	return &out, c.do(ctx, http.MethodGet, c.route("/query"), q, nil, &out)
+}
+
+// QueryBatch answers many class expressions in one request; an expression
+// that fails sets its result's Error.
+func (c *Client) QueryBatch(ctx context.Context, req QueryBatchRequest) (*QueryBatchResponse, error) {
+	var out QueryBatchResponse
+	return &out, c.do(ctx, http.MethodPost, c.route("/query"), nil, req, &out)
+}
+
+// GraphQL runs a GraphQL query with optional variables. Field errors come
+// back in the response's Errors; a query the server cannot parse or
+// validate is an *APIError.
+func (c *Client) GraphQL(ctx context.Context, query string, variables map[string]any) (*GraphQLResponse, error) {
+	var out GraphQLResponse
+	return &out, c.do(ctx, http.MethodPost, c.route("/graphql"), nil, GraphQLRequest{Query: query, Variables: variables}, &out)
+}
+
+// route prefixes a release route with the client's version, if any.
+func (c *Client) route(p string) string {
+	if c.Version == "" {
+		return p
+	}
+	return "/v/" + url.PathEscape(c.Version) + p
+}
+
+// do sends a request with an optional JSON body and decodes the JSON
+// response into out, turning error responses into *APIError.
+func (c *Client) do(ctx context.Context, method, p string, query url.Values, body, out any) error {
+	u := strings.TrimRight(c.BaseURL, "/") + p
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var rd io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		rd = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, rd)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	hc := c.HTTP
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Error string `json:"error"`
+		}
+		msg := resp.Status
+		if json.NewDecoder(resp.Body).Decode(&e) == nil && e.Error != "" {
+			msg = e.Error
+		}
+		return &APIError{Status: resp.StatusCode, Message: msg}
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
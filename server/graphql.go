@@ -0,0 +1,873 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/nodeadmin/chebi-parser/ontology"
+)
+
+// GraphQLRequest is the body of POST /graphql.
+type GraphQLRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// GraphQLError is one entry of a GraphQL response's errors.
+type GraphQLError struct {
+	Message string `json:"message"`
+	Path    []any  `json:"path,omitempty"`
+}
+
+// GraphQLResponse is the body of a /graphql response. Data is nil when the
+// request could not be parsed or validated.
+type GraphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []GraphQLError  `json:"errors,omitempty"`
+}
+
+const (
+	// maxGraphQLDepth bounds selection nesting, so parents { parents { ...
+	// cannot be nested without limit.
+	maxGraphQLDepth = 12
+	// maxGraphQLTerms bounds the terms one request resolves.
+	maxGraphQLTerms = 50000
+)
+
+// handleGraphQL serves a read-only subset of GraphQL over a release, so a
+// client can fetch a term with its nested parents, children and ancestors
+// in one request instead of one per node. The schema is
+//
+//	type Query {
+//	  term(id: String!): Term              # ID, alt ID or name
+//	  terms(ids: [String!]!): [Term]
+//	  search(q: String!, limit: Int, obsolete: Boolean): [Term]
+//	}
+//	type Term {
+//	  id: String!  name: String  displayName: String  namespace: String
+//	  definition: String  comment: String  obsolete: Boolean!
+//	  subsets: [String!]!  altIds: [String!]!  xrefs: [String!]!
+//	  replacedBy: [String!]!  consider: [String!]!
+//	  synonyms: [Synonym!]!  chemistry: Chemistry!
+//	  parents: [Term!]!  children: [Term!]!  ancestors: [Term!]!
+//	}
+//	type Synonym { text: String!  scope: String!  type: String  xrefs: [String!]! }
+//	type Chemistry {
+//	  formula: String  charge: String  mass: String
+//	  monoisotopicMass: String  inchikey: String  smiles: String
+//	}
+//
+// Operations may be named and declare variables; aliases and __typename
+// are supported. Fragments, directives, mutations and subscriptions are
+// not.
+func (s *Server) handleGraphQL(w http.ResponseWriter, req *http.Request, r *Release) {
+	var body GraphQLRequest
+	if req.Method == http.MethodGet {
+		q := req.URL.Query()
+		body.Query, body.OperationName = q.Get("query"), q.Get("operationName")
+		if v := q.Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &body.Variables); err != nil {
+				writeGraphQLError(w, "invalid variables: "+err.Error())
+				return
+			}
+		}
+	} else {
+		dec := json.NewDecoder(http.MaxBytesReader(w, req.Body, 1<<20))
+		if err := dec.Decode(&body); err != nil {
+			writeGraphQLError(w, "invalid request body: "+err.Error())
+			return
+		}
+	}
+	if body.Query == "" {
+		writeGraphQLError(w, "query is required")
+		return
+	}
+	op, err := parseGraphQL(body.Query, body.OperationName)
+	if err != nil {
+		writeGraphQLError(w, err.Error())
+		return
+	}
+	ex := &gqlExec{s: s, r: r, vars: body.Variables, defaults: op.defaults}
+	if err := ex.validate(op.sel, gqlQueryFields, 1); err != nil {
+		writeGraphQLError(w, err.Error())
+		return
+	}
+	data := ex.object(op.sel, nil, func(f *gqlField, path []any) any { return ex.query(f, path) })
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(data)
+	writeJSON(w, http.StatusOK, GraphQLResponse{Data: bytes.TrimSpace(buf.Bytes()), Errors: ex.errs})
+}
+
+func writeGraphQLError(w http.ResponseWriter, msg string) {
+	writeJSON(w, http.StatusBadRequest, GraphQLResponse{Errors: []GraphQLError{{Message: msg}}})
+}
+
+// gqlKind is what a field returns.
+type gqlKind int
+
+const (
+	gqlScalar gqlKind = iota
+	gqlTerm           // a Term, or a list of them
+	gqlSynonym
+	gqlChemistry
+)
+
+// Field tables per type: the kind each field returns and the arguments it
+// takes.
+type gqlFieldDef struct {
+	kind gqlKind
+	args []string
+}
+
+var (
+	gqlQueryFields = map[string]gqlFieldDef{
+		"term":   {gqlTerm, []string{"id"}},
+		"terms":  {gqlTerm, []string{"ids"}},
+		"search": {gqlTerm, []string{"q", "limit", "obsolete"}},
+	}
+	gqlTermFields = map[string]gqlFieldDef{
+		"id": {}, "name": {}, "displayName": {}, "namespace": {}, "definition": {},
+		"comment": {}, "obsolete": {}, "subsets": {}, "altIds": {}, "xrefs": {},
+		"replacedBy": {}, "consider": {},
+		"synonyms":  {kind: gqlSynonym},
+		"chemistry": {kind: gqlChemistry},
+		"parents":   {kind: gqlTerm},
+		"children":  {kind: gqlTerm},
+		"ancestors": {kind: gqlTerm},
+	}
+	gqlSynonymFields   = map[string]gqlFieldDef{"text": {}, "scope": {}, "type": {}, "xrefs": {}}
+	gqlChemistryFields = map[string]gqlFieldDef{
+		"formula": {}, "charge": {}, "mass": {}, "monoisotopicMass": {}, "inchikey": {}, "smiles": {},
+	}
+	gqlFieldsOf = map[gqlKind]map[string]gqlFieldDef{
+		gqlTerm: gqlTermFields, gqlSynonym: gqlSynonymFields, gqlChemistry: gqlChemistryFields,
+	}
+	// gqlChemKeys maps Chemistry fields to ontology.ChemProperty names.
+	gqlChemKeys = map[string]string{
+		"formula": "formula", "charge": "charge", "mass": "mass",
+		"monoisotopicMass": "monoisotopicmass", "inchikey": "inchikey", "smiles": "smiles",
+	}
+)
+
+// gqlExec resolves one validated operation against a release. Errors from
+// individual fields are collected and the field answered with null.
+type gqlExec struct {
+	s        *Server
+	r        *Release
+	vars     map[string]any
+	defaults map[string]any
+	errs     []GraphQLError
+	terms    int
+}
+
+// validate checks a selection set against the fields of its type, before
+// anything is resolved.
+func (ex *gqlExec) validate(sel []*gqlField, fields map[string]gqlFieldDef, depth int) error {
+	if depth > maxGraphQLDepth {
+		return fmt.Errorf("selections are nested more than %d deep", maxGraphQLDepth)
+	}
+	for _, f := range sel {
+		if f.name == "__typename" {
+			continue
+		}
+		def, ok := fields[f.name]
+		if !ok {
+			return fmt.Errorf("line %d: unknown field %q", f.line, f.name)
+		}
+		for name := range f.args {
+			if !contains(def.args, name) {
+				return fmt.Errorf("line %d: unknown argument %q on field %q", f.line, name, f.name)
+			}
+		}
+		if def.kind == gqlScalar {
+			if f.sel != nil {
+				return fmt.Errorf("line %d: field %q is a scalar and takes no selection", f.line, f.name)
+			}
+			continue
+		}
+		if f.sel == nil {
+			return fmt.Errorf("line %d: field %q needs a selection", f.line, f.name)
+		}
+		if err := ex.validate(f.sel, gqlFieldsOf[def.kind], depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (ex *gqlExec) fail(path []any, format string, args ...any) any {
+	ex.errs = append(ex.errs, GraphQLError{Message: fmt.Sprintf(format, args...), Path: append([]any(nil), path...)})
+	return nil
+}
+
+// object answers a selection set, in selection order, with each field's
+// value from resolve.
+func (ex *gqlExec) object(sel []*gqlField, path []any, resolve func(f *gqlField, path []any) any) gqlObject {
+	obj := make(gqlObject, 0, len(sel))
+	for _, f := range sel {
+		p := append(path[:len(path):len(path)], f.key())
+		obj = append(obj, gqlEntry{f.key(), resolve(f, p)})
+	}
+	return obj
+}
+
+// query resolves a field of Query.
+func (ex *gqlExec) query(f *gqlField, path []any) any {
+	switch f.name {
+	case "__typename":
+		return "Query"
+	case "term":
+		ref, err := ex.stringArg(f, "id", true)
+		if err != nil {
+			return ex.fail(path, "%v", err)
+		}
+		return ex.lookup(f, path, ref)
+	case "terms":
+		v, err := ex.arg(f, "ids")
+		if err != nil {
+			return ex.fail(path, "%v", err)
+		}
+		refs, ok := v.([]any)
+		if !ok {
+			return ex.fail(path, "argument ids must be a list of strings")
+		}
+		out := make([]any, len(refs))
+		for i, ref := range refs {
+			s, ok := ref.(string)
+			if !ok {
+				return ex.fail(path, "argument ids must be a list of strings")
+			}
+			out[i] = ex.lookup(f, append(path[:len(path):len(path)], i), s)
+		}
+		return out
+	case "search":
+		q, err := ex.stringArg(f, "q", true)
+		if err != nil {
+			return ex.fail(path, "%v", err)
+		}
+		limit, err := ex.intArg(f, "limit")
+		if err != nil {
+			return ex.fail(path, "%v", err)
+		}
+		var obsolete *bool
+		if v, err := ex.arg(f, "obsolete"); err != nil {
+			return ex.fail(path, "%v", err)
+		} else if v != nil {
+			b, ok := v.(bool)
+			if !ok {
+				return ex.fail(path, "argument obsolete must be a Boolean")
+			}
+			obsolete = &b
+		}
+		matches, err := ex.r.Storage.Search(q, ex.s.resolveOptions(limit, obsolete))
+		if err != nil {
+			return ex.fail(path, "%v", err)
+		}
+		ids := make([]string, len(matches))
+		for i, m := range matches {
+			ids[i] = m.ID
+		}
+		return ex.termList(f, path, ids, nil)
+	}
+	return nil
+}
+
+// lookup resolves a term reference, answering null with an error if it is
+// unknown or ambiguous.
+func (ex *gqlExec) lookup(f *gqlField, path []any, ref string) any {
+	id, err := ex.r.Storage.Lookup(ref)
+	if err != nil {
+		return ex.fail(path, "%v", err)
+	}
+	t, err := ex.r.Storage.GetTerm(id)
+	if err != nil {
+		return ex.fail(path, "%v", err)
+	}
+	if t == nil {
+		return ex.fail(path, "term %s not found", ref)
+	}
+	return ex.term(f.sel, path, t)
+}
+
+// termList resolves a list of IDs; err is the storage error that produced
+// them.
+func (ex *gqlExec) termList(f *gqlField, path []any, ids []string, err error) any {
+	if err != nil {
+		return ex.fail(path, "%v", err)
+	}
+	out := make([]any, 0, len(ids))
+	for i, id := range ids {
+		p := append(path[:len(path):len(path)], i)
+		t, err := ex.r.Storage.GetTerm(id)
+		if err != nil {
+			return ex.fail(p, "%v", err)
+		}
+		if t == nil {
+			// A dangling is_a target is listed by ID only.
+			t = &ontology.Term{ID: id}
+		}
+		out = append(out, ex.term(f.sel, p, t))
+	}
+	return out
+}
+
+// term resolves a selection on one Term.
+func (ex *gqlExec) term(sel []*gqlField, path []any, t *ontology.Term) any {
+	if ex.terms++; ex.terms > maxGraphQLTerms {
+		if ex.terms == maxGraphQLTerms+1 {
+			return ex.fail(path, "query resolves more than %d terms", maxGraphQLTerms)
+		}
+		return nil
+	}
+	return ex.object(sel, path, func(f *gqlField, path []any) any {
+		switch f.name {
+		case "__typename":
+			return "Term"
+		case "id":
+			return t.ID
+		case "name":
+			return optional(t.Name)
+		case "displayName":
+			name, err := ex.r.Storage.Label(t.ID)
+			if err != nil {
+				return ex.fail(path, "%v", err)
+			}
+			return optional(name)
+		case "namespace":
+			return optional(t.Namespace)
+		case "definition":
+			return optional(t.Definition)
+		case "comment":
+			return optional(t.Comment)
+		case "obsolete":
+			return t.IsObsolete
+		case "subsets":
+			return nonNil(t.Subsets)
+		case "altIds":
+			return nonNil(t.AltIDs)
+		case "xrefs":
+			return nonNil(t.Xrefs)
+		case "replacedBy":
+			return nonNil(t.ReplacedBy)
+		case "consider":
+			return nonNil(t.Consider)
+		case "synonyms":
+			out := make([]any, len(t.Synonyms))
+			for i := range t.Synonyms {
+				syn := &t.Synonyms[i]
+				out[i] = ex.object(f.sel, append(path[:len(path):len(path)], i), func(f *gqlField, _ []any) any {
+					switch f.name {
+					case "__typename":
+						return "Synonym"
+					case "text":
+						return syn.Text
+					case "scope":
+						return syn.Scope
+					case "type":
+						return optional(syn.Type)
+					case "xrefs":
+						return nonNil(syn.Xrefs)
+					}
+					return nil
+				})
+			}
+			return out
+		case "chemistry":
+			return ex.object(f.sel, path, func(f *gqlField, _ []any) any {
+				if f.name == "__typename" {
+					return "Chemistry"
+				}
+				v, _ := ontology.ChemProperty(t, gqlChemKeys[f.name])
+				return optional(v)
+			})
+		case "parents":
+			ids, err := ex.r.Storage.Parents(t.ID)
+			return ex.termList(f, path, ids, err)
+		case "children":
+			ids, err := ex.r.Storage.Children(t.ID)
+			return ex.termList(f, path, ids, err)
+		case "ancestors":
+			ids, err := ex.r.Storage.Closure(t.ID)
+			return ex.termList(f, path, ids, err)
+		}
+		return nil
+	})
+}
+
+// optional answers an empty string with null.
+func optional(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// arg returns an argument's value with variables substituted, or nil if
+// it is absent.
+func (ex *gqlExec) arg(f *gqlField, name string) (any, error) {
+	return ex.value(f.args[name])
+}
+
+func (ex *gqlExec) value(v any) (any, error) {
+	switch v := v.(type) {
+	case gqlVar:
+		if val, ok := ex.vars[string(v)]; ok {
+			return val, nil
+		}
+		if val, ok := ex.defaults[string(v)]; ok {
+			return ex.value(val)
+		}
+		return nil, fmt.Errorf("variable $%s is not set", string(v))
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			var err error
+			if out[i], err = ex.value(e); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+func (ex *gqlExec) stringArg(f *gqlField, name string, required bool) (string, error) {
+	v, err := ex.arg(f, name)
+	if err != nil {
+		return "", err
+	}
+	s, ok := v.(string)
+	if !ok || (required && s == "") {
+		return "", fmt.Errorf("argument %s of %s must be a non-empty String", name, f.name)
+	}
+	return s, nil
+}
+
+func (ex *gqlExec) intArg(f *gqlField, name string) (int, error) {
+	v, err := ex.arg(f, name)
+	if err != nil || v == nil {
+		return 0, err
+	}
+	// Variables arrive as JSON numbers.
+	n, ok := v.(float64)
+	if !ok || n < 1 || n != float64(int(n)) {
+		return 0, fmt.Errorf("argument %s of %s must be a positive Int", name, f.name)
+	}
+	return int(n), nil
+}
+
+// gqlObject is a response object that keeps its fields in selection
+// order, as GraphQL requires.
+type gqlObject []gqlEntry
+
+type gqlEntry struct {
+	key   string
+	value any
+}
+
+func (o gqlObject) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, e := range o {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, _ := json.Marshal(e.key)
+		b.Write(k)
+		b.WriteByte(':')
+		v, err := json.Marshal(e.value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// gqlField is one field of a selection set.
+type gqlField struct {
+	alias, name string
+	args        map[string]any // string, float64, bool, nil, []any or gqlVar
+	sel         []*gqlField    // nil for a scalar
+	line        int
+}
+
+func (f *gqlField) key() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+// gqlVar is a $variable reference in an argument.
+type gqlVar string
+
+// gqlOperation is the parsed operation to run.
+type gqlOperation struct {
+	sel      []*gqlField
+	defaults map[string]any // variable default values
+}
+
+// parseGraphQL parses a query document and returns the operation named
+// name, or its only operation if name is empty.
+func parseGraphQL(src, name string) (*gqlOperation, error) {
+	p := &gqlParser{src: src, line: 1}
+	var ops []*gqlOperation
+	var names []string
+	for p.skip(); p.pos < len(p.src); p.skip() {
+		opName, op, err := p.operation()
+		if err != nil {
+			return nil, err
+		}
+		ops, names = append(ops, op), append(names, opName)
+	}
+	if len(ops) == 0 {
+		return nil, fmt.Errorf("document has no operation")
+	}
+	if name == "" {
+		if len(ops) > 1 {
+			return nil, fmt.Errorf("document has %d operations; set operationName", len(ops))
+		}
+		return ops[0], nil
+	}
+	for i, n := range names {
+		if n == name {
+			return ops[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no operation named %q", name)
+}
+
+type gqlParser struct {
+	src  string
+	pos  int
+	line int
+}
+
+func (p *gqlParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+// skip passes whitespace, commas (insignificant in GraphQL) and comments.
+func (p *gqlParser) skip() {
+	for p.pos < len(p.src) {
+		switch c := p.src[p.pos]; {
+		case c == '\n':
+			p.line++
+			p.pos++
+		case c == ' ' || c == '\t' || c == '\r' || c == ',':
+			p.pos++
+		case c == '#':
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		case strings.HasPrefix(p.src[p.pos:], "\uFEFF"):
+			p.pos += len("\uFEFF")
+		default:
+			return
+		}
+	}
+}
+
+func (p *gqlParser) peek() byte {
+	p.skip()
+	if p.pos < len(p.src) {
+		return p.src[p.pos]
+	}
+	return 0
+}
+
+func (p *gqlParser) expect(c byte) error {
+	if p.peek() != c {
+		if p.pos >= len(p.src) {
+			return p.errorf("expected %q, found end of document", c)
+		}
+		return p.errorf("expected %q, found %q", c, p.src[p.pos])
+	}
+	p.pos++
+	return nil
+}
+
+func (p *gqlParser) name() (string, error) {
+	p.skip()
+	start := p.pos
+	for p.pos < len(p.src) {
+		c := rune(p.src[p.pos])
+		if c == '_' || c < unicode.MaxASCII && (unicode.IsLetter(c) || p.pos > start && unicode.IsDigit(c)) {
+			p.pos++
+			continue
+		}
+		break
+	}
+	if p.pos == start {
+		if p.pos >= len(p.src) {
+			return "", p.errorf("expected a name, found end of document")
+		}
+		return "", p.errorf("expected a name, found %q", p.src[p.pos])
+	}
+	return p.src[start:p.pos], nil
+}
+
+// operation parses a shorthand { ... } query or "query Name($v: T = d) { ... }".
+func (p *gqlParser) operation() (string, *gqlOperation, error) {
+	op := &gqlOperation{defaults: map[string]any{}}
+	var opName string
+	if p.peek() != '{' {
+		kw, err := p.name()
+		if err != nil {
+			return "", nil, err
+		}
+		switch kw {
+		case "query":
+		case "mutation", "subscription":
+			return "", nil, p.errorf("%s operations are not supported", kw)
+		case "fragment":
+			return "", nil, p.errorf("fragments are not supported")
+		default:
+			return "", nil, p.errorf("unexpected %q", kw)
+		}
+		if c := p.peek(); c != '{' && c != '(' {
+			if opName, err = p.name(); err != nil {
+				return "", nil, err
+			}
+		}
+		if p.peek() == '(' {
+			if err := p.variableDefinitions(op); err != nil {
+				return "", nil, err
+			}
+		}
+	}
+	if p.peek() == '@' {
+		return "", nil, p.errorf("directives are not supported")
+	}
+	sel, err := p.selectionSet()
+	if err != nil {
+		return "", nil, err
+	}
+	op.sel = sel
+	return opName, op, nil
+}
+
+// variableDefinitions parses ($name: Type = default, ...), keeping the
+// defaults; types are not checked beyond their syntax.
+func (p *gqlParser) variableDefinitions(op *gqlOperation) error {
+	p.pos++ // (
+	for p.peek() != ')' {
+		if err := p.expect('$'); err != nil {
+			return err
+		}
+		name, err := p.name()
+		if err != nil {
+			return err
+		}
+		if err := p.expect(':'); err != nil {
+			return err
+		}
+		if err := p.typeRef(); err != nil {
+			return err
+		}
+		if p.peek() == '=' {
+			p.pos++
+			v, err := p.value()
+			if err != nil {
+				return err
+			}
+			op.defaults[name] = v
+		}
+	}
+	p.pos++ // )
+	return nil
+}
+
+func (p *gqlParser) typeRef() error {
+	if p.peek() == '[' {
+		p.pos++
+		if err := p.typeRef(); err != nil {
+			return err
+		}
+		if err := p.expect(']'); err != nil {
+			return err
+		}
+	} else if _, err := p.name(); err != nil {
+		return err
+	}
+	if p.peek() == '!' {
+		p.pos++
+	}
+	return nil
+}
+
+func (p *gqlParser) selectionSet() ([]*gqlField, error) {
+	if err := p.expect('{'); err != nil {
+		return nil, err
+	}
+	var sel []*gqlField
+	for p.peek() != '}' {
+		if p.pos >= len(p.src) {
+			return nil, p.errorf("unterminated selection set")
+		}
+		if strings.HasPrefix(p.src[p.pos:], "...") {
+			return nil, p.errorf("fragments are not supported")
+		}
+		f := &gqlField{line: p.line}
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() == ':' {
+			p.pos++
+			f.alias = name
+			if name, err = p.name(); err != nil {
+				return nil, err
+			}
+		}
+		f.name = name
+		if p.peek() == '(' {
+			if f.args, err = p.arguments(); err != nil {
+				return nil, err
+			}
+		}
+		if p.peek() == '@' {
+			return nil, p.errorf("directives are not supported")
+		}
+		if p.peek() == '{' {
+			if f.sel, err = p.selectionSet(); err != nil {
+				return nil, err
+			}
+		}
+		sel = append(sel, f)
+	}
+	p.pos++ // }
+	if len(sel) == 0 {
+		return nil, p.errorf("empty selection set")
+	}
+	return sel, nil
+}
+
+func (p *gqlParser) arguments() (map[string]any, error) {
+	p.pos++ // (
+	args := map[string]any{}
+	for p.peek() != ')' {
+		if p.pos >= len(p.src) {
+			return nil, p.errorf("unterminated arguments")
+		}
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(':'); err != nil {
+			return nil, err
+		}
+		if args[name], err = p.value(); err != nil {
+			return nil, err
+		}
+	}
+	p.pos++ // )
+	return args, nil
+}
+
+// value parses an argument value. Numbers become float64, like JSON
+// variables, and enum values their name.
+func (p *gqlParser) value() (any, error) {
+	switch c := p.peek(); {
+	case c == '$':
+		p.pos++
+		name, err := p.name()
+		return gqlVar(name), err
+	case c == '"':
+		return p.stringValue()
+	case c == '[':
+		p.pos++
+		list := []any{}
+		for p.peek() != ']' {
+			if p.pos >= len(p.src) {
+				return nil, p.errorf("unterminated list")
+			}
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		p.pos++
+		return list, nil
+	case c == '-' || c >= '0' && c <= '9':
+		start := p.pos
+		p.pos++
+		for p.pos < len(p.src) && strings.IndexByte("0123456789.eE+-", p.src[p.pos]) >= 0 {
+			p.pos++
+		}
+		n, err := strconv.ParseFloat(p.src[start:p.pos], 64)
+		if err != nil {
+			return nil, p.errorf("invalid number %q", p.src[start:p.pos])
+		}
+		return n, nil
+	case c == '{':
+		return nil, p.errorf("input objects are not supported")
+	}
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	switch name {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	return name, nil
+}
+
+// stringValue parses a "quoted" string with JSON-style escapes.
+func (p *gqlParser) stringValue() (string, error) {
+	if strings.HasPrefix(p.src[p.pos:], `"""`) {
+		end := strings.Index(p.src[p.pos+3:], `"""`)
+		if end < 0 {
+			return "", p.errorf("unterminated block string")
+		}
+		s := p.src[p.pos+3 : p.pos+3+end]
+		p.line += strings.Count(s, "\n")
+		p.pos += 3 + end + 3
+		return strings.TrimSpace(s), nil
+	}
+	start := p.pos
+	p.pos++
+	for p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case '\\':
+			p.pos += 2
+			continue
+		case '\n':
+			return "", p.errorf("unterminated string")
+		case '"':
+			p.pos++
+			var s string
+			if err := json.Unmarshal([]byte(p.src[start:p.pos]), &s); err != nil {
+				return "", p.errorf("invalid string %s", p.src[start:p.pos])
+			}
+			return s, nil
+		}
+		p.pos++
+	}
+	return "", p.errorf("unterminated string")
+}
@@ -0,0 +1,45 @@
+package server
+
+import "net/http"
+
+// Health routes for load balancers and orchestrators. They need no API
+// key, since probes carry none.
+const (
+	healthPath = "/healthz" // live: the process is serving HTTP
+	readyPath  = "/readyz"  // ready: releases are loaded and not draining
+)
+
+// HealthResponse is the body of GET /healthz and GET /readyz.
+type HealthResponse struct {
+	Status   string `json:"status"`
+	Releases int    `json:"releases"`
+}
+
+// SetDraining marks the server as shutting down: /readyz then fails so
+// load balancers stop sending new requests, while in-flight and queued
+// requests are still answered.
+func (s *Server) SetDraining(draining bool) {
+	s.draining.Store(draining)
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, req *http.Request) {
+	writeJSON(w, http.StatusOK, HealthResponse{Status: "ok", Releases: s.releaseCount()})
+}
+
+func (s *Server) handleReady(w http.ResponseWriter, req *http.Request) {
+	n := s.releaseCount()
+	switch {
+	case s.draining.Load():
+		writeJSON(w, http.StatusServiceUnavailable, HealthResponse{Status: "draining", Releases: n})
+	case n == 0:
+		writeJSON(w, http.StatusServiceUnavailable, HealthResponse{Status: "no releases loaded", Releases: n})
+	default:
+		writeJSON(w, http.StatusOK, HealthResponse{Status: "ready", Releases: n})
+	}
+}
+
+func (s *Server) releaseCount() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.releases)
+}
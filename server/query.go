@@ -0,0 +1,174 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/nodeadmin/chebi-parser/ontology"
+	"github.com/nodeadmin/chebi-parser/reasoner"
+)
+
+// QueryMatch is one class or individual answering a class expression.
+type QueryMatch struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// QueryResponse is the body of GET /query and one result of POST /query.
+// Error is set instead of Matches when a batch expression fails.
+type QueryResponse struct {
+	Expr    string       `json:"expr"`
+	Matches []QueryMatch `json:"matches"`
+	Error   string       `json:"error,omitempty"`
+}
+
+// QueryBatchRequest is the body of POST /query.
+type QueryBatchRequest struct {
+	Exprs     []string `json:"exprs"`
+	Instances bool     `json:"instances,omitempty"`
+}
+
+// QueryBatchResponse is the response to POST /query, one result per
+// expression in request order.
+type QueryBatchResponse struct {
+	Results []QueryResponse `json:"results"`
+}
+
+// Reasoner returns the release's classified ontology, reading it from
+// Storage and normalizing and saturating it on first use.
+func (r *Release) Reasoner() (*reasoner.Reasoner, error) {
+	r.reasonerOnce.Do(func() {
+		var ont *ontology.Ontology
+		if ont, r.reasonerErr = r.Storage.Ontology(); r.reasonerErr == nil {
+			r.reasoner = reasoner.New(ont, reasoner.NormalizeOptions{}, 0)
+		}
+	})
+	return r.reasoner, r.reasonerErr
+}
+
+// Intervals returns the interval labels of the release's classification,
+// classifying it on first use. Unlike Reasoner, it does not keep the
+// saturated contexts.
+func (r *Release) Intervals() (*reasoner.IntervalLabels, error) {
+	r.intervalsOnce.Do(func() {
+		var ont *ontology.Ontology
+		if ont, r.intervalsErr = r.Storage.Ontology(); r.intervalsErr == nil {
+			r.intervals = reasoner.ClassifyIntervals(ont, reasoner.NormalizeOptions{}, 0)
+		}
+	})
+	return r.intervals, r.intervalsErr
+}
+
+// query answers one class expression with the subclasses, or the
+// individuals, it subsumes.
+func (r *Release) query(expr string, instances bool) ([]QueryMatch, error) {
+	rs, err := r.Reasoner()
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	if instances {
+		ids, err = rs.Instances(expr)
+	} else {
+		ids, err = rs.Subclasses(expr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names map[string]string
+	if instances {
+		ont, err := r.Storage.Ontology()
+		if err != nil {
+			return nil, err
+		}
+		names = make(map[string]string, len(ont.Individuals))
+		for i := range ont.Individuals {
+			names[ont.Individuals[i].ID] = ont.Individuals[i].Name
+		}
+	}
+	matches := make([]QueryMatch, len(ids))
+	for i, id := range ids {
+		matches[i] = QueryMatch{ID: id, Name: names[id]}
+		t, err := r.Storage.GetTerm(id)
+		if err != nil {
+			return nil, err
+		}
+		if t != nil {
+			matches[i].Name = t.Name
+		}
+	}
+	return matches, nil
+}
+
+func (s *Server) handleQuery(w http.ResponseWriter, req *http.Request, r *Release) {
+	expr := req.URL.Query().Get("expr")
+	if expr == "" {
+		writeError(w, http.StatusBadRequest, "missing expr parameter")
+		return
+	}
+	instances := false
+	if v := req.URL.Query().Get("instances"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "instances must be true or false")
+			return
+		}
+		instances = b
+	}
+	matches, err := r.query(expr, instances)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, QueryResponse{Expr: expr, Matches: matches})
+}
+
+func (s *Server) handleQueryBatch(w http.ResponseWriter, req *http.Request, r *Release) {
+	var body QueryBatchRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, req.Body, maxBatchBody))
+	if err := dec.Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	results := make([]QueryResponse, len(body.Exprs))
+	for i, expr := range body.Exprs {
+		results[i].Expr = expr
+		matches, err := r.query(expr, body.Instances)
+		if err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		results[i].Matches = matches
+	}
+	writeJSON(w, http.StatusOK, QueryBatchResponse{Results: results})
+}
+
+// SubsumesResponse is the body of GET /subsumes.
+type SubsumesResponse struct {
+	Sub      string `json:"sub"`
+	Super    string `json:"super"`
+	Subsumed bool   `json:"subsumed"`
+}
+
+func (s *Server) handleSubsumes(w http.ResponseWriter, req *http.Request, r *Release) {
+	q := req.URL.Query()
+	if q.Get("sub") == "" || q.Get("super") == "" {
+		writeError(w, http.StatusBadRequest, "sub and super are required")
+		return
+	}
+	a, ok := lookupTerm(w, r, q.Get("sub"))
+	if !ok {
+		return
+	}
+	b, ok := lookupTerm(w, r, q.Get("super"))
+	if !ok {
+		return
+	}
+	labels, err := r.Intervals()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, SubsumesResponse{Sub: a.ID, Super: b.ID, Subsumed: labels.IsDescendantOf(a.ID, b.ID)})
+}
@@ -0,0 +1,262 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nodeadmin/chebi-parser/ontology"
+)
+
+// ReloadOptions configure POST /admin/reload.
+type ReloadOptions struct {
+	// Load fetches and parses a release from a source (a file or URL) as
+	// the given version, deriving the version if it is empty.
+	Load func(source, version string) (*Release, error)
+	// Keep is how many releases besides the default stay hosted after a
+	// reload, newest first; older ones are dropped. 0 keeps all.
+	Keep int
+	// MaxLintFindings rejects a release on which ontology.Lint reports
+	// more findings; negative means no limit.
+	MaxLintFindings int
+	// Webhooks are URLs sent a ReleaseEvent when a reload changes the
+	// default release's data-version.
+	Webhooks []string
+	// WebhookSecret, if set, signs each webhook body with HMAC-SHA256 in
+	// the X-Signature-256 header.
+	WebhookSecret string
+	// Logf reports watch and webhook failures, which have no request to
+	// answer; nil discards them.
+	Logf func(format string, args ...any)
+}
+
+func (o *ReloadOptions) logf(format string, args ...any) {
+	if o.Logf != nil {
+		o.Logf(format, args...)
+	}
+}
+
+// ReloadRequest is the body of POST /admin/reload.
+type ReloadRequest struct {
+	Source  string `json:"source"`
+	Version string `json:"version,omitempty"`
+	// NoDefault keeps the current default release; the new release is
+	// then only served under /v/{version}/.
+	NoDefault bool `json:"no_default,omitempty"`
+}
+
+// ReloadStatus is one reload, as returned by the reload routes.
+type ReloadStatus struct {
+	ID           int      `json:"id"`
+	Source       string   `json:"source"`
+	Version      string   `json:"version,omitempty"`
+	State        string   `json:"state"` // running, done or failed
+	Error        string   `json:"error,omitempty"`
+	LintFindings int      `json:"lint_findings"`
+	Dropped      []string `json:"dropped,omitempty"` // versions no longer hosted
+	StartedAt    string   `json:"started_at"`
+	FinishedAt   string   `json:"finished_at,omitempty"`
+}
+
+// maxReloadHistory caps the reloads GET /admin/reloads lists.
+const maxReloadHistory = 50
+
+// reloader runs one reload at a time and remembers recent ones.
+type reloader struct {
+	opts    ReloadOptions
+	mu      sync.Mutex
+	running bool
+	nextID  int
+	history []*ReloadStatus // oldest first
+}
+
+// EnableReload turns on the admin routes POST /admin/reload, which loads,
+// lints and swaps in a release in the background, GET /admin/reloads and
+// POST /admin/default, which rolls back to a release still hosted.
+func (s *Server) EnableReload(opts ReloadOptions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reload = &reloader{opts: opts}
+}
+
+func (s *Server) handleReload(w http.ResponseWriter, req *http.Request) {
+	rl := s.reloadState()
+	if rl == nil {
+		writeError(w, http.StatusNotFound, "reload is not enabled")
+		return
+	}
+	var body ReloadRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, req.Body, 1<<20))
+	if err := dec.Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	if body.Source == "" {
+		writeError(w, http.StatusBadRequest, "source is required")
+		return
+	}
+
+	st, snapshot, ok := rl.start(body)
+	if !ok {
+		writeError(w, http.StatusConflict, "a reload is already running")
+		return
+	}
+	go s.runReload(rl, st, body)
+	writeJSON(w, http.StatusAccepted, snapshot)
+}
+
+// start records a new running reload, or returns false if one is already
+// running. st is guarded by rl.mu; snapshot is a copy of it.
+func (rl *reloader) start(body ReloadRequest) (st *ReloadStatus, snapshot ReloadStatus, ok bool) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if rl.running {
+		return nil, snapshot, false
+	}
+	rl.running = true
+	rl.nextID++
+	st = &ReloadStatus{ID: rl.nextID, Source: body.Source, Version: body.Version, State: "running",
+		StartedAt: time.Now().UTC().Format(time.RFC3339)}
+	rl.history = append(rl.history, st)
+	if len(rl.history) > maxReloadHistory {
+		rl.history = rl.history[len(rl.history)-maxReloadHistory:]
+	}
+	return st, *st, true
+}
+
+// runReload loads the release and swaps it in. A panic while loading is
+// recorded as a failed reload, so it cannot leave the reloader running.
+func (s *Server) runReload(rl *reloader, st *ReloadStatus, body ReloadRequest) {
+	defer func() {
+		if p := recover(); p != nil {
+			rl.finish(st, nil, 0, nil, fmt.Errorf("reload panicked: %v", p))
+		}
+	}()
+	r, err := rl.opts.Load(body.Source, body.Version)
+	if err != nil {
+		rl.finish(st, nil, 0, nil, err)
+		return
+	}
+	s.swapIn(rl, st, body, r)
+}
+
+// finish records the outcome of a reload.
+func (rl *reloader) finish(st *ReloadStatus, r *Release, findings int, dropped []string, err error) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.running = false
+	st.LintFindings = findings
+	st.Dropped = dropped
+	st.FinishedAt = time.Now().UTC().Format(time.RFC3339)
+	if err != nil {
+		st.State, st.Error = "failed", err.Error()
+		return
+	}
+	st.State, st.Version = "done", r.Version
+}
+
+// swapIn lints a loaded release, then swaps it in: adding it and making
+// it the default happen under one lock, so every request sees either the
+// old or the new release. If the default's data-version changed, the
+// webhooks are notified.
+func (s *Server) swapIn(rl *reloader, st *ReloadStatus, body ReloadRequest, r *Release) {
+	rep, err := ontology.Lint(r.Ontology, ontology.LintOptions{})
+	if err != nil {
+		rl.finish(st, nil, 0, nil, err)
+		return
+	}
+	findings := len(rep.Findings)
+	if len(r.Ontology.Terms) == 0 {
+		rl.finish(st, nil, findings, nil, fmt.Errorf("release %q has no terms", r.Version))
+		return
+	}
+	if limit := rl.opts.MaxLintFindings; limit >= 0 && findings > limit {
+		rl.finish(st, nil, findings, nil, fmt.Errorf("release %q has %d lint findings, more than the %d allowed", r.Version, findings, limit))
+		return
+	}
+
+	s.mu.Lock()
+	prev := s.releases[s.defaultVersion]
+	s.releases[r.Version] = r
+	if !body.NoDefault || s.defaultVersion == "" {
+		s.defaultVersion = r.Version
+	}
+	cur := s.releases[s.defaultVersion]
+	dropped := s.dropOldReleases(rl.opts.Keep)
+	s.mu.Unlock()
+	rl.finish(st, r, findings, dropped, nil)
+
+	if len(rl.opts.Webhooks) > 0 && cur == r && (prev == nil || prev.Ontology.DataVersion != r.Ontology.DataVersion) {
+		s.notify(rl.opts, releaseEvent(prev, r, findings))
+	}
+}
+
+// dropOldReleases keeps the default release and the keep most recently
+// loaded others, returning the versions it dropped. s.mu must be held.
+func (s *Server) dropOldReleases(keep int) []string {
+	if keep <= 0 {
+		return nil
+	}
+	var others []*Release
+	for v, r := range s.releases {
+		if v != s.defaultVersion {
+			others = append(others, r)
+		}
+	}
+	sort.Slice(others, func(i, j int) bool { return others[i].LoadedAt.After(others[j].LoadedAt) })
+	var dropped []string
+	for _, r := range others[min(keep, len(others)):] {
+		delete(s.releases, r.Version)
+		dropped = append(dropped, r.Version)
+	}
+	sort.Strings(dropped)
+	return dropped
+}
+
+func (s *Server) handleReloads(w http.ResponseWriter, req *http.Request) {
+	rl := s.reloadState()
+	if rl == nil {
+		writeError(w, http.StatusNotFound, "reload is not enabled")
+		return
+	}
+	rl.mu.Lock()
+	list := make([]ReloadStatus, len(rl.history))
+	for i, st := range rl.history {
+		list[len(list)-1-i] = *st
+	}
+	rl.mu.Unlock()
+	writeJSON(w, http.StatusOK, list)
+}
+
+// DefaultRequest is the body of POST /admin/default.
+type DefaultRequest struct {
+	Version string `json:"version"`
+}
+
+func (s *Server) handleSetDefault(w http.ResponseWriter, req *http.Request) {
+	if s.reloadState() == nil {
+		writeError(w, http.StatusNotFound, "reload is not enabled")
+		return
+	}
+	var body DefaultRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, req.Body, 1<<20))
+	if err := dec.Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	if !s.SetDefault(body.Version) {
+		writeError(w, http.StatusNotFound, "unknown version")
+		return
+	}
+	r, _ := s.release(req)
+	writeMetadata(w, r, true)
+}
+
+func (s *Server) reloadState() *reloader {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.reload
+}
@@ -0,0 +1,646 @@
+// Package server implements the HTTP API used by `chebi-parser serve`.
+//
+// Several ontology releases can be hosted at once. Every route is available
+// both unprefixed, answering from the default release, and under
+// /v/{version}/, answering from a specific release:
+//
+//	GET /healthz                       liveness
+//	GET /readyz                        readiness: 503 before any release is
+//	                                   loaded and while draining
+//	GET /versions                      hosted releases
+//	POST /admin/reload                 load, lint and swap in a release in
+//	                                   the background: {"source": "file or
+//	                                   URL", "version": V} (EnableReload)
+//	GET /admin/reloads                 recent reloads, newest first
+//	POST /admin/default                {"version": V}: switch the default
+//	                                   release, e.g. to roll back
+//	GET /ontology                      release metadata
+//	GET /terms/{id}[?typed=true]       term JSON; typed converts numeric
+//	                                   and boolean property values to JSON
+//	                                   numbers and booleans (Term.Property);
+//	                                   display_name is its Index.Label when
+//	                                   the release has IndexOptions.Labels;
+//	                                   links are its entry, image and xref
+//	                                   URLs (SetLinkTemplates)
+//	GET /terms/{id}/parents            asserted is_a parents
+//	GET /terms/{id}/children           asserted is_a children
+//	GET /terms/{id}/ancestors          transitive is_a ancestors
+//	GET /terms/{id}/references         terms mentioning it (Index.ReferencedBy)
+//	GET /terms/{id}/edges[?direction=up|down]
+//	                                   its relationships as asserted (up,
+//	                                   the default) or as seen from their
+//	                                   targets (down), with explicit
+//	                                   direction (Index.DirectedEdges)
+//	GET /path?from=A&to=B[&relations=is_a,has_role]
+//	                                   shortest relationship path (Index.Path)
+//	GET /resolve?q=NAME[&limit=N]      ranked candidate terms for a name
+//	POST /resolve                      batch: {"names": [...], "limit": N}
+//	GET /query?expr=EXPR[&instances=true]
+//	                                   subclasses (or individuals) of a
+//	                                   class expression (reasoner.Reasoner)
+//	POST /query                        batch: {"exprs": [...], "instances": B}
+//	GET /subsumes?sub=A&super=B        whether A is an inferred subclass of B
+//	                                   (reasoner.IntervalLabels)
+//	POST /graphql                      {"query": Q, "variables": V}: terms
+//	                                   with nested parents, children,
+//	                                   ancestors, synonyms and chemistry in
+//	                                   one request (GET takes ?query=)
+//
+// Both resolve routes score with the server's weights (SetResolveWeights)
+// and accept obsolete=false (a query parameter, or a body field for POST)
+// to leave obsolete terms out. The query routes classify a release on its
+// first query. /subsumes classifies a release on its first request too, but
+// keeps only interval labels, two integers per class in the common case,
+// not the saturated contexts. Client is a Go client for these routes.
+//
+// With API keys configured (SetAPIKeys) every route requires a key, sent
+// as a bearer token or in X-API-Key, and is rate limited per key; read-only
+// keys are refused on admin routes with 403.
+// {id} may also be an alt ID, a label or a synonym (see Index.Lookup); a
+// name matching several terms is answered with 409 and the candidates.
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nodeadmin/chebi-parser/ontology"
+	"github.com/nodeadmin/chebi-parser/reasoner"
+)
+
+// Release is one loaded ontology version. Routes read it through
+// Storage; Ontology and Index are set only for releases held in memory,
+// and are what reloads, watches and webhooks compare.
+type Release struct {
+	Version  string
+	Ontology *ontology.Ontology
+	Index    *ontology.Index
+	// Storage answers every route; NewRelease sets a MemoryStorage over
+	// Index.
+	Storage Storage
+
+	Source       string        // file the release was loaded from
+	SHA256       string        // hex checksum of the source file
+	LoadedAt     time.Time     // when loading finished
+	LoadDuration time.Duration // time spent parsing and indexing
+
+	infoOnce sync.Once
+	info     StorageInfo
+	infoErr  error
+
+	reasonerOnce sync.Once
+	reasoner     *reasoner.Reasoner
+	reasonerErr  error
+
+	intervalsOnce sync.Once
+	intervals     *reasoner.IntervalLabels
+	intervalsErr  error
+}
+
+// NewRelease wraps a parsed ontology, building its index.
+func NewRelease(version string, ont *ontology.Ontology) *Release {
+	return NewReleaseWithOptions(version, ont, ontology.IndexOptions{})
+}
+
+// NewReleaseWithOptions is NewRelease with a choice of index
+// representation, such as the columnar one for large deployments.
+func NewReleaseWithOptions(version string, ont *ontology.Ontology, opts ontology.IndexOptions) *Release {
+	ix := ontology.NewIndexWithOptions(ont, opts)
+	return &Release{
+		Version:  version,
+		Ontology: ont,
+		Index:    ix,
+		Storage:  MemoryStorage{Index: ix},
+		LoadedAt: time.Now(),
+	}
+}
+
+// Metadata is the body of GET /ontology.
+type Metadata struct {
+	Version       string                     `json:"version"`
+	Ontology      string                     `json:"ontology,omitempty"`
+	DataVersion   string                     `json:"data_version,omitempty"`
+	FormatVersion string                     `json:"format_version,omitempty"`
+	Metadata      *ontology.OntologyMetadata `json:"metadata,omitempty"`
+	Terms         int                        `json:"terms"`
+	ObsoleteTerms int                        `json:"obsolete_terms"`
+	TypeDefs      int                        `json:"typedefs"`
+	Source        string                     `json:"source,omitempty"`
+	SHA256        string                     `json:"sha256,omitempty"`
+	LoadedAt      string                     `json:"loaded_at"`
+	LoadTimeMs    int64                      `json:"load_time_ms"`
+	Default       bool                       `json:"default"`
+}
+
+// storageInfo returns r.Storage.Info, read once: releases don't change.
+func (r *Release) storageInfo() (StorageInfo, error) {
+	r.infoOnce.Do(func() {
+		r.info, r.infoErr = r.Storage.Info()
+	})
+	return r.info, r.infoErr
+}
+
+func (r *Release) metadata(isDefault bool) (Metadata, error) {
+	info, err := r.storageInfo()
+	if err != nil {
+		return Metadata{}, err
+	}
+	return Metadata{
+		Version:       r.Version,
+		Ontology:      info.Ontology,
+		DataVersion:   info.DataVersion,
+		FormatVersion: info.FormatVersion,
+		Metadata:      info.Metadata,
+		Terms:         info.Terms,
+		ObsoleteTerms: info.ObsoleteTerms,
+		TypeDefs:      info.TypeDefs,
+		Source:        r.Source,
+		SHA256:        r.SHA256,
+		LoadedAt:      r.LoadedAt.UTC().Format(time.RFC3339),
+		LoadTimeMs:    r.LoadDuration.Milliseconds(),
+		Default:       isDefault,
+	}, nil
+}
+
+// writeMetadata answers with r's metadata, or the error reading it.
+func writeMetadata(w http.ResponseWriter, r *Release, isDefault bool) {
+	m, err := r.metadata(isDefault)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, m)
+}
+
+// Server hosts one or more releases.
+type Server struct {
+	mu             sync.RWMutex
+	releases       map[string]*Release
+	defaultVersion string
+	weights        *ontology.ResolveWeights
+	links          ontology.LinkTemplates
+	keys           map[[sha256.Size]byte]*apiKey // by key hash; nil if open
+	draining       atomic.Bool
+	reload         *reloader // nil unless EnableReload was called
+}
+
+// New returns an empty server.
+func New() *Server {
+	return &Server{releases: make(map[string]*Release)}
+}
+
+// Add hosts a release, replacing any release with the same version. The
+// first release added becomes the default.
+func (s *Server) Add(r *Release) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.releases[r.Version] = r
+	if s.defaultVersion == "" {
+		s.defaultVersion = r.Version
+	}
+}
+
+// SetDefault selects the release answered by unprefixed routes.
+func (s *Server) SetDefault(version string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.releases[version]; !ok {
+		return false
+	}
+	s.defaultVersion = version
+	return true
+}
+
+// SetResolveWeights sets the weights used by the resolve routes; nil
+// restores the defaults.
+func (s *Server) SetResolveWeights(w *ontology.ResolveWeights) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.weights = w
+}
+
+// SetLinkTemplates sets the templates of the links added to term
+// responses; nil leaves links out.
+func (s *Server) SetLinkTemplates(lt ontology.LinkTemplates) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.links = lt
+}
+
+// withLinks returns t with its links under the server's templates, as a
+// copy so the stored term is not changed.
+func (s *Server) withLinks(t *ontology.Term) *ontology.Term {
+	s.mu.RLock()
+	lt := s.links
+	s.mu.RUnlock()
+	if lt == nil {
+		return t
+	}
+	linked := *t
+	linked.Links = lt.Resolve(t)
+	return &linked
+}
+
+// resolveOptions returns the options for a resolve request, leaving out
+// obsolete terms if the request asked to.
+func (s *Server) resolveOptions(limit int, obsolete *bool) ontology.ResolveOptions {
+	s.mu.RLock()
+	w := s.weights
+	s.mu.RUnlock()
+	if obsolete != nil && !*obsolete {
+		if w == nil {
+			w = ontology.DefaultResolveWeights()
+		}
+		cp := *w
+		cp.Obsolete = 0
+		w = &cp
+	}
+	return ontology.ResolveOptions{Limit: limit, Weights: w}
+}
+
+// release returns the release named by the request's {version} path value,
+// or the default release for unprefixed routes.
+func (s *Server) release(req *http.Request) (*Release, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	version := req.PathValue("version")
+	if version == "" {
+		version = s.defaultVersion
+	}
+	r, ok := s.releases[version]
+	return r, ok
+}
+
+// Handler returns the HTTP handler serving all routes.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+healthPath, s.handleHealth)
+	mux.HandleFunc("GET "+readyPath, s.handleReady)
+	mux.HandleFunc("GET /versions", s.handleVersions)
+	mux.HandleFunc("POST /admin/reload", s.admin(s.handleReload))
+	mux.HandleFunc("GET /admin/reloads", s.admin(s.handleReloads))
+	mux.HandleFunc("POST /admin/default", s.admin(s.handleSetDefault))
+	for _, prefix := range []string{"", "/v/{version}"} {
+		mux.HandleFunc("GET "+prefix+"/ontology", s.withRelease(s.handleOntology))
+		mux.HandleFunc("GET "+prefix+"/terms/{id}", s.withRelease(s.handleTerm))
+		mux.HandleFunc("GET "+prefix+"/terms/{id}/parents", s.withRelease(s.handleParents))
+		mux.HandleFunc("GET "+prefix+"/terms/{id}/children", s.withRelease(s.handleChildren))
+		mux.HandleFunc("GET "+prefix+"/terms/{id}/ancestors", s.withRelease(s.handleAncestors))
+		mux.HandleFunc("GET "+prefix+"/terms/{id}/references", s.withRelease(s.handleReferences))
+		mux.HandleFunc("GET "+prefix+"/terms/{id}/edges", s.withRelease(s.handleEdges))
+		mux.HandleFunc("GET "+prefix+"/path", s.withRelease(s.handlePath))
+		mux.HandleFunc("GET "+prefix+"/resolve", s.withRelease(s.handleResolve))
+		mux.HandleFunc("POST "+prefix+"/resolve", s.withRelease(s.handleResolveBatch))
+		mux.HandleFunc("GET "+prefix+"/query", s.withRelease(s.handleQuery))
+		mux.HandleFunc("POST "+prefix+"/query", s.withRelease(s.handleQueryBatch))
+		mux.HandleFunc("GET "+prefix+"/subsumes", s.withRelease(s.handleSubsumes))
+		mux.HandleFunc("GET "+prefix+"/graphql", s.withRelease(s.handleGraphQL))
+		mux.HandleFunc("POST "+prefix+"/graphql", s.withRelease(s.handleGraphQL))
+	}
+	return s.authenticate(mux)
+}
+
+type releaseHandler func(w http.ResponseWriter, req *http.Request, r *Release)
+
+func (s *Server) withRelease(h releaseHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		r, ok := s.release(req)
+		if !ok {
+			writeError(w, http.StatusNotFound, "unknown version")
+			return
+		}
+		h(w, req, r)
+	}
+}
+
+func (s *Server) handleVersions(w http.ResponseWriter, req *http.Request) {
+	s.mu.RLock()
+	list := make([]Metadata, 0, len(s.releases))
+	for v, r := range s.releases {
+		m, err := r.metadata(v == s.defaultVersion)
+		if err != nil {
+			s.mu.RUnlock()
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		list = append(list, m)
+	}
+	s.mu.RUnlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (s *Server) handleOntology(w http.ResponseWriter, req *http.Request, r *Release) {
+	s.mu.RLock()
+	isDefault := r.Version == s.defaultVersion
+	s.mu.RUnlock()
+	writeMetadata(w, r, isDefault)
+}
+
+// term resolves the {id} path value, following alt IDs and names.
+func term(w http.ResponseWriter, req *http.Request, r *Release) (*ontology.Term, bool) {
+	return lookupTerm(w, r, req.PathValue("id"))
+}
+
+// lookupTerm resolves ref, writing a 404 or 409 response if it fails.
+func lookupTerm(w http.ResponseWriter, r *Release, ref string) (*ontology.Term, bool) {
+	id, err := r.Storage.Lookup(ref)
+	if err != nil {
+		if re, ok := err.(*ontology.RefError); ok {
+			if len(re.Candidates) > 0 {
+				writeError(w, http.StatusConflict, err.Error())
+			} else {
+				writeError(w, http.StatusNotFound, "term not found")
+			}
+		} else {
+			writeError(w, http.StatusInternalServerError, err.Error())
+		}
+		return nil, false
+	}
+	t, err := r.Storage.GetTerm(id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+	if t == nil {
+		writeError(w, http.StatusNotFound, "term not found")
+		return nil, false
+	}
+	return t, true
+}
+
+// writeIDs answers with the IDs a storage method returned, or its error.
+func writeIDs(w http.ResponseWriter, ids []string, err error) {
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, nonNil(ids))
+}
+
+// typedTerm is a term whose property values are converted to their
+// datatype's JSON value, shadowing the string Properties.
+type typedTerm struct {
+	*ontology.Term
+	Properties  map[string]any `json:"properties,omitempty"`
+	DisplayName string         `json:"display_name,omitempty"`
+}
+
+// displayName returns id's label for the display_name field: "" unless
+// the release is served with label preferences.
+func (r *Release) displayName(id string) (string, error) {
+	info, err := r.storageInfo()
+	if err != nil || len(info.Labels) == 0 {
+		return "", err
+	}
+	return r.Storage.Label(id)
+}
+
+// labeledTerm is a term with its label under the release's
+// IndexOptions.Labels, for releases served with a label preference.
+type labeledTerm struct {
+	*ontology.Term
+	DisplayName string `json:"display_name"`
+}
+
+func (s *Server) handleTerm(w http.ResponseWriter, req *http.Request, r *Release) {
+	typed := false
+	if v := req.URL.Query().Get("typed"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "typed must be true or false")
+			return
+		}
+		typed = b
+	}
+	t, ok := term(w, req, r)
+	if !ok {
+		return
+	}
+	t = s.withLinks(t)
+	name, err := r.displayName(t.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !typed {
+		if name == "" {
+			writeJSON(w, http.StatusOK, t)
+		} else {
+			writeJSON(w, http.StatusOK, labeledTerm{Term: t, DisplayName: name})
+		}
+		return
+	}
+	// A value its datatype does not admit stays a string.
+	props, _ := t.NativeProperties()
+	writeJSON(w, http.StatusOK, typedTerm{Term: t, Properties: props, DisplayName: name})
+}
+
+func (s *Server) handleParents(w http.ResponseWriter, req *http.Request, r *Release) {
+	if t, ok := term(w, req, r); ok {
+		ids, err := r.Storage.Parents(t.ID)
+		writeIDs(w, ids, err)
+	}
+}
+
+func (s *Server) handleChildren(w http.ResponseWriter, req *http.Request, r *Release) {
+	if t, ok := term(w, req, r); ok {
+		ids, err := r.Storage.Children(t.ID)
+		writeIDs(w, ids, err)
+	}
+}
+
+func (s *Server) handleAncestors(w http.ResponseWriter, req *http.Request, r *Release) {
+	if t, ok := term(w, req, r); ok {
+		ids, err := r.Storage.Closure(t.ID)
+		writeIDs(w, ids, err)
+	}
+}
+
+// PathResponse is the body of GET /path.
+type PathResponse struct {
+	From  string              `json:"from"`
+	To    string              `json:"to"`
+	Edges []ontology.PathEdge `json:"edges"`
+	Text  string              `json:"text"`
+}
+
+func (s *Server) handlePath(w http.ResponseWriter, req *http.Request, r *Release) {
+	q := req.URL.Query()
+	if q.Get("from") == "" || q.Get("to") == "" {
+		writeError(w, http.StatusBadRequest, "from and to are required")
+		return
+	}
+	a, ok := lookupTerm(w, r, q.Get("from"))
+	if !ok {
+		return
+	}
+	b, ok := lookupTerm(w, r, q.Get("to"))
+	if !ok {
+		return
+	}
+	var rels []string
+	if v := q.Get("relations"); v != "" {
+		rels = strings.Split(v, ",")
+	}
+	edges, err := r.Storage.Path(a.ID, b.ID, rels)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if edges == nil {
+		writeError(w, http.StatusNotFound, "no path between "+a.ID+" and "+b.ID)
+		return
+	}
+	var labelErr error
+	text := ontology.FormatPathFunc(edges, func(id string) string {
+		name, err := r.Storage.Label(id)
+		if err != nil {
+			labelErr = err
+		}
+		return name
+	})
+	if labelErr != nil {
+		writeError(w, http.StatusInternalServerError, labelErr.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, PathResponse{From: a.ID, To: b.ID, Edges: edges, Text: text})
+}
+
+// ResolveResponse is the body of GET /resolve.
+type ResolveResponse struct {
+	Query   string           `json:"query"`
+	Matches []ontology.Match `json:"matches"`
+}
+
+func (s *Server) handleResolve(w http.ResponseWriter, req *http.Request, r *Release) {
+	q := req.URL.Query().Get("q")
+	if q == "" {
+		writeError(w, http.StatusBadRequest, "missing q parameter")
+		return
+	}
+	limit := 0
+	if v := req.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		limit = n
+	}
+	var obsolete *bool
+	if v := req.URL.Query().Get("obsolete"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "obsolete must be true or false")
+			return
+		}
+		obsolete = &b
+	}
+	matches, err := r.Storage.Search(q, s.resolveOptions(limit, obsolete))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if matches == nil {
+		matches = []ontology.Match{}
+	}
+	writeJSON(w, http.StatusOK, ResolveResponse{Query: q, Matches: matches})
+}
+
+// maxBatchBody caps the size of a POST /resolve request.
+const maxBatchBody = 64 << 20
+
+// ResolveBatchRequest is the body of POST /resolve.
+type ResolveBatchRequest struct {
+	Names    []string `json:"names"`
+	Limit    int      `json:"limit,omitempty"`
+	Obsolete *bool    `json:"obsolete,omitempty"`
+}
+
+// ResolveBatchResponse is the response to POST /resolve, one result per
+// name in request order.
+type ResolveBatchResponse struct {
+	Results []ontology.ResolveResult `json:"results"`
+}
+
+func (s *Server) handleResolveBatch(w http.ResponseWriter, req *http.Request, r *Release) {
+	var body ResolveBatchRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, req.Body, maxBatchBody))
+	if err := dec.Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	if body.Limit < 0 {
+		writeError(w, http.StatusBadRequest, "limit must be a positive integer")
+		return
+	}
+	results, err := r.Storage.SearchBatch(body.Names, s.resolveOptions(body.Limit, body.Obsolete))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, ResolveBatchResponse{Results: results})
+}
+
+func (s *Server) handleReferences(w http.ResponseWriter, req *http.Request, r *Release) {
+	if t, ok := term(w, req, r); ok {
+		refs, err := r.Storage.References(t.ID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if refs == nil {
+			refs = []ontology.Reference{}
+		}
+		writeJSON(w, http.StatusOK, refs)
+	}
+}
+
+func (s *Server) handleEdges(w http.ResponseWriter, req *http.Request, r *Release) {
+	d := ontology.DirectionUp
+	if v := req.URL.Query().Get("direction"); v != "" {
+		var err error
+		if d, err = ontology.ParseDirection(v); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if t, ok := term(w, req, r); ok {
+		edges, err := r.Storage.Edges(t.ID, d)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if edges == nil {
+			edges = []ontology.DirectedEdge{}
+		}
+		writeJSON(w, http.StatusOK, edges)
+	}
+}
+
+func nonNil(ids []string) []string {
+	if ids == nil {
+		return []string{}
+	}
+	return ids
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
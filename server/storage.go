@@ -0,0 +1,113 @@
+package server
+
+import "github.com/nodeadmin/chebi-parser/ontology"
+
+// Storage is where a release is read from: every route but the admin
+// ones answers through it, so a release can be served from a backend
+// other than the in-memory index. Methods take primary IDs, as returned
+// by Lookup, and return errors so that backends reading from disk can
+// fail.
+type Storage interface {
+	// Lookup resolves an ID, alt ID or name to a primary ID, returning an
+	// *ontology.RefError if it is unknown or ambiguous.
+	Lookup(ref string) (string, error)
+	// GetTerm returns the term, or nil if there is none.
+	GetTerm(id string) (*ontology.Term, error)
+	// Label returns the term's label under the release's label
+	// preferences (its name if there are none), or "" for an unknown
+	// term.
+	Label(id string) (string, error)
+	Parents(id string) ([]string, error)
+	Children(id string) ([]string, error)
+	// Closure returns all transitive is_a ancestors of id, nearest first.
+	Closure(id string) ([]string, error)
+	Search(name string, opts ontology.ResolveOptions) ([]ontology.Match, error)
+	// SearchBatch is Search for every name, returning results in input
+	// order.
+	SearchBatch(names []string, opts ontology.ResolveOptions) ([]ontology.ResolveResult, error)
+	// References returns every mention of id in other terms, as
+	// ontology.Index.ReferencedBy does.
+	References(id string) ([]ontology.Reference, error)
+	// Edges returns the relationships of id read in direction d, as
+	// ontology.Index.DirectedEdges does.
+	Edges(id string, d ontology.Direction) ([]ontology.DirectedEdge, error)
+	// Path returns a shortest chain of relationships from one term to
+	// another, as ontology.Index.Path does: nil if they are not connected.
+	Path(from, to string, relations []string) ([]ontology.PathEdge, error)
+	Info() (StorageInfo, error)
+	// Ontology returns the whole release, for the reasoner behind the
+	// query and subsumes routes. A backend on disk may read it in on the
+	// first call.
+	Ontology() (*ontology.Ontology, error)
+}
+
+// StorageInfo describes a stored release for GET /ontology.
+type StorageInfo struct {
+	Ontology      string
+	DataVersion   string
+	FormatVersion string
+	Metadata      *ontology.OntologyMetadata
+	Terms         int
+	ObsoleteTerms int
+	TypeDefs      int
+	// Labels are the label preferences the release is served with.
+	Labels ontology.LabelPrefs
+}
+
+// MemoryStorage serves a release from its in-memory index.
+type MemoryStorage struct {
+	Index *ontology.Index
+}
+
+func (m MemoryStorage) Lookup(ref string) (string, error) { return m.Index.Lookup(ref) }
+
+func (m MemoryStorage) GetTerm(id string) (*ontology.Term, error) { return m.Index.Term(id), nil }
+
+func (m MemoryStorage) Label(id string) (string, error) { return m.Index.Label(id), nil }
+
+func (m MemoryStorage) Parents(id string) ([]string, error) { return m.Index.Parents(id), nil }
+
+func (m MemoryStorage) Children(id string) ([]string, error) { return m.Index.Children(id), nil }
+
+func (m MemoryStorage) Closure(id string) ([]string, error) { return m.Index.Ancestors(id), nil }
+
+func (m MemoryStorage) Search(name string, opts ontology.ResolveOptions) ([]ontology.Match, error) {
+	return m.Index.Resolve(name, opts), nil
+}
+
+func (m MemoryStorage) SearchBatch(names []string, opts ontology.ResolveOptions) ([]ontology.ResolveResult, error) {
+	return m.Index.ResolveBatch(names, opts, 0), nil
+}
+
+func (m MemoryStorage) References(id string) ([]ontology.Reference, error) {
+	return m.Index.ReferencedBy(id), nil
+}
+
+func (m MemoryStorage) Edges(id string, d ontology.Direction) ([]ontology.DirectedEdge, error) {
+	return m.Index.DirectedEdges(id, d), nil
+}
+
+func (m MemoryStorage) Path(from, to string, relations []string) ([]ontology.PathEdge, error) {
+	return m.Index.Path(from, to, relations), nil
+}
+
+func (m MemoryStorage) Info() (StorageInfo, error) {
+	ont := m.Index.Ontology()
+	info := StorageInfo{
+		Ontology:      ont.Ontology,
+		DataVersion:   ont.DataVersion,
+		FormatVersion: ont.FormatVersion,
+		Metadata:      ont.Metadata,
+		Terms:         len(ont.Terms),
+		TypeDefs:      len(ont.TypeDefs),
+		Labels:        m.Index.Labels(),
+	}
+	for i := range ont.Terms {
+		if ont.Terms[i].IsObsolete {
+			info.ObsoleteTerms++
+		}
+	}
+	return info, nil
+}
+
+func (m MemoryStorage) Ontology() (*ontology.Ontology, error) { return m.Index.Ontology(), nil }
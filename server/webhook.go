@@ -0,0 +1,106 @@
+package server
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/nodeadmin/chebi-parser/ontology"
+)
+
+// ReleaseEvent is the JSON body posted to webhooks when the default
+// release's data-version changes.
+type ReleaseEvent struct {
+	Event               string                   `json:"event"` // "release.changed"
+	Version             string                   `json:"version"`
+	DataVersion         string                   `json:"data_version,omitempty"`
+	Source              string                   `json:"source,omitempty"`
+	SHA256              string                   `json:"sha256,omitempty"`
+	Terms               int                      `json:"terms"`
+	LintFindings        int                      `json:"lint_findings"`
+	PreviousVersion     string                   `json:"previous_version,omitempty"`
+	PreviousDataVersion string                   `json:"previous_data_version,omitempty"`
+	Changes             *ontology.ReleaseChanges `json:"changes,omitempty"` // nil without a previous release
+	Time                string                   `json:"time"`
+}
+
+func releaseEvent(prev, r *Release, findings int) ReleaseEvent {
+	e := ReleaseEvent{
+		Event:        "release.changed",
+		Version:      r.Version,
+		DataVersion:  r.Ontology.DataVersion,
+		Source:       r.Source,
+		SHA256:       r.SHA256,
+		Terms:        len(r.Ontology.Terms),
+		LintFindings: findings,
+		Time:         time.Now().UTC().Format(time.RFC3339),
+	}
+	if prev != nil {
+		changes := ontology.CompareReleases(prev.Ontology, r.Ontology)
+		e.PreviousVersion = prev.Version
+		e.PreviousDataVersion = prev.Ontology.DataVersion
+		e.Changes = &changes
+	}
+	return e
+}
+
+// webhookAttempts is how many times a webhook is tried, with doubling
+// delays from webhookRetryDelay between attempts.
+const (
+	webhookAttempts   = 4
+	webhookRetryDelay = 2 * time.Second
+)
+
+// notify posts the event to every webhook, retrying failures, and logs
+// the webhooks that could not be reached.
+func (s *Server) notify(opts ReloadOptions, e ReleaseEvent) {
+	body, err := json.Marshal(e)
+	if err != nil {
+		opts.logf("webhook: %v", err)
+		return
+	}
+	for _, u := range opts.Webhooks {
+		delay := webhookRetryDelay
+		for attempt := 1; ; attempt++ {
+			err := postWebhook(u, body, opts.WebhookSecret)
+			if err == nil {
+				break
+			}
+			if attempt == webhookAttempts {
+				opts.logf("webhook %s: giving up after %d attempts: %v", u, attempt, err)
+				break
+			}
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}
+}
+
+func postWebhook(url string, body []byte, secret string) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event", "release.changed")
+	if secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		req.Header.Set("X-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s", resp.Status)
+	}
+	return nil
+}
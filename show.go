@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/nodeadmin/chebi-parser/internal/exitcode"
+	"github.com/nodeadmin/chebi-parser/ontology"
+)
+
+// showCommand prints a card for each term named on the command line: name,
+// definition, formula, mass, parents, roles and key xrefs, or the same as
+// JSON for scripts.
+func showCommand() (*flag.FlagSet, func() error) {
+	fs := newFlagSet("show")
+	input := fs.String("input", os.Getenv("CHEBI_SNAPSHOT"), "Ontology file or snapshot (default: $CHEBI_SNAPSHOT)")
+	format := fs.String("format", "auto", "Input format: auto, obo, owl, msgpack")
+	labels := fs.String("label-prefs", "", labelPrefsUsage)
+	asJSON := fs.Bool("json", false, "Write JSON instead of text")
+	return fs, func() error {
+		if *input == "" || fs.NArg() == 0 {
+			return exitcode.Errorf(exitcode.Usage, "usage: chebi-parser show [-input <file>] [-label-prefs INN,name] [-json] TERM...")
+		}
+		prefs, err := labelPrefs(*labels)
+		if err != nil {
+			return err
+		}
+		ont, err := loadOntology(*input, *format)
+		if err != nil {
+			return err
+		}
+		ix := ontology.NewIndexWithOptions(ont, ontology.IndexOptions{Labels: prefs})
+		ids, err := ix.LookupAll(fs.Args())
+		if err != nil {
+			return err
+		}
+
+		bw := bufio.NewWriter(os.Stdout)
+		if *asJSON {
+			cards := make([]*ontology.Card, len(ids))
+			for i, id := range ids {
+				cards[i] = ix.NewCard(id)
+			}
+			enc := json.NewEncoder(bw)
+			enc.SetEscapeHTML(false)
+			enc.SetIndent("", "  ")
+			if len(cards) == 1 {
+				err = enc.Encode(cards[0])
+			} else {
+				err = enc.Encode(cards)
+			}
+			if err != nil {
+				return err
+			}
+		} else {
+			for i, id := range ids {
+				if i > 0 {
+					fmt.Fprintln(bw)
+				}
+				if err := ontology.WriteCard(ix.NewCard(id), bw); err != nil {
+					return err
+				}
+			}
+		}
+		return bw.Flush()
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/nodeadmin/chebi-parser/internal/exitcode"
+	"github.com/nodeadmin/chebi-parser/ontology"
+)
+
+// signatureCommand lists the classes, relations and individuals an ontology
+// uses, and the external prefixes it references, as TSV (kind, id) or
+// JSON. With -against it instead lists the referenced entities a target
+// release does not define, and fails if there are any.
+func signatureCommand() (*flag.FlagSet, func() error) {
+	fs := newFlagSet("signature")
+	input := fs.String("input", "", "Ontology file (.obo, .owl or .msgpack)")
+	format := fs.String("format", "auto", "Input format: auto, obo, owl, msgpack")
+	against := fs.String("against", "", "Target release the ontology's references must exist in (an extension's ChEBI release)")
+	asJSON := fs.Bool("json", false, "Write JSON instead of TSV")
+	return fs, func() error {
+		if *input == "" {
+			return exitcode.Errorf(exitcode.Usage, "usage: chebi-parser signature -input <file> [-against <release>] [-json]")
+		}
+		ont, err := loadOntology(*input, *format)
+		if err != nil {
+			return err
+		}
+		sig := ontology.Signature(ont)
+
+		var missing []string
+		if *against != "" {
+			target, err := loadOntology(*against, "auto")
+			if err != nil {
+				return err
+			}
+			missing = sig.Missing(target)
+		}
+
+		bw := bufio.NewWriter(os.Stdout)
+		switch {
+		case *asJSON:
+			enc := json.NewEncoder(bw)
+			enc.SetEscapeHTML(false)
+			enc.SetIndent("", "  ")
+			var v any = sig
+			if *against != "" {
+				if missing == nil {
+					missing = []string{}
+				}
+				v = missing
+			}
+			if err := enc.Encode(v); err != nil {
+				return err
+			}
+		case *against != "":
+			for _, id := range missing {
+				fmt.Fprintln(bw, id)
+			}
+		default:
+			fmt.Fprintln(bw, "kind\tid")
+			for _, kind := range []struct {
+				name string
+				ids  []string
+			}{{"class", sig.Classes}, {"relation", sig.Relations}, {"individual", sig.Individuals}} {
+				for _, id := range kind.ids {
+					fmt.Fprintf(bw, "%s\t%s\n", kind.name, id)
+				}
+			}
+		}
+		if err := bw.Flush(); err != nil {
+			return err
+		}
+
+		if *against != "" {
+			if len(missing) > 0 {
+				return exitcode.Errorf(exitcode.Validation, "%d referenced entities are not in %s", len(missing), *against)
+			}
+			fmt.Fprintf(os.Stderr, "All references found in %s\n", *against)
+			return nil
+		}
+		prefixes := make([]string, 0, len(sig.External))
+		for p, ids := range sig.External {
+			prefixes = append(prefixes, fmt.Sprintf("%s (%d)", p, len(ids)))
+		}
+		sort.Strings(prefixes)
+		if len(prefixes) == 0 {
+			prefixes = append(prefixes, "none")
+		}
+		fmt.Fprintf(os.Stderr, "%d classes, %d relations, %d individuals; external prefixes: %s\n",
+			len(sig.Classes), len(sig.Relations), len(sig.Individuals), strings.Join(prefixes, ", "))
+		return nil
+	}
+}
@@ -0,0 +1,8 @@
+//go:build chebislim
+
+package slim
+
+import _ "embed"
+
+//go:embed chebi_slim.msgpack.gz
+var snapshot []byte
@@ -0,0 +1,6 @@
+//go:build !chebislim
+
+package slim
+
+// snapshot is empty without the chebislim tag; see Load.
+var snapshot []byte
@@ -0,0 +1,133 @@
+// Package slim gives programs a small ChEBI snapshot compiled into the
+// binary, so they can resolve common terms offline without a data file.
+//
+// The snapshot (chebi_slim.msgpack.gz, gzipped msgpack) is embedded only
+// when building with the chebislim tag:
+//
+//	go build -tags chebislim ./...
+//
+// Without it the package adds nothing to the binary and Load returns
+// ErrNotEmbedded. The checked-in snapshot is built from testdata; build
+// one from a release with `make slim CHEBI_OBO=chebi.obo`, which runs
+// `chebi-parser embed-slim`.
+package slim
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+
+	"github.com/nodeadmin/chebi-parser/ontology"
+)
+
+// ErrNotEmbedded is returned by Load in binaries built without the
+// chebislim tag.
+var ErrNotEmbedded = errors.New("slim: no snapshot embedded (build with -tags chebislim)")
+
+// Embedded reports whether the binary carries a snapshot.
+func Embedded() bool { return len(snapshot) > 0 }
+
+var loaded struct {
+	once sync.Once
+	ix   *ontology.Index
+	err  error
+}
+
+// Load decodes the embedded snapshot on first use and returns an index
+// over it, shared by every caller: the ontology must not be modified.
+func Load() (*ontology.Index, error) {
+	loaded.once.Do(func() {
+		if !Embedded() {
+			loaded.err = ErrNotEmbedded
+			return
+		}
+		ont, err := Read(bytes.NewReader(snapshot))
+		if err != nil {
+			loaded.err = fmt.Errorf("slim: embedded snapshot: %w", err)
+			return
+		}
+		loaded.ix = ontology.NewIndex(ont)
+	})
+	return loaded.ix, loaded.err
+}
+
+// Options selects what a snapshot keeps.
+type Options struct {
+	Subsets    []string // terms in one of these subsets; all live terms if empty
+	Roots      []string // only these roots and their is_a descendants
+	Properties []string // property keys to keep, such as the ChEBI formula IRI
+}
+
+// Build returns the compact copy of ont that a snapshot holds: the live
+// terms selected by opts, each with only its ID, name, subsets, alt IDs,
+// synonyms (without xrefs), is_a edges to other kept terms and the
+// properties listed in opts. Typedefs, individuals and header metadata
+// other than the versions are left out.
+func Build(ont *ontology.Ontology, opts Options) *ontology.Ontology {
+	kept := ontology.Filter(ont, ontology.FilterOptions{Subsets: opts.Subsets, Roots: opts.Roots, DropObsolete: true})
+	in := make(map[string]bool, len(kept.Terms))
+	for i := range kept.Terms {
+		in[kept.Terms[i].ID] = true
+	}
+	out := &ontology.Ontology{
+		FormatVersion: kept.FormatVersion,
+		DataVersion:   kept.DataVersion,
+		Ontology:      kept.Ontology,
+		Terms:         make([]ontology.Term, 0, len(kept.Terms)),
+	}
+	for i := range kept.Terms {
+		t := &kept.Terms[i]
+		c := ontology.Term{ID: t.ID, Name: t.Name, Subsets: t.Subsets, AltIDs: t.AltIDs}
+		for _, syn := range t.Synonyms {
+			syn.Xrefs = nil
+			c.Synonyms = append(c.Synonyms, syn)
+		}
+		for _, rel := range t.Relationships {
+			if rel.Type == "is_a" && in[rel.TargetID] {
+				c.Relationships = append(c.Relationships, ontology.Relationship{Type: "is_a", TargetID: rel.TargetID})
+			}
+		}
+		for k, v := range t.Properties {
+			if slices.Contains(opts.Properties, k) {
+				if c.Properties == nil {
+					c.Properties = make(map[string]string)
+				}
+				c.Properties[k] = v
+				if dt, ok := t.PropertyTypes[k]; ok {
+					if c.PropertyTypes == nil {
+						c.PropertyTypes = make(map[string]string)
+					}
+					c.PropertyTypes[k] = dt
+				}
+			}
+		}
+		out.Terms = append(out.Terms, c)
+	}
+	return out
+}
+
+// Write writes ont in the snapshot encoding: gzipped msgpack.
+func Write(ont *ontology.Ontology, w io.Writer) error {
+	zw, err := gzip.NewWriterLevel(w, gzip.BestCompression)
+	if err != nil {
+		return err
+	}
+	if err := ontology.WriteMsgpack(ont, zw); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// Read reads a snapshot written by Write.
+func Read(r io.Reader) (*ontology.Ontology, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return ontology.ReadMsgpack(zr)
+}
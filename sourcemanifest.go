@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nodeadmin/chebi-parser/ontology"
+)
+
+// cdxBOM is the subset of a CycloneDX 1.5 bill of materials the source
+// manifest uses: one data component per fetched source.
+type cdxBOM struct {
+	BOMFormat   string         `json:"bomFormat"`
+	SpecVersion string         `json:"specVersion"`
+	Version     int            `json:"version"`
+	Metadata    cdxMetadata    `json:"metadata"`
+	Components  []cdxComponent `json:"components"`
+}
+
+type cdxMetadata struct {
+	Component cdxComponent `json:"component"`
+}
+
+type cdxComponent struct {
+	Type         string        `json:"type"`
+	BOMRef       string        `json:"bom-ref,omitempty"`
+	Name         string        `json:"name"`
+	Version      string        `json:"version,omitempty"`
+	Description  string        `json:"description,omitempty"`
+	Hashes       []cdxHash     `json:"hashes,omitempty"`
+	Licenses     []cdxLicense  `json:"licenses,omitempty"`
+	ExternalRefs []cdxExtRef   `json:"externalReferences,omitempty"`
+	Properties   []cdxProperty `json:"properties,omitempty"`
+}
+
+type cdxHash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+type cdxLicense struct {
+	License cdxLicenseRef `json:"license"`
+}
+
+type cdxLicenseRef struct {
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"`
+}
+
+type cdxExtRef struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+type cdxProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// fetchSources returns the fetch steps the named step's result derives
+// from, in recipe order.
+func (p *pipeline) fetchSources(name string) []*recipeStep {
+	want := map[string]bool{name: true}
+	var out []*recipeStep
+	// Inputs are always earlier steps, so one backward pass finds them all.
+	for i := len(p.recipe.Steps) - 1; i >= 0; i-- {
+		st := &p.recipe.Steps[i]
+		if !want[st.Name] {
+			continue
+		}
+		if st.Run == "fetch" {
+			out = append(out, st)
+		}
+		for _, in := range st.Inputs {
+			want[in] = true
+		}
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out
+}
+
+// writeSourceManifest writes a CycloneDX-style manifest of the sources a
+// merge step combined: each fetched file with its SHA-256, the ontology
+// and version IRIs and the licenses its header declares.
+func (p *pipeline) writeSourceManifest(st *recipeStep, file string) error {
+	bom := cdxBOM{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: "1.5",
+		Version:     1,
+		Metadata: cdxMetadata{Component: cdxComponent{
+			Type: "data",
+			Name: st.Name,
+			Hashes: []cdxHash{
+				{Alg: "SHA-256", Content: fileHash(file)},
+			},
+		}},
+		Components: []cdxComponent{},
+	}
+	for _, src := range p.fetchSources(st.Name) {
+		res := p.results[src.Name]
+		h, err := p.sourceHeader(src, res.File)
+		if err != nil {
+			return err
+		}
+		c := cdxComponent{
+			Type:    "data",
+			BOMRef:  src.Name,
+			Name:    h.Ontology,
+			Version: h.VersionIRI,
+			Hashes:  []cdxHash{{Alg: "SHA-256", Content: res.Hash}},
+		}
+		if c.Name == "" {
+			c.Name = filepath.Base(src.Source)
+		}
+		var licenses []string
+		if h.Metadata != nil {
+			c.Description = h.Metadata.Title
+			licenses = h.Metadata.Licenses
+		}
+		for _, l := range licenses {
+			ref := cdxLicenseRef{Name: l}
+			if strings.Contains(l, "://") {
+				ref.URL = l
+			}
+			c.Licenses = append(c.Licenses, cdxLicense{License: ref})
+		}
+		url := src.Source
+		if !strings.Contains(url, "://") {
+			if abs, err := filepath.Abs(url); err == nil {
+				url = "file://" + filepath.ToSlash(abs)
+			}
+		}
+		c.ExternalRefs = []cdxExtRef{{Type: "distribution", URL: url}}
+		if len(licenses) == 0 {
+			c.Properties = append(c.Properties, cdxProperty{Name: "chebi-parser:license", Value: "none declared in header"})
+		}
+		bom.Components = append(bom.Components, c)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(st.Manifest), 0o755); err != nil {
+		return err
+	}
+	return writeFileWith(st.Manifest, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		return enc.Encode(bom)
+	})
+}
+
+// sourceHeader reads the header of a fetched file. OBO and OWL headers
+// are scanned without parsing the rest; other formats are loaded.
+func (p *pipeline) sourceHeader(src *recipeStep, file string) (*ontology.SourceHeader, error) {
+	format := src.Format
+	if format == "" || format == "auto" {
+		format = detectFormat(file, "auto")
+	}
+	if format == "obo" || format == "owl" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return ontology.ScanHeader(f)
+	}
+	ont, err := p.load(src.Name)
+	if err != nil {
+		return nil, err
+	}
+	return &ontology.SourceHeader{Ontology: ont.Ontology, VersionIRI: ont.DataVersion, Metadata: ont.Metadata}, nil
+}
@@ -0,0 +1,127 @@
+// Command chebi-sqlite exports an ontology to a SQLite file and serves
+// the chebi-parser HTTP API from such a file:
+//
+//	chebi-sqlite export -input chebi.obo -output chebi.db [-label-prefs INN,name]
+//	chebi-sqlite serve -db chebi.db [-addr :8080] [-version 239]
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/nodeadmin/chebi-parser/ontology"
+	"github.com/nodeadmin/chebi-parser/server"
+	"github.com/nodeadmin/chebi-parser/sqlite"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	var err error
+	switch os.Args[1] {
+	case "export":
+		err = runExport(os.Args[2:])
+	case "serve":
+		err = runServe(os.Args[2:])
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: chebi-sqlite export -input <file> -output <file.db> [-label-prefs INN,name]")
+	fmt.Fprintln(os.Stderr, "       chebi-sqlite serve -db <file.db> [-addr :8080] [-version name]")
+	os.Exit(2)
+}
+
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	input := fs.String("input", "", "Ontology file (.obo or .owl)")
+	output := fs.String("output", "", "SQLite file to create")
+	labels := fs.String("label-prefs", "", "Label preferences for display names, as in chebi-parser serve")
+	fs.Parse(args)
+	if *input == "" || *output == "" {
+		usage()
+	}
+	prefs, err := ontology.ParseLabelPrefs(*labels)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	ont, err := parse(*input)
+	if err != nil {
+		return fmt.Errorf("%s: %w", *input, err)
+	}
+	if err := sqlite.ExportFile(*output, ont, ontology.IndexOptions{Labels: prefs}); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d terms to %s in %v\n", len(ont.Terms), *output, time.Since(start))
+	return nil
+}
+
+func parse(path string) (*ontology.Ontology, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".obo":
+		return ontology.ParseOBO(f)
+	case ".owl", ".xml", ".rdf":
+		return ontology.ParseOWL(f)
+	}
+	return nil, fmt.Errorf("cannot detect format for %q", path)
+}
+
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	db := fs.String("db", "", "SQLite file written by chebi-sqlite export")
+	addr := fs.String("addr", ":8080", "Listen address")
+	version := fs.String("version", "", "Version name of the release (default: file name)")
+	fs.Parse(args)
+	if *db == "" {
+		usage()
+	}
+	st, err := sqlite.Open(*db)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+	if *version == "" {
+		*version = strings.TrimSuffix(filepath.Base(*db), filepath.Ext(*db))
+	}
+	srv := server.New()
+	srv.SetLinkTemplates(ontology.DefaultLinkTemplates)
+	srv.Add(&server.Release{Version: *version, Storage: st, Source: *db, LoadedAt: time.Now()})
+
+	hs := &http.Server{Addr: *addr, Handler: srv.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	errc := make(chan error, 1)
+	go func() {
+		fmt.Fprintf(os.Stderr, "Serving %s as version %q on %s\n", *db, *version, *addr)
+		errc <- hs.ListenAndServe()
+	}()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return hs.Shutdown(shutdownCtx)
+}
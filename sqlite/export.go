@@ -0,0 +1,249 @@
+// Package sqlite stores a release in a SQLite file and serves it from
+// there: Export writes the file, Open returns a server.Storage over it,
+// so a small deployment can answer every route of the server package
+// without holding the ontology in memory. It is a module of its own so
+// that the parser and server stay free of dependencies.
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/nodeadmin/chebi-parser/ontology"
+	_ "modernc.org/sqlite" // registers the "sqlite" driver
+)
+
+// schemaVersion is stored in meta; Open refuses files of another version.
+const schemaVersion = "1"
+
+// Name kinds in the name table: the keys Lookup and Search find terms by.
+const (
+	kindLabel   = "label"   // NormalizeRef of the name
+	kindExact   = "exact"   // NormalizeRef of an EXACT synonym
+	kindSynonym = "synonym" // NormalizeRef of any other synonym
+	kindFolded  = "folded"  // FoldName of the name or a synonym
+	kindToken   = "token"   // a TokenizeName token of the name or a synonym
+)
+
+// Hierarchy kinds: the answers of the parents, children and ancestors
+// routes, precomputed in order.
+const (
+	kindParent   = "parent"
+	kindChild    = "child"
+	kindAncestor = "ancestor"
+)
+
+const schema = `
+CREATE TABLE meta (
+    key   TEXT PRIMARY KEY,
+    value TEXT NOT NULL
+);
+
+-- body is the term as JSON; label is its label under the release's label
+-- preferences.
+CREATE TABLE term (
+    id    TEXT PRIMARY KEY,
+    label TEXT NOT NULL,
+    body  TEXT NOT NULL
+);
+
+CREATE TABLE typedef (
+    id   TEXT PRIMARY KEY,
+    body TEXT NOT NULL
+);
+
+CREATE TABLE alt_id (
+    alt_id  TEXT PRIMARY KEY,
+    term_id TEXT NOT NULL
+);
+
+CREATE TABLE name (
+    key     TEXT NOT NULL,
+    kind    TEXT NOT NULL,
+    term_id TEXT NOT NULL
+);
+CREATE INDEX name_key ON name (kind, key);
+
+CREATE TABLE hierarchy (
+    term_id  TEXT NOT NULL,
+    kind     TEXT NOT NULL,
+    seq      INTEGER NOT NULL,
+    other_id TEXT NOT NULL,
+    PRIMARY KEY (term_id, kind, seq)
+);
+
+-- ReferencedBy of each term, in order.
+CREATE TABLE reference (
+    term_id  TEXT NOT NULL,
+    seq      INTEGER NOT NULL,
+    ref_id   TEXT NOT NULL,
+    field    TEXT NOT NULL,
+    relation TEXT NOT NULL,
+    via      TEXT NOT NULL,
+    PRIMARY KEY (term_id, seq)
+);
+`
+
+// ExportFile writes ont to a new SQLite file at path, with labels chosen
+// by opts.Labels. It refuses to overwrite an existing file.
+func ExportFile(path string, ont *ontology.Ontology, opts ontology.IndexOptions) error {
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("%s already exists", path)
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	if err := Export(db, ont, opts); err != nil {
+		db.Close()
+		os.Remove(path)
+		return err
+	}
+	return db.Close()
+}
+
+// Export creates the tables Open reads in an empty database and fills
+// them from ont in one transaction.
+func Export(db *sql.DB, ont *ontology.Ontology, opts ontology.IndexOptions) error {
+	if _, err := db.Exec(schema); err != nil {
+		return err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := export(tx, ont, opts); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func export(tx *sql.Tx, ont *ontology.Ontology, opts ontology.IndexOptions) error {
+	ix := ontology.NewIndexWithOptions(ont, ontology.IndexOptions{Labels: opts.Labels})
+	header := *ont
+	header.Terms, header.TypeDefs = nil, nil
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	obsolete := 0
+	for i := range ont.Terms {
+		if ont.Terms[i].IsObsolete {
+			obsolete++
+		}
+	}
+	for _, kv := range [][2]string{
+		{"schema_version", schemaVersion},
+		{"header", string(headerJSON)},
+		{"labels", opts.Labels.String()},
+		{"terms", fmt.Sprint(len(ont.Terms))},
+		{"obsolete_terms", fmt.Sprint(obsolete)},
+		{"typedefs", fmt.Sprint(len(ont.TypeDefs))},
+	} {
+		if _, err := tx.Exec(`INSERT INTO meta (key, value) VALUES (?, ?)`, kv[0], kv[1]); err != nil {
+			return err
+		}
+	}
+
+	insTerm, err := tx.Prepare(`INSERT INTO term (id, label, body) VALUES (?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	insAlt, err := tx.Prepare(`INSERT OR IGNORE INTO alt_id (alt_id, term_id) VALUES (?, ?)`)
+	if err != nil {
+		return err
+	}
+	insName, err := tx.Prepare(`INSERT INTO name (key, kind, term_id) VALUES (?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	insHier, err := tx.Prepare(`INSERT INTO hierarchy (term_id, kind, seq, other_id) VALUES (?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	insRef, err := tx.Prepare(`INSERT INTO reference (term_id, seq, ref_id, field, relation, via) VALUES (?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	for i := range ont.Terms {
+		t := &ont.Terms[i]
+		body, err := json.Marshal(t)
+		if err != nil {
+			return err
+		}
+		if _, err := insTerm.Exec(t.ID, ix.Label(t.ID), body); err != nil {
+			return fmt.Errorf("term %s: %w", t.ID, err)
+		}
+		for _, alt := range t.AltIDs {
+			if ix.Primary(alt) == t.ID {
+				if _, err := insAlt.Exec(alt, t.ID); err != nil {
+					return err
+				}
+			}
+		}
+		for _, n := range termNames(t) {
+			if _, err := insName.Exec(n[0], n[1], t.ID); err != nil {
+				return err
+			}
+		}
+		for kind, ids := range map[string][]string{
+			kindParent:   ix.Parents(t.ID),
+			kindChild:    ix.Children(t.ID),
+			kindAncestor: ix.Ancestors(t.ID),
+		} {
+			for seq, id := range ids {
+				if _, err := insHier.Exec(t.ID, kind, seq, id); err != nil {
+					return err
+				}
+			}
+		}
+		for seq, r := range ix.ReferencedBy(t.ID) {
+			if _, err := insRef.Exec(t.ID, seq, r.ID, r.Field, r.Relation, r.Via); err != nil {
+				return err
+			}
+		}
+	}
+	for i := range ont.TypeDefs {
+		body, err := json.Marshal(&ont.TypeDefs[i])
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO typedef (id, body) VALUES (?, ?)`, ont.TypeDefs[i].ID, body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// termNames returns the distinct (key, kind) rows of t's name table.
+func termNames(t *ontology.Term) [][2]string {
+	var out [][2]string
+	seen := make(map[[2]string]bool)
+	add := func(key, kind string) {
+		k := [2]string{key, kind}
+		if key != "" && !seen[k] {
+			seen[k] = true
+			out = append(out, k)
+		}
+	}
+	texts := []string{t.Name}
+	add(ontology.NormalizeRef(t.Name), kindLabel)
+	for _, syn := range t.Synonyms {
+		texts = append(texts, syn.Text)
+		if syn.Scope == "EXACT" {
+			add(ontology.NormalizeRef(syn.Text), kindExact)
+		} else {
+			add(ontology.NormalizeRef(syn.Text), kindSynonym)
+		}
+	}
+	for _, text := range texts {
+		add(ontology.FoldName(text), kindFolded)
+		for _, tok := range ontology.TokenizeName(text) {
+			add(tok, kindToken)
+		}
+	}
+	return out
+}
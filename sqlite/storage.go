@@ -0,0 +1,462 @@
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/nodeadmin/chebi-parser/ontology"
+	"github.com/nodeadmin/chebi-parser/server"
+)
+
+// Storage serves a release from a file written by Export. Lookups and
+// name search read only the candidate terms and rank them as
+// ontology.Index does; the fuzzy tier scans the folded names on disk. The
+// query and subsumes routes read the whole ontology in on first use, as
+// the reasoner needs it.
+type Storage struct {
+	db     *sql.DB
+	info   server.StorageInfo
+	header ontology.Ontology
+
+	ontOnce sync.Once
+	ont     *ontology.Ontology
+	ontErr  error
+
+	invOnce sync.Once
+	inv     map[string]string
+	invErr  error
+}
+
+var _ server.Storage = (*Storage)(nil)
+
+// Open opens a file written by Export, read-only.
+func Open(path string) (*Storage, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return nil, err
+	}
+	s := &Storage{db: db}
+	if err := s.readMeta(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Close closes the database.
+func (s *Storage) Close() error { return s.db.Close() }
+
+func (s *Storage) readMeta() error {
+	rows, err := s.db.Query(`SELECT key, value FROM meta`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	meta := make(map[string]string)
+	for rows.Next() {
+		var k, v string
+		if err := rows.Scan(&k, &v); err != nil {
+			return err
+		}
+		meta[k] = v
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if meta["schema_version"] != schemaVersion {
+		return fmt.Errorf("unsupported schema version %q (want %s)", meta["schema_version"], schemaVersion)
+	}
+	if err := json.Unmarshal([]byte(meta["header"]), &s.header); err != nil {
+		return fmt.Errorf("header: %w", err)
+	}
+	labels, err := ontology.ParseLabelPrefs(meta["labels"])
+	if err != nil {
+		return err
+	}
+	s.info = server.StorageInfo{
+		Ontology:      s.header.Ontology,
+		DataVersion:   s.header.DataVersion,
+		FormatVersion: s.header.FormatVersion,
+		Metadata:      s.header.Metadata,
+		Labels:        labels,
+	}
+	for key, n := range map[string]*int{"terms": &s.info.Terms, "obsolete_terms": &s.info.ObsoleteTerms, "typedefs": &s.info.TypeDefs} {
+		if *n, err = strconv.Atoi(meta[key]); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// Info returns the header and counts Export stored.
+func (s *Storage) Info() (server.StorageInfo, error) { return s.info, nil }
+
+// GetTerm returns the term with the given ID, or nil.
+func (s *Storage) GetTerm(id string) (*ontology.Term, error) {
+	var body []byte
+	err := s.db.QueryRow(`SELECT body FROM term WHERE id = ?`, id).Scan(&body)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	t := new(ontology.Term)
+	if err := json.Unmarshal(body, t); err != nil {
+		return nil, fmt.Errorf("term %s: %w", id, err)
+	}
+	return t, nil
+}
+
+// Label returns the label Export chose for id, or "" if there is none.
+func (s *Storage) Label(id string) (string, error) {
+	var label string
+	err := s.db.QueryRow(`SELECT label FROM term WHERE id = ?`, id).Scan(&label)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return label, err
+}
+
+// primary returns the primary ID for id, resolving alt IDs, or "".
+func (s *Storage) primary(id string) (string, error) {
+	var primary string
+	err := s.db.QueryRow(`SELECT id FROM term WHERE id = ?
+		UNION ALL SELECT term_id FROM alt_id WHERE alt_id = ? LIMIT 1`, id, id).Scan(&primary)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return primary, err
+}
+
+func (s *Storage) Parents(id string) ([]string, error)  { return s.hierarchy(id, kindParent) }
+func (s *Storage) Children(id string) ([]string, error) { return s.hierarchy(id, kindChild) }
+func (s *Storage) Closure(id string) ([]string, error)  { return s.hierarchy(id, kindAncestor) }
+
+func (s *Storage) hierarchy(id, kind string) ([]string, error) {
+	return s.strings(`SELECT other_id FROM hierarchy WHERE term_id = ? AND kind = ? ORDER BY seq`, id, kind)
+}
+
+// strings returns the single text column of a query.
+func (s *Storage) strings(query string, args ...any) ([]string, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, rows.Err()
+}
+
+// candidates returns an index over the terms with the given IDs, for
+// Lookup and Search to rank as the in-memory index would.
+func (s *Storage) candidates(ids []string) (*ontology.Index, error) {
+	sub := &ontology.Ontology{}
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		t, err := s.GetTerm(id)
+		if err != nil {
+			return nil, err
+		}
+		if t != nil {
+			sub.Terms = append(sub.Terms, *t)
+		}
+	}
+	return ontology.NewIndexWithOptions(sub, ontology.IndexOptions{Labels: s.info.Labels}), nil
+}
+
+// Lookup resolves ref as ontology.Index.Lookup does.
+func (s *Storage) Lookup(ref string) (string, error) {
+	ref = strings.TrimSpace(ref)
+	id, err := s.primary(ref)
+	if err != nil || id != "" {
+		return id, err
+	}
+	ids, err := s.strings(`SELECT term_id FROM name WHERE kind IN (?, ?, ?) AND key = ?`,
+		kindLabel, kindExact, kindSynonym, ontology.NormalizeRef(ref))
+	if err != nil {
+		return "", err
+	}
+	ix, err := s.candidates(ids)
+	if err != nil {
+		return "", err
+	}
+	return ix.Lookup(ref)
+}
+
+// Search resolves name as ontology.Index.Resolve does.
+func (s *Storage) Search(name string, opts ontology.ResolveOptions) ([]ontology.Match, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, nil
+	}
+	var ids []string
+	id, err := s.primary(name)
+	if err != nil {
+		return nil, err
+	}
+	if id != "" {
+		ids = append(ids, id)
+	}
+	named, err := s.strings(`SELECT term_id FROM name WHERE (kind IN (?, ?, ?) AND key = ?) OR (kind = ? AND key = ?)`,
+		kindLabel, kindExact, kindSynonym, ontology.NormalizeRef(name), kindFolded, ontology.FoldName(name))
+	if err != nil {
+		return nil, err
+	}
+	ids = append(ids, named...)
+	if len(ids) == 0 {
+		// Resolve only tries the recall tiers when the others find
+		// nothing. A term with every token is not sure to match in one
+		// name, so the fuzzy candidates are needed either way.
+		if ids, err = s.tokenCandidates(name); err != nil {
+			return nil, err
+		}
+		fuzzy, err := s.fuzzyCandidates(ontology.FoldName(name), opts.MaxEdits)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, fuzzy...)
+	}
+	ix, err := s.candidates(ids)
+	if err != nil {
+		return nil, err
+	}
+	return ix.Resolve(name, opts), nil
+}
+
+// fuzzyCandidates returns the terms with a folded name within Resolve's
+// fuzzy edit distance of folded.
+func (s *Storage) fuzzyCandidates(folded string, maxEdits int) ([]string, error) {
+	edits := ontology.FuzzyEdits(folded, maxEdits)
+	if edits == 0 {
+		return nil, nil
+	}
+	n := utf8.RuneCountInString(folded)
+	rows, err := s.db.Query(`SELECT key, term_id FROM name WHERE kind = ? AND length(key) BETWEEN ? AND ?`,
+		kindFolded, n-edits, n+edits)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var key, id string
+		if err := rows.Scan(&key, &id); err != nil {
+			return nil, err
+		}
+		if ontology.EditDistance(folded, key, edits) <= edits {
+			out = append(out, id)
+		}
+	}
+	return out, rows.Err()
+}
+
+// tokenCandidates returns the terms with every token of name among the
+// tokens of their name and synonyms; Resolve then checks that one name
+// holds them all.
+func (s *Storage) tokenCandidates(name string) ([]string, error) {
+	seen := make(map[string]bool)
+	var toks []any
+	for _, tok := range ontology.TokenizeName(name) {
+		if !seen[tok] {
+			seen[tok] = true
+			toks = append(toks, tok)
+		}
+	}
+	if len(toks) == 0 {
+		return nil, nil
+	}
+	query := `SELECT term_id FROM name WHERE kind = ? AND key IN (?` + strings.Repeat(", ?", len(toks)-1) +
+		`) GROUP BY term_id HAVING COUNT(*) = ?`
+	args := append(append([]any{kindToken}, toks...), len(toks))
+	return s.strings(query, args...)
+}
+
+// SearchBatch runs Search for every name, once per distinct name.
+func (s *Storage) SearchBatch(names []string, opts ontology.ResolveOptions) ([]ontology.ResolveResult, error) {
+	out := make([]ontology.ResolveResult, len(names))
+	first := make(map[string]int, len(names))
+	for i, n := range names {
+		if j, ok := first[n]; ok {
+			out[i] = out[j]
+			continue
+		}
+		first[n] = i
+		matches, err := s.Search(n, opts)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = ontology.NewResolveResult(n, matches)
+	}
+	return out, nil
+}
+
+// References returns what ontology.Index.ReferencedBy returned for id at
+// export.
+func (s *Storage) References(id string) ([]ontology.Reference, error) {
+	return s.references(`SELECT ref_id, field, relation, via FROM reference WHERE term_id = ? ORDER BY seq`, id)
+}
+
+func (s *Storage) references(query string, args ...any) ([]ontology.Reference, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []ontology.Reference
+	for rows.Next() {
+		var r ontology.Reference
+		if err := rows.Scan(&r.ID, &r.Field, &r.Relation, &r.Via); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// inverses returns the declared inverse of every relation, read once.
+func (s *Storage) inverses() (map[string]string, error) {
+	s.invOnce.Do(func() {
+		var bodies []string
+		if bodies, s.invErr = s.strings(`SELECT body FROM typedef`); s.invErr != nil {
+			return
+		}
+		ont := &ontology.Ontology{TypeDefs: make([]ontology.TypeDef, len(bodies))}
+		for i, body := range bodies {
+			if s.invErr = json.Unmarshal([]byte(body), &ont.TypeDefs[i]); s.invErr != nil {
+				return
+			}
+		}
+		s.inv = ontology.InverseRelations(ont)
+	})
+	return s.inv, s.invErr
+}
+
+// Edges lists the relationships of id as ontology.Index.DirectedEdges
+// does.
+func (s *Storage) Edges(id string, d ontology.Direction) ([]ontology.DirectedEdge, error) {
+	primary, err := s.primary(id)
+	if err != nil {
+		return nil, err
+	}
+	if primary == "" {
+		primary = id
+	}
+	var out []ontology.DirectedEdge
+	if d == ontology.DirectionUp {
+		t, err := s.GetTerm(primary)
+		if err != nil || t == nil {
+			return nil, err
+		}
+		for _, rel := range t.Relationships {
+			out = append(out, ontology.DirectedEdge{Subject: t.ID, Relation: rel.Type, Object: rel.TargetID, Direction: ontology.DirectionUp, Asserted: rel.Type})
+		}
+		return out, nil
+	}
+	inv, err := s.inverses()
+	if err != nil {
+		return nil, err
+	}
+	refs, err := s.References(primary)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range refs {
+		if r.Field == ontology.RefRelationship {
+			up := ontology.DirectedEdge{Subject: r.ID, Relation: r.Relation, Object: primary, Direction: ontology.DirectionUp, Asserted: r.Relation}
+			out = append(out, up.Flip(inv))
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Object != out[j].Object {
+			return out[i].Object < out[j].Object
+		}
+		return out[i].Relation < out[j].Relation
+	})
+	return out, nil
+}
+
+// Path finds a shortest chain of relationships as ontology.Index.Path
+// does, reading one term's edges at a time.
+func (s *Storage) Path(from, to string, relations []string) ([]ontology.PathEdge, error) {
+	a, err := s.primary(from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := s.primary(to)
+	if err != nil || a == "" || b == "" {
+		return nil, err
+	}
+	return ontology.FindPath(a, b, relations, func(id string, inverse bool) ([]ontology.PathEdge, error) {
+		var steps []ontology.PathEdge
+		if !inverse {
+			t, err := s.GetTerm(id)
+			if err != nil || t == nil {
+				return nil, err
+			}
+			for _, rel := range t.Relationships {
+				if rel.TargetID != "" {
+					steps = append(steps, ontology.PathEdge{From: id, Relation: rel.Type, To: rel.TargetID})
+				}
+			}
+			return steps, nil
+		}
+		refs, err := s.references(`SELECT ref_id, field, relation, via FROM reference
+			WHERE term_id = ? AND field = ? AND via = '' ORDER BY seq`, id, ontology.RefRelationship)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range refs {
+			steps = append(steps, ontology.PathEdge{From: id, Relation: r.Relation, To: r.ID, Inverse: true})
+		}
+		return steps, nil
+	})
+}
+
+// Ontology reads the whole release in on the first call.
+func (s *Storage) Ontology() (*ontology.Ontology, error) {
+	s.ontOnce.Do(func() {
+		ont := s.header
+		ont.Terms = make([]ontology.Term, 0, s.info.Terms)
+		var bodies []string
+		if bodies, s.ontErr = s.strings(`SELECT body FROM term ORDER BY rowid`); s.ontErr != nil {
+			return
+		}
+		for _, body := range bodies {
+			var t ontology.Term
+			if s.ontErr = json.Unmarshal([]byte(body), &t); s.ontErr != nil {
+				return
+			}
+			ont.Terms = append(ont.Terms, t)
+		}
+		if bodies, s.ontErr = s.strings(`SELECT body FROM typedef ORDER BY rowid`); s.ontErr != nil {
+			return
+		}
+		for _, body := range bodies {
+			var td ontology.TypeDef
+			if s.ontErr = json.Unmarshal([]byte(body), &td); s.ontErr != nil {
+				return
+			}
+			ont.TypeDefs = append(ont.TypeDefs, td)
+		}
+		s.ont = &ont
+	})
+	return s.ont, s.ontErr
+}
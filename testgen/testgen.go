@@ -0,0 +1,149 @@
+// Package testgen generates synthetic ontologies for fuzzing the parsers and
+// stress-testing the reasoner beyond the size of real ChEBI releases.
+//
+// Generation is deterministic for a given Config. Terms form an is_a DAG
+// rooted at the first term; existential relationships, intersection_of
+// definitions, transitive relations and property chains are sprinkled in
+// at the configured densities.
+package testgen
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+
+	"github.com/nodeadmin/chebi-parser/ontology"
+)
+
+// Config controls the shape of a generated ontology.
+type Config struct {
+	Terms         int     // number of terms, including the root
+	Branching     int     // children per term in the backbone is_a tree
+	MultiParent   float64 // probability of an extra is_a parent
+	Relations     int     // number of relation types
+	Transitive    float64 // fraction of relation types that are transitive
+	Chains        int     // number of two-step property chains
+	RelDensity    float64 // mean existential relationships per term
+	CrossProducts float64 // fraction of terms with an intersection_of definition
+	Obsolete      float64 // fraction of terms marked obsolete
+	Synonyms      float64 // mean synonyms per term
+	Prefix        string  // ID prefix, e.g. "SYN"
+	Seed          uint64
+}
+
+// DefaultConfig returns a ChEBI-like shape at a small size.
+func DefaultConfig() Config {
+	return Config{
+		Terms:         10000,
+		Branching:     8,
+		MultiParent:   0.3,
+		Relations:     10,
+		Transitive:    0.3,
+		Chains:        2,
+		RelDensity:    1.0,
+		CrossProducts: 0.05,
+		Obsolete:      0.01,
+		Synonyms:      1.5,
+		Prefix:        "SYN",
+		Seed:          1,
+	}
+}
+
+// Generate builds a synthetic ontology from cfg.
+func Generate(cfg Config) *ontology.Ontology {
+	if cfg.Terms < 1 {
+		cfg.Terms = 1
+	}
+	if cfg.Branching < 1 {
+		cfg.Branching = 1
+	}
+	if cfg.Prefix == "" {
+		cfg.Prefix = "SYN"
+	}
+	rng := rand.New(rand.NewPCG(cfg.Seed, cfg.Seed^0x9e3779b97f4a7c15))
+
+	ont := &ontology.Ontology{
+		FormatVersion: "1.2",
+		DataVersion:   fmt.Sprintf("testgen/%d", cfg.Seed),
+		Ontology:      "testgen",
+		Terms:         make([]ontology.Term, cfg.Terms),
+	}
+
+	roles := make([]string, cfg.Relations)
+	for i := range roles {
+		roles[i] = fmt.Sprintf("rel%d", i)
+		ont.TypeDefs = append(ont.TypeDefs, ontology.TypeDef{
+			ID:           roles[i],
+			Name:         fmt.Sprintf("relation %d", i),
+			IsTransitive: rng.Float64() < cfg.Transitive,
+		})
+	}
+	if len(roles) > 0 {
+		for i := 0; i < cfg.Chains; i++ {
+			td := &ont.TypeDefs[rng.IntN(len(roles))]
+			chain := roles[rng.IntN(len(roles))] + " " + roles[rng.IntN(len(roles))]
+			td.HoldsOverChain = append(td.HoldsOverChain, chain)
+		}
+	}
+
+	id := func(i int) string { return fmt.Sprintf("%s:%07d", cfg.Prefix, i) }
+	for i := range ont.Terms {
+		t := &ont.Terms[i]
+		t.ID = id(i)
+		t.Name = fmt.Sprintf("synthetic entity %d", i)
+		t.Definition = fmt.Sprintf("A generated term at position %d.", i)
+		for n := poisson(rng, cfg.Synonyms); n > 0; n-- {
+			t.Synonyms = append(t.Synonyms, ontology.Synonym{
+				Text:  fmt.Sprintf("syn-%d-%d", i, n),
+				Scope: synonymScopes[rng.IntN(len(synonymScopes))],
+			})
+		}
+		if i == 0 {
+			continue
+		}
+
+		// Backbone tree plus optional extra parents drawn from earlier
+		// terms, which keeps the is_a graph acyclic.
+		parent := (i - 1) / cfg.Branching
+		t.Relationships = append(t.Relationships, ontology.Relationship{Type: "is_a", TargetID: id(parent)})
+		if i > 1 && rng.Float64() < cfg.MultiParent {
+			if p := rng.IntN(i); p != parent {
+				t.Relationships = append(t.Relationships, ontology.Relationship{Type: "is_a", TargetID: id(p)})
+			}
+		}
+		if len(roles) > 0 {
+			for n := poisson(rng, cfg.RelDensity); n > 0; n-- {
+				t.Relationships = append(t.Relationships, ontology.Relationship{
+					Type:     roles[rng.IntN(len(roles))],
+					TargetID: id(rng.IntN(cfg.Terms)),
+				})
+			}
+			if rng.Float64() < cfg.CrossProducts {
+				t.IntersectionOf = []ontology.IntersectionPart{
+					{TargetID: id(parent)},
+					{Relationship: roles[rng.IntN(len(roles))], TargetID: id(rng.IntN(cfg.Terms))},
+				}
+			}
+		}
+		t.IsObsolete = rng.Float64() < cfg.Obsolete
+	}
+	return ont
+}
+
+var synonymScopes = []string{"EXACT", "RELATED", "BROAD", "NARROW"}
+
+// poisson draws from a Poisson distribution with the given mean using
+// Knuth's method, which is fine for the small means used here.
+func poisson(rng *rand.Rand, mean float64) int {
+	if mean <= 0 {
+		return 0
+	}
+	l, k, p := math.Exp(-mean), 0, 1.0
+	for {
+		p *= rng.Float64()
+		if p <= l {
+			return k
+		}
+		k++
+	}
+}
@@ -0,0 +1,181 @@
+package testgen
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/nodeadmin/chebi-parser/ontology"
+)
+
+const oboURI = "http://purl.obolibrary.org/obo/"
+
+// WriteOBO writes ont in OBO 1.2 flat-file syntax. It covers the subset of
+// the model produced by Generate.
+func WriteOBO(ont *ontology.Ontology, w io.Writer) error {
+	bw := bufio.NewWriterSize(w, 256*1024)
+	fmt.Fprintf(bw, "format-version: %s\n", ont.FormatVersion)
+	if ont.DataVersion != "" {
+		fmt.Fprintf(bw, "data-version: %s\n", ont.DataVersion)
+	}
+	if ont.Ontology != "" {
+		fmt.Fprintf(bw, "ontology: %s\n", ont.Ontology)
+	}
+
+	for i := range ont.Terms {
+		t := &ont.Terms[i]
+		fmt.Fprintf(bw, "\n[Term]\nid: %s\n", t.ID)
+		if t.Name != "" {
+			fmt.Fprintf(bw, "name: %s\n", t.Name)
+		}
+		if t.Definition != "" {
+			fmt.Fprintf(bw, "def: %q []\n", t.Definition)
+		}
+		for _, syn := range t.Synonyms {
+			fmt.Fprintf(bw, "synonym: %q %s []\n", syn.Text, syn.Scope)
+		}
+		for _, x := range t.Xrefs {
+			fmt.Fprintf(bw, "xref: %s\n", x)
+		}
+		for _, rel := range t.Relationships {
+			if rel.Type == "is_a" {
+				fmt.Fprintf(bw, "is_a: %s\n", rel.TargetID)
+			}
+		}
+		for _, part := range t.IntersectionOf {
+			if part.Relationship == "" {
+				fmt.Fprintf(bw, "intersection_of: %s\n", part.TargetID)
+			} else {
+				fmt.Fprintf(bw, "intersection_of: %s %s\n", part.Relationship, part.TargetID)
+			}
+		}
+		for _, rel := range t.Relationships {
+			if rel.Type != "is_a" {
+				fmt.Fprintf(bw, "relationship: %s %s\n", rel.Type, rel.TargetID)
+			}
+		}
+		if t.IsObsolete {
+			bw.WriteString("is_obsolete: true\n")
+		}
+	}
+
+	for i := range ont.TypeDefs {
+		td := &ont.TypeDefs[i]
+		fmt.Fprintf(bw, "\n[Typedef]\nid: %s\n", td.ID)
+		if td.Name != "" {
+			fmt.Fprintf(bw, "name: %s\n", td.Name)
+		}
+		if td.IsTransitive {
+			bw.WriteString("is_transitive: true\n")
+		}
+		for _, chain := range td.HoldsOverChain {
+			fmt.Fprintf(bw, "holds_over_chain: %s\n", chain)
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteOWL writes ont as OWL RDF/XML in the layout of the ChEBI release.
+// Intersection definitions are written as owl:equivalentClass axioms.
+func WriteOWL(ont *ontology.Ontology, w io.Writer) error {
+	bw := bufio.NewWriterSize(w, 256*1024)
+	bw.WriteString(`<?xml version="1.0"?>
+<rdf:RDF xmlns="http://purl.obolibrary.org/obo/"
+     xmlns:owl="http://www.w3.org/2002/07/owl#"
+     xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+     xmlns:rdfs="http://www.w3.org/2000/01/rdf-schema#"
+     xmlns:obo="http://purl.obolibrary.org/obo/"
+     xmlns:oboInOwl="http://www.geneontology.org/formats/oboInOwl#">
+`)
+	fmt.Fprintf(bw, "    <owl:Ontology rdf:about=\"%s%s.owl\"/>\n", oboURI, escape(ont.Ontology))
+
+	for i := range ont.TypeDefs {
+		td := &ont.TypeDefs[i]
+		fmt.Fprintf(bw, "    <owl:ObjectProperty rdf:about=\"%s\">\n", uri(td.ID))
+		if td.IsTransitive {
+			bw.WriteString("        <rdf:type rdf:resource=\"http://www.w3.org/2002/07/owl#TransitiveProperty\"/>\n")
+		}
+		if td.Name != "" {
+			fmt.Fprintf(bw, "        <rdfs:label>%s</rdfs:label>\n", escape(td.Name))
+		}
+		for _, chain := range td.HoldsOverChain {
+			bw.WriteString("        <owl:propertyChainAxiom rdf:parseType=\"Collection\">\n")
+			for _, r := range strings.Fields(chain) {
+				fmt.Fprintf(bw, "            <rdf:Description rdf:about=\"%s\"/>\n", uri(r))
+			}
+			bw.WriteString("        </owl:propertyChainAxiom>\n")
+		}
+		bw.WriteString("    </owl:ObjectProperty>\n")
+	}
+
+	for i := range ont.Terms {
+		t := &ont.Terms[i]
+		fmt.Fprintf(bw, "    <owl:Class rdf:about=\"%s\">\n", uri(t.ID))
+		if t.Name != "" {
+			fmt.Fprintf(bw, "        <rdfs:label>%s</rdfs:label>\n", escape(t.Name))
+		}
+		if t.Definition != "" {
+			fmt.Fprintf(bw, "        <obo:IAO_0000115>%s</obo:IAO_0000115>\n", escape(t.Definition))
+		}
+		for _, rel := range t.Relationships {
+			if rel.Type == "is_a" {
+				fmt.Fprintf(bw, "        <rdfs:subClassOf rdf:resource=\"%s\"/>\n", uri(rel.TargetID))
+				continue
+			}
+			fmt.Fprintf(bw, "        <rdfs:subClassOf>\n            <owl:Restriction>\n"+
+				"                <owl:onProperty rdf:resource=\"%s\"/>\n"+
+				"                <owl:someValuesFrom rdf:resource=\"%s\"/>\n"+
+				"            </owl:Restriction>\n        </rdfs:subClassOf>\n", uri(rel.Type), uri(rel.TargetID))
+		}
+		if len(t.IntersectionOf) > 0 {
+			bw.WriteString("        <owl:equivalentClass>\n            <owl:Class>\n" +
+				"                <owl:intersectionOf rdf:parseType=\"Collection\">\n")
+			for _, part := range t.IntersectionOf {
+				if part.Relationship == "" {
+					fmt.Fprintf(bw, "                    <rdf:Description rdf:about=\"%s\"/>\n", uri(part.TargetID))
+					continue
+				}
+				fmt.Fprintf(bw, "                    <owl:Restriction>\n"+
+					"                        <owl:onProperty rdf:resource=\"%s\"/>\n"+
+					"                        <owl:someValuesFrom rdf:resource=\"%s\"/>\n"+
+					"                    </owl:Restriction>\n", uri(part.Relationship), uri(part.TargetID))
+			}
+			bw.WriteString("                </owl:intersectionOf>\n            </owl:Class>\n        </owl:equivalentClass>\n")
+		}
+		for _, syn := range t.Synonyms {
+			tag := "oboInOwl:has" + scopeTag(syn.Scope) + "Synonym"
+			fmt.Fprintf(bw, "        <%s>%s</%s>\n", tag, escape(syn.Text), tag)
+		}
+		if t.IsObsolete {
+			bw.WriteString("        <owl:deprecated>true</owl:deprecated>\n")
+		}
+		bw.WriteString("    </owl:Class>\n")
+	}
+	bw.WriteString("</rdf:RDF>\n")
+	return bw.Flush()
+}
+
+// uri converts an OBO ID ("SYN:0000001", "rel3") to its PURL.
+func uri(id string) string {
+	return oboURI + escape(strings.Replace(id, ":", "_", 1))
+}
+
+func scopeTag(scope string) string {
+	switch scope {
+	case "BROAD":
+		return "Broad"
+	case "NARROW":
+		return "Narrow"
+	case "RELATED":
+		return "Related"
+	}
+	return "Exact"
+}
+
+func escape(s string) string {
+	var sb strings.Builder
+	xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}
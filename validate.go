@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nodeadmin/chebi-parser/internal/exitcode"
+	"github.com/nodeadmin/chebi-parser/ontology"
+)
+
+// validateIDsCommand checks a list of CHEBI IDs, labels or synonyms against a
+// release and writes a TSV report: id, status, primary, replaced_by,
+// consider, ancestor, candidates.
+func validateIDsCommand() (*flag.FlagSet, func() error) {
+	fs := newFlagSet("validate-ids")
+	input := fs.String("input", "", "Ontology file (.obo, .owl or .msgpack)")
+	format := fs.String("format", "auto", "Input format: auto, obo, owl, msgpack")
+	ids := fs.String("ids", "", "File of IDs or names, one per line or in a TSV column (- for stdin)")
+	column := fs.Int("column", 1, "1-based TSV column holding the IDs")
+	header := fs.Bool("header", false, "Skip the first line of the ID file")
+	ancestor := fs.Bool("ancestor", false, "Report the nearest non-obsolete ancestor of obsolete IDs")
+	output := fs.String("output", "", "Report file (default: stdout)")
+	return fs, func() error {
+		if *input == "" || *ids == "" {
+			return exitcode.Errorf(exitcode.Usage, "usage: chebi-parser validate-ids -input <file> -ids <file> [-column N] [-header] [-ancestor] [-output report.tsv]")
+		}
+		ont, err := loadOntology(*input, *format)
+		if err != nil {
+			return err
+		}
+		ix := ontology.NewIndex(ont)
+
+		list, err := readIDs(*ids, *column, *header)
+		if err != nil {
+			return err
+		}
+
+		out := os.Stdout
+		if *output != "" {
+			f, err := os.Create(*output)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			out = f
+		}
+		bw := bufio.NewWriter(out)
+		fmt.Fprintln(bw, "id\tstatus\tprimary\treplaced_by\tconsider\tancestor\tcandidates")
+
+		counts := make(map[string]int)
+		for _, id := range list {
+			c := ix.Validate(id, *ancestor)
+			counts[c.Status]++
+			fmt.Fprintf(bw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", c.ID, c.Status, c.Primary,
+				strings.Join(c.Replaced, ","), strings.Join(c.Consider, ","), c.Ancestor,
+				strings.Join(c.Candidates, ","))
+		}
+		if err := bw.Flush(); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(os.Stderr, "%d valid, %d alt IDs, %d names, %d obsolete, %d ambiguous, %d unknown\n",
+			counts[ontology.IDValid], counts[ontology.IDAltID], counts[ontology.IDName],
+			counts[ontology.IDObsolete], counts[ontology.IDAmbiguous], counts[ontology.IDUnknown])
+		return nil
+	}
+}